@@ -58,6 +58,8 @@ func buildCommand(ctx context.Context, msg message.Msg, early *earlyHardeningErr
 	{
 		var (
 			flagIdentifierFile int
+			flagInstanceID     string
+			flagStrict         bool
 		)
 		c.NewCommand("app", "Load and start container from configuration file", func(args []string) error {
 			if len(args) < 1 {
@@ -68,12 +70,26 @@ func buildCommand(ctx context.Context, msg message.Msg, early *earlyHardeningErr
 			if config != nil && config.Container != nil {
 				config.Container.Args = append(config.Container.Args, args[1:]...)
 			}
+			for _, err := range config.CheckWritableBinds(os.Getuid(), os.Stat) {
+				if flagStrict {
+					log.Fatal(err.Error())
+				}
+				log.Printf("warning: %v", err)
+			}
 
-			outcome.Main(ctx, msg, config, flagIdentifierFile)
+			var gen hst.IDGenerator
+			if flagInstanceID != "" {
+				gen = func() ([]byte, error) { return []byte(flagInstanceID), nil }
+			}
+			outcome.MainGenerator(ctx, msg, config, flagIdentifierFile, gen)
 			panic("unreachable")
 		}).
 			Flag(&flagIdentifierFile, "identifier-fd", command.IntFlag(-1),
-				"Write identifier of current instance to fd after successful startup")
+				"Write identifier of current instance to fd after successful startup").
+			Flag(&flagInstanceID, "instance-id", command.StringFlag(""),
+				"Use this hex-encoded identifier for the instance instead of generating one").
+			Flag(&flagStrict, "strict", command.BoolFlag(false),
+				"Treat configuration warnings as errors")
 	}
 
 	{
@@ -91,7 +107,7 @@ func buildCommand(ctx context.Context, msg message.Msg, early *earlyHardeningErr
 
 			flagPrivateRuntime, flagPrivateTmpdir bool
 
-			flagWayland, flagX11, flagDBus, flagPulse bool
+			flagWayland, flagX11, flagDBus, flagPulse, flagPipeWire bool
 		)
 
 		c.NewCommand("run", "Configure and start a permissive container", func(args []string) error {
@@ -149,6 +165,9 @@ func buildCommand(ctx context.Context, msg message.Msg, early *earlyHardeningErr
 			if flagPulse {
 				et |= hst.EPulse
 			}
+			if flagPipeWire {
+				et |= hst.EPipeWire
+			}
 
 			config := &hst.Config{
 				ID:          flagID,
@@ -298,7 +317,9 @@ func buildCommand(ctx context.Context, msg message.Msg, early *earlyHardeningErr
 			Flag(&flagDBus, "dbus", command.BoolFlag(false),
 				"Enable proxied connection to D-Bus").
 			Flag(&flagPulse, "pulse", command.BoolFlag(false),
-				"Enable direct connection to PulseAudio")
+				"Enable direct connection to PulseAudio").
+			Flag(&flagPipeWire, "pipewire", command.BoolFlag(false),
+				"Enable direct connection to PipeWire")
 	}
 
 	{
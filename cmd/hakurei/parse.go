@@ -16,12 +16,11 @@ import (
 	"hakurei.app/message"
 )
 
-// tryPath attempts to read [hst.Config] from multiple sources.
+// tryPath attempts to read [hst.Config] from multiple sources via [hst.DecodeConfig].
 // tryPath reads from [os.Stdin] if name has value "-".
 // Otherwise, name is passed to tryFd, and if that returns nil, name is passed to [os.Open].
 func tryPath(msg message.Msg, name string) (config *hst.Config) {
 	var r io.ReadCloser
-	config = new(hst.Config)
 
 	if name != "-" {
 		r = tryFd(msg, name)
@@ -39,7 +38,11 @@ func tryPath(msg message.Msg, name string) (config *hst.Config) {
 		r = os.Stdin
 	}
 
-	decodeJSON(log.Fatal, "load configuration", r, &config)
+	if v, err := hst.DecodeConfig(r); err != nil {
+		log.Fatal(getMessage("cannot load configuration:", err))
+	} else {
+		config = v
+	}
 	if err := r.Close(); err != nil {
 		log.Fatal(err.Error())
 	}
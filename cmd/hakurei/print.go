@@ -143,6 +143,9 @@ func printShowInstance(
 
 	printDBus := func(c *hst.BusConfig) {
 		t.Printf(" Filter:\t%v\n", c.Filter)
+		if c.PolicyRef != nil {
+			t.Printf(" Policy:\t%s\n", c.PolicyRef)
+		}
 		if len(c.See) > 0 {
 			t.Printf(" See:\t%q\n", c.See)
 		}
@@ -64,7 +64,7 @@ func TestPrintShowInstance(t *testing.T) {
  Identity:       9 (org.chromium.Chromium)
  Enablements:    wayland, dbus, pulseaudio
  Groups:         video, dialout, plugdev
- Flags:          multiarch, compat, devel, userns, net, abstract, tty, mapuid, device, runtime, tmpdir
+ Flags:          multiarch, compat, devel, userns, net, abstract, tty, mapuid, device, runtime, tmpdir, gpu, envstrict, machineid, readonlyroot, netfiles
  Home:           /data/data/org.chromium.Chromium
  Hostname:       localhost
  Path:           /run/current-system/sw/bin/chromium
@@ -161,7 +161,7 @@ App
  Identity:       9 (org.chromium.Chromium)
  Enablements:    wayland, dbus, pulseaudio
  Groups:         video, dialout, plugdev
- Flags:          multiarch, compat, devel, userns, net, abstract, tty, mapuid, device, runtime, tmpdir
+ Flags:          multiarch, compat, devel, userns, net, abstract, tty, mapuid, device, runtime, tmpdir, gpu, envstrict, machineid, readonlyroot, netfiles
  Home:           /data/data/org.chromium.Chromium
  Hostname:       localhost
  Path:           /run/current-system/sw/bin/chromium
@@ -356,7 +356,12 @@ App
     "map_real_uid": true,
     "device": true,
     "share_runtime": true,
-    "share_tmpdir": true
+    "share_tmpdir": true,
+    "gpu_config": true,
+    "env_strict": true,
+    "machine_id": true,
+    "read_only_root": true,
+    "network_files": true
   },
   "time": "1970-01-01T00:00:00.000000009Z"
 }
@@ -507,7 +512,12 @@ App
     "map_real_uid": true,
     "device": true,
     "share_runtime": true,
-    "share_tmpdir": true
+    "share_tmpdir": true,
+    "gpu_config": true,
+    "env_strict": true,
+    "machine_id": true,
+    "read_only_root": true,
+    "network_files": true
   }
 }
 `, true},
@@ -705,7 +715,12 @@ func TestPrintPs(t *testing.T) {
       "map_real_uid": true,
       "device": true,
       "share_runtime": true,
-      "share_tmpdir": true
+      "share_tmpdir": true,
+      "gpu_config": true,
+      "env_strict": true,
+      "machine_id": true,
+      "read_only_root": true,
+      "network_files": true
     },
     "time": "1970-01-01T00:00:00.000000009Z"
   },
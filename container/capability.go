@@ -1,6 +1,7 @@
 package container
 
 import (
+	"slices"
 	"syscall"
 	"unsafe"
 )
@@ -13,6 +14,7 @@ const (
 	PR_CAP_AMBIENT_CLEAR_ALL = 0x4
 
 	CAP_SYS_ADMIN    = 0x15
+	CAP_NET_ADMIN    = 0xc
 	CAP_SETPCAP      = 0x8
 	CAP_DAC_OVERRIDE = 0x1
 )
@@ -48,6 +50,18 @@ func capset(hdrp *capHeader, datap *[2]capData) error {
 	return nil
 }
 
+func capget(hdrp *capHeader, datap *[2]capData) error {
+	r, _, errno := syscall.Syscall(
+		syscall.SYS_CAPGET,
+		uintptr(unsafe.Pointer(hdrp)),
+		uintptr(unsafe.Pointer(&datap[0])), 0,
+	)
+	if r != 0 {
+		return errno
+	}
+	return nil
+}
+
 // capBoundingSetDrop drops a capability from the calling thread's capability bounding set.
 func capBoundingSetDrop(cap uintptr) error { return Prctl(syscall.PR_CAPBSET_DROP, cap, 0) }
 
@@ -56,3 +70,50 @@ func capAmbientClearAll() error { return Prctl(PR_CAP_AMBIENT, PR_CAP_AMBIENT_CL
 
 // capAmbientRaise adds to the ambient capability set of the calling thread.
 func capAmbientRaise(cap uintptr) error { return Prctl(PR_CAP_AMBIENT, PR_CAP_AMBIENT_RAISE, cap) }
+
+// setupCaps returns capabilities raised in the ambient set of container init for setup,
+// excluding those present in drop. A [StartError] is returned if a dropped capability is
+// required by a setup step: CAP_SYS_ADMIN and CAP_SETPCAP are always required, while
+// CAP_DAC_OVERRIDE is only required for accessing upperdir and workdir of a writable overlay.
+// CAP_NET_ADMIN is only raised if loopback is true, for bringing up the loopback interface.
+// Cgroup placement happens via [SysProcAttr.CgroupFD] and does not require any capability.
+// All of these are dropped by init before the initial program is started.
+func setupCaps(ops *Ops, drop []uintptr, loopback bool) ([]uintptr, error) {
+	caps := make([]uintptr, 0, 4)
+	for _, c := range []uintptr{
+		// general container setup
+		CAP_SYS_ADMIN,
+		// drop capabilities
+		CAP_SETPCAP,
+		// overlay access to upperdir and workdir
+		CAP_DAC_OVERRIDE,
+		// loopback interface in the new network namespace
+		CAP_NET_ADMIN,
+	} {
+		if c == CAP_NET_ADMIN && !loopback {
+			continue
+		}
+		if !slices.Contains(drop, c) {
+			caps = append(caps, c)
+			continue
+		}
+
+		switch c {
+		case CAP_SYS_ADMIN:
+			return nil, &StartError{false, "CAP_SYS_ADMIN is required for container setup", syscall.EINVAL, true, false, StartErrSetup}
+		case CAP_SETPCAP:
+			return nil, &StartError{false, "CAP_SETPCAP is required for dropping capabilities", syscall.EINVAL, true, false, StartErrSetup}
+		case CAP_NET_ADMIN:
+			return nil, &StartError{false, "CAP_NET_ADMIN is required for bringing up loopback", syscall.EINVAL, true, false, StartErrSetup}
+		case CAP_DAC_OVERRIDE:
+			if ops != nil {
+				for _, op := range *ops {
+					if o, ok := op.(*MountOverlayOp); ok && o.Upper != nil {
+						return nil, &StartError{false, "CAP_DAC_OVERRIDE is required for writable overlay on " + o.Target.String(), syscall.EINVAL, true, false, StartErrSetup}
+					}
+				}
+			}
+		}
+	}
+	return caps, nil
+}
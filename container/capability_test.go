@@ -1,6 +1,13 @@
 package container
 
-import "testing"
+import (
+	"reflect"
+	"syscall"
+	"testing"
+
+	"hakurei.app/container/check"
+	"hakurei.app/container/fhs"
+)
 
 func TestCapToIndex(t *testing.T) {
 	t.Parallel()
@@ -45,3 +52,88 @@ func TestCapToMask(t *testing.T) {
 		})
 	}
 }
+
+func TestSetupCaps(t *testing.T) {
+	t.Parallel()
+
+	cgroupOps := new(Ops).
+		Bind(fhs.AbsRoot, fhs.AbsRoot, 0).
+		Proc(fhs.AbsProc)
+	readonlyOverlay := new(Ops).
+		Overlay(fhs.AbsRoot, nil, nil,
+			check.MustAbs("/var/lib/planterette/base/debian:f92c9052"),
+			check.MustAbs("/var/lib/planterette/app/org.chromium.Chromium@debian:f92c9052"))
+	ephemeralOverlay := new(Ops).
+		Bind(fhs.AbsRoot, fhs.AbsRoot, 0).
+		OverlayEphemeral(check.MustAbs("/nix/store"), check.MustAbs("/mnt-root/nix/.ro-store"))
+	persistentOverlay := new(Ops).
+		Overlay(check.MustAbs("/nix/store"),
+			check.MustAbs("/mnt-root/nix/.rw-store/upper"),
+			check.MustAbs("/mnt-root/nix/.rw-store/work"),
+			check.MustAbs("/mnt-root/nix/.ro-store"))
+
+	testCases := []struct {
+		name     string
+		ops      *Ops
+		drop     []uintptr
+		loopback bool
+		want     []uintptr
+		wantErr  error
+	}{
+		{"default", ephemeralOverlay, nil, false,
+			[]uintptr{CAP_SYS_ADMIN, CAP_SETPCAP, CAP_DAC_OVERRIDE}, nil},
+		{"nil ops", nil, []uintptr{CAP_DAC_OVERRIDE}, false,
+			[]uintptr{CAP_SYS_ADMIN, CAP_SETPCAP}, nil},
+		{"unrelated", cgroupOps, []uintptr{CAP_NET_ADMIN}, false,
+			[]uintptr{CAP_SYS_ADMIN, CAP_SETPCAP, CAP_DAC_OVERRIDE}, nil},
+		{"cgroup", cgroupOps, []uintptr{CAP_DAC_OVERRIDE}, false,
+			[]uintptr{CAP_SYS_ADMIN, CAP_SETPCAP}, nil},
+		{"overlay readonly", readonlyOverlay, []uintptr{CAP_DAC_OVERRIDE}, false,
+			[]uintptr{CAP_SYS_ADMIN, CAP_SETPCAP}, nil},
+
+		{"overlay ephemeral", ephemeralOverlay, []uintptr{CAP_DAC_OVERRIDE}, false, nil, &StartError{
+			Step:   "CAP_DAC_OVERRIDE is required for writable overlay on /nix/store",
+			Err:    syscall.EINVAL,
+			Origin: true,
+			Kind:   StartErrSetup,
+		}},
+		{"overlay persistent", persistentOverlay, []uintptr{CAP_DAC_OVERRIDE}, false, nil, &StartError{
+			Step:   "CAP_DAC_OVERRIDE is required for writable overlay on /nix/store",
+			Err:    syscall.EINVAL,
+			Origin: true,
+			Kind:   StartErrSetup,
+		}},
+		{"loopback", cgroupOps, nil, true,
+			[]uintptr{CAP_SYS_ADMIN, CAP_SETPCAP, CAP_DAC_OVERRIDE, CAP_NET_ADMIN}, nil},
+		{"loopback drop", cgroupOps, []uintptr{CAP_NET_ADMIN}, true, nil, &StartError{
+			Step:   "CAP_NET_ADMIN is required for bringing up loopback",
+			Err:    syscall.EINVAL,
+			Origin: true,
+			Kind:   StartErrSetup,
+		}},
+		{"sys admin", cgroupOps, []uintptr{CAP_SYS_ADMIN}, false, nil, &StartError{
+			Step:   "CAP_SYS_ADMIN is required for container setup",
+			Err:    syscall.EINVAL,
+			Origin: true,
+			Kind:   StartErrSetup,
+		}},
+		{"setpcap", readonlyOverlay, []uintptr{CAP_DAC_OVERRIDE, CAP_SETPCAP}, false, nil, &StartError{
+			Step:   "CAP_SETPCAP is required for dropping capabilities",
+			Err:    syscall.EINVAL,
+			Origin: true,
+			Kind:   StartErrSetup,
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := setupCaps(tc.ops, tc.drop, tc.loopback)
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("setupCaps: error = %#v, want %#v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("setupCaps: %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package container
 
 import (
+	"bytes"
 	"context"
 	"encoding/gob"
 	"errors"
@@ -10,6 +11,7 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"slices"
 	"strconv"
 	"sync"
 	. "syscall"
@@ -31,6 +33,20 @@ const (
 	initSetupTimeout = 5 * time.Second
 )
 
+const (
+	// ExitFailure is returned by [Container.ExitCode] if container init was not observed exiting.
+	ExitFailure = iota + 1
+	// ExitCancel is returned by [Container.ExitCode] if Wait reported cancellation of the [Container] context.
+	ExitCancel
+	// ExitOrphan is returned by [Container.ExitCode] if container init was killed by SIGKILL
+	// not delivered by the [Container], which is attributed to Pdeathsig.
+	ExitOrphan
+
+	// ExitRequest is returned by [Container.ExitCode] if container init was terminated by a signal
+	// after cancellation was delivered to it.
+	ExitRequest = 254
+)
+
 type (
 	// Container represents a container environment being prepared or run.
 	// None of [Container] methods are safe for concurrent use.
@@ -39,12 +55,83 @@ type (
 		// with behaviour identical to its [exec.Cmd] counterpart.
 		ExtraFiles []*os.File
 
+		/* InitEnv is the environment of container init itself, useful for passing
+		variables such as GODEBUG when debugging container setup.
+
+		Container init never passes its own environment to the initial process,
+		whose environment is specified separately by the Env field of [Params].
+		The setup pipe variable takes precedence over entries of InitEnv. */
+		InitEnv []string
+
+		/* PidFile is the pathname of a file the host pid of container init is written to
+		once it starts, for integration with process supervisors. The file is removed by Wait.
+
+		If the file cannot be written, Start cancels the container and returns [StartError].
+		Wait must still be called to release resources associated with the [Container]. */
+		PidFile *check.Absolute
+
+		/* OnReady is called with the host pid of container init once it starts, while init
+		blocks waiting for Serve. This is useful for setup depending on init being alive,
+		such as joining it to an external cgroup or attaching a debugger.
+
+		OnReady is called by Start on the calling goroutine, which does not hold the locked
+		OS thread container init is started from. Its effects are best-effort: a panic is
+		recovered and logged, and does not prevent the container from starting. */
+		OnReady func(pid int)
+
+		/* NetSetup is called with the host pid of container init once it starts, after the
+		pidfile is written and before OnReady. This is the integration point for configuring
+		the container network namespace from the host, for example by moving one end of a veth
+		pair into /proc/<pid>/ns/net, or by starting slirp4netns. The network namespace remains
+		alive while Start is blocked on NetSetup, as init waits for Serve.
+
+		NetSetup is not called if HostNet is true. If NetSetup returns a non-nil error, Start
+		cancels the container and returns [StartError], and OnReady is not called.
+		Wait must still be called to release resources associated with the [Container]. */
+		NetSetup func(pid int) error
+
 		// param pipe for shim and init
 		setup *os.File
 		// cancels cmd
 		cancel context.CancelFunc
 		// closed after Wait returns
 		wait chan struct{}
+		// closed after Wait called by WaitContext returns
+		waitContext chan struct{}
+		// start time of container init, set by Start
+		started time.Time
+		// time Wait observed container init exiting
+		exited time.Time
+		// read end of the lingering process report pipe, set by Start
+		lingeringReport *os.File
+		// lingering processes reported by init, set by Wait
+		lingering []ProcInfo
+		// error returned by Wait, valid once waited is set
+		waitErr error
+		// whether Wait returned
+		waited bool
+		// guards exited, waitErr and waited, which are read concurrently with Wait called by WaitContext
+		waitMu sync.Mutex
+		// whether standard streams are connected to a pseudo-terminal allocated by StartPTY
+		pty bool
+		// setup steps reported by init, set by Start if ReportStatus is set
+		status chan string
+		// parent end of the dial socket, set by Start if ServeDial is set
+		dial *os.File
+		// serialises dial requests
+		dialMu sync.Mutex
+		// parent end of the seccomp listener socket, set by Start if the syscall filter has a listener
+		notify *os.File
+		// serialises access to notify
+		notifyMu sync.Mutex
+		// log files opened for StdoutFile and StderrFile, closed by Wait
+		logFiles []*logFile
+		// host pid of the process whose namespaces are joined, set by EnterContainer
+		enter int
+		// whether Cancel delivered cancellation to container init, guarded by waitMu
+		cancelDelivered bool
+		// deep copy of Params taken by Start before applying defaults, for Restart
+		initial *Params
 
 		Stdin  io.Reader
 		Stdout io.Writer
@@ -69,31 +156,129 @@ type (
 		Path *check.Absolute
 		// Initial process argv.
 		Args []string
+		// Check Path in the container filesystem before starting the initial process.
+		CheckPath bool
+
+		/* Pathnames of files standard output and standard error of the container are appended to,
+		if the corresponding Stdout or Stderr field of [Container] is nil. Both may refer to the same
+		file. Files are created with mode 0600 if they do not exist, and are written by the host.
+
+		Once a write would grow a file beyond LogMaxSize bytes, it is renamed with the suffix ".1",
+		and previously rotated files have their suffix incremented. Up to LogKeep rotated files are
+		kept, or one if LogKeep is not positive. A zero LogMaxSize disables rotation. */
+		StdoutFile, StderrFile *check.Absolute
+		LogMaxSize             int64
+		LogKeep                int
 		// Absolute path to the delegated cgroup directory, nil to disable cgroup enforcement.
 		CgroupPath *check.Absolute
-		// Deliver SIGINT to the initial process on context cancellation.
+		/* Forward context cancellation to the initial process.
+
+		Cancellation is delivered in two layers: cancelling the [Container] context causes
+		[Container.Cancel] to deliver [CancelSignal] to init, and init then delivers ForwardSignal
+		to the initial process if ForwardCancel is set. Otherwise, init exits on [CancelSignal]
+		and all processes in the container are killed by the pid namespace teardown. */
 		ForwardCancel bool
+		// Signal delivered to the initial process by init on context cancellation if
+		// ForwardCancel is set. The zero value is interpreted as SIGINT.
+		ForwardSignal Signal
 		// Time to wait for processes lingering after the initial process terminates.
 		AdoptWaitDelay time.Duration
+		/* Signal delivered once to all processes lingering after the initial process terminates.
+
+		Init becomes a child subreaper, and orphaned descendants are delivered this signal
+		at the start of AdoptWaitDelay. Processes remaining once AdoptWaitDelay elapses are
+		killed by the pid namespace teardown. ForwardCancel only targets the initial process,
+		so no process is signalled twice by init. The zero value disables this behaviour. */
+		ReapSignal Signal
+		// Report processes still present once AdoptWaitDelay elapses, made available via
+		// [Container.LingeringProcesses]. Processes are enumerated via procfs mounted on /proc
+		// in the container. This has no effect if AdoptWaitDelay resolves to zero.
+		ReportLingering bool
+		// Report setup steps reached by the container init via [Container.Status].
+		ReportStatus bool
+		// Establish connections within the container network namespace via [Container.Dial].
+		ServeDial bool
+
+		/* Existing user namespace to start init in, in place of creating one.
+
+		A file descriptor is referred to via its /proc/self/fd entry. This is mutually
+		exclusive with the uid and gid mappings: Uid and Gid must either be zero or match
+		the identity of the current process in that namespace, which must be mapped in it.
+
+		Since setns(2) into a user namespace is impossible for a multithreaded process, it is
+		joined by container init before the Go runtime starts, as described for [EnterContainer],
+		and the remaining namespaces are created in it. This requires CAP_SYS_ADMIN in the joined
+		namespace, which its owner has from the parent namespace. Since the network namespace
+		only exists once init starts, NetSetup is not supported, and neither is DropSetupCaps,
+		as init holds all capabilities in the joined namespace. If this is the user namespace
+		of the current process, for nesting within another sandbox, init is started in it
+		directly, and the current process must have CAP_SYS_ADMIN in it. */
+		UserNamespace *check.Absolute
 
 		// Mapped Uid in user namespace.
 		Uid int
 		// Mapped Gid in user namespace.
 		Gid int
+		// Uid mapped in place of a Uid lesser than 1. The zero value is interpreted as
+		// the value of the overflowuid sysctl of the host, see [OverflowUid].
+		OverflowUid int
+		// Gid mapped in place of a Gid lesser than 1. The zero value is interpreted as
+		// the value of the overflowgid sysctl of the host, see [OverflowGid].
+		OverflowGid int
 		// Hostname value in UTS namespace.
 		Hostname string
+		// Clock offsets of a new time namespace entered by the initial process.
+		// A nil value does not create a time namespace.
+		TimeOffset *TimeOffset
 		// Sequential container setup ops.
 		*Ops
+		/* Remount every mount point in the container read-only once all setup ops are applied.
+
+		Each pathname in WritablePaths is bind mounted onto itself beforehand, and it and mount points
+		beneath it retain their flags, so writable paths such as /tmp or the home directory remain
+		writable whether or not they were mount points. Pathnames not present in the container are
+		ignored. Failure to remount is reported as a [StartError] of kind [StartErrMount]. */
+		ReadOnlyRoot bool
+		// Container pathnames remaining writable under ReadOnlyRoot.
+		// Has no effect if ReadOnlyRoot is false.
+		WritablePaths []*check.Absolute
 
 		// Seccomp system call filter rules.
 		SeccompRules []std.NativeRule
+		/* Precompiled cBPF program loaded in place of a filter compiled from SeccompRules or
+		SeccompPresets. Mutually exclusive with SeccompRules.
+
+		SeccompFlags, SeccompExtraArch, SeccompDenySocket and SeccompKill only affect compilation of rules,
+		and have no effect on SeccompProgram. SeccompDisable still prevents it from being loaded. */
+		SeccompProgram []byte
 		// Extra seccomp flags.
 		SeccompFlags seccomp.ExportFlag
+		// Architectures added to the filter compiled from SeccompRules or SeccompPresets, for
+		// running binaries of those architectures under emulation. See [seccomp.Arch] for
+		// its interaction with [seccomp.AllowMultiarch]. Has no effect on SeccompProgram.
+		SeccompExtraArch []seccomp.Arch
 		// Seccomp presets. Has no effect unless SeccompRules is zero-length.
 		SeccompPresets std.FilterPreset
+		// Address families denied to socket(2) on top of SeccompRules or SeccompPresets.
+		// Start fails with ENOTSUP on architectures where these cannot be enforced.
+		SeccompDenySocket []std.ScmpDatum
+		// Action taken by denied system calls in place of returning EPERM, one of
+		// [seccomp.KillTrap], [seccomp.KillThread], [seccomp.KillProcess] or [seccomp.DenyENOSYS].
+		// Zero retains the default behaviour. [seccomp.KillProcess] falls back to
+		// [seccomp.KillThread] on kernels lacking support for it.
+		SeccompKill seccomp.ExportFlag
 		// Do not load seccomp program.
 		SeccompDisable bool
 
+		/* Nice value of container init and the initial program, clamped to the range of
+		[NiceMin] to [NiceMax]. The zero value retains the nice value inherited from the
+		calling process. A value lesser than the inherited one requires CAP_SYS_NICE in the
+		init user namespace, or a sufficient RLIMIT_NICE. */
+		Nice int
+		// I/O scheduling class of container init and the initial program.
+		// The zero value retains the class inherited from the calling process.
+		IOClass IOClass
+
 		// Permission bits of newly created parent directories.
 		// The zero value is interpreted as 0755.
 		ParentPerm os.FileMode
@@ -101,13 +286,67 @@ type (
 		RetainSession bool
 		// Do not [syscall.CLONE_NEWNET].
 		HostNet bool
+		// Bring up the loopback interface of the new network namespace via rtnetlink,
+		// so the initial program may bind to the loopback addresses without access to
+		// the host network. Ignored if HostNet is true.
+		LoopbackOnly bool
 		// Do not [LANDLOCK_SCOPE_ABSTRACT_UNIX_SOCKET].
 		HostAbstract bool
+		// Maximum attempts at creating and enforcing the landlock ruleset on transient errors.
+		// The zero value is interpreted as 3, a negative value disables retries.
+		LandlockRetry int
+		/* Filesystem access allowed to the initial program by landlock path beneath rules.
+
+		Every filesystem action supported by the kernel is handled, so actions not allowed
+		by a rule are denied. Since landlock denies mount operations to a restricted process,
+		these rules are enforced by init after all setup ops, and pathnames are resolved in
+		the container. Rules are not enforced on kernels lacking landlock support.
+		The zero value does not restrict filesystem access. */
+		LandlockFS []LandlockPathRule
 		// Retain CAP_SYS_ADMIN.
 		Privileged bool
+		/* Capabilities withheld from the ambient set of container init during setup.
+
+		Only CAP_DAC_OVERRIDE may be dropped, and only in the absence of a writable overlay,
+		[Container.Start] returns a [StartError] otherwise. This has no effect on capabilities
+		of the initial process, which are dropped regardless. */
+		DropSetupCaps []uintptr
 	}
 )
 
+// TimeOffset holds clock offsets of a time namespace, see time_namespaces(7).
+type TimeOffset struct {
+	// Offset applied to CLOCK_MONOTONIC and its variants.
+	Monotonic time.Duration
+	// Offset applied to CLOCK_BOOTTIME and its variants.
+	Boottime time.Duration
+}
+
+// Bytes returns the representation of [TimeOffset] accepted by /proc/self/timens_offsets.
+func (o *TimeOffset) Bytes() []byte {
+	buf := make([]byte, 0, 1<<6)
+	for _, c := range [...]struct {
+		name   string
+		offset time.Duration
+	}{
+		{"monotonic", o.Monotonic},
+		{"boottime", o.Boottime},
+	} {
+		// nanoseconds must be non-negative, seconds holds the sign of the offset
+		sec, nsec := int64(c.offset/time.Second), int64(c.offset%time.Second)
+		if nsec < 0 {
+			sec, nsec = sec-1, nsec+int64(time.Second)
+		}
+		buf = append(buf, c.name...)
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, sec, 10)
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, nsec, 10)
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
 // A StartError contains additional information on a container startup failure.
 type StartError struct {
 	// Fatal suggests whether this error should be considered fatal for the entire program.
@@ -120,6 +359,62 @@ type StartError struct {
 	Origin bool
 	// Passthrough is whether the Error method is passed through to Err.
 	Passthrough bool
+	// Kind is the class of failure this error describes.
+	Kind StartErrorKind
+}
+
+// StartErrorKind identifies the class of failure described by a [StartError].
+// Unlike Step, values of StartErrorKind are stable and suitable for programmatic handling.
+type StartErrorKind uint8
+
+const (
+	// StartErrOther is a failure not described by any other [StartErrorKind].
+	StartErrOther StartErrorKind = iota
+	// StartErrNamespace is a failure to set up or join a namespace.
+	StartErrNamespace
+	// StartErrLandlock is a failure to create or enforce a landlock ruleset.
+	StartErrLandlock
+	// StartErrSeccomp is a failure to set up prerequisites of the syscall filter.
+	StartErrSeccomp
+	// StartErrCgroup is a failure to access the delegated cgroup.
+	StartErrCgroup
+	// StartErrSetup is a failure to set up communication with container init.
+	StartErrSetup
+	// StartErrExec is a failure to start container init or the initial process.
+	StartErrExec
+	// StartErrMount is a failure to set up the container filesystem.
+	StartErrMount
+	// StartErrPidFile is a failure to write the pidfile.
+	StartErrPidFile
+	// StartErrNetwork is a failure to configure the container network namespace.
+	StartErrNetwork
+)
+
+func (k StartErrorKind) String() string {
+	switch k {
+	case StartErrOther:
+		return "other"
+	case StartErrNamespace:
+		return "namespace"
+	case StartErrLandlock:
+		return "landlock"
+	case StartErrSeccomp:
+		return "seccomp"
+	case StartErrCgroup:
+		return "cgroup"
+	case StartErrSetup:
+		return "setup"
+	case StartErrExec:
+		return "exec"
+	case StartErrMount:
+		return "mount"
+	case StartErrPidFile:
+		return "pidfile"
+	case StartErrNetwork:
+		return "network"
+	default:
+		return "invalid kind " + strconv.Itoa(int(k))
+	}
 }
 
 func (e *StartError) Unwrap() error { return e.Err }
@@ -196,41 +491,100 @@ func ensureCloseOnExec() error {
 	if closeOnExecErr == nil {
 		return nil
 	}
-	return &StartError{Fatal: true, Step: "set FD_CLOEXEC on all open files", Err: closeOnExecErr, Passthrough: true}
+	return &StartError{Fatal: true, Step: "set FD_CLOEXEC on all open files", Err: closeOnExecErr, Passthrough: true, Kind: StartErrSetup}
 }
 
 // Start starts the container init. The init process blocks until Serve is called.
 func (p *Container) Start() error {
 	if p == nil || p.cmd == nil ||
-		p.Ops == nil || len(*p.Ops) == 0 {
+		(p.enter == 0 && (p.Ops == nil || len(*p.Ops) == 0)) {
 		return errors.New("container: starting an invalid container")
 	}
 	if p.cmd.Process != nil {
 		return errors.New("container: already started")
 	}
+	initial := p.Params.clone()
+	p.initial = &initial
 
 	if err := ensureCloseOnExec(); err != nil {
 		return err
 	}
 
-	// map to overflow id to work around ownership checks
-	if p.Uid < 1 {
-		p.Uid = OverflowUid(p.msg)
+	var enterFile, usernsFile *os.File
+	if p.enter != 0 {
+		if f, err := openEnter(p.enter); err != nil {
+			return err
+		} else {
+			enterFile = f
+		}
+		defer func() {
+			if err := enterFile.Close(); err != nil {
+				p.msg.Verbosef("cannot close pidfd: %v", err)
+			}
+		}()
+
+		// the pid namespace and all processes in it belong to the running container
+		p.ReapSignal, p.ReportLingering = 0, false
+	} else if p.UserNamespace != nil {
+		if f, err := p.checkUserNamespace(); err != nil {
+			return err
+		} else {
+			usernsFile = f
+		}
+	}
+	if usernsFile != nil {
+		defer func() {
+			if err := usernsFile.Close(); err != nil {
+				p.msg.Verbosef("cannot close user namespace: %v", err)
+			}
+		}()
+
+		// the network namespace is only created by init once it starts
+		if p.NetSetup != nil && !p.HostNet {
+			return &StartError{false, "NetSetup is not supported in a joined user namespace", ENOTSUP, true, false, StartErrNamespace}
+		}
 	}
-	if p.Gid < 1 {
-		p.Gid = OverflowGid(p.msg)
+	p.Params.applyDefaults(p.msg, p.pty)
+
+	for _, rule := range p.LandlockFS {
+		if rule.Path == nil {
+			return &StartError{false, "invalid landlock filesystem rule", EBADE, true, false, StartErrLandlock}
+		}
 	}
 
-	if !p.RetainSession {
-		p.SeccompPresets |= std.PresetDenyTTY
+	if len(p.SeccompProgram) > 0 {
+		if len(p.SeccompRules) > 0 {
+			return &StartError{false, "SeccompProgram and SeccompRules are mutually exclusive", EINVAL, true, false, StartErrSeccomp}
+		}
+		if !seccomp.ValidProgram(p.SeccompProgram) {
+			return &StartError{false, "invalid seccomp program length", seccomp.ErrInvalidProgram, true, false, StartErrSeccomp}
+		}
+	} else if !p.SeccompDisable && len(p.SeccompDenySocket) > 0 && seccomp.DenySocketFamily(p.SeccompDenySocket...) == nil {
+		return &StartError{false, "socket families cannot be denied on this architecture", ENOTSUP, true, false, StartErrSeccomp}
 	}
 
-	if p.AdoptWaitDelay == 0 {
-		p.AdoptWaitDelay = 5 * time.Second
+	if p.IOClass < IOClassNone || p.IOClass > IOClassIdle {
+		return &StartError{false, "invalid I/O scheduling class " + strconv.Itoa(int(p.IOClass)), EINVAL, true, false, StartErrSetup}
 	}
-	// to allow disabling this behaviour
-	if p.AdoptWaitDelay < 0 {
-		p.AdoptWaitDelay = 0
+
+	var ambientCaps []uintptr
+	// capabilities in a joined user namespace are gained via setns(2) instead
+	if p.enter == 0 && usernsFile == nil {
+		if caps, err := setupCaps(p.Ops, p.DropSetupCaps, p.LoopbackOnly && !p.HostNet); err != nil {
+			return err
+		} else {
+			ambientCaps = caps
+		}
+	}
+
+	if p.TimeOffset != nil && p.enter == 0 {
+		// present since Linux 5.6, alongside CLONE_NEWTIME
+		if _, err := os.Stat(fhs.Proc + "self/ns/time"); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return &StartError{false, "kernel version too old for CLONE_NEWTIME", ENOSYS, true, false, StartErrNamespace}
+			}
+			return &StartError{false, "check time namespace support", err, false, false, StartErrNamespace}
+		}
 	}
 
 	if p.cmd.Stdin == nil {
@@ -245,16 +599,24 @@ func (p *Container) Start() error {
 
 	p.cmd.Args = []string{initName}
 	p.cmd.WaitDelay = p.WaitDelay
-	if p.Cancel != nil {
-		p.cmd.Cancel = func() error { return p.Cancel(p.cmd) }
-	} else {
-		p.cmd.Cancel = func() error { return p.cmd.Process.Signal(CancelSignal) }
+	p.cmd.Cancel = func() (err error) {
+		if p.Cancel != nil {
+			err = p.Cancel(p.cmd)
+		} else {
+			err = p.cmd.Process.Signal(CancelSignal)
+		}
+		if err == nil {
+			p.waitMu.Lock()
+			p.cancelDelivered = true
+			p.waitMu.Unlock()
+		}
+		return
 	}
 	p.cmd.Dir = fhs.Root
 	var cgroupFile *os.File
 	if p.CgroupPath != nil {
 		if f, err := os.OpenFile(p.CgroupPath.String(), os.O_RDONLY|O_CLOEXEC, 0); err != nil {
-			return &StartError{false, "open cgroup directory", err, false, false}
+			return &StartError{false, "open cgroup directory", err, false, false, StartErrCgroup}
 		} else {
 			cgroupFile = f
 		}
@@ -270,36 +632,104 @@ func (p *Container) Start() error {
 	p.cmd.SysProcAttr = &SysProcAttr{
 		Setsid:    !p.RetainSession,
 		Pdeathsig: SIGKILL,
-		Cloneflags: CLONE_NEWUSER | CLONE_NEWPID | CLONE_NEWNS |
+		Cloneflags: CLONE_NEWPID | CLONE_NEWNS |
 			CLONE_NEWIPC | CLONE_NEWUTS | CLONE_NEWCGROUP,
 
-		AmbientCaps: []uintptr{
-			// general container setup
-			CAP_SYS_ADMIN,
-			// drop capabilities
-			CAP_SETPCAP,
-			// overlay access to upperdir and workdir
-			CAP_DAC_OVERRIDE,
-		},
+		AmbientCaps: ambientCaps,
 
 	}
+	var usernsFlags uintptr
+	if p.pty && !p.RetainSession {
+		// the terminal end of the pseudo-terminal is always fd 0 of init
+		p.cmd.SysProcAttr.Setctty, p.cmd.SysProcAttr.Ctty = true, 0
+	}
 	if cgroupFile != nil {
 		p.cmd.SysProcAttr.UseCgroupFD = true
 		p.cmd.SysProcAttr.CgroupFD = int(cgroupFile.Fd())
 	}
-	if !p.HostNet {
-		p.cmd.SysProcAttr.Cloneflags |= CLONE_NEWNET
+	if p.enter != 0 {
+		// namespaces are joined by init in place of creating new ones
+		p.cmd.SysProcAttr.Cloneflags = 0
+	} else if usernsFile != nil {
+		// namespaces are created by init once it joins the user namespace
+		usernsFlags = p.cmd.SysProcAttr.Cloneflags
+		if !p.HostNet {
+			usernsFlags |= CLONE_NEWNET
+		}
+		p.cmd.SysProcAttr.Cloneflags = 0
+	} else {
+		if p.UserNamespace == nil {
+			p.cmd.SysProcAttr.Cloneflags |= CLONE_NEWUSER
+		}
+		if !p.HostNet {
+			p.cmd.SysProcAttr.Cloneflags |= CLONE_NEWNET
+		}
 	}
 
 	// place setup pipe before user supplied extra files, this is later restored by init
 	if fd, f, err := Setup(&p.cmd.ExtraFiles); err != nil {
-		return &StartError{true, "set up params stream", err, false, false}
+		return &StartError{true, "set up params stream", err, false, false, StartErrSetup}
 	} else {
 		p.setup = f
-		p.cmd.Env = []string{setupEnv + "=" + strconv.Itoa(fd)}
+		p.cmd.Env = append(slices.Clip(p.InitEnv), setupEnv+"="+strconv.Itoa(fd))
 	}
 	p.cmd.ExtraFiles = append(p.cmd.ExtraFiles, p.ExtraFiles...)
 
+	// placed after user supplied extra files, only held by init once it starts
+	var lingeringWriter *os.File
+	if p.ReportLingering && p.AdoptWaitDelay > 0 {
+		if r, w, err := os.Pipe(); err != nil {
+			return &StartError{true, "set up lingering process report pipe", err, false, false, StartErrSetup}
+		} else {
+			p.lingeringReport, lingeringWriter = r, w
+			p.cmd.ExtraFiles = append(p.cmd.ExtraFiles, w)
+		}
+	}
+	// placed after the lingering process report pipe, init closes it before starting the initial program
+	var statusReader, statusWriter *os.File
+	if p.ReportStatus {
+		if r, w, err := os.Pipe(); err != nil {
+			return &StartError{true, "set up status report pipe", err, false, false, StartErrSetup}
+		} else {
+			statusReader, statusWriter = r, w
+			p.cmd.ExtraFiles = append(p.cmd.ExtraFiles, w)
+		}
+	}
+	// placed after the status report pipe, held by init until it terminates
+	var dialChild *os.File
+	if p.ServeDial {
+		if parent, child, err := newDialSocket(); err != nil {
+			return &StartError{true, "set up dial socket", err, false, false, StartErrSetup}
+		} else {
+			p.dial, dialChild = parent, child
+			p.cmd.ExtraFiles = append(p.cmd.ExtraFiles, child)
+		}
+	}
+	// placed after the dial socket, closed by init once the syscall filter is loaded
+	var notifyChild *os.File
+	if p.seccompNotify() {
+		if parent, child, err := newDialSocket(); err != nil {
+			return &StartError{true, "set up seccomp listener socket", err, false, false, StartErrSetup}
+		} else {
+			p.notify, notifyChild = parent, child
+			p.cmd.ExtraFiles = append(p.cmd.ExtraFiles, child)
+		}
+	}
+	// placed last, closed by init before the Go runtime starts
+	if enterFile != nil {
+		p.cmd.Env = append(p.cmd.Env, enterEnv+"="+strconv.Itoa(3+len(p.cmd.ExtraFiles)))
+		p.cmd.ExtraFiles = append(p.cmd.ExtraFiles, enterFile)
+	}
+	if usernsFile != nil {
+		p.cmd.Env = append(p.cmd.Env, usernsEnv+"="+strconv.Itoa(3+len(p.cmd.ExtraFiles))+" "+strconv.Itoa(int(usernsFlags)))
+		p.cmd.ExtraFiles = append(p.cmd.ExtraFiles, usernsFile)
+	}
+
+	// opened last, as these are only closed by Wait once the process starts
+	if err := p.openLogFiles(); err != nil {
+		return err
+	}
+
 	done := make(chan error, 1)
 	go func() {
 		runtime.LockOSThread()
@@ -308,11 +738,14 @@ func (p *Container) Start() error {
 		done <- func() error { // setup depending on per-thread state must happen here
 			// PR_SET_NO_NEW_PRIVS: depends on per-thread state but acts on all processes created from that thread
 			if err := SetNoNewPrivs(); err != nil {
-				return &StartError{true, "prctl(PR_SET_NO_NEW_PRIVS)", err, false, false}
+				return &StartError{true, "prctl(PR_SET_NO_NEW_PRIVS)", err, false, false, StartErrSeccomp}
 			}
 
-			// landlock: depends on per-thread state but acts on a process group
-			{
+			// landlock: depends on per-thread state but acts on a process group;
+			// a landlock domain prevents joining namespaces of processes outside it,
+			// so this is enforced by init after joining them for EnterContainer
+			if p.enter == 0 {
+				var rulesetFd int
 				rulesetAttr := &RulesetAttr{Scoped: LANDLOCK_SCOPE_SIGNAL}
 				if !p.HostAbstract {
 					rulesetAttr.Scoped |= LANDLOCK_SCOPE_ABSTRACT_UNIX_SOCKET
@@ -324,24 +757,29 @@ func (p *Container) Start() error {
 						// already covered by namespaces (pid)
 						goto landlockOut
 					}
-					return &StartError{false, "get landlock ABI", err, false, false}
+					return &StartError{false, "get landlock ABI", err, false, false, StartErrLandlock}
 				} else if abi < 6 {
 					if p.HostAbstract {
 						// see above comment
 						goto landlockOut
 					}
-					return &StartError{false, "kernel version too old for LANDLOCK_SCOPE_ABSTRACT_UNIX_SOCKET", ENOSYS, true, false}
+					return &StartError{false, "kernel version too old for LANDLOCK_SCOPE_ABSTRACT_UNIX_SOCKET", ENOSYS, true, false, StartErrLandlock}
 				} else {
 					p.msg.Verbosef("landlock abi version %d", abi)
 				}
 
-				if rulesetFd, err := rulesetAttr.Create(0); err != nil {
-					return &StartError{true, "create landlock ruleset", err, false, false}
+				if err := landlockRetry(p.LandlockRetry, func() (err error) {
+					rulesetFd, err = rulesetAttr.Create(0)
+					return
+				}); err != nil {
+					return &StartError{true, "create landlock ruleset", err, false, false, StartErrLandlock}
 				} else {
 					p.msg.Verbosef("enforcing landlock ruleset %s", rulesetAttr)
-					if err = LandlockRestrictSelf(rulesetFd, 0); err != nil {
+					if err = landlockRetry(p.LandlockRetry, func() error {
+						return LandlockRestrictSelf(rulesetFd, 0)
+					}); err != nil {
 						_ = Close(rulesetFd)
-						return &StartError{true, "enforce landlock ruleset", err, false, false}
+						return &StartError{true, "enforce landlock ruleset", err, false, false, StartErrLandlock}
 					}
 					if err = Close(rulesetFd); err != nil {
 						p.msg.Verbosef("cannot close landlock ruleset: %v", err)
@@ -354,15 +792,89 @@ func (p *Container) Start() error {
 
 			p.msg.Verbose("starting container init")
 			if err := p.cmd.Start(); err != nil {
-				return &StartError{false, "start container init", err, false, true}
+				return &StartError{false, "start container init", err, false, true, StartErrExec}
 			}
+			p.started = processStartTime(p.msg, p.cmd.Process.Pid)
 			return nil
 		}()
 
 		// keep this thread alive until Wait returns for cancel
 		<-p.wait
 	}()
-	return <-done
+	err := <-done
+	if lingeringWriter != nil {
+		if closeErr := lingeringWriter.Close(); closeErr != nil {
+			p.msg.Verbosef("cannot close lingering process report pipe: %v", closeErr)
+		}
+		if err != nil {
+			_ = p.lingeringReport.Close()
+			p.lingeringReport = nil
+		}
+	}
+	if statusWriter != nil {
+		if closeErr := statusWriter.Close(); closeErr != nil {
+			p.msg.Verbosef("cannot close status report pipe: %v", closeErr)
+		}
+		if err != nil {
+			_ = statusReader.Close()
+		} else {
+			p.status = make(chan string, statusStepsMax)
+			go p.readStatus(statusReader, p.status)
+		}
+	}
+	if dialChild != nil {
+		if closeErr := dialChild.Close(); closeErr != nil {
+			p.msg.Verbosef("cannot close dial socket: %v", closeErr)
+		}
+		if err != nil {
+			p.closeDial()
+		}
+	}
+	if notifyChild != nil {
+		if closeErr := notifyChild.Close(); closeErr != nil {
+			p.msg.Verbosef("cannot close seccomp listener socket: %v", closeErr)
+		}
+		if err != nil {
+			p.closeNotify()
+		}
+	}
+	if err != nil {
+		p.closeLogFiles()
+		return err
+	}
+
+	if p.PidFile != nil {
+		if err := os.WriteFile(
+			p.PidFile.String(),
+			[]byte(strconv.Itoa(p.cmd.Process.Pid)+"\n"),
+			0644,
+		); err != nil {
+			p.cancel()
+			return &StartError{false, "write pidfile", err, false, false, StartErrPidFile}
+		}
+	}
+
+	if p.NetSetup != nil && !p.HostNet && p.enter == 0 {
+		if err := p.NetSetup(p.cmd.Process.Pid); err != nil {
+			p.cancel()
+			return &StartError{false, "set up container network", err, false, false, StartErrNetwork}
+		}
+	}
+
+	if p.OnReady != nil {
+		p.ready(p.cmd.Process.Pid)
+	}
+	return nil
+}
+
+// ready calls OnReady, recovering from a panic.
+func (p *Container) ready(pid int) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.msg.Verbosef("recovered from panic in OnReady: %v", r)
+		}
+	}()
+	p.OnReady(pid)
 }
 
 // Serve serves [Container.Params] to the container init.
@@ -375,12 +887,12 @@ func (p *Container) Serve() error {
 	setup := p.setup
 	p.setup = nil
 	if err := setup.SetDeadline(time.Now().Add(initSetupTimeout)); err != nil {
-		return &StartError{true, "set init pipe deadline", err, false, true}
+		return &StartError{true, "set init pipe deadline", err, false, true, StartErrSetup}
 	}
 
 	if p.Path == nil {
 		p.cancel()
-		return &StartError{false, "invalid executable pathname", EINVAL, true, false}
+		return &StartError{false, "invalid executable pathname", EINVAL, true, false, StartErrExec}
 	}
 
 	// do not transmit nil
@@ -412,13 +924,90 @@ func (p *Container) Wait() error {
 	}
 
 	err := p.cmd.Wait()
+	p.waitMu.Lock()
+	if p.cmd.ProcessState != nil {
+		p.exited = time.Now()
+	}
+	p.waitErr, p.waited = err, true
+	p.waitMu.Unlock()
 	p.cancel()
+	p.closeDial()
+	p.closeNotify()
+	// copying to log files completes before cmd.Wait returns
+	p.closeLogFiles()
+	if p.lingeringReport != nil {
+		// all write ends are closed once init terminates
+		if decodeErr := gob.NewDecoder(p.lingeringReport).Decode(&p.lingering); decodeErr != nil && !errors.Is(decodeErr, io.EOF) {
+			p.msg.Verbosef("cannot decode lingering process report: %v", decodeErr)
+		}
+		if closeErr := p.lingeringReport.Close(); closeErr != nil {
+			p.msg.Verbosef("cannot close lingering process report pipe: %v", closeErr)
+		}
+		p.lingeringReport = nil
+	}
+	if p.PidFile != nil {
+		if removeErr := os.Remove(p.PidFile.String()); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			p.msg.Verbosef("cannot remove pidfile: %v", removeErr)
+		}
+	}
 	if p.wait != nil && err == nil {
 		close(p.wait)
 	}
 	return err
 }
 
+/*
+WaitContext is like [Container.Wait], but returns once ctx is done even if container init has
+not yet exited. In that case, the container is cancelled via the same path as cancellation of
+the [Container] context and the error returned by [context.Context.Err] is returned.
+
+Container init is likely still running after WaitContext returns due to ctx being done. Its
+termination is awaited by calling WaitContext again, and Wait must not be called afterwards.
+*/
+func (p *Container) WaitContext(ctx context.Context) error {
+	if p.cmd == nil || p.cmd.Process == nil {
+		return EINVAL
+	}
+
+	if p.waitContext == nil {
+		p.waitContext = make(chan struct{})
+		go func() { _ = p.Wait(); close(p.waitContext) }()
+	}
+
+	select {
+	case <-p.waitContext:
+		p.waitMu.Lock()
+		defer p.waitMu.Unlock()
+		return p.waitErr
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+// LingeringProcesses returns processes reported by init as still present once AdoptWaitDelay
+// elapsed. This is populated by Wait if ReportLingering is set, and is nil otherwise.
+func (p *Container) LingeringProcesses() []ProcInfo { return p.lingering }
+
+/*
+Environ returns a copy of the environment of the initial program, useful for logging the
+environment a container runs with. This is the value of Env once [Container.Start] returned,
+which is sent to init as is and no longer changes for the lifetime of the [Container].
+
+Within hakurei, Env is collapsed from the container configuration by the privileged process,
+and already holds every variable injected by hakurei, such as HOME, SHELL and XDG_RUNTIME_DIR.
+*/
+func (p *Container) Environ() []string { return slices.Clone(p.Env) }
+
+/*
+ResolvedPresets returns the syscall filter presets in effect for the container. This is the
+value of SeccompPresets once [Container.Start] returned, and the value returned by
+[Params.EffectiveSeccompPresets] before that.
+
+This is useful for confirming whether [std.PresetDenyTTY] was applied.
+*/
+func (p *Container) ResolvedPresets() std.FilterPreset { return p.effectiveSeccompPresets(p.pty) }
+
 // StdinPipe calls the [exec.Cmd] method with the same name.
 func (p *Container) StdinPipe() (w io.WriteCloser, err error) {
 	if p.Stdin != nil {
@@ -449,6 +1038,230 @@ func (p *Container) StderrPipe() (r io.ReadCloser, err error) {
 	return
 }
 
+/*
+StartPTY allocates a pseudo-terminal, connects its terminal end to the standard streams of the
+container init and starts the container via Start. The master end of the pseudo-terminal is
+returned on success, and is owned by the caller. Standard streams must not already be set.
+
+Unless RetainSession is set, the container init is started in a new session with the
+pseudo-terminal as its controlling terminal, which the initial program inherits. Otherwise, the
+container init remains in the session of the caller and the pseudo-terminal is only connected to
+its standard streams. In either case, [std.PresetDenyTTY] is not enabled implicitly.
+
+Window size is not propagated automatically: the caller is expected to set the initial window size
+and forward changes of its own terminal to the master end via the TIOCSWINSZ ioctl, which causes
+the kernel to deliver SIGWINCH to the foreground process group of the pseudo-terminal.
+*/
+func (p *Container) StartPTY() (*os.File, error) {
+	if p == nil || p.cmd == nil {
+		return nil, errors.New("container: starting an invalid container")
+	}
+	if p.Stdin != nil || p.Stdout != nil || p.Stderr != nil {
+		return nil, errors.New("container: standard streams already set")
+	}
+
+	master, tty, err := openPTY()
+	if err != nil {
+		return nil, &StartError{true, "allocate pseudo-terminal", err, false, false, StartErrSetup}
+	}
+	p.Stdin, p.Stdout, p.Stderr = tty, tty, tty
+	p.pty = true
+
+	err = p.Start()
+	// held by the container init if it started successfully
+	if closeErr := tty.Close(); closeErr != nil {
+		p.msg.Verbosef("cannot close terminal: %v", closeErr)
+	}
+	if err != nil {
+		_ = master.Close()
+		p.Stdin, p.Stdout, p.Stderr = nil, nil, nil
+		p.pty = false
+		return nil, err
+	}
+	return master, nil
+}
+
+/*
+EffectiveSeccompPresets returns SeccompPresets as adjusted by [Container.Start], without
+starting the container. Presets added implicitly are obtained by clearing the requested bits.
+
+Unless RetainSession is set, [std.PresetDenyTTY] is always enabled, since terminal input is
+only expected to be handled by an initial process sharing the session of its caller.
+The returned presets have no effect if SeccompRules is non-empty or SeccompDisable is set.
+*/
+func (p *Params) EffectiveSeccompPresets() std.FilterPreset { return p.effectiveSeccompPresets(false) }
+
+// effectiveSeccompPresets is like EffectiveSeccompPresets, but does not enable
+// [std.PresetDenyTTY] for a container started via [Container.StartPTY] if pty is true.
+func (p *Params) effectiveSeccompPresets(pty bool) std.FilterPreset {
+	presets := p.SeccompPresets
+	if !p.RetainSession && !pty {
+		presets |= std.PresetDenyTTY
+	}
+	return presets
+}
+
+// clone returns a copy of [Params] sharing no slices with p. Ops is not copied.
+func (p *Params) clone() Params {
+	params := *p
+	params.Env = slices.Clone(p.Env)
+	params.Args = slices.Clone(p.Args)
+	if p.TimeOffset != nil {
+		offset := *p.TimeOffset
+		params.TimeOffset = &offset
+	}
+	params.WritablePaths = slices.Clone(p.WritablePaths)
+	params.SeccompRules = slices.Clone(p.SeccompRules)
+	for i, rule := range params.SeccompRules {
+		if rule.Arg != nil {
+			arg := *rule.Arg
+			params.SeccompRules[i].Arg = &arg
+		}
+	}
+	params.SeccompProgram = slices.Clone(p.SeccompProgram)
+	params.SeccompExtraArch = slices.Clone(p.SeccompExtraArch)
+	params.SeccompDenySocket = slices.Clone(p.SeccompDenySocket)
+	params.LandlockFS = slices.Clone(p.LandlockFS)
+	params.DropSetupCaps = slices.Clone(p.DropSetupCaps)
+	return params
+}
+
+// applyDefaults replaces zero values of [Params] with their defaults, as done by [Container.Start].
+// The value of pty is passed to effectiveSeccompPresets.
+func (p *Params) applyDefaults(msg message.Msg, pty bool) {
+	// map to overflow id to work around ownership checks
+	if p.UserNamespace == nil {
+		if p.Uid < 1 {
+			if p.OverflowUid > 0 {
+				p.Uid = p.OverflowUid
+			} else {
+				p.Uid = OverflowUid(msg)
+			}
+		}
+		if p.Gid < 1 {
+			if p.OverflowGid > 0 {
+				p.Gid = p.OverflowGid
+			} else {
+				p.Gid = OverflowGid(msg)
+			}
+		}
+	}
+
+	p.SeccompPresets = p.effectiveSeccompPresets(pty)
+
+	if p.AdoptWaitDelay == 0 {
+		p.AdoptWaitDelay = 5 * time.Second
+	}
+	// to allow disabling this behaviour
+	if p.AdoptWaitDelay < 0 {
+		p.AdoptWaitDelay = 0
+	}
+
+	if p.LandlockRetry == 0 {
+		p.LandlockRetry = 3
+	}
+	if p.LandlockRetry < 0 {
+		p.LandlockRetry = 1
+	}
+}
+
+/*
+Explain writes a human-readable description of [Params] to w, as resolved by [Container.Start]
+and [Container.Serve], without starting the container. The [Container] is not modified.
+
+Unlike the one-line summary returned by String, this includes every setup [Op] in the order
+they are applied, and seccomp presets decoded to their names.
+*/
+func (p *Container) Explain(w io.Writer) error {
+	params := p.Params
+	params.applyDefaults(p.msg, p.pty)
+	if params.Dir == nil {
+		params.Dir = fhs.AbsRoot
+	}
+
+	namespaces := "pid, mount, ipc, uts, cgroup"
+	if params.UserNamespace == nil {
+		namespaces = "user, " + namespaces
+	}
+	if !params.HostNet {
+		namespaces += ", net"
+		if params.LoopbackOnly {
+			namespaces += " (loopback)"
+		}
+	}
+	if params.TimeOffset != nil {
+		namespaces += ", time"
+	}
+	if p.enter != 0 {
+		namespaces = "user, pid, mount, net, ipc, uts, cgroup of process " + strconv.Itoa(p.enter)
+	}
+	session := "new"
+	if params.RetainSession {
+		session = "retained"
+	}
+	scope := "signal"
+	if !params.HostAbstract {
+		scope += ", abstract unix socket"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "path:       %s\n", params.Path)
+	fmt.Fprintf(&buf, "args:       %q\n", params.Args)
+	fmt.Fprintf(&buf, "dir:        %s\n", params.Dir)
+	fmt.Fprintf(&buf, "env:        %q\n", params.Env)
+	fmt.Fprintf(&buf, "uid:        %d\n", params.Uid)
+	fmt.Fprintf(&buf, "gid:        %d\n", params.Gid)
+	fmt.Fprintf(&buf, "hostname:   %q\n", params.Hostname)
+	fmt.Fprintf(&buf, "namespaces: %s\n", namespaces)
+	if params.UserNamespace != nil {
+		fmt.Fprintf(&buf, "userns:     %s\n", params.UserNamespace)
+	}
+	fmt.Fprintf(&buf, "session:    %s\n", session)
+	fmt.Fprintf(&buf, "landlock:   %s\n", scope)
+	for _, rule := range params.LandlockFS {
+		fmt.Fprintf(&buf, "  %s: %s\n", rule.Path, rule.Access)
+	}
+	if params.CgroupPath != nil {
+		fmt.Fprintf(&buf, "cgroup:     %s\n", params.CgroupPath)
+	}
+	fmt.Fprintf(&buf, "adopt wait: %s\n", params.AdoptWaitDelay)
+
+	switch {
+	case params.SeccompDisable:
+		buf.WriteString("seccomp:    disabled\n")
+	case len(params.SeccompProgram) > 0:
+		fmt.Fprintf(&buf, "seccomp:    %d byte program\n", len(params.SeccompProgram))
+	case len(params.SeccompRules) > 0:
+		fmt.Fprintf(&buf, "seccomp:    %d rules\n", len(params.SeccompRules))
+	default:
+		fmt.Fprintf(&buf, "seccomp:    presets %s\n", params.SeccompPresets)
+	}
+	if !params.SeccompDisable {
+		fmt.Fprintf(&buf, "            flags %#x, action %#x, %d denied socket families\n",
+			int(params.SeccompFlags), int(params.SeccompKill), len(params.SeccompDenySocket))
+		if len(params.SeccompExtraArch) > 0 {
+			fmt.Fprintf(&buf, "            %d extra architectures\n", len(params.SeccompExtraArch))
+		}
+	}
+
+	if params.Ops == nil || len(*params.Ops) == 0 {
+		buf.WriteString("ops:        none\n")
+	} else {
+		buf.WriteString("ops:\n")
+		for i, op := range *params.Ops {
+			if op == nil {
+				fmt.Fprintf(&buf, "  %d: <nil>\n", i)
+				continue
+			}
+			prefix, _ := op.prefix()
+			fmt.Fprintf(&buf, "  %d: %s %s\n", i, prefix, op)
+		}
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
 func (p *Container) String() string {
 	return fmt.Sprintf("argv: %q, filter: %v, rules: %d, flags: %#x, presets: %#x",
 		p.Args, !p.SeccompDisable, len(p.SeccompRules), int(p.SeccompFlags), int(p.SeccompPresets))
@@ -462,6 +1275,141 @@ func (p *Container) ProcessState() *os.ProcessState {
 	return p.cmd.ProcessState
 }
 
+/*
+ExitCode returns the exit code of container init and whether it was terminated by a signal.
+Container init already reports signals terminating the initial process as 128 plus the signal
+number. This is safe to call concurrently with Wait called by WaitContext.
+
+[ExitFailure] is returned if Wait has not returned, or failed without observing container init
+exit. [ExitCancel] is returned if Wait reported cancellation of the context of the [Container],
+which does not happen if the initial program handled a forwarded cancellation by exiting with
+a non-zero status. [ExitRequest] is returned if container init was terminated by a signal once
+cancellation was delivered, by [CancelSignal] or by SIGKILL as WaitDelay elapsed. Otherwise,
+container init killed by SIGKILL is reported as [ExitOrphan]: the [Container] does not deliver
+SIGKILL outside the cancel path, so it is attributed to Pdeathsig as the thread starting container
+init exits, although it is indistinguishable from SIGKILL delivered by another process.
+Other signals terminating container init are reported as 128 plus the signal number.
+
+These values are identical to their counterparts in package hst.
+*/
+func (p *Container) ExitCode() (code int, signaled bool) {
+	p.waitMu.Lock()
+	waited, waitErr, canceled := p.waited, p.waitErr, p.cancelDelivered
+	p.waitMu.Unlock()
+
+	// ProcessState is set by Wait before waited
+	if !waited || p.cmd == nil || p.cmd.ProcessState == nil {
+		return exitCode(0, false, canceled, waitErr)
+	}
+	ws, _ := p.cmd.ProcessState.Sys().(WaitStatus)
+	return exitCode(ws, true, canceled, waitErr)
+}
+
+// exitCode implements [Container.ExitCode].
+func exitCode(ws WaitStatus, exited, canceled bool, waitErr error) (code int, signaled bool) {
+	if errors.Is(waitErr, context.Canceled) || errors.Is(waitErr, context.DeadlineExceeded) {
+		return ExitCancel, false
+	}
+	if !exited {
+		return ExitFailure, false
+	}
+
+	switch {
+	case ws.Exited():
+		return ws.ExitStatus(), false
+
+	case ws.Signaled():
+		if canceled {
+			return ExitRequest, true
+		}
+		if ws.Signal() == SIGKILL {
+			return ExitOrphan, true
+		}
+		return 128 + int(ws.Signal()), true
+
+	default: // unreachable
+		return ExitFailure, false
+	}
+}
+
+// StartTime returns the time container init started, as reported by procfs.
+// An error is returned if the [Container] has not been started.
+func (p *Container) StartTime() (time.Time, error) {
+	if p.started.IsZero() {
+		return time.Time{}, EINVAL
+	}
+	return p.started, nil
+}
+
+// Uptime returns the duration since container init started. Zero is returned before
+// the [Container] is started, and the final duration after Wait observes it exiting.
+// This is safe to call concurrently with Wait.
+func (p *Container) Uptime() time.Duration {
+	if p.started.IsZero() {
+		return 0
+	}
+	p.waitMu.Lock()
+	exited := p.exited
+	p.waitMu.Unlock()
+	if !exited.IsZero() {
+		return exited.Sub(p.started)
+	}
+	return time.Since(p.started)
+}
+
+/*
+Signal delivers sig to container init, which is the leader of the container pid namespace
+and not the initial process. Container init forwards [SIGTERM], [SIGQUIT] and [SIGHUP] to
+the initial process, while [CancelSignal] is handled as described by ForwardCancel. Other
+signals cause container init to exit, terminating all processes in the container.
+
+Signal is safe to call before Wait returns. [EINVAL] is returned if the [Container] has not
+been started, and [os.ErrProcessDone] once Wait has observed container init exiting.
+*/
+func (p *Container) Signal(sig os.Signal) error {
+	if p.cmd == nil || p.cmd.Process == nil {
+		return EINVAL
+	}
+	return p.cmd.Process.Signal(sig)
+}
+
+// Pid returns the host pid of container init, or -1 if the [Container] has not been started.
+// Pid is safe to call concurrently with Wait, and keeps returning the same value after it returns.
+func (p *Container) Pid() int {
+	if p.cmd == nil {
+		return -1
+	}
+	if proc := p.cmd.Process; proc != nil {
+		return proc.Pid
+	}
+	return -1
+}
+
+// Freeze freezes all processes in the delegated cgroup by writing to cgroup.freeze.
+// [EINVAL] is returned if CgroupPath is nil.
+func (p *Container) Freeze() error { return p.writeCgroupFreeze("1", "freeze cgroup") }
+
+// Thaw undoes the effects of [Container.Freeze]. [EINVAL] is returned if CgroupPath is nil.
+func (p *Container) Thaw() error { return p.writeCgroupFreeze("0", "thaw cgroup") }
+
+// writeCgroupFreeze writes state to cgroup.freeze of the delegated cgroup.
+func (p *Container) writeCgroupFreeze(state, step string) error {
+	if p.CgroupPath == nil {
+		return EINVAL
+	}
+
+	// cgroup.freeze is always present for non-root cgroups and must not be created
+	if f, err := os.OpenFile(p.CgroupPath.Append("cgroup.freeze").String(), os.O_WRONLY|O_CLOEXEC, 0); err != nil {
+		return &StartError{false, step, err, false, false, StartErrCgroup}
+	} else if _, err = f.WriteString(state); err != nil {
+		_ = f.Close()
+		return &StartError{false, step, err, false, false, StartErrCgroup}
+	} else if err = f.Close(); err != nil {
+		return &StartError{false, step, err, false, false, StartErrCgroup}
+	}
+	return nil
+}
+
 // New returns the address to a new instance of [Container] that requires further initialisation before use.
 func New(ctx context.Context, msg message.Msg) *Container {
 	if msg == nil {
@@ -482,3 +1430,52 @@ func NewCommand(ctx context.Context, msg message.Msg, pathname *check.Absolute,
 	z.Args = append([]string{name}, args...)
 	return z
 }
+
+/*
+Restart returns the address to a new instance of [Container] bound to ctx, initialised
+from the [Params] of the current [Container] for restarting it under identical configuration.
+The new [Container] shares no pipes or process state with the current [Container], which
+does not have to be waited on first.
+
+If the current [Container] has been started, its [Params] are taken as they were before
+Start applied defaults to them. Ops is copied via its serialised representation, and all
+other slices of [Params] are cloned, so they may be modified without affecting the current
+[Container]. Other exported fields are carried over as is, with the exception of ExtraFiles, which refer to files that are
+typically consumed by the previous instance and must be populated again by the caller.
+Standard streams of a [Container] started via StartPTY are not carried over either.
+*/
+func (p *Container) Restart(ctx context.Context) (*Container, error) {
+	if p == nil {
+		return nil, EINVAL
+	}
+
+	params := &p.Params
+	if p.initial != nil {
+		params = p.initial
+	}
+
+	z := New(ctx, p.msg)
+	z.Params = params.clone()
+	if params.Ops != nil {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(params.Ops); err != nil {
+			return nil, &StartError{false, "copy container ops", err, false, false, StartErrSetup}
+		}
+		z.Ops = new(Ops)
+		if err := gob.NewDecoder(&buf).Decode(z.Ops); err != nil {
+			return nil, &StartError{false, "copy container ops", err, false, false, StartErrSetup}
+		}
+	}
+
+	z.InitEnv = slices.Clone(p.InitEnv)
+	z.PidFile = p.PidFile
+	z.OnReady = p.OnReady
+	z.NetSetup = p.NetSetup
+	if !p.pty {
+		z.Stdin, z.Stdout, z.Stderr = p.Stdin, p.Stdout, p.Stderr
+	}
+	z.Cancel = p.Cancel
+	z.WaitDelay = p.WaitDelay
+	z.enter = p.enter
+	return z, nil
+}
@@ -3,20 +3,29 @@ package container_test
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
 	"log"
 	"net"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
 	"testing"
 	"time"
+	"unsafe"
 
 	"hakurei.app/command"
 	"hakurei.app/container"
@@ -269,6 +278,15 @@ var containerTestCases = []struct {
 		),
 		9, 9, nil, 0, std.PresetStrict},
 
+	{"tmpfs size", true, false, false, true,
+		earlyOps(new(container.Ops).
+			Tmpfs(fhs.AbsDevShm, 1<<20, 01777),
+		),
+		earlyMnt(
+			ent("/", "/dev/shm", "rw,nosuid,nodev,relatime", "tmpfs", "ephemeral", "rw,size=1024k"),
+		),
+		9, 9, nil, 0, std.PresetStrict},
+
 	{"dev", true, true /* go test output is not a tty */, false, false,
 		earlyOps(new(container.Ops).
 			Dev(check.MustAbs("/dev"), true),
@@ -394,6 +412,76 @@ var containerTestCases = []struct {
 		1 << 3, 1 << 14, nil, 0, std.PresetStrict},
 }
 
+func TestStartErrorKind(t *testing.T) {
+	t.Parallel()
+
+	t.Run("string", func(t *testing.T) {
+		t.Parallel()
+		seen := make(map[string]container.StartErrorKind)
+		for k := container.StartErrOther; k <= container.StartErrNetwork; k++ {
+			s := k.String()
+			if strings.HasPrefix(s, "invalid") {
+				t.Errorf("String: %q", s)
+			}
+			if v, ok := seen[s]; ok {
+				t.Errorf("String: %d and %d are both %q", v, k, s)
+			}
+			seen[s] = k
+		}
+		if got, want := (container.StartErrNetwork + 1).String(), "invalid kind 10"; got != want {
+			t.Errorf("String: %q, want %q", got, want)
+		}
+	})
+
+	// every construction site must set a kind other than StartErrOther
+	t.Run("construction", func(t *testing.T) {
+		t.Parallel()
+		fset := token.NewFileSet()
+		pathnames, err := filepath.Glob("*.go")
+		if err != nil {
+			t.Fatalf("Glob: error = %v", err)
+		}
+		var n int
+		for _, pathname := range pathnames {
+			if strings.HasSuffix(pathname, "_test.go") {
+				continue
+			}
+			var f *ast.File
+			if f, err = parser.ParseFile(fset, pathname, nil, 0); err != nil {
+				t.Fatalf("ParseFile: error = %v", err)
+			}
+			ast.Inspect(f, func(node ast.Node) bool {
+				lit, ok := node.(*ast.CompositeLit)
+				if !ok {
+					return true
+				}
+				if ident, ok := lit.Type.(*ast.Ident); !ok || ident.Name != "StartError" {
+					return true
+				}
+				n++
+
+				var kind ast.Expr
+				for i, elt := range lit.Elts {
+					if kv, ok := elt.(*ast.KeyValueExpr); ok {
+						if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Kind" {
+							kind = kv.Value
+						}
+					} else if i == 5 {
+						kind = elt
+					}
+				}
+				if ident, ok := kind.(*ast.Ident); !ok || !strings.HasPrefix(ident.Name, "StartErr") || ident.Name == "StartErrOther" {
+					t.Errorf("%s: StartError does not set a specific kind", fset.Position(lit.Pos()))
+				}
+				return true
+			})
+		}
+		if n == 0 {
+			t.Fatal("no StartError construction sites found")
+		}
+	})
+}
+
 func TestContainer(t *testing.T) {
 	t.Parallel()
 
@@ -411,6 +499,20 @@ func TestContainer(t *testing.T) {
 		} else if code := ps.ExitCode(); code != wantExitCode {
 			t.Errorf("ExitCode: %d, want %d", code, wantExitCode)
 		}
+		if code, signaled := c.ExitCode(); code != container.ExitCancel || signaled {
+			t.Errorf("ExitCode: (%d, %v), want (%d, %v)", code, signaled, container.ExitCancel, false)
+		}
+
+		if start, err := c.StartTime(); err != nil {
+			t.Errorf("StartTime: error = %v", err)
+		} else if start.After(time.Now()) {
+			t.Errorf("StartTime: %v is in the future", start)
+		}
+		if uptime := c.Uptime(); uptime <= 0 {
+			t.Errorf("Uptime: %v", uptime)
+		} else if final := c.Uptime(); final != uptime {
+			t.Errorf("Uptime: %v, want final value %v", final, uptime)
+		}
 	}))
 
 	t.Run("forward", testContainerCancel(func(c *container.Container) {
@@ -428,6 +530,181 @@ func TestContainer(t *testing.T) {
 		}
 	}))
 
+	t.Run("signal", testContainerBlock(nil, func(t *testing.T, c *container.Container, _ context.CancelFunc) {
+		if err := c.Signal(syscall.SIGHUP); err != nil {
+			t.Errorf("Signal: error = %v", err)
+		}
+	}, func(t *testing.T, c *container.Container) {
+		var exitError *exec.ExitError
+		if err := c.Wait(); !errors.As(err, &exitError) {
+			if m, ok := container.InternalMessageFromError(err); ok {
+				t.Error(m)
+			}
+			t.Errorf("Wait: error = %v", err)
+		}
+		if code := exitError.ExitCode(); code != blockExitCodeHangup {
+			t.Errorf("ExitCode: %d, want %d", code, blockExitCodeHangup)
+		}
+		if err := c.Signal(syscall.SIGHUP); !errors.Is(err, os.ErrProcessDone) {
+			t.Errorf("Signal: error = %v, want %v", err, os.ErrProcessDone)
+		}
+	}))
+
+	t.Run("wait context", testContainerBlock(nil, func(*testing.T, *container.Container, context.CancelFunc) {}, func(t *testing.T, c *container.Container) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		if err := c.WaitContext(ctx); !reflect.DeepEqual(err, context.Canceled) {
+			t.Errorf("WaitContext: error = %v, want %v", err, context.Canceled)
+		}
+		if err := c.WaitContext(t.Context()); !reflect.DeepEqual(err, context.Canceled) {
+			if m, ok := container.InternalMessageFromError(err); ok {
+				t.Error(m)
+			}
+			t.Errorf("WaitContext: error = %#v, want %#v", err, context.Canceled)
+		}
+		if code, signaled := c.ExitCode(); code != container.ExitCancel || signaled {
+			t.Errorf("ExitCode: (%d, %v), want (%d, %v)", code, signaled, container.ExitCancel, false)
+		}
+	}))
+
+	var readyPid int
+	t.Run("ready", testContainerCancel(func(c *container.Container) {
+		// panic is recovered by Start
+		c.OnReady = func(pid int) { readyPid = pid; panic("unreachable") }
+	}, func(t *testing.T, c *container.Container) {
+		if err := c.Wait(); !reflect.DeepEqual(err, context.Canceled) {
+			t.Errorf("Wait: error = %v, want %v", err, context.Canceled)
+		}
+		if ps := c.ProcessState(); ps == nil {
+			t.Errorf("ProcessState unexpectedly returned nil")
+		} else if readyPid != ps.Pid() {
+			t.Errorf("OnReady: pid = %d, want %d", readyPid, ps.Pid())
+		} else if pid := c.Pid(); pid != readyPid {
+			t.Errorf("Pid: %d, want %d", pid, readyPid)
+		}
+	}))
+
+	pidFile := check.MustAbs(t.TempDir()).Append("init.pid")
+	t.Run("pidfile", testContainerCancel(func(c *container.Container) {
+		c.PidFile = pidFile
+	}, func(t *testing.T, c *container.Container) {
+		var pid int
+		if data, err := os.ReadFile(pidFile.String()); err != nil {
+			t.Errorf("ReadFile: error = %v", err)
+		} else if pid, err = strconv.Atoi(strings.TrimSuffix(string(data), "\n")); err != nil {
+			t.Errorf("Atoi: error = %v", err)
+		}
+
+		if err := c.Wait(); !reflect.DeepEqual(err, context.Canceled) {
+			t.Errorf("Wait: error = %v, want %v", err, context.Canceled)
+		}
+		if ps := c.ProcessState(); ps == nil {
+			t.Errorf("ProcessState unexpectedly returned nil")
+		} else if ps.Pid() != pid {
+			t.Errorf("pidfile: %d, want %d", pid, ps.Pid())
+		}
+		if _, err := os.Stat(pidFile.String()); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Stat: error = %v, want %v", err, os.ErrNotExist)
+		}
+	}))
+
+	t.Run("pidfile error", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+		defer cancel()
+
+		c := helperNewContainer(ctx, "block")
+		c.Stdout, c.Stderr = os.Stdout, os.Stderr
+		c.WaitDelay = helperDefaultTimeout
+		c.PidFile = check.MustAbs(container.Nonexistent).Append("init.pid")
+
+		var startError *container.StartError
+		if err := c.Start(); !errors.As(err, &startError) {
+			t.Fatalf("Start: error = %v", err)
+		} else if startError.Step != "write pidfile" || !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Start: error = %v", err)
+		}
+		_ = c.Wait()
+	})
+
+	var netSetupPid int
+	var netSetupNs string
+	t.Run("net setup", testContainerCancel(func(c *container.Container) {
+		c.NetSetup = func(pid int) (err error) {
+			netSetupPid = pid
+			netSetupNs, err = os.Readlink(fhs.Proc + strconv.Itoa(pid) + "/ns/net")
+			return
+		}
+	}, func(t *testing.T, c *container.Container) {
+		if err := c.Wait(); !reflect.DeepEqual(err, context.Canceled) {
+			t.Errorf("Wait: error = %v, want %v", err, context.Canceled)
+		}
+		if pid := c.Pid(); netSetupPid != pid {
+			t.Errorf("NetSetup: pid = %d, want %d", netSetupPid, pid)
+		}
+		if ns, err := os.Readlink(fhs.Proc + "self/ns/net"); err != nil {
+			t.Errorf("Readlink: error = %v", err)
+		} else if netSetupNs == "" || netSetupNs == ns {
+			t.Errorf("NetSetup: netns = %q, host %q", netSetupNs, ns)
+		}
+	}))
+
+	t.Run("net setup error", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+		defer cancel()
+
+		c := helperNewContainer(ctx, "block")
+		c.Stdout, c.Stderr = os.Stdout, os.Stderr
+		c.WaitDelay = helperDefaultTimeout
+		c.NetSetup = func(int) error { return syscall.ENETUNREACH }
+		c.OnReady = func(int) { t.Error("OnReady called after NetSetup failure") }
+
+		if err := c.Start(); !reflect.DeepEqual(err, &container.StartError{
+			Step: "set up container network",
+			Err:  syscall.ENETUNREACH,
+			Kind: container.StartErrNetwork,
+		}) {
+			t.Errorf("Start: error = %v", err)
+		}
+		_ = c.Wait()
+	})
+
+	t.Run("landlock fs", func(t *testing.T) {
+		t.Parallel()
+		if _, err := container.LandlockGetABI(); err != nil {
+			t.Skipf("landlock not available: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+		defer cancel()
+
+		c := helperNewContainer(ctx, "landlock")
+		c.Stdout, c.Stderr = os.Stdout, os.Stderr
+		c.Tmpfs(check.MustAbs("/tmp"), 0, 0755)
+		c.LandlockFS = []container.LandlockPathRule{{Path: check.MustAbs("/"),
+			Access: container.LANDLOCK_ACCESS_FS_EXECUTE |
+				container.LANDLOCK_ACCESS_FS_READ_FILE |
+				container.LANDLOCK_ACCESS_FS_READ_DIR}}
+
+		if err := c.Start(); err != nil {
+			if m, ok := container.InternalMessageFromError(err); ok {
+				t.Fatal(m)
+			} else {
+				t.Fatalf("cannot start container: %v", err)
+			}
+		} else if err = c.Serve(); err != nil {
+			if m, ok := container.InternalMessageFromError(err); ok {
+				t.Error(m)
+			} else {
+				t.Errorf("cannot serve setup params: %v", err)
+			}
+		}
+		if err := c.Wait(); err != nil {
+			t.Errorf("Wait: error = %v", err)
+		}
+	})
+
 	for i, tc := range containerTestCases {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
@@ -549,10 +826,22 @@ func hostnameFromTestCase(name string) string {
 func testContainerCancel(
 	containerExtra func(c *container.Container),
 	waitCheck func(t *testing.T, c *container.Container),
+) func(t *testing.T) {
+	return testContainerBlock(containerExtra, func(_ *testing.T, _ *container.Container, cancel context.CancelFunc) {
+		cancel()
+	}, waitCheck)
+}
+
+// testContainerBlock starts the block helper and calls ready once it is ready to receive signals.
+func testContainerBlock(
+	containerExtra func(c *container.Container),
+	ready func(t *testing.T, c *container.Container, cancel context.CancelFunc),
+	waitCheck func(t *testing.T, c *container.Container),
 ) func(t *testing.T) {
 	return func(t *testing.T) {
 		t.Parallel()
 		ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+		defer cancel()
 
 		c := helperNewContainer(ctx, "block")
 		c.Stdout, c.Stderr = os.Stdout, os.Stderr
@@ -561,13 +850,13 @@ func testContainerCancel(
 			containerExtra(c)
 		}
 
-		ready := make(chan struct{})
+		readyCh := make(chan struct{})
 		if r, w, err := os.Pipe(); err != nil {
 			t.Fatalf("cannot pipe: %v", err)
 		} else {
 			c.ExtraFiles = append(c.ExtraFiles, w)
 			go func() {
-				defer close(ready)
+				defer close(readyCh)
 				if _, err = r.Read(make([]byte, 1)); err != nil {
 					panic(err.Error())
 				}
@@ -587,8 +876,8 @@ func testContainerCancel(
 				t.Errorf("cannot serve setup params: %v", err)
 			}
 		}
-		<-ready
-		cancel()
+		<-readyCh
+		ready(t, c, cancel)
 		waitCheck(t, c)
 	}
 }
@@ -608,100 +897,1192 @@ func TestContainerString(t *testing.T) {
 	}
 }
 
-const (
-	blockExitCodeInterrupt = 2
-)
-
-func init() {
-	helperCommands = append(helperCommands, func(c command.Command) {
-		c.Command("block", command.UsageInternal, func(args []string) error {
-			sig := make(chan os.Signal, 1)
-			signal.Notify(sig, os.Interrupt)
-			go func() { <-sig; os.Exit(blockExitCodeInterrupt) }()
+func TestExitCode(t *testing.T) {
+	t.Parallel()
 
-			if _, err := os.NewFile(3, "sync").Write([]byte{0}); err != nil {
-				return fmt.Errorf("write to sync pipe: %v", err)
+	testCases := []struct {
+		name     string
+		ws       syscall.WaitStatus
+		exited   bool
+		canceled bool
+		waitErr  error
+
+		wantCode     int
+		wantSignaled bool
+	}{
+		{"not started", 0, false, false, nil, container.ExitFailure, false},
+		{"wait error", 0, false, false, syscall.ECHILD, container.ExitFailure, false},
+		{"success", 0, true, false, nil, 0, false},
+		{"exit", 1 << 8, true, false, &exec.ExitError{}, 1, false},
+		{"exit initial program signal", (128 + 15) << 8, true, false, &exec.ExitError{}, 128 + 15, false},
+		{"signal", syscall.WaitStatus(syscall.SIGTERM), true, false, &exec.ExitError{}, 128 + 15, true},
+		{"pdeathsig", syscall.WaitStatus(syscall.SIGKILL), true, false, &exec.ExitError{}, container.ExitOrphan, true},
+		{"pdeathsig core", syscall.WaitStatus(syscall.SIGKILL) | 0x80, true, false, &exec.ExitError{}, container.ExitOrphan, true},
+		{"cancel", 0, true, true, context.Canceled, container.ExitCancel, false},
+		{"cancel exit", 1 << 8, true, true, &exec.ExitError{}, 1, false},
+		{"cancel signal", syscall.WaitStatus(container.CancelSignal), true, true, &exec.ExitError{}, container.ExitRequest, true},
+		{"cancel wait delay", syscall.WaitStatus(syscall.SIGKILL), true, true, &exec.ExitError{}, container.ExitRequest, true},
+		{"cancel killed", syscall.WaitStatus(syscall.SIGKILL), true, true, context.Canceled, container.ExitCancel, false},
+		{"deadline", 0, true, true, context.DeadlineExceeded, container.ExitCancel, false},
+		{"cancel before exit", 0, false, false, context.Canceled, container.ExitCancel, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			code, signaled := container.ExitCodeFromStatus(tc.ws, tc.exited, tc.canceled, tc.waitErr)
+			if code != tc.wantCode || signaled != tc.wantSignaled {
+				t.Errorf("ExitCode: (%d, %v), want (%d, %v)", code, signaled, tc.wantCode, tc.wantSignaled)
 			}
-			select {}
 		})
+	}
 
-		c.Command("container", command.UsageInternal, func(args []string) error {
-			if len(args) != 1 {
-				return syscall.EINVAL
-			}
-			tc := containerTestCases[0]
-			if i, err := strconv.Atoi(args[0]); err != nil {
-				return fmt.Errorf("cannot parse test case index: %v", err)
-			} else {
-				tc = containerTestCases[i]
-			}
-
-			if uid := syscall.Getuid(); uid != tc.uid {
-				return fmt.Errorf("uid: %d, want %d", uid, tc.uid)
-			}
-			if gid := syscall.Getgid(); gid != tc.gid {
-				return fmt.Errorf("gid: %d, want %d", gid, tc.gid)
-			}
+	t.Run("container", func(t *testing.T) {
+		t.Parallel()
+		c := container.New(t.Context(), message.New(nil))
+		if code, signaled := c.ExitCode(); code != container.ExitFailure || signaled {
+			t.Errorf("ExitCode: (%d, %v), want (%d, %v)", code, signaled, container.ExitFailure, false)
+		}
+	})
 
-			wantHost := hostnameFromTestCase(tc.name)
-			if host, err := os.Hostname(); err != nil {
-				return fmt.Errorf("cannot get hostname: %v", err)
-			} else if host != wantHost {
-				return fmt.Errorf("hostname: %q, want %q", host, wantHost)
-			}
+	t.Run("hst", func(t *testing.T) {
+		t.Parallel()
+		if container.ExitFailure != hst.ExitFailure || container.ExitCancel != hst.ExitCancel ||
+			container.ExitOrphan != hst.ExitOrphan || container.ExitRequest != hst.ExitRequest {
+			t.Errorf("ExitCode: values (%d, %d, %d, %d) differ from hst (%d, %d, %d, %d)",
+				container.ExitFailure, container.ExitCancel, container.ExitOrphan, container.ExitRequest,
+				hst.ExitFailure, hst.ExitCancel, hst.ExitOrphan, hst.ExitRequest)
+		}
+	})
+}
 
-			if p, err := os.ReadFile("/etc/hostname"); err != nil {
-				return fmt.Errorf("cannot read /etc/hostname: %v", err)
-			} else if string(p) != wantHost {
-				return fmt.Errorf("/etc/hostname: %q, want %q", string(p), wantHost)
-			}
+func TestTimeOffsetBytes(t *testing.T) {
+	t.Parallel()
 
-			if _, err := os.Create(pathReadonly + "/nonexistent"); !errors.Is(err, syscall.EROFS) {
-				return err
+	testCases := []struct {
+		name   string
+		offset container.TimeOffset
+		want   string
+	}{
+		{"zero", container.TimeOffset{}, "monotonic 0 0\nboottime 0 0\n"},
+		{"positive", container.TimeOffset{Monotonic: 72 * time.Hour, Boottime: 1500 * time.Millisecond},
+			"monotonic 259200 0\nboottime 1 500000000\n"},
+		{"negative", container.TimeOffset{Monotonic: -90 * time.Minute, Boottime: -1500 * time.Millisecond},
+			"monotonic -5400 0\nboottime -2 500000000\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := string(tc.offset.Bytes()); got != tc.want {
+				t.Errorf("Bytes: %q, want %q", got, tc.want)
 			}
+		})
+	}
+}
 
-			{
-				var fail bool
+func TestContainerExplain(t *testing.T) {
+	t.Parallel()
+	msg := message.New(nil)
+	c := container.NewCommand(t.Context(), msg, check.MustAbs("/run/current-system/sw/bin/bash"), "bash", "-l")
+	c.Env = []string{"TERM=xterm"}
+	c.Uid, c.Gid = 1000, 100
+	c.Hostname = "hakurei-explain"
+	c.SeccompFlags |= seccomp.AllowMultiarch
+	c.SeccompPresets = std.PresetExt | std.PresetDenyNS
+	c.SeccompKill = seccomp.DenyENOSYS
+	c.
+		Bind(check.MustAbs("/nix/store"), check.MustAbs("/nix/store"), 0).
+		Proc(check.MustAbs("/proc/")).
+		Tmpfs(check.MustAbs("/tmp/"), 0, 0755)
+
+	want := `path:       /run/current-system/sw/bin/bash
+args:       ["bash" "-l"]
+dir:        /
+env:        ["TERM=xterm"]
+uid:        1000
+gid:        100
+hostname:   "hakurei-explain"
+namespaces: user, pid, mount, ipc, uts, cgroup, net
+session:    new
+landlock:   signal, abstract unix socket
+adopt wait: 5s
+seccomp:    presets ext, denyns, denytty
+            flags 0x1, action 0x40, 0 denied socket families
+ops:
+  0: mounting "/nix/store" flags 0x0
+  1: mounting proc on "/proc/"
+  2: mounting tmpfs on "/tmp/" size 0
+`
+	buf := new(strings.Builder)
+	if err := c.Explain(buf); err != nil {
+		t.Fatalf("Explain: error = %v", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("Explain:\n%s\nwant:\n%s", got, want)
+	}
+	if c.SeccompPresets != std.PresetExt|std.PresetDenyNS || c.Dir != nil || c.AdoptWaitDelay != 0 {
+		t.Errorf("Explain: clobbered Params")
+	}
+}
 
-				var mnt []*vfs.MountInfoEntry
-				if f, err := os.Open(pathWantMnt); err != nil {
-					return fmt.Errorf("cannot open expected mount points: %v", err)
-				} else if err = gob.NewDecoder(f).Decode(&mnt); err != nil {
-					return fmt.Errorf("cannot parse expected mount points: %v", err)
-				} else if err = f.Close(); err != nil {
-					return fmt.Errorf("cannot close expected mount points: %v", err)
-				}
+func TestContainerUptime(t *testing.T) {
+	t.Parallel()
+	c := container.New(t.Context(), message.New(nil))
+	if _, err := c.StartTime(); !reflect.DeepEqual(err, syscall.EINVAL) {
+		t.Errorf("StartTime: error = %v, want %v", err, syscall.EINVAL)
+	}
+	if uptime := c.Uptime(); uptime != 0 {
+		t.Errorf("Uptime: %v, want 0", uptime)
+	}
+	if pid := c.Pid(); pid != -1 {
+		t.Errorf("Pid: %d, want -1", pid)
+	}
+	if err := c.Signal(syscall.SIGHUP); !reflect.DeepEqual(err, syscall.EINVAL) {
+		t.Errorf("Signal: error = %v, want %v", err, syscall.EINVAL)
+	}
+	if err := c.WaitContext(t.Context()); !reflect.DeepEqual(err, syscall.EINVAL) {
+		t.Errorf("WaitContext: error = %v, want %v", err, syscall.EINVAL)
+	}
+}
 
-				if tc.ro && len(mnt) > 0 {
-					// Remount("/", syscall.MS_RDONLY)
-					mnt[0].VfsOptstr = "ro,nosuid,nodev"
-				}
+func TestContainerRestart(t *testing.T) {
+	t.Parallel()
 
-				var d *vfs.MountInfoDecoder
-				if f, err := os.Open("/proc/self/mountinfo"); err != nil {
-					return fmt.Errorf("cannot open mountinfo: %v", err)
-				} else {
-					d = vfs.NewMountInfoDecoder(f)
-				}
+	c := container.NewCommand(t.Context(), message.New(nil), check.MustAbs("/run/current-system/sw/bin/bash"), "bash", "-c", "exit 0")
+	c.Env = []string{"HOME=/proc/nonexistent"}
+	c.Ops = new(container.Ops).
+		Root(check.MustAbs("/"), std.BindWritable).
+		Proc(check.MustAbs("/proc/")).
+		Tmpfs(check.MustAbs("/tmp/"), 1<<12, 0755)
+	c.Hostname = "hakurei-restart"
+	c.ExtraFiles = []*os.File{os.Stdin}
+	c.InitEnv = []string{"GODEBUG=gctrace=1"}
+	c.WaitDelay = time.Second
+
+	r, err := c.Restart(t.Context())
+	if err != nil {
+		t.Fatalf("Restart: error = %v", err)
+	}
 
-				i := 0
-				for cur := range d.Entries() {
-					if i == len(mnt) {
-						return fmt.Errorf("got more than %d entries", len(mnt))
-					}
+	if !reflect.DeepEqual(r.Params, c.Params) {
+		t.Errorf("Restart: Params = %#v, want %#v", r.Params, c.Params)
+	}
+	if r.Ops == c.Ops || &(*r.Ops)[0] == &(*c.Ops)[0] || (*r.Ops)[0] == (*c.Ops)[0] {
+		t.Error("Restart: Ops not copied")
+	}
+	if &r.Env[0] == &c.Env[0] || &r.Args[0] == &c.Args[0] {
+		t.Error("Restart: Env or Args not cloned")
+	}
+	if r.ExtraFiles != nil {
+		t.Errorf("Restart: ExtraFiles = %#v", r.ExtraFiles)
+	}
+	if !reflect.DeepEqual(r.InitEnv, c.InitEnv) || r.WaitDelay != c.WaitDelay {
+		t.Errorf("Restart: InitEnv = %q, WaitDelay = %v", r.InitEnv, r.WaitDelay)
+	}
+	if pid := r.Pid(); pid != -1 {
+		t.Errorf("Pid: %d, want -1", pid)
+	}
 
-					// ugly hack but should be reliable and is less likely to false negative than comparing by parsed flags
-					cur.VfsOptstr = strings.TrimSuffix(cur.VfsOptstr, ",relatime")
-					cur.VfsOptstr = strings.TrimSuffix(cur.VfsOptstr, ",noatime")
-					mnt[i].VfsOptstr = strings.TrimSuffix(mnt[i].VfsOptstr, ",relatime")
-					mnt[i].VfsOptstr = strings.TrimSuffix(mnt[i].VfsOptstr, ",noatime")
+	if _, err = (*container.Container)(nil).Restart(t.Context()); !reflect.DeepEqual(err, syscall.EINVAL) {
+		t.Errorf("Restart: error = %v, want %v", err, syscall.EINVAL)
+	}
 
-					if !cur.EqualWithIgnore(mnt[i], "\x00") {
-						fail = true
-						log.Printf("[FAIL] %s", cur)
-					} else {
-						log.Printf("[ OK ] %s", cur)
-					}
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+
+		c := container.NewCommand(t.Context(), message.New(nil), check.MustAbs("/run/current-system/sw/bin/bash"), "bash", "-c", "exit 0")
+		c.Ops = new(container.Ops).Root(check.MustAbs("/"), std.BindWritable)
+		c.AdoptWaitDelay = -1
+		c.WritablePaths = []*check.Absolute{check.MustAbs("/tmp/")}
+		// rejected by Start after defaults are applied
+		c.LandlockFS = []container.LandlockPathRule{{}}
+		want := c.Params
+		want.WritablePaths = slices.Clone(c.WritablePaths)
+		want.LandlockFS = slices.Clone(c.LandlockFS)
+
+		wantErr := &container.StartError{Step: "invalid landlock filesystem rule", Err: syscall.EBADE, Origin: true, Kind: container.StartErrLandlock}
+		if err := c.Start(); !reflect.DeepEqual(err, wantErr) {
+			t.Fatalf("Start: error = %v, want %v", err, wantErr)
+		}
+		if c.AdoptWaitDelay != 0 {
+			t.Fatalf("Start: AdoptWaitDelay = %v", c.AdoptWaitDelay)
+		}
+
+		r, err := c.Restart(t.Context())
+		if err != nil {
+			t.Fatalf("Restart: error = %v", err)
+		}
+		if r.Ops == c.Ops {
+			t.Error("Restart: Ops not copied")
+		}
+		r.Ops = want.Ops
+		if !reflect.DeepEqual(r.Params, want) {
+			t.Errorf("Restart: Params = %#v, want %#v", r.Params, want)
+		}
+		if &r.WritablePaths[0] == &c.WritablePaths[0] || &r.LandlockFS[0] == &c.LandlockFS[0] {
+			t.Error("Restart: WritablePaths or LandlockFS not cloned")
+		}
+	})
+}
+
+func TestContainerStartPTY(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+	defer cancel()
+
+	c := helperNewContainer(ctx, "pty")
+	c.Stdout = os.Stdout
+	if _, err := c.StartPTY(); err == nil {
+		t.Fatal("StartPTY unexpectedly succeeded with standard streams set")
+	}
+	c.Stdout = nil
+
+	master, err := c.StartPTY()
+	if err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Fatal(m)
+		} else {
+			t.Fatalf("cannot start container: %v", err)
+		}
+	}
+	defer func() {
+		if err := master.Close(); err != nil {
+			t.Errorf("Close: error = %v", err)
+		}
+	}()
+	if presets := c.ResolvedPresets(); presets&std.PresetDenyTTY != 0 {
+		t.Errorf("ResolvedPresets: %s", presets)
+	}
+
+	output := make(chan []byte, 1)
+	go func() {
+		// fails with EIO once all terminal ends are closed
+		data, _ := io.ReadAll(master)
+		output <- data
+	}()
+
+	if err = c.Serve(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Error(m)
+		} else {
+			t.Errorf("cannot serve setup params: %v", err)
+		}
+	}
+	if err = c.Wait(); err != nil {
+		t.Errorf("Wait: error = %v", err)
+	}
+	if data := <-output; !bytes.Contains(data, []byte("pty ok")) {
+		t.Errorf("StartPTY: output = %q", string(data))
+	}
+}
+
+func TestContainerStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+	defer cancel()
+
+	c := helperNewContainer(ctx, "true")
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	if c.Status() != nil {
+		t.Fatal("Status: unexpected channel before Start")
+	}
+	c.ReportStatus = true
+
+	if err := c.Start(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Fatal(m)
+		} else {
+			t.Fatalf("cannot start container: %v", err)
+		}
+	} else if err = c.Serve(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Error(m)
+		} else {
+			t.Errorf("cannot serve setup params: %v", err)
+		}
+	}
+
+	var got []string
+	for step := range c.Status() {
+		got = append(got, step)
+	}
+	if err := c.Wait(); err != nil {
+		t.Errorf("Wait: error = %v", err)
+	}
+
+	want := []string{
+		container.StatusPrepareOps,
+		container.StatusApplyOps,
+		container.StatusSeccomp,
+		container.StatusStart,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Status: %q, want %q", got, want)
+	}
+}
+
+func TestContainerEnter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("inaccessible", func(t *testing.T) {
+		t.Parallel()
+
+		c := container.EnterContainer(t.Context(), message.New(nil), 1<<30)
+		c.Path, c.Args = absHelperInnerPath, []string{"helper", "true"}
+		var startError *container.StartError
+		if err := c.Start(); !errors.As(err, &startError) {
+			t.Fatalf("Start: error = %v", err)
+		} else if startError.Kind != container.StartErrNamespace || !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Start: error = %v", err)
+		}
+	})
+
+	t.Run("not init", func(t *testing.T) {
+		t.Parallel()
+
+		// the constructor must ignore the variable outside container init
+		cmd := exec.CommandContext(t.Context(), os.Args[0], "-test.run=^$")
+		cmd.Env = append(os.Environ(), "HAKUREI_ENTER=invalid")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("Run: error = %v, output:\n%s", err, out)
+		}
+	})
+
+	const hostname = "hakurei-enter"
+	t.Run("block", testContainerBlock(func(c *container.Container) {
+		c.Hostname = hostname
+		// init of the entering container reads sysctl via procfs
+		c.Proc(fhs.AbsProc)
+	}, func(t *testing.T, c *container.Container, cancel context.CancelFunc) {
+		defer cancel()
+
+		msg := message.New(nil)
+		msg.SwapVerbose(testing.Verbose())
+		z := container.EnterContainer(t.Context(), msg, c.Pid())
+		z.Path, z.Args = absHelperInnerPath, []string{"helper", "hostname", hostname}
+		z.Env = []string{envDoCheck + "=1"}
+		z.Stdout, z.Stderr = os.Stdout, os.Stderr
+
+		if err := z.Start(); err != nil {
+			if m, ok := container.InternalMessageFromError(err); ok {
+				t.Fatal(m)
+			} else {
+				t.Fatalf("cannot start container: %v", err)
+			}
+		} else if err = z.Serve(); err != nil {
+			if m, ok := container.InternalMessageFromError(err); ok {
+				t.Error(m)
+			} else {
+				t.Errorf("cannot serve setup params: %v", err)
+			}
+		}
+		if err := z.Wait(); err != nil {
+			t.Errorf("Wait: error = %v", err)
+		}
+	}, func(t *testing.T, c *container.Container) {
+		if err := c.Wait(); !reflect.DeepEqual(err, context.Canceled) {
+			t.Errorf("Wait: error = %v, want %v", err, context.Canceled)
+		}
+	}))
+}
+
+func TestContainerUserNamespace(t *testing.T) {
+	t.Parallel()
+
+	t.Run("block", testContainerBlock(nil, func(t *testing.T, c *container.Container, cancel context.CancelFunc) {
+		defer cancel()
+
+		userns := check.MustAbs("/proc/" + strconv.Itoa(c.Pid()) + "/ns/user")
+		for _, tc := range []struct {
+			name string
+			uid  int
+			ok   bool
+		}{
+			{"identity", 0, true},
+			{"mapped", c.Uid, true},
+			{"differs", c.Uid + 1, false},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				z := helperNewContainer(t.Context(), "true")
+				z.UserNamespace, z.Uid = userns, tc.uid
+				z.Stdout, z.Stderr = os.Stdout, os.Stderr
+
+				if err := z.Start(); err != nil {
+					if m, ok := container.InternalMessageFromError(err); ok {
+						t.Fatal(m)
+					} else {
+						t.Fatalf("cannot start container: %v", err)
+					}
+				} else if err = z.Serve(); err != nil {
+					if m, ok := container.InternalMessageFromError(err); ok {
+						t.Error(m)
+					} else {
+						t.Errorf("cannot serve setup params: %v", err)
+					}
+				}
+				if err := z.Wait(); (err == nil) != tc.ok {
+					t.Errorf("Wait: error = %v", err)
+				}
+			})
+		}
+	}, func(t *testing.T, c *container.Container) {
+		if err := c.Wait(); !reflect.DeepEqual(err, context.Canceled) {
+			t.Errorf("Wait: error = %v, want %v", err, context.Canceled)
+		}
+	}))
+}
+
+func TestContainerLoopback(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+	defer cancel()
+
+	c := helperNewContainer(ctx, "loopback")
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	c.LoopbackOnly = true
+	c.Proc(fhs.AbsProc)
+
+	if err := c.Start(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Fatal(m)
+		} else {
+			t.Fatalf("cannot start container: %v", err)
+		}
+	} else if err = c.Serve(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Error(m)
+		} else {
+			t.Errorf("cannot serve setup params: %v", err)
+		}
+	}
+	if err := c.Wait(); err != nil {
+		t.Errorf("Wait: error = %v", err)
+	}
+}
+
+func TestContainerDial(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+	defer cancel()
+
+	c := helperNewContainer(ctx, "echo")
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	c.LoopbackOnly = true
+	if _, err := c.Dial("tcp", 0); !errors.Is(err, container.ErrDialNotServed) {
+		t.Fatalf("Dial: error = %v, want %v", err, container.ErrDialNotServed)
+	}
+	c.ServeDial = true
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("cannot pipe: %v", err)
+	}
+	c.ExtraFiles = append(c.ExtraFiles, w)
+
+	if err = c.Start(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Fatal(m)
+		} else {
+			t.Fatalf("cannot start container: %v", err)
+		}
+	} else if err = c.Serve(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Error(m)
+		} else {
+			t.Errorf("cannot serve setup params: %v", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("Close: error = %v", err)
+	}
+
+	// the echo helper reports the port it listens on once ready
+	var port uint16
+	if err = binary.Read(r, binary.NativeEndian, &port); err != nil {
+		_ = c.Wait()
+		t.Fatalf("cannot read port: %v", err)
+	}
+
+	if _, err = c.Dial("unix", port); !reflect.DeepEqual(err, net.UnknownNetworkError("unix")) {
+		t.Errorf("Dial: error = %v", err)
+	}
+	if _, err = c.Dial("tcp", port+1); !errors.As(err, new(container.DialError)) {
+		t.Errorf("Dial: error = %v", err)
+	}
+
+	if f, err := c.Dial("tcp", port); err != nil {
+		t.Errorf("Dial: error = %v", err)
+	} else {
+		want := []byte("hello from the host\n")
+		got := make([]byte, len(want))
+		if _, err = f.Write(want); err != nil {
+			t.Errorf("Write: error = %v", err)
+		} else if _, err = io.ReadFull(f, got); err != nil {
+			t.Errorf("ReadFull: error = %v", err)
+		} else if !bytes.Equal(got, want) {
+			t.Errorf("Dial: echoed %q, want %q", got, want)
+		}
+		if err = f.Close(); err != nil {
+			t.Errorf("Close: error = %v", err)
+		}
+	}
+
+	if err = c.Wait(); err != nil {
+		t.Errorf("Wait: error = %v", err)
+	}
+	if _, err = c.Dial("tcp", port); !errors.Is(err, container.ErrDialNotServed) {
+		t.Errorf("Dial: error = %v, want %v", err, container.ErrDialNotServed)
+	}
+}
+
+func TestContainerSeccompProgram(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		rules   []std.NativeRule
+		program []byte
+		wantErr error
+	}{
+		{"exclusive", make([]std.NativeRule, 1), make([]byte, seccomp.InstructionSize), &container.StartError{
+			Step:   "SeccompProgram and SeccompRules are mutually exclusive",
+			Err:    syscall.EINVAL,
+			Origin: true,
+			Kind:   container.StartErrSeccomp,
+		}},
+		{"length", nil, make([]byte, seccomp.InstructionSize+1), &container.StartError{
+			Step:   "invalid seccomp program length",
+			Err:    seccomp.ErrInvalidProgram,
+			Origin: true,
+			Kind:   container.StartErrSeccomp,
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			c := container.NewCommand(t.Context(), message.New(nil), check.MustAbs("/bin/true"), "true")
+			c.Proc(fhs.AbsProc)
+			c.SeccompRules, c.SeccompProgram = tc.rules, tc.program
+			if err := c.Start(); !reflect.DeepEqual(err, tc.wantErr) {
+				t.Errorf("Start: error = %#v, want %#v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestContainerSeccompDenySocket(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+	defer cancel()
+
+	c := helperNewContainer(ctx, "socket")
+	c.Proc(fhs.AbsProc)
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	c.SeccompDenySocket = []std.ScmpDatum{syscall.AF_INET, syscall.AF_INET6}
+
+	if runtime.GOARCH == "386" {
+		wantErr := &container.StartError{
+			Step:   "socket families cannot be denied on this architecture",
+			Err:    syscall.ENOTSUP,
+			Origin: true,
+			Kind:   container.StartErrSeccomp,
+		}
+		if err := c.Start(); !reflect.DeepEqual(err, wantErr) {
+			t.Errorf("Start: error = %#v, want %#v", err, wantErr)
+		}
+		return
+	}
+
+	if err := c.Start(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Fatal(m)
+		} else {
+			t.Fatalf("cannot start container: %v", err)
+		}
+	} else if err = c.Serve(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Error(m)
+		} else {
+			t.Errorf("cannot serve setup params: %v", err)
+		}
+	}
+	if err := c.Wait(); err != nil {
+		t.Errorf("Wait: error = %v", err)
+	}
+}
+
+func TestContainerPriority(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid class", func(t *testing.T) {
+		t.Parallel()
+		c := container.NewCommand(t.Context(), message.New(nil), check.MustAbs("/bin/true"), "true")
+		c.Proc(fhs.AbsProc)
+		c.IOClass = container.IOClassIdle + 1
+		wantErr := &container.StartError{
+			Step:   "invalid I/O scheduling class 4",
+			Err:    syscall.EINVAL,
+			Origin: true,
+			Kind:   container.StartErrSetup,
+		}
+		if err := c.Start(); !reflect.DeepEqual(err, wantErr) {
+			t.Errorf("Start: error = %#v, want %#v", err, wantErr)
+		}
+	})
+
+	testCases := []struct {
+		name    string
+		nice    int
+		class   container.IOClass
+		want    string
+		wantCls string
+	}{
+		{"lower", 10, container.IOClassBestEffort, "10", "2"},
+		{"clamp", 1 << 10, container.IOClassIdle, "19", "3"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+			defer cancel()
+
+			c := helperNewContainer(ctx, "priority", tc.want, tc.wantCls)
+			c.Proc(fhs.AbsProc)
+			c.Stdout, c.Stderr = os.Stdout, os.Stderr
+			c.Nice, c.IOClass = tc.nice, tc.class
+
+			if err := c.Start(); err != nil {
+				if m, ok := container.InternalMessageFromError(err); ok {
+					t.Fatal(m)
+				} else {
+					t.Fatalf("cannot start container: %v", err)
+				}
+			} else if err = c.Serve(); err != nil {
+				if m, ok := container.InternalMessageFromError(err); ok {
+					t.Error(m)
+				} else {
+					t.Errorf("cannot serve setup params: %v", err)
+				}
+			}
+			if err := c.Wait(); err != nil {
+				t.Errorf("Wait: error = %v", err)
+			}
+		})
+	}
+}
+
+func TestContainerReadOnlyRoot(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+	defer cancel()
+
+	c := helperNewContainer(ctx, "readonly")
+	c.Proc(fhs.AbsProc).
+		Tmpfs(fhs.AbsTmp, 0, 01777).
+		Tmpfs(check.MustAbs("/usr/local"), 0, 0755).
+		Mkdir(check.MustAbs("/usr/local/state"), 0755).
+		Place(fhs.AbsEtc.Append("hostname"), []byte("hakurei-check\n"))
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	c.ReadOnlyRoot, c.WritablePaths = true, []*check.Absolute{
+		fhs.AbsTmp,
+		check.MustAbs("/usr/local/state"),
+		check.MustAbs("/nonexistent"),
+	}
+
+	if err := c.Start(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Fatal(m)
+		} else {
+			t.Fatalf("cannot start container: %v", err)
+		}
+	} else if err = c.Serve(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Error(m)
+		} else {
+			t.Errorf("cannot serve setup params: %v", err)
+		}
+	}
+	if err := c.Wait(); err != nil {
+		t.Errorf("Wait: error = %v", err)
+	}
+}
+
+func TestContainerSeccompListener(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+	defer cancel()
+
+	c := helperNewContainer(ctx, "getppid", strconv.Itoa(seccompListenerPpid))
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	c.SeccompRules = append(seccomp.Preset(0, 0), std.NativeRule{Syscall: std.SNR_GETPPID, Errno: std.ErrnoNotify})
+	if _, err := c.SeccompListener(); !errors.Is(err, container.ErrNotifyNotServed) {
+		t.Fatalf("SeccompListener: error = %v, want %v", err, container.ErrNotifyNotServed)
+	}
+
+	if err := c.Start(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Fatal(m)
+		} else {
+			t.Fatalf("cannot start container: %v", err)
+		}
+	} else if err = c.Serve(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Error(m)
+		} else {
+			t.Errorf("cannot serve setup params: %v", err)
+		}
+	}
+
+	listener, err := c.SeccompListener()
+	if err != nil {
+		_ = c.Wait()
+		t.Fatalf("SeccompListener: error = %v", err)
+	}
+	if _, err = c.SeccompListener(); !errors.Is(err, container.ErrNotifyNotServed) {
+		t.Errorf("SeccompListener: error = %v, want %v", err, container.ErrNotifyNotServed)
+	}
+
+	go func() {
+		for {
+			n, recvErr := seccomp.NotifyReceive(listener)
+			if recvErr != nil {
+				// listener closed by the test
+				return
+			}
+			resp := seccomp.NotifyResponse{ID: n.ID, Val: seccompListenerPpid}
+			if n.Data.Nr != int32(std.SNR_GETPPID) {
+				resp.Val, resp.Flags = 0, seccomp.NotifyFlagContinue
+			}
+			if respErr := seccomp.NotifyRespond(listener, &resp); respErr != nil {
+				t.Errorf("NotifyRespond: error = %v", respErr)
+			}
+		}
+	}()
+
+	if err = c.Wait(); err != nil {
+		t.Errorf("Wait: error = %v", err)
+	}
+	if err = listener.Close(); err != nil {
+		t.Errorf("Close: error = %v", err)
+	}
+}
+
+// seccompListenerPpid is the value returned for getppid by TestContainerSeccompListener.
+const seccompListenerPpid = 0xbabe
+
+func TestContainerEnviron(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+	defer cancel()
+
+	env := []string{"HOME=/home/chronos", "PATH=/run/current-system/sw/bin"}
+	c := helperNewContainer(ctx, append([]string{"environ"}, env...)...)
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	c.Env = append(c.Env, env...)
+	want := slices.Clone(c.Env)
+
+	if err := c.Start(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Fatal(m)
+		} else {
+			t.Fatalf("cannot start container: %v", err)
+		}
+	} else if err = c.Serve(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Error(m)
+		} else {
+			t.Errorf("cannot serve setup params: %v", err)
+		}
+	}
+
+	got := c.Environ()
+	if !slices.Equal(got, want) {
+		t.Errorf("Environ: %q, want %q", got, want)
+	}
+	got[0] = "HOME=/nonexistent"
+	if slices.Contains(c.Environ(), got[0]) {
+		t.Errorf("Environ: result shares the underlying array of Env")
+	}
+
+	if err := c.Wait(); err != nil {
+		t.Errorf("Wait: error = %v", err)
+	}
+}
+
+func TestContainerLogFiles(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(t.Context(), helperDefaultTimeout)
+	defer cancel()
+
+	d := check.MustAbs(t.TempDir())
+	c := helperNewContainer(ctx, "print", "stdout\n", "stderr\n")
+	c.StdoutFile, c.StderrFile = d.Append("stdout"), d.Append("stderr")
+	// output of the helper does not fit alongside existing contents
+	c.LogMaxSize = int64(len("previous\n"))
+	if err := os.WriteFile(c.StdoutFile.String(), []byte("previous\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Start(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Fatal(m)
+		} else {
+			t.Fatalf("cannot start container: %v", err)
+		}
+	} else if err = c.Serve(); err != nil {
+		if m, ok := container.InternalMessageFromError(err); ok {
+			t.Error(m)
+		} else {
+			t.Errorf("cannot serve setup params: %v", err)
+		}
+	}
+	if err := c.Wait(); err != nil {
+		t.Errorf("Wait: error = %v", err)
+	}
+
+	want := map[string]string{
+		"stdout":   "stdout\n",
+		"stdout.1": "previous\n",
+	}
+	if !testing.Verbose() {
+		// init also writes to standard error in verbose mode
+		want["stderr"] = "stderr\n"
+	}
+	for name, want := range want {
+		if data, err := os.ReadFile(d.Append(name).String()); err != nil {
+			t.Errorf("ReadFile: error = %v", err)
+		} else if string(data) != want {
+			t.Errorf("%s: %q, want %q", name, data, want)
+		}
+	}
+}
+
+func TestContainerFreeze(t *testing.T) {
+	t.Parallel()
+
+	c := container.New(t.Context(), message.New(nil))
+	if err := c.Freeze(); !reflect.DeepEqual(err, syscall.EINVAL) {
+		t.Errorf("Freeze: error = %v, want %v", err, syscall.EINVAL)
+	}
+	if err := c.Thaw(); !reflect.DeepEqual(err, syscall.EINVAL) {
+		t.Errorf("Thaw: error = %v, want %v", err, syscall.EINVAL)
+	}
+
+	c.CgroupPath = check.MustAbs(t.TempDir())
+	pathname := c.CgroupPath.Append("cgroup.freeze").String()
+	wantErr := &container.StartError{Step: "freeze cgroup", Err: &os.PathError{Op: "open", Path: pathname, Err: syscall.ENOENT}, Kind: container.StartErrCgroup}
+	if err := c.Freeze(); !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("Freeze: error = %#v, want %#v", err, wantErr)
+	}
+
+	if err := os.WriteFile(pathname, []byte("0"), 0600); err != nil {
+		t.Fatalf("cannot create cgroup.freeze: %v", err)
+	}
+	for _, tc := range []struct {
+		name string
+		f    func() error
+		want string
+	}{
+		{"Freeze", c.Freeze, "1"},
+		{"Thaw", c.Thaw, "0"},
+	} {
+		if err := tc.f(); err != nil {
+			t.Fatalf("%s: error = %v", tc.name, err)
+		}
+		if got, err := os.ReadFile(pathname); err != nil {
+			t.Fatalf("cannot read cgroup.freeze: %v", err)
+		} else if string(got) != tc.want {
+			t.Errorf("%s: cgroup.freeze = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+const (
+	blockExitCodeInterrupt = 2
+	blockExitCodeHangup    = 3
+)
+
+func init() {
+	helperCommands = append(helperCommands, func(c command.Command) {
+		c.Command("block", command.UsageInternal, func(args []string) error {
+			sig := make(chan os.Signal, 1)
+			signal.Notify(sig, os.Interrupt, syscall.SIGHUP)
+			go func() {
+				if <-sig == syscall.SIGHUP {
+					os.Exit(blockExitCodeHangup)
+				}
+				os.Exit(blockExitCodeInterrupt)
+			}()
+
+			if _, err := os.NewFile(3, "sync").Write([]byte{0}); err != nil {
+				return fmt.Errorf("write to sync pipe: %v", err)
+			}
+			select {}
+		})
+
+		c.Command("true", command.UsageInternal, func(args []string) error { return nil })
+
+		c.Command("hostname", command.UsageInternal, func(args []string) error {
+			if len(args) != 1 {
+				return syscall.EINVAL
+			}
+			if name, err := os.Hostname(); err != nil {
+				return err
+			} else if name != args[0] {
+				return fmt.Errorf("hostname = %q, want %q", name, args[0])
+			}
+			return nil
+		})
+
+		c.Command("priority", command.UsageInternal, func(args []string) error {
+			if len(args) != 2 {
+				return syscall.EINVAL
+			}
+			data, err := os.ReadFile("/proc/self/stat")
+			if err != nil {
+				return err
+			}
+			// comm may contain spaces, fields resume after its closing parenthesis
+			fields := strings.Fields(string(data[bytes.LastIndexByte(data, ')')+1:]))
+			if len(fields) < 17 {
+				return fmt.Errorf("unexpected stat %q", data)
+			}
+			// field 19, nice
+			if fields[16] != args[0] {
+				return fmt.Errorf("nice = %s, want %s", fields[16], args[0])
+			}
+
+			r, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_GET, 1, 0, 0)
+			if errno != 0 {
+				return os.NewSyscallError("ioprio_get", errno)
+			}
+			if class := strconv.Itoa(int(r >> 13)); class != args[1] {
+				return fmt.Errorf("I/O scheduling class = %s, want %s", class, args[1])
+			}
+			return nil
+		})
+
+		c.Command("socket", command.UsageInternal, func(args []string) error {
+			if fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0); !errors.Is(err, syscall.EAFNOSUPPORT) {
+				if err == nil {
+					_ = syscall.Close(fd)
+				}
+				return fmt.Errorf("socket AF_INET: error = %v, want %v", err, syscall.EAFNOSUPPORT)
+			}
+			if fd, err := syscall.Socket(syscall.AF_UNIX, syscall.SOCK_STREAM, 0); err != nil {
+				return fmt.Errorf("socket AF_UNIX: error = %v", err)
+			} else {
+				return syscall.Close(fd)
+			}
+		})
+
+		c.Command("readonly", command.UsageInternal, func(args []string) error {
+			if err := os.WriteFile("/usr/local/check", nil, 0644); !errors.Is(err, syscall.EROFS) {
+				return fmt.Errorf("write /usr/local: error = %v, want %v", err, syscall.EROFS)
+			}
+			if err := os.WriteFile("/tmp/check", nil, 0644); err != nil {
+				return err
+			}
+			if err := os.WriteFile("/usr/local/state/check", nil, 0644); err != nil {
+				return err
+			}
+			if data, err := os.ReadFile("/etc/hostname"); err != nil {
+				return err
+			} else if string(data) != "hakurei-check\n" {
+				return fmt.Errorf("hostname = %q", data)
+			}
+			return nil
+		})
+
+		c.Command("print", command.UsageInternal, func(args []string) error {
+			if len(args) != 2 {
+				return syscall.EINVAL
+			}
+			if _, err := os.Stdout.WriteString(args[0]); err != nil {
+				return err
+			}
+			_, err := os.Stderr.WriteString(args[1])
+			return err
+		})
+
+		c.Command("environ", command.UsageInternal, func(args []string) error {
+			environ := os.Environ()
+			for _, v := range args {
+				if !slices.Contains(environ, v) {
+					return fmt.Errorf("environment %q does not contain %q", environ, v)
+				}
+			}
+			return nil
+		})
+
+		c.Command("getppid", command.UsageInternal, func(args []string) error {
+			if len(args) != 1 {
+				return syscall.EINVAL
+			}
+			if ppid := strconv.Itoa(syscall.Getppid()); ppid != args[0] {
+				return fmt.Errorf("getppid = %s, want %s", ppid, args[0])
+			}
+			return nil
+		})
+
+		c.Command("loopback", command.UsageInternal, func(args []string) error {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				return err
+			}
+			var conn net.Conn
+			if conn, err = net.Dial("tcp", l.Addr().String()); err != nil {
+				return errors.Join(err, l.Close())
+			}
+			if err = errors.Join(conn.Close(), l.Close()); err != nil {
+				return err
+			}
+
+			// CAP_NET_ADMIN raised for setup must not reach the initial program
+			var status []byte
+			if status, err = os.ReadFile("/proc/self/status"); err != nil {
+				return err
+			}
+			for _, line := range strings.Split(string(status), "\n") {
+				if (strings.HasPrefix(line, "CapEff:") || strings.HasPrefix(line, "CapAmb:")) &&
+					strings.TrimSpace(line[7:]) != "0000000000000000" {
+					return fmt.Errorf("unexpected capabilities %q", line)
+				}
+			}
+			return nil
+		})
+
+		c.Command("echo", command.UsageInternal, func(args []string) error {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				return err
+			}
+			port := uint16(l.Addr().(*net.TCPAddr).Port)
+			if err = binary.Write(os.NewFile(3, "sync"), binary.NativeEndian, port); err != nil {
+				return fmt.Errorf("write to sync pipe: %v", err)
+			}
+
+			var conn net.Conn
+			if conn, err = l.Accept(); err != nil {
+				return err
+			}
+			if _, err = io.Copy(conn, conn); err != nil {
+				return err
+			}
+			return errors.Join(conn.Close(), l.Close())
+		})
+
+		c.Command("pty", command.UsageInternal, func(args []string) error {
+			for fd := range 3 {
+				if !container.Isatty(fd) {
+					return fmt.Errorf("fd %d is not a terminal", fd)
+				}
+			}
+			// only succeeds on the controlling terminal
+			var pgrp int32
+			if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, 0, syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgrp))); errno != 0 {
+				return fmt.Errorf("TIOCGPGRP: %v", errno)
+			}
+			_, err := os.Stdout.WriteString("pty ok\n")
+			return err
+		})
+
+		c.Command("landlock", command.UsageInternal, func(args []string) error {
+			if _, err := os.ReadFile(helperInnerPath); err != nil {
+				return err
+			}
+			if err := os.WriteFile("/tmp/landlock", nil, 0644); !errors.Is(err, syscall.EACCES) {
+				return fmt.Errorf("WriteFile: error = %v, want %v", err, syscall.EACCES)
+			}
+			return nil
+		})
+
+		c.Command("container", command.UsageInternal, func(args []string) error {
+			if len(args) != 1 {
+				return syscall.EINVAL
+			}
+			tc := containerTestCases[0]
+			if i, err := strconv.Atoi(args[0]); err != nil {
+				return fmt.Errorf("cannot parse test case index: %v", err)
+			} else {
+				tc = containerTestCases[i]
+			}
+
+			if uid := syscall.Getuid(); uid != tc.uid {
+				return fmt.Errorf("uid: %d, want %d", uid, tc.uid)
+			}
+			if gid := syscall.Getgid(); gid != tc.gid {
+				return fmt.Errorf("gid: %d, want %d", gid, tc.gid)
+			}
+
+			wantHost := hostnameFromTestCase(tc.name)
+			if host, err := os.Hostname(); err != nil {
+				return fmt.Errorf("cannot get hostname: %v", err)
+			} else if host != wantHost {
+				return fmt.Errorf("hostname: %q, want %q", host, wantHost)
+			}
+
+			if p, err := os.ReadFile("/etc/hostname"); err != nil {
+				return fmt.Errorf("cannot read /etc/hostname: %v", err)
+			} else if string(p) != wantHost {
+				return fmt.Errorf("/etc/hostname: %q, want %q", string(p), wantHost)
+			}
+
+			if _, err := os.Create(pathReadonly + "/nonexistent"); !errors.Is(err, syscall.EROFS) {
+				return err
+			}
+
+			{
+				var fail bool
+
+				var mnt []*vfs.MountInfoEntry
+				if f, err := os.Open(pathWantMnt); err != nil {
+					return fmt.Errorf("cannot open expected mount points: %v", err)
+				} else if err = gob.NewDecoder(f).Decode(&mnt); err != nil {
+					return fmt.Errorf("cannot parse expected mount points: %v", err)
+				} else if err = f.Close(); err != nil {
+					return fmt.Errorf("cannot close expected mount points: %v", err)
+				}
+
+				if tc.ro && len(mnt) > 0 {
+					// Remount("/", syscall.MS_RDONLY)
+					mnt[0].VfsOptstr = "ro,nosuid,nodev"
+				}
+
+				var d *vfs.MountInfoDecoder
+				if f, err := os.Open("/proc/self/mountinfo"); err != nil {
+					return fmt.Errorf("cannot open mountinfo: %v", err)
+				} else {
+					d = vfs.NewMountInfoDecoder(f)
+				}
+
+				i := 0
+				for cur := range d.Entries() {
+					if i == len(mnt) {
+						return fmt.Errorf("got more than %d entries", len(mnt))
+					}
+
+					// ugly hack but should be reliable and is less likely to false negative than comparing by parsed flags
+					cur.VfsOptstr = strings.TrimSuffix(cur.VfsOptstr, ",relatime")
+					cur.VfsOptstr = strings.TrimSuffix(cur.VfsOptstr, ",noatime")
+					mnt[i].VfsOptstr = strings.TrimSuffix(mnt[i].VfsOptstr, ",relatime")
+					mnt[i].VfsOptstr = strings.TrimSuffix(mnt[i].VfsOptstr, ",noatime")
+
+					if !cur.EqualWithIgnore(mnt[i], "\x00") {
+						fail = true
+						log.Printf("[FAIL] %s", cur)
+					} else {
+						log.Printf("[ OK ] %s", cur)
+					}
 
 					i++
 				}
@@ -725,6 +2106,8 @@ func init() {
 
 const (
 	envDoCheck = "HAKUREI_TEST_DO_CHECK"
+	// envInitOnly is only passed to container init and must never reach the helper.
+	envInitOnly = "HAKUREI_TEST_INIT_ONLY"
 
 	helperDefaultTimeout = 5 * time.Second
 	helperInnerPath      = "/usr/bin/helper"
@@ -745,6 +2128,9 @@ func TestMain(m *testing.M) {
 			log.SetPrefix("helper: ")
 			return nil
 		})
+		if _, ok := os.LookupEnv(envInitOnly); ok {
+			log.Fatal(envInitOnly + " leaked into the container environment")
+		}
 		for _, f := range helperCommands {
 			f(c)
 		}
@@ -766,6 +2152,7 @@ func helperNewContainerLibPaths(ctx context.Context, libPaths *[]*check.Absolute
 
 	c = container.NewCommand(ctx, msg, absHelperInnerPath, "helper", args...)
 	c.Env = append(c.Env, envDoCheck+"=1")
+	c.InitEnv = append(c.InitEnv, envInitOnly+"=1")
 	c.Bind(executable, absHelperInnerPath, 0)
 
 	// in case test has cgo enabled
@@ -784,3 +2171,60 @@ func helperNewContainerLibPaths(ctx context.Context, libPaths *[]*check.Absolute
 func helperNewContainer(ctx context.Context, args ...string) (c *container.Container) {
 	return helperNewContainerLibPaths(ctx, new([]*check.Absolute), args...)
 }
+
+func TestResolvedPresets(t *testing.T) {
+	t.Parallel()
+
+	for _, retainSession := range []bool{false, true} {
+		t.Run("retain session "+strconv.FormatBool(retainSession), func(t *testing.T) {
+			t.Parallel()
+
+			z := container.New(t.Context(), message.New(nil))
+			z.RetainSession = retainSession
+			z.SeccompPresets = std.PresetExt | std.PresetDenyDevel
+
+			got := z.ResolvedPresets()
+			if applied := got&std.PresetDenyTTY != 0; applied == retainSession {
+				t.Errorf("ResolvedPresets: %s", got)
+			}
+			if want := std.PresetExt | std.PresetDenyDevel; got&^std.PresetDenyTTY != want {
+				t.Errorf("ResolvedPresets: %s, want %s", got&^std.PresetDenyTTY, want)
+			}
+		})
+	}
+}
+
+func TestEffectiveSeccompPresets(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		params container.Params
+		want   std.FilterPreset
+	}{
+		{"zero", container.Params{}, std.PresetDenyTTY},
+		{"retain session", container.Params{RetainSession: true}, 0},
+		{"retain session strict", container.Params{
+			RetainSession:  true,
+			SeccompPresets: std.PresetStrict,
+		}, std.PresetStrict},
+		{"implicit deny tty", container.Params{
+			SeccompPresets: std.PresetExt | std.PresetDenyNS | std.PresetDenyDevel,
+		}, std.PresetStrict},
+		{"explicit deny tty", container.Params{
+			SeccompPresets: std.PresetDenyTTY | std.PresetLinux32,
+		}, std.PresetDenyTTY | std.PresetLinux32},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			requested := tc.params.SeccompPresets
+			if got := tc.params.EffectiveSeccompPresets(); got != tc.want {
+				t.Errorf("EffectiveSeccompPresets: %#x, want %#x", got, tc.want)
+			}
+			if tc.params.SeccompPresets != requested {
+				t.Errorf("EffectiveSeccompPresets: clobbered SeccompPresets %s", tc.params.SeccompPresets)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package container
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	. "syscall"
+	"time"
+
+	"hakurei.app/message"
+)
+
+// ErrDialNotServed is returned by [Container.Dial] if the container was not started with ServeDial set.
+var ErrDialNotServed = errors.New("container does not serve dial requests")
+
+// dialTimeout is the duration container init waits for a connection to be established.
+const dialTimeout = 5 * time.Second
+
+// dialRequestMax is the maximum size of a dial request or response message.
+const dialRequestMax = 1 << 8
+
+// DialError is returned by [Container.Dial] for a connection container init failed to establish.
+type DialError string
+
+func (e DialError) Error() string { return "cannot dial in container: " + string(e) }
+
+// newDialSocket returns both ends of a socket pair transmitting dial requests and connected descriptors.
+func newDialSocket() (parent, child *os.File, err error) {
+	var fds [2]int
+	if fds, err = Socketpair(AF_UNIX, SOCK_SEQPACKET|SOCK_CLOEXEC, 0); err != nil {
+		return nil, nil, os.NewSyscallError("socketpair", err)
+	}
+	return os.NewFile(uintptr(fds[0]), "dial"), os.NewFile(uintptr(fds[1]), "dial"), nil
+}
+
+/*
+Dial connects to port on the loopback interface of the container network namespace, returning
+the connected socket. The connection is established by container init on behalf of the caller,
+which is useful for reaching services in a container without joining its network namespace.
+Network is one of "tcp" or "udp".
+
+Dial returns [ErrDialNotServed] unless ServeDial is set and [Container.Start] returned successfully,
+and fails once Wait returns. Dial is safe for concurrent use.
+*/
+func (p *Container) Dial(network string, port uint16) (*os.File, error) {
+	switch network {
+	case "tcp", "udp":
+	default:
+		return nil, net.UnknownNetworkError(network)
+	}
+
+	p.dialMu.Lock()
+	defer p.dialMu.Unlock()
+	if p.dial == nil {
+		return nil, ErrDialNotServed
+	}
+
+	if _, err := p.dial.Write([]byte(network + " " + strconv.Itoa(int(port)))); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, dialRequestMax)
+	oob := make([]byte, CmsgSpace(4))
+	n, oobn, _, _, err := Recvmsg(int(p.dial.Fd()), buf, oob, MSG_CMSG_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("recvmsg", err)
+	}
+	if n == 0 {
+		return nil, ErrDialNotServed
+	}
+	if buf[0] != 0 {
+		return nil, DialError(buf[1:n])
+	}
+
+	var msgs []SocketControlMessage
+	if msgs, err = ParseSocketControlMessage(oob[:oobn]); err != nil {
+		return nil, os.NewSyscallError("recvmsg", err)
+	}
+	for _, m := range msgs {
+		if fds, _ := ParseUnixRights(&m); len(fds) == 1 {
+			return os.NewFile(uintptr(fds[0]), network+" "+strconv.Itoa(int(port))), nil
+		}
+	}
+	return nil, DialError("unexpected response")
+}
+
+// closeDial closes the parent end of the dial socket if present.
+func (p *Container) closeDial() {
+	p.dialMu.Lock()
+	defer p.dialMu.Unlock()
+	if p.dial == nil {
+		return
+	}
+	if err := p.dial.Close(); err != nil {
+		p.msg.Verbosef("cannot close dial socket: %v", err)
+	}
+	p.dial = nil
+}
+
+// serveDial serves dial requests received on f until the parent closes its end.
+// This is called by container init on a separate goroutine.
+func serveDial(msg message.Msg, f *os.File) {
+	buf := make([]byte, dialRequestMax)
+	for {
+		n, err := f.Read(buf)
+		if err != nil || n == 0 {
+			// parent closed its end or terminated
+			return
+		}
+
+		resp, rights := []byte{0}, []byte(nil)
+		c, dialErr := dialLoopback(string(buf[:n]))
+		if dialErr != nil {
+			resp = append([]byte{1}, dialErr.Error()...)
+			if len(resp) > dialRequestMax {
+				resp = resp[:dialRequestMax]
+			}
+		} else {
+			rights = UnixRights(int(c.Fd()))
+		}
+
+		if err = Sendmsg(int(f.Fd()), resp, rights, nil, 0); err != nil {
+			msg.Verbosef("cannot respond to dial request: %v", err)
+		}
+		if c != nil {
+			// duplicated into the message, no longer needed here
+			if err = c.Close(); err != nil {
+				msg.Verbosef("cannot close dialed connection: %v", err)
+			}
+		}
+	}
+}
+
+// dialLoopback connects to the loopback address according to a dial request.
+func dialLoopback(request string) (*os.File, error) {
+	network, port, ok := strings.Cut(request, " ")
+	if !ok {
+		return nil, EINVAL
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return nil, err
+	}
+
+	c, err := net.DialTimeout(network, net.JoinHostPort("127.0.0.1", port), dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if fc, ok := c.(interface{ File() (*os.File, error) }); !ok {
+		return nil, EINVAL
+	} else {
+		return fc.File()
+	}
+}
@@ -37,6 +37,12 @@ type syscallDispatcher interface {
 	setDumpable(dumpable uintptr) error
 	// setNoNewPrivs provides [SetNoNewPrivs].
 	setNoNewPrivs() error
+	// setChildSubreaper provides [SetChildSubreaper].
+	setChildSubreaper() error
+	// setNice provides [SetNice].
+	setNice(nice int) error
+	// setIOClass provides [SetIOClass].
+	setIOClass(class IOClass) error
 
 	// lastcap provides [LastCap].
 	lastcap(msg message.Msg) uintptr
@@ -57,19 +63,37 @@ type syscallDispatcher interface {
 	bindMount(msg message.Msg, source, target string, flags uintptr) error
 	// remount provides procPaths.remount.
 	remount(msg message.Msg, target string, flags uintptr) error
+	// remountExcept provides procPaths.remountExcept.
+	remountExcept(msg message.Msg, target string, flags uintptr, except []string) error
 	// mountTmpfs provides mountTmpfs.
 	mountTmpfs(fsname, target string, flags uintptr, size int, perm os.FileMode) error
 	// ensureFile provides ensureFile.
 	ensureFile(name string, perm, pperm os.FileMode) error
 
 	// seccompLoad provides [seccomp.Load].
-	seccompLoad(rules []std.NativeRule, flags seccomp.ExportFlag) error
+	seccompLoad(rules []std.NativeRule, flags seccomp.ExportFlag, extra []seccomp.Arch) error
+	// seccompLoadNotify provides loadNotify.
+	seccompLoadNotify(rules []std.NativeRule, flags seccomp.ExportFlag, extra []seccomp.Arch, socket *os.File) error
+	// seccompLoadProgram provides [seccomp.LoadProgram].
+	seccompLoadProgram(program []byte) error
+	// seccompKillProcessSupported provides [seccomp.KillProcessSupported].
+	seccompKillProcessSupported() bool
+	// landlockGetABI provides [LandlockGetABI].
+	landlockGetABI() (int, error)
+	// landlockCreateRuleset provides [RulesetAttr.Create].
+	landlockCreateRuleset(rulesetAttr *RulesetAttr) (fd int, err error)
+	// landlockAddPathBeneath provides [LandlockAddPathBeneath].
+	landlockAddPathBeneath(rulesetFd int, access LandlockAccessFS, parentFd int) error
+	// landlockRestrictSelf provides [LandlockRestrictSelf].
+	landlockRestrictSelf(rulesetFd int) error
 	// notify provides [signal.Notify].
 	notify(c chan<- os.Signal, sig ...os.Signal)
 	// start starts [os/exec.Cmd].
 	start(c *exec.Cmd) error
 	// signal signals the underlying process of [os/exec.Cmd].
 	signal(c *exec.Cmd, sig os.Signal) error
+	// kill provides syscall.Kill.
+	kill(pid int, sig syscall.Signal) error
 	// evalSymlinks provides [filepath.EvalSymlinks].
 	evalSymlinks(path string) (string, error)
 
@@ -77,6 +101,14 @@ type syscallDispatcher interface {
 	exit(code int)
 	// getpid provides [os.Getpid].
 	getpid() int
+	// entered returns whether namespaces were joined on behalf of [EnterContainer].
+	entered() bool
+	// usernsJoined returns whether the user namespace in [Params.UserNamespace] was joined.
+	usernsJoined() bool
+	// getuid provides [os.Getuid].
+	getuid() int
+	// getgid provides [os.Getgid].
+	getgid() int
 	// stat provides [os.Stat].
 	stat(name string) (os.FileInfo, error)
 	// mkdir provides [os.Mkdir].
@@ -85,8 +117,12 @@ type syscallDispatcher interface {
 	mkdirTemp(dir, pattern string) (string, error)
 	// mkdirAll provides [os.MkdirAll].
 	mkdirAll(path string, perm os.FileMode) error
+	// chmod provides [os.Chmod].
+	chmod(name string, mode os.FileMode) error
 	// readdir provides [os.ReadDir].
 	readdir(name string) ([]os.DirEntry, error)
+	// readFile provides [os.ReadFile].
+	readFile(name string) ([]byte, error)
 	// openNew provides [os.Open].
 	openNew(name string) (osFile, error)
 	// writeFile provides [os.WriteFile].
@@ -97,6 +133,8 @@ type syscallDispatcher interface {
 	remove(name string) error
 	// newFile provides os.NewFile.
 	newFile(fd uintptr, name string) *os.File
+	// closeOnExec provides syscall.CloseOnExec.
+	closeOnExec(fd int)
 	// symlink provides os.Symlink.
 	symlink(oldname, newname string) error
 	// readlink provides [os.Readlink].
@@ -106,6 +144,10 @@ type syscallDispatcher interface {
 	umask(mask int) (oldmask int)
 	// sethostname provides syscall.Sethostname
 	sethostname(p []byte) (err error)
+	// unshare provides syscall.Unshare
+	unshare(flags int) (err error)
+	// loopbackUp provides loopbackUp.
+	loopbackUp() error
 	// chdir provides syscall.Chdir
 	chdir(path string) (err error)
 	// fchdir provides syscall.Fchdir
@@ -141,6 +183,9 @@ func (direct) lockOSThread() { runtime.LockOSThread() }
 func (direct) setPtracer(pid uintptr) error       { return SetPtracer(pid) }
 func (direct) setDumpable(dumpable uintptr) error { return SetDumpable(dumpable) }
 func (direct) setNoNewPrivs() error               { return SetNoNewPrivs() }
+func (direct) setChildSubreaper() error           { return SetChildSubreaper() }
+func (direct) setNice(nice int) error             { return SetNice(nice) }
+func (direct) setIOClass(class IOClass) error     { return SetIOClass(class) }
 
 func (direct) lastcap(msg message.Msg) uintptr                 { return LastCap(msg) }
 func (direct) capset(hdrp *capHeader, datap *[2]capData) error { return capset(hdrp, datap) }
@@ -158,6 +203,9 @@ func (direct) bindMount(msg message.Msg, source, target string, flags uintptr) e
 func (direct) remount(msg message.Msg, target string, flags uintptr) error {
 	return hostProc.remount(msg, target, flags)
 }
+func (direct) remountExcept(msg message.Msg, target string, flags uintptr, except []string) error {
+	return hostProc.remountExcept(msg, target, flags, except)
+}
 func (k direct) mountTmpfs(fsname, target string, flags uintptr, size int, perm os.FileMode) error {
 	return mountTmpfs(k, fsname, target, flags, size, perm)
 }
@@ -165,21 +213,41 @@ func (direct) ensureFile(name string, perm, pperm os.FileMode) error {
 	return ensureFile(name, perm, pperm)
 }
 
-func (direct) seccompLoad(rules []std.NativeRule, flags seccomp.ExportFlag) error {
-	return seccomp.Load(rules, flags)
+func (direct) seccompLoad(rules []std.NativeRule, flags seccomp.ExportFlag, extra []seccomp.Arch) error {
+	return seccomp.Load(rules, flags, extra...)
+}
+func (direct) seccompLoadNotify(rules []std.NativeRule, flags seccomp.ExportFlag, extra []seccomp.Arch, socket *os.File) error {
+	return loadNotify(rules, flags, extra, socket)
+}
+func (direct) seccompLoadProgram(program []byte) error { return seccomp.LoadProgram(program) }
+func (direct) seccompKillProcessSupported() bool       { return seccomp.KillProcessSupported() }
+func (direct) landlockGetABI() (int, error)            { return LandlockGetABI() }
+func (direct) landlockCreateRuleset(rulesetAttr *RulesetAttr) (fd int, err error) {
+	return rulesetAttr.Create(0)
+}
+func (direct) landlockAddPathBeneath(rulesetFd int, access LandlockAccessFS, parentFd int) error {
+	return LandlockAddPathBeneath(rulesetFd, access, parentFd)
 }
+func (direct) landlockRestrictSelf(rulesetFd int) error    { return LandlockRestrictSelf(rulesetFd, 0) }
 func (direct) notify(c chan<- os.Signal, sig ...os.Signal) { signal.Notify(c, sig...) }
 func (direct) start(c *exec.Cmd) error                     { return c.Start() }
 func (direct) signal(c *exec.Cmd, sig os.Signal) error     { return c.Process.Signal(sig) }
+func (direct) kill(pid int, sig syscall.Signal) error      { return syscall.Kill(pid, sig) }
 func (direct) evalSymlinks(path string) (string, error)    { return filepath.EvalSymlinks(path) }
 
 func (direct) exit(code int)                                 { os.Exit(code) }
 func (direct) getpid() int                                   { return os.Getpid() }
+func (direct) entered() bool                                 { return enterJoined() }
+func (direct) usernsJoined() bool                            { return usernsJoined() }
+func (direct) getuid() int                                   { return os.Getuid() }
+func (direct) getgid() int                                   { return os.Getgid() }
 func (direct) stat(name string) (os.FileInfo, error)         { return os.Stat(name) }
 func (direct) mkdir(name string, perm os.FileMode) error     { return os.Mkdir(name, perm) }
 func (direct) mkdirTemp(dir, pattern string) (string, error) { return os.MkdirTemp(dir, pattern) }
 func (direct) mkdirAll(path string, perm os.FileMode) error  { return os.MkdirAll(path, perm) }
+func (direct) chmod(name string, mode os.FileMode) error     { return os.Chmod(name, mode) }
 func (direct) readdir(name string) ([]os.DirEntry, error)    { return os.ReadDir(name) }
+func (direct) readFile(name string) ([]byte, error)          { return os.ReadFile(name) }
 func (direct) openNew(name string) (osFile, error)           { return os.Open(name) }
 func (direct) writeFile(name string, data []byte, perm os.FileMode) error {
 	return os.WriteFile(name, data, perm)
@@ -193,6 +261,7 @@ func (direct) remove(name string) error {
 func (direct) newFile(fd uintptr, name string) *os.File {
 	return os.NewFile(fd, name)
 }
+func (direct) closeOnExec(fd int) { syscall.CloseOnExec(fd) }
 func (direct) symlink(oldname, newname string) error {
 	return os.Symlink(oldname, newname)
 }
@@ -202,6 +271,8 @@ func (direct) readlink(name string) (string, error) {
 
 func (direct) umask(mask int) (oldmask int)     { return syscall.Umask(mask) }
 func (direct) sethostname(p []byte) (err error) { return syscall.Sethostname(p) }
+func (direct) loopbackUp() error                { return loopbackUp() }
+func (direct) unshare(flags int) (err error)    { return syscall.Unshare(flags) }
 func (direct) chdir(path string) (err error)    { return syscall.Chdir(path) }
 func (direct) fchdir(fd int) (err error)        { return syscall.Fchdir(fd) }
 func (direct) open(path string, mode int, perm uint32) (fd int, err error) {
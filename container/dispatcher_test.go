@@ -297,6 +297,24 @@ func (isDirFi) ModTime() time.Time { panic("unreachable") }
 func (fi isDirFi) IsDir() bool     { return bool(fi) }
 func (isDirFi) Sys() any           { panic("unreachable") }
 
+type modeFi fs.FileMode
+
+func (modeFi) Name() string         { panic("unreachable") }
+func (modeFi) Size() int64          { panic("unreachable") }
+func (fi modeFi) Mode() fs.FileMode { return fs.FileMode(fi) }
+func (modeFi) ModTime() time.Time   { panic("unreachable") }
+func (modeFi) IsDir() bool          { panic("unreachable") }
+func (modeFi) Sys() any             { panic("unreachable") }
+
+type devFi uint64
+
+func (devFi) Name() string       { panic("unreachable") }
+func (devFi) Size() int64        { panic("unreachable") }
+func (devFi) Mode() fs.FileMode  { panic("unreachable") }
+func (devFi) ModTime() time.Time { panic("unreachable") }
+func (devFi) IsDir() bool        { panic("unreachable") }
+func (fi devFi) Sys() any        { return &syscall.Stat_t{Dev: uint64(fi)} }
+
 func stubDir(names ...string) []os.DirEntry {
 	d := make([]os.DirEntry, len(names))
 	for i, name := range names {
@@ -342,6 +360,20 @@ func (k *kstub) setDumpable(dumpable uintptr) error {
 }
 
 func (k *kstub) setNoNewPrivs() error { k.Helper(); return k.Expects("setNoNewPrivs").Err }
+func (k *kstub) setChildSubreaper() error {
+	k.Helper()
+	return k.Expects("setChildSubreaper").Err
+}
+func (k *kstub) setNice(nice int) error {
+	k.Helper()
+	return k.Expects("setNice").Error(
+		stub.CheckArg(k.Stub, "nice", nice, 0))
+}
+func (k *kstub) setIOClass(class IOClass) error {
+	k.Helper()
+	return k.Expects("setIOClass").Error(
+		stub.CheckArg(k.Stub, "class", class, 0))
+}
 func (k *kstub) lastcap(msg message.Msg) uintptr {
 	k.Helper()
 	k.checkMsg(msg)
@@ -439,6 +471,47 @@ func (k *kstub) remount(msg message.Msg, target string, flags uintptr) error {
 		stub.CheckArg(k.Stub, "flags", flags, 1))
 }
 
+func (k *kstub) remountExcept(msg message.Msg, target string, flags uintptr, except []string) error {
+	k.Helper()
+	k.checkMsg(msg)
+	return k.Expects("remountExcept").Error(
+		stub.CheckArg(k.Stub, "target", target, 0),
+		stub.CheckArg(k.Stub, "flags", flags, 1),
+		stub.CheckArgReflect(k.Stub, "except", except, 2))
+}
+
+func (k *kstub) seccompKillProcessSupported() bool {
+	k.Helper()
+	return k.Expects("seccompKillProcessSupported").Ret.(bool)
+}
+
+func (k *kstub) landlockGetABI() (int, error) {
+	k.Helper()
+	expect := k.Expects("landlockGetABI")
+	return expect.Ret.(int), expect.Err
+}
+
+func (k *kstub) landlockCreateRuleset(rulesetAttr *RulesetAttr) (fd int, err error) {
+	k.Helper()
+	expect := k.Expects("landlockCreateRuleset")
+	return expect.Ret.(int), expect.Error(
+		stub.CheckArgReflect(k.Stub, "rulesetAttr", rulesetAttr, 0))
+}
+
+func (k *kstub) landlockAddPathBeneath(rulesetFd int, access LandlockAccessFS, parentFd int) error {
+	k.Helper()
+	return k.Expects("landlockAddPathBeneath").Error(
+		stub.CheckArg(k.Stub, "rulesetFd", rulesetFd, 0),
+		stub.CheckArg(k.Stub, "access", access, 1),
+		stub.CheckArg(k.Stub, "parentFd", parentFd, 2))
+}
+
+func (k *kstub) landlockRestrictSelf(rulesetFd int) error {
+	k.Helper()
+	return k.Expects("landlockRestrictSelf").Error(
+		stub.CheckArg(k.Stub, "rulesetFd", rulesetFd, 0))
+}
+
 func (k *kstub) mountTmpfs(fsname, target string, flags uintptr, size int, perm os.FileMode) error {
 	k.Helper()
 	return k.Expects("mountTmpfs").Error(
@@ -457,11 +530,27 @@ func (k *kstub) ensureFile(name string, perm, pperm os.FileMode) error {
 		stub.CheckArg(k.Stub, "pperm", pperm, 2))
 }
 
-func (k *kstub) seccompLoad(rules []std.NativeRule, flags seccomp.ExportFlag) error {
+func (k *kstub) seccompLoad(rules []std.NativeRule, flags seccomp.ExportFlag, extra []seccomp.Arch) error {
 	k.Helper()
 	return k.Expects("seccompLoad").Error(
 		stub.CheckArgReflect(k.Stub, "rules", rules, 0),
-		stub.CheckArg(k.Stub, "flags", flags, 1))
+		stub.CheckArg(k.Stub, "flags", flags, 1),
+		stub.CheckArgReflect(k.Stub, "extra", extra, 2))
+}
+
+func (k *kstub) seccompLoadNotify(rules []std.NativeRule, flags seccomp.ExportFlag, extra []seccomp.Arch, socket *os.File) error {
+	k.Helper()
+	return k.Expects("seccompLoadNotify").Error(
+		stub.CheckArgReflect(k.Stub, "rules", rules, 0),
+		stub.CheckArg(k.Stub, "flags", flags, 1),
+		stub.CheckArgReflect(k.Stub, "extra", extra, 2),
+		stub.CheckArg(k.Stub, "socket", socket, 3))
+}
+
+func (k *kstub) seccompLoadProgram(program []byte) error {
+	k.Helper()
+	return k.Expects("seccompLoadProgram").Error(
+		stub.CheckArgReflect(k.Stub, "program", program, 0))
 }
 
 func (k *kstub) notify(c chan<- os.Signal, sig ...os.Signal) {
@@ -510,6 +599,13 @@ func (k *kstub) signal(c *exec.Cmd, sig os.Signal) error {
 		stub.CheckArg(k.Stub, "sig", sig, 4))
 }
 
+func (k *kstub) kill(pid int, sig syscall.Signal) error {
+	k.Helper()
+	return k.Expects("kill").Error(
+		stub.CheckArg(k.Stub, "pid", pid, 0),
+		stub.CheckArg(k.Stub, "sig", sig, 1))
+}
+
 func (k *kstub) evalSymlinks(path string) (string, error) {
 	k.Helper()
 	expect := k.Expects("evalSymlinks")
@@ -526,7 +622,14 @@ func (k *kstub) exit(code int) {
 	panic(stub.PanicExit)
 }
 
-func (k *kstub) getpid() int { k.Helper(); return k.Expects("getpid").Ret.(int) }
+func (k *kstub) getpid() int   { k.Helper(); return k.Expects("getpid").Ret.(int) }
+func (k *kstub) entered() bool { k.Helper(); return k.Expects("entered").Ret.(bool) }
+func (k *kstub) usernsJoined() bool {
+	k.Helper()
+	return k.Expects("usernsJoined").Ret.(bool)
+}
+func (k *kstub) getuid() int { k.Helper(); return k.Expects("getuid").Ret.(int) }
+func (k *kstub) getgid() int { k.Helper(); return k.Expects("getgid").Ret.(int) }
 
 func (k *kstub) stat(name string) (os.FileInfo, error) {
 	k.Helper()
@@ -557,6 +660,13 @@ func (k *kstub) mkdirAll(path string, perm os.FileMode) error {
 		stub.CheckArg(k.Stub, "perm", perm, 1))
 }
 
+func (k *kstub) chmod(name string, mode os.FileMode) error {
+	k.Helper()
+	return k.Expects("chmod").Error(
+		stub.CheckArg(k.Stub, "name", name, 0),
+		stub.CheckArg(k.Stub, "mode", mode, 1))
+}
+
 func (k *kstub) readdir(name string) ([]os.DirEntry, error) {
 	k.Helper()
 	expect := k.Expects("readdir")
@@ -564,6 +674,13 @@ func (k *kstub) readdir(name string) ([]os.DirEntry, error) {
 		stub.CheckArg(k.Stub, "name", name, 0))
 }
 
+func (k *kstub) readFile(name string) ([]byte, error) {
+	k.Helper()
+	expect := k.Expects("readFile")
+	return expect.Ret.([]byte), expect.Error(
+		stub.CheckArg(k.Stub, "name", name, 0))
+}
+
 func (k *kstub) openNew(name string) (osFile, error) {
 	k.Helper()
 	expect := k.Expects("openNew")
@@ -593,6 +710,14 @@ func (k *kstub) remove(name string) error {
 		stub.CheckArg(k.Stub, "name", name, 0))
 }
 
+func (k *kstub) closeOnExec(fd int) {
+	k.Helper()
+	if k.Expects("closeOnExec").Error(
+		stub.CheckArg(k.Stub, "fd", fd, 0)) != nil {
+		k.FailNow()
+	}
+}
+
 func (k *kstub) newFile(fd uintptr, name string) *os.File {
 	k.Helper()
 	expect := k.Expects("newFile")
@@ -633,6 +758,14 @@ func (k *kstub) sethostname(p []byte) (err error) {
 		stub.CheckArgReflect(k.Stub, "p", p, 0))
 }
 
+func (k *kstub) loopbackUp() error { k.Helper(); return k.Expects("loopbackUp").Err }
+
+func (k *kstub) unshare(flags int) (err error) {
+	k.Helper()
+	return k.Expects("unshare").Error(
+		stub.CheckArg(k.Stub, "flags", flags, 0))
+}
+
 func (k *kstub) chdir(path string) (err error) {
 	k.Helper()
 	return k.Expects("chdir").Error(
@@ -0,0 +1,151 @@
+#define _GNU_SOURCE
+#include "enter.h"
+#include <errno.h>
+#include <fcntl.h>
+#include <limits.h>
+#include <sched.h>
+#include <signal.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/prctl.h>
+#include <sys/wait.h>
+#include <unistd.h>
+
+int hakurei_enter_joined = 0;
+int hakurei_userns_joined = 0;
+static pid_t hakurei_enter_child = -1;
+
+static void hakurei_enter_fatal(const char *msg) {
+    fprintf(stderr, "init: %s: %s\n", msg, strerror(errno));
+    _exit(EXIT_FAILURE);
+}
+
+static void hakurei_enter_forward(int sig) {
+    int savedErrno = errno;
+    if (hakurei_enter_child > 0)
+        kill(hakurei_enter_child, sig);
+    errno = savedErrno;
+}
+
+/* returns whether the last element of argv0 is HAKUREI_ENTER_INIT, as checked by TryArgv0 */
+static int hakurei_enter_is_init(void) {
+    char buf[PATH_MAX + 1];
+    int fd = open("/proc/self/cmdline", O_RDONLY | O_CLOEXEC);
+    if (fd == -1)
+        return 0;
+    ssize_t n;
+    while ((n = read(fd, buf, sizeof(buf) - 1)) == -1 && errno == EINTR)
+        ;
+    close(fd);
+    if (n <= 0)
+        return 0;
+    buf[n] = '\0';
+
+    const char *base = strrchr(buf, '/');
+    base = base == NULL ? buf : base + 1;
+    return strcmp(base, HAKUREI_ENTER_INIT) == 0;
+}
+
+/* parses a non-negative int at the start of s, terminated by end */
+static int hakurei_enter_parse(const char *s, char end, const char **next, const char *msg) {
+    char *p;
+    errno = 0;
+    long v = strtol(s, &p, 10);
+    if (errno != 0 || p == s || *p != end || v < 0 || v > INT_MAX) {
+        errno = EBADF;
+        hakurei_enter_fatal(msg);
+    }
+    if (next != NULL)
+        *next = p + 1;
+    return (int)v;
+}
+
+/* forks a child to serve as container init, since a joined or created pid namespace only applies
+ * to children; returns in the child, the parent forwards signals to it and exits with its status */
+static void hakurei_enter_fork(void) {
+    hakurei_enter_child = fork();
+    if (hakurei_enter_child == -1)
+        hakurei_enter_fatal("cannot fork");
+    if (hakurei_enter_child == 0) {
+        if (prctl(PR_SET_PDEATHSIG, SIGKILL) != 0)
+            hakurei_enter_fatal("cannot set parent death signal");
+        return;
+    }
+
+    struct sigaction action = {0};
+    action.sa_handler = hakurei_enter_forward;
+    action.sa_flags = SA_RESTART;
+    if (sigemptyset(&action.sa_mask) != 0)
+        hakurei_enter_fatal("cannot initialise signal mask");
+    for (int sig = 1; sig < NSIG; sig++) {
+        if (sig == SIGKILL || sig == SIGSTOP || sig == SIGCHLD)
+            continue;
+        /* signals reserved by the C library are rejected and not forwarded */
+        sigaction(sig, &action, NULL);
+    }
+
+    int status;
+    while (waitpid(hakurei_enter_child, &status, 0) == -1)
+        if (errno != EINTR)
+            hakurei_enter_fatal("cannot wait for init");
+
+    if (WIFEXITED(status))
+        _exit(WEXITSTATUS(status));
+    if (WIFSIGNALED(status)) {
+        /* terminate with the same signal, falling back to the shell convention */
+        sigset_t set;
+        signal(WTERMSIG(status), SIG_DFL);
+        sigemptyset(&set);
+        sigaddset(&set, WTERMSIG(status));
+        sigprocmask(SIG_UNBLOCK, &set, NULL);
+        raise(WTERMSIG(status));
+        _exit(128 + WTERMSIG(status));
+    }
+    _exit(EXIT_FAILURE);
+}
+
+/* joins the user namespace referred to by the file descriptor in HAKUREI_USERNS_ENV and creates
+ * the remaining namespaces in it, HAKUREI_USERNS_ENV holds the descriptor and clone flags */
+static void hakurei_userns(const char *s) {
+    const char *next;
+    int fd = hakurei_enter_parse(s, ' ', &next, "invalid " HAKUREI_USERNS_ENV);
+    int flags = hakurei_enter_parse(next, '\0', NULL, "invalid " HAKUREI_USERNS_ENV);
+
+    if (setns(fd, CLONE_NEWUSER) != 0)
+        hakurei_enter_fatal("cannot join user namespace");
+    if (close(fd) != 0)
+        hakurei_enter_fatal("cannot close user namespace");
+    if (unshare(flags) != 0)
+        hakurei_enter_fatal("cannot create namespaces");
+
+    hakurei_enter_fork();
+    hakurei_userns_joined = 1;
+}
+
+/* joins namespaces of the process referred to by the pidfd in HAKUREI_ENTER_ENV,
+ * or the user namespace referred to by HAKUREI_USERNS_ENV,
+ * this must happen before the Go runtime starts any threads
+ *
+ * This constructor is linked into every program importing package container, so it only acts
+ * on the variables in container init, identified the same way as by TryArgv0. Other programs
+ * inheriting the variables, such as the initial program or a nested hakurei, ignore them. */
+__attribute__((constructor)) static void hakurei_enter(void) {
+    const char *s = getenv(HAKUREI_ENTER_ENV), *u = getenv(HAKUREI_USERNS_ENV);
+    if ((s == NULL && u == NULL) || !hakurei_enter_is_init())
+        return;
+    if (s == NULL) {
+        hakurei_userns(u);
+        return;
+    }
+
+    int fd = hakurei_enter_parse(s, '\0', NULL, "invalid " HAKUREI_ENTER_ENV);
+    if (setns(fd, CLONE_NEWUSER | CLONE_NEWNS | CLONE_NEWPID | CLONE_NEWNET |
+                      CLONE_NEWIPC | CLONE_NEWUTS | CLONE_NEWCGROUP) != 0)
+        hakurei_enter_fatal("cannot join namespaces");
+    if (close(fd) != 0)
+        hakurei_enter_fatal("cannot close pidfd");
+
+    hakurei_enter_fork();
+    hakurei_enter_joined = 1;
+}
@@ -0,0 +1,71 @@
+package container
+
+/*
+#include "enter.h"
+*/
+import "C"
+import (
+	"context"
+	"os"
+	"strconv"
+	. "syscall"
+
+	"hakurei.app/container/fhs"
+	"hakurei.app/message"
+)
+
+// enterEnv is the pidfd of the process whose namespaces container init joins,
+// and must match HAKUREI_ENTER_ENV in enter.h.
+const enterEnv = "HAKUREI_ENTER"
+
+// enterNamespaces are namespaces joined by container init started via [EnterContainer].
+var enterNamespaces = [...]string{"user", "mnt", "pid", "net", "ipc", "uts", "cgroup"}
+
+/*
+EnterContainer returns the address to a new instance of [Container] joining the namespaces of
+the process identified by pid, typically the init of a running container, in place of creating
+new ones. This is useful for attaching a shell or starting a sidecar process in a running container.
+
+Since setns(2) into a user or mount namespace is impossible for a multithreaded process, the
+namespaces are joined by container init before the Go runtime starts. This happens in a C
+constructor linked into every program importing this package, which only acts if the last
+element of argv0 is that of container init, so [TryArgv0] must be called by the program
+starting as container init, and the variable it receives is ignored by other programs. The user, mount, pid,
+network, IPC, UTS and cgroup namespaces are joined, the time namespace is not.
+The initial program runs with the identity mapped to the current user in the joined user namespace.
+
+The filesystem and namespaces of the running container are used as is, so Ops, UserNamespace, Uid,
+Gid, Hostname, TimeOffset, LoopbackOnly, ReapSignal, ReportLingering and NetSetup have no effect.
+Landlock and the syscall filter are applied as configured on top of those of the running container.
+Container init reads sysctl values via procfs, which must be mounted on /proc in the running container.
+If the namespaces of pid are inaccessible, [Container.Start] returns [StartError].
+*/
+func EnterContainer(ctx context.Context, msg message.Msg, pid int) *Container {
+	p := New(ctx, msg)
+	p.enter = pid
+	return p
+}
+
+// enterJoined returns whether the current process joined namespaces on behalf of [EnterContainer].
+func enterJoined() bool { return C.hakurei_enter_joined != 0 }
+
+// usernsJoined returns whether the current process joined the user namespace in [Params.UserNamespace].
+func usernsJoined() bool { return C.hakurei_userns_joined != 0 }
+
+// openEnter checks access to namespaces of the process identified by pid and returns its pidfd.
+func openEnter(pid int) (*os.File, error) {
+	if pid <= 0 {
+		return nil, &StartError{false, "invalid process to enter", EINVAL, true, false, StartErrNamespace}
+	}
+	for _, name := range enterNamespaces {
+		if _, err := os.Readlink(fhs.Proc + strconv.Itoa(pid) + "/ns/" + name); err != nil {
+			return nil, &StartError{false, "access " + name + " namespace of process " + strconv.Itoa(pid), err, false, false, StartErrNamespace}
+		}
+	}
+
+	fd, _, errno := Syscall(_SYS_PIDFD_OPEN, uintptr(pid), 0, 0)
+	if errno != 0 {
+		return nil, &StartError{false, "open process " + strconv.Itoa(pid), os.NewSyscallError("pidfd_open", errno), false, false, StartErrNamespace}
+	}
+	return os.NewFile(fd, "pidfd"), nil
+}
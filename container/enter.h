@@ -0,0 +1,9 @@
+/* see enter.go for documentation */
+#define HAKUREI_ENTER_ENV "HAKUREI_ENTER"
+/* see userns.go for documentation */
+#define HAKUREI_USERNS_ENV "HAKUREI_USERNS"
+/* last element of argv0 of container init, must match initName in init.go */
+#define HAKUREI_ENTER_INIT "init"
+
+extern int hakurei_enter_joined;
+extern int hakurei_userns_joined;
@@ -11,6 +11,11 @@ import (
 
 // messageFromError returns a printable error message for a supported concrete type.
 func messageFromError(err error) (string, bool) {
+	var startError *StartError
+	if errors.As(err, &startError) && startError != nil {
+		return startError.Message(), true
+	}
+
 	if m, ok := messagePrefixP[MountError]("cannot ", err); ok {
 		return m, ok
 	}
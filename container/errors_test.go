@@ -52,6 +52,9 @@ func TestMessageFromError(t *testing.T) {
 		{"tmpfs", TmpfsSizeError(-1),
 			"tmpfs size -1 out of bounds", true},
 
+		{"start", &StartError{Step: `mount overlay on "/nix/store"`, Err: syscall.EPERM},
+			`cannot mount overlay on "/nix/store": operation not permitted`, true},
+
 		{"unsupported", stub.UniqueError(0xdeadbeef), zeroString, false},
 	}
 	for _, tc := range testCases {
@@ -0,0 +1,7 @@
+package container
+
+// LandlockRetry exposes landlockRetry for testing.
+var LandlockRetry = landlockRetry
+
+// ExitCodeFromStatus exposes exitCode for testing.
+var ExitCodeFromStatus = exitCode
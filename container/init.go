@@ -1,6 +1,7 @@
 package container
 
 import (
+	"encoding/gob"
 	"errors"
 	"fmt"
 	"log"
@@ -12,8 +13,10 @@ import (
 	. "syscall"
 	"time"
 
+	"hakurei.app/container/check"
 	"hakurei.app/container/fhs"
 	"hakurei.app/container/seccomp"
+	"hakurei.app/container/std"
 	"hakurei.app/message"
 )
 
@@ -105,8 +108,13 @@ func initEntrypoint(k syscallDispatcher, msg message.Msg) {
 		panic("attempting to call initEntrypoint with nil msg")
 	}
 
+	// namespaces joined on behalf of EnterContainer are already set up by the running container
+	var entered bool
 	if k.getpid() != 1 {
-		k.fatal(msg, "this process must run as pid 1")
+		if !k.entered() {
+			k.fatal(msg, "this process must run as pid 1")
+		}
+		entered = true
 	}
 
 	if err := k.setPtracer(0); err != nil {
@@ -130,7 +138,7 @@ func initEntrypoint(k syscallDispatcher, msg message.Msg) {
 
 		k.fatalf(msg, "cannot decode init setup payload: %v", err)
 	} else {
-		if params.Ops == nil {
+		if params.Ops == nil && !entered {
 			k.fatal(msg, "invalid setup parameters")
 		}
 		if params.ParentPerm == 0 {
@@ -143,49 +151,133 @@ func initEntrypoint(k syscallDispatcher, msg message.Msg) {
 		offsetSetup = int(setupFd + 1)
 	}
 
-	// write uid/gid map here so parent does not need to set dumpable
-	if err := k.setDumpable(SUID_DUMP_USER); err != nil {
-		k.fatalf(msg, "cannot set SUID_DUMP_USER: %v", err)
-	}
-	if err := k.writeFile(fhs.Proc+"self/uid_map",
-		append([]byte{}, strconv.Itoa(params.Uid)+" "+strconv.Itoa(params.HostUid)+" 1\n"...),
-		0); err != nil {
-		k.fatalf(msg, "%v", err)
-	}
-	if err := k.writeFile(fhs.Proc+"self/setgroups",
-		[]byte("deny\n"),
-		0); err != nil && !os.IsNotExist(err) {
-		k.fatalf(msg, "%v", err)
+	// placed after all extra files and the lingering process report pipe, in the order set up by Start
+	offsetReport := offsetSetup + params.Count
+	if params.ReportLingering && params.AdoptWaitDelay > 0 {
+		offsetReport++
+	}
+	var statusReport *os.File
+	if params.ReportStatus {
+		// closed before starting the initial program
+		k.closeOnExec(offsetReport)
+		statusReport = k.newFile(uintptr(offsetReport), "status report")
+		offsetReport++
+	}
+	var dialSocket *os.File
+	if params.ServeDial {
+		// held until init terminates, must not be inherited by the initial program
+		k.closeOnExec(offsetReport)
+		dialSocket = k.newFile(uintptr(offsetReport), "dial socket")
+		offsetReport++
+	}
+	var notifySocket *os.File
+	if params.seccompNotify() {
+		// closed once the syscall filter is loaded
+		k.closeOnExec(offsetReport)
+		notifySocket = k.newFile(uintptr(offsetReport), "seccomp listener socket")
+	}
+	reportStatus := func(step string) {
+		if statusReport == nil {
+			return
+		}
+		if _, err := statusReport.Write([]byte(step + "\n")); err != nil {
+			msg.Verbosef("cannot report status %q: %v", step, err)
+		}
 	}
-	if err := k.writeFile(fhs.Proc+"self/gid_map",
-		append([]byte{}, strconv.Itoa(params.Gid)+" "+strconv.Itoa(params.HostGid)+" 1\n"...),
-		0); err != nil {
-		k.fatalf(msg, "%v", err)
+
+	// a joined user namespace already has its mappings established
+	if params.UserNamespace == nil && !entered {
+		// write uid/gid map here so parent does not need to set dumpable
+		if err := k.setDumpable(SUID_DUMP_USER); err != nil {
+			k.fatalf(msg, "cannot set SUID_DUMP_USER: %v", err)
+		}
+		if err := k.writeFile(fhs.Proc+"self/uid_map",
+			append([]byte{}, strconv.Itoa(params.Uid)+" "+strconv.Itoa(params.HostUid)+" 1\n"...),
+			0); err != nil {
+			k.fatalf(msg, "%v", err)
+		}
+		if err := k.writeFile(fhs.Proc+"self/setgroups",
+			[]byte("deny\n"),
+			0); err != nil && !os.IsNotExist(err) {
+			k.fatalf(msg, "%v", err)
+		}
+		if err := k.writeFile(fhs.Proc+"self/gid_map",
+			append([]byte{}, strconv.Itoa(params.Gid)+" "+strconv.Itoa(params.HostGid)+" 1\n"...),
+			0); err != nil {
+			k.fatalf(msg, "%v", err)
+		}
+		if err := k.setDumpable(SUID_DUMP_DISABLE); err != nil {
+			k.fatalf(msg, "cannot set SUID_DUMP_DISABLE: %v", err)
+		}
+	} else if !entered && k.usernsJoined() {
+		// the identity of init in a user namespace joined by the constructor is not known to the parent
+		for _, id := range [...]struct {
+			name     string
+			pathname string
+			want     int
+			current  int
+		}{
+			{"uid", fhs.Proc + "self/uid_map", params.Uid, k.getuid()},
+			{"gid", fhs.Proc + "self/gid_map", params.Gid, k.getgid()},
+		} {
+			if data, err := k.readFile(id.pathname); err != nil {
+				k.fatalf(msg, "%v", &StartError{true, "read " + id.name + " mapping", err, false, false, StartErrNamespace})
+			} else if err = checkIdentity(id.name, string(data), id.want, id.current); err != nil {
+				k.fatalf(msg, "%v", err)
+			}
+		}
 	}
-	if err := k.setDumpable(SUID_DUMP_DISABLE); err != nil {
-		k.fatalf(msg, "cannot set SUID_DUMP_DISABLE: %v", err)
+
+	var (
+		oldmask = k.umask(0)
+		lastcap uintptr
+		state   = &setupState{Params: &params.Params, Msg: msg}
+	)
+	if entered {
+		lastcap = k.lastcap(msg)
+		// deferred by Start to after joining namespaces
+		if err := landlockRestrictScope(k, msg, params.HostAbstract, params.LandlockRetry); err != nil {
+			k.fatalf(msg, "cannot enforce landlock ruleset: %v", err)
+		}
+		goto enteredOut
 	}
 
-	oldmask := k.umask(0)
 	if params.Hostname != "" {
 		if err := k.sethostname([]byte(params.Hostname)); err != nil {
 			k.fatalf(msg, "cannot set hostname: %v", err)
 		}
 	}
 
+	if params.TimeOffset != nil {
+		// offsets can no longer be set once a process enters the namespace, so init remains
+		// in the original time namespace and the initial process is the first to enter it
+		if err := k.unshare(CLONE_NEWTIME); err != nil {
+			k.fatalf(msg, "cannot create time namespace: %v", err)
+		}
+		if err := k.writeFile(fhs.Proc+"self/timens_offsets", params.TimeOffset.Bytes(), 0); err != nil {
+			k.fatalf(msg, "cannot set time namespace offsets: %v", err)
+		}
+	}
+
+	if params.LoopbackOnly && !params.HostNet {
+		reportStatus(StatusLoopback)
+		if err := k.loopbackUp(); err != nil {
+			k.fatalf(msg, "%v", &StartError{true, "bring up loopback interface", err, false, false, StartErrNetwork})
+		}
+	}
+
 	// cache sysctl before pivot_root
-	lastcap := k.lastcap(msg)
+	lastcap = k.lastcap(msg)
 
 	if err := k.mount(zeroString, fhs.Root, zeroString, MS_SILENT|MS_SLAVE|MS_REC, zeroString); err != nil {
 		k.fatalf(msg, "cannot make / rslave: %v", optionalErrorUnwrap(err))
 	}
 
-	state := &setupState{Params: &params.Params, Msg: msg}
-
 	/* early is called right before pivot_root into intermediate root;
 	this step is mostly for gathering information that would otherwise be difficult to obtain
 	via library functions after pivot_root, and implementations are expected to avoid changing
 	the state of the mount namespace */
+	reportStatus(StatusPrepareOps)
 	for i, op := range *params.Ops {
 		if op == nil || !op.Valid() {
 			k.fatalf(msg, "invalid op at index %d", i)
@@ -229,6 +321,7 @@ func initEntrypoint(k syscallDispatcher, msg message.Msg) {
 	this step sets up the container filesystem, and implementations are expected to keep the host root
 	and sysroot mount points intact but otherwise can do whatever they need to;
 	chdir is allowed but discouraged */
+	reportStatus(StatusApplyOps)
 	for i, op := range *params.Ops {
 		// ops already checked during early setup
 		if prefix, ok := op.prefix(); ok {
@@ -243,6 +336,27 @@ func initEntrypoint(k syscallDispatcher, msg message.Msg) {
 		}
 	}
 
+	if params.ReadOnlyRoot {
+		writable := make([]string, 0, len(params.WritablePaths))
+		for _, a := range params.WritablePaths {
+			if a == nil {
+				continue
+			}
+			// a directory on the mount being remounted is not writable on its own
+			target := toSysroot(a.String())
+			if err := k.mount(target, target, zeroString, MS_SILENT|MS_BIND|MS_REC, zeroString); err != nil {
+				if errors.Is(err, ENOENT) {
+					continue
+				}
+				k.fatalf(msg, "%v", &StartError{true, "bind writable path " + strconv.Quote(a.String()) + " onto itself", err, false, false, StartErrMount})
+			}
+			writable = append(writable, target)
+		}
+		if err := k.remountExcept(msg, sysrootPath, MS_RDONLY|MS_REC, writable); err != nil {
+			k.fatalf(msg, "%v", &StartError{true, "remount container root read-only", err, false, false, StartErrMount})
+		}
+	}
+
 	// setup requiring host root complete at this point
 	if err := k.mount(hostDir, hostDir, zeroString, MS_SILENT|MS_REC|MS_PRIVATE, zeroString); err != nil {
 		k.fatalf(msg, "cannot make host root rprivate: %v", optionalErrorUnwrap(err))
@@ -281,6 +395,13 @@ func initEntrypoint(k syscallDispatcher, msg message.Msg) {
 		}
 	}
 
+enteredOut:
+	if params.CheckPath {
+		if err := checkInitialProgram(k, params.Path); err != nil {
+			k.fatalf(msg, "%v", err)
+		}
+	}
+
 	if err := k.capAmbientClearAll(); err != nil {
 		k.fatalf(msg, "cannot clear the ambient capability set: %v", err)
 	}
@@ -308,13 +429,60 @@ func initEntrypoint(k syscallDispatcher, msg message.Msg) {
 		k.fatalf(msg, "cannot capset: %v", err)
 	}
 
+	// set on the calling thread, which creates the initial program
+	if params.Nice != 0 {
+		nice := clampNice(params.Nice)
+		if nice != params.Nice {
+			msg.Verbosef("nice value %d out of range, clamped to %d", params.Nice, nice)
+		}
+		if err := k.setNice(nice); err != nil {
+			k.fatalf(msg, "cannot set nice value: %v", err)
+		}
+	}
+	if params.IOClass != IOClassNone {
+		if err := k.setIOClass(params.IOClass); err != nil {
+			k.fatalf(msg, "cannot set I/O scheduling class: %v", err)
+		}
+	}
+
+	if len(params.LandlockFS) > 0 {
+		reportStatus(StatusLandlock)
+		if err := landlockRestrictFS(k, msg, params.LandlockFS, params.LandlockRetry); err != nil {
+			k.fatalf(msg, "cannot enforce landlock filesystem rules: %v", err)
+		}
+	}
+
 	if !params.SeccompDisable {
+		reportStatus(StatusSeccomp)
+	}
+	if !params.SeccompDisable && len(params.SeccompProgram) > 0 {
+		if err := k.seccompLoadProgram(params.SeccompProgram); err != nil {
+			k.fatalf(msg, "cannot load syscall filter: %v", err)
+		}
+		msg.Verbosef("%d byte filter program loaded", len(params.SeccompProgram))
+	} else if !params.SeccompDisable {
 		rules := params.SeccompRules
 		if len(rules) == 0 { // non-empty rules slice always overrides presets
-			msg.Verbosef("resolving presets %#x", params.SeccompPresets)
+			msg.Verbosef("resolving presets %s", params.SeccompPresets)
 			rules = seccomp.Preset(params.SeccompPresets, params.SeccompFlags)
+			if params.SeccompPresets&std.PresetAllowIOUring != 0 {
+				msg.Verbose("io_uring setup is logged, operations submitted through it are not subject to the syscall filter")
+			}
+		}
+		if len(params.SeccompDenySocket) > 0 {
+			rules = append(slices.Clip(rules), seccomp.DenySocketFamily(params.SeccompDenySocket...)...)
 		}
-		if err := k.seccompLoad(rules, params.SeccompFlags); err != nil {
+		flags := params.SeccompFlags | params.SeccompKill&seccomp.DenyMask
+		if flags&seccomp.KillProcess != 0 && !k.seccompKillProcessSupported() {
+			msg.Verbose("SECCOMP_RET_KILL_PROCESS not supported, falling back to SECCOMP_RET_KILL_THREAD")
+			flags = flags&^seccomp.KillProcess | seccomp.KillThread
+		}
+		if notifySocket != nil {
+			// the listener is passed to the parent, which services notifications on behalf of the container
+			if err := k.seccompLoadNotify(rules, flags, params.SeccompExtraArch, notifySocket); err != nil {
+				k.fatalf(msg, "cannot load syscall filter: %v", err)
+			}
+		} else if err := k.seccompLoad(rules, flags, params.SeccompExtraArch); err != nil {
 			// this also indirectly asserts PR_SET_NO_NEW_PRIVS
 			k.fatalf(msg, "cannot load syscall filter: %v", err)
 		}
@@ -328,6 +496,13 @@ func initEntrypoint(k syscallDispatcher, msg message.Msg) {
 		// setup fd is placed before all extra files
 		extraFiles[i] = k.newFile(uintptr(offsetSetup+i), "extra file "+strconv.Itoa(i))
 	}
+	var lingeringReport *os.File
+	if params.ReportLingering && params.AdoptWaitDelay > 0 {
+		// placed after all extra files, must not be inherited by the initial program
+		fd := offsetSetup + params.Count
+		k.closeOnExec(fd)
+		lingeringReport = k.newFile(uintptr(fd), "lingering process report")
+	}
 	k.umask(oldmask)
 
 	if err := closeSetup(); err != nil {
@@ -341,10 +516,26 @@ func initEntrypoint(k syscallDispatcher, msg message.Msg) {
 	cmd.ExtraFiles = extraFiles
 	cmd.Dir = params.Dir.String()
 
+	if params.ReapSignal != 0 {
+		if err := k.setChildSubreaper(); err != nil {
+			k.fatalf(msg, "cannot set child subreaper: %v", err)
+		}
+	}
+
 	msg.Verbosef("starting initial program %s", params.Path)
+	reportStatus(StatusStart)
 	if err := k.start(cmd); err != nil {
 		k.fatalf(msg, "%v", err)
 	}
+	if statusReport != nil {
+		if err := statusReport.Close(); err != nil {
+			msg.Verbosef("cannot close status report pipe: %v", err)
+		}
+	}
+	if dialSocket != nil {
+		// served until the parent closes its end, on behalf of the host
+		go serveDial(msg, dialSocket)
+	}
 
 	type winfo struct {
 		wpid    int
@@ -389,7 +580,7 @@ func initEntrypoint(k syscallDispatcher, msg message.Msg) {
 	// handle signals to dump withheld messages
 	sig := make(chan os.Signal, 2)
 	k.notify(sig, CancelSignal,
-		os.Interrupt, SIGTERM, SIGQUIT)
+		os.Interrupt, SIGTERM, SIGQUIT, SIGHUP)
 
 	// closed after residualProcessTimeout has elapsed after initial process death
 	timeout := make(chan struct{})
@@ -400,13 +591,17 @@ func initEntrypoint(k syscallDispatcher, msg message.Msg) {
 		case s := <-sig:
 			if s == CancelSignal && params.ForwardCancel && cmd.Process != nil {
 				msg.Verbose("forwarding context cancellation")
-				if err := k.signal(cmd, os.Interrupt); err != nil {
+				var forward os.Signal = os.Interrupt
+				if params.ForwardSignal != 0 {
+					forward = params.ForwardSignal
+				}
+				if err := k.signal(cmd, forward); err != nil {
 					k.printf(msg, "cannot forward cancellation: %v", err)
 				}
 				continue
 			}
 
-			if s == SIGTERM || s == SIGQUIT {
+			if s == SIGTERM || s == SIGQUIT || s == SIGHUP {
 				msg.Verbosef("got %s, forwarding to initial process", s.String())
 				if err := k.signal(cmd, s); err != nil {
 					k.printf(msg, "cannot forward signal: %v", err)
@@ -449,17 +644,46 @@ func initEntrypoint(k syscallDispatcher, msg message.Msg) {
 					r = 255
 					msg.Verbosef("initial process exited with status %#x", w.wstatus)
 				}
+
+				if params.ReapSignal != 0 {
+					msg.Verbosef("delivering %s to lingering processes", params.ReapSignal)
+					if err := k.kill(-1, params.ReapSignal); err != nil && !errors.Is(err, ESRCH) {
+						k.printf(msg, "cannot signal lingering processes: %v", err)
+					}
+				}
 			}
 
 		case <-timeout:
 			k.printf(msg, "timeout exceeded waiting for lingering processes")
+			if lingeringReport != nil {
+				if procs := lingeringProcesses(k, msg); len(procs) > 0 {
+					if err := gob.NewEncoder(lingeringReport).Encode(procs); err != nil {
+						k.printf(msg, "cannot report lingering processes: %v", err)
+					}
+				}
+			}
 			msg.BeforeExit()
 			k.exit(r)
 		}
 	}
 }
 
-// initName is the prefix used by log.std in the init process.
+// checkInitialProgram checks that pathname refers to an executable file in the container filesystem.
+func checkInitialProgram(k syscallDispatcher, pathname *check.Absolute) error {
+	fi, err := k.stat(pathname.String())
+	if err != nil {
+		return &StartError{true, "initial program not found in container", err, false, false, StartErrExec}
+	}
+	if mode := fi.Mode(); mode.IsDir() {
+		return &StartError{true, "initial program " + strconv.Quote(pathname.String()) + " not executable in container", EISDIR, false, false, StartErrExec}
+	} else if mode.Perm()&0111 == 0 {
+		return &StartError{true, "initial program " + strconv.Quote(pathname.String()) + " not executable in container", EACCES, false, false, StartErrExec}
+	}
+	return nil
+}
+
+// initName is the prefix used by log.std in the init process,
+// and must match HAKUREI_ENTER_INIT in enter.h.
 const initName = "init"
 
 // TryArgv0 calls [Init] if the last element of argv0 is "init".
@@ -1,6 +1,7 @@
 package container
 
 import (
+	"io/fs"
 	"math"
 	"os"
 	"syscall"
@@ -21,6 +22,7 @@ func TestInitEntrypoint(t *testing.T) {
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
 				call("getpid", stub.ExpectArgs{}, 1<<10, nil),
+				call("entered", stub.ExpectArgs{}, false, nil),
 				call("fatal", stub.ExpectArgs{[]any{"this process must run as pid 1"}}, nil, nil),
 			},
 		}, nil},
@@ -263,6 +265,278 @@ func TestInitEntrypoint(t *testing.T) {
 			},
 		}, nil},
 
+		{"loopbackUp", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					Uid:            1 << 16,
+					Gid:            1 << 15,
+					Hostname:       "hakurei-check",
+					Ops:            (*Ops)(sliceAddr(make(Ops, 1))),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					LoopbackOnly:   true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(68), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("65536 1000 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("32768 100 1\n"), os.FileMode(0)}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
+				call("loopbackUp", stub.ExpectArgs{}, nil, stub.UniqueError(67)),
+				call("fatalf", stub.ExpectArgs{"%v", []any{&StartError{true, "bring up loopback interface", stub.UniqueError(67), false, false, StartErrNetwork}}}, nil, nil),
+			},
+		}, nil},
+
+		{"sethostname joined userns", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					UserNamespace:  check.MustAbs("/proc/self/fd/3"),
+					Uid:            1000,
+					Gid:            100,
+					Hostname:       "hakurei-check",
+					Ops:            (*Ops)(sliceAddr(make(Ops, 1))),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(68), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("usernsJoined", stub.ExpectArgs{}, false, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, stub.UniqueError(67)),
+				call("fatalf", stub.ExpectArgs{"cannot set hostname: %v", []any{stub.UniqueError(67)}}, nil, nil),
+			},
+		}, nil},
+
+		{"joined userns read uid_map", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					UserNamespace:  check.MustAbs("/proc/self/fd/3"),
+					Uid:            1000,
+					Gid:            100,
+					Hostname:       "hakurei-check",
+					Ops:            (*Ops)(sliceAddr(make(Ops, 1))),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(68), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("usernsJoined", stub.ExpectArgs{}, true, nil),
+				call("getuid", stub.ExpectArgs{}, 1000, nil),
+				call("getgid", stub.ExpectArgs{}, 100, nil),
+				call("readFile", stub.ExpectArgs{"/proc/self/uid_map"}, ([]byte)(nil), stub.UniqueError(69)),
+				call("fatalf", stub.ExpectArgs{"%v", []any{&StartError{true, "read uid mapping", stub.UniqueError(69), false, false, StartErrNamespace}}}, nil, nil),
+			},
+		}, nil},
+
+		{"joined userns uid differs", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					UserNamespace:  check.MustAbs("/proc/self/fd/3"),
+					Uid:            1000,
+					Gid:            100,
+					Hostname:       "hakurei-check",
+					Ops:            (*Ops)(sliceAddr(make(Ops, 1))),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(68), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("usernsJoined", stub.ExpectArgs{}, true, nil),
+				call("getuid", stub.ExpectArgs{}, 65534, nil),
+				call("getgid", stub.ExpectArgs{}, 100, nil),
+				call("readFile", stub.ExpectArgs{"/proc/self/uid_map"}, []byte("         0     100000      65536\n      1000       1000          1\n"), nil),
+				call("fatalf", stub.ExpectArgs{"%v", []any{&StartError{false, "requested uid 1000 differs from uid 65534 in joined user namespace", syscall.EINVAL, true, false, StartErrNamespace}}}, nil, nil),
+			},
+		}, nil},
+
+		{"joined userns gid unmapped", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					UserNamespace:  check.MustAbs("/proc/self/fd/3"),
+					Uid:            1000,
+					Gid:            100,
+					Hostname:       "hakurei-check",
+					Ops:            (*Ops)(sliceAddr(make(Ops, 1))),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(68), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("usernsJoined", stub.ExpectArgs{}, true, nil),
+				call("getuid", stub.ExpectArgs{}, 1000, nil),
+				call("getgid", stub.ExpectArgs{}, 100, nil),
+				call("readFile", stub.ExpectArgs{"/proc/self/uid_map"}, []byte("         0     100000      65536\n      1000       1000          1\n"), nil),
+				call("readFile", stub.ExpectArgs{"/proc/self/gid_map"}, []byte("      1000       1000          1\n"), nil),
+				call("fatalf", stub.ExpectArgs{"%v", []any{&StartError{false, "gid 100 is not mapped in joined user namespace", syscall.EINVAL, true, false, StartErrNamespace}}}, nil, nil),
+			},
+		}, nil},
+
+		{"sethostname joined userns mapped", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					UserNamespace:  check.MustAbs("/proc/self/fd/3"),
+					Uid:            1000,
+					Gid:            100,
+					Hostname:       "hakurei-check",
+					Ops:            (*Ops)(sliceAddr(make(Ops, 1))),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(68), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("usernsJoined", stub.ExpectArgs{}, true, nil),
+				call("getuid", stub.ExpectArgs{}, 1000, nil),
+				call("getgid", stub.ExpectArgs{}, 100, nil),
+				call("readFile", stub.ExpectArgs{"/proc/self/uid_map"}, []byte("         0     100000      65536\n      1000       1000          1\n"), nil),
+				call("readFile", stub.ExpectArgs{"/proc/self/gid_map"}, []byte("       100        100          1\n"), nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, stub.UniqueError(67)),
+				call("fatalf", stub.ExpectArgs{"cannot set hostname: %v", []any{stub.UniqueError(67)}}, nil, nil),
+			},
+		}, nil},
+
+		{"unshare time", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					Uid:            1 << 16,
+					Gid:            1 << 15,
+					Hostname:       "hakurei-check",
+					TimeOffset:     &TimeOffset{Monotonic: -90 * time.Minute, Boottime: 1500 * time.Millisecond},
+					Ops:            (*Ops)(sliceAddr(make(Ops, 1))),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(68), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("65536 1000 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("32768 100 1\n"), os.FileMode(0)}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
+				call("unshare", stub.ExpectArgs{syscall.CLONE_NEWTIME}, nil, stub.UniqueError(67)),
+				call("fatalf", stub.ExpectArgs{"cannot create time namespace: %v", []any{stub.UniqueError(67)}}, nil, nil),
+			},
+		}, nil},
+
+		{"timens offsets", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					Uid:            1 << 16,
+					Gid:            1 << 15,
+					Hostname:       "hakurei-check",
+					TimeOffset:     &TimeOffset{Monotonic: -90 * time.Minute, Boottime: 1500 * time.Millisecond},
+					Ops:            (*Ops)(sliceAddr(make(Ops, 1))),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(68), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("65536 1000 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("32768 100 1\n"), os.FileMode(0)}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
+				call("unshare", stub.ExpectArgs{syscall.CLONE_NEWTIME}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/timens_offsets", []byte("monotonic -5400 0\nboottime 1 500000000\n"), os.FileMode(0)}, nil, stub.UniqueError(67)),
+				call("fatalf", stub.ExpectArgs{"cannot set time namespace offsets: %v", []any{stub.UniqueError(67)}}, nil, nil),
+			},
+		}, nil},
+
 		{"mount rslave root", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
@@ -920,7 +1194,7 @@ func TestInitEntrypoint(t *testing.T) {
 			},
 		}, nil},
 
-		{"unmount host", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+		{"writable bind", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
 				call("getpid", stub.ExpectArgs{}, 1, nil),
@@ -940,7 +1214,9 @@ func TestInitEntrypoint(t *testing.T) {
 					SeccompPresets: std.PresetStrict,
 					RetainSession:  true,
 					Privileged:     true,
-				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(40), nil),
+					ReadOnlyRoot:   true,
+					WritablePaths:  []*check.Absolute{check.MustAbs("/tmp"), nil, check.MustAbs("/home/chronos")},
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(42), nil),
 				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
 				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
 				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
@@ -971,13 +1247,12 @@ func TestInitEntrypoint(t *testing.T) {
 				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0755)}, nil, nil),
 				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
 				/* end apply */
-				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
-				call("unmount", stub.ExpectArgs{"host", 2}, nil, stub.UniqueError(39)),
-				call("fatalf", stub.ExpectArgs{"cannot unmount host root: %v", []any{stub.UniqueError(39)}}, nil, nil),
+				call("mount", stub.ExpectArgs{"/sysroot/tmp", "/sysroot/tmp", "", uintptr(0xd000), ""}, nil, stub.UniqueError(40)),
+				call("fatalf", stub.ExpectArgs{"%v", []any{&StartError{true, "bind writable path \"/tmp\" onto itself", stub.UniqueError(40), false, false, StartErrMount}}}, nil, nil),
 			},
 		}, nil},
 
-		{"open ir", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+		{"remountExcept", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
 				call("getpid", stub.ExpectArgs{}, 1, nil),
@@ -997,7 +1272,9 @@ func TestInitEntrypoint(t *testing.T) {
 					SeccompPresets: std.PresetStrict,
 					RetainSession:  true,
 					Privileged:     true,
-				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(38), nil),
+					ReadOnlyRoot:   true,
+					WritablePaths:  []*check.Absolute{check.MustAbs("/tmp"), nil, check.MustAbs("/home/chronos")},
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(42), nil),
 				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
 				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
 				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
@@ -1028,15 +1305,14 @@ func TestInitEntrypoint(t *testing.T) {
 				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0755)}, nil, nil),
 				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
 				/* end apply */
-				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
-				call("unmount", stub.ExpectArgs{"host", 2}, nil, nil),
-				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, syscall.EINTR),
-				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, stub.UniqueError(37)),
-				call("fatalf", stub.ExpectArgs{"cannot open intermediate root: %v", []any{stub.UniqueError(37)}}, nil, nil),
+				call("mount", stub.ExpectArgs{"/sysroot/tmp", "/sysroot/tmp", "", uintptr(0xd000), ""}, nil, nil),
+				call("mount", stub.ExpectArgs{"/sysroot/home/chronos", "/sysroot/home/chronos", "", uintptr(0xd000), ""}, nil, &MountError{"/sysroot/home/chronos", "/sysroot/home/chronos", "", 0xd000, "", syscall.ENOENT}),
+				call("remountExcept", stub.ExpectArgs{"/sysroot", uintptr(0x4001), []string{"/sysroot/tmp"}}, nil, stub.UniqueError(41)),
+				call("fatalf", stub.ExpectArgs{"%v", []any{&StartError{true, "remount container root read-only", stub.UniqueError(41), false, false, StartErrMount}}}, nil, nil),
 			},
 		}, nil},
 
-		{"chdir sysroot", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+		{"unmount host", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
 				call("getpid", stub.ExpectArgs{}, 1, nil),
@@ -1056,7 +1332,7 @@ func TestInitEntrypoint(t *testing.T) {
 					SeccompPresets: std.PresetStrict,
 					RetainSession:  true,
 					Privileged:     true,
-				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(36), nil),
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(40), nil),
 				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
 				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
 				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
@@ -1088,15 +1364,12 @@ func TestInitEntrypoint(t *testing.T) {
 				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
 				/* end apply */
 				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
-				call("unmount", stub.ExpectArgs{"host", 2}, nil, nil),
-				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, syscall.EINTR),
-				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, nil),
-				call("chdir", stub.ExpectArgs{"/sysroot"}, nil, stub.UniqueError(35)),
-				call("fatalf", stub.ExpectArgs{"cannot enter sysroot: %v", []any{stub.UniqueError(35)}}, nil, nil),
+				call("unmount", stub.ExpectArgs{"host", 2}, nil, stub.UniqueError(39)),
+				call("fatalf", stub.ExpectArgs{"cannot unmount host root: %v", []any{stub.UniqueError(39)}}, nil, nil),
 			},
 		}, nil},
 
-		{"pivotRoot sysroot", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+		{"open ir", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
 				call("getpid", stub.ExpectArgs{}, 1, nil),
@@ -1116,7 +1389,7 @@ func TestInitEntrypoint(t *testing.T) {
 					SeccompPresets: std.PresetStrict,
 					RetainSession:  true,
 					Privileged:     true,
-				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(34), nil),
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(38), nil),
 				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
 				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
 				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
@@ -1150,14 +1423,12 @@ func TestInitEntrypoint(t *testing.T) {
 				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
 				call("unmount", stub.ExpectArgs{"host", 2}, nil, nil),
 				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, syscall.EINTR),
-				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, nil),
-				call("chdir", stub.ExpectArgs{"/sysroot"}, nil, nil),
-				call("pivotRoot", stub.ExpectArgs{".", "."}, nil, stub.UniqueError(33)),
-				call("fatalf", stub.ExpectArgs{"cannot pivot into sysroot: %v", []any{stub.UniqueError(33)}}, nil, nil),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, stub.UniqueError(37)),
+				call("fatalf", stub.ExpectArgs{"cannot open intermediate root: %v", []any{stub.UniqueError(37)}}, nil, nil),
 			},
 		}, nil},
 
-		{"fchdir ir", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+		{"chdir sysroot", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
 				call("getpid", stub.ExpectArgs{}, 1, nil),
@@ -1177,7 +1448,128 @@ func TestInitEntrypoint(t *testing.T) {
 					SeccompPresets: std.PresetStrict,
 					RetainSession:  true,
 					Privileged:     true,
-				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(32), nil),
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(36), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("65536 1000 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("32768 100 1\n"), os.FileMode(0)}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
+				call("lastcap", stub.ExpectArgs{}, uintptr(40), nil),
+				call("mount", stub.ExpectArgs{"", "/", "", uintptr(0x8c000), ""}, nil, nil),
+				/* begin early */
+				call("evalSymlinks", stub.ExpectArgs{"/"}, "/", nil),
+				/* end early */
+				call("mount", stub.ExpectArgs{"rootfs", "/proc/self/fd", "tmpfs", uintptr(6), ""}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/proc/self/fd"}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"sysroot", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"sysroot", "sysroot", "", uintptr(0xd000), ""}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"host", os.FileMode(0755)}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{"/proc/self/fd", "host"}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				/* begin apply */
+				call("stat", stub.ExpectArgs{"/host"}, isDirFi(true), nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot", os.FileMode(0700)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"mounting %q flags %#x", []any{"/sysroot", uintptr(0x4001)}}, nil, nil),
+				call("bindMount", stub.ExpectArgs{"/host", "/sysroot", uintptr(0x4001), false}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"%s %s", []any{"mounting", &MountProcOp{Target: check.MustAbs("/proc/")}}}, nil, nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
+				/* end apply */
+				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
+				call("unmount", stub.ExpectArgs{"host", 2}, nil, nil),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, syscall.EINTR),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, nil),
+				call("chdir", stub.ExpectArgs{"/sysroot"}, nil, stub.UniqueError(35)),
+				call("fatalf", stub.ExpectArgs{"cannot enter sysroot: %v", []any{stub.UniqueError(35)}}, nil, nil),
+			},
+		}, nil},
+
+		{"pivotRoot sysroot", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					Uid:            1 << 16,
+					Gid:            1 << 15,
+					Hostname:       "hakurei-check",
+					Ops:            new(Ops).Bind(check.MustAbs("/"), check.MustAbs("/"), std.BindDevice).Proc(check.MustAbs("/proc/")),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(34), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("65536 1000 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("32768 100 1\n"), os.FileMode(0)}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
+				call("lastcap", stub.ExpectArgs{}, uintptr(40), nil),
+				call("mount", stub.ExpectArgs{"", "/", "", uintptr(0x8c000), ""}, nil, nil),
+				/* begin early */
+				call("evalSymlinks", stub.ExpectArgs{"/"}, "/", nil),
+				/* end early */
+				call("mount", stub.ExpectArgs{"rootfs", "/proc/self/fd", "tmpfs", uintptr(6), ""}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/proc/self/fd"}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"sysroot", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"sysroot", "sysroot", "", uintptr(0xd000), ""}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"host", os.FileMode(0755)}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{"/proc/self/fd", "host"}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				/* begin apply */
+				call("stat", stub.ExpectArgs{"/host"}, isDirFi(true), nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot", os.FileMode(0700)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"mounting %q flags %#x", []any{"/sysroot", uintptr(0x4001)}}, nil, nil),
+				call("bindMount", stub.ExpectArgs{"/host", "/sysroot", uintptr(0x4001), false}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"%s %s", []any{"mounting", &MountProcOp{Target: check.MustAbs("/proc/")}}}, nil, nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
+				/* end apply */
+				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
+				call("unmount", stub.ExpectArgs{"host", 2}, nil, nil),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, syscall.EINTR),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, nil),
+				call("chdir", stub.ExpectArgs{"/sysroot"}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{".", "."}, nil, stub.UniqueError(33)),
+				call("fatalf", stub.ExpectArgs{"cannot pivot into sysroot: %v", []any{stub.UniqueError(33)}}, nil, nil),
+			},
+		}, nil},
+
+		{"fchdir ir", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					Uid:            1 << 16,
+					Gid:            1 << 15,
+					Hostname:       "hakurei-check",
+					Ops:            new(Ops).Bind(check.MustAbs("/"), check.MustAbs("/"), std.BindDevice).Proc(check.MustAbs("/proc/")),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(32), nil),
 				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
 				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
 				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
@@ -1411,6 +1803,141 @@ func TestInitEntrypoint(t *testing.T) {
 			},
 		}, nil},
 
+		{"checkInitialProgram", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					Uid:            1 << 16,
+					Gid:            1 << 15,
+					Hostname:       "hakurei-check",
+					Ops:            new(Ops).Bind(check.MustAbs("/"), check.MustAbs("/"), std.BindDevice).Proc(check.MustAbs("/proc/")),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+					CheckPath:      true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(24), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("65536 1000 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("32768 100 1\n"), os.FileMode(0)}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
+				call("lastcap", stub.ExpectArgs{}, uintptr(40), nil),
+				call("mount", stub.ExpectArgs{"", "/", "", uintptr(0x8c000), ""}, nil, nil),
+				/* begin early */
+				call("evalSymlinks", stub.ExpectArgs{"/"}, "/", nil),
+				/* end early */
+				call("mount", stub.ExpectArgs{"rootfs", "/proc/self/fd", "tmpfs", uintptr(6), ""}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/proc/self/fd"}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"sysroot", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"sysroot", "sysroot", "", uintptr(0xd000), ""}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"host", os.FileMode(0755)}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{"/proc/self/fd", "host"}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				/* begin apply */
+				call("stat", stub.ExpectArgs{"/host"}, isDirFi(true), nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot", os.FileMode(0700)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"mounting %q flags %#x", []any{"/sysroot", uintptr(0x4001)}}, nil, nil),
+				call("bindMount", stub.ExpectArgs{"/host", "/sysroot", uintptr(0x4001), false}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"%s %s", []any{"mounting", &MountProcOp{Target: check.MustAbs("/proc/")}}}, nil, nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
+				/* end apply */
+				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
+				call("unmount", stub.ExpectArgs{"host", 2}, nil, nil),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, syscall.EINTR),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, nil),
+				call("chdir", stub.ExpectArgs{"/sysroot"}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{".", "."}, nil, nil),
+				call("fchdir", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("unmount", stub.ExpectArgs{".", 2}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				call("close", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("stat", stub.ExpectArgs{"/bin/zsh"}, modeFi(0644), nil),
+				call("fatalf", stub.ExpectArgs{"%v", []any{&StartError{true, "initial program \"/bin/zsh\" not executable in container", syscall.EACCES, false, false, StartErrExec}}}, nil, nil),
+			},
+		}, nil},
+
+		{"capAmbientClearAll checkInitialProgram", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					Uid:            1 << 16,
+					Gid:            1 << 15,
+					Hostname:       "hakurei-check",
+					Ops:            new(Ops).Bind(check.MustAbs("/"), check.MustAbs("/"), std.BindDevice).Proc(check.MustAbs("/proc/")),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+					CheckPath:      true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(24), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("65536 1000 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("32768 100 1\n"), os.FileMode(0)}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
+				call("lastcap", stub.ExpectArgs{}, uintptr(40), nil),
+				call("mount", stub.ExpectArgs{"", "/", "", uintptr(0x8c000), ""}, nil, nil),
+				/* begin early */
+				call("evalSymlinks", stub.ExpectArgs{"/"}, "/", nil),
+				/* end early */
+				call("mount", stub.ExpectArgs{"rootfs", "/proc/self/fd", "tmpfs", uintptr(6), ""}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/proc/self/fd"}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"sysroot", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"sysroot", "sysroot", "", uintptr(0xd000), ""}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"host", os.FileMode(0755)}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{"/proc/self/fd", "host"}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				/* begin apply */
+				call("stat", stub.ExpectArgs{"/host"}, isDirFi(true), nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot", os.FileMode(0700)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"mounting %q flags %#x", []any{"/sysroot", uintptr(0x4001)}}, nil, nil),
+				call("bindMount", stub.ExpectArgs{"/host", "/sysroot", uintptr(0x4001), false}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"%s %s", []any{"mounting", &MountProcOp{Target: check.MustAbs("/proc/")}}}, nil, nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
+				/* end apply */
+				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
+				call("unmount", stub.ExpectArgs{"host", 2}, nil, nil),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, syscall.EINTR),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, nil),
+				call("chdir", stub.ExpectArgs{"/sysroot"}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{".", "."}, nil, nil),
+				call("fchdir", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("unmount", stub.ExpectArgs{".", 2}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				call("close", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("stat", stub.ExpectArgs{"/bin/zsh"}, modeFi(0755), nil),
+				call("capAmbientClearAll", stub.ExpectArgs{}, nil, stub.UniqueError(23)),
+				call("fatalf", stub.ExpectArgs{"cannot clear the ambient capability set: %v", []any{stub.UniqueError(23)}}, nil, nil),
+			},
+		}, nil},
+
 		{"capAmbientClearAll", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
@@ -1870,24 +2397,573 @@ func TestInitEntrypoint(t *testing.T) {
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x28)}, nil, nil),
 				call("capAmbientRaise", stub.ExpectArgs{uintptr(0x15)}, nil, nil),
 				call("capset", stub.ExpectArgs{&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, &[2]capData{{0, 0x200000, 0x200000}, {0, 0, 0}}}, nil, nil),
-				call("verbosef", stub.ExpectArgs{"resolving presets %#x", []any{std.FilterPreset(0xf)}}, nil, nil),
-				call("seccompLoad", stub.ExpectArgs{seccomp.Preset(0xf, 0), seccomp.ExportFlag(0)}, nil, stub.UniqueError(15)),
+				call("verbosef", stub.ExpectArgs{"resolving presets %s", []any{std.FilterPreset(0xf)}}, nil, nil),
+				call("seccompLoad", stub.ExpectArgs{seccomp.Preset(0xf, 0), seccomp.ExportFlag(0), []seccomp.Arch(nil)}, nil, stub.UniqueError(15)),
 				call("fatalf", stub.ExpectArgs{"cannot load syscall filter: %v", []any{stub.UniqueError(15)}}, nil, nil),
 			},
 		}, nil},
 
-		{"start", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+		{"seccompLoadProgram", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
 				call("getpid", stub.ExpectArgs{}, 1, nil),
-				call("setPtracer", stub.ExpectArgs{uintptr(0)}, 0, stub.UniqueError(14)),
-				call("verbosef", stub.ExpectArgs{"cannot enable ptrace protection via Yama LSM: %v", []any{stub.UniqueError(14)}}, nil, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
 				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
-					Dir:            check.MustAbs("/.hakurei/nonexistent"),
-					Path:           check.MustAbs("/run/current-system/sw/bin/bash"),
-					Args:           []string{"bash", "-c", "false"},
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					Uid:            1 << 16,
+					Gid:            1 << 15,
+					Hostname:       "hakurei-check",
+					Ops:            new(Ops).Bind(check.MustAbs("/"), check.MustAbs("/"), std.BindDevice).Proc(check.MustAbs("/proc/")),
+					SeccompProgram: make([]byte, 8),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(16), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("65536 1000 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("32768 100 1\n"), os.FileMode(0)}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
+				call("lastcap", stub.ExpectArgs{}, uintptr(40), nil),
+				call("mount", stub.ExpectArgs{"", "/", "", uintptr(0x8c000), ""}, nil, nil),
+				/* begin early */
+				call("evalSymlinks", stub.ExpectArgs{"/"}, "/", nil),
+				/* end early */
+				call("mount", stub.ExpectArgs{"rootfs", "/proc/self/fd", "tmpfs", uintptr(6), ""}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/proc/self/fd"}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"sysroot", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"sysroot", "sysroot", "", uintptr(0xd000), ""}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"host", os.FileMode(0755)}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{"/proc/self/fd", "host"}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				/* begin apply */
+				call("stat", stub.ExpectArgs{"/host"}, isDirFi(true), nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot", os.FileMode(0700)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"mounting %q flags %#x", []any{"/sysroot", uintptr(0x4001)}}, nil, nil),
+				call("bindMount", stub.ExpectArgs{"/host", "/sysroot", uintptr(0x4001), false}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"%s %s", []any{"mounting", &MountProcOp{Target: check.MustAbs("/proc/")}}}, nil, nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
+				/* end apply */
+				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
+				call("unmount", stub.ExpectArgs{"host", 2}, nil, nil),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, syscall.EINTR),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, nil),
+				call("chdir", stub.ExpectArgs{"/sysroot"}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{".", "."}, nil, nil),
+				call("fchdir", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("unmount", stub.ExpectArgs{".", 2}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				call("close", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("capAmbientClearAll", stub.ExpectArgs{}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x0)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x2)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x3)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x4)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x5)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x6)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x7)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x8)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x9)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xa)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xb)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xc)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xd)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xe)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xf)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x10)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x11)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x12)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x13)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x14)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x16)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x17)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x18)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x19)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1a)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1b)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1c)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1d)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1e)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1f)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x20)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x21)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x22)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x23)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x24)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x25)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x26)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x27)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x28)}, nil, nil),
+				call("capAmbientRaise", stub.ExpectArgs{uintptr(0x15)}, nil, nil),
+				call("capset", stub.ExpectArgs{&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, &[2]capData{{0, 0x200000, 0x200000}, {0, 0, 0}}}, nil, nil),
+				call("seccompLoadProgram", stub.ExpectArgs{make([]byte, 8)}, nil, stub.UniqueError(15)),
+				call("fatalf", stub.ExpectArgs{"cannot load syscall filter: %v", []any{stub.UniqueError(15)}}, nil, nil),
+			},
+		}, nil},
+
+		{"seccompKillProcessSupported", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					Uid:            1 << 16,
+					Gid:            1 << 15,
+					Hostname:       "hakurei-check",
+					Ops:            new(Ops).Bind(check.MustAbs("/"), check.MustAbs("/"), std.BindDevice).Proc(check.MustAbs("/proc/")),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					SeccompKill:    seccomp.KillProcess,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(16), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("65536 1000 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("32768 100 1\n"), os.FileMode(0)}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
+				call("lastcap", stub.ExpectArgs{}, uintptr(40), nil),
+				call("mount", stub.ExpectArgs{"", "/", "", uintptr(0x8c000), ""}, nil, nil),
+				/* begin early */
+				call("evalSymlinks", stub.ExpectArgs{"/"}, "/", nil),
+				/* end early */
+				call("mount", stub.ExpectArgs{"rootfs", "/proc/self/fd", "tmpfs", uintptr(6), ""}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/proc/self/fd"}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"sysroot", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"sysroot", "sysroot", "", uintptr(0xd000), ""}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"host", os.FileMode(0755)}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{"/proc/self/fd", "host"}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				/* begin apply */
+				call("stat", stub.ExpectArgs{"/host"}, isDirFi(true), nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot", os.FileMode(0700)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"mounting %q flags %#x", []any{"/sysroot", uintptr(0x4001)}}, nil, nil),
+				call("bindMount", stub.ExpectArgs{"/host", "/sysroot", uintptr(0x4001), false}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"%s %s", []any{"mounting", &MountProcOp{Target: check.MustAbs("/proc/")}}}, nil, nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
+				/* end apply */
+				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
+				call("unmount", stub.ExpectArgs{"host", 2}, nil, nil),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, syscall.EINTR),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, nil),
+				call("chdir", stub.ExpectArgs{"/sysroot"}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{".", "."}, nil, nil),
+				call("fchdir", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("unmount", stub.ExpectArgs{".", 2}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				call("close", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("capAmbientClearAll", stub.ExpectArgs{}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x0)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x2)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x3)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x4)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x5)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x6)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x7)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x8)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x9)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xa)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xb)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xc)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xd)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xe)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xf)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x10)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x11)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x12)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x13)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x14)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x16)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x17)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x18)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x19)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1a)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1b)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1c)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1d)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1e)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1f)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x20)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x21)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x22)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x23)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x24)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x25)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x26)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x27)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x28)}, nil, nil),
+				call("capAmbientRaise", stub.ExpectArgs{uintptr(0x15)}, nil, nil),
+				call("capset", stub.ExpectArgs{&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, &[2]capData{{0, 0x200000, 0x200000}, {0, 0, 0}}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"resolving presets %s", []any{std.FilterPreset(0xf)}}, nil, nil),
+				call("seccompKillProcessSupported", stub.ExpectArgs{}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"SECCOMP_RET_KILL_PROCESS not supported, falling back to SECCOMP_RET_KILL_THREAD"}}, nil, nil),
+				call("seccompLoad", stub.ExpectArgs{seccomp.Preset(0xf, 0), seccomp.KillThread, []seccomp.Arch(nil)}, nil, stub.UniqueError(15)),
+				call("fatalf", stub.ExpectArgs{"cannot load syscall filter: %v", []any{stub.UniqueError(15)}}, nil, nil),
+			},
+		}, nil},
+
+		{"seccompLoad deny enosys", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					Uid:            1 << 16,
+					Gid:            1 << 15,
+					Hostname:       "hakurei-check",
+					Ops:            new(Ops).Bind(check.MustAbs("/"), check.MustAbs("/"), std.BindDevice).Proc(check.MustAbs("/proc/")),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					SeccompKill:    seccomp.DenyENOSYS | seccomp.AllowCAN,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(16), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("65536 1000 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("32768 100 1\n"), os.FileMode(0)}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
+				call("lastcap", stub.ExpectArgs{}, uintptr(40), nil),
+				call("mount", stub.ExpectArgs{"", "/", "", uintptr(0x8c000), ""}, nil, nil),
+				/* begin early */
+				call("evalSymlinks", stub.ExpectArgs{"/"}, "/", nil),
+				/* end early */
+				call("mount", stub.ExpectArgs{"rootfs", "/proc/self/fd", "tmpfs", uintptr(6), ""}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/proc/self/fd"}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"sysroot", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"sysroot", "sysroot", "", uintptr(0xd000), ""}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"host", os.FileMode(0755)}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{"/proc/self/fd", "host"}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				/* begin apply */
+				call("stat", stub.ExpectArgs{"/host"}, isDirFi(true), nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot", os.FileMode(0700)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"mounting %q flags %#x", []any{"/sysroot", uintptr(0x4001)}}, nil, nil),
+				call("bindMount", stub.ExpectArgs{"/host", "/sysroot", uintptr(0x4001), false}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"%s %s", []any{"mounting", &MountProcOp{Target: check.MustAbs("/proc/")}}}, nil, nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
+				/* end apply */
+				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
+				call("unmount", stub.ExpectArgs{"host", 2}, nil, nil),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, syscall.EINTR),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, nil),
+				call("chdir", stub.ExpectArgs{"/sysroot"}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{".", "."}, nil, nil),
+				call("fchdir", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("unmount", stub.ExpectArgs{".", 2}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				call("close", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("capAmbientClearAll", stub.ExpectArgs{}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x0)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x2)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x3)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x4)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x5)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x6)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x7)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x8)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x9)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xa)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xb)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xc)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xd)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xe)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xf)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x10)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x11)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x12)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x13)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x14)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x16)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x17)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x18)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x19)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1a)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1b)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1c)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1d)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1e)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1f)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x20)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x21)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x22)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x23)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x24)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x25)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x26)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x27)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x28)}, nil, nil),
+				call("capAmbientRaise", stub.ExpectArgs{uintptr(0x15)}, nil, nil),
+				call("capset", stub.ExpectArgs{&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, &[2]capData{{0, 0x200000, 0x200000}, {0, 0, 0}}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"resolving presets %s", []any{std.FilterPreset(0xf)}}, nil, nil),
+				call("seccompLoad", stub.ExpectArgs{seccomp.Preset(0xf, 0), seccomp.DenyENOSYS, []seccomp.Arch(nil)}, nil, stub.UniqueError(15)),
+				call("fatalf", stub.ExpectArgs{"cannot load syscall filter: %v", []any{stub.UniqueError(15)}}, nil, nil),
+			},
+		}, nil},
+
+		{"start", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, 0, stub.UniqueError(14)),
+				call("verbosef", stub.ExpectArgs{"cannot enable ptrace protection via Yama LSM: %v", []any{stub.UniqueError(14)}}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei/nonexistent"),
+					Path:           check.MustAbs("/run/current-system/sw/bin/bash"),
+					Args:           []string{"bash", "-c", "false"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					Uid:            1 << 24,
+					Gid:            1 << 23,
+					Hostname:       "hakurei-check",
+					Ops:            new(Ops).Bind(check.MustAbs("/"), check.MustAbs("/"), std.BindDevice).Proc(check.MustAbs("/proc/")),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompDisable: true,
+					ParentPerm:     0750,
+				}, 1971, 127, 2, false}, uintptr(0x39)}, stub.UniqueError(13), nil),
+				call("swapVerbose", stub.ExpectArgs{false}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("16777216 1971 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("8388608 127 1\n"), os.FileMode(0)}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
+				call("lastcap", stub.ExpectArgs{}, uintptr(40), nil),
+				call("mount", stub.ExpectArgs{"", "/", "", uintptr(0x8c000), ""}, nil, nil),
+				/* begin early */
+				call("evalSymlinks", stub.ExpectArgs{"/"}, "/", nil),
+				/* end early */
+				call("mount", stub.ExpectArgs{"rootfs", "/proc/self/fd", "tmpfs", uintptr(6), ""}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/proc/self/fd"}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"sysroot", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"sysroot", "sysroot", "", uintptr(0xd000), ""}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"host", os.FileMode(0755)}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{"/proc/self/fd", "host"}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				/* begin apply */
+				call("stat", stub.ExpectArgs{"/host"}, isDirFi(true), nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot", os.FileMode(0700)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"mounting %q flags %#x", []any{"/sysroot", uintptr(0x4001)}}, nil, nil),
+				call("bindMount", stub.ExpectArgs{"/host", "/sysroot", uintptr(0x4001), false}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"%s %s", []any{"mounting", &MountProcOp{Target: check.MustAbs("/proc/")}}}, nil, nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0750)}, nil, nil),
+				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
+				/* end apply */
+				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
+				call("unmount", stub.ExpectArgs{"host", 2}, nil, nil),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, syscall.EINTR),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, nil),
+				call("chdir", stub.ExpectArgs{"/sysroot"}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{".", "."}, nil, nil),
+				call("fchdir", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("unmount", stub.ExpectArgs{".", 2}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				call("close", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("capAmbientClearAll", stub.ExpectArgs{}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x0)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x2)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x3)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x4)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x5)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x6)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x7)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x8)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x9)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xa)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xb)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xc)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xd)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xe)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xf)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x10)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x11)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x12)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x13)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x14)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x15)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x16)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x17)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x18)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x19)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1a)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1b)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1c)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1d)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1e)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1f)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x20)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x21)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x22)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x23)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x24)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x25)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x26)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x27)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x28)}, nil, nil),
+				call("capset", stub.ExpectArgs{&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, new([2]capData)}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{"syscall filter not configured"}}, nil, nil),
+				call("newFile", stub.ExpectArgs{uintptr(0x3a), "extra file 0"}, (*os.File)(nil), nil),
+				call("newFile", stub.ExpectArgs{uintptr(0x3b), "extra file 1"}, (*os.File)(nil), nil),
+				call("umask", stub.ExpectArgs{022}, 0, nil),
+				call("fatalf", stub.ExpectArgs{"cannot close setup pipe: %v", []any{stub.UniqueError(13)}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"starting initial program %s", []any{check.MustAbs("/run/current-system/sw/bin/bash")}}, nil, nil),
+				call("start", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent"}, nil, stub.UniqueError(12)),
+				call("fatalf", stub.ExpectArgs{"%v", []any{stub.UniqueError(12)}}, nil, nil),
+			},
+		}, nil},
+
+		{"lowlastcap signaled cancel forward error", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			/* entrypoint */
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, 0, stub.UniqueError(11)),
+				call("verbosef", stub.ExpectArgs{"cannot enable ptrace protection via Yama LSM: %v", []any{stub.UniqueError(11)}}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei/nonexistent"),
+					Path:           check.MustAbs("/run/current-system/sw/bin/bash"),
+					Args:           []string{"bash", "-c", "false"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Nanosecond,
+					Uid:            1 << 24,
+					Gid:            1 << 23,
+					Hostname:       "hakurei-check",
+					Ops:            new(Ops).Bind(check.MustAbs("/"), check.MustAbs("/"), std.BindDevice).Proc(check.MustAbs("/proc/")),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompDisable: true,
+					ParentPerm:     0750,
+				}, 1971, 127, 2, false}, uintptr(0x39)}, stub.UniqueError(10), nil),
+				call("swapVerbose", stub.ExpectArgs{false}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("16777216 1971 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("8388608 127 1\n"), os.FileMode(0)}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
+				call("lastcap", stub.ExpectArgs{}, uintptr(4), nil),
+				call("mount", stub.ExpectArgs{"", "/", "", uintptr(0x8c000), ""}, nil, nil),
+				/* begin early */
+				call("evalSymlinks", stub.ExpectArgs{"/"}, "/", nil),
+				/* end early */
+				call("mount", stub.ExpectArgs{"rootfs", "/proc/self/fd", "tmpfs", uintptr(6), ""}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/proc/self/fd"}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"sysroot", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"sysroot", "sysroot", "", uintptr(0xd000), ""}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"host", os.FileMode(0755)}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{"/proc/self/fd", "host"}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				/* begin apply */
+				call("stat", stub.ExpectArgs{"/host"}, isDirFi(true), nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot", os.FileMode(0700)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"mounting %q flags %#x", []any{"/sysroot", uintptr(0x4001)}}, nil, nil),
+				call("bindMount", stub.ExpectArgs{"/host", "/sysroot", uintptr(0x4001), false}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"%s %s", []any{"mounting", &MountProcOp{Target: check.MustAbs("/proc/")}}}, nil, nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0750)}, nil, nil),
+				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
+				/* end apply */
+				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
+				call("unmount", stub.ExpectArgs{"host", 2}, nil, nil),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, syscall.EINTR),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, nil),
+				call("chdir", stub.ExpectArgs{"/sysroot"}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{".", "."}, nil, nil),
+				call("fchdir", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("unmount", stub.ExpectArgs{".", 2}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				call("close", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("capAmbientClearAll", stub.ExpectArgs{}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x0)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x2)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x3)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x4)}, nil, nil),
+				call("capset", stub.ExpectArgs{&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, new([2]capData)}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{"syscall filter not configured"}}, nil, nil),
+				call("newFile", stub.ExpectArgs{uintptr(0x3a), "extra file 0"}, (*os.File)(nil), nil),
+				call("newFile", stub.ExpectArgs{uintptr(0x3b), "extra file 1"}, (*os.File)(nil), nil),
+				call("umask", stub.ExpectArgs{022}, 0, nil),
+				call("fatalf", stub.ExpectArgs{"cannot close setup pipe: %v", []any{stub.UniqueError(10)}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"starting initial program %s", []any{check.MustAbs("/run/current-system/sw/bin/bash")}}, nil, nil),
+				call("start", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent"}, &os.Process{Pid: 0xbad}, nil),
+				call("New", stub.ExpectArgs{}, nil, nil),
+				call("notify", stub.ExpectArgs{func(c chan<- os.Signal) { c <- CancelSignal }, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP}}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{"forwarding context cancellation"}}, nil, nil),
+				// magicWait4Signal as ret causes wait4 stub to unblock
+				call("signal", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent", os.Interrupt}, magicWait4Signal, stub.UniqueError(9)),
+				call("printf", stub.ExpectArgs{"cannot forward cancellation: %v", []any{stub.UniqueError(9)}}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"initial process exited with signal %s", []any{syscall.Signal(0x4e)}}, nil, nil),
+				call("printf", stub.ExpectArgs{"timeout exceeded waiting for lingering processes", ([]any)(nil)}, nil, nil),
+				call("beforeExit", stub.ExpectArgs{}, nil, nil),
+				call("exit", stub.ExpectArgs{0xce}, nil, nil),
+			},
+
+			/* wait4 */
+			Tracks: []stub.Expect{{Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+
+				// magicWait4Signal as args[4] causes this to block until simulated signal is delivered
+				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xfade01ce), 0, nil, magicWait4Signal}, 0xbad, nil),
+				// this terminates the goroutine at the call, preventing it from leaking while preserving behaviour
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil, stub.PanicExit}, 0, syscall.ECHILD),
+			}}},
+		}, nil},
+
+		{"lowlastcap signaled cancel forward signal", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			/* entrypoint */
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, 0, stub.UniqueError(11)),
+				call("verbosef", stub.ExpectArgs{"cannot enable ptrace protection via Yama LSM: %v", []any{stub.UniqueError(11)}}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei/nonexistent"),
+					Path:           check.MustAbs("/run/current-system/sw/bin/bash"),
+					Args:           []string{"bash", "-c", "false"},
 					ForwardCancel:  true,
-					AdoptWaitDelay: 5 * time.Second,
+					ForwardSignal:  syscall.SIGTERM,
+					AdoptWaitDelay: 5 * time.Nanosecond,
 					Uid:            1 << 24,
 					Gid:            1 << 23,
 					Hostname:       "hakurei-check",
@@ -1895,7 +2971,7 @@ func TestInitEntrypoint(t *testing.T) {
 					SeccompRules:   make([]std.NativeRule, 0),
 					SeccompDisable: true,
 					ParentPerm:     0750,
-				}, 1971, 127, 2, false}, uintptr(0x39)}, stub.UniqueError(13), nil),
+				}, 1971, 127, 2, false}, uintptr(0x39)}, stub.UniqueError(10), nil),
 				call("swapVerbose", stub.ExpectArgs{false}, false, nil),
 				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
 				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
@@ -1905,7 +2981,7 @@ func TestInitEntrypoint(t *testing.T) {
 				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
 				call("umask", stub.ExpectArgs{0}, 022, nil),
 				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
-				call("lastcap", stub.ExpectArgs{}, uintptr(40), nil),
+				call("lastcap", stub.ExpectArgs{}, uintptr(4), nil),
 				call("mount", stub.ExpectArgs{"", "/", "", uintptr(0x8c000), ""}, nil, nil),
 				/* begin early */
 				call("evalSymlinks", stub.ExpectArgs{"/"}, "/", nil),
@@ -1942,61 +3018,46 @@ func TestInitEntrypoint(t *testing.T) {
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x2)}, nil, nil),
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x3)}, nil, nil),
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x4)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x5)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x6)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x7)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x8)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x9)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xa)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xb)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xc)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xd)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xe)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xf)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x10)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x11)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x12)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x13)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x14)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x15)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x16)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x17)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x18)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x19)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1a)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1b)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1c)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1d)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1e)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1f)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x20)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x21)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x22)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x23)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x24)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x25)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x26)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x27)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x28)}, nil, nil),
 				call("capset", stub.ExpectArgs{&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, new([2]capData)}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{"syscall filter not configured"}}, nil, nil),
 				call("newFile", stub.ExpectArgs{uintptr(0x3a), "extra file 0"}, (*os.File)(nil), nil),
 				call("newFile", stub.ExpectArgs{uintptr(0x3b), "extra file 1"}, (*os.File)(nil), nil),
 				call("umask", stub.ExpectArgs{022}, 0, nil),
-				call("fatalf", stub.ExpectArgs{"cannot close setup pipe: %v", []any{stub.UniqueError(13)}}, nil, nil),
+				call("fatalf", stub.ExpectArgs{"cannot close setup pipe: %v", []any{stub.UniqueError(10)}}, nil, nil),
 				call("verbosef", stub.ExpectArgs{"starting initial program %s", []any{check.MustAbs("/run/current-system/sw/bin/bash")}}, nil, nil),
-				call("start", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent"}, nil, stub.UniqueError(12)),
-				call("fatalf", stub.ExpectArgs{"%v", []any{stub.UniqueError(12)}}, nil, nil),
+				call("start", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent"}, &os.Process{Pid: 0xbad}, nil),
+				call("New", stub.ExpectArgs{}, nil, nil),
+				call("notify", stub.ExpectArgs{func(c chan<- os.Signal) { c <- CancelSignal }, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP}}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{"forwarding context cancellation"}}, nil, nil),
+				// magicWait4Signal as ret causes wait4 stub to unblock
+				call("signal", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent", syscall.SIGTERM}, magicWait4Signal, stub.UniqueError(9)),
+				call("printf", stub.ExpectArgs{"cannot forward cancellation: %v", []any{stub.UniqueError(9)}}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"initial process exited with signal %s", []any{syscall.Signal(0x4e)}}, nil, nil),
+				call("printf", stub.ExpectArgs{"timeout exceeded waiting for lingering processes", ([]any)(nil)}, nil, nil),
+				call("beforeExit", stub.ExpectArgs{}, nil, nil),
+				call("exit", stub.ExpectArgs{0xce}, nil, nil),
 			},
+
+			/* wait4 */
+			Tracks: []stub.Expect{{Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+
+				// magicWait4Signal as args[4] causes this to block until simulated signal is delivered
+				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xfade01ce), 0, nil, magicWait4Signal}, 0xbad, nil),
+				// this terminates the goroutine at the call, preventing it from leaking while preserving behaviour
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil, stub.PanicExit}, 0, syscall.ECHILD),
+			}}},
 		}, nil},
 
-		{"lowlastcap signaled cancel forward error", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+		{"lowlastcap signaled cancel passthrough", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
 			/* entrypoint */
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
 				call("getpid", stub.ExpectArgs{}, 1, nil),
-				call("setPtracer", stub.ExpectArgs{uintptr(0)}, 0, stub.UniqueError(11)),
-				call("verbosef", stub.ExpectArgs{"cannot enable ptrace protection via Yama LSM: %v", []any{stub.UniqueError(11)}}, nil, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, 0, stub.UniqueError(8)),
+				call("verbosef", stub.ExpectArgs{"cannot enable ptrace protection via Yama LSM: %v", []any{stub.UniqueError(8)}}, nil, nil),
 				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
 					Dir:            check.MustAbs("/.hakurei/nonexistent"),
 					Path:           check.MustAbs("/run/current-system/sw/bin/bash"),
@@ -2010,7 +3071,7 @@ func TestInitEntrypoint(t *testing.T) {
 					SeccompRules:   make([]std.NativeRule, 0),
 					SeccompDisable: true,
 					ParentPerm:     0750,
-				}, 1971, 127, 2, false}, uintptr(0x39)}, stub.UniqueError(10), nil),
+				}, 1971, 127, 2, false}, uintptr(0x39)}, stub.UniqueError(7), nil),
 				call("swapVerbose", stub.ExpectArgs{false}, false, nil),
 				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
 				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
@@ -2062,21 +3123,21 @@ func TestInitEntrypoint(t *testing.T) {
 				call("newFile", stub.ExpectArgs{uintptr(0x3a), "extra file 0"}, (*os.File)(nil), nil),
 				call("newFile", stub.ExpectArgs{uintptr(0x3b), "extra file 1"}, (*os.File)(nil), nil),
 				call("umask", stub.ExpectArgs{022}, 0, nil),
-				call("fatalf", stub.ExpectArgs{"cannot close setup pipe: %v", []any{stub.UniqueError(10)}}, nil, nil),
+				call("fatalf", stub.ExpectArgs{"cannot close setup pipe: %v", []any{stub.UniqueError(7)}}, nil, nil),
 				call("verbosef", stub.ExpectArgs{"starting initial program %s", []any{check.MustAbs("/run/current-system/sw/bin/bash")}}, nil, nil),
 				call("start", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent"}, &os.Process{Pid: 0xbad}, nil),
 				call("New", stub.ExpectArgs{}, nil, nil),
-				call("notify", stub.ExpectArgs{func(c chan<- os.Signal) { c <- CancelSignal }, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT}}, nil, nil),
-				call("verbose", stub.ExpectArgs{[]any{"forwarding context cancellation"}}, nil, nil),
+				call("notify", stub.ExpectArgs{func(c chan<- os.Signal) { c <- syscall.SIGQUIT }, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"got %s, forwarding to initial process", []any{"quit"}}, nil, nil),
 				// magicWait4Signal as ret causes wait4 stub to unblock
-				call("signal", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent", os.Interrupt}, magicWait4Signal, stub.UniqueError(9)),
-				call("printf", stub.ExpectArgs{"cannot forward cancellation: %v", []any{stub.UniqueError(9)}}, nil, nil),
+				call("signal", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent", syscall.SIGQUIT}, magicWait4Signal, stub.UniqueError(0xfe)),
+				call("printf", stub.ExpectArgs{"cannot forward signal: %v", []any{stub.UniqueError(0xfe)}}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
 				call("verbosef", stub.ExpectArgs{"initial process exited with signal %s", []any{syscall.Signal(0x4e)}}, nil, nil),
-				call("printf", stub.ExpectArgs{"timeout exceeded waiting for lingering processes", ([]any)(nil)}, nil, nil),
+				call("printf", stub.ExpectArgs{"timeout exceeded waiting for lingering processes", []any(nil)}, nil, nil),
 				call("beforeExit", stub.ExpectArgs{}, nil, nil),
-				call("exit", stub.ExpectArgs{0xce}, nil, nil),
+				call("exit", stub.ExpectArgs{206}, nil, nil),
 			},
 
 			/* wait4 */
@@ -2090,7 +3151,7 @@ func TestInitEntrypoint(t *testing.T) {
 			}}},
 		}, nil},
 
-		{"lowlastcap signaled cancel passthrough", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+		{"lowlastcap signaled hangup", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
 			/* entrypoint */
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
@@ -2166,10 +3227,10 @@ func TestInitEntrypoint(t *testing.T) {
 				call("verbosef", stub.ExpectArgs{"starting initial program %s", []any{check.MustAbs("/run/current-system/sw/bin/bash")}}, nil, nil),
 				call("start", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent"}, &os.Process{Pid: 0xbad}, nil),
 				call("New", stub.ExpectArgs{}, nil, nil),
-				call("notify", stub.ExpectArgs{func(c chan<- os.Signal) { c <- syscall.SIGQUIT }, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT}}, nil, nil),
-				call("verbosef", stub.ExpectArgs{"got %s, forwarding to initial process", []any{"quit"}}, nil, nil),
+				call("notify", stub.ExpectArgs{func(c chan<- os.Signal) { c <- syscall.SIGHUP }, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"got %s, forwarding to initial process", []any{"hangup"}}, nil, nil),
 				// magicWait4Signal as ret causes wait4 stub to unblock
-				call("signal", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent", syscall.SIGQUIT}, magicWait4Signal, stub.UniqueError(0xfe)),
+				call("signal", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent", syscall.SIGHUP}, magicWait4Signal, stub.UniqueError(0xfe)),
 				call("printf", stub.ExpectArgs{"cannot forward signal: %v", []any{stub.UniqueError(0xfe)}}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
@@ -2266,7 +3327,7 @@ func TestInitEntrypoint(t *testing.T) {
 				call("verbosef", stub.ExpectArgs{"starting initial program %s", []any{check.MustAbs("/run/current-system/sw/bin/bash")}}, nil, nil),
 				call("start", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent"}, &os.Process{Pid: 0xbad}, nil),
 				call("New", stub.ExpectArgs{}, nil, nil),
-				call("notify", stub.ExpectArgs{func(c chan<- os.Signal) { c <- os.Interrupt }, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT}}, nil, nil),
+				call("notify", stub.ExpectArgs{func(c chan<- os.Signal) { c <- os.Interrupt }, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP}}, nil, nil),
 				call("verbosef", stub.ExpectArgs{"got %s", []any{"interrupt"}}, nil, nil),
 				call("beforeExit", stub.ExpectArgs{}, nil, nil),
 				call("exit", stub.ExpectArgs{0}, nil, nil),
@@ -2357,7 +3418,7 @@ func TestInitEntrypoint(t *testing.T) {
 				call("verbosef", stub.ExpectArgs{"starting initial program %s", []any{check.MustAbs("/run/current-system/sw/bin/bash")}}, nil, nil),
 				call("start", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent"}, &os.Process{Pid: 0xbad}, nil),
 				call("New", stub.ExpectArgs{}, nil, nil),
-				call("notify", stub.ExpectArgs{nil, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT}}, nil, nil),
+				call("notify", stub.ExpectArgs{nil, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP}}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
 				call("verbosef", stub.ExpectArgs{"initial process exited with signal %s", []any{syscall.Signal(0x4e)}}, nil, nil),
@@ -2371,18 +3432,120 @@ func TestInitEntrypoint(t *testing.T) {
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
 
 				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xfade01ce), 0, nil}, 0xbad, nil),
-				// this terminates the goroutine at the call, preventing it from leaking while preserving behaviour
-				call("wait4", stub.ExpectArgs{-1, nil, 0, nil, stub.PanicExit}, 0, syscall.ECHILD),
+				// this terminates the goroutine at the call, preventing it from leaking while preserving behaviour
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil, stub.PanicExit}, 0, syscall.ECHILD),
+			}}},
+		}, nil},
+
+		{"lowlastcap signaled", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			/* entrypoint */
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 1, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, 0, stub.UniqueError(4)),
+				call("verbosef", stub.ExpectArgs{"cannot enable ptrace protection via Yama LSM: %v", []any{stub.UniqueError(4)}}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei/nonexistent"),
+					Path:           check.MustAbs("/run/current-system/sw/bin/bash"),
+					Args:           []string{"bash", "-c", "false"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					Uid:            1 << 24,
+					Gid:            1 << 13,
+					Hostname:       "hakurei-check",
+					Ops:            new(Ops).Bind(check.MustAbs("/"), check.MustAbs("/"), std.BindDevice).Proc(check.MustAbs("/proc/")),
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompDisable: true,
+					ParentPerm:     0750,
+				}, 1971, 127, 2, false}, uintptr(0x39)}, stub.UniqueError(3), nil),
+				call("swapVerbose", stub.ExpectArgs{false}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("16777216 1971 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("8192 127 1\n"), os.FileMode(0)}, nil, nil),
+				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
+				call("lastcap", stub.ExpectArgs{}, uintptr(4), nil),
+				call("mount", stub.ExpectArgs{"", "/", "", uintptr(0x8c000), ""}, nil, nil),
+				/* begin early */
+				call("evalSymlinks", stub.ExpectArgs{"/"}, "/", nil),
+				/* end early */
+				call("mount", stub.ExpectArgs{"rootfs", "/proc/self/fd", "tmpfs", uintptr(6), ""}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/proc/self/fd"}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"sysroot", os.FileMode(0755)}, nil, nil),
+				call("mount", stub.ExpectArgs{"sysroot", "sysroot", "", uintptr(0xd000), ""}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"host", os.FileMode(0755)}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{"/proc/self/fd", "host"}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				/* begin apply */
+				call("stat", stub.ExpectArgs{"/host"}, isDirFi(true), nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot", os.FileMode(0700)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"mounting %q flags %#x", []any{"/sysroot", uintptr(0x4001)}}, nil, nil),
+				call("bindMount", stub.ExpectArgs{"/host", "/sysroot", uintptr(0x4001), false}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"%s %s", []any{"mounting", &MountProcOp{Target: check.MustAbs("/proc/")}}}, nil, nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0750)}, nil, nil),
+				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
+				/* end apply */
+				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
+				call("unmount", stub.ExpectArgs{"host", 2}, nil, nil),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, syscall.EINTR),
+				call("open", stub.ExpectArgs{"/", syscall.O_DIRECTORY | syscall.O_RDONLY, uint32(0)}, math.MaxInt, nil),
+				call("chdir", stub.ExpectArgs{"/sysroot"}, nil, nil),
+				call("pivotRoot", stub.ExpectArgs{".", "."}, nil, nil),
+				call("fchdir", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("unmount", stub.ExpectArgs{".", 2}, nil, nil),
+				call("chdir", stub.ExpectArgs{"/"}, nil, nil),
+				call("close", stub.ExpectArgs{math.MaxInt}, nil, nil),
+				call("capAmbientClearAll", stub.ExpectArgs{}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x0)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x2)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x3)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x4)}, nil, nil),
+				call("capset", stub.ExpectArgs{&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, new([2]capData)}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{"syscall filter not configured"}}, nil, nil),
+				call("newFile", stub.ExpectArgs{uintptr(0x3a), "extra file 0"}, (*os.File)(nil), nil),
+				call("newFile", stub.ExpectArgs{uintptr(0x3b), "extra file 1"}, (*os.File)(nil), nil),
+				call("umask", stub.ExpectArgs{022}, 0, nil),
+				call("fatalf", stub.ExpectArgs{"cannot close setup pipe: %v", []any{stub.UniqueError(3)}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"starting initial program %s", []any{check.MustAbs("/run/current-system/sw/bin/bash")}}, nil, nil),
+				call("start", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent"}, &os.Process{Pid: 0xbad}, nil),
+				call("New", stub.ExpectArgs{}, nil, nil),
+				call("notify", stub.ExpectArgs{nil, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP}}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"initial process exited with signal %s", []any{syscall.Signal(0x4e)}}, nil, nil),
+				call("beforeExit", stub.ExpectArgs{}, nil, nil),
+				call("exit", stub.ExpectArgs{0xce}, nil, nil),
+			},
+
+			/* wait4 */
+			Tracks: []stub.Expect{{Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
+				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xdeaf), 0, nil}, 0xbabe, nil),
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
+				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xfade01ce), 0, nil}, 0xbad, nil),
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
+				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xdeaf), 0, nil}, 0xbabe, nil),
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.ENOMEM),
+				call("printf", stub.ExpectArgs{"unexpected wait4 response: %v", []any{syscall.ENOMEM}}, nil, nil),
 			}}},
 		}, nil},
 
-		{"lowlastcap signaled", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+		{"strangewait nopriv notty noseccomp yamafault", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
 			/* entrypoint */
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
 				call("getpid", stub.ExpectArgs{}, 1, nil),
-				call("setPtracer", stub.ExpectArgs{uintptr(0)}, 0, stub.UniqueError(4)),
-				call("verbosef", stub.ExpectArgs{"cannot enable ptrace protection via Yama LSM: %v", []any{stub.UniqueError(4)}}, nil, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, 0, stub.UniqueError(2)),
+				call("verbosef", stub.ExpectArgs{"cannot enable ptrace protection via Yama LSM: %v", []any{stub.UniqueError(2)}}, nil, nil),
 				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
 					Dir:            check.MustAbs("/.hakurei/nonexistent"),
 					Path:           check.MustAbs("/run/current-system/sw/bin/bash"),
@@ -2390,23 +3553,23 @@ func TestInitEntrypoint(t *testing.T) {
 					ForwardCancel:  true,
 					AdoptWaitDelay: 5 * time.Second,
 					Uid:            1 << 24,
-					Gid:            1 << 13,
+					Gid:            1 << 23,
 					Hostname:       "hakurei-check",
 					Ops:            new(Ops).Bind(check.MustAbs("/"), check.MustAbs("/"), std.BindDevice).Proc(check.MustAbs("/proc/")),
 					SeccompRules:   make([]std.NativeRule, 0),
 					SeccompDisable: true,
 					ParentPerm:     0750,
-				}, 1971, 127, 2, false}, uintptr(0x39)}, stub.UniqueError(3), nil),
+				}, 1971, 127, 2, false}, uintptr(0x39)}, stub.UniqueError(1), nil),
 				call("swapVerbose", stub.ExpectArgs{false}, false, nil),
 				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
 				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
 				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("16777216 1971 1\n"), os.FileMode(0)}, nil, nil),
 				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
-				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("8192 127 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("8388608 127 1\n"), os.FileMode(0)}, nil, nil),
 				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
 				call("umask", stub.ExpectArgs{0}, 022, nil),
 				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
-				call("lastcap", stub.ExpectArgs{}, uintptr(4), nil),
+				call("lastcap", stub.ExpectArgs{}, uintptr(40), nil),
 				call("mount", stub.ExpectArgs{"", "/", "", uintptr(0x8c000), ""}, nil, nil),
 				/* begin early */
 				call("evalSymlinks", stub.ExpectArgs{"/"}, "/", nil),
@@ -2443,21 +3606,57 @@ func TestInitEntrypoint(t *testing.T) {
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x2)}, nil, nil),
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x3)}, nil, nil),
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x4)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x5)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x6)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x7)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x8)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x9)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xa)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xb)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xc)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xd)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xe)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xf)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x10)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x11)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x12)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x13)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x14)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x15)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x16)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x17)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x18)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x19)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1a)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1b)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1c)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1d)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1e)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1f)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x20)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x21)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x22)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x23)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x24)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x25)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x26)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x27)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x28)}, nil, nil),
 				call("capset", stub.ExpectArgs{&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, new([2]capData)}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{"syscall filter not configured"}}, nil, nil),
 				call("newFile", stub.ExpectArgs{uintptr(0x3a), "extra file 0"}, (*os.File)(nil), nil),
 				call("newFile", stub.ExpectArgs{uintptr(0x3b), "extra file 1"}, (*os.File)(nil), nil),
 				call("umask", stub.ExpectArgs{022}, 0, nil),
-				call("fatalf", stub.ExpectArgs{"cannot close setup pipe: %v", []any{stub.UniqueError(3)}}, nil, nil),
+				call("fatalf", stub.ExpectArgs{"cannot close setup pipe: %v", []any{stub.UniqueError(1)}}, nil, nil),
 				call("verbosef", stub.ExpectArgs{"starting initial program %s", []any{check.MustAbs("/run/current-system/sw/bin/bash")}}, nil, nil),
 				call("start", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent"}, &os.Process{Pid: 0xbad}, nil),
 				call("New", stub.ExpectArgs{}, nil, nil),
-				call("notify", stub.ExpectArgs{nil, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT}}, nil, nil),
+				call("notify", stub.ExpectArgs{nil, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP}}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
-				call("verbosef", stub.ExpectArgs{"initial process exited with signal %s", []any{syscall.Signal(0x4e)}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"initial process exited with code %d", []any{1}}, nil, nil),
 				call("beforeExit", stub.ExpectArgs{}, nil, nil),
-				call("exit", stub.ExpectArgs{0xce}, nil, nil),
+				call("exit", stub.ExpectArgs{1}, nil, nil),
 			},
 
 			/* wait4 */
@@ -2470,7 +3669,7 @@ func TestInitEntrypoint(t *testing.T) {
 				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
 				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
 				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
-				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xfade01ce), 0, nil}, 0xbad, nil),
+				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xfade0100), 0, nil}, 0xbad, nil),
 				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
 				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xdeaf), 0, nil}, 0xbabe, nil),
 				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.ENOMEM),
@@ -2478,33 +3677,34 @@ func TestInitEntrypoint(t *testing.T) {
 			}}},
 		}, nil},
 
-		{"strangewait nopriv notty noseccomp yamafault", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+		{"success", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
 			/* entrypoint */
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
 				call("getpid", stub.ExpectArgs{}, 1, nil),
-				call("setPtracer", stub.ExpectArgs{uintptr(0)}, 0, stub.UniqueError(2)),
-				call("verbosef", stub.ExpectArgs{"cannot enable ptrace protection via Yama LSM: %v", []any{stub.UniqueError(2)}}, nil, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
 				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
-					Dir:            check.MustAbs("/.hakurei/nonexistent"),
-					Path:           check.MustAbs("/run/current-system/sw/bin/bash"),
-					Args:           []string{"bash", "-c", "false"},
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
 					ForwardCancel:  true,
 					AdoptWaitDelay: 5 * time.Second,
-					Uid:            1 << 24,
-					Gid:            1 << 23,
+					Uid:            1 << 16,
+					Gid:            1 << 15,
 					Hostname:       "hakurei-check",
 					Ops:            new(Ops).Bind(check.MustAbs("/"), check.MustAbs("/"), std.BindDevice).Proc(check.MustAbs("/proc/")),
 					SeccompRules:   make([]std.NativeRule, 0),
-					SeccompDisable: true,
-					ParentPerm:     0750,
-				}, 1971, 127, 2, false}, uintptr(0x39)}, stub.UniqueError(1), nil),
-				call("swapVerbose", stub.ExpectArgs{false}, false, nil),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(0), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
 				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
 				call("setDumpable", stub.ExpectArgs{uintptr(1)}, nil, nil),
-				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("16777216 1971 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/uid_map", []byte("65536 1000 1\n"), os.FileMode(0)}, nil, nil),
 				call("writeFile", stub.ExpectArgs{"/proc/self/setgroups", []byte("deny\n"), os.FileMode(0)}, nil, nil),
-				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("8388608 127 1\n"), os.FileMode(0)}, nil, nil),
+				call("writeFile", stub.ExpectArgs{"/proc/self/gid_map", []byte("32768 100 1\n"), os.FileMode(0)}, nil, nil),
 				call("setDumpable", stub.ExpectArgs{uintptr(0)}, nil, nil),
 				call("umask", stub.ExpectArgs{0}, 022, nil),
 				call("sethostname", stub.ExpectArgs{[]byte("hakurei-check")}, nil, nil),
@@ -2526,7 +3726,7 @@ func TestInitEntrypoint(t *testing.T) {
 				call("verbosef", stub.ExpectArgs{"mounting %q flags %#x", []any{"/sysroot", uintptr(0x4001)}}, nil, nil),
 				call("bindMount", stub.ExpectArgs{"/host", "/sysroot", uintptr(0x4001), false}, nil, nil),
 				call("verbosef", stub.ExpectArgs{"%s %s", []any{"mounting", &MountProcOp{Target: check.MustAbs("/proc/")}}}, nil, nil),
-				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0750)}, nil, nil),
+				call("mkdirAll", stub.ExpectArgs{"/sysroot/proc", os.FileMode(0755)}, nil, nil),
 				call("mount", stub.ExpectArgs{"proc", "/sysroot/proc", "proc", uintptr(0xe), ""}, nil, nil),
 				/* end apply */
 				call("mount", stub.ExpectArgs{"host", "host", "", uintptr(0x4c000), ""}, nil, nil),
@@ -2561,7 +3761,6 @@ func TestInitEntrypoint(t *testing.T) {
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x12)}, nil, nil),
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x13)}, nil, nil),
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x14)}, nil, nil),
-				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x15)}, nil, nil),
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x16)}, nil, nil),
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x17)}, nil, nil),
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x18)}, nil, nil),
@@ -2581,21 +3780,26 @@ func TestInitEntrypoint(t *testing.T) {
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x26)}, nil, nil),
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x27)}, nil, nil),
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x28)}, nil, nil),
-				call("capset", stub.ExpectArgs{&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, new([2]capData)}, nil, nil),
-				call("verbose", stub.ExpectArgs{[]any{"syscall filter not configured"}}, nil, nil),
-				call("newFile", stub.ExpectArgs{uintptr(0x3a), "extra file 0"}, (*os.File)(nil), nil),
-				call("newFile", stub.ExpectArgs{uintptr(0x3b), "extra file 1"}, (*os.File)(nil), nil),
+				call("capAmbientRaise", stub.ExpectArgs{uintptr(0x15)}, nil, nil),
+				call("capset", stub.ExpectArgs{&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, &[2]capData{{0, 0x200000, 0x200000}, {0, 0, 0}}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"resolving presets %s", []any{std.FilterPreset(0xf)}}, nil, nil),
+				call("seccompLoad", stub.ExpectArgs{seccomp.Preset(0xf, 0), seccomp.ExportFlag(0), []seccomp.Arch(nil)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"%d filter rules loaded", []any{73}}, nil, nil),
+				call("newFile", stub.ExpectArgs{uintptr(10), "extra file 0"}, (*os.File)(nil), nil),
+				call("newFile", stub.ExpectArgs{uintptr(11), "extra file 1"}, (*os.File)(nil), nil),
+				call("newFile", stub.ExpectArgs{uintptr(12), "extra file 2"}, (*os.File)(nil), nil),
 				call("umask", stub.ExpectArgs{022}, 0, nil),
-				call("fatalf", stub.ExpectArgs{"cannot close setup pipe: %v", []any{stub.UniqueError(1)}}, nil, nil),
-				call("verbosef", stub.ExpectArgs{"starting initial program %s", []any{check.MustAbs("/run/current-system/sw/bin/bash")}}, nil, nil),
-				call("start", stub.ExpectArgs{"/run/current-system/sw/bin/bash", []string{"bash", "-c", "false"}, ([]string)(nil), "/.hakurei/nonexistent"}, &os.Process{Pid: 0xbad}, nil),
+				call("fatalf", stub.ExpectArgs{"cannot close setup pipe: %v", []any{stub.UniqueError(0)}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"starting initial program %s", []any{check.MustAbs("/bin/zsh")}}, nil, nil),
+				call("start", stub.ExpectArgs{"/bin/zsh", []string{"zsh", "-c", "exec vim"}, []string{"DISPLAY=:0"}, "/.hakurei"}, &os.Process{Pid: 0xcafe}, nil),
 				call("New", stub.ExpectArgs{}, nil, nil),
-				call("notify", stub.ExpectArgs{nil, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT}}, nil, nil),
+				call("notify", stub.ExpectArgs{nil, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP}}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
-				call("verbosef", stub.ExpectArgs{"initial process exited with code %d", []any{1}}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"initial process exited with status %#x", []any{syscall.WaitStatus(0xfade007f)}}, nil, nil),
 				call("beforeExit", stub.ExpectArgs{}, nil, nil),
-				call("exit", stub.ExpectArgs{1}, nil, nil),
+				call("exit", stub.ExpectArgs{0xff}, nil, nil),
 			},
 
 			/* wait4 */
@@ -2608,15 +3812,125 @@ func TestInitEntrypoint(t *testing.T) {
 				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
 				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
 				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
-				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xfade0100), 0, nil}, 0xbad, nil),
+				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xfade007f), 0, nil}, 0xcafe, nil),
 				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
 				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xdeaf), 0, nil}, 0xbabe, nil),
-				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.ENOMEM),
-				call("printf", stub.ExpectArgs{"unexpected wait4 response: %v", []any{syscall.ENOMEM}}, nil, nil),
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.ECHILD),
 			}}},
 		}, nil},
 
-		{"success", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+		{"success entered", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
+			/* entrypoint */
+			Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+				call("getpid", stub.ExpectArgs{}, 0xbeef, nil),
+				call("entered", stub.ExpectArgs{}, true, nil),
+				call("setPtracer", stub.ExpectArgs{uintptr(0)}, nil, nil),
+				call("receive", stub.ExpectArgs{"HAKUREI_SETUP", new(initParams), new(uintptr), &initParams{Params{
+					Dir:            check.MustAbs("/.hakurei"),
+					Env:            []string{"DISPLAY=:0"},
+					Path:           check.MustAbs("/bin/zsh"),
+					Args:           []string{"zsh", "-c", "exec vim"},
+					ForwardCancel:  true,
+					AdoptWaitDelay: 5 * time.Second,
+					Uid:            1 << 16,
+					Gid:            1 << 15,
+					Hostname:       "hakurei-check",
+					SeccompRules:   make([]std.NativeRule, 0),
+					SeccompPresets: std.PresetStrict,
+					RetainSession:  true,
+					Privileged:     true,
+				}, 1000, 100, 3, true}, uintptr(9)}, stub.UniqueError(0), nil),
+				call("swapVerbose", stub.ExpectArgs{true}, false, nil),
+				call("verbose", stub.ExpectArgs{[]any{"received setup parameters"}}, nil, nil),
+				call("umask", stub.ExpectArgs{0}, 022, nil),
+				call("lastcap", stub.ExpectArgs{}, uintptr(40), nil),
+				call("landlockGetABI", stub.ExpectArgs{}, 6, nil),
+				call("landlockCreateRuleset", stub.ExpectArgs{&RulesetAttr{Scoped: LANDLOCK_SCOPE_ABSTRACT_UNIX_SOCKET | LANDLOCK_SCOPE_SIGNAL}}, 5, nil),
+				call("verbosef", stub.ExpectArgs{"enforcing landlock ruleset %s", []any{&RulesetAttr{Scoped: LANDLOCK_SCOPE_ABSTRACT_UNIX_SOCKET | LANDLOCK_SCOPE_SIGNAL}}}, nil, nil),
+				call("landlockRestrictSelf", stub.ExpectArgs{5}, nil, nil),
+				call("close", stub.ExpectArgs{5}, nil, nil),
+				call("capAmbientClearAll", stub.ExpectArgs{}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x0)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x2)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x3)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x4)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x5)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x6)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x7)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x8)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x9)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xa)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xb)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xc)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xd)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xe)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0xf)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x10)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x11)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x12)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x13)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x14)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x16)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x17)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x18)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x19)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1a)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1b)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1c)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1d)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1e)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x1f)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x20)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x21)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x22)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x23)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x24)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x25)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x26)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x27)}, nil, nil),
+				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x28)}, nil, nil),
+				call("capAmbientRaise", stub.ExpectArgs{uintptr(0x15)}, nil, nil),
+				call("capset", stub.ExpectArgs{&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, &[2]capData{{0, 0x200000, 0x200000}, {0, 0, 0}}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"resolving presets %s", []any{std.FilterPreset(0xf)}}, nil, nil),
+				call("seccompLoad", stub.ExpectArgs{seccomp.Preset(0xf, 0), seccomp.ExportFlag(0), []seccomp.Arch(nil)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"%d filter rules loaded", []any{73}}, nil, nil),
+				call("newFile", stub.ExpectArgs{uintptr(10), "extra file 0"}, (*os.File)(nil), nil),
+				call("newFile", stub.ExpectArgs{uintptr(11), "extra file 1"}, (*os.File)(nil), nil),
+				call("newFile", stub.ExpectArgs{uintptr(12), "extra file 2"}, (*os.File)(nil), nil),
+				call("umask", stub.ExpectArgs{022}, 0, nil),
+				call("fatalf", stub.ExpectArgs{"cannot close setup pipe: %v", []any{stub.UniqueError(0)}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"starting initial program %s", []any{check.MustAbs("/bin/zsh")}}, nil, nil),
+				call("start", stub.ExpectArgs{"/bin/zsh", []string{"zsh", "-c", "exec vim"}, []string{"DISPLAY=:0"}, "/.hakurei"}, &os.Process{Pid: 0xcafe}, nil),
+				call("New", stub.ExpectArgs{}, nil, nil),
+				call("notify", stub.ExpectArgs{nil, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP}}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"initial process exited with status %#x", []any{syscall.WaitStatus(0xfade007f)}}, nil, nil),
+				call("beforeExit", stub.ExpectArgs{}, nil, nil),
+				call("exit", stub.ExpectArgs{0xff}, nil, nil),
+			},
+
+			/* wait4 */
+			Tracks: []stub.Expect{{Calls: []stub.Call{
+				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
+
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
+				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xdeaf), 0, nil}, 0xbabe, nil),
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
+				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xfade007f), 0, nil}, 0xcafe, nil),
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.EINTR),
+				call("wait4", stub.ExpectArgs{-1, syscall.WaitStatus(0xdeaf), 0, nil}, 0xbabe, nil),
+				call("wait4", stub.ExpectArgs{-1, nil, 0, nil}, 0, syscall.ECHILD),
+			}}},
+		}, nil},
+
+		{"success reap", func(k *kstub) error { initEntrypoint(k, k); return nil }, stub.Expect{
 			/* entrypoint */
 			Calls: []stub.Call{
 				call("lockOSThread", stub.ExpectArgs{}, nil, nil),
@@ -2629,6 +3943,7 @@ func TestInitEntrypoint(t *testing.T) {
 					Args:           []string{"zsh", "-c", "exec vim"},
 					ForwardCancel:  true,
 					AdoptWaitDelay: 5 * time.Second,
+					ReapSignal:     syscall.SIGTERM,
 					Uid:            1 << 16,
 					Gid:            1 << 15,
 					Hostname:       "hakurei-check",
@@ -2721,22 +4036,25 @@ func TestInitEntrypoint(t *testing.T) {
 				call("capBoundingSetDrop", stub.ExpectArgs{uintptr(0x28)}, nil, nil),
 				call("capAmbientRaise", stub.ExpectArgs{uintptr(0x15)}, nil, nil),
 				call("capset", stub.ExpectArgs{&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, &[2]capData{{0, 0x200000, 0x200000}, {0, 0, 0}}}, nil, nil),
-				call("verbosef", stub.ExpectArgs{"resolving presets %#x", []any{std.FilterPreset(0xf)}}, nil, nil),
-				call("seccompLoad", stub.ExpectArgs{seccomp.Preset(0xf, 0), seccomp.ExportFlag(0)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"resolving presets %s", []any{std.FilterPreset(0xf)}}, nil, nil),
+				call("seccompLoad", stub.ExpectArgs{seccomp.Preset(0xf, 0), seccomp.ExportFlag(0), []seccomp.Arch(nil)}, nil, nil),
 				call("verbosef", stub.ExpectArgs{"%d filter rules loaded", []any{73}}, nil, nil),
 				call("newFile", stub.ExpectArgs{uintptr(10), "extra file 0"}, (*os.File)(nil), nil),
 				call("newFile", stub.ExpectArgs{uintptr(11), "extra file 1"}, (*os.File)(nil), nil),
 				call("newFile", stub.ExpectArgs{uintptr(12), "extra file 2"}, (*os.File)(nil), nil),
 				call("umask", stub.ExpectArgs{022}, 0, nil),
 				call("fatalf", stub.ExpectArgs{"cannot close setup pipe: %v", []any{stub.UniqueError(0)}}, nil, nil),
+				call("setChildSubreaper", stub.ExpectArgs{}, nil, nil),
 				call("verbosef", stub.ExpectArgs{"starting initial program %s", []any{check.MustAbs("/bin/zsh")}}, nil, nil),
 				call("start", stub.ExpectArgs{"/bin/zsh", []string{"zsh", "-c", "exec vim"}, []string{"DISPLAY=:0"}, "/.hakurei"}, &os.Process{Pid: 0xcafe}, nil),
 				call("New", stub.ExpectArgs{}, nil, nil),
-				call("notify", stub.ExpectArgs{nil, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT}}, nil, nil),
+				call("notify", stub.ExpectArgs{nil, []os.Signal{CancelSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP}}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
 				call("verbose", stub.ExpectArgs{[]any{os.ErrInvalid.Error()}}, nil, nil),
 				call("verbosef", stub.ExpectArgs{"initial process exited with status %#x", []any{syscall.WaitStatus(0xfade007f)}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"delivering %s to lingering processes", []any{syscall.SIGTERM}}, nil, nil),
+				call("kill", stub.ExpectArgs{-1, syscall.SIGTERM}, nil, syscall.ESRCH),
 				call("beforeExit", stub.ExpectArgs{}, nil, nil),
 				call("exit", stub.ExpectArgs{0xff}, nil, nil),
 			},
@@ -2760,6 +4078,176 @@ func TestInitEntrypoint(t *testing.T) {
 	})
 }
 
+func TestCheckInitialProgram(t *testing.T) {
+	t.Parallel()
+
+	checkSimple(t, "checkInitialProgram", []simpleTestCase{
+		{"stat", func(k *kstub) error {
+			return checkInitialProgram(k, check.MustAbs("/run/current-system/sw/bin/bash"))
+		}, stub.Expect{Calls: []stub.Call{
+			call("stat", stub.ExpectArgs{"/run/current-system/sw/bin/bash"}, modeFi(0), stub.UniqueError(1)),
+		}}, &StartError{true, "initial program not found in container", stub.UniqueError(1), false, false, StartErrExec}},
+
+		{"directory", func(k *kstub) error {
+			return checkInitialProgram(k, check.MustAbs("/run/current-system/sw/bin"))
+		}, stub.Expect{Calls: []stub.Call{
+			call("stat", stub.ExpectArgs{"/run/current-system/sw/bin"}, modeFi(fs.ModeDir|0755), nil),
+		}}, &StartError{true, "initial program \"/run/current-system/sw/bin\" not executable in container", syscall.EISDIR, false, false, StartErrExec}},
+
+		{"not executable", func(k *kstub) error {
+			return checkInitialProgram(k, check.MustAbs("/etc/passwd"))
+		}, stub.Expect{Calls: []stub.Call{
+			call("stat", stub.ExpectArgs{"/etc/passwd"}, modeFi(0644), nil),
+		}}, &StartError{true, "initial program \"/etc/passwd\" not executable in container", syscall.EACCES, false, false, StartErrExec}},
+
+		{"success", func(k *kstub) error {
+			return checkInitialProgram(k, check.MustAbs("/run/current-system/sw/bin/bash"))
+		}, stub.Expect{Calls: []stub.Call{
+			call("stat", stub.ExpectArgs{"/run/current-system/sw/bin/bash"}, modeFi(0555), nil),
+		}}, nil},
+	})
+}
+
+func TestLandlockRestrictFS(t *testing.T) {
+	t.Parallel()
+
+	const readExec = LANDLOCK_ACCESS_FS_EXECUTE | LANDLOCK_ACCESS_FS_READ_FILE | LANDLOCK_ACCESS_FS_READ_DIR
+	rules := []LandlockPathRule{
+		{check.MustAbs("/"), readExec | LANDLOCK_ACCESS_FS_IOCTL_DEV},
+		{check.MustAbs("/dev"), LANDLOCK_ACCESS_FS_IOCTL_DEV},
+		{check.MustAbs("/tmp"), _LANDLOCK_ACCESS_FS_DELIM - 1},
+	}
+	// LANDLOCK_ACCESS_FS_IOCTL_DEV is not handled by ABI version 3
+	attr := &RulesetAttr{HandledAccessFS: LANDLOCK_ACCESS_FS_IOCTL_DEV - 1}
+
+	checkSimple(t, "landlockRestrictFS", []simpleTestCase{
+		{"abi", func(k *kstub) error {
+			return landlockRestrictFS(k, k, rules, 3)
+		}, stub.Expect{Calls: []stub.Call{
+			call("landlockGetABI", stub.ExpectArgs{}, -1, syscall.EOPNOTSUPP),
+			call("verbosef", stub.ExpectArgs{"cannot get landlock ABI, filesystem rules not enforced: %v", []any{syscall.EOPNOTSUPP}}, nil, nil),
+		}}, nil},
+
+		{"create", func(k *kstub) error {
+			return landlockRestrictFS(k, k, rules, 3)
+		}, stub.Expect{Calls: []stub.Call{
+			call("landlockGetABI", stub.ExpectArgs{}, 3, nil),
+			call("landlockCreateRuleset", stub.ExpectArgs{attr}, -1, syscall.E2BIG),
+		}}, os.NewSyscallError("landlock_create_ruleset", syscall.E2BIG)},
+
+		{"open", func(k *kstub) error {
+			return landlockRestrictFS(k, k, rules, 3)
+		}, stub.Expect{Calls: []stub.Call{
+			call("landlockGetABI", stub.ExpectArgs{}, 3, nil),
+			call("landlockCreateRuleset", stub.ExpectArgs{attr}, 5, nil),
+			call("open", stub.ExpectArgs{"/", O_PATH | syscall.O_CLOEXEC, uint32(0)}, -1, stub.UniqueError(2)),
+			call("close", stub.ExpectArgs{5}, nil, stub.UniqueError(1)),
+			call("verbosef", stub.ExpectArgs{"cannot close landlock ruleset: %v", []any{stub.UniqueError(1)}}, nil, nil),
+		}}, &os.PathError{Op: "open", Path: "/", Err: stub.UniqueError(2)}},
+
+		{"add", func(k *kstub) error {
+			return landlockRestrictFS(k, k, rules, 3)
+		}, stub.Expect{Calls: []stub.Call{
+			call("landlockGetABI", stub.ExpectArgs{}, 3, nil),
+			call("landlockCreateRuleset", stub.ExpectArgs{attr}, 5, nil),
+			call("open", stub.ExpectArgs{"/", O_PATH | syscall.O_CLOEXEC, uint32(0)}, 6, nil),
+			call("landlockAddPathBeneath", stub.ExpectArgs{5, readExec, 6}, nil, stub.UniqueError(0)),
+			call("close", stub.ExpectArgs{6}, nil, nil),
+			call("close", stub.ExpectArgs{5}, nil, nil),
+		}}, &os.PathError{Op: "landlock_add_rule", Path: "/", Err: stub.UniqueError(0)}},
+
+		{"restrict", func(k *kstub) error {
+			return landlockRestrictFS(k, k, rules[:1], 3)
+		}, stub.Expect{Calls: []stub.Call{
+			call("landlockGetABI", stub.ExpectArgs{}, 3, nil),
+			call("landlockCreateRuleset", stub.ExpectArgs{attr}, 5, nil),
+			call("open", stub.ExpectArgs{"/", O_PATH | syscall.O_CLOEXEC, uint32(0)}, 6, nil),
+			call("landlockAddPathBeneath", stub.ExpectArgs{5, readExec, 6}, nil, nil),
+			call("close", stub.ExpectArgs{6}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"enforcing landlock ruleset %s", []any{attr}}, nil, nil),
+			call("landlockRestrictSelf", stub.ExpectArgs{5}, nil, syscall.EPERM),
+			call("close", stub.ExpectArgs{5}, nil, nil),
+		}}, os.NewSyscallError("landlock_restrict_self", syscall.EPERM)},
+
+		{"success", func(k *kstub) error {
+			return landlockRestrictFS(k, k, rules, 3)
+		}, stub.Expect{Calls: []stub.Call{
+			call("landlockGetABI", stub.ExpectArgs{}, 3, nil),
+			call("landlockCreateRuleset", stub.ExpectArgs{attr}, 5, nil),
+			call("open", stub.ExpectArgs{"/", O_PATH | syscall.O_CLOEXEC, uint32(0)}, 6, nil),
+			call("landlockAddPathBeneath", stub.ExpectArgs{5, readExec, 6}, nil, nil),
+			call("close", stub.ExpectArgs{6}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"landlock rule on %q allows no handled action, skipping", []any{check.MustAbs("/dev")}}, nil, nil),
+			call("open", stub.ExpectArgs{"/tmp", O_PATH | syscall.O_CLOEXEC, uint32(0)}, 7, nil),
+			call("landlockAddPathBeneath", stub.ExpectArgs{5, LANDLOCK_ACCESS_FS_IOCTL_DEV - 1, 7}, nil, nil),
+			call("close", stub.ExpectArgs{7}, nil, stub.UniqueError(3)),
+			call("verbosef", stub.ExpectArgs{"cannot close %q: %v", []any{check.MustAbs("/tmp"), stub.UniqueError(3)}}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"enforcing landlock ruleset %s", []any{attr}}, nil, nil),
+			call("landlockRestrictSelf", stub.ExpectArgs{5}, nil, nil),
+			call("close", stub.ExpectArgs{5}, nil, nil),
+		}}, nil},
+	})
+}
+
+func TestLandlockRestrictScope(t *testing.T) {
+	t.Parallel()
+
+	attr := &RulesetAttr{Scoped: LANDLOCK_SCOPE_ABSTRACT_UNIX_SOCKET | LANDLOCK_SCOPE_SIGNAL}
+	checkSimple(t, "landlockRestrictScope", []simpleTestCase{
+		{"abi", func(k *kstub) error {
+			return landlockRestrictScope(k, k, false, 3)
+		}, stub.Expect{Calls: []stub.Call{
+			call("landlockGetABI", stub.ExpectArgs{}, -1, syscall.EOPNOTSUPP),
+		}}, syscall.EOPNOTSUPP},
+
+		{"abi host abstract", func(k *kstub) error {
+			return landlockRestrictScope(k, k, true, 3)
+		}, stub.Expect{Calls: []stub.Call{
+			call("landlockGetABI", stub.ExpectArgs{}, -1, syscall.EOPNOTSUPP),
+		}}, nil},
+
+		{"old", func(k *kstub) error {
+			return landlockRestrictScope(k, k, false, 3)
+		}, stub.Expect{Calls: []stub.Call{
+			call("landlockGetABI", stub.ExpectArgs{}, 5, nil),
+		}}, syscall.ENOSYS},
+
+		{"old host abstract", func(k *kstub) error {
+			return landlockRestrictScope(k, k, true, 3)
+		}, stub.Expect{Calls: []stub.Call{
+			call("landlockGetABI", stub.ExpectArgs{}, 5, nil),
+		}}, nil},
+
+		{"create", func(k *kstub) error {
+			return landlockRestrictScope(k, k, false, 3)
+		}, stub.Expect{Calls: []stub.Call{
+			call("landlockGetABI", stub.ExpectArgs{}, 6, nil),
+			call("landlockCreateRuleset", stub.ExpectArgs{attr}, -1, syscall.E2BIG),
+		}}, os.NewSyscallError("landlock_create_ruleset", syscall.E2BIG)},
+
+		{"restrict", func(k *kstub) error {
+			return landlockRestrictScope(k, k, false, 3)
+		}, stub.Expect{Calls: []stub.Call{
+			call("landlockGetABI", stub.ExpectArgs{}, 6, nil),
+			call("landlockCreateRuleset", stub.ExpectArgs{attr}, 5, nil),
+			call("verbosef", stub.ExpectArgs{"enforcing landlock ruleset %s", []any{attr}}, nil, nil),
+			call("landlockRestrictSelf", stub.ExpectArgs{5}, nil, syscall.EPERM),
+			call("close", stub.ExpectArgs{5}, nil, stub.UniqueError(0)),
+			call("verbosef", stub.ExpectArgs{"cannot close landlock ruleset: %v", []any{stub.UniqueError(0)}}, nil, nil),
+		}}, os.NewSyscallError("landlock_restrict_self", syscall.EPERM)},
+
+		{"success host abstract", func(k *kstub) error {
+			return landlockRestrictScope(k, k, true, 3)
+		}, stub.Expect{Calls: []stub.Call{
+			call("landlockGetABI", stub.ExpectArgs{}, 7, nil),
+			call("landlockCreateRuleset", stub.ExpectArgs{&RulesetAttr{Scoped: LANDLOCK_SCOPE_SIGNAL}}, 5, nil),
+			call("verbosef", stub.ExpectArgs{"enforcing landlock ruleset %s", []any{&RulesetAttr{Scoped: LANDLOCK_SCOPE_SIGNAL}}}, nil, nil),
+			call("landlockRestrictSelf", stub.ExpectArgs{5}, nil, nil),
+			call("close", stub.ExpectArgs{5}, nil, nil),
+		}}, nil},
+	})
+}
+
 func TestOpsGrow(t *testing.T) {
 	t.Parallel()
 	ops := new(Ops)
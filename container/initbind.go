@@ -75,13 +75,20 @@ func (b *BindMountOp) apply(state *setupState, k syscallDispatcher) error {
 		return err
 	}
 
-	var flags uintptr = syscall.MS_REC
+	var flags uintptr
+	if b.Flags&std.BindNoRecursive == 0 {
+		// read-only is applied to every submount by the remount pass of bindMount
+		flags |= syscall.MS_REC
+	}
 	if b.Flags&std.BindWritable == 0 {
 		flags |= syscall.MS_RDONLY
 	}
 	if b.Flags&std.BindDevice == 0 {
 		flags |= syscall.MS_NODEV
 	}
+	if b.Flags&std.BindNoExec != 0 {
+		flags |= syscall.MS_NOEXEC
+	}
 
 	if b.sourceFinal.String() == b.Target.String() {
 		state.Verbosef("mounting %q flags %#x", target, flags)
@@ -89,6 +89,32 @@ func TestBindMountOp(t *testing.T) {
 			call("bindMount", stub.ExpectArgs{"/host/dev/null", "/sysroot/dev/null", uintptr(0x4001), false}, nil, nil),
 		}, nil},
 
+		{"success non-recursive", new(Params), &BindMountOp{
+			Source: check.MustAbs("/nix/store"),
+			Target: check.MustAbs("/nix/store"),
+			Flags:  std.BindNoRecursive,
+		}, []stub.Call{
+			call("evalSymlinks", stub.ExpectArgs{"/nix/store"}, "/nix/store", nil),
+		}, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{"/host/nix/store"}, isDirFi(true), nil),
+			call("mkdirAll", stub.ExpectArgs{"/sysroot/nix/store", os.FileMode(0700)}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"mounting %q flags %#x", []any{"/sysroot/nix/store", uintptr(0x5)}}, nil, nil),
+			call("bindMount", stub.ExpectArgs{"/host/nix/store", "/sysroot/nix/store", uintptr(0x5), false}, nil, nil),
+		}, nil},
+
+		{"success noexec", new(Params), &BindMountOp{
+			Source: check.MustAbs("/var/cache/shared"),
+			Target: check.MustAbs("/var/cache"),
+			Flags:  std.BindWritable | std.BindNoExec,
+		}, []stub.Call{
+			call("evalSymlinks", stub.ExpectArgs{"/var/cache/shared"}, "/var/cache/shared", nil),
+		}, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{"/host/var/cache/shared"}, isDirFi(true), nil),
+			call("mkdirAll", stub.ExpectArgs{"/sysroot/var/cache", os.FileMode(0700)}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"mounting %q on %q flags %#x", []any{"/host/var/cache/shared", "/sysroot/var/cache", uintptr(0x400c)}}, nil, nil),
+			call("bindMount", stub.ExpectArgs{"/host/var/cache/shared", "/sysroot/var/cache", uintptr(0x400c), false}, nil, nil),
+		}, nil},
+
 		{"success device", new(Params), &BindMountOp{
 			Source: check.MustAbs("/dev/null"),
 			Target: check.MustAbs("/dev/null"),
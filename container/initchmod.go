@@ -0,0 +1,48 @@
+package container
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+
+	"hakurei.app/container/check"
+)
+
+func init() { gob.Register(new(ChmodOp)) }
+
+// Chmod appends an [Op] that changes the mode of an existing file in the container filesystem.
+func (f *Ops) Chmod(name *check.Absolute, mode os.FileMode) *Ops {
+	*f = append(*f, &ChmodOp{name, mode})
+	return f
+}
+
+// ChmodOp changes the mode of the existing file at container Path to Mode.
+// This is usually appended after ops mounting or creating Path.
+type ChmodOp struct {
+	Path *check.Absolute
+	Mode os.FileMode
+}
+
+func (c *ChmodOp) Valid() bool                                { return c != nil && c.Path != nil }
+func (c *ChmodOp) early(*setupState, syscallDispatcher) error { return nil }
+func (c *ChmodOp) apply(_ *setupState, k syscallDispatcher) error {
+	if err := k.chmod(toSysroot(c.Path.String()), c.Mode); err != nil {
+		// report pathname in the container filesystem
+		var pathError *os.PathError
+		if errors.As(err, &pathError) {
+			return &os.PathError{Op: "chmod", Path: c.Path.String(), Err: pathError.Err}
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *ChmodOp) Is(op Op) bool {
+	vc, ok := op.(*ChmodOp)
+	return ok && c.Valid() && vc.Valid() &&
+		c.Path.Is(vc.Path) &&
+		c.Mode == vc.Mode
+}
+func (*ChmodOp) prefix() (string, bool) { return "changing", true }
+func (c *ChmodOp) String() string       { return fmt.Sprintf("mode of %q to %s", c.Path, c.Mode) }
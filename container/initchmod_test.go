@@ -0,0 +1,63 @@
+package container
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"hakurei.app/container/check"
+	"hakurei.app/container/stub"
+)
+
+func TestChmodOp(t *testing.T) {
+	t.Parallel()
+
+	checkOpBehaviour(t, []opBehaviourTestCase{
+		{"nonexistent", new(Params), &ChmodOp{
+			Path: check.MustAbs("/etc/nonexistent"),
+			Mode: 0700,
+		}, nil, nil, []stub.Call{
+			call("chmod", stub.ExpectArgs{"/sysroot/etc/nonexistent", os.FileMode(0700)}, nil, &os.PathError{Op: "chmod", Path: "/sysroot/etc/nonexistent", Err: syscall.ENOENT}),
+		}, &os.PathError{Op: "chmod", Path: "/etc/nonexistent", Err: syscall.ENOENT}},
+
+		{"chmod", new(Params), &ChmodOp{
+			Path: check.MustAbs("/etc/hostname"),
+			Mode: 0444,
+		}, nil, nil, []stub.Call{
+			call("chmod", stub.ExpectArgs{"/sysroot/etc/hostname", os.FileMode(0444)}, nil, stub.UniqueError(0)),
+		}, stub.UniqueError(0)},
+
+		{"success", new(Params), &ChmodOp{
+			Path: check.MustAbs("/var/lib/app"),
+			Mode: 01777,
+		}, nil, nil, []stub.Call{
+			call("chmod", stub.ExpectArgs{"/sysroot/var/lib/app", os.FileMode(01777)}, nil, nil),
+		}, nil},
+	})
+
+	checkOpsValid(t, []opValidTestCase{
+		{"nil", (*ChmodOp)(nil), false},
+		{"zero", new(ChmodOp), false},
+		{"valid", &ChmodOp{Path: check.MustAbs("/etc/hostname")}, true},
+	})
+
+	checkOpsBuilder(t, []opsBuilderTestCase{
+		{"hostname", new(Ops).Chmod(check.MustAbs("/etc/hostname"), 0444), Ops{
+			&ChmodOp{Path: check.MustAbs("/etc/hostname"), Mode: 0444},
+		}},
+	})
+
+	checkOpIs(t, []opIsTestCase{
+		{"zero", new(ChmodOp), new(ChmodOp), false},
+		{"path differs", &ChmodOp{Path: check.MustAbs("/"), Mode: 0755}, &ChmodOp{Path: check.MustAbs("/etc/"), Mode: 0755}, false},
+		{"mode differs", &ChmodOp{Path: check.MustAbs("/")}, &ChmodOp{Path: check.MustAbs("/"), Mode: 0755}, false},
+		{"equals", &ChmodOp{Path: check.MustAbs("/"), Mode: 0755}, &ChmodOp{Path: check.MustAbs("/"), Mode: 0755}, true},
+	})
+
+	checkOpMeta(t, []opMetaTestCase{
+		{"hostname", &ChmodOp{
+			Path: check.MustAbs("/etc/hostname"),
+			Mode: 0444,
+		}, "changing", `mode of "/etc/hostname" to -r--r--r--`},
+	})
+}
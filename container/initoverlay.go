@@ -4,7 +4,9 @@ import (
 	"encoding/gob"
 	"fmt"
 	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 
 	"hakurei.app/container/check"
 	"hakurei.app/container/fhs"
@@ -30,6 +32,9 @@ const (
 	OverlayReadonlyLower
 	// OverlayEmptyLower is set when [MountOverlayOp.Lower] has length of zero.
 	OverlayEmptyLower
+	// OverlayCrossDevice is set when [MountOverlayOp.Upper] and [MountOverlayOp.Work]
+	// do not reside on the same filesystem.
+	OverlayCrossDevice
 )
 
 // OverlayArgumentError is returned for [MountOverlayOp] supplied with invalid argument.
@@ -49,12 +54,16 @@ func (e *OverlayArgumentError) Error() string {
 	case OverlayEmptyLower:
 		return "overlay requires at least one lowerdir"
 
+	case OverlayCrossDevice:
+		return fmt.Sprintf("workdir is not on the same filesystem as upperdir %q", e.Value)
+
 	default:
 		return fmt.Sprintf("invalid overlay argument error %#x", e.Type)
 	}
 }
 
 // Overlay appends an [Op] that mounts the overlay pseudo filesystem on [MountOverlayOp.Target].
+// The state and work directories must reside on the same filesystem, and at least one layer is required.
 func (f *Ops) Overlay(target, state, work *check.Absolute, layers ...*check.Absolute) *Ops {
 	*f = append(*f, &MountOverlayOp{
 		Target: target,
@@ -128,6 +137,9 @@ func (o *MountOverlayOp) early(_ *setupState, k syscallDispatcher) error {
 		}
 	}
 	// readonly handled in apply
+	if o.Upper != nil && len(o.Lower) == 0 {
+		return &OverlayArgumentError{OverlayEmptyLower, zeroString}
+	}
 
 	if !o.ephemeral {
 		if o.Upper != o.Work && (o.Upper == nil || o.Work == nil) {
@@ -136,19 +148,28 @@ func (o *MountOverlayOp) early(_ *setupState, k syscallDispatcher) error {
 		}
 
 		if o.Upper != nil {
+			var upper, work string
 			if v, err := k.evalSymlinks(o.Upper.String()); err != nil {
 				return err
 			} else {
+				upper = v
 				o.upper = check.EscapeOverlayDataSegment(toHost(v))
 			}
-		}
-
-		if o.Work != nil {
 			if v, err := k.evalSymlinks(o.Work.String()); err != nil {
 				return err
 			} else {
+				work = v
 				o.work = check.EscapeOverlayDataSegment(toHost(v))
 			}
+
+			// the kernel rejects this with a non-descriptive EINVAL
+			if upperDev, err := statDev(k, upper); err != nil {
+				return err
+			} else if workDev, err := statDev(k, work); err != nil {
+				return err
+			} else if upperDev != workDev {
+				return &OverlayArgumentError{OverlayCrossDevice, upper}
+			}
 		}
 	}
 
@@ -191,9 +212,6 @@ func (o *MountOverlayOp) apply(state *setupState, k syscallDispatcher) error {
 		}
 		// "upperdir=" and "workdir=" may be omitted. In that case the overlay will be read-only
 	} else {
-		if len(o.Lower) == 0 {
-			return &OverlayArgumentError{OverlayEmptyLower, zeroString}
-		}
 		options = append(options,
 			OptionOverlayUpperdir+"="+o.upper,
 			OptionOverlayWorkdir+"="+o.work)
@@ -202,7 +220,21 @@ func (o *MountOverlayOp) apply(state *setupState, k syscallDispatcher) error {
 		OptionOverlayLowerdir+"="+strings.Join(o.lower, check.SpecialOverlayPath),
 		OptionOverlayUserxattr)
 
-	return k.mount(SourceOverlay, target, FstypeOverlay, 0, strings.Join(options, check.SpecialOverlayOption))
+	if err := k.mount(SourceOverlay, target, FstypeOverlay, 0, strings.Join(options, check.SpecialOverlayOption)); err != nil {
+		return &StartError{Step: "mount overlay on " + strconv.Quote(o.Target.String()), Err: optionalErrorUnwrap(err), Kind: StartErrMount}
+	}
+	return nil
+}
+
+// statDev returns the device number of the filesystem containing pathname.
+func statDev(k syscallDispatcher, pathname string) (uint64, error) {
+	if fi, err := k.stat(pathname); err != nil {
+		return 0, err
+	} else if st, ok := fi.Sys().(*syscall.Stat_t); !ok {
+		return 0, OpStateError("overlay")
+	} else {
+		return st.Dev, nil
+	}
 }
 
 func (o *MountOverlayOp) Is(op Op) bool {
@@ -3,6 +3,7 @@ package container
 import (
 	"errors"
 	"os"
+	"syscall"
 	"testing"
 
 	"hakurei.app/container/check"
@@ -29,6 +30,9 @@ func TestMountOverlayOp(t *testing.T) {
 			{"lower short", &OverlayArgumentError{OverlayEmptyLower, zeroString},
 				"overlay requires at least one lowerdir"},
 
+			{"cross device", &OverlayArgumentError{OverlayCrossDevice, "/mnt-root/nix/.rw-store/.upper"},
+				`workdir is not on the same filesystem as upperdir "/mnt-root/nix/.rw-store/.upper"`},
+
 			{"oob", &OverlayArgumentError{0xdeadbeef, zeroString},
 				"invalid overlay argument error 0xdeadbeef"},
 		}
@@ -158,12 +162,12 @@ func TestMountOverlayOp(t *testing.T) {
 			Target: check.MustAbs("/nix/store"),
 			Upper:  check.MustAbs("/mnt-root/nix/.rw-store/upper"),
 			Work:   check.MustAbs("/mnt-root/nix/.rw-store/work"),
-		}, []stub.Call{
-			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/upper"}, "/mnt-root/nix/.rw-store/.upper", nil),
-			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/work"}, "/mnt-root/nix/.rw-store/.work", nil),
-		}, nil, []stub.Call{
-			call("mkdirAll", stub.ExpectArgs{"/sysroot/nix/store", os.FileMode(0700)}, nil, nil),
-		}, &OverlayArgumentError{OverlayEmptyLower, zeroString}},
+		}, nil, &OverlayArgumentError{OverlayEmptyLower, zeroString}, nil, nil},
+
+		{"nil lower ephemeral", &Params{ParentPerm: 0700}, &MountOverlayOp{
+			Target: check.MustAbs("/nix/store"),
+			Upper:  check.MustAbs("/"),
+		}, nil, &OverlayArgumentError{OverlayEmptyLower, zeroString}, nil, nil},
 
 		{"evalSymlinks upper", &Params{ParentPerm: 0700}, &MountOverlayOp{
 			Target: check.MustAbs("/nix/store"),
@@ -184,6 +188,41 @@ func TestMountOverlayOp(t *testing.T) {
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/work"}, "/mnt-root/nix/.rw-store/.work", stub.UniqueError(3)),
 		}, stub.UniqueError(3), nil, nil},
 
+		{"stat upper", &Params{ParentPerm: 0700}, &MountOverlayOp{
+			Target: check.MustAbs("/nix/store"),
+			Lower:  []*check.Absolute{check.MustAbs("/mnt-root/nix/.ro-store")},
+			Upper:  check.MustAbs("/mnt-root/nix/.rw-store/upper"),
+			Work:   check.MustAbs("/mnt-root/nix/.rw-store/work"),
+		}, []stub.Call{
+			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/upper"}, "/mnt-root/nix/.rw-store/.upper", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/work"}, "/mnt-root/nix/.rw-store/.work", nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.upper"}, devFi(0), stub.UniqueError(9)),
+		}, stub.UniqueError(9), nil, nil},
+
+		{"stat work", &Params{ParentPerm: 0700}, &MountOverlayOp{
+			Target: check.MustAbs("/nix/store"),
+			Lower:  []*check.Absolute{check.MustAbs("/mnt-root/nix/.ro-store")},
+			Upper:  check.MustAbs("/mnt-root/nix/.rw-store/upper"),
+			Work:   check.MustAbs("/mnt-root/nix/.rw-store/work"),
+		}, []stub.Call{
+			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/upper"}, "/mnt-root/nix/.rw-store/.upper", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/work"}, "/mnt-root/nix/.rw-store/.work", nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.upper"}, devFi(0xfd00), nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.work"}, devFi(0), stub.UniqueError(8)),
+		}, stub.UniqueError(8), nil, nil},
+
+		{"cross device", &Params{ParentPerm: 0700}, &MountOverlayOp{
+			Target: check.MustAbs("/nix/store"),
+			Lower:  []*check.Absolute{check.MustAbs("/mnt-root/nix/.ro-store")},
+			Upper:  check.MustAbs("/mnt-root/nix/.rw-store/upper"),
+			Work:   check.MustAbs("/tmp/work"),
+		}, []stub.Call{
+			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/upper"}, "/mnt-root/nix/.rw-store/.upper", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/tmp/work"}, "/tmp/work", nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.upper"}, devFi(0xfd00), nil),
+			call("stat", stub.ExpectArgs{"/tmp/work"}, devFi(0x2a), nil),
+		}, &OverlayArgumentError{OverlayCrossDevice, "/mnt-root/nix/.rw-store/.upper"}, nil, nil},
+
 		{"evalSymlinks lower", &Params{ParentPerm: 0700}, &MountOverlayOp{
 			Target: check.MustAbs("/nix/store"),
 			Lower:  []*check.Absolute{check.MustAbs("/mnt-root/nix/.ro-store")},
@@ -192,6 +231,8 @@ func TestMountOverlayOp(t *testing.T) {
 		}, []stub.Call{
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/upper"}, "/mnt-root/nix/.rw-store/.upper", nil),
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/work"}, "/mnt-root/nix/.rw-store/.work", nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.upper"}, devFi(0xfd00), nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.work"}, devFi(0xfd00), nil),
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.ro-store"}, "/mnt-root/nix/ro-store", stub.UniqueError(2)),
 		}, stub.UniqueError(2), nil, nil},
 
@@ -203,6 +244,8 @@ func TestMountOverlayOp(t *testing.T) {
 		}, []stub.Call{
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/upper"}, "/mnt-root/nix/.rw-store/.upper", nil),
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/work"}, "/mnt-root/nix/.rw-store/.work", nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.upper"}, devFi(0xfd00), nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.work"}, devFi(0xfd00), nil),
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.ro-store"}, "/mnt-root/nix/ro-store", nil),
 		}, nil, []stub.Call{
 			call("mkdirAll", stub.ExpectArgs{"/sysroot/nix/store", os.FileMode(0700)}, nil, stub.UniqueError(1)),
@@ -216,11 +259,19 @@ func TestMountOverlayOp(t *testing.T) {
 		}, []stub.Call{
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/upper"}, "/mnt-root/nix/.rw-store/.upper", nil),
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/work"}, "/mnt-root/nix/.rw-store/.work", nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.upper"}, devFi(0xfd00), nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.work"}, devFi(0xfd00), nil),
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.ro-store"}, "/mnt-root/nix/ro-store", nil),
 		}, nil, []stub.Call{
 			call("mkdirAll", stub.ExpectArgs{"/sysroot/nix/store", os.FileMode(0700)}, nil, nil),
-			call("mount", stub.ExpectArgs{"overlay", "/sysroot/nix/store", "overlay", uintptr(0), "upperdir=/host/mnt-root/nix/.rw-store/.upper,workdir=/host/mnt-root/nix/.rw-store/.work,lowerdir=/host/mnt-root/nix/ro-store,userxattr"}, nil, stub.UniqueError(0)),
-		}, stub.UniqueError(0)},
+			call("mount", stub.ExpectArgs{"overlay", "/sysroot/nix/store", "overlay", uintptr(0), "upperdir=/host/mnt-root/nix/.rw-store/.upper,workdir=/host/mnt-root/nix/.rw-store/.work,lowerdir=/host/mnt-root/nix/ro-store,userxattr"}, nil, &MountError{
+				Source: SourceOverlay,
+				Target: "/sysroot/nix/store",
+				Fstype: FstypeOverlay,
+				Data:   "upperdir=/host/mnt-root/nix/.rw-store/.upper,workdir=/host/mnt-root/nix/.rw-store/.work,lowerdir=/host/mnt-root/nix/ro-store,userxattr",
+				Errno:  syscall.EPERM,
+			}),
+		}, &StartError{Step: `mount overlay on "/nix/store"`, Err: syscall.EPERM, Kind: StartErrMount}},
 
 		{"success single layer", &Params{ParentPerm: 0700}, &MountOverlayOp{
 			Target: check.MustAbs("/nix/store"),
@@ -230,6 +281,8 @@ func TestMountOverlayOp(t *testing.T) {
 		}, []stub.Call{
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/upper"}, "/mnt-root/nix/.rw-store/.upper", nil),
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/work"}, "/mnt-root/nix/.rw-store/.work", nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.upper"}, devFi(0xfd00), nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.work"}, devFi(0xfd00), nil),
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.ro-store"}, "/mnt-root/nix/ro-store", nil),
 		}, nil, []stub.Call{
 			call("mkdirAll", stub.ExpectArgs{"/sysroot/nix/store", os.FileMode(0700)}, nil, nil),
@@ -254,6 +307,8 @@ func TestMountOverlayOp(t *testing.T) {
 		}, []stub.Call{
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/upper"}, "/mnt-root/nix/.rw-store/.upper", nil),
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.rw-store/work"}, "/mnt-root/nix/.rw-store/.work", nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.upper"}, devFi(0xfd00), nil),
+			call("stat", stub.ExpectArgs{"/mnt-root/nix/.rw-store/.work"}, devFi(0xfd00), nil),
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.ro-store"}, "/mnt-root/nix/ro-store", nil),
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.ro-store0"}, "/mnt-root/nix/ro-store0", nil),
 			call("evalSymlinks", stub.ExpectArgs{"/mnt-root/nix/.ro-store1"}, "/mnt-root/nix/ro-store1", nil),
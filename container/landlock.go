@@ -1,11 +1,16 @@
 package container
 
 import (
+	"errors"
+	"os"
 	"strings"
 	"syscall"
+	"time"
 	"unsafe"
 
+	"hakurei.app/container/check"
 	"hakurei.app/container/std"
+	"hakurei.app/message"
 )
 
 // include/uapi/linux/landlock.h
@@ -14,6 +19,11 @@ const (
 	LANDLOCK_CREATE_RULESET_VERSION = 1 << iota
 )
 
+const (
+	LANDLOCK_RULE_PATH_BENEATH = 1 + iota
+	LANDLOCK_RULE_NET_PORT
+)
+
 // LandlockAccessFS is bitmask of handled filesystem actions.
 type LandlockAccessFS uint64
 
@@ -230,6 +240,27 @@ func (rulesetAttr *RulesetAttr) Create(flags uintptr) (fd int, err error) {
 	return fd, nil
 }
 
+// pathBeneathAttr is equivalent to struct landlock_path_beneath_attr.
+type pathBeneathAttr struct {
+	// Bitmask of allowed actions for this file hierarchy.
+	AllowedAccess LandlockAccessFS
+	// File descriptor, preferably opened with O_PATH, which identifies the parent directory
+	// of a file hierarchy, or just a file.
+	ParentFd int32
+}
+
+// LandlockAddPathBeneath adds a rule allowing access beneath the file hierarchy identified by parentFd.
+func LandlockAddPathBeneath(rulesetFd int, access LandlockAccessFS, parentFd int) error {
+	attr := pathBeneathAttr{access, int32(parentFd)}
+	r, _, errno := syscall.Syscall6(std.SYS_LANDLOCK_ADD_RULE,
+		uintptr(rulesetFd), LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&attr)),
+		0, 0, 0)
+	if r != 0 {
+		return errno
+	}
+	return nil
+}
+
 func LandlockGetABI() (int, error) {
 	return (*RulesetAttr)(nil).Create(LANDLOCK_CREATE_RULESET_VERSION)
 }
@@ -241,3 +272,147 @@ func LandlockRestrictSelf(rulesetFd int, flags uintptr) error {
 	}
 	return nil
 }
+
+// landlockRetry calls f up to attempts times for as long as it fails with a transient error.
+func landlockRetry(attempts int, f func() error) error {
+	for i := 1; ; i++ {
+		err := f()
+		if err == nil || i >= attempts ||
+			!(errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR)) {
+			return err
+		}
+		time.Sleep(time.Duration(i) * time.Millisecond)
+	}
+}
+
+// LandlockPathRule allows access beneath a file hierarchy in the container.
+type LandlockPathRule struct {
+	// Pathname of the file hierarchy, in the container.
+	Path *check.Absolute
+	// Bitmask of allowed actions beneath Path.
+	Access LandlockAccessFS
+}
+
+// landlockAccessFSSupported returns filesystem actions handled by landlock ABI version abi.
+func landlockAccessFSSupported(abi int) LandlockAccessFS {
+	switch {
+	case abi < 1:
+		return 0
+	case abi < 2:
+		return LANDLOCK_ACCESS_FS_REFER - 1
+	case abi < 3:
+		return LANDLOCK_ACCESS_FS_TRUNCATE - 1
+	case abi < 5:
+		return LANDLOCK_ACCESS_FS_IOCTL_DEV - 1
+	default:
+		return _LANDLOCK_ACCESS_FS_DELIM - 1
+	}
+}
+
+// landlockRestrictFS enforces a landlock ruleset handling every filesystem action supported
+// by the kernel, so only actions allowed by rules remain possible. Filesystem rules are
+// skipped if landlock is unavailable.
+func landlockRestrictFS(k syscallDispatcher, msg message.Msg, rules []LandlockPathRule, attempts int) error {
+	abi, err := k.landlockGetABI()
+	if err != nil {
+		msg.Verbosef("cannot get landlock ABI, filesystem rules not enforced: %v", err)
+		return nil
+	}
+	handled := landlockAccessFSSupported(abi)
+	if handled == 0 {
+		msg.Verbosef("landlock ABI version %d does not handle filesystem access", abi)
+		return nil
+	}
+
+	var rulesetFd int
+	rulesetAttr := &RulesetAttr{HandledAccessFS: handled}
+	if err = landlockRetry(attempts, func() (err error) {
+		rulesetFd, err = k.landlockCreateRuleset(rulesetAttr)
+		return
+	}); err != nil {
+		return os.NewSyscallError("landlock_create_ruleset", err)
+	}
+
+	if err = landlockAddPathRules(k, msg, rulesetFd, handled, rules); err == nil {
+		msg.Verbosef("enforcing landlock ruleset %s", rulesetAttr)
+		if err = landlockRetry(attempts, func() error {
+			return k.landlockRestrictSelf(rulesetFd)
+		}); err != nil {
+			err = os.NewSyscallError("landlock_restrict_self", err)
+		}
+	}
+	if closeErr := k.close(rulesetFd); closeErr != nil {
+		msg.Verbosef("cannot close landlock ruleset: %v", closeErr)
+		// not fatal
+	}
+	return err
+}
+
+// landlockRestrictScope enforces a landlock ruleset scoping signals and, unless hostAbstract
+// is true, abstract unix sockets, like the ruleset enforced by [Container.Start].
+func landlockRestrictScope(k syscallDispatcher, msg message.Msg, hostAbstract bool, attempts int) error {
+	rulesetAttr := &RulesetAttr{Scoped: LANDLOCK_SCOPE_SIGNAL}
+	if !hostAbstract {
+		rulesetAttr.Scoped |= LANDLOCK_SCOPE_ABSTRACT_UNIX_SOCKET
+	}
+
+	if abi, err := k.landlockGetABI(); err != nil {
+		if hostAbstract {
+			// resources are already covered by namespaces, see Start
+			return nil
+		}
+		return err
+	} else if abi < 6 {
+		if hostAbstract {
+			return nil
+		}
+		return syscall.ENOSYS
+	}
+
+	var rulesetFd int
+	if err := landlockRetry(attempts, func() (err error) {
+		rulesetFd, err = k.landlockCreateRuleset(rulesetAttr)
+		return
+	}); err != nil {
+		return os.NewSyscallError("landlock_create_ruleset", err)
+	}
+
+	msg.Verbosef("enforcing landlock ruleset %s", rulesetAttr)
+	var err error
+	if err = landlockRetry(attempts, func() error {
+		return k.landlockRestrictSelf(rulesetFd)
+	}); err != nil {
+		err = os.NewSyscallError("landlock_restrict_self", err)
+	}
+	if closeErr := k.close(rulesetFd); closeErr != nil {
+		msg.Verbosef("cannot close landlock ruleset: %v", closeErr)
+		// not fatal
+	}
+	return err
+}
+
+// landlockAddPathRules adds rules to the ruleset referred to by rulesetFd.
+func landlockAddPathRules(k syscallDispatcher, msg message.Msg, rulesetFd int, handled LandlockAccessFS, rules []LandlockPathRule) error {
+	for _, rule := range rules {
+		// actions not handled by the kernel are always allowed
+		access := rule.Access & handled
+		if access == 0 {
+			msg.Verbosef("landlock rule on %q allows no handled action, skipping", rule.Path)
+			continue
+		}
+
+		fd, err := k.open(rule.Path.String(), O_PATH|syscall.O_CLOEXEC, 0)
+		if err != nil {
+			return &os.PathError{Op: "open", Path: rule.Path.String(), Err: err}
+		}
+		err = k.landlockAddPathBeneath(rulesetFd, access, fd)
+		if closeErr := k.close(fd); closeErr != nil {
+			msg.Verbosef("cannot close %q: %v", rule.Path, closeErr)
+			// not fatal
+		}
+		if err != nil {
+			return &os.PathError{Op: "landlock_add_rule", Path: rule.Path.String(), Err: err}
+		}
+	}
+	return nil
+}
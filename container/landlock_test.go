@@ -1,6 +1,8 @@
 package container_test
 
 import (
+	"errors"
+	"syscall"
 	"testing"
 	"unsafe"
 
@@ -63,3 +65,37 @@ func TestLandlockAttrSize(t *testing.T) {
 		t.Errorf("Sizeof: %d, want %d", got, want)
 	}
 }
+
+func TestLandlockRetry(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		attempts int
+		errs     []error
+		want     error
+		wantN    int
+	}{
+		{"success", 3, []error{nil}, nil, 1},
+		{"transient", 3, []error{syscall.EAGAIN, syscall.EINTR, nil}, nil, 3},
+		{"exhausted", 2, []error{syscall.EAGAIN, syscall.EAGAIN, nil}, syscall.EAGAIN, 2},
+		{"permanent", 3, []error{syscall.EINVAL, nil}, syscall.EINVAL, 1},
+		{"single", 1, []error{syscall.EINTR, nil}, syscall.EINTR, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var n int
+			if err := container.LandlockRetry(tc.attempts, func() error {
+				n++
+				return tc.errs[n-1]
+			}); !errors.Is(err, tc.want) {
+				t.Errorf("LandlockRetry: error = %v, want %v", err, tc.want)
+			}
+			if n != tc.wantN {
+				t.Errorf("LandlockRetry: %d attempts, want %d", n, tc.wantN)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package container
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"hakurei.app/container/fhs"
+	"hakurei.app/message"
+)
+
+// ProcInfo describes a process in the container pid namespace.
+type ProcInfo struct {
+	// Process id in the container pid namespace.
+	Pid int
+	// Command name of the process, as reported by /proc/pid/comm.
+	Comm string
+}
+
+func (p ProcInfo) String() string { return strconv.Itoa(p.Pid) + " (" + p.Comm + ")" }
+
+// lingeringProcesses returns processes other than init present in the container pid namespace,
+// ordered by pid. This requires procfs to be mounted on /proc in the container.
+func lingeringProcesses(k syscallDispatcher, msg message.Msg) []ProcInfo {
+	entries, err := k.readdir(fhs.Proc)
+	if err != nil {
+		msg.Verbosef("cannot enumerate lingering processes: %v", err)
+		return nil
+	}
+
+	var procs []ProcInfo
+	for _, ent := range entries {
+		pid, err := strconv.Atoi(ent.Name())
+		if err != nil || pid == 1 {
+			continue
+		}
+
+		var comm string
+		if data, err := k.readFile(fhs.Proc + ent.Name() + "/comm"); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// process exited after readdir
+				continue
+			}
+			msg.Verbosef("cannot read command name of process %d: %v", pid, err)
+		} else {
+			comm = strings.TrimSuffix(string(data), "\n")
+		}
+		procs = append(procs, ProcInfo{pid, comm})
+	}
+	slices.SortFunc(procs, func(a, b ProcInfo) int { return a.Pid - b.Pid })
+	return procs
+}
@@ -0,0 +1,61 @@
+package container
+
+import (
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"hakurei.app/container/stub"
+)
+
+func TestLingeringProcesses(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		calls []stub.Call
+		want  []ProcInfo
+	}{
+		{"readdir", []stub.Call{
+			call("readdir", stub.ExpectArgs{"/proc/"}, stubDir(), stub.UniqueError(0)),
+			call("verbosef", stub.ExpectArgs{"cannot enumerate lingering processes: %v", []any{stub.UniqueError(0)}}, nil, nil),
+		}, nil},
+
+		{"none", []stub.Call{
+			call("readdir", stub.ExpectArgs{"/proc/"}, stubDir("1", "acpi", "self", "sys", "thread-self", "uptime"), nil),
+		}, nil},
+
+		{"success", []stub.Call{
+			call("readdir", stub.ExpectArgs{"/proc/"}, stubDir("1", "10", "2", "3", "9", "self", "sys"), nil),
+			call("readFile", stub.ExpectArgs{"/proc/10/comm"}, []byte("pipewire\n"), nil),
+			call("readFile", stub.ExpectArgs{"/proc/2/comm"}, []byte("dbus-daemon\n"), nil),
+			call("readFile", stub.ExpectArgs{"/proc/3/comm"}, []byte(nil), &os.PathError{Op: "open", Path: "/proc/3/comm", Err: syscall.ENOENT}),
+			call("readFile", stub.ExpectArgs{"/proc/9/comm"}, []byte(nil), stub.UniqueError(1)),
+			call("verbosef", stub.ExpectArgs{"cannot read command name of process %d: %v", []any{9, stub.UniqueError(1)}}, nil, nil),
+		}, []ProcInfo{{2, "dbus-daemon"}, {9, ""}, {10, "pipewire"}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			k := &kstub{nil, stub.New(t, func(s *stub.Stub[syscallDispatcher]) syscallDispatcher { return &kstub{nil, s} }, stub.Expect{Calls: tc.calls})}
+			defer stub.HandleExit(t)
+			if got := lingeringProcesses(k, k); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("lingeringProcesses: %v, want %v", got, tc.want)
+			}
+			k.VisitIncomplete(func(s *stub.Stub[syscallDispatcher]) {
+				t.Helper()
+				t.Errorf("lingeringProcesses: %d calls, want %d", s.Pos(), s.Len())
+			})
+		})
+	}
+}
+
+func TestProcInfoString(t *testing.T) {
+	t.Parallel()
+	want := "2 (dbus-daemon)"
+	if got := (ProcInfo{2, "dbus-daemon"}).String(); got != want {
+		t.Errorf("String: %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+package container
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// logFileKeepDefault is the number of rotated log files kept if LogKeep is not positive.
+const logFileKeepDefault = 1
+
+/*
+logFile is an [io.WriteCloser] appending to a file, which is rotated once a write would grow it
+beyond a size limit. The file is renamed with the suffix ".1" on rotation, and existing rotated
+files have their suffix incremented, up to a number of files beyond which the oldest is removed.
+
+A write is never split across files, so a single write larger than the size limit is written
+to a fresh file in its entirety, and rotated away by the next write.
+*/
+type logFile struct {
+	// pathname of the current file
+	name string
+	// size limit of the current file, zero disables rotation
+	max int64
+	// number of rotated files kept
+	keep int
+
+	f    *os.File
+	size int64
+	mu   sync.Mutex
+}
+
+// openLogFile opens or creates the log file at name for appending.
+func openLogFile(name string, max int64, keep int) (*logFile, error) {
+	if keep <= 0 {
+		keep = logFileKeepDefault
+	}
+	w := &logFile{name: name, max: max, keep: keep}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// open opens the current file and stores its size.
+func (w *logFile) open() error {
+	f, err := os.OpenFile(w.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	var fi os.FileInfo
+	if fi, err = f.Stat(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.f, w.size = f, fi.Size()
+	return nil
+}
+
+func (w *logFile) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	if w.max > 0 && w.size > 0 && w.size+int64(len(p)) > w.max {
+		if err = w.rotate(); err != nil {
+			return
+		}
+	}
+	n, err = w.f.Write(p)
+	w.size += int64(n)
+	return
+}
+
+// rotate shifts rotated files by one and replaces the current file with an empty one.
+func (w *logFile) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	w.f = nil
+
+	if err := os.Remove(w.rotated(w.keep)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for i := w.keep - 1; i > 0; i-- {
+		if err := os.Rename(w.rotated(i), w.rotated(i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(w.name, w.rotated(1)); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// rotated returns the pathname of the rotated file at index i.
+func (w *logFile) rotated(i int) string { return w.name + "." + strconv.Itoa(i) }
+
+func (w *logFile) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// openLogFiles opens StdoutFile and StderrFile for standard streams not otherwise set up.
+func (p *Container) openLogFiles() error {
+	var stdout *logFile
+	if p.cmd.Stdout == nil && p.StdoutFile != nil {
+		if w, err := openLogFile(p.StdoutFile.String(), p.LogMaxSize, p.LogKeep); err != nil {
+			return &StartError{false, "open standard output log file", err, false, false, StartErrSetup}
+		} else {
+			stdout = w
+			p.logFiles = append(p.logFiles, w)
+			p.cmd.Stdout = w
+		}
+	}
+	if p.cmd.Stderr == nil && p.StderrFile != nil {
+		if stdout != nil && p.StderrFile.Is(p.StdoutFile) {
+			// rotated as a whole
+			p.cmd.Stderr = stdout
+		} else if w, err := openLogFile(p.StderrFile.String(), p.LogMaxSize, p.LogKeep); err != nil {
+			p.closeLogFiles()
+			return &StartError{false, "open standard error log file", err, false, false, StartErrSetup}
+		} else {
+			p.logFiles = append(p.logFiles, w)
+			p.cmd.Stderr = w
+		}
+	}
+	return nil
+}
+
+// closeLogFiles closes all log files opened by openLogFiles.
+func (p *Container) closeLogFiles() {
+	for _, w := range p.logFiles {
+		if err := w.Close(); err != nil {
+			p.msg.Verbosef("cannot close log file: %v", err)
+		}
+	}
+	p.logFiles = nil
+}
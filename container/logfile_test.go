@@ -0,0 +1,106 @@
+package container
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLogFile(t *testing.T) {
+	t.Parallel()
+
+	type file struct {
+		suffix string
+		data   string
+	}
+
+	testCases := []struct {
+		name string
+		// existing contents of the current file
+		initial string
+		max     int64
+		keep    int
+		writes  []string
+		want    []file
+	}{
+		{"unlimited", "", 0, 0, []string{"aaaa", "bbbb", "cccc"}, []file{
+			{"", "aaaabbbbcccc"},
+		}},
+		{"exact", "", 8, 0, []string{"aaaa", "bbbb"}, []file{
+			{"", "aaaabbbb"},
+		}},
+		{"boundary", "", 8, 0, []string{"aaaa", "bbbb", "c"}, []file{
+			{"", "c"},
+			{".1", "aaaabbbb"},
+		}},
+		{"boundary existing", "aaaaaaa", 8, 0, []string{"b", "c"}, []file{
+			{"", "c"},
+			{".1", "aaaaaaab"},
+		}},
+		{"oversized", "", 4, 0, []string{"aa", "bbbbbbbb", "c"}, []file{
+			{"", "c"},
+			{".1", "bbbbbbbb"},
+		}},
+		{"oversized empty", "", 4, 0, []string{"bbbbbbbb"}, []file{
+			{"", "bbbbbbbb"},
+		}},
+		{"keep", "", 2, 2, []string{"aa", "bb", "cc", "dd"}, []file{
+			{"", "dd"},
+			{".1", "cc"},
+			{".2", "bb"},
+		}},
+		{"keep default", "", 2, -1, []string{"aa", "bb", "cc"}, []file{
+			{"", "cc"},
+			{".1", "bb"},
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			d := t.TempDir()
+			name := filepath.Join(d, "log")
+			if tc.initial != "" {
+				if err := os.WriteFile(name, []byte(tc.initial), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			w, err := openLogFile(name, tc.max, tc.keep)
+			if err != nil {
+				t.Fatalf("openLogFile: error = %v", err)
+			}
+			for _, s := range tc.writes {
+				if n, err := w.Write([]byte(s)); err != nil {
+					t.Fatalf("Write: error = %v", err)
+				} else if n != len(s) {
+					t.Fatalf("Write: %d, want %d", n, len(s))
+				}
+			}
+			if err = w.Close(); err != nil {
+				t.Fatalf("Close: error = %v", err)
+			}
+			if _, err = w.Write(nil); !errors.Is(err, os.ErrClosed) {
+				t.Errorf("Write: error = %v, want %v", err, os.ErrClosed)
+			}
+
+			entries, err := os.ReadDir(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]file, len(entries))
+			for i, ent := range entries {
+				var data []byte
+				if data, err = os.ReadFile(filepath.Join(d, ent.Name())); err != nil {
+					t.Fatal(err)
+				}
+				got[i] = file{strings.TrimPrefix(ent.Name(), "log"), string(data)}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("openLogFile: %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	. "syscall"
 
 	"hakurei.app/container/vfs"
@@ -99,7 +100,13 @@ func (p *procPaths) bindMount(msg message.Msg, source, target string, flags uint
 
 // remount applies flags on target, recursively if MS_REC is set.
 func (p *procPaths) remount(msg message.Msg, target string, flags uintptr) error {
-	// syscallDispatcher methods bindMount, remount must not be called from this function
+	return p.remountExcept(msg, target, flags, nil)
+}
+
+// remountExcept is like remount, but skips mount points beneath target at or beneath a pathname in except.
+// Pathnames in except are expected to be mount points, flags of a mount point containing one are changed.
+func (p *procPaths) remountExcept(msg message.Msg, target string, flags uintptr, except []string) error {
+	// syscallDispatcher methods bindMount, remount, remountExcept must not be called from this function
 
 	var targetFinal string
 	if v, err := p.k.evalSymlinks(target); err != nil {
@@ -130,7 +137,7 @@ func (p *procPaths) remount(msg message.Msg, target string, flags uintptr) error
 		}
 	}
 
-	mf := MS_NOSUID | flags&MS_NODEV | flags&MS_RDONLY
+	mf := MS_NOSUID | flags&MS_NODEV | flags&MS_NOEXEC | flags&MS_RDONLY
 	return p.mountinfo(func(d *vfs.MountInfoDecoder) error {
 		n, err := d.Unfold(targetKFinal)
 		if err != nil {
@@ -146,7 +153,7 @@ func (p *procPaths) remount(msg message.Msg, target string, flags uintptr) error
 
 		for cur := range n.Collective() {
 			// avoid remounting twice
-			if cur == n {
+			if cur == n || remountExcepted(cur.Clean, except) {
 				continue
 			}
 
@@ -159,6 +166,16 @@ func (p *procPaths) remount(msg message.Msg, target string, flags uintptr) error
 	})
 }
 
+// remountExcepted returns whether pathname is or is contained by a pathname in except.
+func remountExcepted(pathname string, except []string) bool {
+	for _, e := range except {
+		if pathname == e || strings.HasPrefix(pathname, e+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // remountWithFlags remounts mount point described by [vfs.MountInfoNode].
 func remountWithFlags(k syscallDispatcher, msg message.Msg, n *vfs.MountInfoNode, mf uintptr) error {
 	// syscallDispatcher methods bindMount, remount must not be called from this function
@@ -0,0 +1,73 @@
+package container
+
+import (
+	"encoding/binary"
+	"os"
+	"syscall"
+)
+
+// loopbackIndex is the interface index of lo, which is always the first
+// interface registered in a new network namespace.
+const loopbackIndex = 1
+
+// loopbackUp brings up the loopback interface of the network namespace of the
+// calling thread via rtnetlink. This requires CAP_NET_ADMIN in the user namespace
+// owning the network namespace.
+func loopbackUp() error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	defer func() { _ = syscall.Close(fd) }()
+
+	sa := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}
+	if err = syscall.Bind(fd, sa); err != nil {
+		return os.NewSyscallError("bind", err)
+	}
+
+	const seq = 1
+	req := make([]byte, 0, syscall.NLMSG_HDRLEN+syscall.SizeofIfInfomsg)
+	// struct nlmsghdr
+	req = binary.NativeEndian.AppendUint32(req, syscall.NLMSG_HDRLEN+syscall.SizeofIfInfomsg)
+	req = binary.NativeEndian.AppendUint16(req, syscall.RTM_NEWLINK)
+	req = binary.NativeEndian.AppendUint16(req, syscall.NLM_F_REQUEST|syscall.NLM_F_ACK)
+	req = binary.NativeEndian.AppendUint32(req, seq)
+	req = binary.NativeEndian.AppendUint32(req, 0)
+	// struct ifinfomsg
+	req = append(req, syscall.AF_UNSPEC, 0)
+	req = binary.NativeEndian.AppendUint16(req, 0)
+	req = binary.NativeEndian.AppendUint32(req, loopbackIndex)
+	req = binary.NativeEndian.AppendUint32(req, syscall.IFF_UP)
+	req = binary.NativeEndian.AppendUint32(req, syscall.IFF_UP)
+	if err = syscall.Sendto(fd, req, 0, sa); err != nil {
+		return os.NewSyscallError("sendto", err)
+	}
+
+	buf := make([]byte, os.Getpagesize())
+	for {
+		var n int
+		if n, _, err = syscall.Recvfrom(fd, buf, 0); err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			return os.NewSyscallError("recvfrom", err)
+		}
+
+		var msgs []syscall.NetlinkMessage
+		if msgs, err = syscall.ParseNetlinkMessage(buf[:n]); err != nil {
+			return os.NewSyscallError("recvfrom", err)
+		}
+		for _, m := range msgs {
+			if m.Header.Seq != seq || m.Header.Type != syscall.NLMSG_ERROR {
+				continue
+			}
+			if len(m.Data) < 4 {
+				return os.NewSyscallError("recvfrom", syscall.EBADMSG)
+			}
+			if errno := -int32(binary.NativeEndian.Uint32(m.Data)); errno != 0 {
+				return os.NewSyscallError("RTM_NEWLINK", syscall.Errno(errno))
+			}
+			return nil
+		}
+	}
+}
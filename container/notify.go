@@ -0,0 +1,101 @@
+package container
+
+import (
+	"errors"
+	"os"
+	"slices"
+	. "syscall"
+
+	"hakurei.app/container/seccomp"
+	"hakurei.app/container/std"
+)
+
+// ErrNotifyNotServed is returned by [Container.SeccompListener] if the syscall filter of the
+// container has no listener, or if container init terminated before loading the syscall filter.
+var ErrNotifyNotServed = errors.New("container does not serve a seccomp listener")
+
+// seccompNotify returns whether the syscall filter loaded by init has a listener.
+func (p *Params) seccompNotify() bool {
+	if p.SeccompDisable || len(p.SeccompProgram) > 0 {
+		return false
+	}
+	return slices.ContainsFunc(p.SeccompRules, func(rule std.NativeRule) bool {
+		return rule.Errno == std.ErrnoNotify
+	})
+}
+
+// loadNotify loads a syscall filter with a listener and passes the listener over socket.
+// Both socket and the listener are closed before loadNotify returns.
+func loadNotify(rules []std.NativeRule, flags seccomp.ExportFlag, extra []seccomp.Arch, socket *os.File) error {
+	defer socket.Close()
+
+	listener, err := seccomp.LoadNotify(rules, flags, extra...)
+	if err != nil {
+		return err
+	}
+	// duplicated into the message, no longer needed here
+	defer listener.Close()
+
+	if err = Sendmsg(int(socket.Fd()), []byte{0}, UnixRights(int(listener.Fd())), nil, 0); err != nil {
+		return os.NewSyscallError("sendmsg", err)
+	}
+	return nil
+}
+
+/*
+SeccompListener returns the listener of the syscall filter loaded by container init, received over
+a socket set up by [Container.Start] if SeccompRules contains a rule with [std.ErrnoNotify].
+This blocks until the syscall filter is loaded, so it must be called after [Container.Serve].
+
+The listener is owned by the caller, and notifications received via [seccomp.NotifyReceive] must be
+answered via [seccomp.NotifyRespond], as the calling process is suspended until a response is
+received. Once the listener is closed, pending and future matching system calls fail with ENOSYS.
+SeccompListener returns [ErrNotifyNotServed] if called more than once.
+*/
+func (p *Container) SeccompListener() (*os.File, error) {
+	p.notifyMu.Lock()
+	defer p.notifyMu.Unlock()
+	if p.notify == nil {
+		return nil, ErrNotifyNotServed
+	}
+	defer p.closeNotifyLocked()
+
+	buf := make([]byte, 1)
+	oob := make([]byte, CmsgSpace(4))
+	n, oobn, _, _, err := Recvmsg(int(p.notify.Fd()), buf, oob, MSG_CMSG_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("recvmsg", err)
+	}
+	if n == 0 {
+		return nil, ErrNotifyNotServed
+	}
+
+	var msgs []SocketControlMessage
+	if msgs, err = ParseSocketControlMessage(oob[:oobn]); err != nil {
+		return nil, os.NewSyscallError("recvmsg", err)
+	}
+	for _, m := range msgs {
+		if fds, _ := ParseUnixRights(&m); len(fds) == 1 {
+			return os.NewFile(uintptr(fds[0]), "seccomp listener"), nil
+		}
+	}
+	return nil, ErrNotifyNotServed
+}
+
+// closeNotify closes the parent end of the seccomp listener socket if present.
+func (p *Container) closeNotify() {
+	p.notifyMu.Lock()
+	defer p.notifyMu.Unlock()
+	p.closeNotifyLocked()
+}
+
+// closeNotifyLocked closes the parent end of the seccomp listener socket while holding notifyMu.
+func (p *Container) closeNotifyLocked() {
+	if p.notify == nil {
+		return
+	}
+	if err := p.notify.Close(); err != nil {
+		p.msg.Verbosef("cannot close seccomp listener socket: %v", err)
+	}
+	p.notify = nil
+}
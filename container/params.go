@@ -3,6 +3,7 @@ package container
 import (
 	"encoding/gob"
 	"errors"
+	"io"
 	"os"
 	"strconv"
 	"syscall"
@@ -25,6 +26,11 @@ var (
 
 // Receive retrieves setup fd from the environment and receives params.
 func Receive(key string, e any, fdp *uintptr) (func() error, error) {
+	return ReceiveFunc(key, func(r io.Reader) error { return gob.NewDecoder(r).Decode(e) }, fdp)
+}
+
+// ReceiveFunc retrieves setup fd from the environment and receives params via decode.
+func ReceiveFunc(key string, decode func(r io.Reader) error, fdp *uintptr) (func() error, error) {
 	var setup *os.File
 
 	if s, ok := os.LookupEnv(key); !ok {
@@ -43,5 +49,5 @@ func Receive(key string, e any, fdp *uintptr) (func() error, error) {
 		}
 	}
 
-	return setup.Close, gob.NewDecoder(setup).Decode(e)
+	return setup.Close, decode(setup)
 }
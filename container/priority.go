@@ -0,0 +1,71 @@
+package container
+
+import (
+	"strconv"
+	. "syscall"
+)
+
+// Range of nice values accepted by setpriority(2).
+const (
+	NiceMin = -20
+	NiceMax = 19
+)
+
+// IOClass is an I/O scheduling class, see ioprio_set(2).
+type IOClass int
+
+const (
+	// IOClassNone retains the I/O scheduling class inherited from the calling process.
+	IOClassNone IOClass = iota
+	// IOClassRealtime is IOPRIO_CLASS_RT, which requires CAP_SYS_ADMIN in the init user namespace.
+	IOClassRealtime
+	// IOClassBestEffort is IOPRIO_CLASS_BE.
+	IOClassBestEffort
+	// IOClassIdle is IOPRIO_CLASS_IDLE.
+	IOClassIdle
+)
+
+func (class IOClass) String() string {
+	switch class {
+	case IOClassNone:
+		return "none"
+	case IOClassRealtime:
+		return "realtime"
+	case IOClassBestEffort:
+		return "best-effort"
+	case IOClassIdle:
+		return "idle"
+
+	default:
+		return "invalid class " + strconv.Itoa(int(class))
+	}
+}
+
+// linux/ioprio.h
+const (
+	_IOPRIO_WHO_PROCESS = 1
+	_IOPRIO_CLASS_SHIFT = 13
+
+	// priority level within the realtime and best-effort classes, same as the kernel default
+	ioprioLevelDefault = 4
+)
+
+// SetNice sets the nice value of the calling thread, inherited by processes it creates.
+func SetNice(nice int) error { return Setpriority(PRIO_PROCESS, 0, nice) }
+
+// SetIOClass sets the I/O scheduling class of the calling thread, inherited by processes it creates.
+// The priority level within the realtime and best-effort classes is set to the kernel default.
+func SetIOClass(class IOClass) error {
+	var level uintptr
+	if class == IOClassRealtime || class == IOClassBestEffort {
+		level = ioprioLevelDefault
+	}
+	_, _, errno := Syscall(SYS_IOPRIO_SET, _IOPRIO_WHO_PROCESS, 0, uintptr(class)<<_IOPRIO_CLASS_SHIFT|level)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// clampNice returns nice clamped to the range of [NiceMin] to [NiceMax].
+func clampNice(nice int) int { return min(max(nice, NiceMin), NiceMax) }
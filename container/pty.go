@@ -0,0 +1,39 @@
+package container
+
+import (
+	"os"
+	"strconv"
+	. "syscall"
+	"unsafe"
+
+	"hakurei.app/container/fhs"
+)
+
+// openPTY allocates a pseudo-terminal via the devpts instance mounted on /dev/pts
+// of the calling process, returning its master and terminal ends.
+func openPTY() (master, tty *os.File, err error) {
+	var fd int
+	if fd, err = Open(fhs.Dev+"ptmx", O_RDWR|O_NOCTTY|O_CLOEXEC, 0); err != nil {
+		return nil, nil, &os.PathError{Op: "open", Path: fhs.Dev + "ptmx", Err: err}
+	}
+	master = os.NewFile(uintptr(fd), fhs.Dev+"ptmx")
+
+	var (
+		unlock int32
+		n      uint32
+	)
+	if _, _, errno := Syscall(SYS_IOCTL, master.Fd(), TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		_ = master.Close()
+		return nil, nil, os.NewSyscallError("ioctl", errno)
+	}
+	if _, _, errno := Syscall(SYS_IOCTL, master.Fd(), TIOCGPTN, uintptr(unsafe.Pointer(&n))); errno != 0 {
+		_ = master.Close()
+		return nil, nil, os.NewSyscallError("ioctl", errno)
+	}
+
+	if tty, err = os.OpenFile(fhs.Dev+"pts/"+strconv.Itoa(int(n)), O_RDWR|O_NOCTTY, 0); err != nil {
+		_ = master.Close()
+		return nil, nil, err
+	}
+	return
+}
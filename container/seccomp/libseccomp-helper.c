@@ -10,13 +10,18 @@
 #define LEN(arr) (sizeof(arr) / sizeof((arr)[0]))
 
 int32_t hakurei_scmp_make_filter(
-    int *ret_p, uintptr_t allocate_p,
+    int *ret_p, int *notify_fd_p, uintptr_t allocate_p,
     uint32_t arch, uint32_t multiarch,
+    const uint32_t *extra_arch, size_t extra_arch_sz,
     struct hakurei_syscall_rule *rules,
     size_t rules_sz, hakurei_export_flag flags) {
     int i;
     int last_allowed_family;
     int disallowed;
+    int notify = 0;
+    uint32_t deny_action = 0;
+    int deny = 0;
+    uint32_t action;
     struct hakurei_syscall_rule *rule;
     void *buf;
     size_t len = 0;
@@ -68,14 +73,49 @@ int32_t hakurei_scmp_make_filter(
         }
     }
 
+    /* Architectures of binaries running under emulation, e.g. via
+     * binfmt_misc, are added regardless of the native arch. */
+    for (i = 0; i < extra_arch_sz; i++) {
+        *ret_p = seccomp_arch_add(ctx, extra_arch[i]);
+        if (*ret_p < 0 && *ret_p != -EEXIST) {
+            res = 8;
+            goto out;
+        }
+    }
+
+    /* Deny actions only replace EPERM: ENOSYS and EAFNOSUPPORT
+     * are relied on by userspace to fall back to other interfaces */
+    /* SCMP_ACT_KILL_THREAD is 0, so selection is tracked separately */
+    deny = 1;
+    if (flags & HAKUREI_EXPORT_KILL_PROCESS)
+        deny_action = SCMP_ACT_KILL_PROCESS;
+    else if (flags & HAKUREI_EXPORT_KILL_THREAD)
+        deny_action = SCMP_ACT_KILL_THREAD;
+    else if (flags & HAKUREI_EXPORT_KILL_TRAP)
+        deny_action = SCMP_ACT_TRAP;
+    else if (flags & HAKUREI_EXPORT_DENY_ENOSYS)
+        deny_action = SCMP_ACT_ERRNO(ENOSYS);
+    else
+        deny = 0;
+
     for (i = 0; i < rules_sz; i++) {
         rule = &rules[i];
-        assert(rule->m_errno == EPERM || rule->m_errno == ENOSYS);
+        assert(rule->m_errno == EPERM || rule->m_errno == ENOSYS || rule->m_errno == EAFNOSUPPORT ||
+               rule->m_errno == HAKUREI_ERRNO_NOTIFY || rule->m_errno == HAKUREI_ERRNO_LOG);
+
+        action = SCMP_ACT_ERRNO(rule->m_errno);
+        if (rule->m_errno == HAKUREI_ERRNO_NOTIFY) {
+            action = SCMP_ACT_NOTIFY;
+            notify = 1;
+        } else if (rule->m_errno == HAKUREI_ERRNO_LOG)
+            action = SCMP_ACT_LOG;
+        else if (deny && rule->m_errno == EPERM)
+            action = deny_action;
 
         if (rule->arg)
-            *ret_p = seccomp_rule_add(ctx, SCMP_ACT_ERRNO(rule->m_errno), rule->syscall, 1, *rule->arg);
+            *ret_p = seccomp_rule_add(ctx, action, rule->syscall, 1, *rule->arg);
         else
-            *ret_p = seccomp_rule_add(ctx, SCMP_ACT_ERRNO(rule->m_errno), rule->syscall, 0);
+            *ret_p = seccomp_rule_add(ctx, action, rule->syscall, 0);
 
         if (*ret_p == -EFAULT) {
             res = 4;
@@ -110,6 +150,18 @@ int32_t hakurei_scmp_make_filter(
             res = 7;
             goto out;
         }
+
+        /* The listener is created by seccomp_load if any rule selects
+         * SCMP_ACT_NOTIFY, and is owned by the caller from this point. */
+        if (notify) {
+            *ret_p = seccomp_notify_fd(ctx);
+            if (*ret_p < 0) {
+                res = 9;
+                goto out;
+            }
+            *notify_fd_p = *ret_p;
+            *ret_p = 0;
+        }
     } else {
         *ret_p = seccomp_export_bpf_mem(ctx, NULL, &len);
         if (*ret_p != 0) {
@@ -136,3 +188,8 @@ out:
 
     return res;
 }
+
+int hakurei_scmp_kill_process_supported(void) {
+    /* API level 3 indicates kernel support for SECCOMP_RET_KILL_PROCESS */
+    return seccomp_api_get() >= 3;
+}
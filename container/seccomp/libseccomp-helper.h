@@ -10,8 +10,17 @@ typedef enum {
     HAKUREI_EXPORT_MULTIARCH = 1 << 0,
     HAKUREI_EXPORT_CAN = 1 << 1,
     HAKUREI_EXPORT_BLUETOOTH = 1 << 2,
+    HAKUREI_EXPORT_KILL_TRAP = 1 << 3,
+    HAKUREI_EXPORT_KILL_THREAD = 1 << 4,
+    HAKUREI_EXPORT_KILL_PROCESS = 1 << 5,
+    HAKUREI_EXPORT_DENY_ENOSYS = 1 << 6,
 } hakurei_export_flag;
 
+/* m_errno value selecting SCMP_ACT_NOTIFY, equivalent to std.ErrnoNotify */
+#define HAKUREI_ERRNO_NOTIFY (-1)
+/* m_errno value selecting SCMP_ACT_LOG, equivalent to std.ErrnoLog */
+#define HAKUREI_ERRNO_LOG (-2)
+
 struct hakurei_syscall_rule {
     int syscall;
     int m_errno;
@@ -20,7 +29,9 @@ struct hakurei_syscall_rule {
 
 extern void *hakurei_scmp_allocate(uintptr_t f, size_t len);
 int32_t hakurei_scmp_make_filter(
-    int *ret_p, uintptr_t allocate_p,
+    int *ret_p, int *notify_fd_p, uintptr_t allocate_p,
     uint32_t arch, uint32_t multiarch,
+    const uint32_t *extra_arch, size_t extra_arch_sz,
     struct hakurei_syscall_rule *rules,
     size_t rules_sz, hakurei_export_flag flags);
+int hakurei_scmp_kill_process_supported(void);
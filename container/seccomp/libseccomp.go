@@ -10,6 +10,7 @@ import "C"
 import (
 	"errors"
 	"fmt"
+	"os"
 	"runtime"
 	"runtime/cgo"
 	"syscall"
@@ -75,8 +76,52 @@ const (
 	AllowCAN ExportFlag = C.HAKUREI_EXPORT_CAN
 	// AllowBluetooth allows AF_BLUETOOTH.
 	AllowBluetooth ExportFlag = C.HAKUREI_EXPORT_BLUETOOTH
+
+	// KillTrap delivers SIGSYS in place of failing with EPERM.
+	KillTrap ExportFlag = C.HAKUREI_EXPORT_KILL_TRAP
+	// KillThread kills the offending thread in place of failing with EPERM.
+	// Takes precedence over KillTrap.
+	KillThread ExportFlag = C.HAKUREI_EXPORT_KILL_THREAD
+	// KillProcess kills the entire process in place of failing with EPERM.
+	// Takes precedence over KillThread and KillTrap. Requires kernel support, see [KillProcessSupported].
+	KillProcess ExportFlag = C.HAKUREI_EXPORT_KILL_PROCESS
+
+	// DenyENOSYS fails with ENOSYS in place of failing with EPERM,
+	// causing denied system calls to appear unimplemented.
+	// Kill actions take precedence over DenyENOSYS.
+	DenyENOSYS ExportFlag = C.HAKUREI_EXPORT_DENY_ENOSYS
+
+	// KillMask covers all flags selecting a kill action.
+	KillMask = KillTrap | KillThread | KillProcess
+	// DenyMask covers all flags selecting an action in place of failing with EPERM.
+	DenyMask = KillMask | DenyENOSYS
+)
+
+/*
+Arch identifies an architecture added to the filter on top of the native architecture, so system
+calls made by binaries of that architecture running under emulation are evaluated against the
+rules instead of being killed. This is useful when running foreign binaries via qemu-user and
+binfmt_misc. Rules are translated to system call numbers of every architecture in the filter.
+
+With [AllowMultiarch], the 32-bit counterpart of the native architecture is already added on
+amd64 and arm64. Listing it in place of setting [AllowMultiarch] adds it all the same, but the
+personality(2) restrictions of [std.PresetLinux32] are still determined by the presets in use.
+*/
+type Arch = C.uint32_t
+
+const (
+	ArchX86     Arch = C.SCMP_ARCH_X86
+	ArchAMD64   Arch = C.SCMP_ARCH_X86_64
+	ArchARM     Arch = C.SCMP_ARCH_ARM
+	ArchARM64   Arch = C.SCMP_ARCH_AARCH64
+	ArchRISCV64 Arch = C.SCMP_ARCH_RISCV64
+	ArchPPC64LE Arch = C.SCMP_ARCH_PPC64LE
+	ArchS390X   Arch = C.SCMP_ARCH_S390X
 )
 
+// KillProcessSupported returns whether the running kernel supports [KillProcess].
+func KillProcessSupported() bool { return C.hakurei_scmp_kill_process_supported() != 0 }
+
 var resPrefix = [...]string{
 	0: "",
 	1: "seccomp_init failed",
@@ -86,6 +131,8 @@ var resPrefix = [...]string{
 	5: "seccomp_rule_add failed",
 	6: "seccomp_export_bpf_mem failed",
 	7: "seccomp_load failed",
+	8: "seccomp_arch_add failed (extra)",
+	9: "seccomp_notify_fd failed",
 }
 
 // cbAllocateBuffer is the function signature for the function handle passed to hakurei_export_filter
@@ -99,8 +146,9 @@ func hakurei_scmp_allocate(f C.uintptr_t, len C.size_t) (buf unsafe.Pointer) {
 }
 
 // makeFilter generates a bpf program from a slice of [std.NativeRule] and writes the resulting byte slice to p.
-// The filter is installed to the current process if p is nil.
-func makeFilter(rules []std.NativeRule, flags ExportFlag, p *[]byte) error {
+// The filter is installed to the current process if p is nil, and its listener is written to notify if
+// rules contains [std.ErrnoNotify] and notify is not nil.
+func makeFilter(rules []std.NativeRule, flags ExportFlag, extra []Arch, p *[]byte, notify *int) error {
 	if len(rules) == 0 {
 		return ErrInvalidRules
 	}
@@ -123,8 +171,14 @@ func makeFilter(rules []std.NativeRule, flags ExportFlag, p *[]byte) error {
 	}
 
 	var ret C.int
+	notifyFd := C.int(-1)
 
 	var scmpPinner runtime.Pinner
+	var extraP *C.uint32_t
+	if len(extra) > 0 {
+		scmpPinner.Pin(&extra[0])
+		extraP = (*C.uint32_t)(unsafe.Pointer(&extra[0]))
+	}
 	for i := range rules {
 		rule := &rules[i]
 		scmpPinner.Pin(rule)
@@ -146,8 +200,9 @@ func makeFilter(rules []std.NativeRule, flags ExportFlag, p *[]byte) error {
 	}
 
 	res, err := C.hakurei_scmp_make_filter(
-		&ret, C.uintptr_t(allocateP),
+		&ret, &notifyFd, C.uintptr_t(allocateP),
 		arch, multiarch,
+		extraP, C.size_t(len(extra)),
 		(*syscallRule)(unsafe.Pointer(&rules[0])),
 		C.size_t(len(rules)),
 		flags,
@@ -160,19 +215,47 @@ func makeFilter(rules []std.NativeRule, flags ExportFlag, p *[]byte) error {
 	if prefix := resPrefix[res]; prefix != "" {
 		return &LibraryError{prefix, syscall.Errno(-ret), err}
 	}
+	if notify != nil {
+		*notify = int(notifyFd)
+	} else if notifyFd >= 0 {
+		_ = syscall.Close(int(notifyFd))
+	}
 	return err
 }
 
-// Export generates a bpf program from a slice of [std.NativeRule].
+// Export generates a bpf program from a slice of [std.NativeRule], evaluating system calls
+// of the native architecture and architectures in extra, see [Arch].
 // Errors returned by libseccomp is wrapped in [LibraryError].
-func Export(rules []std.NativeRule, flags ExportFlag) (data []byte, err error) {
-	err = makeFilter(rules, flags, &data)
+func Export(rules []std.NativeRule, flags ExportFlag, extra ...Arch) (data []byte, err error) {
+	err = makeFilter(rules, flags, extra, &data, nil)
 	return
 }
 
-// Load generates a bpf program from a slice of [std.NativeRule] and enforces it on the current process.
-// Errors returned by libseccomp is wrapped in [LibraryError].
-func Load(rules []std.NativeRule, flags ExportFlag) error { return makeFilter(rules, flags, nil) }
+// Load generates a bpf program from a slice of [std.NativeRule] like [Export] and enforces it on the current process.
+// Errors returned by libseccomp is wrapped in [LibraryError]. The listener of a filter containing [std.ErrnoNotify]
+// is closed, so matching system calls fail with ENOSYS, see [LoadNotify].
+func Load(rules []std.NativeRule, flags ExportFlag, extra ...Arch) error {
+	return makeFilter(rules, flags, extra, nil, nil)
+}
+
+/*
+LoadNotify is like [Load], but returns the listener of the filter, which receives a notification for every
+system call matching a rule with [std.ErrnoNotify]. The listener is nil if rules does not contain such a rule.
+
+The caller takes ownership of the listener and must service notifications via [NotifyReceive] and
+[NotifyRespond]: a process making a matching system call blocks until a response is sent. Once the
+listener is closed, pending and subsequent matching system calls fail with ENOSYS.
+*/
+func LoadNotify(rules []std.NativeRule, flags ExportFlag, extra ...Arch) (*os.File, error) {
+	fd := -1
+	if err := makeFilter(rules, flags, extra, nil, &fd); err != nil {
+		return nil, err
+	}
+	if fd < 0 {
+		return nil, nil
+	}
+	return os.NewFile(uintptr(fd), "seccomp listener"), nil
+}
 
 type (
 	// Comparison operators.
@@ -1,8 +1,12 @@
 package seccomp_test
 
 import (
+	"bytes"
 	"crypto/sha512"
+	"encoding/binary"
 	"errors"
+	"reflect"
+	"runtime"
 	"syscall"
 	"testing"
 
@@ -109,6 +113,170 @@ func TestExport(t *testing.T) {
 	}
 }
 
+func TestDenySocketFamily(t *testing.T) {
+	t.Parallel()
+
+	if got := DenySocketFamily(); got != nil {
+		t.Errorf("DenySocketFamily: %#v, want nil", got)
+	}
+
+	rules := DenySocketFamily(syscall.AF_INET, syscall.AF_INET6)
+	if runtime.GOARCH == "386" {
+		if rules != nil {
+			t.Fatalf("DenySocketFamily: %#v, want nil", rules)
+		}
+		return
+	}
+
+	want := []NativeRule{
+		{Syscall: SNR_SOCKET, Errno: ScmpErrno(syscall.EAFNOSUPPORT), Arg: &ScmpArgCmp{Arg: 0, Op: SCMP_CMP_EQ, DatumA: syscall.AF_INET}},
+		{Syscall: SNR_SOCKET, Errno: ScmpErrno(syscall.EAFNOSUPPORT), Arg: &ScmpArgCmp{Arg: 0, Op: SCMP_CMP_EQ, DatumA: syscall.AF_INET6}},
+		{Syscall: SNR_SOCKETCALL, Errno: ScmpErrno(syscall.EAFNOSUPPORT), Arg: &ScmpArgCmp{Arg: 0, Op: SCMP_CMP_EQ, DatumA: 1}},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Fatalf("DenySocketFamily: %#v, want %#v", rules, want)
+	}
+
+	for _, flags := range []ExportFlag{0, AllowMultiarch} {
+		if _, err := Export(append(Preset(PresetStrict, flags), rules...), flags); err != nil {
+			t.Fatalf("Export: error = %v", err)
+		}
+	}
+}
+
+func TestPresetIOUring(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		presets FilterPreset
+		logged  bool
+	}{
+		{"compat", 0, false},
+		{"compat allow", PresetAllowIOUring, true},
+		{"ext", PresetExt, false},
+		{"strict", PresetStrict, false},
+		{"ext allow", PresetExt | PresetAllowIOUring, true},
+		{"strict allow", PresetStrict | PresetAllowIOUring, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rules := Preset(tc.presets, 0)
+			for _, num := range []ScmpSyscall{SNR_IO_URING_SETUP, SNR_IO_URING_ENTER, SNR_IO_URING_REGISTER} {
+				var logged bool
+				for _, rule := range rules {
+					if rule.Syscall == num {
+						if rule.Errno != ErrnoLog || rule.Arg != nil {
+							t.Errorf("Preset: %#v", rule)
+						}
+						logged = true
+					}
+				}
+				if want := tc.logged && num != SNR_IO_URING_ENTER; logged != want {
+					t.Errorf("Preset: syscall %d logged = %v, want %v", num, logged, want)
+				}
+			}
+
+			if _, err := Export(rules, 0); err != nil {
+				t.Fatalf("Export: error = %v", err)
+			}
+		})
+	}
+}
+
+func TestKillAction(t *testing.T) {
+	t.Parallel()
+
+	rules := Preset(PresetStrict, 0)
+	base, err := Export(rules, 0)
+	if err != nil {
+		t.Fatalf("Export: error = %v", err)
+	}
+
+	for _, flags := range []ExportFlag{KillTrap, KillThread, KillProcess, DenyENOSYS} {
+		var data []byte
+		if data, err = Export(rules, flags); err != nil {
+			t.Fatalf("Export: error = %v", err)
+		}
+		if sha512.Sum512(data) == sha512.Sum512(base) {
+			t.Errorf("Export: flags %#x did not change program", flags)
+		}
+	}
+
+	// kill actions only replace EPERM
+	enosys := []NativeRule{{Syscall: SNR_CLONE3, Errno: ScmpErrno(syscall.ENOSYS)}}
+	var want, got []byte
+	if want, err = Export(enosys, 0); err != nil {
+		t.Fatalf("Export: error = %v", err)
+	}
+	if got, err = Export(enosys, KillProcess); err != nil {
+		t.Fatalf("Export: error = %v", err)
+	}
+	if sha512.Sum512(got) != sha512.Sum512(want) {
+		t.Errorf("Export: KillProcess changed ENOSYS rule")
+	}
+
+	// kill actions take precedence over DenyENOSYS
+	if want, err = Export(rules, KillTrap); err != nil {
+		t.Fatalf("Export: error = %v", err)
+	}
+	if got, err = Export(rules, KillTrap|DenyENOSYS); err != nil {
+		t.Fatalf("Export: error = %v", err)
+	}
+	if sha512.Sum512(got) != sha512.Sum512(want) {
+		t.Errorf("Export: DenyENOSYS took precedence over KillTrap")
+	}
+}
+
+func TestExtraArch(t *testing.T) {
+	t.Parallel()
+
+	// the filter compares the architecture of every system call against AUDIT_ARCH values,
+	// which are the values of Arch, so an added architecture appears as a jump constant
+	contains := func(data []byte, arch Arch) bool {
+		k := binary.LittleEndian.AppendUint32(nil, uint32(arch))
+		for i := 0; i+8 <= len(data); i += 8 {
+			if bytes.Equal(data[i+4:i+8], k) {
+				return true
+			}
+		}
+		return false
+	}
+
+	extra := ArchRISCV64
+	if runtime.GOARCH == "riscv64" {
+		extra = ArchARM64
+	}
+
+	rules := Preset(PresetStrict, 0)
+	base, err := Export(rules, 0)
+	if err != nil {
+		t.Fatalf("Export: error = %v", err)
+	}
+	if contains(base, extra) {
+		t.Fatalf("Export: unexpected architecture %#x", uint32(extra))
+	}
+
+	var data []byte
+	if data, err = Export(rules, 0, extra); err != nil {
+		t.Fatalf("Export: error = %v", err)
+	}
+	if !contains(data, extra) {
+		t.Errorf("Export: architecture %#x not added", uint32(extra))
+	}
+
+	// adding an architecture already present in the filter is not an error
+	var dup []byte
+	if dup, err = Export(rules, 0, extra, extra); err != nil {
+		t.Fatalf("Export: error = %v", err)
+	}
+	if !bytes.Equal(dup, data) {
+		t.Errorf("Export: duplicate architecture changed program")
+	}
+}
+
 func BenchmarkExport(b *testing.B) {
 	const exportFlags = AllowMultiarch | AllowCAN | AllowBluetooth
 	const presetFlags = PresetExt | PresetDenyNS | PresetDenyTTY | PresetDenyDevel | PresetLinux32
@@ -0,0 +1,91 @@
+package seccomp
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// notifyIoctlRecv is the value of SECCOMP_IOCTL_NOTIF_RECV.
+	notifyIoctlRecv = 0xc0502100
+	// notifyIoctlSend is the value of SECCOMP_IOCTL_NOTIF_SEND.
+	notifyIoctlSend = 0xc0182101
+
+	// NotifyFlagContinue is the value of SECCOMP_USER_NOTIF_FLAG_CONTINUE. Setting it in
+	// [NotifyResponse.Flags] lets the system call proceed in place of returning Val or Error.
+	NotifyFlagContinue = 1 << 0
+)
+
+// Data is equivalent to struct seccomp_data.
+type Data struct {
+	// System call number.
+	Nr int32
+	// AUDIT_ARCH_* value of the calling process.
+	Arch uint32
+	// Instruction pointer at the time of the system call.
+	InstructionPointer uint64
+	// Up to 6 system call arguments.
+	Args [6]uint64
+}
+
+// Notification is equivalent to struct seccomp_notif.
+type Notification struct {
+	// Unique identifier of the notification, passed back in [NotifyResponse.ID].
+	ID uint64
+	// Thread id of the calling thread, as seen from the pid namespace of the listener.
+	Pid uint32
+	// Currently unused.
+	Flags uint32
+	// System call made by the calling process.
+	Data Data
+}
+
+// NotifyResponse is equivalent to struct seccomp_notif_resp.
+type NotifyResponse struct {
+	// Value of [Notification.ID] this response is for.
+	ID uint64
+	// Return value of the system call if Error is zero.
+	Val int64
+	// Negated errno value returned by the system call.
+	Error int32
+	// Response flags, such as [NotifyFlagContinue].
+	Flags uint32
+}
+
+// NotifyReceive blocks until a notification is received on listener.
+func NotifyReceive(listener *os.File) (*Notification, error) {
+	var n Notification
+	if err := notifyIoctl(listener, notifyIoctlRecv, unsafe.Pointer(&n)); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// NotifyRespond sends resp to listener. This returns ENOENT if the calling process
+// no longer waits for a response, for example if it was interrupted by a signal.
+func NotifyRespond(listener *os.File, resp *NotifyResponse) error {
+	return notifyIoctl(listener, notifyIoctlSend, unsafe.Pointer(resp))
+}
+
+// notifyIoctl issues an ioctl request on listener.
+func notifyIoctl(listener *os.File, req uintptr, arg unsafe.Pointer) error {
+	rc, err := listener.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	if err = rc.Control(func(fd uintptr) {
+		for {
+			if _, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg)); errno != syscall.EINTR {
+				return
+			}
+		}
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return os.NewSyscallError("ioctl", errno)
+	}
+	return nil
+}
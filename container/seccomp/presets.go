@@ -28,6 +28,9 @@ func Preset(presets FilterPreset, flags ExportFlag) (rules []NativeRule) {
 	if flags&AllowMultiarch == 0 {
 		l += len(presetEmu)
 	}
+	if presets&PresetAllowIOUring != 0 {
+		l += len(presetIOUring)
+	}
 	if presets&PresetExt != 0 {
 		l += len(presetCommonExt)
 		if presets&PresetDenyNS != 0 {
@@ -52,6 +55,9 @@ func Preset(presets FilterPreset, flags ExportFlag) (rules []NativeRule) {
 	if flags&AllowMultiarch == 0 {
 		rules = append(rules, presetEmu...)
 	}
+	if presets&PresetAllowIOUring != 0 {
+		rules = append(rules, presetIOUring...)
+	}
 	if presets&PresetExt != 0 {
 		rules = append(rules, presetCommonExt...)
 		if presets&PresetDenyNS != 0 {
@@ -114,6 +120,13 @@ var (
 		{Syscall: SNR_SWAPON, Errno: ScmpErrno(EPERM), Arg: nil},
 	}
 
+	/* hakurei: io_uring submissions bypass the syscall filter, so instances being set up
+	 * are logged, io_uring_enter is left alone as it is called on every submission */
+	presetIOUring = []NativeRule{
+		{Syscall: SNR_IO_URING_SETUP, Errno: ErrnoLog, Arg: nil},
+		{Syscall: SNR_IO_URING_REGISTER, Errno: ErrnoLog, Arg: nil},
+	}
+
 	presetNamespace = []NativeRule{
 		/* Don't allow subnamespace setups: */
 		{Syscall: SNR_UNSHARE, Errno: ScmpErrno(EPERM), Arg: nil},
@@ -202,6 +215,32 @@ var (
 	}
 )
 
+// ArgEq returns the address of a [ScmpArgCmp] matching argument arg against datum for equality.
+func ArgEq(arg ScmpUint, datum ScmpDatum) *ScmpArgCmp {
+	return &ScmpArgCmp{Arg: arg, Op: SCMP_CMP_EQ, DatumA: datum}
+}
+
+// socketcallSocket is the socketcall(2) call number of socket(2), SYS_SOCKET in linux/net.h.
+const socketcallSocket = 1
+
+// DenySocketFamily returns rules failing socket(2) with EAFNOSUPPORT for each address family in families.
+// Socket creation through socketcall(2) is failed unconditionally, which only affects the compat x86 arch
+// when multiarch is allowed, since its arguments cannot be inspected.
+//
+// On architectures multiplexing socket calls through socketcall(2), such as 386,
+// the family argument cannot be inspected and the returned slice is always empty.
+func DenySocketFamily(families ...ScmpDatum) []NativeRule {
+	if !socketFilter || len(families) == 0 {
+		return nil
+	}
+
+	rules := make([]NativeRule, len(families), len(families)+1)
+	for i, family := range families {
+		rules[i] = NativeRule{Syscall: SNR_SOCKET, Errno: ScmpErrno(EAFNOSUPPORT), Arg: ArgEq(0, family)}
+	}
+	return append(rules, NativeRule{Syscall: SNR_SOCKETCALL, Errno: ScmpErrno(EAFNOSUPPORT), Arg: ArgEq(0, socketcallSocket)})
+}
+
 func presetDevel(allowedPersonality ScmpDatum) []NativeRule {
 	return []NativeRule{
 		/* Profiling operations; we expect these to be done by tools from outside
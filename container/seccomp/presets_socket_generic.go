@@ -0,0 +1,6 @@
+//go:build !386
+
+package seccomp
+
+/* socket(2) is a real syscall and its arguments can be inspected */
+const socketFilter = true
@@ -0,0 +1,8 @@
+//go:build 386
+
+package seccomp
+
+/* Architectures multiplexing socket calls through socketcall(2): the
+ * arguments are passed through a pointer and cannot be inspected, and
+ * libseccomp fails to add argument comparisons for the pseudo-syscall */
+const socketFilter = false
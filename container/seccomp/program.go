@@ -0,0 +1,54 @@
+package seccomp
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"hakurei.app/container/std"
+)
+
+const (
+	// InstructionSize is the size of a single cBPF instruction, struct sock_filter.
+	InstructionSize = 8
+	// MaxInstructions is the maximum number of instructions in a program accepted by the kernel.
+	MaxInstructions = 4096
+
+	// seccompSetModeFilter is the value of SECCOMP_SET_MODE_FILTER.
+	seccompSetModeFilter = 1
+)
+
+// ErrInvalidProgram is returned for a program of invalid length.
+var ErrInvalidProgram = errors.New("invalid seccomp program length")
+
+// ValidProgram returns whether the length of program is acceptable for [LoadProgram].
+func ValidProgram(program []byte) bool {
+	return len(program) > 0 &&
+		len(program)%InstructionSize == 0 &&
+		len(program)/InstructionSize <= MaxInstructions
+}
+
+// sockFprog is equivalent to struct sock_fprog.
+type sockFprog struct {
+	len    uint16
+	filter unsafe.Pointer
+}
+
+// LoadProgram enforces a precompiled cBPF program on the current thread via seccomp(2).
+// PR_SET_NO_NEW_PRIVS must be set on the calling thread.
+func LoadProgram(program []byte) error {
+	if !ValidProgram(program) {
+		return ErrInvalidProgram
+	}
+
+	prog := sockFprog{uint16(len(program) / InstructionSize), unsafe.Pointer(unsafe.SliceData(program))}
+	if _, _, errno := syscall.Syscall(
+		uintptr(std.SNR_SECCOMP),
+		seccompSetModeFilter, 0,
+		uintptr(unsafe.Pointer(&prog)),
+	); errno != 0 {
+		return os.NewSyscallError("seccomp", errno)
+	}
+	return nil
+}
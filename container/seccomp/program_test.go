@@ -0,0 +1,38 @@
+package seccomp_test
+
+import (
+	"errors"
+	"testing"
+
+	"hakurei.app/container/seccomp"
+)
+
+func TestValidProgram(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		program []byte
+		want    bool
+	}{
+		{"nil", nil, false},
+		{"short", make([]byte, seccomp.InstructionSize-1), false},
+		{"partial", make([]byte, seccomp.InstructionSize*2+3), false},
+		{"oversized", make([]byte, seccomp.InstructionSize*(seccomp.MaxInstructions+1)), false},
+		{"single", make([]byte, seccomp.InstructionSize), true},
+		{"max", make([]byte, seccomp.InstructionSize*seccomp.MaxInstructions), true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := seccomp.ValidProgram(tc.program); got != tc.want {
+				t.Errorf("ValidProgram: %v, want %v", got, tc.want)
+			}
+			if !tc.want {
+				if err := seccomp.LoadProgram(tc.program); !errors.Is(err, seccomp.ErrInvalidProgram) {
+					t.Errorf("LoadProgram: error = %v, want %v", err, seccomp.ErrInvalidProgram)
+				}
+			}
+		})
+	}
+}
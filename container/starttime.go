@@ -0,0 +1,118 @@
+package container
+
+import (
+	"encoding/binary"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	. "syscall"
+
+	"hakurei.app/message"
+)
+
+const (
+	// userHZ is the unit of clock tick values exposed via procfs assumed if AT_CLKTCK is unavailable.
+	userHZ = 100
+
+	// _AT_CLKTCK is the auxiliary vector entry holding the frequency of times(2).
+	_AT_CLKTCK = 17
+)
+
+// clockTicks returns the unit of clock tick values exposed via procfs, as passed to the
+// current process in its auxiliary vector.
+var clockTicks = sync.OnceValue(func() uint64 {
+	if data, err := os.ReadFile("/proc/self/auxv"); err == nil {
+		if hz, ok := parseAuxv(data, _AT_CLKTCK); ok && hz != 0 {
+			return hz
+		}
+	}
+	return userHZ
+})
+
+// parseAuxv returns the value of entry typ in the contents of /proc/self/auxv.
+func parseAuxv(data []byte, typ uint64) (uint64, bool) {
+	const size = int(unsafe.Sizeof(uintptr(0)))
+	for len(data) >= 2*size {
+		var k, v uint64
+		if size == 8 {
+			k, v = binary.NativeEndian.Uint64(data), binary.NativeEndian.Uint64(data[size:])
+		} else {
+			k, v = uint64(binary.NativeEndian.Uint32(data)), uint64(binary.NativeEndian.Uint32(data[size:]))
+		}
+		data = data[2*size:]
+
+		if k == typ {
+			return v, true
+		}
+		if k == 0 { // AT_NULL
+			break
+		}
+	}
+	return 0, false
+}
+
+// processStartTime returns the start time of process pid as reported by procfs.
+// The current time is returned if procfs is unavailable.
+func processStartTime(msg message.Msg, pid int) time.Time {
+	now := time.Now()
+
+	var ticks, uptime time.Duration
+	if data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat"); err != nil {
+		msg.Verbosef("cannot read process start time: %v", err)
+		return now
+	} else if ticks, err = parseStatStartTime(string(data), clockTicks()); err != nil {
+		msg.Verbosef("cannot parse process start time: %v", err)
+		return now
+	}
+	if data, err := os.ReadFile("/proc/uptime"); err != nil {
+		msg.Verbosef("cannot read system uptime: %v", err)
+		return now
+	} else if uptime, err = parseUptime(string(data)); err != nil {
+		msg.Verbosef("cannot parse system uptime: %v", err)
+		return now
+	}
+
+	if ticks > uptime {
+		// unreachable
+		return now
+	}
+	return now.Add(ticks - uptime)
+}
+
+// parseStatStartTime returns the starttime field of the contents of /proc/pid/stat,
+// in clock ticks of frequency hz.
+func parseStatStartTime(stat string, hz uint64) (time.Duration, error) {
+	// comm may contain any character, fields resume after the last closing parenthesis
+	i := strings.LastIndexByte(stat, ')')
+	if i == -1 {
+		return 0, EINVAL
+	}
+
+	// starttime is field 22, fields following comm start from field 3
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 22-2 {
+		return 0, EINVAL
+	}
+	ticks, err := strconv.ParseUint(fields[22-3], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ticks) * time.Second / time.Duration(hz), nil
+}
+
+// parseUptime returns the system uptime from the contents of /proc/uptime.
+func parseUptime(uptime string) (time.Duration, error) {
+	fields := strings.Fields(uptime)
+	if len(fields) == 0 {
+		return 0, EINVAL
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
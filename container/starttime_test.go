@@ -0,0 +1,124 @@
+package container
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestParseStatStartTime(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		stat    string
+		want    time.Duration
+		wantErr error
+	}{
+		{"success", "1 (init) S 0 1 1 0 -1 4194560 43829 1566553 106 2455 89 175 3957 2731 20 0 1 0 13 22319104 3347 18446744073709551615 1 1 0 0 0 0 671173123 4096 1260 0 0 0 17 3 0 0 0 0 0 0 0 0 0 0 0 0 0\n",
+			130 * time.Millisecond, nil},
+		{"comm", "4242 (a) b) (c) R 1 4242 4242 34816 4242 4194304 147 0 0 0 0 0 0 0 20 0 1 0 1234567 8265728 416 18446744073709551615\n",
+			12345670 * time.Millisecond, nil},
+		{"no comm", "4242 a R 1", 0, syscall.EINVAL},
+		{"short", "4242 (a) R 1 4242 4242", 0, syscall.EINVAL},
+		{"invalid", "4242 (a) R 1 4242 4242 34816 4242 4194304 147 0 0 0 0 0 0 0 20 0 1 0 x 8265728\n",
+			0, &strconv.NumError{Func: "ParseUint", Num: "x", Err: strconv.ErrSyntax}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseStatStartTime(tc.stat, userHZ)
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("parseStatStartTime: error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("parseStatStartTime: %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseUptime(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		uptime  string
+		want    time.Duration
+		wantErr error
+	}{
+		{"success", "350735.47 234388.90\n", 350735470 * time.Millisecond, nil},
+		{"empty", "", 0, syscall.EINVAL},
+		{"invalid", "x 0\n", 0, &strconv.NumError{Func: "ParseFloat", Num: "x", Err: strconv.ErrSyntax}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseUptime(tc.uptime)
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("parseUptime: error = %v, want %v", err, tc.wantErr)
+			}
+			if got.Round(time.Millisecond) != tc.want {
+				t.Errorf("parseUptime: %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseAuxv(t *testing.T) {
+	t.Parallel()
+
+	auxv := func(entries ...uintptr) []byte {
+		var data []byte
+		for _, v := range entries {
+			if unsafe.Sizeof(v) == 8 {
+				data = binary.NativeEndian.AppendUint64(data, uint64(v))
+			} else {
+				data = binary.NativeEndian.AppendUint32(data, uint32(v))
+			}
+		}
+		return data
+	}
+
+	testCases := []struct {
+		name   string
+		data   []byte
+		want   uint64
+		wantOk bool
+	}{
+		{"success", auxv(6, 4096, _AT_CLKTCK, 250, 0, 0), 250, true},
+		{"after null", auxv(6, 4096, 0, 0, _AT_CLKTCK, 250), 0, false},
+		{"missing", auxv(6, 4096, 0, 0), 0, false},
+		{"truncated", auxv(6, 4096, _AT_CLKTCK)[:int(unsafe.Sizeof(uintptr(0)))*3], 0, false},
+		{"empty", nil, 0, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := parseAuxv(tc.data, _AT_CLKTCK)
+			if got != tc.want || ok != tc.wantOk {
+				t.Errorf("parseAuxv: (%d, %v), want (%d, %v)", got, ok, tc.want, tc.wantOk)
+			}
+		})
+	}
+
+	t.Run("clock ticks", func(t *testing.T) {
+		t.Parallel()
+		if hz := clockTicks(); hz == 0 {
+			t.Errorf("clockTicks: %d", hz)
+		}
+		if got, err := parseStatStartTime("1 (init) S"+strings.Repeat(" 0", 18)+" 250\n", 250); err != nil {
+			t.Fatalf("parseStatStartTime: error = %v", err)
+		} else if got != time.Second {
+			t.Errorf("parseStatStartTime: %v, want %v", got, time.Second)
+		}
+	})
+}
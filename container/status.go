@@ -0,0 +1,57 @@
+package container
+
+import (
+	"bufio"
+	"os"
+)
+
+// Setup steps reported by the container init via [Container.Status], in the order they are reached.
+// Steps also failing in the parent share the name of the corresponding [StartError.Step].
+const (
+	// StatusLoopback is reported before bringing up the loopback interface if LoopbackOnly is set.
+	StatusLoopback = "bring up loopback interface"
+	// StatusPrepareOps is reported before every [Op] is prepared in the host root.
+	StatusPrepareOps = "prepare container ops"
+	// StatusApplyOps is reported before every [Op] is applied in the intermediate root.
+	StatusApplyOps = "apply container ops"
+	// StatusLandlock is reported before enforcing LandlockFS if it is non-empty.
+	StatusLandlock = "enforce landlock filesystem rules"
+	// StatusSeccomp is reported before loading the syscall filter unless SeccompDisable is set.
+	StatusSeccomp = "load syscall filter"
+	// StatusStart is reported before starting the initial program.
+	StatusStart = "start initial program"
+)
+
+// statusStepsMax is the number of setup steps the container init reports at most.
+const statusStepsMax = 6
+
+// readStatus sends setup steps reported by the container init on r to status, and closes
+// status once the container init closes its end of the pipe.
+func (p *Container) readStatus(r *os.File, status chan<- string) {
+	defer close(status)
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		select {
+		case status <- s.Text():
+		default:
+			// unreachable with a well-behaved init
+			p.msg.Verbosef("dropping unexpected status %q", s.Text())
+		}
+	}
+	if err := s.Err(); err != nil {
+		p.msg.Verbosef("cannot read status report: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		p.msg.Verbosef("cannot close status report pipe: %v", err)
+	}
+}
+
+/*
+Status returns a channel receiving names of setup steps reported by the container init as it
+reaches them, one of the Status constants. The channel is closed once the initial program is
+started, or if the container init exits before that.
+
+Status returns nil unless ReportStatus is set and [Container.Start] returned successfully.
+The channel is buffered to hold every step, so it does not have to be drained.
+*/
+func (p *Container) Status() <-chan string { return p.status }
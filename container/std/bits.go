@@ -1,6 +1,11 @@
 // Package std contains constants from container packages without depending on cgo.
 package std
 
+import (
+	"strconv"
+	"strings"
+)
+
 const (
 	// BindOptional skips nonexistent host paths.
 	BindOptional = 1 << iota
@@ -10,6 +15,10 @@ const (
 	BindDevice
 	// BindEnsure attempts to create the host path if it does not exist.
 	BindEnsure
+	// BindNoRecursive mounts the host path without its submounts.
+	BindNoRecursive
+	// BindNoExec disallows program execution on this filesystem.
+	BindNoExec
 )
 
 // FilterPreset specifies parts of the syscall filter preset to enable.
@@ -26,7 +35,51 @@ const (
 	PresetDenyDevel
 	// PresetLinux32 sets PER_LINUX32.
 	PresetLinux32
+	/* PresetAllowIOUring explicitly allows io_uring, logging io_uring_setup and io_uring_register
+	via the audit subsystem so instances created in the container are recorded. No other preset
+	filters io_uring, this does not change the posture of [PresetExt] or [PresetStrict].
+
+	Operations submitted through an io_uring instance are carried out by the kernel on
+	behalf of the process without entering the syscall path, so they are not subject to
+	the seccomp filter. Allowing io_uring lets the initial program perform operations
+	such as opening files and connecting sockets that bypass rules denying the equivalent
+	system calls, and exposes an interface with a history of privilege escalation bugs. */
+	PresetAllowIOUring
 
 	// PresetStrict is a strict preset useful as a default value.
 	PresetStrict = PresetExt | PresetDenyNS | PresetDenyTTY | PresetDenyDevel
+
+	presetMax = PresetAllowIOUring << 1
 )
+
+func (presets FilterPreset) String() string {
+	switch presets {
+	case PresetExt:
+		return "ext"
+	case PresetDenyNS:
+		return "denyns"
+	case PresetDenyTTY:
+		return "denytty"
+	case PresetDenyDevel:
+		return "denydevel"
+	case PresetLinux32:
+		return "linux32"
+	case PresetAllowIOUring:
+		return "iouring"
+
+	default:
+		s := make([]string, 0, 1<<3)
+		for p := FilterPreset(1); p < presetMax; p <<= 1 {
+			if presets&p != 0 {
+				s = append(s, p.String())
+			}
+		}
+		if rem := presets &^ (presetMax - 1); rem != 0 {
+			s = append(s, "0x"+strconv.FormatInt(int64(rem), 16))
+		}
+		if len(s) == 0 {
+			return "none"
+		}
+		return strings.Join(s, ", ")
+	}
+}
@@ -0,0 +1,36 @@
+package std_test
+
+import (
+	"testing"
+
+	"hakurei.app/container/std"
+)
+
+func TestFilterPresetString(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		presets std.FilterPreset
+		want    string
+	}{
+		{0, "none"},
+		{std.PresetExt, "ext"},
+		{std.PresetDenyNS, "denyns"},
+		{std.PresetDenyTTY, "denytty"},
+		{std.PresetDenyDevel, "denydevel"},
+		{std.PresetLinux32, "linux32"},
+		{std.PresetAllowIOUring, "iouring"},
+		{std.PresetStrict, "ext, denyns, denytty, denydevel"},
+		{std.PresetExt | std.PresetLinux32, "ext, linux32"},
+		{std.PresetExt | std.PresetAllowIOUring, "ext, iouring"},
+		{std.PresetDenyTTY | 1<<10, "denytty, 0x400"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.presets.String(); got != tc.want {
+				t.Errorf("String: %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
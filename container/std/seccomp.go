@@ -36,13 +36,25 @@ type (
 	NativeRule struct {
 		// Syscall is the arch-dependent syscall number to act against.
 		Syscall ScmpSyscall `json:"syscall"`
-		// Errno is the errno value to return when the condition is satisfied.
+		// Errno is the errno value to return when the condition is satisfied,
+		// or [ErrnoNotify] to notify the listener of the filter instead,
+		// or [ErrnoLog] to allow the call and log it.
 		Errno ScmpErrno `json:"errno"`
 		// Arg is the optional struct scmp_arg_cmp passed to libseccomp.
 		Arg *ScmpArgCmp `json:"arg,omitempty"`
 	}
 )
 
+// ErrnoNotify is a [NativeRule.Errno] value suspending the calling process and notifying the listener
+// of the filter, in place of returning an errno value. A system call matching such a rule fails with
+// ENOSYS if the filter has no listener, such as when it is loaded from a precompiled program.
+const ErrnoNotify ScmpErrno = -1
+
+// ErrnoLog is a [NativeRule.Errno] value allowing the system call and logging it via the audit
+// subsystem, in place of returning an errno value. Whether such a record is emitted depends on
+// the actions_logged sysctl of seccomp.
+const ErrnoLog ScmpErrno = -2
+
 // MarshalJSON resolves the name of [ScmpSyscall] and encodes it as a [json] string.
 // If such a name does not exist, the syscall number is encoded instead.
 func (num *ScmpSyscall) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,35 @@
+package std
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"syscall"
+)
+
+// socketFamily maps address family names to their values.
+var socketFamily = map[string]ScmpDatum{
+	"unix":      syscall.AF_UNIX,
+	"inet":      syscall.AF_INET,
+	"inet6":     syscall.AF_INET6,
+	"netlink":   syscall.AF_NETLINK,
+	"can":       syscall.AF_CAN,
+	"bluetooth": syscall.AF_BLUETOOTH,
+}
+
+// SocketFamilyResolveName resolves an address family value by its lowercase name without the AF_ prefix.
+// Only families not unconditionally denied by the syscall filter are recognised.
+func SocketFamilyResolveName(name string) (family ScmpDatum, ok bool) {
+	family, ok = socketFamily[name]
+	return
+}
+
+// SocketFamilyNameError is returned when resolving an invalid address family name.
+type SocketFamilyNameError string
+
+func (e SocketFamilyNameError) Error() string {
+	return "invalid socket family " + strconv.Quote(string(e))
+}
+
+// SocketFamilyNames returns names recognised by [SocketFamilyResolveName] in ascending order.
+func SocketFamilyNames() []string { return slices.Sorted(maps.Keys(socketFamily)) }
@@ -0,0 +1,54 @@
+package std_test
+
+import (
+	"slices"
+	"syscall"
+	"testing"
+
+	"hakurei.app/container/std"
+)
+
+func TestSocketFamilyResolveName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		want std.ScmpDatum
+		ok   bool
+	}{
+		{"unix", syscall.AF_UNIX, true},
+		{"inet", syscall.AF_INET, true},
+		{"inet6", syscall.AF_INET6, true},
+		{"netlink", syscall.AF_NETLINK, true},
+		{"can", syscall.AF_CAN, true},
+		{"bluetooth", syscall.AF_BLUETOOTH, true},
+
+		{"AF_INET", 0, false},
+		{"packet", 0, false},
+		{"", 0, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got, ok := std.SocketFamilyResolveName(tc.name); got != tc.want || ok != tc.ok {
+				t.Errorf("SocketFamilyResolveName: %d, %v, want %d, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		const want = `invalid socket family "packet"`
+		if got := std.SocketFamilyNameError("packet").Error(); got != want {
+			t.Fatalf("Error: %q, want %q", got, want)
+		}
+	})
+}
+
+func TestSocketFamilyNames(t *testing.T) {
+	t.Parallel()
+	want := []string{"bluetooth", "can", "inet", "inet6", "netlink", "unix"}
+	if got := std.SocketFamilyNames(); !slices.Equal(got, want) {
+		t.Errorf("SocketFamilyNames: %q, want %q", got, want)
+	}
+}
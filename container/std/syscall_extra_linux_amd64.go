@@ -18,6 +18,7 @@ var syscallNumExtra = map[string]ScmpSyscall{
 	"setresuid32":     SNR_SETRESUID32,
 	"setreuid32":      SNR_SETREUID32,
 	"setuid32":        SNR_SETUID32,
+	"socketcall":      SNR_SOCKETCALL,
 }
 
 const (
@@ -38,4 +39,5 @@ const (
 	SNR_SETRESUID32     ScmpSyscall = __PNR_setresuid32
 	SNR_SETREUID32      ScmpSyscall = __PNR_setreuid32
 	SNR_SETUID32        ScmpSyscall = __PNR_setuid32
+	SNR_SOCKETCALL      ScmpSyscall = __PNR_socketcall
 )
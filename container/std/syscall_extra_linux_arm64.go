@@ -28,6 +28,7 @@ var syscallNumExtra = map[string]ScmpSyscall{
 	"switch_endian":   SNR_SWITCH_ENDIAN,
 	"vm86":            SNR_VM86,
 	"vm86old":         SNR_VM86OLD,
+	"socketcall":      SNR_SOCKETCALL,
 }
 
 const (
@@ -52,4 +53,5 @@ const (
 	SNR_SWITCH_ENDIAN   ScmpSyscall = __PNR_switch_endian
 	SNR_VM86            ScmpSyscall = __PNR_vm86
 	SNR_VM86OLD         ScmpSyscall = __PNR_vm86old
+	SNR_SOCKETCALL      ScmpSyscall = __PNR_socketcall
 )
@@ -29,6 +29,15 @@ func SetDumpable(dumpable uintptr) error { return Prctl(PR_SET_DUMPABLE, dumpabl
 // SetNoNewPrivs sets the calling thread's no_new_privs attribute.
 func SetNoNewPrivs() error { return Prctl(PR_SET_NO_NEW_PRIVS, 1, 0) }
 
+// linux/prctl.h, not defined in syscall on all targets
+const _PR_SET_CHILD_SUBREAPER = 0x24
+
+// SetChildSubreaper sets the "child subreaper" attribute of the calling process.
+func SetChildSubreaper() error { return Prctl(_PR_SET_CHILD_SUBREAPER, 1, 0) }
+
+// asm-generic/unistd.h, shared by all targets since Linux 5.1
+const _SYS_PIDFD_OPEN = 434
+
 // Isatty tests whether a file descriptor refers to a terminal.
 func Isatty(fd int) bool {
 	var buf [8]byte
@@ -24,26 +24,27 @@ const (
 	kernelCapLastCapPath  = fhs.ProcSys + "kernel/cap_last_cap"
 )
 
-func mustReadSysctl(msg message.Msg) {
-	sysctlOnce.Do(func() {
-		if v, err := os.ReadFile(kernelOverflowuidPath); err != nil {
-			msg.GetLogger().Fatalf("cannot read %q: %v", kernelOverflowuidPath, err)
-		} else if kernelOverflowuid, err = strconv.Atoi(string(bytes.TrimSpace(v))); err != nil {
-			msg.GetLogger().Fatalf("cannot interpret %q: %v", kernelOverflowuidPath, err)
-		}
-
-		if v, err := os.ReadFile(kernelOverflowgidPath); err != nil {
-			msg.GetLogger().Fatalf("cannot read %q: %v", kernelOverflowgidPath, err)
-		} else if kernelOverflowgid, err = strconv.Atoi(string(bytes.TrimSpace(v))); err != nil {
-			msg.GetLogger().Fatalf("cannot interpret %q: %v", kernelOverflowgidPath, err)
-		}
-
-		if v, err := os.ReadFile(kernelCapLastCapPath); err != nil {
-			msg.GetLogger().Fatalf("cannot read %q: %v", kernelCapLastCapPath, err)
-		} else if kernelCapLastCap, err = strconv.Atoi(string(bytes.TrimSpace(v))); err != nil {
-			msg.GetLogger().Fatalf("cannot interpret %q: %v", kernelCapLastCapPath, err)
-		}
-	})
+func mustReadSysctl(msg message.Msg) { sysctlOnce.Do(func() { readSysctl(msg) }) }
+
+// readSysctl reads sysctl values cached by mustReadSysctl.
+func readSysctl(msg message.Msg) {
+	if v, err := os.ReadFile(kernelOverflowuidPath); err != nil {
+		msg.GetLogger().Fatalf("cannot read %q: %v", kernelOverflowuidPath, err)
+	} else if kernelOverflowuid, err = strconv.Atoi(string(bytes.TrimSpace(v))); err != nil {
+		msg.GetLogger().Fatalf("cannot interpret %q: %v", kernelOverflowuidPath, err)
+	}
+
+	if v, err := os.ReadFile(kernelOverflowgidPath); err != nil {
+		msg.GetLogger().Fatalf("cannot read %q: %v", kernelOverflowgidPath, err)
+	} else if kernelOverflowgid, err = strconv.Atoi(string(bytes.TrimSpace(v))); err != nil {
+		msg.GetLogger().Fatalf("cannot interpret %q: %v", kernelOverflowgidPath, err)
+	}
+
+	if v, err := os.ReadFile(kernelCapLastCapPath); err != nil {
+		msg.GetLogger().Fatalf("cannot read %q: %v", kernelCapLastCapPath, err)
+	} else if kernelCapLastCap, err = strconv.Atoi(string(bytes.TrimSpace(v))); err != nil {
+		msg.GetLogger().Fatalf("cannot interpret %q: %v", kernelCapLastCapPath, err)
+	}
 }
 
 func OverflowUid(msg message.Msg) int { mustReadSysctl(msg); return kernelOverflowuid }
@@ -0,0 +1,32 @@
+package container
+
+import (
+	"sync"
+	"testing"
+
+	"hakurei.app/message"
+)
+
+func TestApplyDefaultsOverflow(t *testing.T) {
+	// not parallel: replaces the sysctl cache
+
+	msg := message.New(nil)
+	sysctlOnce = sync.Once{}
+	p := Params{OverflowUid: 1 << 10, OverflowGid: 1 << 9}
+	p.applyDefaults(msg, false)
+	if p.Uid != 1<<10 || p.Gid != 1<<9 {
+		t.Errorf("applyDefaults: uid = %d, gid = %d", p.Uid, p.Gid)
+	}
+
+	var read bool
+	sysctlOnce.Do(func() { read = true; readSysctl(msg) })
+	if !read {
+		t.Error("applyDefaults: sysctl read despite overflow ids")
+	}
+
+	p = Params{Uid: 1000, OverflowUid: 1 << 10}
+	p.applyDefaults(msg, false)
+	if p.Uid != 1000 || p.Gid != OverflowGid(msg) {
+		t.Errorf("applyDefaults: uid = %d, gid = %d", p.Uid, p.Gid)
+	}
+}
@@ -0,0 +1,130 @@
+package container
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	. "syscall"
+
+	"hakurei.app/container/fhs"
+)
+
+// usernsEnv is the file descriptor of the user namespace container init joins followed by clone
+// flags of namespaces it creates in it, and must match HAKUREI_USERNS_ENV in enter.h.
+const usernsEnv = "HAKUREI_USERNS"
+
+// linux/nsfs.h
+const _NS_GET_NSTYPE = 0xb703
+
+/*
+checkUserNamespace validates [Params.UserNamespace] and returns a file referring to it if it is to
+be joined by container init, or nil if it is the user namespace of the calling process.
+
+Joining a user namespace is impossible for a multithreaded process, so it is joined by container
+init before the Go runtime starts, see [EnterContainer]. The Uid and Gid fields are checked against
+the mappings of a joined user namespace by init once it is joined. For the user namespace of the
+calling process, init is started in it without CLONE_NEWUSER, Uid and Gid are checked and resolved
+here, and the remaining namespaces are created by init, which requires CAP_SYS_ADMIN in it.
+*/
+func (p *Params) checkUserNamespace() (*os.File, error) {
+	var target, self Stat_t
+	if err := Stat(p.UserNamespace.String(), &target); err != nil {
+		return nil, &StartError{false, "access user namespace", &os.PathError{Op: "stat", Path: p.UserNamespace.String(), Err: err}, false, false, StartErrNamespace}
+	}
+	if err := Stat(fhs.Proc+"self/ns/user", &self); err != nil {
+		return nil, &StartError{false, "access user namespace", &os.PathError{Op: "stat", Path: fhs.Proc + "self/ns/user", Err: err}, false, false, StartErrNamespace}
+	}
+	if target.Dev != self.Dev || target.Ino != self.Ino {
+		return p.openUserNamespace()
+	}
+
+	var data [2]capData
+	if err := capget(&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, &data); err != nil {
+		return nil, &StartError{false, "read capabilities", os.NewSyscallError("capget", err), false, false, StartErrNamespace}
+	}
+	if data[capToIndex(CAP_SYS_ADMIN)].effective&capToMask(CAP_SYS_ADMIN) == 0 {
+		return nil, &StartError{false, "CAP_SYS_ADMIN in user namespace " + strconv.Quote(p.UserNamespace.String()) +
+			" is required to create container namespaces", EPERM, true, false, StartErrNamespace}
+	}
+
+	for _, id := range [...]struct {
+		name     string
+		pathname string
+		want     *int
+		current  int
+	}{
+		{"uid", fhs.Proc + "self/uid_map", &p.Uid, Getuid()},
+		{"gid", fhs.Proc + "self/gid_map", &p.Gid, Getgid()},
+	} {
+		if data, err := os.ReadFile(id.pathname); err != nil {
+			return nil, &StartError{false, "read " + id.name + " mapping", err, false, false, StartErrNamespace}
+		} else if err = checkIdentity(id.name, string(data), *id.want, id.current); err != nil {
+			return nil, err
+		}
+		*id.want = id.current
+	}
+	return nil, nil
+}
+
+// openUserNamespace opens [Params.UserNamespace] to be joined by container init.
+func (p *Params) openUserNamespace() (*os.File, error) {
+	if len(p.DropSetupCaps) > 0 {
+		return nil, &StartError{false, "DropSetupCaps is not supported in a joined user namespace", ENOTSUP, true, false, StartErrNamespace}
+	}
+
+	f, err := os.OpenFile(p.UserNamespace.String(), os.O_RDONLY|O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &StartError{false, "open user namespace", err, false, false, StartErrNamespace}
+	}
+	if nstype, _, errno := Syscall(SYS_IOCTL, f.Fd(), _NS_GET_NSTYPE, 0); errno != 0 || nstype != CLONE_NEWUSER {
+		_ = f.Close()
+		return nil, &StartError{false, strconv.Quote(p.UserNamespace.String()) + " is not a user namespace", EINVAL, true, false, StartErrNamespace}
+	}
+	return f, nil
+}
+
+// checkIdentity checks that the identity current is mapped in idMap, and that want is either zero or current.
+func checkIdentity(name, idMap string, want, current int) error {
+	if want != 0 && want != current {
+		return &StartError{false, "requested " + name + " " + strconv.Itoa(want) +
+			" differs from " + name + " " + strconv.Itoa(current) + " in joined user namespace", EINVAL, true, false, StartErrNamespace}
+	}
+	if mapped, err := idMapped(idMap, current); err != nil {
+		return &StartError{false, "parse " + name + " mapping", err, false, false, StartErrNamespace}
+	} else if !mapped {
+		return &StartError{false, name + " " + strconv.Itoa(current) +
+			" is not mapped in joined user namespace", EINVAL, true, false, StartErrNamespace}
+	}
+	return nil
+}
+
+// idMapped returns whether id is covered by the contents of /proc/pid/uid_map or /proc/pid/gid_map.
+func idMapped(idMap string, id int) (bool, error) {
+	for _, line := range strings.Split(idMap, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return false, EINVAL
+		}
+
+		var start, count uint64
+		if v, err := strconv.ParseUint(fields[0], 10, 32); err != nil {
+			return false, err
+		} else {
+			start = v
+		}
+		if v, err := strconv.ParseUint(fields[2], 10, 32); err != nil {
+			return false, err
+		} else {
+			count = v
+		}
+
+		if id >= 0 && uint64(id) >= start && uint64(id) < start+count {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,93 @@
+package container
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"hakurei.app/container/check"
+)
+
+func TestIdMapped(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		idMap   string
+		id      int
+		want    bool
+		wantErr error
+	}{
+		{"initial", "         0          0 4294967295\n", 1000, true, nil},
+		{"single", "      1000       1000          1\n", 1000, true, nil},
+		{"single unmapped", "      1000       1000          1\n", 1001, false, nil},
+		{"multiple", "         0       1000          1\n         1     100000      65536\n", 65536, true, nil},
+		{"multiple end", "         0       1000          1\n         1     100000      65536\n", 65537, false, nil},
+		{"empty", "", 0, false, nil},
+		{"negative", "         0          0 4294967295\n", -1, false, nil},
+		{"short", "0 0\n", 0, false, syscall.EINVAL},
+		{"invalid start", "x 0 1\n", 0, false, &strconv.NumError{Func: "ParseUint", Num: "x", Err: strconv.ErrSyntax}},
+		{"invalid count", "0 0 x\n", 0, false, &strconv.NumError{Func: "ParseUint", Num: "x", Err: strconv.ErrSyntax}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := idMapped(tc.idMap, tc.id)
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("idMapped: error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("idMapped: %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckUserNamespace(t *testing.T) {
+	t.Parallel()
+
+	var data [2]capData
+	if err := capget(&capHeader{_LINUX_CAPABILITY_VERSION_3, 0}, &data); err != nil {
+		t.Fatalf("capget: error = %v", err)
+	}
+	var wantSelf error
+	if data[capToIndex(CAP_SYS_ADMIN)].effective&capToMask(CAP_SYS_ADMIN) == 0 {
+		wantSelf = &StartError{false, "CAP_SYS_ADMIN in user namespace \"/proc/self/ns/user\" is required to create container namespaces", syscall.EPERM, true, false, StartErrNamespace}
+	}
+
+	testCases := []struct {
+		name     string
+		pathname string
+		wantErr  error
+	}{
+		{"nonexistent", "/proc/nonexistent", &StartError{false, "access user namespace", &os.PathError{Op: "stat", Path: "/proc/nonexistent", Err: syscall.ENOENT}, false, false, StartErrNamespace}},
+		{"file", "/proc/self/status", &StartError{false, "\"/proc/self/status\" is not a user namespace", syscall.EINVAL, true, false, StartErrNamespace}},
+		{"net", "/proc/self/ns/net", &StartError{false, "\"/proc/self/ns/net\" is not a user namespace", syscall.EINVAL, true, false, StartErrNamespace}},
+		{"self", "/proc/self/ns/user", wantSelf},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &Params{UserNamespace: check.MustAbs(tc.pathname)}
+			if f, err := p.checkUserNamespace(); !reflect.DeepEqual(err, tc.wantErr) {
+				t.Errorf("checkUserNamespace: error = %v, want %v", err, tc.wantErr)
+			} else if f != nil {
+				t.Errorf("checkUserNamespace: file = %v", f.Name())
+			}
+		})
+	}
+
+	t.Run("drop setup caps", func(t *testing.T) {
+		t.Parallel()
+
+		p := &Params{UserNamespace: check.MustAbs("/proc/1/ns/user"), DropSetupCaps: []uintptr{CAP_DAC_OVERRIDE}}
+		wantErr := &StartError{false, "DropSetupCaps is not supported in a joined user namespace", syscall.ENOTSUP, true, false, StartErrNamespace}
+		if _, err := p.openUserNamespace(); !reflect.DeepEqual(err, wantErr) {
+			t.Errorf("openUserNamespace: error = %v, want %v", err, wantErr)
+		}
+	})
+}
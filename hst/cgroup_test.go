@@ -1,8 +1,14 @@
 package hst
 
 import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestCgroupInstancePath(t *testing.T) {
@@ -24,3 +30,246 @@ func TestCgroupInstancePath(t *testing.T) {
 		t.Fatalf("InstancePath: got %q, want prefix %q", got, wantPrefix)
 	}
 }
+
+func TestCgroupValidate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		config *CgroupConfig
+		want   error
+	}{
+		{"nil", nil, nil},
+		{"zero", new(CgroupConfig), nil},
+		{"limits", &CgroupConfig{LimitCPU: 50000, CPUWeight: 200, LimitMemory: 1 << 30, MemorySwapMax: 1 << 29, MemoryLow: 1 << 28, LimitPids: 64}, nil},
+		{"accounting", &CgroupConfig{Accounting: true}, nil},
+		{"cpuset", &CgroupConfig{CPUSet: "0"}, nil},
+		{"io", &CgroupConfig{LimitIO: map[string]CgroupIOLimit{
+			"8:0":   {RBPS: 1 << 20, WIOPS: 120},
+			"259:0": {WBPS: 1 << 20},
+		}}, nil},
+
+		{"negative pids", &CgroupConfig{LimitPids: -1}, &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: "cgroup limit pids cannot be negative"}},
+		{"cpu weight bounds", &CgroupConfig{CPUWeight: CgroupCPUWeightMax}, nil},
+		{"cpu weight range", &CgroupConfig{CPUWeight: CgroupCPUWeightMax + 1}, &AppError{Step: "validate configuration", Err: syscall.ERANGE,
+			Msg: "cgroup cpu weight 10001 out of range"}},
+		{"accounting cpu weight", &CgroupConfig{Accounting: true, CPUWeight: 100}, &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: "cgroup accounting mode cannot be combined with limits"}},
+		{"accounting limits", &CgroupConfig{Accounting: true, LimitMemory: 1 << 30}, &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: "cgroup accounting mode cannot be combined with limits"}},
+		{"accounting memory low", &CgroupConfig{Accounting: true, MemoryLow: 1 << 28}, &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: "cgroup accounting mode cannot be combined with limits"}},
+		{"accounting io", &CgroupConfig{Accounting: true, LimitIO: map[string]CgroupIOLimit{"8:0": {RBPS: 1}}}, &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: "cgroup accounting mode cannot be combined with limits"}},
+		{"accounting cpuset", &CgroupConfig{Accounting: true, CPUSet: "0"}, &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: "cgroup accounting mode cannot be combined with limits"}},
+		{"io device name", &CgroupConfig{LimitIO: map[string]CgroupIOLimit{"sda": {RBPS: 1}}}, &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: `invalid cgroup io device "sda"`}},
+		{"io device minor", &CgroupConfig{LimitIO: map[string]CgroupIOLimit{"8:": {RBPS: 1}}}, &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: `invalid cgroup io device "8:"`}},
+		{"io device sign", &CgroupConfig{LimitIO: map[string]CgroupIOLimit{"+8:0": {RBPS: 1}}}, &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: `invalid cgroup io device "+8:0"`}},
+		{"io device extra", &CgroupConfig{LimitIO: map[string]CgroupIOLimit{"8:0:1": {RBPS: 1}}}, &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: `invalid cgroup io device "8:0:1"`}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if err := tc.config.Validate(); !reflect.DeepEqual(err, tc.want) {
+				t.Errorf("Validate: error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestCgroupSlicePath(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		slice   string
+		want    string
+		wantErr error
+	}{
+		{"default", "", defaultCgroupSlice, nil},
+		{"relative", "user.slice/hakurei.slice", CgroupRoot + "/user.slice/hakurei.slice", nil},
+		{"relative clean", "user.slice/../hakurei.slice/", CgroupRoot + "/hakurei.slice", nil},
+		{"absolute", "/run/cgroup/hakurei.slice", "/run/cgroup/hakurei.slice", nil},
+
+		{"escape", "../../etc", "", &CgroupPathError{"../../etc", "/sys/etc", ErrCgroupEscape}},
+		{"escape sibling", "../cgroup.slice", "", &CgroupPathError{"../cgroup.slice", "/sys/fs/cgroup.slice", ErrCgroupEscape}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := (&CgroupConfig{Slice: tc.slice}).SlicePath()
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("SlicePath: error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if got.String() != tc.want {
+				t.Errorf("SlicePath: %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("validate", func(t *testing.T) {
+		t.Parallel()
+		err := (&CgroupConfig{Slice: "../../etc"}).Validate()
+		if !errors.Is(err, ErrCgroupPath) {
+			t.Errorf("Validate: error = %v, want %v", err, ErrCgroupPath)
+		}
+		if !errors.Is(err, ErrCgroupEscape) {
+			t.Errorf("Validate: error = %v, want %v", err, ErrCgroupEscape)
+		}
+
+		const wantMsg = `invalid cgroup slice "../../etc" resolving to "/sys/etc": path escapes cgroup root`
+		if msg, ok := err.(*AppError); !ok || msg.Message() != wantMsg {
+			t.Errorf("Message: %v, want %q", err, wantMsg)
+		}
+	})
+}
+
+func TestCgroupConfigJSON(t *testing.T) {
+	t.Parallel()
+
+	want := &CgroupConfig{LimitPids: 64, LimitIO: map[string]CgroupIOLimit{
+		"8:0":   {RBPS: 1 << 20, WBPS: 1 << 19, RIOPS: 240, WIOPS: 120},
+		"259:0": {WBPS: 1 << 20},
+	}}
+	const wantData = `{"limit_pids":64,"limit_io":{"259:0":{"wbps":1048576},"8:0":{"rbps":1048576,"wbps":524288,"riops":240,"wiops":120}}}`
+
+	if data, err := json.Marshal(want); err != nil {
+		t.Fatalf("Marshal: error = %v", err)
+	} else if string(data) != wantData {
+		t.Errorf("Marshal: %s, want %s", data, wantData)
+	}
+
+	var got CgroupConfig
+	if err := json.Unmarshal([]byte(wantData), &got); err != nil {
+		t.Fatalf("Unmarshal: error = %v", err)
+	}
+	if !reflect.DeepEqual(&got, want) {
+		t.Errorf("Unmarshal: %#v, want %#v", &got, want)
+	}
+}
+
+func TestCgroupReadStats(t *testing.T) {
+	t.Parallel()
+
+	var id ID
+	if err := id.UnmarshalText([]byte("0123456789abcdef0123456789abcdef")); err != nil {
+		t.Fatalf("UnmarshalText: %v", err)
+	}
+
+	const cpuStat = "usage_usec 2500000\nuser_usec 2000000\nsystem_usec 500000\n" +
+		"core_sched.force_idle_usec 0\nnr_periods 40\nnr_throttled 3\nthrottled_usec 1500\n" +
+		"nr_bursts 0\nburst_usec 0\n"
+
+	testCases := []struct {
+		name    string
+		files   map[string]string
+		want    *CgroupStats
+		wantMsg string
+	}{
+		{"full", map[string]string{
+			"cpu.stat":       cpuStat,
+			"memory.current": "1048576\n",
+			"memory.peak":    "4194304\n",
+			"pids.current":   "7\n",
+		}, &CgroupStats{
+			CPUUsage:            2500 * time.Millisecond,
+			CPUUser:             2 * time.Second,
+			CPUSystem:           500 * time.Millisecond,
+			CPUThrottledPeriods: 3,
+			CPUThrottled:        1500 * time.Microsecond,
+			MemoryCurrent:       1 << 20,
+			MemoryPeak:          1 << 22,
+			PidsCurrent:         7,
+		}, ""},
+
+		{"no memory.peak", map[string]string{
+			"cpu.stat":       cpuStat,
+			"memory.current": "1048576\n",
+			"pids.current":   "7\n",
+		}, &CgroupStats{
+			CPUUsage:            2500 * time.Millisecond,
+			CPUUser:             2 * time.Second,
+			CPUSystem:           500 * time.Millisecond,
+			CPUThrottledPeriods: 3,
+			CPUThrottled:        1500 * time.Microsecond,
+			MemoryCurrent:       1 << 20,
+			PidsCurrent:         7,
+		}, ""},
+
+		{"cpu only", map[string]string{
+			"cpu.stat": "usage_usec 1\nuser_usec 1\nsystem_usec 0\n",
+		}, &CgroupStats{
+			CPUUsage: time.Microsecond,
+			CPUUser:  time.Microsecond,
+		}, ""},
+
+		{"missing", nil, nil, ""},
+		{"invalid cpu.stat", map[string]string{
+			"cpu.stat": "usage_usec\n",
+		}, nil, "invalid cpu.stat in "},
+		{"invalid pids.current", map[string]string{
+			"cpu.stat":     cpuStat,
+			"pids.current": "max\n",
+		}, nil, "invalid pids.current in "},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &CgroupConfig{Slice: t.TempDir()}
+			if tc.files != nil {
+				pathname, err := cfg.InstancePath("42", &id)
+				if err != nil {
+					t.Fatalf("InstancePath: error = %v", err)
+				}
+				if err = os.MkdirAll(pathname.String(), 0700); err != nil {
+					t.Fatal(err)
+				}
+				for name, data := range tc.files {
+					if err = os.WriteFile(pathname.Append(name).String(), []byte(data), 0600); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			got, err := cfg.ReadStats("42", &id)
+			if tc.want != nil {
+				if err != nil {
+					t.Fatalf("ReadStats: error = %v", err)
+				}
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("ReadStats: %#v, want %#v", got, tc.want)
+				}
+				return
+			}
+
+			var e *AppError
+			if !errors.As(err, &e) {
+				t.Fatalf("ReadStats: error = %v", err)
+			}
+			if tc.wantMsg == "" {
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("ReadStats: error = %v, want %v", err, os.ErrNotExist)
+				}
+			} else if !strings.HasPrefix(e.Msg, tc.wantMsg) {
+				t.Errorf("ReadStats: Msg = %q, want prefix %q", e.Msg, tc.wantMsg)
+			}
+		})
+	}
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+		if _, err := new(CgroupConfig).ReadStats("42", nil); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("ReadStats: error = %v, want %v", err, syscall.EINVAL)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package hst
+
+import (
+	"bufio"
+	"bytes"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"hakurei.app/container/check"
+)
+
+// memoryEventsName is the name of the file holding memory event counters of a cgroup.
+const memoryEventsName = "memory.events"
+
+// memoryEventsBuffer is the capacity of the channel returned by [MemoryWatcher.Events].
+const memoryEventsBuffer = 1 << 4
+
+// MemoryEvent describes a memory event counter of a cgroup that increased.
+type MemoryEvent struct {
+	// Name of the counter as it appears in memory.events, e.g. "high" or "max".
+	Name string
+	// Number of times the event occurred since the cgroup was created.
+	Count uint64
+}
+
+// MemoryWatcher delivers increases of memory event counters of a cgroup.
+type MemoryWatcher struct {
+	// pathname of memory.events
+	pathname string
+	// inotify instance watching pathname
+	f *os.File
+	// counters of the last read
+	counts map[string]uint64
+
+	events chan MemoryEvent
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// WatchMemoryEvents starts watching memory.events of the per-instance cgroup directory of
+// [CgroupConfig.InstancePath] via [WatchCgroupMemoryEvents]. This is useful for launchers
+// reacting to a running container approaching its memory limit, such as by reclaiming memory
+// or stopping the container, independently of the events logged by the shim if MemoryEvents is set.
+func (c *CgroupConfig) WatchMemoryEvents(identity string, id *ID) (*MemoryWatcher, error) {
+	pathname, err := c.InstancePath(identity, id)
+	if err != nil {
+		return nil, &AppError{Step: "watch memory events", Err: err}
+	}
+	return WatchCgroupMemoryEvents(pathname)
+}
+
+// WatchCgroupMemoryEvents starts watching memory.events of the cgroup at pathname.
+// Counters are read once before returning, so only events occurring afterwards are delivered.
+func WatchCgroupMemoryEvents(pathname *check.Absolute) (*MemoryWatcher, error) {
+	w := &MemoryWatcher{
+		pathname: pathname.Append(memoryEventsName).String(),
+		events:   make(chan MemoryEvent, memoryEventsBuffer),
+		done:     make(chan struct{}),
+	}
+
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	// nonblocking descriptor is registered with the runtime poller
+	w.f = os.NewFile(uintptr(fd), "inotify")
+	if _, err = syscall.InotifyAddWatch(fd, w.pathname, syscall.IN_MODIFY); err != nil {
+		_ = w.f.Close()
+		return nil, &os.PathError{Op: "inotify_add_watch", Path: w.pathname, Err: err}
+	}
+	if w.counts, err = readMemoryEvents(w.pathname); err != nil {
+		_ = w.f.Close()
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	go w.watch()
+	return w, nil
+}
+
+// Events returns the channel events are delivered on. It is closed once the watcher stops,
+// either by a call to Close or when memory.events can no longer be read, e.g. when the cgroup is removed.
+func (w *MemoryWatcher) Events() <-chan MemoryEvent { return w.events }
+
+// Close stops the watcher and waits for it to release its resources. Close must only be called once.
+func (w *MemoryWatcher) Close() error {
+	close(w.done)
+	err := w.f.Close()
+	w.wg.Wait()
+	return err
+}
+
+// watch delivers events until the inotify instance is closed or memory.events can no longer be read.
+func (w *MemoryWatcher) watch() {
+	defer w.wg.Done()
+	defer close(w.events)
+
+	buf := make([]byte, syscall.SizeofInotifyEvent+syscall.PathMax+1)
+	for {
+		if _, err := w.f.Read(buf); err != nil {
+			return
+		}
+		counts, err := readMemoryEvents(w.pathname)
+		if err != nil {
+			return
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(counts)) {
+			if counts[name] <= w.counts[name] {
+				continue
+			}
+			select {
+			case w.events <- MemoryEvent{name, counts[name]}:
+			case <-w.done:
+				return
+			}
+		}
+		w.counts = counts
+	}
+}
+
+// readMemoryEvents reads and parses the memory.events file at pathname.
+func readMemoryEvents(pathname string) (map[string]uint64, error) {
+	data, err := os.ReadFile(pathname)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]uint64)
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		name, value, ok := strings.Cut(s.Text(), " ")
+		if !ok {
+			return nil, &os.PathError{Op: "parse", Path: pathname, Err: strconv.ErrSyntax}
+		}
+		if counts[name], err = strconv.ParseUint(value, 10, 64); err != nil {
+			return nil, &os.PathError{Op: "parse", Path: pathname, Err: err}
+		}
+	}
+	return counts, s.Err()
+}
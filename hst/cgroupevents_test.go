@@ -0,0 +1,89 @@
+package hst
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"hakurei.app/container/check"
+)
+
+func TestWatchCgroupMemoryEvents(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+		if _, err := new(CgroupConfig).WatchMemoryEvents("42", nil); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("WatchMemoryEvents: error = %v, want %v", err, syscall.EINVAL)
+		}
+	})
+
+	t.Run("nonexistent", func(t *testing.T) {
+		t.Parallel()
+		if _, err := WatchCgroupMemoryEvents(check.MustAbs(t.TempDir())); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("WatchCgroupMemoryEvents: error = %v", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		d := t.TempDir()
+		if err := os.WriteFile(filepath.Join(d, memoryEventsName), []byte("high\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := WatchCgroupMemoryEvents(check.MustAbs(d)); !errors.Is(err, strconv.ErrSyntax) {
+			t.Errorf("WatchCgroupMemoryEvents: error = %v", err)
+		}
+	})
+
+	t.Run("events", func(t *testing.T) {
+		t.Parallel()
+		d := t.TempDir()
+		pathname := filepath.Join(d, memoryEventsName)
+		write := func(data string) {
+			if err := os.WriteFile(pathname, []byte(data), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		write("low 0\nhigh 2\nmax 0\noom 0\noom_kill 0\n")
+
+		w, err := WatchCgroupMemoryEvents(check.MustAbs(d))
+		if err != nil {
+			t.Fatalf("WatchCgroupMemoryEvents: error = %v", err)
+		}
+		receive := func() (MemoryEvent, bool) {
+			select {
+			case ev, ok := <-w.Events():
+				return ev, ok
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for event")
+				panic("unreachable")
+			}
+		}
+
+		write("low 0\nhigh 5\nmax 1\noom 0\noom_kill 0\n")
+		var got []MemoryEvent
+		for range 2 {
+			if ev, ok := receive(); !ok {
+				t.Fatal("Events: unexpected close")
+			} else {
+				got = append(got, ev)
+			}
+		}
+		if want := []MemoryEvent{{"high", 5}, {"max", 1}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Events: %#v, want %#v", got, want)
+		}
+
+		if err = w.Close(); err != nil {
+			t.Errorf("Close: error = %v", err)
+		}
+		if _, ok := <-w.Events(); ok {
+			t.Error("Events: not closed after Close")
+		}
+	})
+}
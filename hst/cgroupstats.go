@@ -0,0 +1,106 @@
+package hst
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"hakurei.app/container/check"
+)
+
+// CgroupStats holds resource usage read from the instance cgroup by [CgroupConfig.ReadStats].
+// Fields backed by a controller file missing from the instance cgroup hold their zero value.
+type CgroupStats struct {
+	// Total CPU time consumed, from usage_usec in cpu.stat.
+	CPUUsage time.Duration `json:"cpu_usage"`
+	// CPU time consumed in user mode, from user_usec in cpu.stat.
+	CPUUser time.Duration `json:"cpu_user"`
+	// CPU time consumed in kernel mode, from system_usec in cpu.stat.
+	CPUSystem time.Duration `json:"cpu_system"`
+	// Number of enforcement periods throttled by cpu.max, from nr_throttled in cpu.stat.
+	CPUThrottledPeriods uint64 `json:"cpu_throttled_periods,omitempty"`
+	// Total time throttled by cpu.max, from throttled_usec in cpu.stat.
+	CPUThrottled time.Duration `json:"cpu_throttled,omitempty"`
+
+	// Memory usage in bytes at the time of reading, from memory.current.
+	MemoryCurrent uint64 `json:"memory_current"`
+	// Peak memory usage in bytes, from memory.peak, zero on kernels older than Linux 5.19.
+	MemoryPeak uint64 `json:"memory_peak"`
+	// Number of processes at the time of reading, from pids.current.
+	PidsCurrent uint64 `json:"pids_current"`
+}
+
+// ReadStats reads resource usage from the per-instance cgroup directory of [CgroupConfig.InstancePath]
+// via [ReadCgroupStats].
+func (c *CgroupConfig) ReadStats(identity string, id *ID) (*CgroupStats, error) {
+	pathname, err := c.InstancePath(identity, id)
+	if err != nil {
+		return nil, &AppError{Step: "read cgroup statistics", Err: err}
+	}
+	return ReadCgroupStats(pathname)
+}
+
+// ReadCgroupStats reads resource usage from the cgroup directory at pathname. Missing controller
+// files are tolerated, but the cgroup must exist, as cpu.stat is always present.
+func ReadCgroupStats(pathname *check.Absolute) (*CgroupStats, error) {
+	stats := new(CgroupStats)
+	if data, err := os.ReadFile(pathname.Append("cpu.stat").String()); err != nil {
+		return nil, &AppError{Step: "read cgroup statistics", Err: err}
+	} else if err = stats.parseCPUStat(data); err != nil {
+		return nil, &AppError{Step: "read cgroup statistics", Err: err,
+			Msg: "invalid cpu.stat in " + strconv.Quote(pathname.String())}
+	}
+
+	for _, f := range []struct {
+		name string
+		v    *uint64
+	}{
+		{"memory.current", &stats.MemoryCurrent},
+		{"memory.peak", &stats.MemoryPeak},
+		{"pids.current", &stats.PidsCurrent},
+	} {
+		if data, err := os.ReadFile(pathname.Append(f.name).String()); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, &AppError{Step: "read cgroup statistics", Err: err}
+		} else if *f.v, err = strconv.ParseUint(string(bytes.TrimSpace(data)), 10, 64); err != nil {
+			return nil, &AppError{Step: "read cgroup statistics", Err: err,
+				Msg: "invalid " + f.name + " in " + strconv.Quote(pathname.String())}
+		}
+	}
+	return stats, nil
+}
+
+// parseCPUStat populates fields of [CgroupStats] backed by cpu.stat. Unknown keys are ignored.
+func (stats *CgroupStats) parseCPUStat(data []byte) error {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		key, value, ok := strings.Cut(s.Text(), " ")
+		if !ok {
+			return strconv.ErrSyntax
+		}
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "usage_usec":
+			stats.CPUUsage = time.Duration(v) * time.Microsecond
+		case "user_usec":
+			stats.CPUUser = time.Duration(v) * time.Microsecond
+		case "system_usec":
+			stats.CPUSystem = time.Duration(v) * time.Microsecond
+		case "nr_throttled":
+			stats.CPUThrottledPeriods = v
+		case "throttled_usec":
+			stats.CPUThrottled = time.Duration(v) * time.Microsecond
+		}
+	}
+	return s.Err()
+}
@@ -2,8 +2,12 @@ package hst
 
 import (
 	"errors"
+	"io/fs"
+	"os"
+	"path"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"hakurei.app/container/check"
 )
@@ -26,6 +30,15 @@ type Config struct {
 	// Direct access to wayland socket, no attempt is made to attach security-context-v1
 	// and the bare socket is made available to the container.
 	DirectWayland bool `json:"direct_wayland,omitempty"`
+	// Direct access to the X11 authority file of the privileged user, bound read-only into the container.
+	// This exposes X11 credentials of the privileged user to the container. Directories containing
+	// the file up to the home directory are made traversable by the target user for the duration.
+	DirectXauthority bool `json:"direct_xauthority,omitempty"`
+	// Register a per-instance MIT-MAGIC-COOKIE-1 authorization with the X server via xauth and
+	// its SECURITY extension, instead of inserting the target user into X11 hosts via ChangeHosts.
+	// Falls back to ChangeHosts if xauth is not found in PATH, or if DISPLAY is not in the :%d form.
+	// Mutually exclusive with DirectXauthority.
+	X11Cookie bool `json:"x11_cookie,omitempty"`
 
 	// Extra acl updates to perform before setuid.
 	ExtraPerms []ExtraPermConfig `json:"extra_perms,omitempty"`
@@ -33,6 +46,7 @@ type Config struct {
 	// Numerical application id, passed to hsu, used to derive init user namespace credentials.
 	Identity int `json:"identity"`
 	// Init user namespace supplementary groups inherited by all container processes.
+	// Group names are resolved against the host group database, numerical group ids are used as is.
 	Groups []string `json:"groups"`
 
 	// High level configuration applied to the underlying [container].
@@ -47,11 +61,13 @@ var (
 	// ErrIdentityBounds is returned by [Config.Validate] for an out of bounds [Config.Identity] value.
 	ErrIdentityBounds = errors.New("identity out of bounds")
 
-	// ErrEnviron is returned by [Config.Validate] if an environment variable name contains '=' or NUL.
+	// ErrEnviron is returned by [Config.Validate] if an environment variable name or pattern is invalid.
 	ErrEnviron = errors.New("invalid environment variable name")
 )
 
 // Validate checks [Config] and returns [AppError] if an invalid value is encountered.
+// Validate does not access the host, so values such as bus policies referenced via
+// [BusConfig.PolicyRef] and the cgroup cpuset are checked when the container is set up.
 func (config *Config) Validate() error {
 	if config == nil {
 		return &AppError{Step: "validate configuration", Err: ErrConfigNull,
@@ -71,6 +87,11 @@ func (config *Config) Validate() error {
 		return err
 	}
 
+	if config.DirectXauthority && config.X11Cookie {
+		return &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: "direct X11 authority access cannot be combined with X11 cookie"}
+	}
+
 	if config.Container == nil {
 		return &AppError{Step: "validate configuration", Err: ErrConfigNull,
 			Msg: "configuration missing container state"}
@@ -91,6 +112,40 @@ func (config *Config) Validate() error {
 	if err := config.Container.validateCgroup(); err != nil {
 		return err
 	}
+	if err := config.Container.validateSocketFamilies(); err != nil {
+		return err
+	}
+	if err := config.Container.validateSeccompAction(); err != nil {
+		return err
+	}
+	if err := config.Container.validateInputDevices(); err != nil {
+		return err
+	}
+	if err := config.Container.validateDevices(); err != nil {
+		return err
+	}
+	if err := config.Container.validateRuntimeSockets(); err != nil {
+		return err
+	}
+	if err := config.Container.validatePublishPorts(); err != nil {
+		return err
+	}
+	if err := config.Container.validateDevShmSize(); err != nil {
+		return err
+	}
+	if err := config.Container.validateIdleTimeout(); err != nil {
+		return err
+	}
+
+	if err := config.Container.validateMountOptions(); err != nil {
+		return err
+	}
+	if err := config.Container.validateFilesystem(); err != nil {
+		return err
+	}
+	if err := config.Container.validatePrivateTmp(); err != nil {
+		return err
+	}
 
 	for key := range config.Container.Env {
 		if strings.IndexByte(key, '=') != -1 || strings.IndexByte(key, 0) != -1 {
@@ -98,10 +153,136 @@ func (config *Config) Validate() error {
 				Msg: "invalid environment variable " + strconv.Quote(key)}
 		}
 	}
+	for _, key := range config.Container.EnvScrub {
+		if key == "" || strings.IndexByte(key, '=') != -1 || strings.IndexByte(key, 0) != -1 {
+			return &AppError{Step: "validate configuration", Err: ErrEnviron,
+				Msg: "invalid environment variable " + strconv.Quote(key)}
+		}
+	}
+	for _, pattern := range config.Container.PassEnv {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" ||
+			strings.IndexByte(pattern, '=') != -1 || strings.IndexByte(pattern, 0) != -1 {
+			return &AppError{Step: "validate configuration", Err: ErrEnviron,
+				Msg: "invalid environment variable pattern " + strconv.Quote(pattern)}
+		}
+	}
 
 	return nil
 }
 
+// WorldWritableError describes a writable bind mount point whose source is a world-writable
+// host path not owned by the caller.
+type WorldWritableError struct {
+	// Index of the offending entry in [ContainerConfig.Filesystem].
+	Index int
+	// Pathname of the offending source in the init mount namespace.
+	Source *check.Absolute
+	// Owner of Source.
+	Uid uint32
+}
+
+func (e *WorldWritableError) Error() string {
+	return "filesystem at index " + strconv.Itoa(e.Index) +
+		" binds world-writable path " + strconv.Quote(e.Source.String()) +
+		" owned by uid " + strconv.FormatUint(uint64(e.Uid), 10) + " as writable"
+}
+
+/*
+CheckWritableBinds returns a [WorldWritableError] for every writable bind mount point in
+[ContainerConfig.Filesystem] whose source is world-writable and not owned by uid.
+
+Such paths hold state shared with every other user on the host, and a container with write
+access to them is able to influence processes running outside the sandbox. This is a lint,
+and it is up to the caller whether these are treated as warnings or errors. Sources that
+cannot be accessed via stat are skipped, as they are reported during container setup.
+*/
+func (config *Config) CheckWritableBinds(uid int, stat func(name string) (os.FileInfo, error)) []*WorldWritableError {
+	if config == nil || config.Container == nil {
+		return nil
+	}
+
+	var errs []*WorldWritableError
+	for i, c := range config.Container.Filesystem {
+		b, ok := c.FilesystemConfig.(*FSBind)
+		if !ok || b == nil || b.Source == nil || !(b.writable() || b.Device) {
+			continue
+		}
+
+		fi, err := stat(b.Source.String())
+		if err != nil || fi.Mode().Perm()&0002 == 0 {
+			continue
+		}
+		owner := ^uint32(0)
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+			owner = st.Uid
+		}
+		if owner != uint32(uid) {
+			errs = append(errs, &WorldWritableError{i, b.Source, owner})
+		}
+	}
+	return errs
+}
+
+// PathError describes a pathname in [ContainerConfig] not present in a container filesystem.
+type PathError struct {
+	// Name of the offending field in [ContainerConfig].
+	Field string
+	// Pathname held by Field.
+	Path *check.Absolute
+	// Error returned while accessing Path, or [syscall.EISDIR] or [syscall.ENOTDIR] for a
+	// file of unexpected type.
+	Err error
+}
+
+func (e *PathError) Unwrap() error { return e.Err }
+func (e *PathError) Error() string {
+	return "container " + e.Field + " " + strconv.Quote(e.Path.String()) + ": " + e.Err.Error()
+}
+
+/*
+ValidatePaths checks that the initial program, shell and home directory of [ContainerConfig]
+are present within rootfs, which represents the root of the container filesystem. This is
+useful for checking a configuration against an image without starting the container.
+
+Paths are resolved relative to the root of rootfs. A [PathError] is joined into the returned
+error for every missing path, or for a path of unexpected type. The structural checks of
+[Config.Validate] are not performed, and null fields are skipped.
+*/
+func (config *Config) ValidatePaths(rootfs fs.FS) error {
+	if config == nil || config.Container == nil {
+		return &AppError{Step: "validate configuration", Err: ErrConfigNull,
+			Msg: "invalid configuration"}
+	}
+
+	var errs []error
+	for _, p := range [...]struct {
+		field string
+		path  *check.Absolute
+		dir   bool
+	}{
+		{"path", config.Container.Path, false},
+		{"shell", config.Container.Shell, false},
+		{"home", config.Container.Home, true},
+	} {
+		if p.path == nil {
+			continue
+		}
+
+		name := strings.TrimPrefix(p.path.String(), "/")
+		if name == "" {
+			name = "."
+		}
+		if fi, err := fs.Stat(rootfs, name); err != nil {
+			errs = append(errs, &PathError{p.field, p.path, err})
+		} else if fi.IsDir() && !p.dir {
+			errs = append(errs, &PathError{p.field, p.path, syscall.EISDIR})
+		} else if !fi.IsDir() && p.dir {
+			errs = append(errs, &PathError{p.field, p.path, syscall.ENOTDIR})
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // ExtraPermConfig describes an acl update to perform before setuid.
 type ExtraPermConfig struct {
 	// Whether to create Path as a directory if it does not exist.
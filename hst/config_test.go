@@ -1,10 +1,18 @@
 package hst_test
 
 import (
+	"errors"
+	"io/fs"
+	"os"
 	"reflect"
+	"syscall"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"hakurei.app/container/check"
 	"hakurei.app/container/fhs"
+	"hakurei.app/container/std"
 	"hakurei.app/hst"
 )
 
@@ -26,6 +34,8 @@ func TestConfigValidate(t *testing.T) {
 			&hst.BadInterfaceError{Interface: "", Segment: "session"}},
 		{"dbus system", &hst.Config{SystemBus: &hst.BusConfig{See: []string{""}}},
 			&hst.BadInterfaceError{Interface: "", Segment: "system"}},
+		{"x11 cookie", &hst.Config{DirectXauthority: true, X11Cookie: true}, &hst.AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: "direct X11 authority access cannot be combined with X11 cookie"}},
 		{"container", &hst.Config{}, &hst.AppError{Step: "validate configuration", Err: hst.ErrConfigNull,
 			Msg: "configuration missing container state"}},
 		{"home", &hst.Config{Container: &hst.ContainerConfig{}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrConfigNull,
@@ -53,6 +63,459 @@ func TestConfigValidate(t *testing.T) {
 			Env:   map[string]string{"TERM\x00": ""},
 		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrEnviron,
 			Msg: `invalid environment variable "TERM\x00"`}},
+		{"env scrub equals", &hst.Config{Container: &hst.ContainerConfig{
+			Home:     fhs.AbsTmp,
+			Shell:    fhs.AbsTmp,
+			Path:     fhs.AbsTmp,
+			EnvScrub: []string{"SSH_AUTH_SOCK", "TERM="},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrEnviron,
+			Msg: `invalid environment variable "TERM="`}},
+		{"env scrub empty", &hst.Config{Container: &hst.ContainerConfig{
+			Home:     fhs.AbsTmp,
+			Shell:    fhs.AbsTmp,
+			Path:     fhs.AbsTmp,
+			EnvScrub: []string{""},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrEnviron,
+			Msg: `invalid environment variable ""`}},
+		{"pass env pattern", &hst.Config{Container: &hst.ContainerConfig{
+			Home:    fhs.AbsTmp,
+			Shell:   fhs.AbsTmp,
+			Path:    fhs.AbsTmp,
+			PassEnv: []string{"MESA_*", "WAYLAND_[DEBUG"},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrEnviron,
+			Msg: `invalid environment variable pattern "WAYLAND_[DEBUG"`}},
+		{"pass env equals", &hst.Config{Container: &hst.ContainerConfig{
+			Home:    fhs.AbsTmp,
+			Shell:   fhs.AbsTmp,
+			Path:    fhs.AbsTmp,
+			PassEnv: []string{"MESA_*="},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrEnviron,
+			Msg: `invalid environment variable pattern "MESA_*="`}},
+		{"socket family", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			DenySocketFamilies: []string{"inet", "AF_INET6"},
+		}}, &hst.AppError{Step: "validate configuration", Err: std.SocketFamilyNameError("AF_INET6"),
+			Msg: `invalid socket family "AF_INET6"`}},
+		{"seccomp action", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			SeccompAction: "kill",
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrSeccompAction,
+			Msg: `invalid seccomp action "kill"`}},
+		{"input device null", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			InputDevices: []*check.Absolute{nil},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrConfigNull,
+			Msg: "input device path must not be null"}},
+		{"input device outside", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			InputDevices: []*check.Absolute{check.MustAbs("/dev/input/event0"), check.MustAbs("/dev/hidraw0")},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrInputDevice,
+			Msg: `input device "/dev/hidraw0" is not under /dev/input/`}},
+		{"input device traversal", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			InputDevices: []*check.Absolute{check.MustAbs("/dev/input/../tty0")},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrInputDevice,
+			Msg: `input device "/dev/input/../tty0" is not under /dev/input/`}},
+		{"input device directory", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			InputDevices: []*check.Absolute{check.MustAbs("/dev/input/")},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrInputDevice,
+			Msg: `input device "/dev/input/" is not under /dev/input/`}},
+		{"device null", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Devices: []*check.Absolute{nil},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrConfigNull,
+			Msg: "device path must not be null"}},
+		{"device outside", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Devices: []*check.Absolute{check.MustAbs("/dev/kvm"), check.MustAbs("/sys/class/kvm")},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrDevice,
+			Msg: `device "/sys/class/kvm" is not under /dev/`}},
+		{"device traversal", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Devices: []*check.Absolute{check.MustAbs("/dev/../etc/shadow")},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrDevice,
+			Msg: `device "/dev/../etc/shadow" is not under /dev/`}},
+		{"device directory", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Devices: []*check.Absolute{check.MustAbs("/dev/")},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrDevice,
+			Msg: `device "/dev/" is not under /dev/`}},
+		{"runtime socket separator", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			RuntimeSockets: []string{"bus", "pulse/native"},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrRuntimeSocket,
+			Msg: `runtime socket "pulse/native" is not a file name`}},
+		{"runtime socket dotdot", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			RuntimeSockets: []string{".."},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrRuntimeSocket,
+			Msg: `runtime socket ".." is not a file name`}},
+		{"runtime socket empty", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			RuntimeSockets: []string{""},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrRuntimeSocket,
+			Msg: `runtime socket "" is not a file name`}},
+		{"publish ports host net", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+			Flags: hst.FHostNet,
+
+			PublishPorts: []hst.PortMap{{Container: 8080, Host: 8080}},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrPublishPort,
+			Msg: `publishing ports requires a container network namespace`}},
+		{"publish ports protocol", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			PublishPorts: []hst.PortMap{{Container: 8080, Host: 8080}, {Protocol: "sctp", Container: 8080, Host: 8080}},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrPublishPort,
+			Msg: `published port at index 1 has invalid protocol "sctp"`}},
+		{"publish ports zero", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			PublishPorts: []hst.PortMap{{Protocol: "udp", Host: 5353}},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrPublishPort,
+			Msg: `published port at index 0 must not be zero`}},
+		{"publish ports duplicate", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			PublishPorts: []hst.PortMap{{Container: 8080, Host: 8080}, {Protocol: "udp", Container: 8080, Host: 8080}, {Protocol: "tcp", Container: 8081, Host: 8080}},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrPublishPort,
+			Msg: `published port at index 2 has the same host port 8080/tcp as published port at index 0`}},
+		{"dev shm size small", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			DevShmSize: 1 << 10,
+		}}, &hst.AppError{Step: "validate configuration", Err: syscall.ERANGE,
+			Msg: `/dev/shm size 1024 out of range`}},
+		{"dev shm size large", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			DevShmSize: 1 << 41,
+		}}, &hst.AppError{Step: "validate configuration", Err: syscall.ERANGE,
+			Msg: `/dev/shm size 2199023255552 out of range`}},
+		{"idle timeout negative", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			IdleTimeout: -1,
+		}}, &hst.AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: `idle timeout cannot be negative`}},
+		{"idle timeout cgroup", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			IdleTimeout: time.Minute,
+		}}, &hst.AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: `idle timeout requires a cgroup`}},
+		{"mount options default", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSEphemeral{Target: fhs.AbsTmp}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/var/cache/shared"), Write: true, Options: []string{"nodev", "nosuid", "noexec"}}},
+			},
+		}}, nil},
+		{"mount options unknown", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSEphemeral{Target: fhs.AbsTmp}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/var/cache/shared"), Write: true, Options: []string{"nosuid", "relatime"}}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountOption,
+			Msg: `unknown mount option "relatime"`}},
+		{"mount options nodev device", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+			Flags: hst.FDevice,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSEphemeral{Target: fhs.AbsTmp}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/var/cache/shared"), Write: true, Device: true, Options: []string{"nodev"}}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountOption,
+			Msg: `mount option "nodev" conflicts with device access`}},
+		{"mount options nodev dev", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+			Flags: hst.FDevice,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSEphemeral{Target: fhs.AbsTmp}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/var/cache/shared"), Write: true, Options: []string{"dev", "nodev"}}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountOption,
+			Msg: `mount option "nodev" conflicts with device access`}},
+		{"mount options exec noexec", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+			Flags: hst.FDevice,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSEphemeral{Target: fhs.AbsTmp}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/var/cache/shared"), Write: true, Options: []string{"noexec", "exec"}}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountOption,
+			Msg: `mount option "noexec" conflicts with "exec"`}},
+		{"mount options dev", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSEphemeral{Target: fhs.AbsTmp}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/var/cache/shared"), Write: true, Options: []string{"nosuid", "dev"}}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountOption,
+			Msg: `mount option "dev" on filesystem at index 1 requires a privileged configuration`}},
+		{"mount options suid", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSEphemeral{Target: fhs.AbsTmp}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/var/cache/shared"), Write: true, Options: []string{"suid"}}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountOption,
+			Msg: `mount option "suid" on filesystem at index 1 requires a privileged configuration`}},
+		{"mount options exec", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSEphemeral{Target: fhs.AbsTmp}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/var/cache/shared"), Write: true, Options: []string{"exec"}}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountOption,
+			Msg: `mount option "exec" on filesystem at index 1 requires a privileged configuration`}},
+		{"mount options privileged", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+			Flags: hst.FDevice,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSEphemeral{Target: fhs.AbsTmp}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/var/cache/shared"), Write: true, Options: []string{"dev", "suid", "exec"}}},
+			},
+		}}, nil},
+		{"filesystem duplicate", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{Target: fhs.AbsRoot, Source: check.MustAbs("/var/lib/hakurei/base/org.debian"), Special: true}},
+				{FilesystemConfig: &hst.FSEphemeral{Target: fhs.AbsTmp}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/tmp/")}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountTarget,
+			Msg: `filesystem at index 2 has the same target "/tmp" as filesystem at index 1`}},
+		{"filesystem duplicate root", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{Target: fhs.AbsRoot, Source: check.MustAbs("/var/lib/hakurei/base/org.debian"), Special: true}},
+				{FilesystemConfig: &hst.FSEphemeral{Target: fhs.AbsRoot}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountTarget,
+			Msg: `filesystem at index 1 has the same target "/" as filesystem at index 0`}},
+		{"filesystem nested", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/run/user/1000/pulse")}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/var/cache")}},
+				{FilesystemConfig: &hst.FSEphemeral{Target: check.MustAbs("/run/user")}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountTarget,
+			Msg: `filesystem at index 0 targeting "/run/user/1000/pulse" is hidden by filesystem at index 2 targeting "/run/user"`}},
+		{"filesystem nested root", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/nix/store")}},
+				{FilesystemConfig: &hst.FSBind{Target: fhs.AbsRoot, Source: check.MustAbs("/var/lib/hakurei/base/org.debian"), Special: true}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountTarget,
+			Msg: `filesystem at index 0 targeting "/nix/store" is hidden by filesystem at index 1 targeting "/"`}},
+		{"filesystem nested ordered", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{Target: fhs.AbsRoot, Source: check.MustAbs("/var/lib/hakurei/base/org.debian"), Special: true}},
+				{FilesystemConfig: &hst.FSEphemeral{Target: check.MustAbs("/run/user")}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/run/user/1000/pulse")}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/run/user-data")}},
+				{FilesystemConfig: &hst.FSLink{Target: check.MustAbs("/run/current-system"), Linkname: "/run/current-system", Dereference: true}},
+			},
+		}}, nil},
+		{"filesystem home target duplicate", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  check.MustAbs("/data/data/org.chromium.Chromium"),
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/mnt/config"), HomeTarget: ".config/"}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/srv/config"), Target: check.MustAbs("/data/data/org.chromium.Chromium/.config")}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountTarget,
+			Msg: `filesystem at index 1 has the same target "/data/data/org.chromium.Chromium/.config" as filesystem at index 0`}},
+		{"filesystem home target nested", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  check.MustAbs("/data/data/org.chromium.Chromium"),
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{HomeSource: ".cache", HomeTarget: ".cache"}},
+				{FilesystemConfig: &hst.FSEphemeral{Target: check.MustAbs("/data/data")}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountTarget,
+			Msg: `filesystem at index 0 targeting "/data/data/org.chromium.Chromium/.cache" is hidden by filesystem at index 1 targeting "/data/data"`}},
+		{"filesystem home source nested", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  check.MustAbs("/data/data/org.chromium.Chromium"),
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{HomeSource: ".cache/chromium", Target: check.MustAbs("/run/cache")}},
+				{FilesystemConfig: &hst.FSEphemeral{Target: check.MustAbs("/run")}},
+			},
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrMountTarget,
+			Msg: `filesystem at index 0 targeting "/run/cache" is hidden by filesystem at index 1 targeting "/run"`}},
+		{"filesystem home escape", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  check.MustAbs("/data/data/org.chromium.Chromium"),
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/mnt/config"), HomeTarget: "../org.mozilla.firefox"}},
+			},
+		}}, &hst.AppError{Step: "expand home relative path", Err: hst.ErrHomeEscape,
+			Msg: `path "../org.mozilla.firefox" escapes home directory`}},
+		{"filesystem home distinct", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  check.MustAbs("/data/data/org.chromium.Chromium"),
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{HomeSource: ".config", HomeTarget: ".config"}},
+				{FilesystemConfig: &hst.FSBind{HomeSource: ".cache", HomeTarget: ".cache"}},
+			},
+		}}, nil},
+		{"private tmp root", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			PrivateTmpPath: fhs.AbsRoot,
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrPrivateTmp,
+			Msg: "private tmp path must not be the root directory"}},
+		{"private tmp mount point", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{Target: fhs.AbsRoot, Source: check.MustAbs("/var/lib/hakurei/base/org.debian"), Special: true}},
+				{FilesystemConfig: &hst.FSEphemeral{Target: fhs.AbsTmp}},
+			},
+			PrivateTmpPath: fhs.AbsTmp,
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrPrivateTmp,
+			Msg: `private tmp path "/tmp" is under filesystem at index 1 targeting "/tmp"`}},
+		{"private tmp nested", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/var/cache")}},
+				{FilesystemConfig: &hst.FSEphemeral{Target: check.MustAbs("/run/user")}},
+			},
+			PrivateTmpPath: check.MustAbs("/run/user/hakurei"),
+		}}, &hst.AppError{Step: "validate configuration", Err: hst.ErrPrivateTmp,
+			Msg: `private tmp path "/run/user/hakurei" is under filesystem at index 1 targeting "/run/user"`}},
+		{"private tmp", &hst.Config{Container: &hst.ContainerConfig{
+			Home:  fhs.AbsTmp,
+			Shell: fhs.AbsTmp,
+			Path:  fhs.AbsTmp,
+
+			Filesystem: []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{Target: fhs.AbsRoot, Source: check.MustAbs("/var/lib/hakurei/base/org.debian"), Special: true}},
+				{FilesystemConfig: &hst.FSEphemeral{Target: check.MustAbs("/run/user")}},
+			},
+			PrivateTmpPath: check.MustAbs("/run/hakurei"),
+		}}, nil},
 		{"valid", &hst.Config{Container: &hst.ContainerConfig{
 			Home:  fhs.AbsTmp,
 			Shell: fhs.AbsTmp,
@@ -69,6 +532,186 @@ func TestConfigValidate(t *testing.T) {
 	}
 }
 
+func TestCheckWritableBinds(t *testing.T) {
+	t.Parallel()
+
+	stat := func(name string) (os.FileInfo, error) {
+		switch name {
+		case "/tmp":
+			return stubFileInfo{os.ModeDir | os.ModeSticky | 0777, 0}, nil
+		case "/home/user/.cache":
+			return stubFileInfo{os.ModeDir | 0777, 1000}, nil
+		case "/srv/share":
+			return stubFileInfo{os.ModeDir | 0775, 0}, nil
+		case "/var/tmp/shared":
+			return stubFileInfo{0666, 1001}, nil
+		default:
+			return nil, &os.PathError{Op: "stat", Path: name, Err: syscall.ENOENT}
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		config *hst.Config
+		want   []*hst.WorldWritableError
+	}{
+		{"nil", nil, nil},
+		{"nil container", new(hst.Config), nil},
+
+		{"clean", &hst.Config{Container: &hst.ContainerConfig{Filesystem: []hst.FilesystemConfigJSON{
+			{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/tmp")}},
+			{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/home/user/.cache"), Write: true}},
+			{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/srv/share"), Write: true}},
+			{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/nonexistent"), Write: true, Optional: true}},
+			{FilesystemConfig: &hst.FSBind{HomeSource: ".cache", Write: true}},
+			{FilesystemConfig: &hst.FSEphemeral{Target: check.MustAbs("/tmp"), Write: true}},
+		}}}, nil},
+
+		{"world-writable", &hst.Config{Container: &hst.ContainerConfig{Filesystem: []hst.FilesystemConfigJSON{
+			{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/home/user/.cache"), Write: true}},
+			{FilesystemConfig: &hst.FSBind{Target: check.MustAbs("/tmp"), Source: check.MustAbs("/tmp"), Write: true}},
+			{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/var/tmp/shared"), Device: true}},
+		}}}, []*hst.WorldWritableError{
+			{Index: 1, Source: check.MustAbs("/tmp"), Uid: 0},
+			{Index: 2, Source: check.MustAbs("/var/tmp/shared"), Uid: 1001},
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.config.CheckWritableBinds(1000, stat); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("CheckWritableBinds: %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		const want = `filesystem at index 1 binds world-writable path "/tmp" owned by uid 0 as writable`
+		if got := (&hst.WorldWritableError{Index: 1, Source: check.MustAbs("/tmp")}).Error(); got != want {
+			t.Errorf("Error: %q, want %q", got, want)
+		}
+	})
+}
+
+func TestConfigValidatePaths(t *testing.T) {
+	t.Parallel()
+
+	rootfs := fstest.MapFS{
+		"run/current-system/sw/bin/zsh":      {Mode: 0755},
+		"run/current-system/sw/bin/chromium": {Mode: 0755},
+		"data/data/org.chromium.Chromium":    {Mode: fs.ModeDir | 0700},
+		"etc":                                {Mode: fs.ModeDir | 0755},
+		"etc/passwd":                         {Mode: 0644},
+	}
+	newConfig := func(pathname, shell, home string) *hst.Config {
+		c := &hst.Config{Container: new(hst.ContainerConfig)}
+		if pathname != "" {
+			c.Container.Path = check.MustAbs(pathname)
+		}
+		if shell != "" {
+			c.Container.Shell = check.MustAbs(shell)
+		}
+		if home != "" {
+			c.Container.Home = check.MustAbs(home)
+		}
+		return c
+	}
+
+	testCases := []struct {
+		name   string
+		config *hst.Config
+		want   []*hst.PathError
+	}{
+		{"valid", newConfig(
+			"/run/current-system/sw/bin/chromium",
+			"/run/current-system/sw/bin/zsh",
+			"/data/data/org.chromium.Chromium",
+		), nil},
+		{"root home", newConfig("", "", "/"), nil},
+		{"skip null", newConfig("", "", ""), nil},
+
+		{"missing", newConfig(
+			"/run/current-system/sw/bin/firefox",
+			"/run/current-system/sw/bin/zsh",
+			"/data/data/org.mozilla.firefox",
+		), []*hst.PathError{
+			{Field: "path", Path: check.MustAbs("/run/current-system/sw/bin/firefox"), Err: fs.ErrNotExist},
+			{Field: "home", Path: check.MustAbs("/data/data/org.mozilla.firefox"), Err: fs.ErrNotExist},
+		}},
+		{"type", newConfig(
+			"/etc",
+			"/etc/passwd",
+			"/etc/passwd",
+		), []*hst.PathError{
+			{Field: "path", Path: check.MustAbs("/etc"), Err: syscall.EISDIR},
+			{Field: "home", Path: check.MustAbs("/etc/passwd"), Err: syscall.ENOTDIR},
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.config.ValidatePaths(rootfs)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("ValidatePaths: error = %v", err)
+				}
+				return
+			}
+
+			var got []*hst.PathError
+			if joined, ok := err.(interface{ Unwrap() []error }); !ok {
+				t.Fatalf("ValidatePaths: error = %#v", err)
+			} else {
+				for _, e := range joined.Unwrap() {
+					var pathError *hst.PathError
+					if !errors.As(e, &pathError) {
+						t.Fatalf("ValidatePaths: unexpected error %#v", e)
+					}
+					got = append(got, pathError)
+				}
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("ValidatePaths: error = %v", err)
+			}
+			for i, want := range tc.want {
+				if got[i].Field != want.Field || got[i].Path.String() != want.Path.String() || !errors.Is(got[i], want.Err) {
+					t.Errorf("ValidatePaths: error[%d] = %#v, want %#v", i, got[i], want)
+				}
+			}
+		})
+	}
+
+	t.Run("null", func(t *testing.T) {
+		t.Parallel()
+		if err := new(hst.Config).ValidatePaths(rootfs); !errors.Is(err, hst.ErrConfigNull) {
+			t.Errorf("ValidatePaths: error = %v", err)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		const want = `container home "/etc/passwd": not a directory`
+		if got := (&hst.PathError{Field: "home", Path: check.MustAbs("/etc/passwd"), Err: syscall.ENOTDIR}).Error(); got != want {
+			t.Errorf("Error: %q, want %q", got, want)
+		}
+	})
+}
+
+// stubFileInfo implements [os.FileInfo] for [hst.Config.CheckWritableBinds].
+type stubFileInfo struct {
+	mode os.FileMode
+	uid  uint32
+}
+
+func (stubFileInfo) Name() string         { panic("unreachable") }
+func (stubFileInfo) Size() int64          { panic("unreachable") }
+func (fi stubFileInfo) Mode() os.FileMode { return fi.mode }
+func (stubFileInfo) ModTime() time.Time   { panic("unreachable") }
+func (stubFileInfo) IsDir() bool          { panic("unreachable") }
+func (fi stubFileInfo) Sys() any          { return &syscall.Stat_t{Uid: fi.uid} }
+
 func TestExtraPermConfig(t *testing.T) {
 	t.Parallel()
 
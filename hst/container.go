@@ -3,12 +3,16 @@ package hst
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"path"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"hakurei.app/container/check"
+	"hakurei.app/container/fhs"
+	"hakurei.app/container/std"
 )
 
 // PrivateTmp is a private writable path in a hakurei container.
@@ -17,9 +21,78 @@ const PrivateTmp = "/.hakurei"
 // AbsPrivateTmp is a [check.Absolute] representation of [PrivateTmp].
 var AbsPrivateTmp = check.MustAbs(PrivateTmp)
 
+// ErrInstanceExists is returned when a per-instance directory of an [ID] already exists on the host,
+// typically because another instance sharing the same [ID] is already running.
+var ErrInstanceExists = errors.New("instance already exists")
+
 // ErrCgroupPath is returned when a cgroup slice resolves outside of the filesystem root.
+// It is matched by every [CgroupPathError] via [errors.Is].
 var ErrCgroupPath = errors.New("invalid cgroup slice path")
 
+// ErrCgroupEscape is held by [CgroupPathError] for a relative cgroup slice resolving outside of [CgroupRoot].
+var ErrCgroupEscape = errors.New("path escapes cgroup root")
+
+// CgroupPathError describes a [CgroupConfig.Slice] value that cannot be resolved.
+type CgroupPathError struct {
+	// Value of [CgroupConfig.Slice].
+	Slice string
+	// Cleaned pathname Slice resolves to.
+	Path string
+	// Either [ErrCgroupEscape] or the error returned by [check.NewAbs].
+	Err error
+}
+
+func (e *CgroupPathError) Unwrap() []error { return []error{ErrCgroupPath, e.Err} }
+func (e *CgroupPathError) Error() string {
+	return "invalid cgroup slice " + strconv.Quote(e.Slice) +
+		" resolving to " + strconv.Quote(e.Path) + ": " + e.Err.Error()
+}
+
+// ErrInputDevice is returned by [Config.Validate] for an entry of [ContainerConfig.InputDevices]
+// that does not refer to a node under [InputDevicePrefix].
+var ErrInputDevice = errors.New("invalid input device path")
+
+// ErrDevice is returned by [Config.Validate] for an entry of [ContainerConfig.Devices]
+// that does not refer to a node under [DevicePrefix].
+var ErrDevice = errors.New("invalid device path")
+
+// ErrRuntimeSocket is returned by [Config.Validate] for an entry of [ContainerConfig.RuntimeSockets]
+// that is not the name of a single directory entry.
+var ErrRuntimeSocket = errors.New("invalid runtime socket name")
+
+// DevicePrefix is the directory holding device nodes on the host.
+const DevicePrefix = "/dev/"
+
+// InputDevicePrefix is the directory holding input device nodes on the host.
+const InputDevicePrefix = "/dev/input/"
+
+// ErrSeccompAction is returned by [Config.Validate] for an unrecognised [ContainerConfig.SeccompAction].
+var ErrSeccompAction = errors.New("invalid seccomp action")
+
+// ErrPublishPort is returned by [Config.Validate] for an invalid [PortMap].
+var ErrPublishPort = errors.New("invalid published port")
+
+// ErrPrivateTmp is returned by [Config.Validate] for a [ContainerConfig.PrivateTmpPath]
+// targeting the root directory or a pathname at or under a mount point in [ContainerConfig.Filesystem].
+var ErrPrivateTmp = errors.New("invalid private tmp path")
+
+// ErrMountTarget is returned by [Config.Validate] for an entry of [ContainerConfig.Filesystem]
+// sharing its target with another entry, or hidden by a later entry targeting an ancestor.
+var ErrMountTarget = errors.New("conflicting mount point target")
+
+// Recognised values of [ContainerConfig.SeccompAction].
+const (
+	// SeccompActionENOSYS fails denied system calls with ENOSYS.
+	SeccompActionENOSYS = "enosys"
+	// SeccompActionTrap delivers SIGSYS to the offending thread.
+	SeccompActionTrap = "trap"
+	// SeccompActionKillThread kills the offending thread.
+	SeccompActionKillThread = "kill-thread"
+	// SeccompActionKillProcess kills the offending process,
+	// falling back to [SeccompActionKillThread] on kernels lacking support for it.
+	SeccompActionKillProcess = "kill-process"
+)
+
 const (
 	// WaitDelayDefault is used when WaitDelay has its zero value.
 	WaitDelayDefault = 5 * time.Second
@@ -27,6 +100,7 @@ const (
 	WaitDelayMax = 30 * time.Second
 )
 
+// Exit codes of the shim, identical to their counterparts in package container.
 const (
 	// ExitFailure is returned if the container fails to start.
 	ExitFailure = iota + 1
@@ -72,6 +146,32 @@ const (
 	// FShareTmpdir shares TMPDIR between containers under the same identity.
 	FShareTmpdir
 
+	// FGPUConfig binds host Vulkan and EGL vendor configuration directories readonly
+	// and points the loaders to them via VK_DRIVER_FILES and __EGL_VENDOR_LIBRARY_DIRS.
+	// Directories absent on the host are skipped. Explicitly configured values of these
+	// variables, or of the deprecated VK_ICD_FILENAMES, take precedence.
+	FGPUConfig
+
+	// FEnvStrict fails container setup when a value in [ContainerConfig.Env] references
+	// a variable absent from the host environment, instead of expanding it to the empty string.
+	FEnvStrict
+
+	// FMachineID places a machine-id unique to the instance on /etc/machine-id and
+	// /var/lib/dbus/machine-id in the container, in place of any inherited from the host.
+	// The value is derived from the instance [ID], and is stable across instances
+	// sharing an [ID] derived via [NewInstanceIDFunc].
+	FMachineID
+
+	// FReadOnlyRoot remounts every mount point in the container read-only once the container
+	// filesystem is set up, including writable [FilesystemConfig] entries. The home directory,
+	// /tmp, /dev/shm, XDG_RUNTIME_DIR and [ContainerConfig.PrivateTmpPath] remain writable.
+	FReadOnlyRoot
+
+	// FNetworkFiles binds /etc/resolv.conf, /etc/hosts and /etc/nsswitch.conf of the host read-only
+	// into the container, skipping files absent on the host. This provides name resolution to a container
+	// whose filesystem does not hold these files, such as one running in its own network namespace.
+	FNetworkFiles
+
 	fMax
 
 	// FAll is [ContainerConfig.Flags] with all currently defined bits set.
@@ -102,6 +202,16 @@ func (flags Flags) String() string {
 		return "runtime"
 	case FShareTmpdir:
 		return "tmpdir"
+	case FGPUConfig:
+		return "gpu"
+	case FEnvStrict:
+		return "envstrict"
+	case FMachineID:
+		return "machineid"
+	case FReadOnlyRoot:
+		return "readonlyroot"
+	case FNetworkFiles:
+		return "netfiles"
 
 	default:
 		s := make([]string, 0, 1<<4)
@@ -127,8 +237,31 @@ type ContainerConfig struct {
 	// Values lesser than zero is equivalent to zero, bypassing [WaitDelayDefault].
 	WaitDelay time.Duration `json:"wait_delay,omitempty"`
 
-	// Initial process environment variables.
+	// Duration in nanoseconds the container may remain idle for before it is cancelled by the shim.
+	// The container is considered idle while CPU time consumed by its instance cgroup stays
+	// negligible, so this requires Cgroup to be set. A zero value disables the idle timeout.
+	// Cancellation follows the same path as an exit request, so the initial process then has
+	// WaitDelay to terminate before the container is killed, and WaitDelay is not counted
+	// towards the idle timeout.
+	IdleTimeout time.Duration `json:"idle_timeout,omitempty"`
+
+	/* Initial process environment variables.
+
+	Values may reference host environment variables as ${NAME}, and $$ expands to a literal $.
+	Only referenced variables are read from the host environment by the privileged process,
+	expansion happens in the shim right before the initial process environment is populated.
+	Variables absent from the host environment expand to the empty string unless [FEnvStrict] is set. */
 	Env map[string]string `json:"env"`
+	// Names of environment variables guaranteed to be absent from the initial process environment.
+	// This is applied after all other sources, including Env and variables passed through from the host.
+	EnvScrub []string `json:"env_scrub,omitempty"`
+	/* Patterns of host environment variable names to pass through to the initial process,
+	in the syntax of [path.Match], for example "WAYLAND_DEBUG" or "MESA_*".
+
+	Matching variables are read from the host environment by the privileged process, and
+	copied into the initial process environment by the shim without replacing any variable
+	already set via Env or by hakurei itself. */
+	PassEnv []string `json:"pass_env,omitempty"`
 
 	/* Container mount points.
 
@@ -147,12 +280,96 @@ type ContainerConfig struct {
 	Path *check.Absolute `json:"path,omitempty"`
 	// Final args passed to the initial program.
 	Args []string `json:"args"`
+	// Whether to launch the initial program as a login shell by prefixing argv[0] with a hyphen.
+	// This only takes effect if Path is the same pathname as Shell.
+	LoginShell bool `json:"login_shell,omitempty"`
 
 	// Flags holds boolean options of [ContainerConfig].
 	Flags Flags `json:"-"`
 
 	// Optional cgroup configuration applied prior to starting the container.
 	Cgroup *CgroupConfig `json:"cgroup,omitempty"`
+
+	/* Address families denied to socket(2) via the syscall filter, useful for
+	denying internet access alongside [FHostNet] while retaining unix and netlink sockets.
+
+	Recognised names are unix, inet, inet6, netlink, can and bluetooth. This has no
+	effect on architectures multiplexing socket calls through socketcall(2), such as 386. */
+	DenySocketFamilies []string `json:"deny_socket_families,omitempty"`
+
+	/* Action taken by system calls denied by the syscall filter in place of failing with EPERM,
+	one of the SeccompAction constants. The zero value retains the default behaviour.
+
+	Rules failing with ENOSYS or EAFNOSUPPORT are never affected, as userspace relies on them
+	to fall back to other interfaces. Any other value causes the emitted filter program to
+	differ from that of Flatpak, even when [FSeccompCompat] is set. */
+	SeccompAction string `json:"seccomp_action,omitempty"`
+
+	/* Host input device nodes under [InputDevicePrefix] to bind into the container, e.g. /dev/input/event3.
+	Nodes absent on the host are skipped.
+
+	This is HIGH RISK: an input device exposes every event it produces, including keystrokes
+	typed into other applications, to any process in the container. Each node must be listed
+	explicitly, and access is granted as described for Devices. This has no additional effect
+	when [FDevice] is set. */
+	InputDevices []*check.Absolute `json:"input_devices,omitempty"`
+
+	/* Host device nodes under [DevicePrefix] to bind into the minimal /dev of the container,
+	e.g. /dev/kvm or /dev/dri/renderD128. Nodes absent on the host are skipped.
+
+	Device access is denied by default: the minimal /dev only holds pseudo-devices such as
+	/dev/null and a private /dev/pts and /dev/shm. Each node must be listed explicitly. Unless
+	its mode already grants read and write access to other users, a node owned by the current
+	user has ACL entries granting the target user this access while the container is running.
+	Access to nodes owned by other users, such as root, is left to their mode and ownership.
+
+	The cgroup device controller is not applied: on cgroup v2 it requires attaching a BPF
+	program, which is not available to an unprivileged user. Access is confined by the nodes
+	present in the minimal /dev, and device nodes cannot be created in the container as it
+	lacks CAP_MKNOD in the init user namespace. This has no additional effect when [FDevice]
+	is set. */
+	Devices []*check.Absolute `json:"devices,omitempty"`
+
+	/* Names of sockets in the host XDG_RUNTIME_DIR to bind into XDG_RUNTIME_DIR of the container
+	under the same name, e.g. "bus" or sockets of desktop portal helpers. Each socket must be present
+	on the host and has ACL entries granting the target user access while the container is running.
+	Sockets set up by enablements, such as the D-Bus proxy, are bound later and take precedence. */
+	RuntimeSockets []string `json:"runtime_sockets,omitempty"`
+
+	/* Pathname of the private writable tmpfs holding files set up by hakurei in the container,
+	such as the PulseAudio cookie and X11 authority file. The zero value is [AbsPrivateTmp].
+
+	This must not be the root directory, or at or under a mount point in Filesystem. */
+	PrivateTmpPath *check.Absolute `json:"private_tmp_path,omitempty"`
+
+	// Size limit in bytes of the tmpfs mounted on /dev/shm, between [DevShmSizeMin] and [DevShmSizeMax].
+	// The zero value keeps the default limit of half of the host memory.
+	DevShmSize uint64 `json:"dev_shm_size,omitempty"`
+
+	/* Ports listened on in the container network namespace to publish on the host loopback
+	interface. The loopback interface of the container is brought up, and connections are
+	forwarded in userspace by the shim for as long as the container is running.
+
+	This requires a container network namespace and cannot be combined with [FHostNet]. */
+	PublishPorts []PortMap `json:"publish_ports,omitempty"`
+}
+
+// PortMap describes a port published from the container network namespace to the host.
+type PortMap struct {
+	// Transport protocol, one of "tcp" or "udp". The zero value is equivalent to "tcp".
+	Protocol string `json:"protocol,omitempty"`
+	// Port on the loopback interface of the container network namespace.
+	Container uint16 `json:"container"`
+	// Port on the host loopback interface.
+	Host uint16 `json:"host"`
+}
+
+// Network returns the name of the network of [PortMap] as passed to [net.Listen].
+func (p *PortMap) Network() string {
+	if p.Protocol == "" {
+		return "tcp"
+	}
+	return p.Protocol
 }
 
 const (
@@ -160,6 +377,11 @@ const (
 	CgroupRoot = "/sys/fs/cgroup"
 	// defaultCgroupSlice is used when Slice is left unspecified.
 	defaultCgroupSlice = CgroupRoot + "/hakurei.slice"
+
+	// CgroupCPUWeightMin is the lowest value of cpu.weight accepted by the kernel.
+	CgroupCPUWeightMin = 1
+	// CgroupCPUWeightMax is the highest value of cpu.weight accepted by the kernel.
+	CgroupCPUWeightMax = 10000
 )
 
 // CgroupConfig configures a cgroup v2 subtree for the container.
@@ -170,10 +392,300 @@ type CgroupConfig struct {
 	// LimitCPU specifies the microsecond quota applied to the default 100000µs period.
 	// A zero value leaves cpu.max untouched.
 	LimitCPU uint64 `json:"limit_cpu,omitempty"`
+	// CPUWeight sets the proportional share cpu.weight, between [CgroupCPUWeightMin] and
+	// [CgroupCPUWeightMax] with the kernel default being 100. A zero value keeps the current weight.
+	// Weight and LimitCPU may be combined: the weight applies under contention, while the
+	// quota caps usage regardless of contention.
+	CPUWeight uint64 `json:"cpu_weight,omitempty"`
 	// LimitMemory caps memory.max in bytes. A zero value keeps the current limit.
 	LimitMemory uint64 `json:"limit_memory,omitempty"`
+	// MemorySwapMax caps memory.swap.max in bytes. A zero value keeps the current limit.
+	MemorySwapMax uint64 `json:"memory_swap_max,omitempty"`
+	// MemoryLow sets the best-effort memory protection memory.low in bytes.
+	// A zero value keeps the current value.
+	MemoryLow uint64 `json:"memory_low,omitempty"`
 	// LimitPids caps pids.max. Zero disables the limit.
 	LimitPids int `json:"limit_pids,omitempty"`
+	// LimitIO throttles block devices via io.max, keyed by device number in MAJ:MIN form.
+	LimitIO map[string]CgroupIOLimit `json:"limit_io,omitempty"`
+	// CPUSet pins the container to a list of online CPUs via cpuset.cpus, e.g. "0-3,7".
+	// An empty string inherits the cpuset of the slice.
+	CPUSet string `json:"cpuset,omitempty"`
+
+	// Accounting creates the instance cgroup for resource accounting only,
+	// without writing any limit files. Mutually exclusive with all limits.
+	Accounting bool `json:"accounting,omitempty"`
+
+	// CPUInfo places a synthesized /proc/cpuinfo in the container only describing
+	// processors in the effective cpuset of the slice, further restricted to CPUSet if set.
+	// The host /proc/cpuinfo is kept if the cpuset controller is not available.
+	CPUInfo bool `json:"cpuinfo,omitempty"`
+
+	// Persist keeps the instance cgroup after the container exits, so its statistics such as
+	// memory.peak and cpu.stat remain available for post-mortem analysis. The caller is
+	// responsible for removing the instance cgroup directory once it is no longer needed.
+	Persist bool `json:"persist,omitempty"`
+
+	// EnableControllers enables controllers required by the configured limits in the slice and in
+	// other existing cgroups above the instance cgroup if they are not already enabled. These cgroups
+	// must not have member processes, and the controllers are disabled again once the container exits
+	// unless other cgroups below them remain. Without this, limits requiring a controller not enabled
+	// in the slice are rejected.
+	EnableControllers bool `json:"enable_controllers,omitempty"`
+
+	// MemoryEvents watches memory.events of the instance cgroup while the container runs, and
+	// logs every memory event counter that increases, such as memory.high being exceeded.
+	// This requires the memory controller to be enabled in the slice. Launchers receive these
+	// events via [CgroupConfig.WatchMemoryEvents] regardless of this field.
+	MemoryEvents bool `json:"memory_events,omitempty"`
+}
+
+// CgroupIOLimit describes the io.max entry of a single block device.
+// Zero values leave the corresponding limit unset.
+type CgroupIOLimit struct {
+	// Read bytes per second.
+	RBPS uint64 `json:"rbps,omitempty"`
+	// Write bytes per second.
+	WBPS uint64 `json:"wbps,omitempty"`
+	// Read IO operations per second.
+	RIOPS uint64 `json:"riops,omitempty"`
+	// Write IO operations per second.
+	WIOPS uint64 `json:"wiops,omitempty"`
+}
+
+// validCgroupDevice returns whether dev is a device number in MAJ:MIN form.
+func validCgroupDevice(dev string) bool {
+	major, minor, ok := strings.Cut(dev, ":")
+	if !ok {
+		return false
+	}
+	if _, err := strconv.ParseUint(major, 10, 32); err != nil {
+		return false
+	}
+	_, err := strconv.ParseUint(minor, 10, 32)
+	return err == nil
+}
+
+func (config *ContainerConfig) validateSocketFamilies() error {
+	for _, name := range config.DenySocketFamilies {
+		if _, ok := std.SocketFamilyResolveName(name); !ok {
+			return &AppError{Step: "validate configuration", Err: std.SocketFamilyNameError(name),
+				Msg: "invalid socket family " + strconv.Quote(name)}
+		}
+	}
+	return nil
+}
+
+func (config *ContainerConfig) validateSeccompAction() error {
+	switch config.SeccompAction {
+	case "", SeccompActionENOSYS, SeccompActionTrap, SeccompActionKillThread, SeccompActionKillProcess:
+		return nil
+
+	default:
+		return &AppError{Step: "validate configuration", Err: ErrSeccompAction,
+			Msg: "invalid seccomp action " + strconv.Quote(config.SeccompAction)}
+	}
+}
+
+const (
+	// DevShmSizeMin is the smallest accepted value of [ContainerConfig.DevShmSize], the size of a page.
+	DevShmSizeMin = 1 << 12
+	// DevShmSizeMax is the largest accepted value of [ContainerConfig.DevShmSize].
+	DevShmSizeMax = 1 << 40
+)
+
+func (config *ContainerConfig) validateDevShmSize() error {
+	// also bounded by the size argument of tmpfs in container
+	if config.DevShmSize != 0 && (config.DevShmSize < DevShmSizeMin || config.DevShmSize > DevShmSizeMax ||
+		config.DevShmSize > math.MaxInt) {
+		return &AppError{Step: "validate configuration", Err: syscall.ERANGE,
+			Msg: "/dev/shm size " + strconv.FormatUint(config.DevShmSize, 10) + " out of range"}
+	}
+	return nil
+}
+
+func (config *ContainerConfig) validateIdleTimeout() error {
+	if config.IdleTimeout < 0 {
+		return &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: "idle timeout cannot be negative"}
+	}
+	if config.IdleTimeout > 0 && config.Cgroup == nil {
+		return &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: "idle timeout requires a cgroup"}
+	}
+	return nil
+}
+
+func (config *ContainerConfig) validatePublishPorts() error {
+	if len(config.PublishPorts) == 0 {
+		return nil
+	}
+	if config.Flags&FHostNet != 0 {
+		return &AppError{Step: "validate configuration", Err: ErrPublishPort,
+			Msg: "publishing ports requires a container network namespace"}
+	}
+
+	seen := make(map[PortMap]int, len(config.PublishPorts))
+	for i, p := range config.PublishPorts {
+		switch p.Protocol {
+		case "", "tcp", "udp":
+		default:
+			return &AppError{Step: "validate configuration", Err: ErrPublishPort,
+				Msg: "published port at index " + strconv.Itoa(i) + " has invalid protocol " + strconv.Quote(p.Protocol)}
+		}
+		if p.Container == 0 || p.Host == 0 {
+			return &AppError{Step: "validate configuration", Err: ErrPublishPort,
+				Msg: "published port at index " + strconv.Itoa(i) + " must not be zero"}
+		}
+
+		key := PortMap{Protocol: p.Network(), Host: p.Host}
+		if j, ok := seen[key]; ok {
+			return &AppError{Step: "validate configuration", Err: ErrPublishPort,
+				Msg: "published port at index " + strconv.Itoa(i) + " has the same host port " +
+					strconv.Itoa(int(p.Host)) + "/" + key.Protocol + " as published port at index " + strconv.Itoa(j)}
+		}
+		seen[key] = i
+	}
+	return nil
+}
+
+func (config *ContainerConfig) validateInputDevices() error {
+	for _, a := range config.InputDevices {
+		if a == nil {
+			return &AppError{Step: "validate configuration", Err: ErrConfigNull,
+				Msg: "input device path must not be null"}
+		}
+		pathname := a.String()
+		// a clean pathname cannot hold a trailing slash or traverse out of the prefix
+		if path.Clean(pathname) != pathname || !strings.HasPrefix(pathname, InputDevicePrefix) {
+			return &AppError{Step: "validate configuration", Err: ErrInputDevice,
+				Msg: "input device " + strconv.Quote(pathname) + " is not under " + InputDevicePrefix}
+		}
+	}
+	return nil
+}
+
+func (config *ContainerConfig) validateDevices() error {
+	for _, a := range config.Devices {
+		if a == nil {
+			return &AppError{Step: "validate configuration", Err: ErrConfigNull,
+				Msg: "device path must not be null"}
+		}
+		pathname := a.String()
+		if path.Clean(pathname) != pathname || !strings.HasPrefix(pathname, DevicePrefix) {
+			return &AppError{Step: "validate configuration", Err: ErrDevice,
+				Msg: "device " + strconv.Quote(pathname) + " is not under " + DevicePrefix}
+		}
+	}
+	return nil
+}
+
+// PrivateTmpDir returns PrivateTmpPath, or [AbsPrivateTmp] if it is null.
+func (config *ContainerConfig) PrivateTmpDir() *check.Absolute {
+	if config == nil || config.PrivateTmpPath == nil {
+		return AbsPrivateTmp
+	}
+	return config.PrivateTmpPath
+}
+
+// validatePrivateTmp checks that PrivateTmpPath is not shadowed by a mount point in Filesystem.
+func (config *ContainerConfig) validatePrivateTmp() error {
+	if config.PrivateTmpPath == nil {
+		return nil
+	}
+	pathname := path.Clean(config.PrivateTmpPath.String())
+	if pathname == fhs.Root {
+		return &AppError{Step: "validate configuration", Err: ErrPrivateTmp,
+			Msg: "private tmp path must not be the root directory"}
+	}
+	for i, c := range config.Filesystem {
+		if !c.Valid() {
+			continue
+		}
+		// a root mount point holds the entire container filesystem
+		target := path.Clean(c.Path().String())
+		if target != fhs.Root && (pathname == target || strings.HasPrefix(pathname, target+"/")) {
+			return &AppError{Step: "validate configuration", Err: ErrPrivateTmp,
+				Msg: "private tmp path " + strconv.Quote(pathname) + " is under filesystem at index " +
+					strconv.Itoa(i) + " targeting " + strconv.Quote(target)}
+		}
+	}
+	return nil
+}
+
+func (config *ContainerConfig) validateRuntimeSockets() error {
+	for _, name := range config.RuntimeSockets {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+			return &AppError{Step: "validate configuration", Err: ErrRuntimeSocket,
+				Msg: "runtime socket " + strconv.Quote(name) + " is not a file name"}
+		}
+	}
+	return nil
+}
+
+// validateMountOptions checks [FSBind.Options] of every bind mount point in Filesystem.
+func (config *ContainerConfig) validateMountOptions() error {
+	privileged := config.Flags&FDevice != 0
+	for i, c := range config.Filesystem {
+		b, ok := c.FilesystemConfig.(*FSBind)
+		if !ok || b == nil {
+			continue
+		}
+		if err := checkMountOptions(b.Options, b.Device); err != nil {
+			return err
+		}
+		if privileged {
+			continue
+		}
+		for _, option := range b.Options {
+			if MountOptionPrivileged(option) {
+				return &AppError{Step: "validate configuration", Err: ErrMountOption,
+					Msg: "mount option " + strconv.Quote(option) + " on filesystem at index " +
+						strconv.Itoa(i) + " requires a privileged configuration"}
+			}
+		}
+	}
+	return nil
+}
+
+// validateFilesystem checks that no mount point in Filesystem is shadowed by another.
+// Home relative targets are resolved against Home before comparison. Invalid entries
+// are skipped, they are rejected when the container state is created.
+func (config *ContainerConfig) validateFilesystem() error {
+	targets := make([]string, len(config.Filesystem))
+	for i, c := range config.Filesystem {
+		if b, ok := c.FilesystemConfig.(*FSBind); ok {
+			// the host home directory is not known here, and does not affect the target
+			v, err := b.ExpandHome(config.Home, fhs.AbsRoot)
+			if err != nil {
+				return err
+			}
+			c = FilesystemConfigJSON{FilesystemConfig: v}
+		}
+		if !c.Valid() {
+			continue
+		}
+		targets[i] = path.Clean(c.Path().String())
+
+		for j, prev := range targets[:i] {
+			switch {
+			case prev == "":
+				continue
+
+			case prev == targets[i]:
+				return &AppError{Step: "validate configuration", Err: ErrMountTarget,
+					Msg: "filesystem at index " + strconv.Itoa(i) + " has the same target " +
+						strconv.Quote(targets[i]) + " as filesystem at index " + strconv.Itoa(j)}
+
+			// only a root element at index 0 is inserted early, it hides every preceding mount point otherwise
+			case targets[i] == fhs.Root || strings.HasPrefix(prev, targets[i]+"/"):
+				return &AppError{Step: "validate configuration", Err: ErrMountTarget,
+					Msg: "filesystem at index " + strconv.Itoa(j) + " targeting " + strconv.Quote(prev) +
+						" is hidden by filesystem at index " + strconv.Itoa(i) + " targeting " + strconv.Quote(targets[i])}
+			}
+		}
+	}
+	return nil
 }
 
 func (config *ContainerConfig) validateCgroup() error {
@@ -192,8 +704,23 @@ func (c *CgroupConfig) Validate() error {
 		return &AppError{Step: "validate configuration", Err: syscall.EINVAL,
 			Msg: "cgroup limit pids cannot be negative"}
 	}
+	if c.CPUWeight != 0 && (c.CPUWeight < CgroupCPUWeightMin || c.CPUWeight > CgroupCPUWeightMax) {
+		return &AppError{Step: "validate configuration", Err: syscall.ERANGE,
+			Msg: "cgroup cpu weight " + strconv.FormatUint(c.CPUWeight, 10) + " out of range"}
+	}
+	for dev := range c.LimitIO {
+		if !validCgroupDevice(dev) {
+			return &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+				Msg: "invalid cgroup io device " + strconv.Quote(dev)}
+		}
+	}
+	if c.Accounting && (c.LimitCPU != 0 || c.CPUWeight != 0 || c.LimitMemory != 0 || c.MemorySwapMax != 0 || c.MemoryLow != 0 ||
+		c.LimitPids != 0 || len(c.LimitIO) != 0 || c.CPUSet != "") {
+		return &AppError{Step: "validate configuration", Err: syscall.EINVAL,
+			Msg: "cgroup accounting mode cannot be combined with limits"}
+	}
 	if _, err := c.slicePath(); err != nil {
-		return &AppError{Step: "validate configuration", Err: err, Msg: "invalid cgroup slice"}
+		return &AppError{Step: "validate configuration", Err: err, Msg: err.Error()}
 	}
 	return nil
 }
@@ -231,13 +758,17 @@ func (c *CgroupConfig) slicePath() (*check.Absolute, error) {
 	if base == "" {
 		base = defaultCgroupSlice
 	}
-	if !path.IsAbs(base) {
+	relative := !path.IsAbs(base)
+	if relative {
 		base = path.Join(CgroupRoot, base)
 	}
 	cleaned := path.Clean(base)
+	if relative && cleaned != CgroupRoot && !strings.HasPrefix(cleaned, CgroupRoot+"/") {
+		return nil, &CgroupPathError{c.Slice, cleaned, ErrCgroupEscape}
+	}
 	abs, err := check.NewAbs(cleaned)
 	if err != nil {
-		return nil, ErrCgroupPath
+		return nil, &CgroupPathError{c.Slice, cleaned, err}
 	}
 	return abs, nil
 }
@@ -276,6 +807,21 @@ type containerConfigJSON = struct {
 	ShareRuntime bool `json:"share_runtime,omitempty"`
 	// Corresponds to [FShareTmpdir]
 	ShareTmpdir bool `json:"share_tmpdir,omitempty"`
+
+	// Corresponds to [FGPUConfig].
+	GPUConfig bool `json:"gpu_config,omitempty"`
+
+	// Corresponds to [FEnvStrict].
+	EnvStrict bool `json:"env_strict,omitempty"`
+
+	// Corresponds to [FMachineID].
+	MachineID bool `json:"machine_id,omitempty"`
+
+	// Corresponds to [FReadOnlyRoot].
+	ReadOnlyRoot bool `json:"read_only_root,omitempty"`
+
+	// Corresponds to [FNetworkFiles].
+	NetworkFiles bool `json:"network_files,omitempty"`
 }
 
 func (c *ContainerConfig) MarshalJSON() ([]byte, error) {
@@ -296,6 +842,11 @@ func (c *ContainerConfig) MarshalJSON() ([]byte, error) {
 		Device:        c.Flags&FDevice != 0,
 		ShareRuntime:  c.Flags&FShareRuntime != 0,
 		ShareTmpdir:   c.Flags&FShareTmpdir != 0,
+		GPUConfig:     c.Flags&FGPUConfig != 0,
+		EnvStrict:     c.Flags&FEnvStrict != 0,
+		MachineID:     c.Flags&FMachineID != 0,
+		ReadOnlyRoot:  c.Flags&FReadOnlyRoot != 0,
+		NetworkFiles:  c.Flags&FNetworkFiles != 0,
 	})
 }
 
@@ -343,5 +894,20 @@ func (c *ContainerConfig) UnmarshalJSON(data []byte) error {
 	if v.ShareTmpdir {
 		c.Flags |= FShareTmpdir
 	}
+	if v.GPUConfig {
+		c.Flags |= FGPUConfig
+	}
+	if v.EnvStrict {
+		c.Flags |= FEnvStrict
+	}
+	if v.MachineID {
+		c.Flags |= FMachineID
+	}
+	if v.ReadOnlyRoot {
+		c.Flags |= FReadOnlyRoot
+	}
+	if v.NetworkFiles {
+		c.Flags |= FNetworkFiles
+	}
 	return nil
 }
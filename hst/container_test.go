@@ -8,6 +8,7 @@ import (
 	"syscall"
 	"testing"
 
+	"hakurei.app/container/check"
 	"hakurei.app/hst"
 )
 
@@ -21,8 +22,8 @@ func TestFlagsString(t *testing.T) {
 	}{
 		{"none", 0, "none"},
 		{"none high", hst.FAll + 1, "none"},
-		{"all", hst.FAll, "multiarch, compat, devel, userns, net, abstract, tty, mapuid, device, runtime, tmpdir"},
-		{"all high", math.MaxUint, "multiarch, compat, devel, userns, net, abstract, tty, mapuid, device, runtime, tmpdir"},
+		{"all", hst.FAll, "multiarch, compat, devel, userns, net, abstract, tty, mapuid, device, runtime, tmpdir, gpu, envstrict, machineid, readonlyroot, netfiles"},
+		{"all high", math.MaxUint, "multiarch, compat, devel, userns, net, abstract, tty, mapuid, device, runtime, tmpdir, gpu, envstrict, machineid, readonlyroot, netfiles"},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -53,7 +54,7 @@ func TestContainerConfig(t *testing.T) {
 		{"hostnet hostabstract mapuid", &hst.ContainerConfig{Flags: hst.FHostNet | hst.FHostAbstract | hst.FMapRealUID},
 			`{"env":null,"filesystem":null,"shell":null,"home":null,"args":null,"host_net":true,"host_abstract":true,"map_real_uid":true}`},
 		{"all", &hst.ContainerConfig{Flags: hst.FAll},
-			`{"env":null,"filesystem":null,"shell":null,"home":null,"args":null,"seccomp_compat":true,"devel":true,"userns":true,"host_net":true,"host_abstract":true,"tty":true,"multiarch":true,"map_real_uid":true,"device":true,"share_runtime":true,"share_tmpdir":true}`},
+			`{"env":null,"filesystem":null,"shell":null,"home":null,"args":null,"seccomp_compat":true,"devel":true,"userns":true,"host_net":true,"host_abstract":true,"tty":true,"multiarch":true,"map_real_uid":true,"device":true,"share_runtime":true,"share_tmpdir":true,"gpu_config":true,"env_strict":true,"machine_id":true,"read_only_root":true,"network_files":true}`},
 	}
 
 	for _, tc := range testCases {
@@ -99,3 +100,25 @@ func TestContainerConfig(t *testing.T) {
 		}
 	})
 }
+
+func TestPrivateTmpDir(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		config *hst.ContainerConfig
+		want   string
+	}{
+		{"nil", nil, hst.PrivateTmp},
+		{"zero", new(hst.ContainerConfig), hst.PrivateTmp},
+		{"custom", &hst.ContainerConfig{PrivateTmpPath: check.MustAbs("/run/hakurei")}, "/run/hakurei"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.config.PrivateTmpDir(); got.String() != tc.want {
+				t.Errorf("PrivateTmpDir: %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -1,10 +1,16 @@
 package hst
 
 import (
+	"errors"
 	"strconv"
 	"strings"
+
+	"hakurei.app/container/check"
 )
 
+// ErrBusPolicyRef is returned for a referenced bus policy itself referencing a policy.
+var ErrBusPolicyRef = errors.New("bus policy references another policy")
+
 // BadInterfaceError is returned when Interface fails an undocumented check in xdg-dbus-proxy,
 // which would have cause a silent failure.
 type BadInterfaceError struct {
@@ -40,6 +46,35 @@ type BusConfig struct {
 	Log bool `json:"log,omitempty"`
 	// Filter enable filtering (--filter)
 	Filter bool `json:"filter"`
+
+	// PolicyRef is the pathname of a shared policy in the [json] representation of [BusConfig],
+	// merged with the rules specified inline via [BusConfig.WithPolicy] when setting up the container.
+	// The policy is read again and the message bus proxy restarted when hakurei receives SIGHUP.
+	PolicyRef *check.Absolute `json:"policy_ref,omitempty"`
+}
+
+/*
+WithPolicy returns a new [BusConfig] holding policy merged with the rules specified inline by c.
+PolicyRef of the result is nil. The policy referenced by PolicyRef is read and passed to WithPolicy
+by the caller setting up the container.
+
+Names of the inline See, Talk and Own rules are appended after those of the referenced policy
+and names already present are omitted. Call and Broadcast rules are merged by name, with inline
+rules taking precedence. Log and Filter are enabled if they are enabled by either.
+*/
+func (c *BusConfig) WithPolicy(policy *BusConfig) *BusConfig {
+	if c == nil || policy == nil {
+		return c
+	}
+	return &BusConfig{
+		See:       mergeUnion(policy.See, c.See),
+		Talk:      mergeUnion(policy.Talk, c.Talk),
+		Own:       mergeUnion(policy.Own, c.Own),
+		Call:      mergeMap(policy.Call, c.Call),
+		Broadcast: mergeMap(policy.Broadcast, c.Broadcast),
+		Log:       policy.Log || c.Log,
+		Filter:    policy.Filter || c.Filter,
+	}
 }
 
 // Interfaces iterates over all interface strings specified in [BusConfig].
@@ -5,6 +5,7 @@ import (
 	"slices"
 	"testing"
 
+	"hakurei.app/container/check"
 	"hakurei.app/hst"
 	"hakurei.app/message"
 )
@@ -116,3 +117,88 @@ func TestBusConfigCheckInterfaces(t *testing.T) {
 		})
 	}
 }
+
+func TestBusConfigWithPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy := &hst.BusConfig{
+		Talk: []string{"org.freedesktop.portal.*"},
+		Own:  []string{"org.chromium.Chromium.*"},
+		Call: map[string]string{
+			"org.freedesktop.portal.*":      "*",
+			"org.freedesktop.Notifications": "*",
+		},
+		Broadcast: map[string]string{"org.freedesktop.portal.*": "@/org/freedesktop/portal/*"},
+		Log:       true,
+		Filter:    true,
+	}
+	ref := check.MustAbs("/etc/hakurei/portal-safe")
+
+	testCases := []struct {
+		name   string
+		c      *hst.BusConfig
+		policy *hst.BusConfig
+		want   *hst.BusConfig
+	}{
+		{"nil", nil, policy, nil},
+		{"inline", &hst.BusConfig{Talk: []string{"org.freedesktop.Notifications"}}, nil,
+			&hst.BusConfig{Talk: []string{"org.freedesktop.Notifications"}}},
+
+		{"referenced", &hst.BusConfig{PolicyRef: ref}, policy, &hst.BusConfig{
+			Talk: []string{"org.freedesktop.portal.*"},
+			Own:  []string{"org.chromium.Chromium.*"},
+			Call: map[string]string{
+				"org.freedesktop.portal.*":      "*",
+				"org.freedesktop.Notifications": "*",
+			},
+			Broadcast: map[string]string{"org.freedesktop.portal.*": "@/org/freedesktop/portal/*"},
+			Log:       true,
+			Filter:    true,
+		}},
+
+		{"merged", &hst.BusConfig{
+			See:       []string{"org.freedesktop.DBus"},
+			Talk:      []string{"org.freedesktop.Notifications", "org.freedesktop.portal.*"},
+			Call:      map[string]string{"org.freedesktop.Notifications": "org.freedesktop.Notifications.Notify@/org/freedesktop/Notifications"},
+			Broadcast: map[string]string{"org.freedesktop.FileManager1": "*"},
+			PolicyRef: ref,
+		}, policy, &hst.BusConfig{
+			See:  []string{"org.freedesktop.DBus"},
+			Talk: []string{"org.freedesktop.portal.*", "org.freedesktop.Notifications"},
+			Own:  []string{"org.chromium.Chromium.*"},
+			Call: map[string]string{
+				"org.freedesktop.portal.*":      "*",
+				"org.freedesktop.Notifications": "org.freedesktop.Notifications.Notify@/org/freedesktop/Notifications",
+			},
+			Broadcast: map[string]string{
+				"org.freedesktop.portal.*":     "@/org/freedesktop/portal/*",
+				"org.freedesktop.FileManager1": "*",
+			},
+			Log:    true,
+			Filter: true,
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.c.WithPolicy(tc.policy); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("WithPolicy:\n%#v\nwant\n%#v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("validate", func(t *testing.T) {
+		t.Parallel()
+
+		config := hst.Template()
+		inline := &hst.BusConfig{Talk: []string{"org.freedesktop.Notifications"}, PolicyRef: check.MustAbs("/proc/nonexistent")}
+		config.SessionBus = inline
+		if err := config.Validate(); err != nil {
+			t.Fatalf("Validate: error = %v", err)
+		}
+		if config.SessionBus != inline || inline.PolicyRef == nil || len(inline.Talk) != 1 {
+			t.Errorf("Validate: modified configuration %#v", config.SessionBus)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package hst
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// ErrUnterminatedComment is returned by [DecodeConfig] for a block comment missing its terminator.
+var ErrUnterminatedComment = errors.New("unterminated block comment")
+
+// DecodeConfig decodes a [Config] from r, permitting line and block comments and trailing commas
+// for annotating hand-edited configuration. Machine-generated configuration should be decoded via
+// [json.Unmarshal] instead, which rejects both.
+//
+// Comments and trailing commas are replaced by whitespace prior to decoding, so offsets reported by
+// the underlying decoder correspond to the original input. Syntax and type errors are returned as
+// [AppError] with a message describing the line and column they occurred on.
+func DecodeConfig(r io.Reader) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, &AppError{Step: "read configuration", Err: err}
+	}
+
+	var offset int
+	if offset, err = stripComments(data); err != nil {
+		return nil, newDecodeError(data, err, int64(offset))
+	}
+	stripTrailingCommas(data)
+
+	config := new(Config)
+	if err = json.Unmarshal(data, config); err != nil {
+		var (
+			syntaxError        *json.SyntaxError
+			unmarshalTypeError *json.UnmarshalTypeError
+		)
+		switch {
+		case errors.As(err, &syntaxError) && syntaxError != nil:
+			return nil, newDecodeError(data, err, syntaxError.Offset-1)
+		case errors.As(err, &unmarshalTypeError) && unmarshalTypeError != nil:
+			return nil, newDecodeError(data, err, unmarshalTypeError.Offset-1)
+		default:
+			return nil, &AppError{Step: "decode configuration", Err: err}
+		}
+	}
+	return config, nil
+}
+
+// newDecodeError returns an [AppError] describing the line and column of the byte at offset in data.
+// Offsets reported by [json] are past the last byte read, so they are decremented by the caller.
+func newDecodeError(data []byte, err error, offset int64) error {
+	if offset < 0 || offset >= int64(len(data)) {
+		return &AppError{Step: "decode configuration", Err: err}
+	}
+	line := bytes.Count(data[:offset], []byte{'\n'}) + 1
+	column := int(offset) - bytes.LastIndexByte(data[:offset], '\n')
+	return &AppError{Step: "decode configuration", Err: err,
+		Msg: "cannot decode configuration: " + err.Error() +
+			" at line " + strconv.Itoa(line) + ", column " + strconv.Itoa(column)}
+}
+
+// stripComments replaces comments in data with spaces in place, retaining newlines.
+// The offset of an unterminated block comment is returned alongside [ErrUnterminatedComment].
+func stripComments(data []byte) (int, error) {
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '"':
+			i = skipString(data, i)
+
+		case '/':
+			if i+1 >= len(data) {
+				break
+			}
+			switch data[i+1] {
+			case '/':
+				for ; i < len(data) && data[i] != '\n'; i++ {
+					data[i] = ' '
+				}
+
+			case '*':
+				start := i
+				end := bytes.Index(data[i+2:], []byte("*/"))
+				if end < 0 {
+					return start, ErrUnterminatedComment
+				}
+				end += i + 2 + len("*/")
+				for ; i < end; i++ {
+					if data[i] != '\n' {
+						data[i] = ' '
+					}
+				}
+				i--
+			}
+		}
+	}
+	return 0, nil
+}
+
+// stripTrailingCommas replaces commas followed only by whitespace and a closing
+// bracket or brace with spaces in place. Comments must already be stripped.
+func stripTrailingCommas(data []byte) {
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '"':
+			i = skipString(data, i)
+
+		case ',':
+			j := i + 1
+			for ; j < len(data); j++ {
+				if c := data[j]; c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+					break
+				}
+			}
+			if j < len(data) && (data[j] == '}' || data[j] == ']') {
+				data[i] = ' '
+			}
+		}
+	}
+}
+
+// skipString returns the index of the closing quote of the string starting at data[i],
+// or the index of the last byte if the string is unterminated.
+func skipString(data []byte, i int) int {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return len(data) - 1
+}
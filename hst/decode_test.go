@@ -0,0 +1,92 @@
+package hst_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"hakurei.app/hst"
+)
+
+func TestDecodeConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("template", func(t *testing.T) {
+		t.Parallel()
+
+		f, err := os.Open("testdata/template.jsonc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = f.Close() })
+
+		if got, err := hst.DecodeConfig(f); err != nil {
+			t.Fatalf("DecodeConfig: error = %v", err)
+		} else {
+			want := hst.Template()
+			want.Container.Flags = hst.FAll
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("DecodeConfig: %#v, want %#v", got, want)
+			}
+		}
+	})
+
+	testCases := []struct {
+		name    string
+		data    string
+		want    *hst.Config
+		wantErr error
+	}{
+		{"plain", `{"id": "org.chromium.Chromium", "identity": 9}`,
+			&hst.Config{ID: "org.chromium.Chromium", Identity: 9}, nil},
+
+		{"comment in string", `{
+	// "id": "comment",
+	"id": "// not a comment /* either */", /* trailing */
+}`, &hst.Config{ID: "// not a comment /* either */"}, nil},
+
+		{"escaped quote", `{"id": "\"//\", ", "groups": ["\\",],}`,
+			&hst.Config{ID: `"//", `, Groups: []string{`\`}}, nil},
+
+		{"unterminated comment", "{\n\t\"id\": \"\" /* */ /*\n}",
+			nil, &hst.AppError{Step: "decode configuration", Err: hst.ErrUnterminatedComment,
+				Msg: "cannot decode configuration: unterminated block comment at line 2, column 17"}},
+
+		{"syntax", "{\n\t// identity\n\t\"identity\": 9\n\t\"id\": \"\"\n}",
+			nil, &hst.AppError{Step: "decode configuration", Err: &json.SyntaxError{},
+				Msg: "cannot decode configuration: invalid character '\"' after object key:value pair at line 4, column 2"}},
+
+		{"type", "{\n\t/* identity */ \"identity\": \"9\"\n}",
+			nil, &hst.AppError{Step: "decode configuration", Err: &json.UnmarshalTypeError{},
+				Msg: "cannot decode configuration: json: cannot unmarshal string into Go struct field Config.identity of type int at line 2, column 31"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := hst.DecodeConfig(strings.NewReader(tc.data))
+			if tc.wantErr == nil {
+				if err != nil {
+					t.Fatalf("DecodeConfig: error = %v", err)
+				}
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("DecodeConfig: %#v, want %#v", got, tc.want)
+				}
+				return
+			}
+
+			var e, wantErr *hst.AppError
+			if !errors.As(err, &e) || !errors.As(tc.wantErr, &wantErr) {
+				t.Fatalf("DecodeConfig: error = %#v, want %#v", err, tc.wantErr)
+			}
+			if e.Step != wantErr.Step || e.Msg != wantErr.Msg ||
+				reflect.TypeOf(e.Err) != reflect.TypeOf(wantErr.Err) ||
+				(wantErr.Err == hst.ErrUnterminatedComment && e.Err != hst.ErrUnterminatedComment) {
+				t.Errorf("DecodeConfig: error = %#v, want %#v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -19,6 +19,8 @@ const (
 	EDBus
 	// EPulse copies the PulseAudio cookie to [hst.PrivateTmp] and exposes the PulseAudio socket.
 	EPulse
+	// EPipeWire exposes the PipeWire native socket. This does not conflict with [EPulse].
+	EPipeWire
 
 	// EM is a noop.
 	EM
@@ -37,6 +39,8 @@ func (e Enablement) String() string {
 		return "dbus"
 	case EPulse:
 		return "pulseaudio"
+	case EPipeWire:
+		return "pipewire"
 	default:
 		buf := new(strings.Builder)
 		buf.Grow(32)
@@ -54,6 +58,39 @@ func (e Enablement) String() string {
 	}
 }
 
+// EnablementError is returned by [ParseEnablements] for an unknown enablement name.
+type EnablementError string
+
+func (e EnablementError) Error() string { return fmt.Sprintf("unknown enablement %q", string(e)) }
+
+// ParseEnablements parses a comma-separated list of enablement names, as returned by
+// [Enablement.String], into [Enablements]. Names are case-insensitive and may be surrounded
+// by whitespace. The name used by the [json] representation of [EPulse] is also accepted.
+func ParseEnablements(s string) (Enablements, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+
+	var e Enablement
+	for _, name := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "wayland":
+			e |= EWayland
+		case "x11":
+			e |= EX11
+		case "dbus":
+			e |= EDBus
+		case "pulseaudio", "pulse":
+			e |= EPulse
+		case "pipewire":
+			e |= EPipeWire
+		default:
+			return 0, EnablementError(name)
+		}
+	}
+	return Enablements(e), nil
+}
+
 // NewEnablements returns the address of [Enablement] as [Enablements].
 func NewEnablements(e Enablement) *Enablements { return (*Enablements)(&e) }
 
@@ -62,10 +99,11 @@ type Enablements Enablement
 
 // enablementsJSON is the [json] representation of [Enablements].
 type enablementsJSON = struct {
-	Wayland bool `json:"wayland,omitempty"`
-	X11     bool `json:"x11,omitempty"`
-	DBus    bool `json:"dbus,omitempty"`
-	Pulse   bool `json:"pulse,omitempty"`
+	Wayland  bool `json:"wayland,omitempty"`
+	X11      bool `json:"x11,omitempty"`
+	DBus     bool `json:"dbus,omitempty"`
+	Pulse    bool `json:"pulse,omitempty"`
+	PipeWire bool `json:"pipewire,omitempty"`
 }
 
 // Unwrap returns the underlying [Enablement].
@@ -81,10 +119,11 @@ func (e *Enablements) MarshalJSON() ([]byte, error) {
 		return nil, syscall.EINVAL
 	}
 	return json.Marshal(&enablementsJSON{
-		Wayland: Enablement(*e)&EWayland != 0,
-		X11:     Enablement(*e)&EX11 != 0,
-		DBus:    Enablement(*e)&EDBus != 0,
-		Pulse:   Enablement(*e)&EPulse != 0,
+		Wayland:  Enablement(*e)&EWayland != 0,
+		X11:      Enablement(*e)&EX11 != 0,
+		DBus:     Enablement(*e)&EDBus != 0,
+		Pulse:    Enablement(*e)&EPulse != 0,
+		PipeWire: Enablement(*e)&EPipeWire != 0,
 	})
 }
 
@@ -111,6 +150,9 @@ func (e *Enablements) UnmarshalJSON(data []byte) error {
 	if v.Pulse {
 		ve |= EPulse
 	}
+	if v.PipeWire {
+		ve |= EPipeWire
+	}
 	*e = Enablements(ve)
 	return nil
 }
@@ -32,6 +32,9 @@ func TestEnablementString(t *testing.T) {
 		{hst.EWayland | hst.EDBus | hst.EPulse, "wayland, dbus, pulseaudio"},
 		{hst.EX11 | hst.EDBus | hst.EPulse, "x11, dbus, pulseaudio"},
 		{hst.EWayland | hst.EX11 | hst.EDBus | hst.EPulse, "wayland, x11, dbus, pulseaudio"},
+		{hst.EPipeWire, "pipewire"},
+		{hst.EPulse | hst.EPipeWire, "pulseaudio, pipewire"},
+		{hst.EWayland | hst.EX11 | hst.EDBus | hst.EPulse | hst.EPipeWire, "wayland, x11, dbus, pulseaudio, pipewire"},
 
 		{1 << 5, "e20"},
 		{1 << 6, "e40"},
@@ -48,6 +51,60 @@ func TestEnablementString(t *testing.T) {
 	}
 }
 
+func TestParseEnablements(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		s       string
+		want    hst.Enablement
+		wantErr error
+	}{
+		{"empty", "", 0, nil},
+		{"blank", " ", 0, nil},
+		{"wayland", "wayland", hst.EWayland, nil},
+		{"pulse", "pulse", hst.EPulse, nil},
+		{"combined", "wayland,x11,dbus", hst.EWayland | hst.EX11 | hst.EDBus, nil},
+		{"case", "Wayland,PULSEAUDIO,PipeWire", hst.EWayland | hst.EPulse | hst.EPipeWire, nil},
+		{"duplicate", "dbus,dbus", hst.EDBus, nil},
+		{"string", "wayland, x11, dbus, pulseaudio, pipewire", hst.EWayland | hst.EX11 | hst.EDBus | hst.EPulse | hst.EPipeWire, nil},
+
+		{"unknown", "wayland,pulseaudi0", 0, hst.EnablementError("pulseaudi0")},
+		{"trailing", "wayland,", 0, hst.EnablementError("")},
+		{"no enablements", "(no enablements)", 0, hst.EnablementError("(no enablements)")},
+		{"raw", "e20", 0, hst.EnablementError("e20")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := hst.ParseEnablements(tc.s)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ParseEnablements: error = %v, want %v", err, tc.wantErr)
+			}
+			if got.Unwrap() != tc.want {
+				t.Errorf("ParseEnablements: %s, want %s", got.Unwrap(), tc.want)
+			}
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		t.Parallel()
+
+		for e := hst.Enablement(1); e < hst.EM; e++ {
+			if got, err := hst.ParseEnablements(e.String()); err != nil {
+				t.Errorf("ParseEnablements(%q): error = %v", e.String(), err)
+			} else if got.Unwrap() != e {
+				t.Errorf("ParseEnablements(%q): %s, want %s", e.String(), got.Unwrap(), e)
+			}
+		}
+	})
+
+	if want := `unknown enablement "pulseaudi0"`; hst.EnablementError("pulseaudi0").Error() != want {
+		t.Errorf("Error: %q, want %q", hst.EnablementError("pulseaudi0").Error(), want)
+	}
+}
+
 func TestEnablements(t *testing.T) {
 	t.Parallel()
 
@@ -63,7 +120,8 @@ func TestEnablements(t *testing.T) {
 		{"x11", hst.NewEnablements(hst.EX11), `{"x11":true}`, `{"value":{"x11":true},"magic":3236757504}`},
 		{"dbus", hst.NewEnablements(hst.EDBus), `{"dbus":true}`, `{"value":{"dbus":true},"magic":3236757504}`},
 		{"pulse", hst.NewEnablements(hst.EPulse), `{"pulse":true}`, `{"value":{"pulse":true},"magic":3236757504}`},
-		{"all", hst.NewEnablements(hst.EWayland | hst.EX11 | hst.EDBus | hst.EPulse), `{"wayland":true,"x11":true,"dbus":true,"pulse":true}`, `{"value":{"wayland":true,"x11":true,"dbus":true,"pulse":true},"magic":3236757504}`},
+		{"pipewire", hst.NewEnablements(hst.EPipeWire), `{"pipewire":true}`, `{"value":{"pipewire":true},"magic":3236757504}`},
+		{"all", hst.NewEnablements(hst.EWayland | hst.EX11 | hst.EDBus | hst.EPulse | hst.EPipeWire), `{"wayland":true,"x11":true,"dbus":true,"pulse":true,"pipewire":true}`, `{"value":{"wayland":true,"x11":true,"dbus":true,"pulse":true,"pipewire":true},"magic":3236757504}`},
 	}
 
 	for _, tc := range testCases {
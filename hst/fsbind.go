@@ -2,6 +2,9 @@ package hst
 
 import (
 	"encoding/gob"
+	"errors"
+	"path"
+	"strconv"
 	"strings"
 
 	"hakurei.app/container/check"
@@ -14,6 +17,9 @@ func init() { gob.Register(new(FSBind)) }
 // FilesystemBind is the type string of a bind mount point.
 const FilesystemBind = "bind"
 
+// ErrHomeEscape is returned by [FSBind.ExpandHome] for a home relative pathname escaping the home directory.
+var ErrHomeEscape = errors.New("path escapes home directory")
+
 // FSBind represents a host to container bind mount.
 type FSBind struct {
 	// Pathname in the container mount namespace. Same as Source if nil.
@@ -30,10 +36,138 @@ type FSBind struct {
 	// Silently skip this mount point if Source does not exist in the init mount namespace.
 	Optional bool `json:"optional,omitempty"`
 
+	// Whether mount points under Source are made available on Target, defaults to true if nil.
+	// Read-only is applied to each of them individually, as a single mount call does not
+	// propagate it to submounts on some kernels.
+	Recursive *bool `json:"recursive,omitempty"`
+	// Whether Target is mounted read-only, defaults to the negation of Write if nil.
+	// Setting this to true alongside Write or Device is invalid.
+	ReadOnly *bool `json:"readonly,omitempty"`
+	// Mount options applied to Target, defaults to nosuid,nodev if empty.
+	// Options weakening the default, namely dev, suid and exec, are only accepted by
+	// [Config.Validate] for privileged configurations, see [MountOptionPrivileged].
+	Options []string `json:"options,omitempty"`
+
 	/* Enable special behaviour:
 	For autoroot: Target must be [fhs.Root].
 	For autoetc:  Target must be [fhs.Etc]. */
 	Special bool `json:"special,omitempty"`
+
+	// Pathname relative to the container home directory, expanded to Target by [FSBind.ExpandHome].
+	// Mutually exclusive with Target.
+	HomeTarget string `json:"home_dst,omitempty"`
+	// Pathname relative to the home directory of the calling user on the host,
+	// expanded to Source by [FSBind.ExpandHome]. Mutually exclusive with Source.
+	HomeSource string `json:"home_src,omitempty"`
+}
+
+// ExpandHome returns a copy of [FSBind] with HomeTarget expanded against the container home directory
+// and HomeSource expanded against the host home directory. The receiver is returned as is if neither
+// is set. Values conflicting with Target or Source are left in place and cause the resulting [FSBind]
+// to be rejected by [FSBind.Valid].
+func (b *FSBind) ExpandHome(home, hostHome *check.Absolute) (*FSBind, error) {
+	if b == nil || (b.HomeTarget == "" && b.HomeSource == "") {
+		return b, nil
+	}
+
+	v := *b
+	var err error
+	if v.HomeTarget != "" && v.Target == nil {
+		if home == nil {
+			return nil, &AppError{Step: "expand home relative path", Err: ErrConfigNull,
+				Msg: "container configuration missing path to home directory"}
+		}
+		if v.Target, err = expandHome(home, v.HomeTarget); err != nil {
+			return nil, err
+		}
+		v.HomeTarget = ""
+	}
+	if v.HomeSource != "" && v.Source == nil {
+		if hostHome == nil {
+			return nil, &AppError{Step: "expand home relative path", Err: ErrConfigNull,
+				Msg: "host home directory is not known"}
+		}
+		if v.Source, err = expandHome(hostHome, v.HomeSource); err != nil {
+			return nil, err
+		}
+		v.HomeSource = ""
+	}
+	return &v, nil
+}
+
+const (
+	// MountOptionNoDev disallows access to devices (special files), this is the default.
+	MountOptionNoDev = "nodev"
+	// MountOptionNoSuid ignores set-user-ID and set-group-ID bits, this is the default.
+	MountOptionNoSuid = "nosuid"
+	// MountOptionNoExec disallows program execution.
+	MountOptionNoExec = "noexec"
+
+	// MountOptionDev allows access to devices (special files), without implying write access.
+	MountOptionDev = "dev"
+	// MountOptionSuid is accepted for completeness, but has no effect as container
+	// bind mounts are always nosuid and container processes run with no_new_privs.
+	MountOptionSuid = "suid"
+	// MountOptionExec explicitly allows program execution, this has no effect as it is the default.
+	MountOptionExec = "exec"
+)
+
+// ErrMountOption is returned by [Config.Validate] for an unknown, conflicting or disallowed entry in [FSBind.Options].
+var ErrMountOption = errors.New("invalid mount option")
+
+// MountOptionPrivileged returns whether option is only accepted for privileged configurations.
+// A configuration is privileged if it sets [FDevice], as it already has access to host devices.
+func MountOptionPrivileged(option string) bool {
+	switch option {
+	case MountOptionDev, MountOptionSuid, MountOptionExec:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkMountOptions returns the first unknown or conflicting option in options.
+// The device argument is the value of [FSBind.Device], which conflicts with nodev.
+func checkMountOptions(options []string, device bool) error {
+	var dev, nodev, exec, noexec bool
+	for _, option := range options {
+		switch option {
+		case MountOptionNoDev:
+			nodev = true
+		case MountOptionNoSuid, MountOptionSuid:
+			break
+		case MountOptionNoExec:
+			noexec = true
+		case MountOptionDev:
+			dev = true
+		case MountOptionExec:
+			exec = true
+
+		default:
+			return &AppError{Step: "validate configuration", Err: ErrMountOption,
+				Msg: "unknown mount option " + strconv.Quote(option)}
+		}
+
+		if (dev || device) && nodev {
+			return &AppError{Step: "validate configuration", Err: ErrMountOption,
+				Msg: "mount option " + strconv.Quote(MountOptionNoDev) + " conflicts with device access"}
+		}
+		if exec && noexec {
+			return &AppError{Step: "validate configuration", Err: ErrMountOption,
+				Msg: "mount option " + strconv.Quote(MountOptionNoExec) + " conflicts with " + strconv.Quote(MountOptionExec)}
+		}
+	}
+	return nil
+}
+
+// expandHome resolves pathname relative to home and rejects results escaping home.
+func expandHome(home *check.Absolute, pathname string) (*check.Absolute, error) {
+	name := path.Clean(pathname)
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return nil, &AppError{Step: "expand home relative path", Err: ErrHomeEscape,
+			Msg: "path " + strconv.Quote(pathname) + " escapes home directory"}
+	}
+	return home.Append(name), nil
 }
 
 // IsAutoRoot returns whether this FSBind has autoroot behaviour enabled.
@@ -50,9 +184,19 @@ func (b *FSBind) Valid() bool {
 	if b == nil || b.Source == nil {
 		return false
 	}
+	// home relative pathnames must be expanded first
+	if b.HomeTarget != "" || b.HomeSource != "" {
+		return false
+	}
 	if b.Ensure && b.Optional {
 		return false
 	}
+	if b.ReadOnly != nil && *b.ReadOnly && (b.Write || b.Device) {
+		return false
+	}
+	if checkMountOptions(b.Options, b.Device) != nil {
+		return false
+	}
 	if b.Special {
 		if b.Target == nil {
 			return false
@@ -69,6 +213,17 @@ func (b *FSBind) Valid() bool {
 	return true
 }
 
+// writable returns whether Target is mounted read-write, not considering Device.
+// IsReadOnly returns whether Target is mounted read-only.
+func (b *FSBind) IsReadOnly() bool { return b.Valid() && !b.writable() && !b.Device }
+
+func (b *FSBind) writable() bool {
+	if b.ReadOnly != nil {
+		return !*b.ReadOnly
+	}
+	return b.Write
+}
+
 func (b *FSBind) Path() *check.Absolute {
 	if !b.Valid() {
 		return nil
@@ -96,7 +251,7 @@ func (b *FSBind) Apply(z *ApplyState) {
 		target = b.Source
 	}
 	var flags int
-	if b.Write {
+	if b.writable() {
 		flags |= std.BindWritable
 	}
 	if b.Device {
@@ -108,6 +263,17 @@ func (b *FSBind) Apply(z *ApplyState) {
 	if b.Optional {
 		flags |= std.BindOptional
 	}
+	if b.Recursive != nil && !*b.Recursive {
+		flags |= std.BindNoRecursive
+	}
+	for _, option := range b.Options {
+		switch option {
+		case MountOptionDev:
+			flags |= std.BindDevice
+		case MountOptionNoExec:
+			flags |= std.BindNoExec
+		}
+	}
 
 	switch {
 	case b.IsAutoRoot():
@@ -129,7 +295,7 @@ func (b *FSBind) String() string {
 	var flagSym string
 	if b.Device {
 		flagSym = "d"
-	} else if b.Write {
+	} else if b.writable() {
 		flagSym = "w"
 	}
 
@@ -1,9 +1,11 @@
 package hst_test
 
 import (
+	"reflect"
 	"testing"
 
 	"hakurei.app/container"
+	"hakurei.app/container/check"
 	"hakurei.app/container/std"
 	"hakurei.app/hst"
 )
@@ -15,6 +17,8 @@ func TestFSBind(t *testing.T) {
 		{"nil", (*hst.FSBind)(nil), false, nil, nil, nil, "<invalid>"},
 		{"ensure optional", &hst.FSBind{Source: m("/"), Ensure: true, Optional: true},
 			false, nil, nil, nil, "<invalid>"},
+		{"home unexpanded", &hst.FSBind{Source: m("/"), HomeTarget: ".config"},
+			false, nil, nil, nil, "<invalid>"},
 
 		{"full", &hst.FSBind{
 			Target:   m("/dev"),
@@ -63,6 +67,82 @@ func TestFSBind(t *testing.T) {
 		}}, m("/tmp"), ms("/mnt/tmp"),
 			"w*/mnt/tmp:/tmp"},
 
+		{"readonly write", &hst.FSBind{Source: m("/"), Write: true, ReadOnly: newBool(true)},
+			false, nil, nil, nil, "<invalid>"},
+		{"readonly device", &hst.FSBind{Source: m("/"), Device: true, ReadOnly: newBool(true)},
+			false, nil, nil, nil, "<invalid>"},
+
+		{"readonly", &hst.FSBind{
+			Target:   m("/nix/store"),
+			Source:   m("/mnt/nix/store"),
+			ReadOnly: newBool(true),
+		}, true, container.Ops{&container.BindMountOp{
+			Source: m("/mnt/nix/store"),
+			Target: m("/nix/store"),
+		}}, m("/nix/store"), ms("/mnt/nix/store"),
+			"*/mnt/nix/store:/nix/store"},
+
+		{"readonly false", &hst.FSBind{
+			Target:   m("/tmp"),
+			Source:   m("/mnt/tmp"),
+			ReadOnly: newBool(false),
+		}, true, container.Ops{&container.BindMountOp{
+			Source: m("/mnt/tmp"),
+			Target: m("/tmp"),
+			Flags:  std.BindWritable,
+		}}, m("/tmp"), ms("/mnt/tmp"),
+			"w*/mnt/tmp:/tmp"},
+
+		{"recursive", &hst.FSBind{
+			Target:    m("/nix/store"),
+			Source:    m("/mnt/nix/store"),
+			Recursive: newBool(true),
+			ReadOnly:  newBool(true),
+		}, true, container.Ops{&container.BindMountOp{
+			Source: m("/mnt/nix/store"),
+			Target: m("/nix/store"),
+		}}, m("/nix/store"), ms("/mnt/nix/store"),
+			"*/mnt/nix/store:/nix/store"},
+
+		{"non-recursive", &hst.FSBind{
+			Target:    m("/nix/store"),
+			Source:    m("/mnt/nix/store"),
+			Recursive: newBool(false),
+		}, true, container.Ops{&container.BindMountOp{
+			Source: m("/mnt/nix/store"),
+			Target: m("/nix/store"),
+			Flags:  std.BindNoRecursive,
+		}}, m("/nix/store"), ms("/mnt/nix/store"),
+			"*/mnt/nix/store:/nix/store"},
+
+		{"options noexec", &hst.FSBind{
+			Target:  m("/var/cache"),
+			Source:  m("/var/cache/shared"),
+			Write:   true,
+			Options: []string{"nosuid", "nodev", "noexec"},
+		}, true, container.Ops{&container.BindMountOp{
+			Source: m("/var/cache/shared"),
+			Target: m("/var/cache"),
+			Flags:  std.BindWritable | std.BindNoExec,
+		}}, m("/var/cache"), ms("/var/cache/shared"),
+			"w*/var/cache/shared:/var/cache"},
+
+		{"options dev", &hst.FSBind{
+			Target:  m("/dev"),
+			Source:  m("/mnt/dev"),
+			Options: []string{"dev", "exec", "suid"},
+		}, true, container.Ops{&container.BindMountOp{
+			Source: m("/mnt/dev"),
+			Target: m("/dev"),
+			Flags:  std.BindDevice,
+		}}, m("/dev"), ms("/mnt/dev"),
+			"*/mnt/dev:/dev"},
+
+		{"options unknown", &hst.FSBind{Source: m("/"), Options: []string{"ro"}},
+			false, nil, nil, nil, "<invalid>"},
+		{"options nodev device", &hst.FSBind{Source: m("/"), Device: true, Options: []string{"nodev"}},
+			false, nil, nil, nil, "<invalid>"},
+
 		{"full no flags", &hst.FSBind{
 			Target: m("/etc"),
 			Source: m("/mnt/etc"),
@@ -122,3 +202,69 @@ func TestFSBind(t *testing.T) {
 		}, m("/etc/"), ms("/etc/"), "autoetc:/etc/"},
 	})
 }
+
+func TestFSBindExpandHome(t *testing.T) {
+	t.Parallel()
+
+	home, hostHome := m("/var/lib/hakurei/u0/a1"), m("/home/user")
+	testCases := []struct {
+		name     string
+		b        *hst.FSBind
+		home     *check.Absolute
+		hostHome *check.Absolute
+		want     *hst.FSBind
+		wantErr  error
+	}{
+		{"nil", nil, home, hostHome, nil, nil},
+		{"absolute", &hst.FSBind{Source: m("/etc")}, nil, nil, &hst.FSBind{Source: m("/etc")}, nil},
+		{"null home", &hst.FSBind{Source: m("/etc"), HomeTarget: ".config"}, nil, hostHome, nil, &hst.AppError{
+			Step: "expand home relative path", Err: hst.ErrConfigNull,
+			Msg: "container configuration missing path to home directory"}},
+		{"null host home", &hst.FSBind{HomeSource: ".config"}, home, nil, nil, &hst.AppError{
+			Step: "expand home relative path", Err: hst.ErrConfigNull,
+			Msg: "host home directory is not known"}},
+
+		{"target", &hst.FSBind{Source: m("/mnt/config"), HomeTarget: ".config/app", Write: true}, home, nil,
+			&hst.FSBind{Source: m("/mnt/config"), Target: m("/var/lib/hakurei/u0/a1/.config/app"), Write: true}, nil},
+		{"source", &hst.FSBind{HomeSource: "./.cache/../.local"}, nil, hostHome,
+			&hst.FSBind{Source: m("/home/user/.local")}, nil},
+		{"both", &hst.FSBind{HomeSource: "a", HomeTarget: "b"}, home, hostHome,
+			&hst.FSBind{Source: m("/home/user/a"), Target: m("/var/lib/hakurei/u0/a1/b")}, nil},
+		{"home", &hst.FSBind{HomeSource: "."}, home, hostHome, &hst.FSBind{Source: hostHome}, nil},
+		{"conflict", &hst.FSBind{Source: m("/etc"), HomeSource: "a"}, home, hostHome,
+			&hst.FSBind{Source: m("/etc"), HomeSource: "a"}, nil},
+
+		{"escape", &hst.FSBind{HomeTarget: ".config/../../a2"}, home, hostHome, nil, &hst.AppError{
+			Step: "expand home relative path", Err: hst.ErrHomeEscape,
+			Msg: `path ".config/../../a2" escapes home directory`}},
+		{"escape parent", &hst.FSBind{HomeSource: ".."}, home, hostHome, nil, &hst.AppError{
+			Step: "expand home relative path", Err: hst.ErrHomeEscape,
+			Msg: `path ".." escapes home directory`}},
+		{"escape absolute", &hst.FSBind{HomeSource: "/etc"}, home, hostHome, nil, &hst.AppError{
+			Step: "expand home relative path", Err: hst.ErrHomeEscape,
+			Msg: `path "/etc" escapes home directory`}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var orig hst.FSBind
+			if tc.b != nil {
+				orig = *tc.b
+			}
+
+			got, err := tc.b.ExpandHome(tc.home, tc.hostHome)
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("ExpandHome: error = %v, want %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ExpandHome: %#v, want %#v", got, tc.want)
+			}
+			if tc.b != nil && !reflect.DeepEqual(*tc.b, orig) {
+				t.Errorf("ExpandHome: clobbered receiver %#v, want %#v", *tc.b, orig)
+			}
+		})
+	}
+}
+
+func newBool(v bool) *bool { return &v }
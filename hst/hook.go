@@ -0,0 +1,31 @@
+package hst
+
+import "slices"
+
+// A ParamsHook makes last-minute changes to the container filesystem and the environment of
+// the initial program that are not expressible in [Config]. See [RegisterParamsHook].
+type ParamsHook func(ops Ops, env map[string]string)
+
+// paramsHooks holds hooks registered via RegisterParamsHook, in the order they are registered.
+var paramsHooks []ParamsHook
+
+/*
+RegisterParamsHook arranges for f to be called once the container is otherwise configured.
+This must only be called during initialisation.
+
+Hooks run in the shim, which is the hakurei program re-executed as the target user, so they
+only take effect for a hakurei build linking the package registering them, for example via a
+blank import added to cmd/hakurei. Hooks run in the order they are registered, after configured
+filesystems and path hiding are applied and before / is remounted read-only, so mount points
+appended via ops may still be created. Values in env are not expanded, and variables listed in
+[ContainerConfig.EnvScrub] are removed regardless.
+*/
+func RegisterParamsHook(f ParamsHook) {
+	if f == nil {
+		panic("attempting to register nil params hook")
+	}
+	paramsHooks = append(paramsHooks, f)
+}
+
+// ParamsHooks returns hooks registered via [RegisterParamsHook], in the order they are registered.
+func ParamsHooks() []ParamsHook { return slices.Clone(paramsHooks) }
@@ -0,0 +1,43 @@
+package hst_test
+
+import (
+	"testing"
+
+	"hakurei.app/hst"
+)
+
+func TestRegisterParamsHook(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		defer func() {
+			want := "attempting to register nil params hook"
+			if r := recover(); r != want {
+				t.Errorf("RegisterParamsHook: panic = %v, want %v", r, want)
+			}
+		}()
+		hst.RegisterParamsHook(nil)
+	})
+
+	t.Run("order", func(t *testing.T) {
+		n := len(hst.ParamsHooks())
+		hst.RegisterParamsHook(func(_ hst.Ops, env map[string]string) { env["HOOK"] += "0" })
+		hst.RegisterParamsHook(func(_ hst.Ops, env map[string]string) { env["HOOK"] += "1" })
+
+		hooks := hst.ParamsHooks()
+		if len(hooks) != n+2 {
+			t.Fatalf("ParamsHooks: len = %d, want %d", len(hooks), n+2)
+		}
+		env := make(map[string]string)
+		for _, f := range hooks[n:] {
+			f(nil, env)
+		}
+		if got := env["HOOK"]; got != "01" {
+			t.Errorf("ParamsHooks: HOOK = %q, want %q", got, "01")
+		}
+
+		// the returned slice must not alias the registry
+		hooks[n] = nil
+		if hst.ParamsHooks()[n] == nil {
+			t.Errorf("ParamsHooks: registry modified via returned slice")
+		}
+	})
+}
@@ -52,6 +52,14 @@ type Paths struct {
 	RunDirPath *check.Absolute `json:"run_dir_path"`
 }
 
+// InstancePaths returns pathnames of the process-specific directories of the instance identified by id,
+// located in [Paths.SharePath] and [Paths.RunDirPath] respectively. These directories hold artifacts of
+// the instance and are only present while it is running, or if it terminated without cleaning up.
+func InstancePaths(paths *Paths, id *ID) (share, runtime *check.Absolute) {
+	name := id.String()
+	return paths.SharePath.Append(name), paths.RunDirPath.Append(name)
+}
+
 // Info holds basic system information collected from the implementation.
 type Info struct {
 	// Version is a hardcoded version string.
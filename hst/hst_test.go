@@ -9,6 +9,7 @@ import (
 	"syscall"
 	"testing"
 
+	"hakurei.app/container/fhs"
 	"hakurei.app/container/stub"
 	"hakurei.app/hst"
 	"hakurei.app/message"
@@ -97,6 +98,30 @@ func TestAppError(t *testing.T) {
 	}
 }
 
+func TestInstancePaths(t *testing.T) {
+	t.Parallel()
+
+	paths := hst.Paths{
+		TempDir:     fhs.AbsTmp,
+		SharePath:   fhs.AbsTmp.Append("hakurei.0"),
+		RuntimePath: fhs.AbsRunUser.Append("1000"),
+		RunDirPath:  fhs.AbsRunUser.Append("1000", "hakurei"),
+	}
+	id := hst.ID{
+		0xba, 0x21, 0xc9, 0xbd,
+		0x33, 0xd9, 0xd3, 0x79,
+		0x17, 0x28, 0x82, 0x81,
+		0xa2, 0xa0, 0xd2, 0x39}
+
+	share, runtime := hst.InstancePaths(&paths, &id)
+	if want := "/tmp/hakurei.0/ba21c9bd33d9d37917288281a2a0d239"; share.String() != want {
+		t.Errorf("InstancePaths: share = %q, want %q", share, want)
+	}
+	if want := "/run/user/1000/hakurei/ba21c9bd33d9d37917288281a2a0d239"; runtime.String() != want {
+		t.Errorf("InstancePaths: runtime = %q, want %q", runtime, want)
+	}
+}
+
 func TestTemplate(t *testing.T) {
 	t.Parallel()
 
@@ -246,7 +271,12 @@ func TestTemplate(t *testing.T) {
 		"map_real_uid": true,
 		"device": true,
 		"share_runtime": true,
-		"share_tmpdir": true
+		"share_tmpdir": true,
+		"gpu_config": true,
+		"env_strict": true,
+		"machine_id": true,
+		"read_only_root": true,
+		"network_files": true
 	}
 }`
 
@@ -4,16 +4,22 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 )
 
 // An ID is a unique identifier held by a running hakurei container.
 type ID [16]byte
 
-// ErrIdentifierLength is returned when encountering a [hex] representation of [ID] with unexpected length.
-var ErrIdentifierLength = errors.New("identifier string has unexpected length")
+var (
+	// ErrIdentifierLength is returned when encountering a [hex] representation of [ID] with unexpected length.
+	ErrIdentifierLength = errors.New("identifier string has unexpected length")
+	// ErrIdentifierZero is returned by [NewInstanceIDFunc] for an [IDGenerator] returning the zero value of [ID].
+	ErrIdentifierZero = errors.New("identifier has zero value")
+)
 
 // IdentifierDecodeError is returned by [ID.UnmarshalText] to provide relevant error descriptions.
 type IdentifierDecodeError struct{ Err error }
@@ -43,6 +49,42 @@ func (a *ID) CreationTime() time.Time {
 // NewInstanceID creates a new unique [ID].
 func NewInstanceID(id *ID) error { return newInstanceID(id, uint64(time.Now().UnixNano())) }
 
+/*
+IDGenerator returns the [hex] representation of a new [ID] as accepted by [ID.UnmarshalText],
+useful for deriving identifiers from an external scheme.
+
+Returned identifiers must be unique. An identifier colliding with that of a running instance
+of the same identity is rejected when the state entry of the instance is saved, and the instance
+fails to start. Collisions with instances of other identities are not detected.
+*/
+type IDGenerator func() ([]byte, error)
+
+// NewInstanceIDFunc creates a new [ID] via gen, or [NewInstanceID] if gen is nil.
+// A non-nil error returned by NewInstanceIDFunc for a non-nil gen is of type [AppError].
+func NewInstanceIDFunc(id *ID, gen IDGenerator) error {
+	if gen == nil {
+		return NewInstanceID(id)
+	}
+
+	const step = "generate instance id"
+	text, err := gen()
+	if err != nil {
+		return &AppError{Step: step, Err: err}
+	}
+
+	var v ID
+	if err = v.UnmarshalText(text); err != nil {
+		return &AppError{Step: step, Err: err,
+			Msg: "generator returned malformed instance id " + strconv.Quote(string(text))}
+	}
+	if v == (ID{}) {
+		return &AppError{Step: step, Err: ErrIdentifierZero,
+			Msg: "generator returned zero instance id"}
+	}
+	*id = v
+	return nil
+}
+
 // newInstanceID creates a new unique [ID] with the specified timestamp.
 func newInstanceID(id *ID, p uint64) error {
 	binary.BigEndian.PutUint64(id[:8], p)
@@ -84,4 +126,8 @@ type State struct {
 
 	// Point in time the shim process was created.
 	Time time.Time `json:"time"`
+
+	// Opaque record of system operations applied on behalf of the instance, for reverting
+	// them if the monitoring process terminates without doing so.
+	System json.RawMessage `json:"system,omitempty"`
 }
@@ -113,3 +113,57 @@ func TestID(t *testing.T) {
 		}
 	})
 }
+
+func TestNewInstanceIDFunc(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		gen  hst.IDGenerator
+		want hst.ID
+		err  error
+	}{
+		{"generator error", func() ([]byte, error) { return nil, hst.ErrIdentifierLength }, hst.ID{},
+			&hst.AppError{Step: "generate instance id", Err: hst.ErrIdentifierLength}},
+		{"malformed", func() ([]byte, error) { return []byte("job-1337"), nil }, hst.ID{},
+			&hst.AppError{Step: "generate instance id", Err: hst.IdentifierDecodeError{Err: hst.ErrIdentifierLength},
+				Msg: `generator returned malformed instance id "job-1337"`}},
+		{"zero", func() ([]byte, error) { return []byte("00000000000000000000000000000000"), nil }, hst.ID{},
+			&hst.AppError{Step: "generate instance id", Err: hst.ErrIdentifierZero,
+				Msg: "generator returned zero instance id"}},
+
+		{"sample", func() ([]byte, error) { return []byte("ba21c9bd33d9d37917288281a2a0d239"), nil }, hst.ID{
+			0xba, 0x21, 0xc9, 0xbd,
+			0x33, 0xd9, 0xd3, 0x79,
+			0x17, 0x28, 0x82, 0x81,
+			0xa2, 0xa0, 0xd2, 0x39}, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got hst.ID
+			if err := hst.NewInstanceIDFunc(&got, tc.gen); !reflect.DeepEqual(err, tc.err) {
+				t.Errorf("NewInstanceIDFunc: error = %#v, want %#v", err, tc.err)
+			}
+			if got != tc.want {
+				t.Errorf("NewInstanceIDFunc: %s, want %s", got.String(), tc.want.String())
+			}
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+
+		var a, b hst.ID
+		if err := hst.NewInstanceIDFunc(&a, nil); err != nil {
+			t.Fatalf("NewInstanceIDFunc: error = %v", err)
+		}
+		if err := hst.NewInstanceIDFunc(&b, nil); err != nil {
+			t.Fatalf("NewInstanceIDFunc: error = %v", err)
+		}
+		if a == b || a == (hst.ID{}) {
+			t.Errorf("NewInstanceIDFunc: %s, %s", a.String(), b.String())
+		}
+	})
+}
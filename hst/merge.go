@@ -0,0 +1,192 @@
+package hst
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+
+	"hakurei.app/container/fhs"
+)
+
+/*
+MergeConfigs returns a new [Config] holding the values of base overridden by override.
+
+Scalar values set in override replace those in base, while a zero value leaves the base value
+in place. Boolean values and [ContainerConfig.Flags] are OR-ed, as their zero value cannot be
+told apart from an unset value, except for [Config.DirectXauthority] and [Config.X11Cookie]: as these
+are mutually exclusive, setting one of them in override clears the other. Nil pointers and slices in override leave the
+corresponding base value untouched, a non-nil [Enablements], [BusConfig] or slice of arguments or
+groups replaces it entirely.
+
+[Config.ExtraPerms], [ContainerConfig.Filesystem], [ContainerConfig.EnvScrub],
+[ContainerConfig.PassEnv], [ContainerConfig.DenySocketFamilies], [ContainerConfig.InputDevices],
+[ContainerConfig.Devices], [ContainerConfig.RuntimeSockets] and [ContainerConfig.PublishPorts] are unioned, with elements of override appended after those of
+base and elements already present omitted. A filesystem
+element targeting / is kept first, and the one in override takes precedence if both are present.
+Environment variables and [CgroupConfig.LimitIO] entries are merged by key, with override winning.
+
+Values not merged may be shared with base or override. The result is not validated and
+should be checked via [Config.Validate] before use.
+*/
+func MergeConfigs(base, override *Config) *Config {
+	if base == nil && override == nil {
+		return nil
+	}
+	if base == nil {
+		base = new(Config)
+	}
+	if override == nil {
+		override = new(Config)
+	}
+
+	c := *base
+	mergeScalar(&c.ID, override.ID)
+	mergeScalar(&c.Identity, override.Identity)
+	c.DirectWayland = base.DirectWayland || override.DirectWayland
+	c.DirectXauthority = base.DirectXauthority || override.DirectXauthority
+	c.X11Cookie = base.X11Cookie || override.X11Cookie
+	// mutually exclusive, the one set in override wins
+	if override.DirectXauthority != override.X11Cookie {
+		c.DirectXauthority, c.X11Cookie = override.DirectXauthority, override.X11Cookie
+	}
+
+	if override.Enablements != nil {
+		e := *override.Enablements
+		c.Enablements = &e
+	}
+	if override.SessionBus != nil {
+		c.SessionBus = override.SessionBus
+	}
+	if override.SystemBus != nil {
+		c.SystemBus = override.SystemBus
+	}
+	if override.Groups != nil {
+		c.Groups = override.Groups
+	}
+	c.ExtraPerms = mergeUnion(base.ExtraPerms, override.ExtraPerms)
+	c.Container = mergeContainer(base.Container, override.Container)
+	return &c
+}
+
+// mergeContainer returns the result of merging two [ContainerConfig] as described in [MergeConfigs].
+func mergeContainer(base, override *ContainerConfig) *ContainerConfig {
+	if override == nil {
+		return base
+	}
+	if base == nil {
+		base = new(ContainerConfig)
+	}
+
+	c := *base
+	mergeScalar(&c.Hostname, override.Hostname)
+	mergeScalar(&c.WaitDelay, override.WaitDelay)
+	mergeScalar(&c.IdleTimeout, override.IdleTimeout)
+	mergeScalar(&c.Username, override.Username)
+	mergeScalar(&c.Shell, override.Shell)
+	mergeScalar(&c.Home, override.Home)
+	mergeScalar(&c.Path, override.Path)
+	mergeScalar(&c.SeccompAction, override.SeccompAction)
+	mergeScalar(&c.DevShmSize, override.DevShmSize)
+	mergeScalar(&c.PrivateTmpPath, override.PrivateTmpPath)
+	if override.Args != nil {
+		c.Args = override.Args
+	}
+	c.LoginShell = base.LoginShell || override.LoginShell
+	c.Flags = base.Flags | override.Flags
+
+	c.Env = mergeMap(base.Env, override.Env)
+	c.EnvScrub = mergeUnion(base.EnvScrub, override.EnvScrub)
+	c.PassEnv = mergeUnion(base.PassEnv, override.PassEnv)
+	c.Filesystem = mergeFilesystem(base.Filesystem, override.Filesystem)
+	c.DenySocketFamilies = mergeUnion(base.DenySocketFamilies, override.DenySocketFamilies)
+	c.InputDevices = mergeUnion(base.InputDevices, override.InputDevices)
+	c.Devices = mergeUnion(base.Devices, override.Devices)
+	c.RuntimeSockets = mergeUnion(base.RuntimeSockets, override.RuntimeSockets)
+	c.PublishPorts = mergeUnion(base.PublishPorts, override.PublishPorts)
+	c.Cgroup = mergeCgroup(base.Cgroup, override.Cgroup)
+	return &c
+}
+
+// mergeCgroup returns the result of merging two [CgroupConfig] as described in [MergeConfigs].
+func mergeCgroup(base, override *CgroupConfig) *CgroupConfig {
+	if override == nil {
+		return base
+	}
+	if base == nil {
+		base = new(CgroupConfig)
+	}
+
+	c := *base
+	mergeScalar(&c.Slice, override.Slice)
+	mergeScalar(&c.LimitCPU, override.LimitCPU)
+	mergeScalar(&c.CPUWeight, override.CPUWeight)
+	mergeScalar(&c.LimitMemory, override.LimitMemory)
+	mergeScalar(&c.MemorySwapMax, override.MemorySwapMax)
+	mergeScalar(&c.MemoryLow, override.MemoryLow)
+	mergeScalar(&c.LimitPids, override.LimitPids)
+	mergeScalar(&c.CPUSet, override.CPUSet)
+	c.LimitIO = mergeMap(base.LimitIO, override.LimitIO)
+	c.Accounting = base.Accounting || override.Accounting
+	c.CPUInfo = base.CPUInfo || override.CPUInfo
+	c.Persist = base.Persist || override.Persist
+	c.EnableControllers = base.EnableControllers || override.EnableControllers
+	c.MemoryEvents = base.MemoryEvents || override.MemoryEvents
+	return &c
+}
+
+// mergeFilesystem returns the union of two filesystem slices, keeping an element targeting / first.
+func mergeFilesystem(base, override []FilesystemConfigJSON) []FilesystemConfigJSON {
+	if override == nil {
+		return base
+	}
+
+	var root []FilesystemConfigJSON
+	if filesystemHasRoot(base) {
+		root, base = base[:1], base[1:]
+	}
+	if filesystemHasRoot(override) {
+		root, override = override[:1], override[1:]
+	}
+	return mergeUnion(slices.Concat(root, base), override)
+}
+
+// filesystemHasRoot returns whether the first element of filesystem targets /.
+func filesystemHasRoot(filesystem []FilesystemConfigJSON) bool {
+	return len(filesystem) > 0 && filesystem[0].Valid() && filesystem[0].Path().String() == fhs.Root
+}
+
+// mergeScalar replaces the value pointed to by p with v if v is not the zero value.
+func mergeScalar[T comparable](p *T, v T) {
+	var zero T
+	if v != zero {
+		*p = v
+	}
+}
+
+// mergeMap returns a new map holding entries of base and override, with override winning.
+func mergeMap[M ~map[K]V, K comparable, V any](base, override M) M {
+	if override == nil {
+		return base
+	}
+	if base == nil {
+		return override
+	}
+	m := maps.Clone(base)
+	maps.Copy(m, override)
+	return m
+}
+
+// mergeUnion returns a new slice holding elements of base followed by elements of override
+// not already present. Equality is determined via [reflect.DeepEqual].
+func mergeUnion[S ~[]E, E any](base, override S) S {
+	if override == nil {
+		return base
+	}
+	s := slices.Clip(base)
+	for _, v := range override {
+		if !slices.ContainsFunc(s, func(e E) bool { return reflect.DeepEqual(e, v) }) {
+			s = append(s, v)
+		}
+	}
+	return s
+}
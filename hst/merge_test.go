@@ -0,0 +1,303 @@
+package hst_test
+
+import (
+	"reflect"
+	"testing"
+
+	"hakurei.app/container/check"
+	"hakurei.app/container/fhs"
+	"hakurei.app/hst"
+)
+
+func TestMergeConfigs(t *testing.T) {
+	t.Parallel()
+
+	m := check.MustAbs
+	rootBase := hst.FilesystemConfigJSON{FilesystemConfig: &hst.FSBind{Target: fhs.AbsRoot, Source: m("/var/lib/hakurei/base/org.debian"), Special: true}}
+	rootOverride := hst.FilesystemConfigJSON{FilesystemConfig: &hst.FSBind{Target: fhs.AbsRoot, Source: m("/var/lib/hakurei/base/org.nixos"), Special: true}}
+	fsEtc := hst.FilesystemConfigJSON{FilesystemConfig: &hst.FSBind{Target: fhs.AbsEtc, Source: fhs.AbsEtc, Special: true}}
+	fsTmp := hst.FilesystemConfigJSON{FilesystemConfig: &hst.FSEphemeral{Target: hst.AbsPrivateTmp}}
+	fsDri := hst.FilesystemConfigJSON{FilesystemConfig: &hst.FSBind{Source: m("/dev/dri"), Device: true, Optional: true}}
+
+	newEnablements := func(e hst.Enablement) *hst.Enablements { v := hst.Enablements(e); return &v }
+
+	testCases := []struct {
+		name           string
+		base, override *hst.Config
+		want           *hst.Config
+	}{
+		{"nil", nil, nil, nil},
+		{"nil base", nil, &hst.Config{ID: "org.chromium.Chromium"}, &hst.Config{ID: "org.chromium.Chromium"}},
+		{"nil override", &hst.Config{ID: "org.chromium.Chromium", Container: &hst.ContainerConfig{Hostname: "localhost"}}, nil,
+			&hst.Config{ID: "org.chromium.Chromium", Container: &hst.ContainerConfig{Hostname: "localhost"}}},
+
+		{"scalar", &hst.Config{
+			ID:            "org.chromium.Chromium",
+			Identity:      9,
+			DirectWayland: true,
+			X11Cookie:     true,
+			Groups:        []string{"video"},
+			Container: &hst.ContainerConfig{
+				Hostname: "localhost",
+				Home:     m("/data/data/org.chromium.Chromium"),
+				Args:     []string{"chromium"},
+			},
+		}, &hst.Config{
+			Identity:         10,
+			DirectXauthority: true,
+			Container: &hst.ContainerConfig{
+				Path: m("/run/current-system/sw/bin/chromium"),
+			},
+		}, &hst.Config{
+			ID:               "org.chromium.Chromium",
+			Identity:         10,
+			DirectWayland:    true,
+			DirectXauthority: true,
+			Groups:           []string{"video"},
+			Container: &hst.ContainerConfig{
+				Hostname: "localhost",
+				Home:     m("/data/data/org.chromium.Chromium"),
+				Path:     m("/run/current-system/sw/bin/chromium"),
+				Args:     []string{"chromium"},
+			},
+		}},
+
+		{"x11 cookie", &hst.Config{
+			DirectXauthority: true,
+		}, &hst.Config{
+			X11Cookie: true,
+		}, &hst.Config{
+			X11Cookie: true,
+		}},
+
+		{"x11 cookie base", &hst.Config{
+			X11Cookie: true,
+		}, &hst.Config{
+			DirectWayland: true,
+		}, &hst.Config{
+			DirectWayland: true,
+			X11Cookie:     true,
+		}},
+
+		{"replace", &hst.Config{
+			Enablements: newEnablements(hst.EWayland | hst.EPulse),
+			SessionBus:  &hst.BusConfig{Talk: []string{"org.freedesktop.Notifications"}},
+			Groups:      []string{"video"},
+			Container:   &hst.ContainerConfig{Args: []string{"chromium"}},
+		}, &hst.Config{
+			Enablements: newEnablements(hst.EDBus),
+			SessionBus:  &hst.BusConfig{Talk: []string{"org.freedesktop.portal.*"}},
+			Groups:      []string{},
+			Container:   &hst.ContainerConfig{Args: []string{"chromium", "--ozone-platform=wayland"}},
+		}, &hst.Config{
+			Enablements: newEnablements(hst.EDBus),
+			SessionBus:  &hst.BusConfig{Talk: []string{"org.freedesktop.portal.*"}},
+			Groups:      []string{},
+			Container:   &hst.ContainerConfig{Args: []string{"chromium", "--ozone-platform=wayland"}},
+		}},
+
+		{"flags", &hst.Config{Container: &hst.ContainerConfig{
+			Flags: hst.FUserns | hst.FMapRealUID,
+		}}, &hst.Config{Container: &hst.ContainerConfig{
+			Flags: hst.FHostNet | hst.FMapRealUID,
+		}}, &hst.Config{Container: &hst.ContainerConfig{
+			Flags: hst.FUserns | hst.FHostNet | hst.FMapRealUID,
+		}}},
+
+		{"union", &hst.Config{
+			ExtraPerms: []hst.ExtraPermConfig{
+				{Path: m("/var/lib/hakurei/u0"), Ensure: true, Execute: true},
+			},
+			Container: &hst.ContainerConfig{
+				Env:                map[string]string{"LANG": "C.UTF-8", "TERM": "xterm"},
+				EnvScrub:           []string{"SSH_AUTH_SOCK"},
+				PassEnv:            []string{"MESA_*"},
+				DenySocketFamilies: []string{"inet", "inet6"},
+				InputDevices:       []*check.Absolute{m("/dev/input/event3")},
+				Devices:            []*check.Absolute{m("/dev/kvm")},
+				RuntimeSockets:     []string{"bus"},
+			},
+		}, &hst.Config{
+			ExtraPerms: []hst.ExtraPermConfig{
+				{Path: m("/var/lib/hakurei/u0"), Ensure: true, Execute: true},
+				{Path: m("/var/lib/hakurei/u0/org.chromium.Chromium"), Read: true, Write: true, Execute: true},
+			},
+			Container: &hst.ContainerConfig{
+				Env:                map[string]string{"TERM": "dumb"},
+				EnvScrub:           []string{"GOOGLE_API_KEY", "SSH_AUTH_SOCK"},
+				PassEnv:            []string{"WAYLAND_DEBUG", "MESA_*"},
+				DenySocketFamilies: []string{"bluetooth", "inet"},
+				InputDevices:       []*check.Absolute{m("/dev/input/event3"), m("/dev/input/event4")},
+				Devices:            []*check.Absolute{m("/dev/dri/renderD128"), m("/dev/kvm")},
+				RuntimeSockets:     []string{"doc-portal", "bus"},
+			},
+		}, &hst.Config{
+			ExtraPerms: []hst.ExtraPermConfig{
+				{Path: m("/var/lib/hakurei/u0"), Ensure: true, Execute: true},
+				{Path: m("/var/lib/hakurei/u0/org.chromium.Chromium"), Read: true, Write: true, Execute: true},
+			},
+			Container: &hst.ContainerConfig{
+				Env:                map[string]string{"LANG": "C.UTF-8", "TERM": "dumb"},
+				EnvScrub:           []string{"SSH_AUTH_SOCK", "GOOGLE_API_KEY"},
+				PassEnv:            []string{"MESA_*", "WAYLAND_DEBUG"},
+				DenySocketFamilies: []string{"inet", "inet6", "bluetooth"},
+				InputDevices:       []*check.Absolute{m("/dev/input/event3"), m("/dev/input/event4")},
+				Devices:            []*check.Absolute{m("/dev/kvm"), m("/dev/dri/renderD128")},
+				RuntimeSockets:     []string{"bus", "doc-portal"},
+			},
+		}},
+
+		{"filesystem append", &hst.Config{Container: &hst.ContainerConfig{
+			Filesystem: []hst.FilesystemConfigJSON{rootBase, fsEtc},
+		}}, &hst.Config{Container: &hst.ContainerConfig{
+			Filesystem: []hst.FilesystemConfigJSON{fsTmp, fsEtc, fsDri},
+		}}, &hst.Config{Container: &hst.ContainerConfig{
+			Filesystem: []hst.FilesystemConfigJSON{rootBase, fsEtc, fsTmp, fsDri},
+		}}},
+
+		{"filesystem root override", &hst.Config{Container: &hst.ContainerConfig{
+			Filesystem: []hst.FilesystemConfigJSON{rootBase, fsEtc},
+		}}, &hst.Config{Container: &hst.ContainerConfig{
+			Filesystem: []hst.FilesystemConfigJSON{rootOverride, fsTmp},
+		}}, &hst.Config{Container: &hst.ContainerConfig{
+			Filesystem: []hst.FilesystemConfigJSON{rootOverride, fsEtc, fsTmp},
+		}}},
+
+		{"filesystem root late", &hst.Config{Container: &hst.ContainerConfig{
+			Filesystem: []hst.FilesystemConfigJSON{fsEtc},
+		}}, &hst.Config{Container: &hst.ContainerConfig{
+			Filesystem: []hst.FilesystemConfigJSON{rootOverride, fsTmp},
+		}}, &hst.Config{Container: &hst.ContainerConfig{
+			Filesystem: []hst.FilesystemConfigJSON{rootOverride, fsEtc, fsTmp},
+		}}},
+
+		{"cgroup", &hst.Config{Container: &hst.ContainerConfig{
+			Cgroup: &hst.CgroupConfig{
+				CPUWeight:   50,
+				LimitMemory: 1 << 30,
+				LimitPids:   1 << 10,
+				LimitIO:     map[string]hst.CgroupIOLimit{"8:0": {RBPS: 1 << 20}},
+
+				MemoryEvents: true,
+			},
+		}}, &hst.Config{Container: &hst.ContainerConfig{
+			Cgroup: &hst.CgroupConfig{
+				CPUWeight: 200,
+				LimitPids: 1 << 8,
+				LimitIO:   map[string]hst.CgroupIOLimit{"8:16": {WBPS: 1 << 20}},
+				CPUInfo:   true,
+			},
+		}}, &hst.Config{Container: &hst.ContainerConfig{
+			Cgroup: &hst.CgroupConfig{
+				CPUWeight:   200,
+				LimitMemory: 1 << 30,
+				LimitPids:   1 << 8,
+				LimitIO: map[string]hst.CgroupIOLimit{
+					"8:0":  {RBPS: 1 << 20},
+					"8:16": {WBPS: 1 << 20},
+				},
+				CPUInfo: true,
+
+				MemoryEvents: true,
+			},
+		}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := hst.MergeConfigs(tc.base, tc.override); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("MergeConfigs: %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("unmodified", func(t *testing.T) {
+		t.Parallel()
+
+		base, override := hst.Template(), hst.Template()
+		override.Container.Filesystem = []hst.FilesystemConfigJSON{fsTmp}
+		override.Container.Env = map[string]string{"TERM": "dumb"}
+		hst.MergeConfigs(base, override)
+
+		if want := hst.Template(); !reflect.DeepEqual(base, want) {
+			t.Errorf("MergeConfigs: base = %#v, want %#v", base, want)
+		}
+		if want := []hst.FilesystemConfigJSON{fsTmp}; !reflect.DeepEqual(override.Container.Filesystem, want) {
+			t.Errorf("MergeConfigs: override filesystem = %#v, want %#v", override.Container.Filesystem, want)
+		}
+	})
+
+	t.Run("template", func(t *testing.T) {
+		t.Parallel()
+
+		if got := hst.MergeConfigs(hst.Template(), hst.Template()); !reflect.DeepEqual(got, hst.Template()) {
+			t.Errorf("MergeConfigs: %#v, want %#v", got, hst.Template())
+		}
+	})
+}
+
+func TestMergeConfigsFields(t *testing.T) {
+	t.Parallel()
+
+	newConfig := func() *hst.Config {
+		return &hst.Config{Container: &hst.ContainerConfig{Cgroup: new(hst.CgroupConfig)}}
+	}
+
+	// sample returns a value of typ which is not the zero value
+	var sample func(t *testing.T, typ reflect.Type) reflect.Value
+	sample = func(t *testing.T, typ reflect.Type) reflect.Value {
+		v := reflect.New(typ).Elem()
+		switch typ.Kind() {
+		case reflect.Bool:
+			v.SetBool(true)
+		case reflect.String:
+			v.SetString("sample")
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.SetInt(1)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			v.SetUint(1)
+		case reflect.Pointer:
+			v.Set(reflect.New(typ.Elem()))
+		case reflect.Slice:
+			v.Set(reflect.MakeSlice(typ, 1, 1))
+		case reflect.Map:
+			v.Set(reflect.MakeMap(typ))
+			v.SetMapIndex(reflect.New(typ.Key()).Elem(), reflect.New(typ.Elem()).Elem())
+		default:
+			t.Fatalf("sample: unsupported kind %s", typ.Kind())
+		}
+		return v
+	}
+
+	// every field set in override alone must be reflected in the result
+	for _, s := range []struct {
+		name string
+		get  func(c *hst.Config) reflect.Value
+	}{
+		{"Config", func(c *hst.Config) reflect.Value { return reflect.ValueOf(c).Elem() }},
+		{"ContainerConfig", func(c *hst.Config) reflect.Value { return reflect.ValueOf(c.Container).Elem() }},
+		{"CgroupConfig", func(c *hst.Config) reflect.Value { return reflect.ValueOf(c.Container.Cgroup).Elem() }},
+	} {
+		typ := s.get(newConfig()).Type()
+		for i := range typ.NumField() {
+			f := typ.Field(i)
+			if !f.IsExported() ||
+				f.Type == reflect.TypeFor[*hst.ContainerConfig]() ||
+				f.Type == reflect.TypeFor[*hst.CgroupConfig]() {
+				continue
+			}
+
+			t.Run(s.name+"."+f.Name, func(t *testing.T) {
+				t.Parallel()
+
+				override := newConfig()
+				s.get(override).Field(i).Set(sample(t, f.Type))
+				got := hst.MergeConfigs(newConfig(), override)
+				if want := s.get(override).Field(i).Interface(); !reflect.DeepEqual(s.get(got).Field(i).Interface(), want) {
+					t.Errorf("MergeConfigs: %s = %#v, want %#v", f.Name, s.get(got).Field(i).Interface(), want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package hst
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"hakurei.app/container/check"
+	"hakurei.app/container/fhs"
+)
+
+// OCISpec is the subset of an OCI runtime configuration (config.json) understood by [ImportOCI].
+type OCISpec struct {
+	// Container process.
+	Process *OCIProcess `json:"process,omitempty"`
+	// Container root filesystem.
+	Root *OCIRoot `json:"root,omitempty"`
+	// Container UTS namespace hostname.
+	Hostname string `json:"hostname,omitempty"`
+	// Additional mounts beyond the root filesystem.
+	Mounts []OCIMount `json:"mounts,omitempty"`
+}
+
+// OCIProcess is the subset of the process section of an OCI runtime configuration.
+type OCIProcess struct {
+	// Whether a terminal is attached to the process.
+	Terminal bool `json:"terminal,omitempty"`
+	// Working directory of the process.
+	Cwd string `json:"cwd,omitempty"`
+	// Environment of the process, in the format of environ(7).
+	Env []string `json:"env,omitempty"`
+	// Arguments of the process, the first element names the executable.
+	Args []string `json:"args,omitempty"`
+}
+
+// OCIRoot is the root section of an OCI runtime configuration.
+type OCIRoot struct {
+	// Pathname to the root filesystem, relative to the bundle if not absolute.
+	Path string `json:"path"`
+	// Whether the root filesystem is read-only.
+	Readonly bool `json:"readonly,omitempty"`
+}
+
+// OCIMount is an element of the mounts section of an OCI runtime configuration.
+type OCIMount struct {
+	// Pathname of the mount point in the container.
+	Destination string `json:"destination"`
+	// Filesystem type of the mount.
+	Type string `json:"type,omitempty"`
+	// Source of the mount, relative to the bundle if not absolute.
+	Source string `json:"source,omitempty"`
+	// Mount options, see mount(8).
+	Options []string `json:"options,omitempty"`
+}
+
+// ociProvided are filesystem types of mount points unconditionally set up by hakurei.
+var ociProvided = []string{"proc", "sysfs", "devpts", "mqueue", "cgroup", "cgroup2"}
+
+// ociProvidedTarget are mount points set up by hakurei, regardless of filesystem type.
+var ociProvidedTarget = []string{"/dev", "/dev/pts", "/dev/shm", "/dev/mqueue", "/proc", "/sys", "/sys/fs/cgroup"}
+
+/*
+ImportOCI translates the process, root, hostname and mounts sections of an OCI runtime
+configuration read from r onto a copy of base, and validates the result via [Config.Validate].
+
+This is a best-effort import of common fields and not an implementation of the OCI runtime
+specification. Relative pathnames are resolved against bundle, environment variables and the
+hostname override their counterparts in base, and mount points are appended to those of base.
+The root filesystem replaces a mount point of base targeting /. Mount points of filesystems
+hakurei sets up itself, such as /dev, /proc and /sys, are skipped. Options without a counterpart
+in hakurei and skipped entries are returned as warnings and otherwise ignored.
+*/
+func ImportOCI(base *Config, bundle *check.Absolute, r io.Reader) (config *Config, warnings []string, err error) {
+	if base == nil || base.Container == nil || bundle == nil {
+		return nil, nil, &AppError{Step: "import OCI configuration", Err: ErrConfigNull,
+			Msg: "invalid base configuration"}
+	}
+
+	var spec OCISpec
+	if err = json.NewDecoder(r).Decode(&spec); err != nil {
+		return nil, nil, &AppError{Step: "decode OCI configuration", Err: err}
+	}
+
+	config = new(Config)
+	*config = *base
+	config.Container = new(ContainerConfig)
+	*config.Container = *base.Container
+	config.Container.Env = maps.Clone(base.Container.Env)
+	config.Container.Filesystem = slices.Clone(base.Container.Filesystem)
+
+	warnf := func(format string, a ...any) { warnings = append(warnings, fmt.Sprintf(format, a...)) }
+	resolve := func(pathname string) *check.Absolute {
+		if a, err := check.NewAbs(pathname); err == nil {
+			return a
+		}
+		return bundle.Append(pathname)
+	}
+
+	if spec.Hostname != "" {
+		config.Container.Hostname = spec.Hostname
+	}
+
+	if p := spec.Process; p != nil {
+		if p.Terminal {
+			warnf("process.terminal is not supported, terminal access is configured via the tty flag")
+		}
+		if p.Cwd != "" && (config.Container.Home == nil || p.Cwd != config.Container.Home.String()) {
+			warnf("process.cwd %q is not supported, the home directory is entered instead", p.Cwd)
+		}
+
+		if len(p.Env) > 0 && config.Container.Env == nil {
+			config.Container.Env = make(map[string]string, len(p.Env))
+		}
+		for _, v := range p.Env {
+			if key, value, ok := strings.Cut(v, "="); !ok {
+				warnf("process.env entry %q is not in the form key=value, skipping", v)
+			} else {
+				config.Container.Env[key] = value
+			}
+		}
+
+		if len(p.Args) > 0 {
+			if a, err := check.NewAbs(p.Args[0]); err != nil {
+				warnf("process.args executable %q is not an absolute pathname, retaining configured path", p.Args[0])
+			} else {
+				config.Container.Path = a
+			}
+			config.Container.Args = slices.Clone(p.Args)
+		}
+	}
+
+	if spec.Root != nil {
+		root := FilesystemConfigJSON{FilesystemConfig: &FSBind{
+			Target: fhs.AbsRoot,
+			Source: resolve(spec.Root.Path),
+			Write:  !spec.Root.Readonly,
+		}}
+		if len(config.Container.Filesystem) > 0 && config.Container.Filesystem[0].Valid() &&
+			config.Container.Filesystem[0].Path().String() == fhs.Root {
+			warnf("root filesystem replaces configured mount point on /")
+			config.Container.Filesystem[0] = root
+		} else {
+			config.Container.Filesystem = slices.Insert(config.Container.Filesystem, 0, root)
+		}
+	}
+
+	for _, m := range spec.Mounts {
+		target, err := check.NewAbs(m.Destination)
+		if err != nil {
+			warnf("mount destination %q is not an absolute pathname, skipping", m.Destination)
+			continue
+		}
+
+		mountType := m.Type
+		if mountType == "" || mountType == "none" {
+			mountType = "bind"
+		}
+		if slices.Contains(ociProvided, mountType) {
+			warnf("%s mount on %q is provided by hakurei, skipping", mountType, m.Destination)
+			continue
+		}
+		if slices.Contains(ociProvidedTarget, path.Clean(m.Destination)) {
+			warnf("%s mount on %q covers a mount point provided by hakurei, skipping", mountType, m.Destination)
+			continue
+		}
+
+		var fs FilesystemConfig
+		switch mountType {
+		case "bind":
+			b := &FSBind{Target: target, Source: resolve(m.Source), Write: true}
+			if slices.Contains(m.Options, "bind") && !slices.Contains(m.Options, "rbind") {
+				b.Recursive = new(bool)
+			}
+			for _, opt := range m.Options {
+				switch opt {
+				case "ro":
+					b.Write = false
+				case "bind", "rbind", "rw", "private", "rprivate", "slave", "rslave", "nosuid":
+					// bind mounts are always private and nosuid
+				case "dev":
+					b.Device = true
+				case "nodev":
+					b.Device = false
+				default:
+					warnf("unsupported bind mount option %q on %q", opt, m.Destination)
+				}
+			}
+			fs = b
+
+		case "tmpfs":
+			e := &FSEphemeral{Target: target, Write: true}
+			for _, opt := range m.Options {
+				key, value, _ := strings.Cut(opt, "=")
+				switch key {
+				case "ro":
+					e.Write = false
+				case "rw", "nosuid", "nodev", "noexec", "strictatime", "relatime", "noatime":
+				case "size":
+					if size, ok := ociParseSize(value); ok {
+						e.Size = size
+					} else {
+						warnf("invalid tmpfs size %q on %q", value, m.Destination)
+					}
+				case "mode":
+					if perm, err := strconv.ParseUint(value, 8, 32); err == nil && perm&^uint64(os.ModePerm) == 0 {
+						e.Perm = os.FileMode(perm)
+					} else {
+						warnf("invalid tmpfs mode %q on %q", value, m.Destination)
+					}
+				default:
+					warnf("unsupported tmpfs option %q on %q", opt, m.Destination)
+				}
+			}
+			fs = e
+
+		default:
+			warnf("unsupported mount type %q on %q, skipping", m.Type, m.Destination)
+			continue
+		}
+		config.Container.Filesystem = append(config.Container.Filesystem, FilesystemConfigJSON{FilesystemConfig: fs})
+	}
+
+	if err = config.Validate(); err != nil {
+		return nil, warnings, err
+	}
+	return config, warnings, nil
+}
+
+// ociParseSize parses a tmpfs size option with an optional binary unit suffix.
+func ociParseSize(s string) (int, bool) {
+	shift := 0
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k', 'K':
+			shift = 10
+		case 'm', 'M':
+			shift = 20
+		case 'g', 'G':
+			shift = 30
+		}
+	}
+	if shift != 0 {
+		s = s[:len(s)-1]
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 || v > (1<<62)>>shift {
+		return 0, false
+	}
+	return v << shift, true
+}
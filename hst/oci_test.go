@@ -0,0 +1,157 @@
+package hst_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"hakurei.app/container/check"
+	"hakurei.app/container/fhs"
+	"hakurei.app/hst"
+)
+
+func TestImportOCI(t *testing.T) {
+	t.Parallel()
+
+	newBase := func() *hst.Config {
+		return &hst.Config{
+			Identity: 9,
+			Container: &hst.ContainerConfig{
+				Env: map[string]string{"LANG": "C.UTF-8", "TERM": "xterm"},
+				Filesystem: []hst.FilesystemConfigJSON{
+					{FilesystemConfig: &hst.FSBind{Target: fhs.AbsRoot, Source: check.MustAbs("/var/lib/hakurei/base/org.chromium.Chromium"), Special: true}},
+					{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/nix/store")}},
+				},
+				Shell: check.MustAbs("/bin/sh"),
+				Home:  check.MustAbs("/data/data/org.chromium.Chromium"),
+				Path:  check.MustAbs("/bin/sh"),
+			},
+		}
+	}
+	bundle := check.MustAbs("/var/lib/oci/bundle")
+
+	testCases := []struct {
+		name   string
+		base   *hst.Config
+		bundle *check.Absolute
+		data   string
+
+		want         func() *hst.Config
+		wantWarnings []string
+		wantErr      error
+	}{
+		{"nil base", nil, bundle, `{}`, nil, nil,
+			&hst.AppError{Step: "import OCI configuration", Err: hst.ErrConfigNull, Msg: "invalid base configuration"}},
+		{"nil bundle", newBase(), nil, `{}`, nil, nil,
+			&hst.AppError{Step: "import OCI configuration", Err: hst.ErrConfigNull, Msg: "invalid base configuration"}},
+
+		{"validate", newBase(), bundle, `{"process": {"env": ["\u0000=invalid"]}}`, nil, nil,
+			&hst.AppError{Step: "validate configuration", Err: hst.ErrEnviron, Msg: `invalid environment variable "\x00"`}},
+
+		{"empty", newBase(), bundle, `{}`, newBase, nil, nil},
+
+		{"full", newBase(), bundle, `{
+	"ociVersion": "1.2.0",
+	"process": {
+		"terminal": true,
+		"cwd": "/srv",
+		"env": ["PATH=/usr/local/bin:/usr/bin:/bin", "TERM=dumb", "invalid"],
+		"args": ["/usr/bin/nginx", "-g", "daemon off;"]
+	},
+	"root": {"path": "rootfs", "readonly": true},
+	"hostname": "nginx",
+	"mounts": [
+		{"destination": "/proc", "type": "proc", "source": "proc"},
+		{"destination": "/dev/pts", "type": "devpts", "source": "devpts", "options": ["nosuid", "noexec"]},
+		{"destination": "/dev", "type": "tmpfs", "source": "tmpfs", "options": ["nosuid", "mode=755"]},
+		{"destination": "/dev/shm", "source": "/dev/shm", "options": ["bind"]},
+		{"destination": "/sys/fs/cgroup/", "type": "bind", "source": "/sys/fs/cgroup"},
+		{"destination": "/run", "type": "tmpfs", "source": "tmpfs", "options": ["nosuid", "strictatime", "mode=755", "size=65536k"]},
+		{"destination": "/tmp", "type": "tmpfs", "options": ["size=invalid", "mode=18", "uid=0"]},
+		{"destination": "/srv", "type": "bind", "source": "data", "options": ["rbind", "ro", "noexec"]},
+		{"destination": "/var/cache/nginx", "source": "/var/cache/nginx", "options": ["bind", "rw"]},
+		{"destination": "relative", "type": "bind", "source": "/"},
+		{"destination": "/sys/fs/fuse", "type": "fusectl", "source": "fusectl"}
+	]
+}`, func() *hst.Config {
+			c := newBase()
+			c.Container.Hostname = "nginx"
+			c.Container.Env = map[string]string{
+				"LANG": "C.UTF-8",
+				"TERM": "dumb",
+				"PATH": "/usr/local/bin:/usr/bin:/bin",
+			}
+			c.Container.Path = check.MustAbs("/usr/bin/nginx")
+			c.Container.Args = []string{"/usr/bin/nginx", "-g", "daemon off;"}
+			c.Container.Filesystem = []hst.FilesystemConfigJSON{
+				{FilesystemConfig: &hst.FSBind{Target: fhs.AbsRoot, Source: check.MustAbs("/var/lib/oci/bundle/rootfs")}},
+				{FilesystemConfig: &hst.FSBind{Source: check.MustAbs("/nix/store")}},
+				{FilesystemConfig: &hst.FSEphemeral{Target: check.MustAbs("/run"), Write: true, Size: 65536 << 10, Perm: 0755}},
+				{FilesystemConfig: &hst.FSEphemeral{Target: check.MustAbs("/tmp"), Write: true}},
+				{FilesystemConfig: &hst.FSBind{Target: check.MustAbs("/srv"), Source: check.MustAbs("/var/lib/oci/bundle/data")}},
+				{FilesystemConfig: &hst.FSBind{Target: check.MustAbs("/var/cache/nginx"), Source: check.MustAbs("/var/cache/nginx"), Write: true, Recursive: new(bool)}},
+			}
+			return c
+		}, []string{
+			"process.terminal is not supported, terminal access is configured via the tty flag",
+			`process.cwd "/srv" is not supported, the home directory is entered instead`,
+			`process.env entry "invalid" is not in the form key=value, skipping`,
+			"root filesystem replaces configured mount point on /",
+			`proc mount on "/proc" is provided by hakurei, skipping`,
+			`devpts mount on "/dev/pts" is provided by hakurei, skipping`,
+			`tmpfs mount on "/dev" covers a mount point provided by hakurei, skipping`,
+			`bind mount on "/dev/shm" covers a mount point provided by hakurei, skipping`,
+			`bind mount on "/sys/fs/cgroup/" covers a mount point provided by hakurei, skipping`,
+			`invalid tmpfs size "invalid" on "/tmp"`,
+			`invalid tmpfs mode "18" on "/tmp"`,
+			`unsupported tmpfs option "uid=0" on "/tmp"`,
+			`unsupported bind mount option "noexec" on "/srv"`,
+			`mount destination "relative" is not an absolute pathname, skipping`,
+			`unsupported mount type "fusectl" on "/sys/fs/fuse", skipping`,
+		}, nil},
+
+		{"relative args", newBase(), bundle, `{"process": {"args": ["nginx"]}}`, func() *hst.Config {
+			c := newBase()
+			c.Container.Args = []string{"nginx"}
+			return c
+		}, []string{`process.args executable "nginx" is not an absolute pathname, retaining configured path`}, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var before *hst.Config
+			if tc.base != nil {
+				before = newBase()
+			}
+
+			got, warnings, err := hst.ImportOCI(tc.base, tc.bundle, strings.NewReader(tc.data))
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("ImportOCI: error = %#v, want %#v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(warnings, tc.wantWarnings) {
+				t.Errorf("ImportOCI: warnings = %#v, want %#v", warnings, tc.wantWarnings)
+			}
+			if tc.want != nil {
+				if want := tc.want(); !reflect.DeepEqual(got, want) {
+					t.Errorf("ImportOCI: %#v, want %#v", got.Container, want.Container)
+				}
+			}
+			if tc.base != nil && !reflect.DeepEqual(tc.base, before) {
+				t.Errorf("ImportOCI: clobbered base %#v", tc.base.Container)
+			}
+		})
+	}
+
+	t.Run("decode", func(t *testing.T) {
+		t.Parallel()
+		var appError *hst.AppError
+		if _, _, err := hst.ImportOCI(newBase(), bundle, strings.NewReader(`{"mounts": {}}`)); !errors.As(err, &appError) {
+			t.Fatalf("ImportOCI: error = %v", err)
+		} else if appError.Step != "decode OCI configuration" || !errors.As(err, new(*json.UnmarshalTypeError)) {
+			t.Errorf("ImportOCI: error = %#v", appError)
+		}
+	})
+}
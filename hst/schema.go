@@ -0,0 +1,169 @@
+package hst
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"hakurei.app/container/check"
+	"hakurei.app/container/std"
+)
+
+// SchemaDialect is the JSON Schema dialect of the document returned by [ConfigSchema].
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaNode is a JSON Schema object. Map keys are marshalled in sorted order.
+type schemaNode = map[string]any
+
+/*
+ConfigSchema returns a JSON Schema document describing the [json] representation of [Config].
+
+The document is generated from the struct tags of [Config] and the types it refers to, and
+includes constraints checked by [Config.Validate] that are expressible in JSON Schema. Values
+accepted by this schema may still be rejected by [Config.Validate], for example a cgroup cpuset
+naming CPUs not online on the host.
+*/
+func ConfigSchema() []byte {
+	root := schemaFor(reflect.TypeFor[Config]())
+	root["$schema"] = SchemaDialect
+	root["title"] = "hakurei application configuration"
+	root["required"] = []string{"container"}
+
+	props := root["properties"].(schemaNode)
+	props["identity"].(schemaNode)["minimum"] = IdentityStart
+	props["identity"].(schemaNode)["maximum"] = IdentityEnd
+
+	container := props["container"].(schemaNode)
+	container["required"] = []string{"home", "path", "shell"}
+	containerProps := container["properties"].(schemaNode)
+	containerProps["env"].(schemaNode)["propertyNames"] = schemaNode{"pattern": "^[^=\\u0000]+$"}
+	containerProps["env_scrub"].(schemaNode)["items"] = schemaNode{"type": "string", "pattern": "^[^=\\u0000]+$"}
+	containerProps["seccomp_action"].(schemaNode)["enum"] = []string{"",
+		SeccompActionENOSYS, SeccompActionTrap, SeccompActionKillThread, SeccompActionKillProcess}
+	containerProps["deny_socket_families"].(schemaNode)["items"] = schemaNode{
+		"enum": std.SocketFamilyNames()}
+	containerProps["input_devices"].(schemaNode)["items"] = schemaNode{
+		"type": "string", "pattern": "^" + InputDevicePrefix}
+	containerProps["devices"].(schemaNode)["items"] = schemaNode{
+		"type": "string", "pattern": "^" + DevicePrefix}
+	containerProps["runtime_sockets"].(schemaNode)["items"] = schemaNode{
+		"type": "string", "pattern": "^[^/\\u0000]+$"}
+
+	cgroupProps := containerProps["cgroup"].(schemaNode)["properties"].(schemaNode)
+	cgroupProps["limit_pids"].(schemaNode)["minimum"] = 0
+	cgroupProps["cpu_weight"].(schemaNode)["maximum"] = CgroupCPUWeightMax
+	cgroupProps["limit_io"].(schemaNode)["propertyNames"] = schemaNode{"pattern": "^[0-9]+:[0-9]+$"}
+	cgroupProps["cpuset"].(schemaNode)["pattern"] = "^[0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*$"
+
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil { // not reached
+		panic(err.Error())
+	}
+	return append(data, '\n')
+}
+
+var (
+	typeAbsolute   = reflect.TypeFor[check.Absolute]()
+	typeDuration   = reflect.TypeFor[time.Duration]()
+	typeContainer  = reflect.TypeFor[ContainerConfig]()
+	typeEnablement = reflect.TypeFor[Enablements]()
+	typeFilesystem = reflect.TypeFor[FilesystemConfigJSON]()
+	typeMarshaler  = reflect.TypeFor[json.Marshaler]()
+)
+
+// schemaFor returns the JSON Schema of the [json] representation of t.
+func schemaFor(t reflect.Type) schemaNode {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case typeAbsolute:
+		return schemaNode{"type": "string", "pattern": "^/"}
+	case typeDuration:
+		return schemaNode{"type": "integer"}
+	case typeContainer:
+		return schemaStruct(reflect.TypeFor[containerConfigJSON]())
+	case typeEnablement:
+		return schemaStruct(reflect.TypeFor[enablementsJSON]())
+	case typeFilesystem:
+		return schemaFilesystem()
+	}
+	if reflect.PointerTo(t).Implements(typeMarshaler) {
+		// catches representations diverging from struct tags
+		panic("schema of " + t.String() + " not implemented")
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return schemaNode{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return schemaNode{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schemaNode{"type": "integer", "minimum": 0}
+	case reflect.String:
+		return schemaNode{"type": "string"}
+	case reflect.Slice:
+		return schemaNode{"type": []string{"array", "null"}, "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return schemaNode{"type": []string{"object", "null"}, "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Struct:
+		return schemaStruct(t)
+	default:
+		panic("schema of " + t.String() + " not implemented")
+	}
+}
+
+// schemaStruct returns the JSON Schema of a struct type, flattening embedded structs.
+func schemaStruct(t reflect.Type) schemaNode {
+	props := make(schemaNode)
+	schemaFields(t, props)
+	return schemaNode{"type": "object", "properties": props, "additionalProperties": false}
+}
+
+// schemaFields populates props with fields of struct type t.
+func schemaFields(t reflect.Type, props schemaNode) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			schemaFields(f.Type, props)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = schemaFor(f.Type)
+	}
+}
+
+// schemaFilesystem returns the JSON Schema of [FilesystemConfigJSON].
+func schemaFilesystem() schemaNode {
+	variants := make([]schemaNode, 0, 4)
+	for _, v := range [...]struct {
+		name string
+		t    reflect.Type
+	}{
+		{FilesystemBind, reflect.TypeFor[FSBind]()},
+		{FilesystemEphemeral, reflect.TypeFor[FSEphemeral]()},
+		{FilesystemOverlay, reflect.TypeFor[FSOverlay]()},
+		{FilesystemLink, reflect.TypeFor[FSLink]()},
+	} {
+		s := schemaStruct(v.t)
+		s["properties"].(schemaNode)["type"] = schemaNode{"const": v.name}
+		s["required"] = []string{"type"}
+		variants = append(variants, s)
+	}
+	return schemaNode{"oneOf": variants}
+}
@@ -0,0 +1,50 @@
+package hst_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"hakurei.app/hst"
+)
+
+func TestConfigSchema(t *testing.T) {
+	t.Parallel()
+
+	got := hst.ConfigSchema()
+	want, err := os.ReadFile("testdata/config.schema.json")
+	if err != nil {
+		t.Fatalf("ReadFile: error = %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("ConfigSchema: does not match testdata/config.schema.json, regenerate it:\n%s", got)
+	}
+
+	var schema struct {
+		Schema     string `json:"$schema"`
+		Properties struct {
+			Container struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"container"`
+		} `json:"properties"`
+	}
+	if err = json.Unmarshal(got, &schema); err != nil {
+		t.Fatalf("Unmarshal: error = %v", err)
+	}
+	if schema.Schema != hst.SchemaDialect {
+		t.Errorf("ConfigSchema: $schema = %q, want %q", schema.Schema, hst.SchemaDialect)
+	}
+
+	for _, name := range []string{
+		"seccomp_compat", "devel", "userns", "host_net", "host_abstract", "tty",
+		"multiarch", "map_real_uid", "device", "share_runtime", "share_tmpdir", "gpu_config",
+		"env_strict", "machine_id", "read_only_root", "network_files",
+	} {
+		if p, ok := schema.Properties.Container.Properties[name]; !ok {
+			t.Errorf("ConfigSchema: flag %q missing", name)
+		} else if string(bytes.Join(bytes.Fields(p), nil)) != `{"type":"boolean"}` {
+			t.Errorf("ConfigSchema: flag %q = %s", name, p)
+		}
+	}
+}
@@ -16,8 +16,37 @@ const (
 	Execute Perm = C.ACL_EXECUTE
 )
 
-// Update replaces ACL_USER entry with qualifier uid.
-func Update(name string, uid int, perms ...Perm) error {
+// Mask holds permissions of the ACL_MASK entry of a file as a bitwise OR of [Perm].
+type Mask int
+
+// MaskNone denotes the absence of an ACL_MASK entry.
+const MaskNone Mask = C.HAKUREI_ACL_MASK_NONE
+
+/*
+Update replaces ACL_USER entry with qualifier uid and returns the prior ACL_MASK entry.
+
+The ACL_MASK entry clamps effective permissions of ACL_USER entries, so it is extended
+to include perms. This only adds granted permissions to the mask, so permissions of other
+entries masked off beforehand remain masked off. If the file has no ACL_MASK entry, or no
+perms are specified, the mask is recalculated from all entries it applies to.
+*/
+func Update(name string, uid int, perms ...Perm) (Mask, error) {
+	return update(name, uid, C.HAKUREI_ACL_MASK_EXTEND, perms)
+}
+
+/*
+Restore removes ACL_USER entry with qualifier uid and recalculates the ACL_MASK entry from the
+remaining entries. The prior mask returned by [Update] is not written back, as entries of other
+users granted in the meantime would be masked off by it. For [MaskNone], the ACL_MASK entry is
+removed instead if no remaining entry requires one.
+*/
+func Restore(name string, uid int, mask Mask) error {
+	_, err := update(name, uid, C.int(mask), nil)
+	return err
+}
+
+// update calls hakurei_acl_update_file_by_uid and returns the prior mask.
+func update(name string, uid int, mask C.int, perms []Perm) (Mask, error) {
 	var p *Perm
 	if len(perms) > 0 {
 		p = &perms[0]
@@ -28,6 +57,7 @@ func Update(name string, uid int, perms ...Perm) error {
 		C.uid_t(uid),
 		(*C.acl_perm_t)(p),
 		C.size_t(len(perms)),
+		&mask,
 	)
-	return newAclPathError(name, int(r), err)
+	return Mask(mask), newAclPathError(name, int(r), err)
 }
@@ -53,7 +53,7 @@ func TestUpdate(t *testing.T) {
 	})
 
 	t.Run("default clear mask", func(t *testing.T) {
-		if err := acl.Update(testFilePath, uid); err != nil {
+		if _, err := acl.Update(testFilePath, uid); err != nil {
 			t.Fatalf("Update: error = %v", err)
 		}
 		if cur = getfacl(t, testFilePath); len(cur) != 4 {
@@ -62,7 +62,7 @@ func TestUpdate(t *testing.T) {
 	})
 
 	t.Run("default clear consistency", func(t *testing.T) {
-		if err := acl.Update(testFilePath, uid); err != nil {
+		if _, err := acl.Update(testFilePath, uid); err != nil {
 			t.Fatalf("Update: error = %v", err)
 		}
 		if val := getfacl(t, testFilePath); !reflect.DeepEqual(val, cur) {
@@ -79,10 +79,125 @@ func TestUpdate(t *testing.T) {
 	testUpdate(t, testFilePath, "rwx", cur, fAclPermRead|fAclPermWrite|fAclPermExecute, acl.Read, acl.Write, acl.Execute)
 }
 
+func TestUpdateMask(t *testing.T) {
+	if os.Getenv("GO_TEST_SKIP_ACL") == "1" {
+		t.Log("acl test skipped")
+		t.SkipNow()
+	}
+
+	testFilePath := path.Join(t.TempDir(), testFileName)
+	if err := os.WriteFile(testFilePath, nil, 0600); err != nil {
+		t.Fatalf("WriteFile: error = %v", err)
+	}
+	// no group permissions, so a recalculated mask would not include execute
+	if err := os.Chmod(testFilePath, 0600); err != nil {
+		t.Fatalf("Chmod: error = %v", err)
+	}
+	orig := getfacl(t, testFilePath)
+
+	t.Run("minimal", func(t *testing.T) {
+		mask, err := acl.Update(testFilePath, uid, acl.Execute)
+		if err != nil {
+			t.Fatalf("Update: error = %v", err)
+		}
+		if mask != acl.MaskNone {
+			t.Errorf("Update: mask = %d, want %d", mask, acl.MaskNone)
+		}
+		if r := respByCred(getfacl(t, testFilePath), fAclTypeMask, -1); r == nil || !r.equals(fAclTypeMask, -1, fAclPermExecute) {
+			t.Fatalf("Update: mask entry %v", r)
+		}
+
+		if err = acl.Restore(testFilePath, uid, mask); err != nil {
+			t.Fatalf("Restore: error = %v", err)
+		}
+		if v := getfacl(t, testFilePath); !reflect.DeepEqual(v, orig) {
+			t.Fatalf("Restore: %v, want %v", v, orig)
+		}
+	})
+
+	t.Run("masked", func(t *testing.T) {
+		// entry of another user masked off entirely
+		if _, err := acl.Update(testFilePath, uid+1, acl.Read, acl.Write, acl.Execute); err != nil {
+			t.Fatalf("Update: error = %v", err)
+		}
+		if err := os.Chmod(testFilePath, 0600); err != nil {
+			t.Fatalf("Chmod: error = %v", err)
+		}
+		t.Cleanup(func() {
+			if err := acl.Restore(testFilePath, uid+1, acl.MaskNone); err != nil {
+				t.Fatalf("Restore: error = %v", err)
+			}
+			if v := getfacl(t, testFilePath); !reflect.DeepEqual(v, orig) {
+				t.Fatalf("Restore: %v, want %v", v, orig)
+			}
+		})
+
+		mask, err := acl.Update(testFilePath, uid, acl.Execute)
+		if err != nil {
+			t.Fatalf("Update: error = %v", err)
+		}
+		if mask != 0 {
+			t.Errorf("Update: mask = %d, want 0", mask)
+		}
+		// granted execute is effective without unmasking the other entry
+		if r := respByCred(getfacl(t, testFilePath), fAclTypeMask, -1); r == nil || !r.equals(fAclTypeMask, -1, fAclPermExecute) {
+			t.Fatalf("Update: mask entry %v", r)
+		}
+
+		if err = acl.Restore(testFilePath, uid, mask); err != nil {
+			t.Fatalf("Restore: error = %v", err)
+		}
+		// the mask is recalculated from the remaining entry
+		if r := respByCred(getfacl(t, testFilePath), fAclTypeMask, -1); r == nil || !r.equals(fAclTypeMask, -1, fAclPermRead|fAclPermWrite|fAclPermExecute) {
+			t.Fatalf("Restore: mask entry %v", r)
+		}
+		if r := respByCred(getfacl(t, testFilePath), fAclTypeUser, int32(uid)); r != nil {
+			t.Fatalf("Restore: entry %v", r)
+		}
+	})
+
+	t.Run("granted in the meantime", func(t *testing.T) {
+		for _, id := range []int{uid + 2, uid + 1} {
+			t.Cleanup(func() {
+				if err := acl.Restore(testFilePath, id, acl.MaskNone); err != nil {
+					t.Fatalf("Restore: error = %v", err)
+				}
+			})
+		}
+		t.Cleanup(func() {
+			if v := getfacl(t, testFilePath); !reflect.DeepEqual(v, orig) {
+				t.Fatalf("Restore: %v, want %v", v, orig)
+			}
+		})
+		if _, err := acl.Update(testFilePath, uid+2, acl.Read); err != nil {
+			t.Fatalf("Update: error = %v", err)
+		}
+
+		mask, err := acl.Update(testFilePath, uid, acl.Read)
+		if err != nil {
+			t.Fatalf("Update: error = %v", err)
+		}
+		if mask != acl.Mask(acl.Read) {
+			t.Errorf("Update: mask = %d, want %d", mask, acl.Read)
+		}
+		if _, err = acl.Update(testFilePath, uid+1, acl.Read, acl.Write); err != nil {
+			t.Fatalf("Update: error = %v", err)
+		}
+
+		// writing back the stale mask would mask off write granted to uid+1
+		if err = acl.Restore(testFilePath, uid, mask); err != nil {
+			t.Fatalf("Restore: error = %v", err)
+		}
+		if r := respByCred(getfacl(t, testFilePath), fAclTypeMask, -1); r == nil || !r.equals(fAclTypeMask, -1, fAclPermRead|fAclPermWrite) {
+			t.Fatalf("Restore: mask entry %v", r)
+		}
+	})
+}
+
 func testUpdate(t *testing.T, testFilePath, name string, cur []*getFAclResp, val fAclPerm, perms ...acl.Perm) {
 	t.Run(name, func(t *testing.T) {
 		t.Cleanup(func() {
-			if err := acl.Update(testFilePath, uid); err != nil {
+			if _, err := acl.Update(testFilePath, uid); err != nil {
 				t.Fatalf("Update: error = %v", err)
 			}
 			if v := getfacl(t, testFilePath); !reflect.DeepEqual(v, cur) {
@@ -90,7 +205,7 @@ func testUpdate(t *testing.T, testFilePath, name string, cur []*getFAclResp, val
 			}
 		})
 
-		if err := acl.Update(testFilePath, uid, perms...); err != nil {
+		if _, err := acl.Update(testFilePath, uid, perms...); err != nil {
 			t.Fatalf("Update: error = %v", err)
 		}
 		r := respByCred(getfacl(t, testFilePath), fAclTypeUser, cred)
@@ -163,7 +278,9 @@ func (c *getFAclInvocation) parse(pipe io.Reader, scanErr chan error) {
 
 	s := bufio.NewScanner(pipe)
 	for s.Scan() {
-		fields := bytes.SplitN(s.Bytes(), []byte{':'}, 3)
+		// effective permissions of masked entries are appended as a comment
+		line, _, _ := bytes.Cut(s.Bytes(), []byte{'\t'})
+		fields := bytes.SplitN(line, []byte{':'}, 3)
 		if len(fields) != 3 {
 			continue
 		}
@@ -229,8 +346,8 @@ func (c *getFAclInvocation) parse(pipe io.Reader, scanErr chan error) {
 			}
 		}
 
-		resp.raw = make([]byte, len(s.Bytes()))
-		copy(resp.raw, s.Bytes())
+		resp.raw = make([]byte, len(line))
+		copy(resp.raw, line)
 		c.val = append(c.val, &resp)
 	}
 	scanErr <- s.Err()
@@ -4,28 +4,105 @@
 #include <stdlib.h>
 #include <sys/acl.h>
 
+static const acl_perm_t hakurei_acl_perms[] = {ACL_READ, ACL_WRITE,
+                                               ACL_EXECUTE};
+
+/* returns the ACL_MASK entry of acl, or NULL if it has none */
+static int hakurei_acl_get_mask(acl_t acl, acl_entry_t *entry_p) {
+    int i;
+    acl_tag_t tag_type;
+
+    *entry_p = NULL;
+    for (i = acl_get_entry(acl, ACL_FIRST_ENTRY, entry_p); i == 1;
+         i = acl_get_entry(acl, ACL_NEXT_ENTRY, entry_p)) {
+        if (acl_get_tag_type(*entry_p, &tag_type) != 0)
+            return -2; /* acl_get_tag_type */
+        if (tag_type == ACL_MASK)
+            return 0;
+    }
+    *entry_p = NULL;
+    return 0;
+}
+
+/* replaces perms of the ACL_MASK entry of acl with mask */
+static int hakurei_acl_set_mask(acl_t acl, int mask) {
+    int ret;
+    size_t i;
+    acl_entry_t entry;
+    acl_permset_t permset;
+
+    ret = hakurei_acl_get_mask(acl, &entry);
+    if (ret != 0)
+        return ret;
+    if (entry == NULL) /* unreachable: ensured by acl_calc_mask */
+        return -10;    /* acl_calc_mask */
+
+    if (acl_get_permset(entry, &permset) != 0)
+        return -6; /* acl_get_permset */
+    if (acl_clear_perms(permset) != 0)
+        return -13; /* acl_clear_perms */
+    for (i = 0; i < sizeof(hakurei_acl_perms) / sizeof(acl_perm_t); i++) {
+        if ((mask & hakurei_acl_perms[i]) == 0)
+            continue;
+        if (acl_add_perm(permset, hakurei_acl_perms[i]) != 0)
+            return -7; /* acl_add_perm */
+    }
+    return 0;
+}
+
 int hakurei_acl_update_file_by_uid(const char *path_p, uid_t uid,
-                                   acl_perm_t *perms, size_t plen) {
+                                   acl_perm_t *perms, size_t plen, int *mask_p) {
     int ret;
     bool v;
+    bool named;
     int i;
+    size_t j;
+    int mask;
+    int granted;
     acl_t acl;
     acl_entry_t entry;
     acl_tag_t tag_type;
     void *qualifier_p;
     acl_permset_t permset;
 
+    named = false;
+    mask = HAKUREI_ACL_MASK_NONE;
+
     ret = -1; /* acl_get_file */
     acl = acl_get_file(path_p, ACL_TYPE_ACCESS);
     if (acl == NULL)
         goto out;
 
-    /* prune entries by uid */
+    /* prune entries by uid, saving the prior mask */
     for (i = acl_get_entry(acl, ACL_FIRST_ENTRY, &entry); i == 1;
          i = acl_get_entry(acl, ACL_NEXT_ENTRY, &entry)) {
         ret = -2; /* acl_get_tag_type */
         if (acl_get_tag_type(entry, &tag_type) != 0)
             goto out;
+
+        if (tag_type == ACL_MASK) {
+            ret = -6; /* acl_get_permset */
+            if (acl_get_permset(entry, &permset) != 0)
+                goto out;
+
+            mask = 0;
+            for (j = 0; j < sizeof(hakurei_acl_perms) / sizeof(acl_perm_t);
+                 j++) {
+                ret = -14; /* acl_get_perm */
+                switch (acl_get_perm(permset, hakurei_acl_perms[j])) {
+                case 1:
+                    mask |= hakurei_acl_perms[j];
+                    break;
+                case 0:
+                    break;
+                default:
+                    goto out;
+                }
+            }
+            continue;
+        }
+        if (tag_type == ACL_GROUP)
+            named = true;
         if (tag_type != ACL_USER)
             continue;
 
@@ -36,8 +113,10 @@ int hakurei_acl_update_file_by_uid(const char *path_p, uid_t uid,
         v = *(uid_t *)qualifier_p == uid;
         acl_free(qualifier_p);
 
-        if (!v)
+        if (!v) {
+            named = true;
             continue;
+        }
 
         ret = -4; /* acl_delete_entry */
         if (acl_delete_entry(acl, entry) != 0)
@@ -46,6 +125,7 @@ int hakurei_acl_update_file_by_uid(const char *path_p, uid_t uid,
 
     if (plen == 0)
         goto set;
+    named = true;
 
     ret = -5; /* acl_create_entry */
     if (acl_create_entry(&acl, &entry) != 0)
@@ -70,17 +150,43 @@ int hakurei_acl_update_file_by_uid(const char *path_p, uid_t uid,
         goto out;
 
 set:
-    ret = -10; /* acl_calc_mask */
-    if (acl_calc_mask(&acl) != 0)
-        goto out;
+    if (*mask_p == HAKUREI_ACL_MASK_NONE && !named) {
+        /* prior ACL had no mask entry and no entry requiring one remains */
+        ret = hakurei_acl_get_mask(acl, &entry);
+        if (ret != 0)
+            goto out;
+        ret = -4; /* acl_delete_entry */
+        if (entry != NULL && acl_delete_entry(acl, entry) != 0)
+            goto out;
+    } else {
+        ret = -10; /* acl_calc_mask */
+        if (acl_calc_mask(&acl) != 0)
+            goto out;
+
+        /* on revert the calculated mask is kept: restoring the prior mask
+         * masks off entries of other users granted in the meantime */
+        if (*mask_p == HAKUREI_ACL_MASK_EXTEND && plen > 0 &&
+            mask != HAKUREI_ACL_MASK_NONE) {
+            /* only add granted perms to the prior mask, as the calculated
+             * mask widens effective perms of entries masked off before */
+            granted = mask;
+            for (j = 0; j < plen; j++)
+                granted |= perms[j];
+            ret = hakurei_acl_set_mask(acl, granted);
+            if (ret != 0)
+                goto out;
+        }
+    }
 
     ret = -11; /* acl_valid */
     if (acl_valid(acl) != 0)
         goto out;
 
     ret = -12; /* acl_set_file */
-    if (acl_set_file(path_p, ACL_TYPE_ACCESS, acl) == 0)
+    if (acl_set_file(path_p, ACL_TYPE_ACCESS, acl) == 0) {
+        *mask_p = mask;
         ret = 0;
+    }
 
 out:
     free((void *)path_p);
@@ -32,6 +32,10 @@ func newAclPathError(name string, r int, err error) error {
 		pathError.Op = "acl_valid"
 	case -12:
 		pathError.Op = "acl_set_file"
+	case -13:
+		pathError.Op = "acl_clear_perms"
+	case -14:
+		pathError.Op = "acl_get_perm"
 
 	default: // unreachable
 		pathError.Op = "setfacl"
@@ -1,4 +1,9 @@
 #include <sys/acl.h>
 
+/* no ACL_MASK entry, or remove the ACL_MASK entry if no longer required */
+#define HAKUREI_ACL_MASK_NONE (-1)
+/* extend the ACL_MASK entry by granted perms, or recalculate it */
+#define HAKUREI_ACL_MASK_EXTEND (-2)
+
 int hakurei_acl_update_file_by_uid(const char *path_p, uid_t uid,
-                                   acl_perm_t *perms, size_t plen);
+                                   acl_perm_t *perms, size_t plen, int *mask_p);
@@ -43,8 +43,12 @@ func TestNewAclPathError(t *testing.T) {
 			&os.PathError{Op: "acl_valid", Path: container.Nonexistent, Err: syscall.ENOTRECOVERABLE}},
 		{"acl_set_file", container.Nonexistent, -12, syscall.ENOTRECOVERABLE,
 			&os.PathError{Op: "acl_set_file", Path: container.Nonexistent, Err: syscall.ENOTRECOVERABLE}},
+		{"acl_clear_perms", container.Nonexistent, -13, syscall.ENOTRECOVERABLE,
+			&os.PathError{Op: "acl_clear_perms", Path: container.Nonexistent, Err: syscall.ENOTRECOVERABLE}},
+		{"acl_get_perm", container.Nonexistent, -14, syscall.ENOTRECOVERABLE,
+			&os.PathError{Op: "acl_get_perm", Path: container.Nonexistent, Err: syscall.ENOTRECOVERABLE}},
 
-		{"acl", container.Nonexistent, -13, syscall.ENOTRECOVERABLE,
+		{"acl", container.Nonexistent, -15, syscall.ENOTRECOVERABLE,
 			&os.PathError{Op: "setfacl", Path: container.Nonexistent, Err: syscall.ENOTRECOVERABLE}},
 		{"invalid", container.Nonexistent, -0xdead, nil,
 			&os.PathError{Op: "setfacl", Path: container.Nonexistent}},
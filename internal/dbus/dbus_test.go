@@ -135,6 +135,9 @@ func testProxyFinaliseStartWaitCloseString(t *testing.T, useSandbox bool) {
 				if err := p.Wait(); err == nil || err.Error() != wantErr {
 					t.Errorf("Wait: error = %v, wantErr %v", err, wantErr)
 				}
+				if err := p.Restart(final); err == nil || err.Error() != wantErr {
+					t.Errorf("Restart: error = %v, wantErr %v", err, wantErr)
+				}
 			}
 
 			{ // check string behaviour
@@ -161,10 +164,35 @@ func testProxyFinaliseStartWaitCloseString(t *testing.T, useSandbox bool) {
 				}
 			}
 
+			{ // check invalid restart behaviour
+				if err := p.Restart(nil); !errors.Is(err, syscall.ENOTRECOVERABLE) {
+					t.Errorf("Restart: error = %v, wantErr %v", err, syscall.ENOTRECOVERABLE)
+				}
+
+				moved := *final
+				moved.Session[1] += ".moved"
+				if err := p.Restart(&moved); !errors.Is(err, dbus.ErrRestartSocket) {
+					t.Errorf("Restart: error = %v, wantErr %v", err, dbus.ErrRestartSocket)
+				}
+			}
+
+			// Wait must keep blocking across a restart
+			waitErr := make(chan error, 1)
+			go func() { waitErr <- p.Wait() }()
+
+			if err := p.Restart(final); err != nil {
+				t.Fatalf("Restart: error = %v\noutput: %s", err, output.String())
+			}
+
 			p.Close()
-			if err := p.Wait(); err != nil {
+			if err := <-waitErr; err != nil {
 				t.Errorf("Wait: error = %v\noutput: %s", err, output.String())
 			}
+
+			wantErr := "dbus: already closed"
+			if err := p.Restart(final); err == nil || err.Error() != wantErr {
+				t.Errorf("Restart: error = %v, wantErr %v", err, wantErr)
+			}
 		})
 	}
 }
@@ -22,15 +22,18 @@ func (p *Proxy) Start() error {
 		return syscall.ENOTRECOVERABLE
 	}
 
-	p.mu.Lock()
-	defer p.mu.Unlock()
 	p.pmu.Lock()
 	defer p.pmu.Unlock()
 
 	if p.cancel != nil || p.cause != nil {
 		return errors.New("dbus: already started")
 	}
+	return p.start()
+}
 
+// start starts xdg-dbus-proxy with the configuration held by final.
+// The caller must hold pmu.
+func (p *Proxy) start() error {
 	ctx, cancel := context.WithCancelCause(p.ctx)
 
 	if !p.useSandbox {
@@ -130,37 +133,116 @@ func (p *Proxy) Start() error {
 	}
 
 	p.cancel, p.cause = cancel, func() error { return context.Cause(ctx) }
+	w := &proxyWaiter{done: make(chan struct{})}
+	go func(h helper.Helper) { w.err = h.Wait(); close(w.done) }(p.helper)
+	p.w = w
 	return nil
 }
 
-var proxyClosed = errors.New("proxy closed")
+var (
+	proxyClosed  = errors.New("proxy closed")
+	proxyRestart = errors.New("proxy restart")
+)
+
+// ErrRestartSocket is returned by [Proxy.Restart] for a [Final] with different proxy socket paths.
+var ErrRestartSocket = errors.New("dbus: restart must not change proxy socket paths")
+
+/*
+Restart terminates xdg-dbus-proxy and starts it again with the filter rules held by final.
+
+The proxy socket paths must remain unchanged. Since the sockets are recreated by the new
+instance, the caller is responsible for exposing their parent directory instead of the
+socket files themselves, and for re-applying any permissions set up on the sockets.
+Connections established through the previous instance are closed as it exits, and
+clients are expected to reconnect to the same address. Between the exit of the previous
+instance and the readiness of the new one, the socket is absent and connection attempts
+fail with ENOENT or ECONNREFUSED, clients retrying for a short while will not notice the
+restart. Restart returns once the new instance is ready to accept connections.
+
+Restart may be called concurrently with [Proxy.Wait], which keeps blocking across the restart.
+An unexpected exit of the previous instance is not treated as an error, as restarting it is
+a reasonable way of recovering.
+*/
+func (p *Proxy) Restart(final *Final) error {
+	if final == nil || final.WriterTo == nil {
+		return syscall.ENOTRECOVERABLE
+	}
+
+	p.pmu.Lock()
+	defer p.pmu.Unlock()
+
+	if p.w == nil || p.cancel == nil || p.cause == nil {
+		return errors.New("dbus: not started")
+	}
+	if final.Session[1] != p.final.Session[1] || final.System[1] != p.final.System[1] {
+		return ErrRestartSocket
+	}
+	if errors.Is(p.cause(), proxyClosed) {
+		return errors.New("dbus: already closed")
+	}
+
+	p.cancel(proxyRestart)
+	<-p.w.done
+	if err := p.w.err; err != nil &&
+		!(errors.Is(err, context.Canceled) && errors.Is(p.cause(), proxyRestart)) {
+		p.msg.Verbosef("message bus proxy exited before restart: %v", err)
+	}
+
+	// xdg-dbus-proxy does not replace an existing socket
+	for _, pathname := range [...]string{p.final.Session[1], p.final.System[1]} {
+		if pathname == "" {
+			continue
+		}
+		if err := os.Remove(pathname); err != nil && !errors.Is(err, os.ErrNotExist) {
+			p.w = &proxyWaiter{done: p.w.done, err: err}
+			return err
+		}
+	}
+
+	p.final = final
+	if err := p.start(); err != nil {
+		// the previous instance is gone, so Wait returns this error
+		p.w = &proxyWaiter{done: p.w.done, err: err}
+		return err
+	}
+	return nil
+}
 
 // Wait blocks until xdg-dbus-proxy exits and releases resources.
 func (p *Proxy) Wait() error {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
 	p.pmu.RLock()
-	if p.helper == nil || p.cancel == nil || p.cause == nil {
+	if p.w == nil || p.cancel == nil || p.cause == nil {
 		p.pmu.RUnlock()
 		return errors.New("dbus: not started")
 	}
 
 	var errs [3]error
+	for {
+		w := p.w
+		p.pmu.RUnlock()
+		<-w.done
 
-	errs[0] = p.helper.Wait()
-	if errors.Is(errs[0], context.Canceled) &&
-		errors.Is(p.cause(), proxyClosed) {
-		errs[0] = nil
+		// a concurrent Restart holds pmu until the replacement is in place
+		p.pmu.RLock()
+		if p.w != w {
+			continue
+		}
+		errs[0] = w.err
+		if errors.Is(errs[0], context.Canceled) &&
+			errors.Is(p.cause(), proxyClosed) {
+			errs[0] = nil
+		}
+		break
 	}
+	session, system := p.final.Session[1], p.final.System[1]
 	p.pmu.RUnlock()
 
 	// ensure socket removal so ephemeral directory is empty at revert
-	if err := os.Remove(p.final.Session[1]); err != nil && !errors.Is(err, os.ErrNotExist) {
+	if err := os.Remove(session); err != nil && !errors.Is(err, os.ErrNotExist) {
 		errs[1] = err
 	}
-	if p.final.System[1] != "" {
-		if err := os.Remove(p.final.System[1]); err != nil && !errors.Is(err, os.ErrNotExist) {
+	if system != "" {
+		if err := os.Remove(system); err != nil && !errors.Is(err, os.ErrNotExist) {
 			errs[2] = err
 		}
 	}
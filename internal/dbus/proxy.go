@@ -23,6 +23,8 @@ type Proxy struct {
 
 	cancel context.CancelCauseFunc
 	cause  func() error
+	// result of the current helper instance, replaced on restart
+	w *proxyWaiter
 
 	final      *Final
 	output     io.Writer
@@ -30,7 +32,13 @@ type Proxy struct {
 
 	name string
 
-	mu, pmu sync.RWMutex
+	pmu sync.RWMutex
+}
+
+// proxyWaiter holds the result of a helper instance once done is closed.
+type proxyWaiter struct {
+	done chan struct{}
+	err  error
 }
 
 func (p *Proxy) String() string {
@@ -38,8 +46,8 @@ func (p *Proxy) String() string {
 		return "(invalid dbus proxy)"
 	}
 
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	p.pmu.RLock()
+	defer p.pmu.RUnlock()
 
 	if p.helper != nil {
 		return p.helper.String()
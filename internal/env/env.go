@@ -16,6 +16,8 @@ type Paths struct {
 	TempDir *check.Absolute
 	// RuntimePath is copied from $XDG_RUNTIME_DIR.
 	RuntimePath *check.Absolute
+	// HomePath is copied from $HOME.
+	HomePath *check.Absolute
 }
 
 // Copy expands [Paths] into [hst.Paths].
@@ -46,7 +48,10 @@ func CopyPathsFunc(
 	tempdir func() string,
 	getenv func(key string) string,
 ) *Paths {
-	const xdgRuntimeDir = "XDG_RUNTIME_DIR"
+	const (
+		xdgRuntimeDir = "XDG_RUNTIME_DIR"
+		home          = "HOME"
+	)
 
 	var env Paths
 
@@ -60,6 +65,9 @@ func CopyPathsFunc(
 	if a, err := check.NewAbs(getenv(xdgRuntimeDir)); err == nil {
 		env.RuntimePath = a
 	}
+	if a, err := check.NewAbs(getenv(home)); err == nil {
+		env.HomePath = a
+	}
 
 	return &env
 }
@@ -85,8 +85,10 @@ func TestCopyPaths(t *testing.T) {
 			"", env.Paths{TempDir: check.MustAbs(container.Nonexistent)}},
 		{"invalid XDG_RUNTIME_DIR", map[string]string{"XDG_RUNTIME_DIR": "\x00"}, container.Nonexistent,
 			"", env.Paths{TempDir: check.MustAbs(container.Nonexistent)}},
-		{"full", map[string]string{"XDG_RUNTIME_DIR": "/\x00"}, container.Nonexistent,
-			"", env.Paths{TempDir: check.MustAbs(container.Nonexistent), RuntimePath: check.MustAbs("/\x00")}},
+		{"invalid HOME", map[string]string{"HOME": "home"}, container.Nonexistent,
+			"", env.Paths{TempDir: check.MustAbs(container.Nonexistent)}},
+		{"full", map[string]string{"XDG_RUNTIME_DIR": "/\x00", "HOME": "/home/user"}, container.Nonexistent,
+			"", env.Paths{TempDir: check.MustAbs(container.Nonexistent), RuntimePath: check.MustAbs("/\x00"), HomePath: check.MustAbs("/home/user")}},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
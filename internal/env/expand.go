@@ -0,0 +1,71 @@
+package env
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrUnterminated is returned by [Expand] for a reference missing its closing brace.
+var ErrUnterminated = errors.New("unterminated variable reference")
+
+// UnresolvedError is returned by [Expand] for a variable not resolved by the mapping function.
+type UnresolvedError string
+
+func (e UnresolvedError) Error() string {
+	return "variable " + strconv.Quote(string(e)) + " is not set"
+}
+
+/*
+Expand replaces references of the form ${NAME} in value with the result of mapping.
+
+The sequence $$ expands to a literal $. A $ not followed by { or $ is kept as-is, so values
+predating expansion remain unchanged unless they contain either sequence. A reference
+for which mapping reports false results in [UnresolvedError].
+*/
+func Expand(value string, mapping func(key string) (string, bool)) (string, error) {
+	i := strings.IndexByte(value, '$')
+	if i == -1 {
+		return value, nil
+	}
+
+	var buf strings.Builder
+	buf.Grow(len(value))
+	for ; i != -1; i = strings.IndexByte(value, '$') {
+		buf.WriteString(value[:i])
+		value = value[i+1:]
+
+		switch {
+		case strings.HasPrefix(value, "$"):
+			buf.WriteByte('$')
+			value = value[1:]
+
+		case strings.HasPrefix(value, "{"):
+			end := strings.IndexByte(value, '}')
+			if end == -1 {
+				return "", ErrUnterminated
+			}
+			key := value[1:end]
+			if v, ok := mapping(key); !ok {
+				return "", UnresolvedError(key)
+			} else {
+				buf.WriteString(v)
+			}
+			value = value[end+1:]
+
+		default:
+			buf.WriteByte('$')
+		}
+	}
+	buf.WriteString(value)
+	return buf.String(), nil
+}
+
+// References returns names of variables referenced in value, in order of appearance.
+func References(value string) (keys []string, err error) {
+	_, err = Expand(value, func(key string) (string, bool) {
+		keys = append(keys, key)
+		return "", true
+	})
+	return
+}
@@ -0,0 +1,74 @@
+package env_test
+
+import (
+	"reflect"
+	"testing"
+
+	"hakurei.app/internal/env"
+)
+
+func TestExpand(t *testing.T) {
+	t.Parallel()
+
+	mapping := func(key string) (string, bool) {
+		switch key {
+		case "HOST_PATH":
+			return "/run/current-system/sw/bin", true
+		case "EMPTY":
+			return "", true
+		default:
+			return "", false
+		}
+	}
+
+	testCases := []struct {
+		name  string
+		value string
+		want  string
+		refs  []string
+		err   error
+	}{
+		{"plain", "/usr/bin", "/usr/bin", nil, nil},
+		{"empty", "", "", nil, nil},
+		{"reference", "${HOST_PATH}:/usr/local/bin", "/run/current-system/sw/bin:/usr/local/bin",
+			[]string{"HOST_PATH"}, nil},
+		{"reference empty", "a${EMPTY}b", "ab", []string{"EMPTY"}, nil},
+		{"multiple", "${HOST_PATH}${EMPTY}${HOST_PATH}", "/run/current-system/sw/bin/run/current-system/sw/bin",
+			[]string{"HOST_PATH", "EMPTY", "HOST_PATH"}, nil},
+		{"escape", "$${HOST_PATH}", "${HOST_PATH}", nil, nil},
+		{"escape trailing", "cost: $$", "cost: $", nil, nil},
+		{"lone", "$HOST_PATH $ $", "$HOST_PATH $ $", nil, nil},
+		{"unterminated", "${HOST_PATH", "", nil, env.ErrUnterminated},
+		{"unresolved", "${NONEXISTENT}:/usr/bin", "", []string{"NONEXISTENT"}, env.UnresolvedError("NONEXISTENT")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, err := env.Expand(tc.value, mapping); !reflect.DeepEqual(err, tc.err) {
+				t.Fatalf("Expand: error = %v, want %v", err, tc.err)
+			} else if got != tc.want {
+				t.Errorf("Expand: %q, want %q", got, tc.want)
+			}
+
+			wantErr := tc.err
+			if _, ok := wantErr.(env.UnresolvedError); ok {
+				wantErr = nil
+			}
+			if refs, err := env.References(tc.value); !reflect.DeepEqual(err, wantErr) {
+				t.Fatalf("References: error = %v, want %v", err, wantErr)
+			} else if wantErr == nil && !reflect.DeepEqual(refs, tc.refs) {
+				t.Errorf("References: %q, want %q", refs, tc.refs)
+			}
+		})
+	}
+}
+
+func TestUnresolvedError(t *testing.T) {
+	t.Parallel()
+
+	const want = `variable "HOST_PATH" is not set`
+	if got := env.UnresolvedError("HOST_PATH").Error(); got != want {
+		t.Errorf("Error: %q, want %q", got, want)
+	}
+}
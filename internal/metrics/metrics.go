@@ -0,0 +1,64 @@
+// Package metrics renders resource usage of a hakurei container in the Prometheus text exposition format.
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"hakurei.app/hst"
+)
+
+// metric describes a single value held by [hst.CgroupStats].
+type metric struct {
+	// Name of the metric without the namespace prefix.
+	name string
+	// Help text emitted in the HELP line.
+	help string
+	// Prometheus metric type emitted in the TYPE line.
+	typ string
+
+	// Returns the value of the metric.
+	value func(stats *hst.CgroupStats) float64
+}
+
+// namespace is prepended to all metric names.
+const namespace = "hakurei_"
+
+// labelEscaper escapes label values as required by the text exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+var metrics = []metric{
+	{"memory_current_bytes", "Total memory currently in use by the container.", "gauge",
+		func(stats *hst.CgroupStats) float64 { return float64(stats.MemoryCurrent) }},
+	{"cpu_usage_seconds_total", "Total CPU time consumed by the container.", "counter",
+		func(stats *hst.CgroupStats) float64 { return stats.CPUUsage.Seconds() }},
+	{"pids_current", "Number of processes currently in the container.", "gauge",
+		func(stats *hst.CgroupStats) float64 { return float64(stats.PidsCurrent) }},
+}
+
+// Write renders resource usage of the container held by stats to w, labelled with identity and id.
+// Statistics are read via [hst.CgroupConfig.ReadStats] or [hst.ReadCgroupStats], which hold the zero
+// value for controller files missing from the cgroup.
+func Write(w io.Writer, stats *hst.CgroupStats, identity string, id *hst.ID) error {
+	if stats == nil {
+		return errors.New("invalid cgroup statistics")
+	}
+	if id == nil {
+		return errors.New("invalid instance id")
+	}
+	labels := fmt.Sprintf(`{identity="%s",instance="%s"}`, labelEscaper.Replace(identity), id.String())
+
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w,
+			"# HELP %[1]s%[2]s %[3]s\n# TYPE %[1]s%[2]s %[4]s\n%[1]s%[2]s%[5]s %[6]s\n",
+			namespace, m.name, m.help, m.typ,
+			labels, strconv.FormatFloat(m.value(stats), 'f', -1, 64),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package metrics_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"hakurei.app/hst"
+	"hakurei.app/internal/metrics"
+)
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	var id hst.ID
+	if err := id.UnmarshalText([]byte("0123456789abcdef0123456789abcdef")); err != nil {
+		t.Fatalf("UnmarshalText: error = %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		stats    *hst.CgroupStats
+		identity string
+		id       *hst.ID
+		want     string
+		wantErr  error
+	}{
+		{"invalid stats", nil, "9", &id, "", errors.New("invalid cgroup statistics")},
+		{"invalid id", new(hst.CgroupStats), "9", nil, "", errors.New("invalid instance id")},
+
+		{"zero", new(hst.CgroupStats), "9", &id, `# HELP hakurei_memory_current_bytes Total memory currently in use by the container.
+# TYPE hakurei_memory_current_bytes gauge
+hakurei_memory_current_bytes{identity="9",instance="0123456789abcdef0123456789abcdef"} 0
+# HELP hakurei_cpu_usage_seconds_total Total CPU time consumed by the container.
+# TYPE hakurei_cpu_usage_seconds_total counter
+hakurei_cpu_usage_seconds_total{identity="9",instance="0123456789abcdef0123456789abcdef"} 0
+# HELP hakurei_pids_current Number of processes currently in the container.
+# TYPE hakurei_pids_current gauge
+hakurei_pids_current{identity="9",instance="0123456789abcdef0123456789abcdef"} 0
+`, nil},
+
+		{"full", &hst.CgroupStats{
+			CPUUsage:      1500 * time.Millisecond,
+			CPUUser:       time.Second,
+			CPUSystem:     500 * time.Millisecond,
+			MemoryCurrent: 1 << 30,
+			PidsCurrent:   12,
+		}, "9", &id, `# HELP hakurei_memory_current_bytes Total memory currently in use by the container.
+# TYPE hakurei_memory_current_bytes gauge
+hakurei_memory_current_bytes{identity="9",instance="0123456789abcdef0123456789abcdef"} 1073741824
+# HELP hakurei_cpu_usage_seconds_total Total CPU time consumed by the container.
+# TYPE hakurei_cpu_usage_seconds_total counter
+hakurei_cpu_usage_seconds_total{identity="9",instance="0123456789abcdef0123456789abcdef"} 1.5
+# HELP hakurei_pids_current Number of processes currently in the container.
+# TYPE hakurei_pids_current gauge
+hakurei_pids_current{identity="9",instance="0123456789abcdef0123456789abcdef"} 12
+`, nil},
+
+		{"escape", &hst.CgroupStats{PidsCurrent: 1}, "a\"b\\c\n", &id, `# HELP hakurei_memory_current_bytes Total memory currently in use by the container.
+# TYPE hakurei_memory_current_bytes gauge
+hakurei_memory_current_bytes{identity="a\"b\\c\n",instance="0123456789abcdef0123456789abcdef"} 0
+# HELP hakurei_cpu_usage_seconds_total Total CPU time consumed by the container.
+# TYPE hakurei_cpu_usage_seconds_total counter
+hakurei_cpu_usage_seconds_total{identity="a\"b\\c\n",instance="0123456789abcdef0123456789abcdef"} 0
+# HELP hakurei_pids_current Number of processes currently in the container.
+# TYPE hakurei_pids_current gauge
+hakurei_pids_current{identity="a\"b\\c\n",instance="0123456789abcdef0123456789abcdef"} 1
+`, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf strings.Builder
+			if err := metrics.Write(&buf, tc.stats, tc.identity, tc.id); !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("Write: error = %v, want %v", err, tc.wantErr)
+			}
+			if got := buf.String(); got != tc.want {
+				t.Errorf("Write:\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
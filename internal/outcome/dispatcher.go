@@ -9,11 +9,13 @@ import (
 	"os/signal"
 	"os/user"
 	"path/filepath"
+	"time"
 
 	"hakurei.app/container"
 	"hakurei.app/container/check"
 	"hakurei.app/container/seccomp"
 	"hakurei.app/container/std"
+	"hakurei.app/hst"
 	"hakurei.app/internal/dbus"
 	"hakurei.app/internal/info"
 	"hakurei.app/message"
@@ -43,6 +45,8 @@ type syscallDispatcher interface {
 	getgid() int
 	// lookupEnv provides [os.LookupEnv].
 	lookupEnv(key string) (string, bool)
+	// environ provides [os.Environ].
+	environ() []string
 	// pipe provides os.Pipe.
 	pipe() (r, w *os.File, err error)
 	// stat provides [os.Stat].
@@ -58,6 +62,8 @@ type syscallDispatcher interface {
 
 	// evalSymlinks provides [filepath.EvalSymlinks].
 	evalSymlinks(path string) (string, error)
+	// lookPath provides [exec.LookPath].
+	lookPath(file string) (string, error)
 
 	// lookupGroupId calls [user.LookupGroup] and returns the Gid field of the resulting [user.Group] struct.
 	lookupGroupId(name string) (string, error)
@@ -76,8 +82,8 @@ type syscallDispatcher interface {
 	overflowGid(msg message.Msg) int
 	// setDumpable provides [container.SetDumpable].
 	setDumpable(dumpable uintptr) error
-	// receive provides [container.Receive].
-	receive(key string, e any, fdp *uintptr) (closeFunc func() error, err error)
+	// receive provides [container.ReceiveFunc] via readState.
+	receive(key string, state *outcomeState, fdp *uintptr) (closeFunc func() error, err error)
 
 	// containerStart provides the Start method of [container.Container].
 	containerStart(z *container.Container) error
@@ -85,6 +91,12 @@ type syscallDispatcher interface {
 	containerServe(z *container.Container) error
 	// containerStart provides the Wait method of [container.Container].
 	containerWait(z *container.Container) error
+	// publishPorts provides newPortPublisher via the Dial method of [container.Container].
+	publishPorts(msg message.Msg, z *container.Container, ports []hst.PortMap) (io.Closer, error)
+	// watchMemory provides logMemoryEvents via [hst.WatchCgroupMemoryEvents].
+	watchMemory(msg message.Msg, pathname *check.Absolute) (io.Closer, error)
+	// watchIdle provides newIdleMonitor via readCPUUsage.
+	watchIdle(msg message.Msg, pathname *check.Absolute, timeout time.Duration, cancel func()) (io.Closer, error)
 
 	// seccompLoad provides [seccomp.Load].
 	seccompLoad(rules []std.NativeRule, flags seccomp.ExportFlag) error
@@ -116,6 +128,7 @@ func (direct) getpid() int                                { return os.Getpid() }
 func (direct) getuid() int                                { return os.Getuid() }
 func (direct) getgid() int                                { return os.Getgid() }
 func (direct) lookupEnv(key string) (string, bool)        { return os.LookupEnv(key) }
+func (direct) environ() []string                          { return os.Environ() }
 func (direct) pipe() (r, w *os.File, err error)           { return os.Pipe() }
 func (direct) stat(name string) (os.FileInfo, error)      { return os.Stat(name) }
 func (direct) open(name string) (osFile, error)           { return os.Open(name) }
@@ -124,6 +137,7 @@ func (direct) tempdir() string                            { return os.TempDir()
 func (direct) exit(code int)                              { os.Exit(code) }
 
 func (direct) evalSymlinks(path string) (string, error) { return filepath.EvalSymlinks(path) }
+func (direct) lookPath(file string) (string, error)     { return exec.LookPath(file) }
 
 func (direct) lookupGroupId(name string) (gid string, err error) {
 	var group *user.Group
@@ -144,13 +158,26 @@ func (direct) prctl(op, arg2, arg3 uintptr) error { return container.Prctl(op, a
 func (direct) overflowUid(msg message.Msg) int    { return container.OverflowUid(msg) }
 func (direct) overflowGid(msg message.Msg) int    { return container.OverflowGid(msg) }
 func (direct) setDumpable(dumpable uintptr) error { return container.SetDumpable(dumpable) }
-func (direct) receive(key string, e any, fdp *uintptr) (func() error, error) {
-	return container.Receive(key, e, fdp)
+func (direct) receive(key string, state *outcomeState, fdp *uintptr) (func() error, error) {
+	return container.ReceiveFunc(key, func(r io.Reader) error { return readState(r, state) }, fdp)
 }
 
 func (direct) containerStart(z *container.Container) error { return z.Start() }
 func (direct) containerServe(z *container.Container) error { return z.Serve() }
 func (direct) containerWait(z *container.Container) error  { return z.Wait() }
+func (direct) publishPorts(msg message.Msg, z *container.Container, ports []hst.PortMap) (io.Closer, error) {
+	return newPortPublisher(msg, z.Dial, ports)
+}
+func (direct) watchMemory(msg message.Msg, pathname *check.Absolute) (io.Closer, error) {
+	w, err := hst.WatchCgroupMemoryEvents(pathname)
+	if err != nil {
+		return nil, err
+	}
+	return logMemoryEvents(msg, w), nil
+}
+func (direct) watchIdle(msg message.Msg, pathname *check.Absolute, timeout time.Duration, cancel func()) (io.Closer, error) {
+	return newIdleMonitor(msg, readCPUUsage(pathname), idleInterval(timeout), timeout, cancel)
+}
 
 func (direct) seccompLoad(rules []std.NativeRule, flags seccomp.ExportFlag) error {
 	return seccomp.Load(rules, flags)
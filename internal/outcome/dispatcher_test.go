@@ -174,6 +174,7 @@ func checkOpBehaviour(t *testing.T, testCases []opBehaviourTestCase) {
 		call("cmdOutput", stub.ExpectArgs{container.Nonexistent, os.Stderr, []string{}, "/"}, []byte("0"), nil),
 		call("tempdir", stub.ExpectArgs{}, container.Nonexistent+"/tmp", nil),
 		call("lookupEnv", stub.ExpectArgs{"XDG_RUNTIME_DIR"}, wantRuntimePath, nil),
+		call("lookupEnv", stub.ExpectArgs{"HOME"}, "/home/ophestra", nil),
 		call("getuid", stub.ExpectArgs{}, 1000, nil),
 		call("getgid", stub.ExpectArgs{}, 100, nil),
 
@@ -348,6 +349,7 @@ func (k *kstub) lookupEnv(key string) (string, bool) {
 	}
 	return expect.Ret.(string), true
 }
+func (k *kstub) environ() []string { k.Helper(); return k.Expects("environ").Ret.([]string) }
 func (k *kstub) stat(name string) (os.FileInfo, error) {
 	k.Helper()
 	expect := k.Expects("stat")
@@ -387,6 +389,13 @@ func (k *kstub) evalSymlinks(path string) (string, error) {
 		stub.CheckArg(k.Stub, "path", path, 0))
 }
 
+func (k *kstub) lookPath(file string) (string, error) {
+	k.Helper()
+	expect := k.Expects("lookPath")
+	return expect.Ret.(string), expect.Error(
+		stub.CheckArg(k.Stub, "file", file, 0))
+}
+
 func (k *kstub) prctl(op, arg2, arg3 uintptr) error {
 	k.Helper()
 	return k.Expects("prctl").Error(
@@ -401,10 +410,10 @@ func (k *kstub) setDumpable(dumpable uintptr) error {
 		stub.CheckArg(k.Stub, "dumpable", dumpable, 0))
 }
 
-func (k *kstub) receive(key string, e any, fdp *uintptr) (closeFunc func() error, err error) {
+func (k *kstub) receive(key string, state *outcomeState, fdp *uintptr) (closeFunc func() error, err error) {
 	k.Helper()
 	expect := k.Expects("receive")
-	reflect.ValueOf(e).Elem().Set(reflect.ValueOf(expect.Args[1]))
+	reflect.ValueOf(state).Elem().Set(reflect.ValueOf(expect.Args[1]))
 	if expect.Args[2] != nil {
 		*fdp = expect.Args[2].(uintptr)
 	}
@@ -436,6 +445,34 @@ func (k *kstub) containerWait(z *container.Container) error {
 	return k.expectCheckContainer(k.Expects("containerWait"), z)
 }
 
+func (k *kstub) publishPorts(_ message.Msg, _ *container.Container, ports []hst.PortMap) (io.Closer, error) {
+	k.Helper()
+	if err := k.Expects("publishPorts").Error(
+		stub.CheckArgReflect(k.Stub, "ports", ports, 0)); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(nil), nil
+}
+
+func (k *kstub) watchMemory(_ message.Msg, pathname *check.Absolute) (io.Closer, error) {
+	k.Helper()
+	if err := k.Expects("watchMemory").Error(
+		stub.CheckArgReflect(k.Stub, "pathname", pathname, 0)); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(nil), nil
+}
+
+func (k *kstub) watchIdle(_ message.Msg, pathname *check.Absolute, timeout time.Duration, _ func()) (io.Closer, error) {
+	k.Helper()
+	if err := k.Expects("watchIdle").Error(
+		stub.CheckArgReflect(k.Stub, "pathname", pathname, 0),
+		stub.CheckArg(k.Stub, "timeout", timeout, 1)); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(nil), nil
+}
+
 func (k *kstub) seccompLoad(rules []std.NativeRule, flags seccomp.ExportFlag) error {
 	k.Helper()
 	return k.Expects("seccompLoad").Error(
@@ -443,6 +480,13 @@ func (k *kstub) seccompLoad(rules []std.NativeRule, flags seccomp.ExportFlag) er
 		stub.CheckArg(k.Stub, "flags", flags, 1))
 }
 
+func (k *kstub) lookupGroupId(name string) (string, error) {
+	k.Helper()
+	expect := k.Expects("lookupGroupId")
+	return expect.Ret.(string), expect.Error(
+		stub.CheckArg(k.Stub, "name", name, 0))
+}
+
 func (k *kstub) cmdOutput(cmd *exec.Cmd) ([]byte, error) {
 	k.Helper()
 	expect := k.Expects("cmdOutput")
@@ -690,35 +734,48 @@ func (panicMsgContext) Value(any) any               { panic("unreachable") }
 // This type is meant to be embedded in partial syscallDispatcher implementations.
 type panicDispatcher struct{}
 
-func (panicDispatcher) new(func(k syscallDispatcher, msg message.Msg))      { panic("unreachable") }
-func (panicDispatcher) getppid() int                                        { panic("unreachable") }
-func (panicDispatcher) getpid() int                                         { panic("unreachable") }
-func (panicDispatcher) getuid() int                                         { panic("unreachable") }
-func (panicDispatcher) getgid() int                                         { panic("unreachable") }
-func (panicDispatcher) lookupEnv(string) (string, bool)                     { panic("unreachable") }
-func (panicDispatcher) pipe() (*os.File, *os.File, error)                   { panic("unreachable") }
-func (panicDispatcher) stat(string) (os.FileInfo, error)                    { panic("unreachable") }
-func (panicDispatcher) open(string) (osFile, error)                         { panic("unreachable") }
-func (panicDispatcher) readdir(string) ([]os.DirEntry, error)               { panic("unreachable") }
-func (panicDispatcher) tempdir() string                                     { panic("unreachable") }
-func (panicDispatcher) exit(int)                                            { panic("unreachable") }
-func (panicDispatcher) evalSymlinks(string) (string, error)                 { panic("unreachable") }
-func (panicDispatcher) prctl(uintptr, uintptr, uintptr) error               { panic("unreachable") }
-func (panicDispatcher) lookupGroupId(string) (string, error)                { panic("unreachable") }
-func (panicDispatcher) cmdOutput(*exec.Cmd) ([]byte, error)                 { panic("unreachable") }
-func (panicDispatcher) overflowUid(message.Msg) int                         { panic("unreachable") }
-func (panicDispatcher) overflowGid(message.Msg) int                         { panic("unreachable") }
-func (panicDispatcher) setDumpable(uintptr) error                           { panic("unreachable") }
-func (panicDispatcher) receive(string, any, *uintptr) (func() error, error) { panic("unreachable") }
-func (panicDispatcher) containerStart(*container.Container) error           { panic("unreachable") }
-func (panicDispatcher) containerServe(*container.Container) error           { panic("unreachable") }
-func (panicDispatcher) containerWait(*container.Container) error            { panic("unreachable") }
-func (panicDispatcher) mustHsuPath() *check.Absolute                        { panic("unreachable") }
-func (panicDispatcher) dbusAddress() (string, string)                       { panic("unreachable") }
-func (panicDispatcher) setupContSignal(int) (io.ReadCloser, func(), error)  { panic("unreachable") }
-func (panicDispatcher) getMsg() message.Msg                                 { panic("unreachable") }
-func (panicDispatcher) fatal(...any)                                        { panic("unreachable") }
-func (panicDispatcher) fatalf(string, ...any)                               { panic("unreachable") }
+func (panicDispatcher) new(func(k syscallDispatcher, msg message.Msg)) { panic("unreachable") }
+func (panicDispatcher) getppid() int                                   { panic("unreachable") }
+func (panicDispatcher) getpid() int                                    { panic("unreachable") }
+func (panicDispatcher) getuid() int                                    { panic("unreachable") }
+func (panicDispatcher) getgid() int                                    { panic("unreachable") }
+func (panicDispatcher) lookupEnv(string) (string, bool)                { panic("unreachable") }
+func (panicDispatcher) environ() []string                              { panic("unreachable") }
+func (panicDispatcher) pipe() (*os.File, *os.File, error)              { panic("unreachable") }
+func (panicDispatcher) stat(string) (os.FileInfo, error)               { panic("unreachable") }
+func (panicDispatcher) open(string) (osFile, error)                    { panic("unreachable") }
+func (panicDispatcher) readdir(string) ([]os.DirEntry, error)          { panic("unreachable") }
+func (panicDispatcher) tempdir() string                                { panic("unreachable") }
+func (panicDispatcher) exit(int)                                       { panic("unreachable") }
+func (panicDispatcher) evalSymlinks(string) (string, error)            { panic("unreachable") }
+func (panicDispatcher) lookPath(string) (string, error)                { panic("unreachable") }
+func (panicDispatcher) prctl(uintptr, uintptr, uintptr) error          { panic("unreachable") }
+func (panicDispatcher) lookupGroupId(string) (string, error)           { panic("unreachable") }
+func (panicDispatcher) cmdOutput(*exec.Cmd) ([]byte, error)            { panic("unreachable") }
+func (panicDispatcher) overflowUid(message.Msg) int                    { panic("unreachable") }
+func (panicDispatcher) overflowGid(message.Msg) int                    { panic("unreachable") }
+func (panicDispatcher) setDumpable(uintptr) error                      { panic("unreachable") }
+func (panicDispatcher) receive(string, *outcomeState, *uintptr) (func() error, error) {
+	panic("unreachable")
+}
+func (panicDispatcher) containerStart(*container.Container) error { panic("unreachable") }
+func (panicDispatcher) containerServe(*container.Container) error { panic("unreachable") }
+func (panicDispatcher) containerWait(*container.Container) error  { panic("unreachable") }
+func (panicDispatcher) publishPorts(message.Msg, *container.Container, []hst.PortMap) (io.Closer, error) {
+	panic("unreachable")
+}
+func (panicDispatcher) watchMemory(message.Msg, *check.Absolute) (io.Closer, error) {
+	panic("unreachable")
+}
+func (panicDispatcher) watchIdle(message.Msg, *check.Absolute, time.Duration, func()) (io.Closer, error) {
+	panic("unreachable")
+}
+func (panicDispatcher) mustHsuPath() *check.Absolute                       { panic("unreachable") }
+func (panicDispatcher) dbusAddress() (string, string)                      { panic("unreachable") }
+func (panicDispatcher) setupContSignal(int) (io.ReadCloser, func(), error) { panic("unreachable") }
+func (panicDispatcher) getMsg() message.Msg                                { panic("unreachable") }
+func (panicDispatcher) fatal(...any)                                       { panic("unreachable") }
+func (panicDispatcher) fatalf(string, ...any)                              { panic("unreachable") }
 
 func (panicDispatcher) notifyContext(context.Context, ...os.Signal) (context.Context, context.CancelFunc) {
 	panic("unreachable")
@@ -2,10 +2,12 @@ package outcome
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/user"
+	"strconv"
 
 	"hakurei.app/hst"
 	"hakurei.app/internal/system"
@@ -17,6 +19,87 @@ func newWithMessageError(msg string, err error) error {
 	return &hst.AppError{Step: "finalise", Err: err, Msg: msg}
 }
 
+// resolveGroups resolves supplementary group names against the host group database.
+// Numerical group ids are passed through unchanged, as hsu expects numerical group ids.
+func resolveGroups(k syscallDispatcher, groups []string) ([]string, error) {
+	supp := make([]string, len(groups))
+	for i, name := range groups {
+		if _, err := strconv.ParseUint(name, 10, 32); err == nil {
+			supp[i] = name
+			continue
+		}
+
+		if gid, err := k.lookupGroupId(name); err != nil {
+			var unknownGroupError user.UnknownGroupError
+			if errors.As(err, &unknownGroupError) {
+				return nil, newWithMessageError(fmt.Sprintf("unknown group %q", name), unknownGroupError)
+			} else {
+				return nil, &hst.AppError{Step: "look up group by name", Err: err, Msg: err.Error()}
+			}
+		} else {
+			supp[i] = gid
+		}
+	}
+	return supp, nil
+}
+
+/*
+resolveConfig returns a shallow copy of config with values depending on the host resolved,
+following a successful call to [hst.Config.Validate]. Bus policies referenced via
+[hst.BusConfig.PolicyRef] are read and merged with the inline rules, and the cgroup cpuset
+is checked against CPUs online on the host. The configuration passed in is not modified.
+*/
+func resolveConfig(k syscallDispatcher, config *hst.Config) (*hst.Config, error) {
+	resolved := *config
+
+	var err error
+	if resolved.SessionBus, err = resolveBus(k, config.SessionBus, "session"); err != nil {
+		return nil, err
+	}
+	if resolved.SystemBus, err = resolveBus(k, config.SystemBus, "system"); err != nil {
+		return nil, err
+	}
+
+	if cgroup := config.Container.Cgroup; cgroup != nil && cgroup.CPUSet != "" {
+		if err = checkCPUSet(k, cgroup.CPUSet); err != nil {
+			return nil, err
+		}
+	}
+	return &resolved, nil
+}
+
+// resolveBus returns c merged with the policy it references, or c itself if it references none.
+// The rules of the result are checked as a whole, as a referenced policy is not seen by Validate.
+func resolveBus(k syscallDispatcher, c *hst.BusConfig, segment string) (*hst.BusConfig, error) {
+	if c == nil || c.PolicyRef == nil {
+		return c, nil
+	}
+	pathname := c.PolicyRef.String()
+
+	var policy hst.BusConfig
+	if data, err := readAll(k, pathname); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, &hst.AppError{Step: "resolve configuration", Err: err,
+				Msg: segment + " bus policy " + strconv.Quote(pathname) + " does not exist"}
+		}
+		return nil, &hst.AppError{Step: "resolve configuration", Err: err,
+			Msg: "cannot read " + segment + " bus policy: " + err.Error()}
+	} else if err = json.Unmarshal(data, &policy); err != nil {
+		return nil, &hst.AppError{Step: "resolve configuration", Err: err,
+			Msg: "cannot decode " + segment + " bus policy " + strconv.Quote(pathname) + ": " + err.Error()}
+	}
+	if policy.PolicyRef != nil {
+		return nil, &hst.AppError{Step: "resolve configuration", Err: hst.ErrBusPolicyRef,
+			Msg: segment + " bus policy " + strconv.Quote(pathname) + " references another policy"}
+	}
+
+	resolved := c.WithPolicy(&policy)
+	if err := resolved.CheckInterfaces(segment); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
 // An outcome is the runnable state of a hakurei container via [hst.Config].
 type outcome struct {
 	// Supplementary group ids. Populated during finalise.
@@ -46,36 +129,31 @@ func (k *outcome) finalise(ctx context.Context, msg message.Msg, id *hst.ID, con
 	if err := config.Validate(); err != nil {
 		return err
 	}
+	resolved, err := resolveConfig(k.syscallDispatcher, config)
+	if err != nil {
+		return err
+	}
 
-	// hsu expects numerical group ids
-	supp := make([]string, len(config.Groups))
-	for i, name := range config.Groups {
-		if gid, err := k.lookupGroupId(name); err != nil {
-			var unknownGroupError user.UnknownGroupError
-			if errors.As(err, &unknownGroupError) {
-				return newWithMessageError(fmt.Sprintf("unknown group %q", name), unknownGroupError)
-			} else {
-				return &hst.AppError{Step: "look up group by name", Err: err, Msg: err.Error()}
-			}
-		} else {
-			supp[i] = gid
-		}
+	supp, err := resolveGroups(k.syscallDispatcher, resolved.Groups)
+	if err != nil {
+		return err
 	}
 
 	// early validation complete at this point
-	s := newOutcomeState(k.syscallDispatcher, msg, id, config, &Hsu{k: k})
+	s := newOutcomeState(k.syscallDispatcher, msg, id, resolved, &Hsu{k: k})
 	if err := s.populateLocal(k.syscallDispatcher, msg); err != nil {
 		return err
 	}
 
-	sys := system.New(k.ctx, msg, s.uid.unwrap())
-	if err := s.newSys(config, sys).toSystem(); err != nil {
+	sys := system.New(k.ctx, msg, s.uid.unwrap()).SetOpTimeout(commitOpTimeout)
+	if err := s.newSys(resolved, sys).toSystem(); err != nil {
 		return err
 	}
 
 	k.sys = sys
 	k.supp = supp
 	k.state = s
+	// the configuration as supplied is registered, resolved values are specific to this host
 	k.config = config
 	return nil
 }
@@ -0,0 +1,161 @@
+package outcome
+
+import (
+	"os"
+	"os/user"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"hakurei.app/container/stub"
+	"hakurei.app/hst"
+)
+
+func TestResolveGroups(t *testing.T) {
+	t.Parallel()
+
+	fResolve := func(want []string, groups ...string) func(k *kstub) error {
+		return func(k *kstub) error {
+			got, err := resolveGroups(k, groups)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("resolveGroups: %q, want %q", got, want)
+			}
+			return err
+		}
+	}
+
+	checkSimple(t, "resolveGroups", []simpleTestCase{
+		{"unknown", fResolve(nil, "video", "nonexistent"), stub.Expect{Calls: []stub.Call{
+			call("lookupGroupId", stub.ExpectArgs{"video"}, "26", nil),
+			call("lookupGroupId", stub.ExpectArgs{"nonexistent"}, "", user.UnknownGroupError("nonexistent")),
+		}}, &hst.AppError{
+			Step: "finalise",
+			Err:  user.UnknownGroupError("nonexistent"),
+			Msg:  `unknown group "nonexistent"`,
+		}},
+
+		{"lookup", fResolve(nil, "video"), stub.Expect{Calls: []stub.Call{
+			call("lookupGroupId", stub.ExpectArgs{"video"}, "", syscall.EIO),
+		}}, &hst.AppError{
+			Step: "look up group by name",
+			Err:  syscall.EIO,
+			Msg:  syscall.EIO.Error(),
+		}},
+
+		{"negative", fResolve(nil, "-1"), stub.Expect{Calls: []stub.Call{
+			call("lookupGroupId", stub.ExpectArgs{"-1"}, "", user.UnknownGroupError("-1")),
+		}}, &hst.AppError{
+			Step: "finalise",
+			Err:  user.UnknownGroupError("-1"),
+			Msg:  `unknown group "-1"`,
+		}},
+
+		{"numeric", fResolve([]string{"20", "0"}, "20", "0"), stub.Expect{}, nil},
+
+		{"success", fResolve([]string{"26", "20", "27"}, "video", "20", "dialout"), stub.Expect{Calls: []stub.Call{
+			call("lookupGroupId", stub.ExpectArgs{"video"}, "26", nil),
+			call("lookupGroupId", stub.ExpectArgs{"dialout"}, "27", nil),
+		}}, nil},
+	})
+}
+
+func TestResolveConfig(t *testing.T) {
+	t.Parallel()
+
+	const policyPath = "/etc/hakurei/portal-safe"
+	const policy = `{"talk":["org.freedesktop.portal.*"],"call":{"org.freedesktop.portal.*":"*"},"filter":true}`
+
+	fResolve := func(want func(config *hst.Config) *hst.Config, f func(config *hst.Config)) func(k *kstub) error {
+		return func(k *kstub) error {
+			config := hst.Template()
+			config.SystemBus = nil
+			f(config)
+			original := *config
+			originalBus := *config.SessionBus
+
+			got, err := resolveConfig(k, config)
+			if !reflect.DeepEqual(*config, original) || !reflect.DeepEqual(*config.SessionBus, originalBus) {
+				t.Errorf("resolveConfig: modified configuration")
+			}
+			if err == nil {
+				if w := want(config); !reflect.DeepEqual(got, w) {
+					t.Errorf("resolveConfig:\n%#v\nwant\n%#v", got, w)
+				}
+			}
+			return err
+		}
+	}
+	inline := func(config *hst.Config) {
+		config.SessionBus = &hst.BusConfig{
+			Talk:      []string{"org.freedesktop.Notifications"},
+			PolicyRef: m(policyPath),
+		}
+	}
+
+	checkSimple(t, "resolveConfig", []simpleTestCase{
+		{"unchanged", fResolve(func(config *hst.Config) *hst.Config {
+			resolved := *config
+			return &resolved
+		}, func(*hst.Config) {}), stub.Expect{}, nil},
+
+		{"policy", fResolve(func(config *hst.Config) *hst.Config {
+			resolved := *config
+			resolved.SessionBus = &hst.BusConfig{
+				Talk:   []string{"org.freedesktop.portal.*", "org.freedesktop.Notifications"},
+				Call:   map[string]string{"org.freedesktop.portal.*": "*"},
+				Filter: true,
+			}
+			return &resolved
+		}, inline), stub.Expect{Calls: []stub.Call{
+			call("open", stub.ExpectArgs{policyPath}, &stubOsFile{Reader: strings.NewReader(policy)}, nil),
+		}}, nil},
+
+		{"policy missing", fResolve(nil, inline), stub.Expect{Calls: []stub.Call{
+			call("open", stub.ExpectArgs{policyPath}, (*stubOsFile)(nil), os.ErrNotExist),
+		}}, &hst.AppError{Step: "resolve configuration", Err: os.ErrNotExist,
+			Msg: `session bus policy "/etc/hakurei/portal-safe" does not exist`}},
+
+		{"policy read", fResolve(nil, inline), stub.Expect{Calls: []stub.Call{
+			call("open", stub.ExpectArgs{policyPath}, (*stubOsFile)(nil), stub.UniqueError(0)),
+		}}, &hst.AppError{Step: "resolve configuration", Err: stub.UniqueError(0),
+			Msg: "cannot read session bus policy: unique error 0 injected by the test suite"}},
+
+		{"policy nested", fResolve(nil, inline), stub.Expect{Calls: []stub.Call{
+			call("open", stub.ExpectArgs{policyPath}, &stubOsFile{Reader: strings.NewReader(`{"policy_ref":"/etc/hakurei/other"}`)}, nil),
+		}}, &hst.AppError{Step: "resolve configuration", Err: hst.ErrBusPolicyRef,
+			Msg: `session bus policy "/etc/hakurei/portal-safe" references another policy`}},
+
+		{"policy interface", fResolve(nil, inline), stub.Expect{Calls: []stub.Call{
+			call("open", stub.ExpectArgs{policyPath}, &stubOsFile{Reader: strings.NewReader(`{"talk":["portal.*"]}`)}, nil),
+		}}, &hst.BadInterfaceError{Interface: "portal.*", Segment: "session"}},
+
+		{"cpuset", fResolve(func(config *hst.Config) *hst.Config {
+			resolved := *config
+			return &resolved
+		}, func(config *hst.Config) {
+			config.Container.Cgroup = &hst.CgroupConfig{CPUSet: "1,3"}
+		}), stub.Expect{Calls: []stub.Call{
+			call("open", stub.ExpectArgs{"/sys/devices/system/cpu/online"}, &stubOsFile{Reader: strings.NewReader("0-3\n")}, nil),
+		}}, nil},
+
+		{"cpuset offline", fResolve(nil, func(config *hst.Config) {
+			config.Container.Cgroup = &hst.CgroupConfig{CPUSet: "0,65535"}
+		}), stub.Expect{Calls: []stub.Call{
+			call("open", stub.ExpectArgs{"/sys/devices/system/cpu/online"}, &stubOsFile{Reader: strings.NewReader("0-3\n")}, nil),
+		}}, &hst.AppError{Step: "resolve configuration", Err: syscall.EINVAL,
+			Msg: "cgroup cpuset CPU 65535 is not online"}},
+
+		{"cpuset format", fResolve(nil, func(config *hst.Config) {
+			config.Container.Cgroup = &hst.CgroupConfig{CPUSet: "0-a"}
+		}), stub.Expect{}, &hst.AppError{Step: "resolve configuration",
+			Err: &strconv.NumError{Func: "Atoi", Num: "a", Err: strconv.ErrSyntax},
+			Msg: `invalid cgroup cpuset "0-a"`}},
+
+		{"cpuset reversed", fResolve(nil, func(config *hst.Config) {
+			config.Container.Cgroup = &hst.CgroupConfig{CPUSet: "3-1"}
+		}), stub.Expect{}, &hst.AppError{Step: "resolve configuration", Err: syscall.EINVAL,
+			Msg: `invalid cgroup cpuset "3-1"`}},
+	})
+}
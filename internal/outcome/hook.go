@@ -0,0 +1,8 @@
+package outcome
+
+// runParamsHooks calls hooks held by state.
+func (state *outcomeStateParams) runParamsHooks() {
+	for _, f := range state.hooks {
+		f(opsAdapter{state.params.Ops}, state.env)
+	}
+}
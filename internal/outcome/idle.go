@@ -0,0 +1,106 @@
+package outcome
+
+import (
+	"time"
+
+	"hakurei.app/container/check"
+	"hakurei.app/hst"
+	"hakurei.app/message"
+)
+
+const (
+	// idlePollMax is the longest interval activity of the container is sampled at.
+	idlePollMax = 5 * time.Second
+	// idleThreshold is the fraction of a single CPU below which the container is considered idle,
+	// so periodic housekeeping such as timers firing does not count as activity.
+	idleThreshold = 100
+)
+
+// idleInterval returns the interval activity is sampled at for timeout.
+func idleInterval(timeout time.Duration) time.Duration {
+	return min(max(timeout/4, time.Millisecond), idlePollMax)
+}
+
+// idleMonitor cancels the container once it remains idle for a timeout.
+type idleMonitor struct {
+	// returns cumulative activity in microseconds
+	read func() (uint64, error)
+	// sampling interval and the idle duration cancel is called after
+	interval, timeout time.Duration
+	// cancels the container, called at most once
+	cancel func()
+	msg    message.Msg
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newIdleMonitor starts monitoring activity reported by read every interval, and calls cancel
+// once activity stays below [idleThreshold] for timeout. Monitoring stops if read returns an error.
+func newIdleMonitor(
+	msg message.Msg,
+	read func() (uint64, error),
+	interval, timeout time.Duration,
+	cancel func(),
+) (*idleMonitor, error) {
+	usage, err := read()
+	if err != nil {
+		return nil, err
+	}
+	m := &idleMonitor{read, interval, timeout, cancel, msg, make(chan struct{}), make(chan struct{})}
+	go m.watch(usage)
+	return m, nil
+}
+
+// watch samples activity until the container is cancelled or the monitor is closed.
+func (m *idleMonitor) watch(usage uint64) {
+	defer close(m.done)
+
+	t := time.NewTicker(m.interval)
+	defer t.Stop()
+
+	threshold := uint64(m.interval.Microseconds() / idleThreshold)
+	last := time.Now()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-t.C:
+			cur, err := m.read()
+			if err != nil {
+				m.msg.Verbosef("cannot sample container activity: %v", err)
+				return
+			}
+			if cur-usage > threshold {
+				last = now
+			}
+			usage = cur
+
+			if idle := now.Sub(last); idle >= m.timeout {
+				printf(m.msg, "container idle for %s, cancelling", idle.Round(time.Millisecond))
+				m.cancel()
+				return
+			}
+		}
+	}
+}
+
+// Close stops the monitor and waits for it to return. Close must only be called once.
+func (m *idleMonitor) Close() error {
+	close(m.stop)
+	<-m.done
+	return nil
+}
+
+// readCPUUsage returns a function reading the CPU time consumed by the cgroup at pathname in
+// microseconds via [hst.ReadCgroupStats]. This statistic is available regardless of whether the
+// cpu controller is enabled.
+func readCPUUsage(pathname *check.Absolute) func() (uint64, error) {
+	return func() (uint64, error) {
+		stats, err := hst.ReadCgroupStats(pathname)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(stats.CPUUsage / time.Microsecond), nil
+	}
+}
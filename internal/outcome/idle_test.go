@@ -0,0 +1,202 @@
+package outcome
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"hakurei.app/container/check"
+	"hakurei.app/container/stub"
+	"hakurei.app/hst"
+	"hakurei.app/message"
+)
+
+func TestIdleMonitor(t *testing.T) {
+	t.Parallel()
+
+	const (
+		interval = time.Millisecond
+		timeout  = 10 * time.Millisecond
+	)
+
+	// stubActivity returns an activity source reporting busy for the first n samples
+	stubActivity := func(n int64) (func() (uint64, error), *atomic.Int64) {
+		var calls atomic.Int64
+		return func() (uint64, error) {
+			c := calls.Add(1)
+			return uint64(min(c, n)) * uint64(time.Second.Microseconds()), nil
+		}, &calls
+	}
+
+	waitCancel := func(t *testing.T, cancelled <-chan struct{}) {
+		t.Helper()
+		select {
+		case <-cancelled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for cancel")
+		}
+	}
+
+	t.Run("read", func(t *testing.T) {
+		t.Parallel()
+		if _, err := newIdleMonitor(message.New(nil), func() (uint64, error) {
+			return 0, stub.UniqueError(0)
+		}, interval, timeout, func() { t.Error("cancel: unexpected call") }); !reflect.DeepEqual(err, stub.UniqueError(0)) {
+			t.Errorf("newIdleMonitor: error = %v", err)
+		}
+	})
+
+	t.Run("idle", func(t *testing.T) {
+		t.Parallel()
+		read, _ := stubActivity(0)
+		cancelled := make(chan struct{})
+		var buf bytes.Buffer
+		m, err := newIdleMonitor(message.New(log.New(&buf, "", 0)), read, interval, timeout, func() { close(cancelled) })
+		if err != nil {
+			t.Fatalf("newIdleMonitor: error = %v", err)
+		}
+		waitCancel(t, cancelled)
+		if got := buf.String(); !strings.HasPrefix(got, "container idle for ") || !strings.HasSuffix(got, ", cancelling\n") {
+			t.Errorf("newIdleMonitor: logged %q", got)
+		}
+		if err = m.Close(); err != nil {
+			t.Errorf("Close: error = %v", err)
+		}
+	})
+
+	t.Run("active", func(t *testing.T) {
+		t.Parallel()
+		const busy = 64
+		read, calls := stubActivity(busy)
+		cancelled := make(chan struct{})
+		var samples int64
+		m, err := newIdleMonitor(message.New(log.New(io.Discard, "", 0)), read, interval, timeout, func() {
+			samples = calls.Load()
+			close(cancelled)
+		})
+		if err != nil {
+			t.Fatalf("newIdleMonitor: error = %v", err)
+		}
+		waitCancel(t, cancelled)
+		if samples <= busy {
+			t.Errorf("cancel: called after %d samples, want more than %d", samples, busy)
+		}
+		if err = m.Close(); err != nil {
+			t.Errorf("Close: error = %v", err)
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		t.Parallel()
+		read, _ := stubActivity(0)
+		m, err := newIdleMonitor(message.New(nil), read, interval, time.Hour, func() { t.Error("cancel: unexpected call") })
+		if err != nil {
+			t.Fatalf("newIdleMonitor: error = %v", err)
+		}
+		time.Sleep(10 * interval)
+		if err = m.Close(); err != nil {
+			t.Errorf("Close: error = %v", err)
+		}
+	})
+
+	t.Run("sample", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int64
+		m, err := newIdleMonitor(message.New(nil), func() (uint64, error) {
+			if calls.Add(1) > 1 {
+				return 0, stub.UniqueError(1)
+			}
+			return 0, nil
+		}, interval, timeout, func() { t.Error("cancel: unexpected call") })
+		if err != nil {
+			t.Fatalf("newIdleMonitor: error = %v", err)
+		}
+		select {
+		case <-m.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for monitor to stop")
+		}
+		if err = m.Close(); err != nil {
+			t.Errorf("Close: error = %v", err)
+		}
+	})
+}
+
+func TestIdleInterval(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{time.Microsecond, time.Millisecond},
+		{time.Second, 250 * time.Millisecond},
+		{time.Hour, idlePollMax},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.timeout.String(), func(t *testing.T) {
+			t.Parallel()
+			if got := idleInterval(tc.timeout); got != tc.want {
+				t.Errorf("idleInterval: %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadCPUUsage(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		data string
+		want uint64
+		err  func(pathname string) error
+	}{
+		{"nonexistent", "\x00", 0, nil},
+		{"usage", "usage_usec 1048576\nuser_usec 1024\nsystem_usec 1048576\n", 1 << 20, nil},
+		{"missing", "user_usec 1024\n", 0, nil},
+		{"invalid", "usage_usec -1\n", 0, func(pathname string) error {
+			return &hst.AppError{Step: "read cgroup statistics",
+				Err: &strconv.NumError{Func: "ParseUint", Num: "-1", Err: strconv.ErrSyntax},
+				Msg: "invalid cpu.stat in " + strconv.Quote(pathname)}
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			d := t.TempDir()
+			if tc.data != "\x00" {
+				if err := os.WriteFile(filepath.Join(d, "cpu.stat"), []byte(tc.data), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := readCPUUsage(check.MustAbs(d))()
+			if tc.data == "\x00" {
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("readCPUUsage: error = %v", err)
+				}
+				return
+			}
+			var wantErr error
+			if tc.err != nil {
+				wantErr = tc.err(d)
+			}
+			if !reflect.DeepEqual(err, wantErr) {
+				t.Errorf("readCPUUsage: error = %v, want %v", err, wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("readCPUUsage: %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
@@ -134,11 +134,13 @@ func (s *outcomeState) populateLocal(k syscallDispatcher, msg message.Msg) error
 }
 
 // instancePath returns a path formatted for outcomeStateSys.instance.
+// The derivation must remain identical to [hst.InstancePaths].
 // This method must only be called from outcomeOp.toContainer if
 // outcomeOp.toSystem has already called outcomeStateSys.instance.
 func (s *outcomeState) instancePath() *check.Absolute { return s.sc.SharePath.Append(s.id.String()) }
 
 // runtimePath returns a path formatted for outcomeStateSys.runtime.
+// The derivation must remain identical to [hst.InstancePaths].
 // This method must only be called from outcomeOp.toContainer if
 // outcomeOp.toSystem has already called outcomeStateSys.runtime.
 func (s *outcomeState) runtimePath() *check.Absolute { return s.sc.RunDirPath.Append(s.id.String()) }
@@ -160,6 +162,10 @@ type outcomeStateSys struct {
 
 	// Copied from [hst.Config]. Safe for read by spWaylandOp.toSystem only.
 	directWayland bool
+	// Copied from [hst.Config]. Safe for read by spX11Op.toSystem only.
+	directXauthority bool
+	// Copied from [hst.Config]. Safe for read by spX11Op.toSystem only.
+	x11Cookie bool
 	// Copied header from [hst.Config]. Safe for read by spFilesystemOp.toSystem only.
 	extraPerms []hst.ExtraPermConfig
 	// Copied address from [hst.Config]. Safe for read by spDBusOp.toSystem only.
@@ -173,7 +179,8 @@ type outcomeStateSys struct {
 func (s *outcomeState) newSys(config *hst.Config, sys *system.I) *outcomeStateSys {
 	return &outcomeStateSys{
 		appId: config.ID, et: config.Enablements.Unwrap(),
-		directWayland: config.DirectWayland, extraPerms: config.ExtraPerms,
+		directWayland: config.DirectWayland, directXauthority: config.DirectXauthority, x11Cookie: config.X11Cookie,
+		extraPerms: config.ExtraPerms,
 		sessionBus: config.SessionBus, systemBus: config.SystemBus,
 		sys: sys, outcomeState: s,
 	}
@@ -181,7 +188,7 @@ func (s *outcomeState) newSys(config *hst.Config, sys *system.I) *outcomeStateSy
 
 // newParams returns the address of a new outcomeStateParams embedding the current outcomeState.
 func (s *outcomeState) newParams() *outcomeStateParams {
-	stateParams := outcomeStateParams{params: new(container.Params), outcomeState: s}
+	stateParams := outcomeStateParams{params: new(container.Params), hooks: hst.ParamsHooks(), outcomeState: s}
 	if s.Container.Env == nil {
 		stateParams.env = make(map[string]string, envAllocSize)
 	} else {
@@ -241,6 +248,21 @@ type outcomeStateParams struct {
 	// Populated by spRuntimeOp.
 	runtimeDir *check.Absolute
 
+	// Ports published on the host loopback interface once the container starts.
+	// Populated by spPortOp.
+	publishPorts []hst.PortMap
+
+	// Instance cgroup watched for memory events once the container starts.
+	// Populated by spCgroupOp.
+	memoryEvents *check.Absolute
+	// Instance cgroup sampled for activity once the container starts.
+	// Populated by spCgroupOp.
+	idleCgroup *check.Absolute
+
+	// Called by spFilesystemOp before the environment is collapsed.
+	// Populated from hooks registered via [hst.RegisterParamsHook].
+	hooks []hst.ParamsHook
+
 	as hst.ApplyState
 	*outcomeState
 }
@@ -277,12 +299,19 @@ func (state *outcomeStateSys) toSystem() error {
 		&spRuntimeOp{},
 		spTmpdirOp{},
 		spAccountOp{},
+		spMachineIDOp{},
+		spNetworkFilesOp{},
+		&spRuntimeSocketOp{},
 
 		// optional via enablements
 		&spWaylandOp{},
 		&spX11Op{},
 		&spPulseOp{},
+		&spPipeWireOp{},
 		&spDBusOp{},
+		&spGPUOp{},
+		&spDeviceOp{},
+		spPortOp{},
 
 		// must run last
 		&spFilesystemOp{},
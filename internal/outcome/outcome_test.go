@@ -3,6 +3,7 @@ package outcome
 import (
 	"testing"
 
+	"hakurei.app/container/fhs"
 	"hakurei.app/hst"
 	"hakurei.app/internal/env"
 )
@@ -32,3 +33,23 @@ func TestOutcomeStateValid(t *testing.T) {
 		})
 	}
 }
+
+func TestInstancePaths(t *testing.T) {
+	t.Parallel()
+
+	id := hst.ID{
+		0xba, 0x21, 0xc9, 0xbd,
+		0x33, 0xd9, 0xd3, 0x79,
+		0x17, 0x28, 0x82, 0x81,
+		0xa2, 0xa0, 0xd2, 0x39}
+	s := outcomeState{ID: &id, id: &stringPair[hst.ID]{id, id.String()}}
+	(&env.Paths{TempDir: fhs.AbsTmp, RuntimePath: fhs.AbsRunUser.Append("1000")}).Copy(&s.sc, 0)
+
+	share, runtime := hst.InstancePaths(&s.sc, s.ID)
+	if want := s.instancePath(); !share.Is(want) {
+		t.Errorf("InstancePaths: share = %q, want %q", share, want)
+	}
+	if want := s.runtimePath(); !runtime.Is(want) {
+		t.Errorf("InstancePaths: runtime = %q, want %q", runtime, want)
+	}
+}
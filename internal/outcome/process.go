@@ -2,11 +2,12 @@ package outcome
 
 import (
 	"context"
-	"encoding/gob"
+	"encoding/json"
 	"errors"
 	"math"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
@@ -27,6 +28,8 @@ const (
 	shimWaitTimeout = 5 * time.Second
 	// Timeout for writing outcomeState to the shim setup pipe.
 	shimSetupTimeout = 5 * time.Second
+	// Maximum duration of applying a single system.Op, for example via an unresponsive X server.
+	commitOpTimeout = 30 * time.Second
 )
 
 // NewStore returns the address of a new instance of [store.Store].
@@ -48,7 +51,7 @@ func (k *outcome) main(msg message.Msg, identifierFd int) {
 		processCommit
 		// transitions to processLifecycle only
 		processServe
-		// transitions to processCleanup only
+		// transitions to processCleanup, or processLifecycle on SIGHUP
 		processLifecycle
 		// transitions to processFinal only
 		processCleanup
@@ -89,6 +92,15 @@ func (k *outcome) main(msg message.Msg, identifierFd int) {
 		// populated in processStart, accessed by processServe
 		shimPipe *os.File
 
+		// result of shimCmd.Wait, populated on first entry into processLifecycle
+		shimWait chan error
+		// closed once ctx is done and the compensated shim timeout elapsed,
+		// populated on first entry into processLifecycle
+		shimTimeout chan struct{}
+		// receives SIGHUP for restarting the message bus proxy, populated on first
+		// entry into processLifecycle if the message bus proxy is enabled
+		reload chan os.Signal
+
 		// perror cancels ctx and prints an error message
 		perror = func(err error, message string) {
 			cancel()
@@ -150,12 +162,19 @@ func (k *outcome) main(msg message.Msg, identifierFd int) {
 				perrorFatal(err, "acquire lock on store segment", processLifecycle)
 				continue
 			}
+			var record []byte
+			if record, err = json.Marshal(k.sys.Record()); err != nil {
+				unlock()
+				perrorFatal(&hst.AppError{Step: "record system setup", Err: err}, "record system setup", processLifecycle)
+				continue
+			}
 			if entryHandle, err = handle.Save(&hst.State{
 				ID:      k.state.id.unwrap(),
 				PID:     os.Getpid(),
 				ShimPID: shimCmd.Process.Pid,
 				Config:  k.config,
 				Time:    startTime,
+				System:  record,
 			}); err != nil {
 				unlock()
 				// transition here to avoid the commit/revert cycle on the doomed instance
@@ -201,12 +220,30 @@ func (k *outcome) main(msg message.Msg, identifierFd int) {
 			}
 
 		case processLifecycle:
-			// this state transition to processCleanup only
+			// this state transition to processCleanup, or back to processLifecycle on SIGHUP
 			processState = processCleanup
 
+			if shimWait == nil {
+				shimWait = make(chan error, 1)
+				go func() { shimWait <- shimCmd.Wait(); cancel() }()
+
+				shimTimeout = make(chan struct{})
+				// this ties processLifecycle to ctx with the additional compensated timeout duration
+				// to allow transition to the next state on a locked up shim
+				go func() { <-ctx.Done(); time.Sleep(k.state.Shim.WaitDelay + shimWaitTimeout); close(shimTimeout) }()
+
+				// SIGHUP resolves bus policies again and restarts the message bus proxy,
+				// and retains its default action of terminating hakurei otherwise
+				if k.config.Enablements.Unwrap()&hst.EDBus != 0 {
+					reload = make(chan os.Signal, 1)
+					signal.Notify(reload, syscall.SIGHUP)
+				}
+			}
+
+			var isReload bool
 			msg.Suspend()
 			select {
-			case err := <-func() chan error { w := make(chan error, 1); go func() { w <- shimCmd.Wait(); cancel() }(); return w }():
+			case err := <-shimWait:
 				wstatus, ok := shimCmd.ProcessState.Sys().(syscall.WaitStatus)
 				if ok {
 					if v := wstatus.ExitStatus(); v != 0 {
@@ -236,20 +273,25 @@ func (k *outcome) main(msg message.Msg, identifierFd int) {
 					}
 				}
 
-			case <-func() chan struct{} {
-				w := make(chan struct{})
-				// this ties processLifecycle to ctx with the additional compensated timeout duration
-				// to allow transition to the next state on a locked up shim
-				go func() { <-ctx.Done(); time.Sleep(k.state.Shim.WaitDelay + shimWaitTimeout); close(w) }()
-				return w
-			}():
+			case <-shimTimeout:
 				// this is only reachable when wait did not return within shimWaitTimeout, after its WaitDelay has elapsed.
 				// This is different from the container failing to terminate within its timeout period, as that is enforced
 				// by the shim. This path is instead reached when there is a lockup in shim preventing it from completing.
 				msg.GetLogger().Printf("process %d did not terminate", shimCmd.Process.Pid)
+
+			case <-reload:
+				processState, isReload = processLifecycle, true
 			}
 			msg.Resume()
 
+			if isReload {
+				if err := k.reloadBus(); err != nil {
+					printMessageError(msg.GetLogger().Println, "cannot restart message bus proxy:", err)
+				} else {
+					msg.Verbose("restarted message bus proxy on SIGHUP")
+				}
+			}
+
 		case processCleanup:
 			// this state transition to processFinal only
 			processState = processFinal
@@ -297,7 +339,7 @@ func (k *outcome) main(msg message.Msg, identifierFd int) {
 					} else {
 						msg.Verbosef("found %d instances, cleaning up without user-scoped operations", n)
 					}
-					ec |= rt ^ (hst.EWayland | hst.EX11 | hst.EDBus | hst.EPulse)
+					ec |= rt ^ (hst.EWayland | hst.EX11 | hst.EDBus | hst.EPulse | hst.EPipeWire)
 					if msg.IsVerbose() {
 						if ec > 0 {
 							msg.Verbose("reverting operations scope", system.TypeString(ec))
@@ -385,7 +427,12 @@ func serveShim(msg message.Msg, shimPipe *os.File, state *outcomeState) error {
 	if err := shimPipe.SetDeadline(time.Now().Add(shimSetupTimeout)); err != nil {
 		msg.Verbose(err.Error())
 	}
-	if err := gob.NewEncoder(shimPipe).Encode(state); err != nil {
+	transport := transportDefault
+	if name, ok := os.LookupEnv(transportEnv); ok {
+		transport = name
+	}
+	msg.Verbosef("transmitting shim config via %s transport", transport)
+	if err := writeState(shimPipe, transport, state); err != nil {
 		msg.Resume()
 		return &hst.AppError{Step: "transmit shim config", Err: err}
 	}
@@ -404,3 +451,12 @@ func printMessageError(println func(v ...any), fallback string, err error) {
 
 	println(m)
 }
+
+// printf formats to the logger of msg if one is set, or as a verbose message otherwise.
+func printf(msg message.Msg, format string, v ...any) {
+	if logger := msg.GetLogger(); logger != nil {
+		logger.Printf(format, v...)
+	} else {
+		msg.Verbosef(format, v...)
+	}
+}
@@ -19,6 +19,12 @@ func IsPollDescriptor(fd uintptr) bool
 
 // Main runs an app according to [hst.Config] and terminates. Main does not return.
 func Main(ctx context.Context, msg message.Msg, config *hst.Config, fd int) {
+	MainGenerator(ctx, msg, config, fd, nil)
+}
+
+// MainGenerator is like [Main], but creates the instance [hst.ID] via gen if it is non-nil.
+// MainGenerator does not return.
+func MainGenerator(ctx context.Context, msg message.Msg, config *hst.Config, fd int, gen hst.IDGenerator) {
 	// avoids runtime internals or standard streams
 	if fd >= 0 {
 		if IsPollDescriptor(uintptr(fd)) || fd < 3 {
@@ -27,8 +33,9 @@ func Main(ctx context.Context, msg message.Msg, config *hst.Config, fd int) {
 	}
 
 	var id hst.ID
-	if err := hst.NewInstanceID(&id); err != nil {
-		log.Fatal(err.Error())
+	if err := hst.NewInstanceIDFunc(&id, gen); err != nil {
+		printMessageError(log.Fatalln, "cannot create instance id:", err)
+		panic("unreachable")
 	}
 
 	k := outcome{syscallDispatcher: direct{msg}}
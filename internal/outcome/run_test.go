@@ -2,8 +2,6 @@ package outcome
 
 import (
 	"bytes"
-	"encoding/gob"
-	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -81,17 +79,18 @@ func TestOutcomeMain(t *testing.T) {
 			Link(m("/run/user/1971/pulse/native"), m("/run/user/1971/hakurei/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/pulse")).
 
 			// spDBusOp
+			Ephemeral(system.Process, m("/tmp/hakurei.0/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/dbus"), 0711).
 			MustProxyDBus(
 				hst.Template().SessionBus,
 				hst.Template().SystemBus, dbus.ProxyPair{
 					"unix:path=/run/user/1971/bus",
-					"/tmp/hakurei.0/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/bus",
+					"/tmp/hakurei.0/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/dbus/bus",
 				}, dbus.ProxyPair{
 					"unix:path=/var/run/dbus/system_bus_socket",
-					"/tmp/hakurei.0/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/system_bus_socket",
+					"/tmp/hakurei.0/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/dbus/system_bus_socket",
 				},
-			).UpdatePerm(m("/tmp/hakurei.0/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/bus"), acl.Read, acl.Write).
-			UpdatePerm(m("/tmp/hakurei.0/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/system_bus_socket"), acl.Read, acl.Write).
+			).UpdatePerm(m("/tmp/hakurei.0/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/dbus/bus"), acl.Read, acl.Write).
+			UpdatePerm(m("/tmp/hakurei.0/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/dbus/system_bus_socket"), acl.Read, acl.Write).
 
 			// spFilesystemOp
 			Ensure(m("/var/lib/hakurei/u0"), 0700).
@@ -106,6 +105,7 @@ func TestOutcomeMain(t *testing.T) {
 				"GOOGLE_DEFAULT_CLIENT_ID=77185425430.apps.googleusercontent.com",
 				"GOOGLE_DEFAULT_CLIENT_SECRET=OTJgUOQcT7lO7GsGZq2G4IlT",
 				"HOME=/data/data/org.chromium.Chromium",
+				"LOGNAME=chronos",
 				"PULSE_COOKIE=/.hakurei/pulse-cookie",
 				"PULSE_SERVER=unix:/run/user/1971/pulse/native",
 				"SHELL=/run/current-system/sw/bin/zsh",
@@ -134,6 +134,15 @@ func TestOutcomeMain(t *testing.T) {
 			Uid:          1971,
 			Gid:          100,
 
+			ReadOnlyRoot: true,
+			WritablePaths: []*check.Absolute{
+				m("/data/data/org.chromium.Chromium"),
+				fhs.AbsTmp,
+				fhs.AbsDevShm,
+				hst.AbsPrivateTmp,
+				m("/run/user/1971"),
+			},
+
 			Ops: new(container.Ops).
 				// resolveRoot
 				Root(m("/var/lib/hakurei/base/org.debian"), std.BindWritable).
@@ -154,6 +163,15 @@ func TestOutcomeMain(t *testing.T) {
 				Place(m("/etc/passwd"), []byte("chronos:x:1971:100:Hakurei:/data/data/org.chromium.Chromium:/run/current-system/sw/bin/zsh\n")).
 				Place(m("/etc/group"), []byte("hakurei:x:100:\n")).
 
+				// spMachineIDOp
+				Place(m("/etc/machine-id"), []byte("3fbc13d5632f4dc1a89b0747b3607aff\n")).
+				Place(m("/var/lib/dbus/machine-id"), []byte("3fbc13d5632f4dc1a89b0747b3607aff\n")).
+
+				// spNetworkFilesOp
+				Bind(m("/etc/resolv.conf"), m("/etc/resolv.conf"), std.BindOptional).
+				Bind(m("/etc/hosts"), m("/etc/hosts"), std.BindOptional).
+				Bind(m("/etc/nsswitch.conf"), m("/etc/nsswitch.conf"), std.BindOptional).
+
 				// spWaylandOp
 				Bind(m("/tmp/hakurei.0/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/wayland"), m("/run/user/1971/wayland-0"), 0).
 
@@ -162,8 +180,9 @@ func TestOutcomeMain(t *testing.T) {
 				Place(m("/.hakurei/pulse-cookie"), bytes.Repeat([]byte{0}, pulseCookieSizeMax)).
 
 				// spDBusOp
-				Bind(m("/tmp/hakurei.0/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/bus"), m("/run/user/1971/bus"), 0).
-				Bind(m("/tmp/hakurei.0/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/system_bus_socket"), m("/var/run/dbus/system_bus_socket"), 0).
+				Bind(m("/tmp/hakurei.0/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/dbus"), m("/.hakurei/dbus"), 0).
+				Link(m("/run/user/1971/bus"), "/.hakurei/dbus/bus", false).
+				Link(m("/var/run/dbus/system_bus_socket"), "/.hakurei/dbus/system_bus_socket", false).
 
 				// spFilesystemOp
 				Etc(fhs.AbsEtc, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").
@@ -231,6 +250,7 @@ func TestOutcomeMain(t *testing.T) {
 			Args: []string{"/run/current-system/sw/bin/zsh"},
 			Env: []string{
 				"HOME=/home/chronos",
+				"LOGNAME=chronos",
 				"SHELL=/run/current-system/sw/bin/zsh",
 				"TERM=xterm-256color",
 				"USER=chronos",
@@ -351,6 +371,7 @@ func TestOutcomeMain(t *testing.T) {
 			Ensure(m("/run/user/1971/hakurei"), 0700).UpdatePermType(system.User, m("/run/user/1971/hakurei"), acl.Execute).
 			Ephemeral(system.Process, m("/run/user/1971/hakurei/ebf083d1b175911782d413369b64ce7c"), 0700).UpdatePermType(system.Process, m("/run/user/1971/hakurei/ebf083d1b175911782d413369b64ce7c"), acl.Execute).
 			Link(m("/run/user/1971/pulse/native"), m("/run/user/1971/hakurei/ebf083d1b175911782d413369b64ce7c/pulse")).
+			Ephemeral(system.Process, m("/tmp/hakurei.0/ebf083d1b175911782d413369b64ce7c/dbus"), 0711).
 			MustProxyDBus(&hst.BusConfig{
 				Talk: []string{
 					"org.freedesktop.Notifications",
@@ -382,13 +403,13 @@ func TestOutcomeMain(t *testing.T) {
 				Filter: true,
 			}, dbus.ProxyPair{
 				"unix:path=/run/user/1971/bus",
-				"/tmp/hakurei.0/ebf083d1b175911782d413369b64ce7c/bus",
+				"/tmp/hakurei.0/ebf083d1b175911782d413369b64ce7c/dbus/bus",
 			}, dbus.ProxyPair{
 				"unix:path=/var/run/dbus/system_bus_socket",
-				"/tmp/hakurei.0/ebf083d1b175911782d413369b64ce7c/system_bus_socket",
+				"/tmp/hakurei.0/ebf083d1b175911782d413369b64ce7c/dbus/system_bus_socket",
 			}).
-			UpdatePerm(m("/tmp/hakurei.0/ebf083d1b175911782d413369b64ce7c/bus"), acl.Read, acl.Write).
-			UpdatePerm(m("/tmp/hakurei.0/ebf083d1b175911782d413369b64ce7c/system_bus_socket"), acl.Read, acl.Write), &container.Params{
+			UpdatePerm(m("/tmp/hakurei.0/ebf083d1b175911782d413369b64ce7c/dbus/bus"), acl.Read, acl.Write).
+			UpdatePerm(m("/tmp/hakurei.0/ebf083d1b175911782d413369b64ce7c/dbus/system_bus_socket"), acl.Read, acl.Write), &container.Params{
 
 			Dir:  m("/home/chronos"),
 			Path: m("/run/current-system/sw/bin/zsh"),
@@ -397,6 +418,7 @@ func TestOutcomeMain(t *testing.T) {
 				"DBUS_SESSION_BUS_ADDRESS=unix:path=/run/user/65534/bus",
 				"DBUS_SYSTEM_BUS_ADDRESS=unix:path=/var/run/dbus/system_bus_socket",
 				"HOME=/home/chronos",
+				"LOGNAME=chronos",
 				"PULSE_COOKIE=" + hst.PrivateTmp + "/pulse-cookie",
 				"PULSE_SERVER=unix:/run/user/65534/pulse/native",
 				"SHELL=/run/current-system/sw/bin/zsh",
@@ -421,8 +443,9 @@ func TestOutcomeMain(t *testing.T) {
 				Bind(m("/tmp/hakurei.0/ebf083d1b175911782d413369b64ce7c/wayland"), m("/run/user/65534/wayland-0"), 0).
 				Bind(m("/run/user/1971/hakurei/ebf083d1b175911782d413369b64ce7c/pulse"), m("/run/user/65534/pulse/native"), 0).
 				Place(m(hst.PrivateTmp+"/pulse-cookie"), bytes.Repeat([]byte{0}, pulseCookieSizeMax)).
-				Bind(m("/tmp/hakurei.0/ebf083d1b175911782d413369b64ce7c/bus"), m("/run/user/65534/bus"), 0).
-				Bind(m("/tmp/hakurei.0/ebf083d1b175911782d413369b64ce7c/system_bus_socket"), m("/var/run/dbus/system_bus_socket"), 0).
+				Bind(m("/tmp/hakurei.0/ebf083d1b175911782d413369b64ce7c/dbus"), m("/.hakurei/dbus"), 0).
+				Link(m("/run/user/65534/bus"), "/.hakurei/dbus/bus", false).
+				Link(m("/var/run/dbus/system_bus_socket"), "/.hakurei/dbus/system_bus_socket", false).
 				Bind(m("/dev/dri"), m("/dev/dri"), std.BindWritable|std.BindDevice|std.BindOptional).
 				Bind(m("/dev/kvm"), m("/dev/kvm"), std.BindWritable|std.BindDevice|std.BindOptional).
 				Etc(m("/etc/"), "ebf083d1b175911782d413369b64ce7c").
@@ -505,6 +528,7 @@ func TestOutcomeMain(t *testing.T) {
 			Ephemeral(system.Process, m("/run/user/1971/hakurei/8e2c76b066dabe574cf073bdb46eb5c1"), 0700).UpdatePermType(system.Process, m("/run/user/1971/hakurei/8e2c76b066dabe574cf073bdb46eb5c1"), acl.Execute).
 			Link(m("/run/user/1971/pulse/native"), m("/run/user/1971/hakurei/8e2c76b066dabe574cf073bdb46eb5c1/pulse")).
 			Ephemeral(system.Process, m("/tmp/hakurei.0/8e2c76b066dabe574cf073bdb46eb5c1"), 0711).
+			Ephemeral(system.Process, m("/tmp/hakurei.0/8e2c76b066dabe574cf073bdb46eb5c1/dbus"), 0711).
 			MustProxyDBus(&hst.BusConfig{
 				Talk: []string{
 					"org.freedesktop.FileManager1", "org.freedesktop.Notifications",
@@ -527,13 +551,13 @@ func TestOutcomeMain(t *testing.T) {
 				Filter: true,
 			}, dbus.ProxyPair{
 				"unix:path=/run/user/1971/bus",
-				"/tmp/hakurei.0/8e2c76b066dabe574cf073bdb46eb5c1/bus",
+				"/tmp/hakurei.0/8e2c76b066dabe574cf073bdb46eb5c1/dbus/bus",
 			}, dbus.ProxyPair{
 				"unix:path=/var/run/dbus/system_bus_socket",
-				"/tmp/hakurei.0/8e2c76b066dabe574cf073bdb46eb5c1/system_bus_socket",
+				"/tmp/hakurei.0/8e2c76b066dabe574cf073bdb46eb5c1/dbus/system_bus_socket",
 			}).
-			UpdatePerm(m("/tmp/hakurei.0/8e2c76b066dabe574cf073bdb46eb5c1/bus"), acl.Read, acl.Write).
-			UpdatePerm(m("/tmp/hakurei.0/8e2c76b066dabe574cf073bdb46eb5c1/system_bus_socket"), acl.Read, acl.Write), &container.Params{
+			UpdatePerm(m("/tmp/hakurei.0/8e2c76b066dabe574cf073bdb46eb5c1/dbus/bus"), acl.Read, acl.Write).
+			UpdatePerm(m("/tmp/hakurei.0/8e2c76b066dabe574cf073bdb46eb5c1/dbus/system_bus_socket"), acl.Read, acl.Write), &container.Params{
 
 			Uid:  1971,
 			Gid:  100,
@@ -544,6 +568,7 @@ func TestOutcomeMain(t *testing.T) {
 				"DBUS_SESSION_BUS_ADDRESS=unix:path=/run/user/1971/bus",
 				"DBUS_SYSTEM_BUS_ADDRESS=unix:path=/var/run/dbus/system_bus_socket",
 				"HOME=/var/lib/persist/module/hakurei/0/1",
+				"LOGNAME=u0_a1",
 				"PULSE_COOKIE=" + hst.PrivateTmp + "/pulse-cookie",
 				"PULSE_SERVER=unix:/run/user/1971/pulse/native",
 				"SHELL=/run/current-system/sw/bin/zsh",
@@ -567,8 +592,9 @@ func TestOutcomeMain(t *testing.T) {
 				Bind(m("/run/user/1971/wayland-0"), m("/run/user/1971/wayland-0"), 0).
 				Bind(m("/run/user/1971/hakurei/8e2c76b066dabe574cf073bdb46eb5c1/pulse"), m("/run/user/1971/pulse/native"), 0).
 				Place(m(hst.PrivateTmp+"/pulse-cookie"), bytes.Repeat([]byte{0}, pulseCookieSizeMax)).
-				Bind(m("/tmp/hakurei.0/8e2c76b066dabe574cf073bdb46eb5c1/bus"), m("/run/user/1971/bus"), 0).
-				Bind(m("/tmp/hakurei.0/8e2c76b066dabe574cf073bdb46eb5c1/system_bus_socket"), m("/var/run/dbus/system_bus_socket"), 0).
+				Bind(m("/tmp/hakurei.0/8e2c76b066dabe574cf073bdb46eb5c1/dbus"), m("/.hakurei/dbus"), 0).
+				Link(m("/run/user/1971/bus"), "/.hakurei/dbus/bus", false).
+				Link(m("/var/run/dbus/system_bus_socket"), "/.hakurei/dbus/system_bus_socket", false).
 				Bind(m("/bin"), m("/bin"), 0).
 				Bind(m("/usr/bin/"), m("/usr/bin/"), 0).
 				Bind(m("/nix/store"), m("/nix/store"), 0).
@@ -590,64 +616,67 @@ func TestOutcomeMain(t *testing.T) {
 		}},
 	}
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
+	for transport := range stateTransports {
+		t.Run(transport, func(t *testing.T) {
 			t.Parallel()
-			gr, gw := io.Pipe()
-
-			var gotSys *system.I
-			{
-				sPriv := newOutcomeState(tc.k, msg, &tc.id, tc.config, &Hsu{k: tc.k})
-				if err := sPriv.populateLocal(tc.k, msg); err != nil {
-					t.Fatalf("populateLocal: error = %#v", err)
-				}
-
-				gotSys = system.New(t.Context(), msg, sPriv.uid.unwrap())
-				if err := sPriv.newSys(tc.config, gotSys).toSystem(); err != nil {
-					t.Fatalf("toSystem: error = %#v", err)
-				}
-
-				go func() {
-					e := gob.NewEncoder(gw)
-					if err := errors.Join(e.Encode(&sPriv)); err != nil {
-						t.Errorf("Encode: error = %v", err)
-						panic("unexpected encode fault")
+			for _, tc := range testCases {
+				t.Run(tc.name, func(t *testing.T) {
+					t.Parallel()
+					gr, gw := io.Pipe()
+
+					var gotSys *system.I
+					{
+						sPriv := newOutcomeState(tc.k, msg, &tc.id, tc.config, &Hsu{k: tc.k})
+						if err := sPriv.populateLocal(tc.k, msg); err != nil {
+							t.Fatalf("populateLocal: error = %#v", err)
+						}
+
+						gotSys = system.New(t.Context(), msg, sPriv.uid.unwrap())
+						if err := sPriv.newSys(tc.config, gotSys).toSystem(); err != nil {
+							t.Fatalf("toSystem: error = %#v", err)
+						}
+
+						go func() {
+							if err := writeState(gw, transport, sPriv); err != nil {
+								t.Errorf("writeState: error = %v", err)
+								panic("unexpected encode fault")
+							}
+						}()
 					}
-				}()
-			}
 
-			var gotParams *container.Params
-			{
-				var sShim outcomeState
-
-				d := gob.NewDecoder(gr)
-				if err := errors.Join(d.Decode(&sShim)); err != nil {
-					t.Fatalf("Decode: error = %v", err)
-				}
-				if err := sShim.populateLocal(tc.k, msg); err != nil {
-					t.Fatalf("populateLocal: error = %#v", err)
-				}
-
-				stateParams := sShim.newParams()
-				for _, op := range sShim.Shim.Ops {
-					if err := op.toContainer(stateParams); err != nil {
-						t.Fatalf("toContainer: error = %#v", err)
+					var gotParams *container.Params
+					{
+						var sShim outcomeState
+
+						if err := readState(gr, &sShim); err != nil {
+							t.Fatalf("readState: error = %v", err)
+						}
+						if err := sShim.populateLocal(tc.k, msg); err != nil {
+							t.Fatalf("populateLocal: error = %#v", err)
+						}
+
+						stateParams := sShim.newParams()
+						for _, op := range sShim.Shim.Ops {
+							if err := op.toContainer(stateParams); err != nil {
+								t.Fatalf("toContainer: error = %#v", err)
+							}
+						}
+						gotParams = stateParams.params
 					}
-				}
-				gotParams = stateParams.params
-			}
 
-			t.Run("sys", func(t *testing.T) {
-				if !gotSys.Equal(tc.wantSys) {
-					t.Errorf("toSystem: sys = %#v, want %#v", gotSys, tc.wantSys)
-				}
-			})
-
-			t.Run("params", func(t *testing.T) {
-				if !reflect.DeepEqual(gotParams, tc.wantParams) {
-					t.Errorf("toContainer: params =\n%s\n, want\n%s", mustMarshal(gotParams), mustMarshal(tc.wantParams))
-				}
-			})
+					t.Run("sys", func(t *testing.T) {
+						if !gotSys.Equal(tc.wantSys) {
+							t.Errorf("toSystem: sys = %#v, want %#v", gotSys, tc.wantSys)
+						}
+					})
+
+					t.Run("params", func(t *testing.T) {
+						if !reflect.DeepEqual(gotParams, tc.wantParams) {
+							t.Errorf("toContainer: params =\n%s\n, want\n%s", mustMarshal(gotParams), mustMarshal(tc.wantParams))
+						}
+					})
+				})
+			}
 		})
 	}
 }
@@ -740,6 +769,11 @@ func (k *stubNixOS) stat(name string) (fs.FileInfo, error) {
 		return stubFileInfoIsDir(true), nil
 	case "/home/ophestra/xdg/config/pulse/cookie":
 		return stubFileInfoPulseCookie{false}, nil
+	case "/bin", "/usr/bin/", "/nix/store", "/run/current-system", "/run/opengl-driver":
+		return stubFileInfoIsDir(true), nil
+	case "/etc/vulkan/icd.d", "/usr/share/vulkan/icd.d",
+		"/etc/glvnd/egl_vendor.d", "/usr/share/glvnd/egl_vendor.d":
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: syscall.ENOENT}
 	default:
 		panic(fmt.Sprintf("attempted to stat unexpected path %q", name))
 	}
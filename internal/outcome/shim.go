@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"strings"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -231,6 +232,48 @@ func shimEntrypoint(k syscallDispatcher) {
 		printMessageError(func(v ...any) { k.fatal(fmt.Sprintln(v...)) },
 			"cannot configure container:", err)
 	}
+	{
+		// values may hold credentials such as API keys
+		env := z.Environ()
+		names := make([]string, len(env))
+		for i, v := range env {
+			names[i], _, _ = strings.Cut(v, "=")
+		}
+		msg.Verbosef("initial program environment: %q", names)
+	}
+
+	// listeners are set up before loading the syscall filter, torn down once the container exits
+	var publisher io.Closer
+	if len(stateParams.publishPorts) > 0 {
+		if p, err := k.publishPorts(msg, z, stateParams.publishPorts); err != nil {
+			printMessageError(func(v ...any) { k.fatal(fmt.Sprintln(v...)) },
+				"cannot publish ports:", err)
+		} else {
+			publisher = p
+		}
+	}
+
+	// watcher is also set up before loading the syscall filter, stopped once the container exits
+	var memoryWatcher io.Closer
+	if stateParams.memoryEvents != nil {
+		if w, err := k.watchMemory(msg, stateParams.memoryEvents); err != nil {
+			printMessageError(func(v ...any) { k.fatal(fmt.Sprintln(v...)) },
+				"cannot watch memory events:", err)
+		} else {
+			memoryWatcher = w
+		}
+	}
+
+	// idle monitor cancels the container the same way as an exit request
+	var idleMonitor io.Closer
+	if stateParams.idleCgroup != nil {
+		if w, err := k.watchIdle(msg, stateParams.idleCgroup, state.Container.IdleTimeout, stop); err != nil {
+			printMessageError(func(v ...any) { k.fatal(fmt.Sprintln(v...)) },
+				"cannot monitor container activity:", err)
+		} else {
+			idleMonitor = w
+		}
+	}
 
 	if err := k.seccompLoad(
 		seccomp.Preset(std.PresetStrict, seccomp.AllowMultiarch),
@@ -239,7 +282,23 @@ func shimEntrypoint(k syscallDispatcher) {
 		k.fatalf("cannot load syscall filter: %v", err)
 	}
 
-	if err := k.containerWait(z); err != nil {
+	err := k.containerWait(z)
+	if publisher != nil {
+		if closeErr := publisher.Close(); closeErr != nil {
+			msg.Verbosef("cannot close published ports: %v", closeErr)
+		}
+	}
+	if memoryWatcher != nil {
+		if closeErr := memoryWatcher.Close(); closeErr != nil {
+			msg.Verbosef("cannot stop memory event watcher: %v", closeErr)
+		}
+	}
+	if idleMonitor != nil {
+		if closeErr := idleMonitor.Close(); closeErr != nil {
+			msg.Verbosef("cannot stop idle monitor: %v", closeErr)
+		}
+	}
+	if err != nil {
 		var exitError *exec.ExitError
 		if !errors.As(err, &exitError) {
 			if errors.Is(err, context.Canceled) {
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"hakurei.app/container"
+	"hakurei.app/container/check"
 	"hakurei.app/container/fhs"
 	"hakurei.app/container/seccomp"
 	"hakurei.app/container/std"
@@ -21,6 +22,24 @@ import (
 func TestShimEntrypoint(t *testing.T) {
 	t.Parallel()
 	shimPreset := seccomp.Preset(std.PresetStrict, seccomp.AllowMultiarch)
+	templateEnvNames := []string{
+		"DBUS_SESSION_BUS_ADDRESS",
+		"DBUS_SYSTEM_BUS_ADDRESS",
+		"GOOGLE_API_KEY",
+		"GOOGLE_DEFAULT_CLIENT_ID",
+		"GOOGLE_DEFAULT_CLIENT_SECRET",
+		"HOME",
+		"LOGNAME",
+		"PULSE_COOKIE",
+		"PULSE_SERVER",
+		"SHELL",
+		"TERM",
+		"USER",
+		"WAYLAND_DISPLAY",
+		"XDG_RUNTIME_DIR",
+		"XDG_SESSION_CLASS",
+		"XDG_SESSION_TYPE",
+	}
 	templateParams := &container.Params{
 		Dir: m("/data/data/org.chromium.Chromium"),
 		Env: []string{
@@ -30,6 +49,7 @@ func TestShimEntrypoint(t *testing.T) {
 			"GOOGLE_DEFAULT_CLIENT_ID=77185425430.apps.googleusercontent.com",
 			"GOOGLE_DEFAULT_CLIENT_SECRET=OTJgUOQcT7lO7GsGZq2G4IlT",
 			"HOME=/data/data/org.chromium.Chromium",
+			"LOGNAME=chronos",
 			"PULSE_COOKIE=/.hakurei/pulse-cookie",
 			"PULSE_SERVER=unix:/run/user/1000/pulse/native",
 			"SHELL=/run/current-system/sw/bin/zsh",
@@ -59,6 +79,16 @@ func TestShimEntrypoint(t *testing.T) {
 		Uid:          1000,
 		Gid:          100,
 
+		// spFilesystemOp
+		ReadOnlyRoot: true,
+		WritablePaths: []*check.Absolute{
+			m("/data/data/org.chromium.Chromium"),
+			fhs.AbsTmp,
+			fhs.AbsDevShm,
+			hst.AbsPrivateTmp,
+			m("/run/user/1000"),
+		},
+
 		Ops: new(container.Ops).
 			// resolveRoot
 			Root(m("/var/lib/hakurei/base/org.debian"), std.BindWritable).
@@ -87,8 +117,9 @@ func TestShimEntrypoint(t *testing.T) {
 			Place(m("/.hakurei/pulse-cookie"), bytes.Repeat([]byte{0}, pulseCookieSizeMax)).
 
 			// spDBusOp
-			Bind(m("/tmp/hakurei.10/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/bus"), m("/run/user/1000/bus"), 0).
-			Bind(m("/tmp/hakurei.10/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/system_bus_socket"), m("/var/run/dbus/system_bus_socket"), 0).
+			Bind(m("/tmp/hakurei.10/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/dbus"), m("/.hakurei/dbus"), 0).
+			Link(m("/run/user/1000/bus"), "/.hakurei/dbus/bus", false).
+			Link(m("/var/run/dbus/system_bus_socket"), "/.hakurei/dbus/system_bus_socket", false).
 
 			// spFilesystemOp
 			Etc(fhs.AbsEtc, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").
@@ -401,6 +432,7 @@ func TestShimEntrypoint(t *testing.T) {
 			call("notifyContext", stub.ExpectArgs{context.Background(), []os.Signal{os.Interrupt, syscall.SIGTERM}}, -1, nil),
 			call("containerStart", stub.ExpectArgs{templateParams}, nil, nil),
 			call("containerServe", stub.ExpectArgs{templateParams}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"initial program environment: %q", []any{templateEnvNames}}, nil, nil),
 			call("seccompLoad", stub.ExpectArgs{shimPreset, seccomp.AllowMultiarch}, nil, stub.UniqueError(2)),
 			call("fatalf", stub.ExpectArgs{"cannot load syscall filter: %v", []any{stub.UniqueError(2)}}, nil, nil),
 
@@ -426,6 +458,7 @@ func TestShimEntrypoint(t *testing.T) {
 			call("notifyContext", stub.ExpectArgs{context.Background(), []os.Signal{os.Interrupt, syscall.SIGTERM}}, 0, nil),
 			call("containerStart", stub.ExpectArgs{templateParams}, nil, nil),
 			call("containerServe", stub.ExpectArgs{templateParams}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"initial program environment: %q", []any{templateEnvNames}}, nil, nil),
 			call("seccompLoad", stub.ExpectArgs{shimPreset, seccomp.AllowMultiarch}, nil, nil),
 			call("containerWait", stub.ExpectArgs{templateParams}, nil, makeExitError(1<<8)),
 			call("exit", stub.ExpectArgs{1}, stub.PanicExit, nil),
@@ -452,6 +485,7 @@ func TestShimEntrypoint(t *testing.T) {
 			call("notifyContext", stub.ExpectArgs{context.Background(), []os.Signal{os.Interrupt, syscall.SIGTERM}}, 0, nil),
 			call("containerStart", stub.ExpectArgs{templateParams}, nil, nil),
 			call("containerServe", stub.ExpectArgs{templateParams}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"initial program environment: %q", []any{templateEnvNames}}, nil, nil),
 			call("seccompLoad", stub.ExpectArgs{shimPreset, seccomp.AllowMultiarch}, nil, nil),
 			call("containerWait", stub.ExpectArgs{templateParams}, nil, makeExitError(1<<8)),
 			call("exit", stub.ExpectArgs{1}, stub.PanicExit, nil),
@@ -479,6 +513,7 @@ func TestShimEntrypoint(t *testing.T) {
 			call("notifyContext", stub.ExpectArgs{context.Background(), []os.Signal{os.Interrupt, syscall.SIGTERM}}, -1, nil),
 			call("containerStart", stub.ExpectArgs{templateParams}, nil, nil),
 			call("containerServe", stub.ExpectArgs{templateParams}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"initial program environment: %q", []any{templateEnvNames}}, nil, nil),
 			call("seccompLoad", stub.ExpectArgs{shimPreset, seccomp.AllowMultiarch}, nil, nil),
 			call("containerWait", stub.ExpectArgs{templateParams}, nil, context.Canceled),
 			call("exit", stub.ExpectArgs{hst.ExitCancel}, stub.PanicExit, nil),
@@ -505,6 +540,7 @@ func TestShimEntrypoint(t *testing.T) {
 			call("notifyContext", stub.ExpectArgs{context.Background(), []os.Signal{os.Interrupt, syscall.SIGTERM}}, -1, nil),
 			call("containerStart", stub.ExpectArgs{templateParams}, nil, nil),
 			call("containerServe", stub.ExpectArgs{templateParams}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"initial program environment: %q", []any{templateEnvNames}}, nil, nil),
 			call("seccompLoad", stub.ExpectArgs{shimPreset, seccomp.AllowMultiarch}, nil, nil),
 			call("containerWait", stub.ExpectArgs{templateParams}, nil, stub.UniqueError(0)),
 			call("verbosef", stub.ExpectArgs{"cannot wait: %v", []any{stub.UniqueError(0)}}, nil, nil),
@@ -532,6 +568,7 @@ func TestShimEntrypoint(t *testing.T) {
 			call("notifyContext", stub.ExpectArgs{context.Background(), []os.Signal{os.Interrupt, syscall.SIGTERM}}, -1, nil),
 			call("containerStart", stub.ExpectArgs{templateParams}, nil, nil),
 			call("containerServe", stub.ExpectArgs{templateParams}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"initial program environment: %q", []any{templateEnvNames}}, nil, nil),
 			call("seccompLoad", stub.ExpectArgs{shimPreset, seccomp.AllowMultiarch}, nil, nil),
 			call("containerWait", stub.ExpectArgs{templateParams}, nil, nil),
 
@@ -1,7 +1,6 @@
 package outcome
 
 import (
-	"encoding/gob"
 	"fmt"
 	"syscall"
 
@@ -9,7 +8,7 @@ import (
 	"hakurei.app/internal/validate"
 )
 
-func init() { gob.Register(spAccountOp{}) }
+func init() { registerOp(spAccountOp{}) }
 
 // spAccountOp sets up user account emulation inside the container.
 type spAccountOp struct{}
@@ -37,9 +36,17 @@ func (s spAccountOp) toContainer(state *outcomeStateParams) error {
 	}
 
 	state.params.Dir = state.Container.Home
-	state.env["HOME"] = state.Container.Home.String()
-	state.env["USER"] = username
-	state.env["SHELL"] = state.Container.Shell.String()
+	for key, value := range map[string]string{
+		"HOME":    state.Container.Home.String(),
+		"USER":    username,
+		"LOGNAME": username,
+		"SHELL":   state.Container.Shell.String(),
+	} {
+		// explicitly configured values take precedence
+		if _, ok := state.Container.Env[key]; !ok {
+			state.env[key] = value
+		}
+	}
 
 	state.params.
 		Place(fhs.AbsEtc.Append("passwd"),
@@ -1,7 +1,9 @@
 package outcome
 
 import (
+	"maps"
 	"os"
+	"strings"
 	"syscall"
 	"testing"
 
@@ -49,10 +51,11 @@ func TestSpAccountOp(t *testing.T) {
 				Place(m("/etc/passwd"), []byte("chronos:x:1000:100:Hakurei:/data/data/org.chromium.Chromium:/run/current-system/sw/bin/zsh\n")).
 				Place(m("/etc/group"), []byte("hakurei:x:100:\n")),
 		}, paramsWantEnv(config, map[string]string{
-			"HOME":  config.Container.Home.String(),
-			"USER":  config.Container.Username,
-			"SHELL": config.Container.Shell.String(),
-		}, nil), nil},
+			"HOME":    config.Container.Home.String(),
+			"USER":    config.Container.Username,
+			"LOGNAME": config.Container.Username,
+			"SHELL":   config.Container.Shell.String(),
+		}, checkPasswdHome), nil},
 
 		{"success", func(bool, bool) outcomeOp { return spAccountOp{} }, hst.Template, nil, []stub.Call{
 			// this op performs basic validation and does not make calls during toSystem
@@ -64,9 +67,55 @@ func TestSpAccountOp(t *testing.T) {
 				Place(m("/etc/passwd"), []byte("chronos:x:1000:100:Hakurei:/data/data/org.chromium.Chromium:/run/current-system/sw/bin/zsh\n")).
 				Place(m("/etc/group"), []byte("hakurei:x:100:\n")),
 		}, paramsWantEnv(config, map[string]string{
-			"HOME":  config.Container.Home.String(),
-			"USER":  config.Container.Username,
-			"SHELL": config.Container.Shell.String(),
-		}, nil), nil},
+			"HOME":    config.Container.Home.String(),
+			"USER":    config.Container.Username,
+			"LOGNAME": config.Container.Username,
+			"SHELL":   config.Container.Shell.String(),
+		}, checkPasswdHome), nil},
+
+		{"success env override", func(bool, bool) outcomeOp { return spAccountOp{} }, func() *hst.Config {
+			c := hst.Template()
+			c.Container.Env = map[string]string{"SHELL": "/bin/sh", "LOGNAME": "root"}
+			return c
+		}, nil, []stub.Call{
+			// this op performs basic validation and does not make calls during toSystem
+		}, newI(), nil, nil, insertsOps(nil), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Dir: config.Container.Home,
+			Ops: new(container.Ops).
+				Place(m("/etc/passwd"), []byte("chronos:x:1000:100:Hakurei:/data/data/org.chromium.Chromium:/run/current-system/sw/bin/zsh\n")).
+				Place(m("/etc/group"), []byte("hakurei:x:100:\n")),
+		}, func(t *testing.T, state *outcomeStateParams) {
+			want := map[string]string{
+				"HOME":    config.Container.Home.String(),
+				"USER":    config.Container.Username,
+				"LOGNAME": "root",
+				"SHELL":   "/bin/sh",
+			}
+			if !maps.Equal(state.env, want) {
+				t.Errorf("toContainer: env = %#v, want %#v", state.env, want)
+			}
+			checkPasswdHome(t, state)
+		}, nil},
 	})
 }
+
+// checkPasswdHome checks that HOME matches the home directory of the emulated passwd entry.
+func checkPasswdHome(t *testing.T, state *outcomeStateParams) {
+	t.Helper()
+
+	for _, op := range *state.params.Ops {
+		if place, ok := op.(*container.TmpfileOp); ok && place.Path.String() == "/etc/passwd" {
+			fields := strings.Split(strings.TrimSpace(string(place.Data)), ":")
+			if len(fields) != 7 {
+				t.Fatalf("toContainer: passwd = %q", place.Data)
+			}
+			if home := state.env["HOME"]; home != fields[5] {
+				t.Errorf("toContainer: HOME = %q, passwd home = %q", home, fields[5])
+			}
+			return
+		}
+	}
+	t.Errorf("toContainer: passwd not placed")
+}
@@ -1,17 +1,31 @@
 package outcome
 
 import (
-	"encoding/gob"
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 
 	"hakurei.app/container/check"
+	"hakurei.app/container/fhs"
 	"hakurei.app/hst"
 	"hakurei.app/internal/system"
+	"hakurei.app/message"
 )
 
-func init() { gob.Register(new(spCgroupOp)) }
+func init() { registerOp(new(spCgroupOp)) }
+
+// cpuinfoPath is the pathname of cpuinfo in both the host and the container.
+var cpuinfoPath = fhs.AbsProc.Append("cpuinfo")
 
 type spCgroupOp struct {
 	Path string
+	// Synthesized cpuinfo, or nil to keep the host cpuinfo.
+	CPUInfo []byte
 }
 
 func (s *spCgroupOp) toSystem(state *outcomeStateSys) error {
@@ -28,11 +42,33 @@ func (s *spCgroupOp) toSystem(state *outcomeStateSys) error {
 		return err
 	}
 
-	state.sys.Cgroup(slicePath, instancePath, system.CgroupLimits{
-		CPU:    state.Container.Cgroup.LimitCPU,
-		Memory: state.Container.Cgroup.LimitMemory,
-		Pids:   state.Container.Cgroup.LimitPids,
-	})
+	// accounting mode only places the process in the instance cgroup
+	var limits system.CgroupLimits
+	if !state.Container.Cgroup.Accounting {
+		limits = system.CgroupLimits{
+			CPU:           state.Container.Cgroup.LimitCPU,
+			CPUWeight:     state.Container.Cgroup.CPUWeight,
+			Memory:        state.Container.Cgroup.LimitMemory,
+			MemorySwapMax: state.Container.Cgroup.MemorySwapMax,
+			MemoryLow:     state.Container.Cgroup.MemoryLow,
+			Pids:          state.Container.Cgroup.LimitPids,
+			IOMax:         state.Container.Cgroup.LimitIO,
+			CPUSet:        state.Container.Cgroup.CPUSet,
+		}
+	}
+	limits.Persist = state.Container.Cgroup.Persist
+	limits.EnableControllers = state.Container.Cgroup.EnableControllers
+
+	if state.Container.Cgroup.CPUInfo {
+		if s.CPUInfo, err = synthCPUInfo(state.k, slicePath, state.Container.Cgroup.CPUSet); err != nil {
+			return err
+		}
+		if s.CPUInfo == nil {
+			state.msg.Verbose("cpuset not available, keeping host " + cpuinfoPath.String())
+		}
+	}
+
+	state.sys.Cgroup(slicePath, instancePath, limits)
 
 	s.Path = instancePath.String()
 	return nil
@@ -47,5 +83,170 @@ func (s *spCgroupOp) toContainer(state *outcomeStateParams) error {
 		return &hst.AppError{Step: "parse cgroup path", Err: err}
 	}
 	state.params.CgroupPath = pathname
+	if state.Container.Cgroup != nil && state.Container.Cgroup.MemoryEvents {
+		state.memoryEvents = pathname
+	}
+	if state.Container.IdleTimeout > 0 {
+		state.idleCgroup = pathname
+	}
+
+	if s.CPUInfo != nil {
+		state.params.Place(cpuinfoPath, s.CPUInfo)
+	}
+	return nil
+}
+
+// synthCPUInfo returns the host cpuinfo filtered to processors in the effective cpuset of slice,
+// further restricted to the cpu list pinned if non-empty. A nil slice is returned if the cpuset
+// controller is not available.
+func synthCPUInfo(k syscallDispatcher, slice *check.Absolute, pinned string) ([]byte, error) {
+	pathname := slice.Append("cpuset.cpus.effective").String()
+	effective, err := readAll(k, pathname)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, &hst.AppError{Step: "read cpuset", Err: err}
+	}
+
+	list := strings.TrimSpace(string(effective))
+	if list == "" {
+		return nil, nil
+	}
+	cpus, err := parseCPUList(list)
+	if err != nil {
+		return nil, newWithMessageError("invalid cpuset at "+strconv.Quote(pathname), err)
+	}
+	if pinned != "" {
+		var pinnedCPUs map[int]bool
+		if pinnedCPUs, err = parseCPUList(pinned); err != nil {
+			return nil, newWithMessageError("invalid cgroup cpuset "+strconv.Quote(pinned), err)
+		}
+		maps.DeleteFunc(cpus, func(cpu int, _ bool) bool { return !pinnedCPUs[cpu] })
+	}
+
+	var data []byte
+	if data, err = readAll(k, cpuinfoPath.String()); err != nil {
+		return nil, &hst.AppError{Step: "read cpuinfo", Err: err}
+	}
+	return filterCPUInfo(data, cpus), nil
+}
+
+// cpuOnlinePath is the pathname of the list of online CPUs.
+var cpuOnlinePath = fhs.AbsSys.Append("devices/system/cpu/online")
+
+// checkCPUSet checks the format of cpuset against CPUs online on the host.
+func checkCPUSet(k syscallDispatcher, cpuset string) error {
+	cpus, err := parseCPUList(cpuset)
+	if err != nil {
+		return &hst.AppError{Step: "resolve configuration", Err: err,
+			Msg: "invalid cgroup cpuset " + strconv.Quote(cpuset)}
+	}
+
+	var online map[int]bool
+	if data, err := readAll(k, cpuOnlinePath.String()); err != nil {
+		return &hst.AppError{Step: "resolve configuration", Err: err,
+			Msg: "cannot determine online CPUs"}
+	} else if online, err = parseCPUList(strings.TrimSpace(string(data))); err != nil {
+		return &hst.AppError{Step: "resolve configuration", Err: err,
+			Msg: "invalid online CPU list " + strconv.Quote(string(data))}
+	}
+	for _, cpu := range slices.Sorted(maps.Keys(cpus)) {
+		if !online[cpu] {
+			return &hst.AppError{Step: "resolve configuration", Err: syscall.EINVAL,
+				Msg: "cgroup cpuset CPU " + strconv.Itoa(cpu) + " is not online"}
+		}
+	}
 	return nil
 }
+
+// parseCPUList parses a cpu list in the format used by cpuset, e.g. "0-3,7".
+func parseCPUList(list string) (map[int]bool, error) {
+	cpus := make(map[int]bool)
+	for _, field := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(field, "-")
+		lo, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, err
+		}
+		hi := lo
+		if isRange {
+			if hi, err = strconv.Atoi(last); err != nil {
+				return nil, err
+			}
+		}
+		if lo < 0 || hi < lo {
+			return nil, syscall.EINVAL
+		}
+
+		for i := lo; i <= hi; i++ {
+			cpus[i] = true
+		}
+	}
+	return cpus, nil
+}
+
+// readAll reads the entire file at pathname.
+func readAll(k syscallDispatcher, pathname string) ([]byte, error) {
+	f, err := k.open(pathname)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return data, err
+}
+
+// filterCPUInfo returns cpuinfo with processor entries not present in cpus removed.
+// Entries not describing a processor are retained.
+func filterCPUInfo(data []byte, cpus map[int]bool) []byte {
+	var buf strings.Builder
+	buf.Grow(len(data))
+	for _, entry := range strings.SplitAfter(string(data), "\n\n") {
+		if id, ok := cpuinfoProcessor(entry); ok && !cpus[id] {
+			continue
+		}
+		buf.WriteString(entry)
+	}
+	return []byte(buf.String())
+}
+
+// cpuinfoProcessor returns the value of the processor field of a cpuinfo entry.
+func cpuinfoProcessor(entry string) (int, bool) {
+	for line := range strings.Lines(entry) {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(key) != "processor" {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(value))
+		return id, err == nil
+	}
+	return -1, false
+}
+
+// memoryEventLogger logs events delivered by [hst.MemoryWatcher] until it is closed.
+type memoryEventLogger struct {
+	w    *hst.MemoryWatcher
+	done chan struct{}
+}
+
+// logMemoryEvents logs events delivered by w on a separate goroutine.
+// Closing the returned memoryEventLogger closes w and waits for that goroutine to return.
+func logMemoryEvents(msg message.Msg, w *hst.MemoryWatcher) *memoryEventLogger {
+	l := &memoryEventLogger{w, make(chan struct{})}
+	go func() {
+		defer close(l.done)
+		for ev := range w.Events() {
+			printf(msg, "memory event %q in instance cgroup, count %d", ev.Name, ev.Count)
+		}
+	}()
+	return l
+}
+
+func (l *memoryEventLogger) Close() error {
+	err := l.w.Close()
+	<-l.done
+	return err
+}
@@ -0,0 +1,209 @@
+package outcome
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"hakurei.app/container"
+	"hakurei.app/container/stub"
+	"hakurei.app/hst"
+	"hakurei.app/internal/system"
+)
+
+func TestSpCgroupOp(t *testing.T) {
+	t.Parallel()
+
+	const (
+		slice    = "/sys/fs/cgroup/hakurei.slice"
+		instance = slice + "/hakurei-9/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+		sampleCPUInfo = "processor\t: 0\nvendor_id\t: GenuineIntel\n\n" +
+			"processor\t: 1\nvendor_id\t: GenuineIntel\n\n" +
+			"processor\t: 2\nvendor_id\t: GenuineIntel\n\n" +
+			"processor\t: 3\nvendor_id\t: GenuineIntel\n\n"
+		wantCPUInfo = "processor\t: 1\nvendor_id\t: GenuineIntel\n\n" +
+			"processor\t: 3\nvendor_id\t: GenuineIntel\n\n"
+		wantCPUInfoPinned = "processor\t: 3\nvendor_id\t: GenuineIntel\n\n"
+	)
+
+	config := func(cpuinfo bool) func() *hst.Config {
+		return func() *hst.Config {
+			c := hst.Template()
+			c.Container.Cgroup = &hst.CgroupConfig{CPUWeight: 200, MemorySwapMax: 1 << 30, MemoryLow: 1 << 28, LimitPids: 64, LimitIO: map[string]hst.CgroupIOLimit{"8:0": {WBPS: 1 << 20}}, CPUInfo: cpuinfo}
+			return c
+		}
+	}
+
+	checkOpBehaviour(t, []opBehaviourTestCase{
+		{"not enabled", func(bool, bool) outcomeOp {
+			return new(spCgroupOp)
+		}, hst.Template, nil, nil, nil, nil, errNotEnabled, nil, nil, nil, nil, nil},
+
+		{"cpuset open", func(bool, bool) outcomeOp {
+			return new(spCgroupOp)
+		}, config(true), nil, []stub.Call{
+			call("open", stub.ExpectArgs{slice + "/cpuset.cpus.effective"}, (*stubOsFile)(nil), stub.UniqueError(1)),
+		}, nil, nil, &hst.AppError{Step: "read cpuset", Err: stub.UniqueError(1)}, nil, nil, nil, nil, nil},
+
+		{"cpuset invalid", func(bool, bool) outcomeOp {
+			return new(spCgroupOp)
+		}, config(true), nil, []stub.Call{
+			call("open", stub.ExpectArgs{slice + "/cpuset.cpus.effective"}, &stubOsFile{Reader: bytes.NewReader([]byte("3-1\n"))}, nil),
+		}, nil, nil, newWithMessageError("invalid cpuset at "+strconv.Quote(slice+"/cpuset.cpus.effective"), syscall.EINVAL), nil, nil, nil, nil, nil},
+
+		{"cpuinfo close", func(bool, bool) outcomeOp {
+			return new(spCgroupOp)
+		}, config(true), nil, []stub.Call{
+			call("open", stub.ExpectArgs{slice + "/cpuset.cpus.effective"}, &stubOsFile{Reader: bytes.NewReader([]byte("1,3\n"))}, nil),
+			call("open", stub.ExpectArgs{"/proc/cpuinfo"}, &stubOsFile{closeErr: stub.UniqueError(0), Reader: bytes.NewReader([]byte(sampleCPUInfo))}, nil),
+		}, nil, nil, &hst.AppError{Step: "read cpuinfo", Err: stub.UniqueError(0)}, nil, nil, nil, nil, nil},
+
+		{"success", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spCgroupOp)
+			}
+			return &spCgroupOp{Path: instance}
+		}, config(false), nil, nil, newI().
+			Cgroup(m(slice), m(instance), system.CgroupLimits{CPUWeight: 200, MemorySwapMax: 1 << 30, MemoryLow: 1 << 28, Pids: 64, IOMax: map[string]hst.CgroupIOLimit{"8:0": {WBPS: 1 << 20}}}), nil, nil, insertsOps(nil), nil, &container.Params{
+			CgroupPath: m(instance),
+			Ops:        new(container.Ops),
+		}, nil, nil},
+
+		{"success cpuset unavailable", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spCgroupOp)
+			}
+			return &spCgroupOp{Path: instance}
+		}, config(true), nil, []stub.Call{
+			call("open", stub.ExpectArgs{slice + "/cpuset.cpus.effective"}, (*stubOsFile)(nil), &os.PathError{Op: "open", Path: slice + "/cpuset.cpus.effective", Err: syscall.ENOENT}),
+			call("verbose", stub.ExpectArgs{[]any{"cpuset not available, keeping host /proc/cpuinfo"}}, nil, nil),
+		}, newI().
+			Cgroup(m(slice), m(instance), system.CgroupLimits{CPUWeight: 200, MemorySwapMax: 1 << 30, MemoryLow: 1 << 28, Pids: 64, IOMax: map[string]hst.CgroupIOLimit{"8:0": {WBPS: 1 << 20}}}), nil, nil, insertsOps(nil), nil, &container.Params{
+			CgroupPath: m(instance),
+			Ops:        new(container.Ops),
+		}, nil, nil},
+
+		{"success cpuinfo", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spCgroupOp)
+			}
+			return &spCgroupOp{Path: instance, CPUInfo: []byte(wantCPUInfo)}
+		}, config(true), nil, []stub.Call{
+			call("open", stub.ExpectArgs{slice + "/cpuset.cpus.effective"}, &stubOsFile{Reader: bytes.NewReader([]byte("1,3\n"))}, nil),
+			call("open", stub.ExpectArgs{"/proc/cpuinfo"}, &stubOsFile{Reader: bytes.NewReader([]byte(sampleCPUInfo))}, nil),
+		}, newI().
+			Cgroup(m(slice), m(instance), system.CgroupLimits{CPUWeight: 200, MemorySwapMax: 1 << 30, MemoryLow: 1 << 28, Pids: 64, IOMax: map[string]hst.CgroupIOLimit{"8:0": {WBPS: 1 << 20}}}), nil, nil, insertsOps(nil), nil, &container.Params{
+			CgroupPath: m(instance),
+			Ops:        new(container.Ops).Place(m("/proc/cpuinfo"), []byte(wantCPUInfo)),
+		}, nil, nil},
+
+		{"success cpuinfo cpuset", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spCgroupOp)
+			}
+			return &spCgroupOp{Path: instance, CPUInfo: []byte(wantCPUInfoPinned)}
+		}, func() *hst.Config {
+			c := config(true)()
+			c.Container.Cgroup.CPUSet = "0,3"
+			return c
+		}, nil, []stub.Call{
+			call("open", stub.ExpectArgs{slice + "/cpuset.cpus.effective"}, &stubOsFile{Reader: bytes.NewReader([]byte("1,3\n"))}, nil),
+			call("open", stub.ExpectArgs{"/proc/cpuinfo"}, &stubOsFile{Reader: bytes.NewReader([]byte(sampleCPUInfo))}, nil),
+		}, newI().
+			Cgroup(m(slice), m(instance), system.CgroupLimits{CPUWeight: 200, MemorySwapMax: 1 << 30, MemoryLow: 1 << 28, Pids: 64, IOMax: map[string]hst.CgroupIOLimit{"8:0": {WBPS: 1 << 20}}, CPUSet: "0,3"}), nil, nil, insertsOps(nil), nil, &container.Params{
+			CgroupPath: m(instance),
+			Ops:        new(container.Ops).Place(m("/proc/cpuinfo"), []byte(wantCPUInfoPinned)),
+		}, nil, nil},
+
+		{"success accounting persist", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spCgroupOp)
+			}
+			return &spCgroupOp{Path: instance}
+		}, func() *hst.Config {
+			c := hst.Template()
+			c.Container.Cgroup = &hst.CgroupConfig{Accounting: true, Persist: true}
+			return c
+		}, nil, nil, newI().
+			Cgroup(m(slice), m(instance), system.CgroupLimits{Persist: true}), nil, nil, insertsOps(nil), nil, &container.Params{
+			CgroupPath: m(instance),
+			Ops:        new(container.Ops),
+		}, nil, nil},
+
+		{"success memory events", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spCgroupOp)
+			}
+			return &spCgroupOp{Path: instance}
+		}, func() *hst.Config {
+			c := hst.Template()
+			c.Container.Cgroup = &hst.CgroupConfig{Accounting: true, MemoryEvents: true}
+			return c
+		}, nil, nil, newI().
+			Cgroup(m(slice), m(instance), system.CgroupLimits{}), nil, nil, insertsOps(nil), nil, &container.Params{
+			CgroupPath: m(instance),
+			Ops:        new(container.Ops),
+		}, func(t *testing.T, state *outcomeStateParams) {
+			if state.memoryEvents == nil || state.memoryEvents.String() != instance {
+				t.Errorf("toContainer: memoryEvents = %v, want %s", state.memoryEvents, instance)
+			}
+			if state.idleCgroup != nil {
+				t.Errorf("toContainer: idleCgroup = %v, want nil", state.idleCgroup)
+			}
+		}, nil},
+
+		{"success idle timeout", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spCgroupOp)
+			}
+			return &spCgroupOp{Path: instance}
+		}, func() *hst.Config {
+			c := hst.Template()
+			c.Container.IdleTimeout = time.Minute
+			c.Container.Cgroup = &hst.CgroupConfig{Accounting: true}
+			return c
+		}, nil, nil, newI().
+			Cgroup(m(slice), m(instance), system.CgroupLimits{}), nil, nil, insertsOps(nil), nil, &container.Params{
+			CgroupPath: m(instance),
+			Ops:        new(container.Ops),
+		}, func(t *testing.T, state *outcomeStateParams) {
+			if state.idleCgroup == nil || state.idleCgroup.String() != instance {
+				t.Errorf("toContainer: idleCgroup = %v, want %s", state.idleCgroup, instance)
+			}
+		}, nil},
+	})
+}
+
+func TestParseCPUList(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		list    string
+		want    map[int]bool
+		wantErr error
+	}{
+		{"single", "7", map[int]bool{7: true}, nil},
+		{"range", "0-3,7", map[int]bool{0: true, 1: true, 2: true, 3: true, 7: true}, nil},
+		{"reversed", "3-1", nil, syscall.EINVAL},
+		{"invalid", "0-a", nil, &strconv.NumError{Func: "Atoi", Num: "a", Err: strconv.ErrSyntax}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseCPUList(tc.list)
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("parseCPUList: error = %v, want %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseCPUList: %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
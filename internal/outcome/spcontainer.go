@@ -1,13 +1,14 @@
 package outcome
 
 import (
-	"encoding/gob"
 	"errors"
 	"io/fs"
+	"maps"
 	"os"
 	"path"
 	"slices"
 	"strconv"
+	"strings"
 	"syscall"
 
 	"hakurei.app/container"
@@ -18,6 +19,7 @@ import (
 	"hakurei.app/hst"
 	"hakurei.app/internal/acl"
 	"hakurei.app/internal/dbus"
+	"hakurei.app/internal/env"
 	"hakurei.app/internal/system"
 	"hakurei.app/internal/validate"
 	"hakurei.app/message"
@@ -25,7 +27,7 @@ import (
 
 const varRunNscd = fhs.Var + "run/nscd"
 
-func init() { gob.Register(new(spParamsOp)) }
+func init() { registerOp(new(spParamsOp)) }
 
 // spParamsOp initialises unordered fields of [container.Params] and the optional root filesystem.
 // This outcomeOp is hardcoded to always run first.
@@ -66,6 +68,10 @@ func (s *spParamsOp) toContainer(state *outcomeStateParams) error {
 	} else {
 		state.params.Args = state.Container.Args
 	}
+	if state.Container.LoginShell && state.Container.Path.Is(state.Container.Shell) {
+		// shells only source profile files when argv[0] begins with a hyphen
+		state.params.Args = append([]string{"-" + state.params.Args[0]}, state.params.Args[1:]...)
+	}
 
 	// the container is canceled when shim is requested to exit or receives an interrupt or termination signal;
 	// this behaviour is implemented in the shim
@@ -88,6 +94,31 @@ func (s *spParamsOp) toContainer(state *outcomeStateParams) error {
 		state.params.SeccompPresets |= std.PresetDenyTTY
 	}
 
+	switch state.Container.SeccompAction {
+	case "":
+	case hst.SeccompActionENOSYS:
+		state.params.SeccompKill = seccomp.DenyENOSYS
+	case hst.SeccompActionTrap:
+		state.params.SeccompKill = seccomp.KillTrap
+	case hst.SeccompActionKillThread:
+		state.params.SeccompKill = seccomp.KillThread
+	case hst.SeccompActionKillProcess:
+		state.params.SeccompKill = seccomp.KillProcess
+	default:
+		return newWithMessageError("invalid seccomp action "+strconv.Quote(state.Container.SeccompAction), hst.ErrSeccompAction)
+	}
+
+	if len(state.Container.DenySocketFamilies) > 0 {
+		state.params.SeccompDenySocket = make([]std.ScmpDatum, len(state.Container.DenySocketFamilies))
+		for i, name := range state.Container.DenySocketFamilies {
+			if family, ok := std.SocketFamilyResolveName(name); !ok {
+				return newWithMessageError("invalid socket family "+strconv.Quote(name), std.SocketFamilyNameError(name))
+			} else {
+				state.params.SeccompDenySocket[i] = family
+			}
+		}
+	}
+
 	if state.Container.Flags&hst.FMapRealUID != 0 {
 		state.params.Uid = state.Mapuid
 		state.params.Gid = state.Mapgid
@@ -100,7 +131,10 @@ func (s *spParamsOp) toContainer(state *outcomeStateParams) error {
 		state.as.Ops = opsAdapter{&ops}
 	}
 
-	rootfs, filesystem, _ := resolveRoot(state.Container)
+	rootfs, filesystem, _, err := resolveRoot(state.Container, state.HomePath)
+	if err != nil {
+		return err
+	}
 	state.filesystem = filesystem
 	if rootfs != nil {
 		rootfs.Apply(&state.as)
@@ -109,28 +143,66 @@ func (s *spParamsOp) toContainer(state *outcomeStateParams) error {
 	// early mount points
 	state.params.
 		Proc(fhs.AbsProc).
-		Tmpfs(hst.AbsPrivateTmp, 1<<12, 0755)
+		Tmpfs(state.Container.PrivateTmpDir(), 1<<12, 0755)
 	if state.Container.Flags&hst.FDevice == 0 {
 		state.params.DevWritable(fhs.AbsDev, true)
 	} else {
 		state.params.Bind(fhs.AbsDev, fhs.AbsDev, std.BindWritable|std.BindDevice)
 	}
 	// /dev is mounted readonly later on, this prevents /dev/shm from going readonly with it
-	state.params.Tmpfs(fhs.AbsDevShm, 0, 01777)
+	state.params.Tmpfs(fhs.AbsDevShm, int(state.Container.DevShmSize), 01777)
 
 	return nil
 }
 
-func init() { gob.Register(new(spFilesystemOp)) }
+func init() { registerOp(new(spFilesystemOp)) }
 
 // spFilesystemOp applies configured filesystems to [container.Params], excluding the optional root filesystem.
 // This outcomeOp is hardcoded to always run last.
 type spFilesystemOp struct {
 	// Matched paths to cover. Stored during toSystem.
 	HidePaths []*check.Absolute
+	// Host environment variables referenced by [hst.ContainerConfig.Env]. Stored during toSystem.
+	EnvHost map[string]string
+	// Host environment variables matching [hst.ContainerConfig.PassEnv]. Stored during toSystem.
+	EnvPass map[string]string
 }
 
 func (s *spFilesystemOp) toSystem(state *outcomeStateSys) error {
+	// only variables referenced in configured values are copied from the host environment,
+	// they are expanded in the shim to avoid leaking anything else into the container
+	for _, key := range slices.Sorted(maps.Keys(state.Container.Env)) {
+		refs, err := env.References(state.Container.Env[key])
+		if err != nil {
+			return newWithMessageError("invalid value of environment variable "+strconv.Quote(key), err)
+		}
+		for _, ref := range refs {
+			if _, ok := s.EnvHost[ref]; ok {
+				continue
+			}
+			if value, ok := state.k.lookupEnv(ref); ok {
+				if s.EnvHost == nil {
+					s.EnvHost = make(map[string]string)
+				}
+				s.EnvHost[ref] = value
+			}
+		}
+	}
+
+	// variables passed through are likewise collected here and copied in the shim
+	if len(state.Container.PassEnv) > 0 {
+		for _, kv := range state.k.environ() {
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok || !matchPassEnv(state.Container.PassEnv, key) {
+				continue
+			}
+			if s.EnvPass == nil {
+				s.EnvPass = make(map[string]string)
+			}
+			s.EnvPass[key] = value
+		}
+	}
+
 	/* retrieve paths and hide them if they're made available in the sandbox;
 
 	this feature tries to improve user experience of permissive defaults, and
@@ -181,7 +253,10 @@ func (s *spFilesystemOp) toSystem(state *outcomeStateSys) error {
 		}
 	}
 
-	_, filesystem, autoroot := resolveRoot(state.Container)
+	_, filesystem, autoroot, err := resolveRoot(state.Container, state.HomePath)
+	if err != nil {
+		return err
+	}
 
 	var hidePathSourceCount int
 	for i, c := range filesystem {
@@ -193,6 +268,21 @@ func (s *spFilesystemOp) toSystem(state *outcomeStateSys) error {
 		hidePathSourceCount += len(c.Host())
 	}
 
+	// fail early for read-only bind mounts of missing sources, which would otherwise
+	// only surface as an opaque mount failure during container setup
+	for _, c := range filesystem {
+		if b, ok := c.FilesystemConfig.(*hst.FSBind); ok && b.IsReadOnly() &&
+			!b.Special && !b.Optional && !b.Ensure {
+			if _, err = state.k.stat(b.Source.String()); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return &hst.AppError{Step: "access bind source", Err: err,
+						Msg: "bind source " + strconv.Quote(b.Source.String()) + " does not exist"}
+				}
+				return &hst.AppError{Step: "access bind source", Err: err}
+			}
+		}
+	}
+
 	// AutoRootOp is a collection of many BindMountOp internally
 	var autoRootEntries []fs.DirEntry
 	if autoroot != nil {
@@ -281,11 +371,53 @@ func (s *spFilesystemOp) toContainer(state *outcomeStateParams) error {
 		state.params.Tmpfs(a, 1<<13, 0755)
 	}
 
+	state.runParamsHooks()
+
 	// no more configured paths beyond this point
 	if state.Container.Flags&hst.FDevice == 0 {
 		state.params.Remount(fhs.AbsDev, syscall.MS_RDONLY)
 	}
 	state.params.Remount(fhs.AbsRoot, syscall.MS_RDONLY)
+	if state.Container.Flags&hst.FReadOnlyRoot != 0 {
+		state.params.ReadOnlyRoot = true
+		state.params.WritablePaths = []*check.Absolute{
+			state.Container.Home,
+			fhs.AbsTmp,
+			fhs.AbsDevShm,
+			state.Container.PrivateTmpDir(),
+		}
+		if state.runtimeDir != nil {
+			state.params.WritablePaths = append(state.params.WritablePaths, state.runtimeDir)
+		}
+	}
+
+	// only configured values not replaced by another outcomeOp are expanded
+	strict := state.Container.Flags&hst.FEnvStrict != 0
+	for key, value := range state.Container.Env {
+		if v, ok := state.env[key]; !ok || v != value {
+			continue
+		}
+		if v, err := env.Expand(value, func(ref string) (string, bool) {
+			v, ok := s.EnvHost[ref]
+			return v, ok || !strict
+		}); err != nil {
+			return newWithMessageError("cannot expand environment variable "+strconv.Quote(key), err)
+		} else {
+			state.env[key] = v
+		}
+	}
+
+	// passed through variables never replace a value set by configuration or another outcomeOp
+	for key, value := range s.EnvPass {
+		if _, ok := state.env[key]; !ok {
+			state.env[key] = value
+		}
+	}
+
+	// scrubbed last to take precedence over every other source
+	for _, key := range state.Container.EnvScrub {
+		delete(state.env, key)
+	}
 
 	state.params.Env = make([]string, 0, len(state.env))
 	for key, value := range state.env {
@@ -297,11 +429,25 @@ func (s *spFilesystemOp) toContainer(state *outcomeStateParams) error {
 	return nil
 }
 
+// matchPassEnv returns whether key matches any pattern in [hst.ContainerConfig.PassEnv].
+func matchPassEnv(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		// patterns validated via hst
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // resolveRoot handles the root filesystem special case for [hst.FilesystemConfig] and additionally resolves autoroot
-// as it requires special handling during path hiding.
-func resolveRoot(c *hst.ContainerConfig) (rootfs hst.FilesystemConfig, filesystem []hst.FilesystemConfigJSON, autoroot *hst.FSBind) {
+// as it requires special handling during path hiding. Home relative pathnames are expanded beforehand.
+func resolveRoot(c *hst.ContainerConfig, hostHome *check.Absolute) (rootfs hst.FilesystemConfig, filesystem []hst.FilesystemConfigJSON, autoroot *hst.FSBind, err error) {
+	if filesystem, err = expandHome(c, hostHome); err != nil {
+		return
+	}
+
 	// root filesystem special case
-	filesystem = c.Filesystem
 	// valid happens late, so root gets it here
 	if len(filesystem) > 0 && filesystem[0].Valid() && filesystem[0].Path().String() == fhs.Root {
 		// if the first element targets /, it is inserted early and excluded from path hiding
@@ -316,6 +462,31 @@ func resolveRoot(c *hst.ContainerConfig) (rootfs hst.FilesystemConfig, filesyste
 	return
 }
 
+// expandHome returns the filesystem of c with home relative pathnames of [hst.FSBind] expanded
+// against the container and host home directories. The configured slice is copied before
+// modification and is never written to.
+func expandHome(c *hst.ContainerConfig, hostHome *check.Absolute) ([]hst.FilesystemConfigJSON, error) {
+	filesystem := c.Filesystem
+	var copied bool
+	for i, fc := range c.Filesystem {
+		b, ok := fc.FilesystemConfig.(*hst.FSBind)
+		if !ok {
+			continue
+		}
+
+		if v, err := b.ExpandHome(c.Home, hostHome); err != nil {
+			return nil, err
+		} else if v != b {
+			if !copied {
+				filesystem = slices.Clone(c.Filesystem)
+				copied = true
+			}
+			filesystem[i] = hst.FilesystemConfigJSON{FilesystemConfig: v}
+		}
+	}
+	return filesystem, nil
+}
+
 // evalSymlinks calls syscallDispatcher.evalSymlinks but discards errors unwrapping to [fs.ErrNotExist].
 func evalSymlinks(msg message.Msg, k syscallDispatcher, v *string) error {
 	if p, err := k.evalSymlinks(*v); err != nil {
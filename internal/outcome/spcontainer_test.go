@@ -16,6 +16,7 @@ import (
 	"hakurei.app/hst"
 	"hakurei.app/internal/acl"
 	"hakurei.app/internal/dbus"
+	"hakurei.app/internal/env"
 	"hakurei.app/internal/system"
 )
 
@@ -86,6 +87,163 @@ func TestSpParamsOp(t *testing.T) {
 			}
 		}), nil},
 
+		{"success dev shm size", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spParamsOp)
+			}
+			return &spParamsOp{Term: "xterm", TermSet: true}
+		}, func() *hst.Config {
+			c := hst.Template()
+			c.Container.Args = nil
+			c.Container.Flags = hst.FHostNet | hst.FHostAbstract | hst.FMapRealUID
+			c.Container.DevShmSize = 1 << 30
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"TERM"}, "xterm", nil),
+		}, newI().
+			Ensure(m(container.Nonexistent+"/tmp/hakurei.0"), 0711), nil, nil, nil, []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Hostname:       config.Container.Hostname,
+			HostNet:        true,
+			HostAbstract:   true,
+			Path:           config.Container.Path,
+			Args:           []string{config.Container.Path.String()},
+			SeccompPresets: std.PresetExt | std.PresetDenyDevel | std.PresetDenyNS | std.PresetDenyTTY,
+			Uid:            1000,
+			Gid:            100,
+			Ops: new(container.Ops).
+				Root(m("/var/lib/hakurei/base/org.debian"), std.BindWritable).
+				Proc(fhs.AbsProc).Tmpfs(hst.AbsPrivateTmp, 1<<12, 0755).
+				DevWritable(fhs.AbsDev, true).
+				Tmpfs(fhs.AbsDevShm, 1<<30, 01777),
+		}, paramsWantEnv(config, map[string]string{
+			"TERM": "xterm",
+		}, nil), nil},
+
+		{"success login shell", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spParamsOp)
+			}
+			return &spParamsOp{Term: "xterm", TermSet: true}
+		}, func() *hst.Config {
+			c := hst.Template()
+			c.Container.Path = c.Container.Shell
+			c.Container.Args = []string{"zsh", "-i"}
+			c.Container.LoginShell = true
+			c.Container.Flags = hst.FHostNet | hst.FHostAbstract | hst.FMapRealUID
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"TERM"}, "xterm", nil),
+		}, newI().
+			Ensure(m(container.Nonexistent+"/tmp/hakurei.0"), 0711), nil, nil, nil, []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Hostname:       config.Container.Hostname,
+			HostNet:        true,
+			HostAbstract:   true,
+			Path:           config.Container.Shell,
+			Args:           []string{"-zsh", "-i"},
+			SeccompPresets: std.PresetExt | std.PresetDenyDevel | std.PresetDenyNS | std.PresetDenyTTY,
+			Uid:            1000,
+			Gid:            100,
+			Ops: new(container.Ops).
+				Root(m("/var/lib/hakurei/base/org.debian"), std.BindWritable).
+				Proc(fhs.AbsProc).Tmpfs(hst.AbsPrivateTmp, 1<<12, 0755).
+				DevWritable(fhs.AbsDev, true).
+				Tmpfs(fhs.AbsDevShm, 0, 01777),
+		}, paramsWantEnv(config, map[string]string{
+			"TERM": "xterm",
+		}, nil), nil},
+
+		{"success deny socket", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spParamsOp)
+			}
+			return &spParamsOp{Term: "xterm", TermSet: true}
+		}, func() *hst.Config {
+			c := hst.Template()
+			c.Container.Args = nil
+			c.Container.Flags = hst.FHostNet | hst.FHostAbstract | hst.FMapRealUID
+			c.Container.DenySocketFamilies = []string{"inet", "inet6"}
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"TERM"}, "xterm", nil),
+		}, newI().
+			Ensure(m(container.Nonexistent+"/tmp/hakurei.0"), 0711), nil, nil, nil, []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Hostname:          config.Container.Hostname,
+			HostNet:           true,
+			HostAbstract:      true,
+			Path:              config.Container.Path,
+			Args:              []string{config.Container.Path.String()},
+			SeccompPresets:    std.PresetExt | std.PresetDenyDevel | std.PresetDenyNS | std.PresetDenyTTY,
+			SeccompDenySocket: []std.ScmpDatum{syscall.AF_INET, syscall.AF_INET6},
+			Uid:               1000,
+			Gid:               100,
+			Ops: new(container.Ops).
+				Root(m("/var/lib/hakurei/base/org.debian"), std.BindWritable).
+				Proc(fhs.AbsProc).Tmpfs(hst.AbsPrivateTmp, 1<<12, 0755).
+				DevWritable(fhs.AbsDev, true).
+				Tmpfs(fhs.AbsDevShm, 0, 01777),
+		}, paramsWantEnv(config, map[string]string{
+			"TERM": "xterm",
+		}, func(t *testing.T, state *outcomeStateParams) {
+			if state.as.AutoEtcPrefix != wantAutoEtcPrefix {
+				t.Errorf("toContainer: as.AutoEtcPrefix = %q, want %q", state.as.AutoEtcPrefix, wantAutoEtcPrefix)
+			}
+
+			wantFilesystems := config.Container.Filesystem[1:]
+			if !reflect.DeepEqual(state.filesystem, wantFilesystems) {
+				t.Errorf("toContainer: filesystem = %#v, want %#v", state.filesystem, wantFilesystems)
+			}
+		}), nil},
+
+		{"success seccomp action", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spParamsOp)
+			}
+			return &spParamsOp{Term: "xterm", TermSet: true}
+		}, func() *hst.Config {
+			c := hst.Template()
+			c.Container.Args = nil
+			c.Container.Flags = hst.FHostNet | hst.FHostAbstract | hst.FMapRealUID
+			c.Container.SeccompAction = hst.SeccompActionENOSYS
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"TERM"}, "xterm", nil),
+		}, newI().
+			Ensure(m(container.Nonexistent+"/tmp/hakurei.0"), 0711), nil, nil, nil, []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Hostname:       config.Container.Hostname,
+			HostNet:        true,
+			HostAbstract:   true,
+			Path:           config.Container.Path,
+			Args:           []string{config.Container.Path.String()},
+			SeccompPresets: std.PresetExt | std.PresetDenyDevel | std.PresetDenyNS | std.PresetDenyTTY,
+			SeccompKill:    seccomp.DenyENOSYS,
+			Uid:            1000,
+			Gid:            100,
+			Ops: new(container.Ops).
+				Root(m("/var/lib/hakurei/base/org.debian"), std.BindWritable).
+				Proc(fhs.AbsProc).Tmpfs(hst.AbsPrivateTmp, 1<<12, 0755).
+				DevWritable(fhs.AbsDev, true).
+				Tmpfs(fhs.AbsDevShm, 0, 01777),
+		}, paramsWantEnv(config, map[string]string{
+			"TERM": "xterm",
+		}, func(t *testing.T, state *outcomeStateParams) {
+			if state.as.AutoEtcPrefix != wantAutoEtcPrefix {
+				t.Errorf("toContainer: as.AutoEtcPrefix = %q, want %q", state.as.AutoEtcPrefix, wantAutoEtcPrefix)
+			}
+
+			wantFilesystems := config.Container.Filesystem[1:]
+			if !reflect.DeepEqual(state.filesystem, wantFilesystems) {
+				t.Errorf("toContainer: filesystem = %#v, want %#v", state.filesystem, wantFilesystems)
+			}
+		}), nil},
+
 		{"success", func(isShim, _ bool) outcomeOp {
 			if !isShim {
 				return new(spParamsOp)
@@ -131,6 +289,7 @@ func TestSpFilesystemOp(t *testing.T) {
 	var stubDebianRoot = stubDir("bin", "dev", "etc", "home", "lib64", "lost+found",
 		"mnt", "nix", "proc", "root", "run", "srv", "sys", "tmp", "usr", "var")
 	config := hst.Template()
+	wantWritablePaths := []*check.Absolute{config.Container.Home, fhs.AbsTmp, fhs.AbsDevShm, hst.AbsPrivateTmp}
 
 	newConfigSmall := func() *hst.Config {
 		c := hst.Template()
@@ -239,6 +398,51 @@ func TestSpFilesystemOp(t *testing.T) {
 			Msg:  "impossible path hiding state reached",
 		}, nil, nil, nil, nil, nil},
 
+		{"bind source missing", func(bool, bool) outcomeOp { return new(spFilesystemOp) }, func() *hst.Config {
+			c := newConfigSmall()
+			c.Container.Filesystem = append(c.Container.Filesystem,
+				hst.FilesystemConfigJSON{FilesystemConfig: &hst.FSBind{Source: m("/etc/xdg/nonexistent"), Optional: true}},
+				hst.FilesystemConfigJSON{FilesystemConfig: &hst.FSBind{Source: m("/var/lib/hakurei/u0/cache"), Write: true}},
+				hst.FilesystemConfigJSON{FilesystemConfig: &hst.FSBind{Source: m("/etc/xdg/hakurei"), Target: m("/etc/xdg/hakurei")}},
+			)
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{dbus.SystemBusAddress}, "invalid:meow=0;unix:path=/system_bus_socket;unix:path=system_bus_socket", nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is in an unusual location", []any{"/system_bus_socket"}}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is not absolute", []any{"system_bus_socket"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/xdg_runtime_dir"}, nePrefix+"/xdg_runtime_dir", nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/tmp/hakurei.0"}, nePrefix+"/tmp/hakurei.0", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/run/nscd"}, "", &os.PathError{Op: "lstat", Path: "/var/run/nscd", Err: os.ErrNotExist}),
+			call("verbosef", stub.ExpectArgs{"path %q does not yet exist", []any{"/var/run/nscd"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{"/"}, nePrefix+"/etc/dbus", nil), // to match hidePaths
+			call("stat", stub.ExpectArgs{"/etc/xdg/hakurei"}, stubFileInfoMode(0), &os.PathError{Op: "stat", Path: "/etc/xdg/hakurei", Err: syscall.ENOENT}),
+		}, nil, nil, &hst.AppError{
+			Step: "access bind source",
+			Err:  &os.PathError{Op: "stat", Path: "/etc/xdg/hakurei", Err: syscall.ENOENT},
+			Msg:  `bind source "/etc/xdg/hakurei" does not exist`,
+		}, nil, nil, nil, nil, nil},
+
+		{"bind source not directory", func(bool, bool) outcomeOp { return new(spFilesystemOp) }, func() *hst.Config {
+			c := newConfigSmall()
+			c.Container.Filesystem = append(c.Container.Filesystem,
+				hst.FilesystemConfigJSON{FilesystemConfig: &hst.FSBind{Source: m("/etc/xdg.conf/hakurei")}},
+			)
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{dbus.SystemBusAddress}, "invalid:meow=0;unix:path=/system_bus_socket;unix:path=system_bus_socket", nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is in an unusual location", []any{"/system_bus_socket"}}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is not absolute", []any{"system_bus_socket"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/xdg_runtime_dir"}, nePrefix+"/xdg_runtime_dir", nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/tmp/hakurei.0"}, nePrefix+"/tmp/hakurei.0", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/run/nscd"}, "", &os.PathError{Op: "lstat", Path: "/var/run/nscd", Err: os.ErrNotExist}),
+			call("verbosef", stub.ExpectArgs{"path %q does not yet exist", []any{"/var/run/nscd"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{"/"}, nePrefix+"/etc/dbus", nil), // to match hidePaths
+			call("stat", stub.ExpectArgs{"/etc/xdg.conf/hakurei"}, stubFileInfoMode(0), &os.PathError{Op: "stat", Path: "/etc/xdg.conf/hakurei", Err: syscall.ENOTDIR}),
+		}, nil, nil, &hst.AppError{
+			Step: "access bind source",
+			Err:  &os.PathError{Op: "stat", Path: "/etc/xdg.conf/hakurei", Err: syscall.ENOTDIR},
+		}, nil, nil, nil, nil, nil},
+
 		{"evalSymlinks late", func(bool, bool) outcomeOp { return new(spFilesystemOp) }, newConfigSmall, nil, []stub.Call{
 			call("lookupEnv", stub.ExpectArgs{dbus.SystemBusAddress}, "invalid:meow=0;unix:path=/system_bus_socket;unix:path=system_bus_socket", nil),
 			call("verbosef", stub.ExpectArgs{"dbus socket %q is in an unusual location", []any{"/system_bus_socket"}}, nil, nil),
@@ -357,6 +561,314 @@ func TestSpFilesystemOp(t *testing.T) {
 				"GOOGLE_DEFAULT_CLIENT_SECRET=OTJgUOQcT7lO7GsGZq2G4IlT",
 			},
 
+			ReadOnlyRoot:  true,
+			WritablePaths: wantWritablePaths,
+
+			Ops: new(container.Ops).
+				Etc(fhs.AbsEtc, wantAutoEtcPrefix).
+				OverlayReadonly(
+					check.MustAbs("/nix/store"),
+					fhs.AbsVarLib.Append("hakurei/base/org.nixos/.ro-store"),
+					fhs.AbsVarLib.Append("hakurei/base/org.nixos/org.chromium.Chromium")).
+				Readonly(hst.AbsPrivateTmp, 0755).
+				Tmpfs(m("/proc/nonexistent/eval/etc/dbus"), 1<<13, 0755).
+				Remount(fhs.AbsDev, syscall.MS_RDONLY).
+				Remount(fhs.AbsRoot, syscall.MS_RDONLY),
+		}, nil, nil},
+
+		{"success scrub", func(isShim, clearUnexported bool) outcomeOp {
+			if !isShim {
+				return new(spFilesystemOp)
+			}
+			return &spFilesystemOp{HidePaths: []*check.Absolute{m("/proc/nonexistent/eval/etc/dbus")}}
+		}, func() *hst.Config {
+			c := newConfigSmall()
+			c.Container.EnvScrub = []string{"TERM", "GOOGLE_API_KEY", "SSH_AUTH_SOCK"}
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{dbus.SystemBusAddress}, "invalid:meow=0;unix:path=/system_bus_socket;unix:path=system_bus_socket", nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is in an unusual location", []any{"/system_bus_socket"}}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is not absolute", []any{"system_bus_socket"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/xdg_runtime_dir"}, nePrefix+"/xdg_runtime_dir", nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/tmp/hakurei.0"}, nePrefix+"/tmp/hakurei.0", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/run/nscd"}, "", &os.PathError{Op: "lstat", Path: "/var/run/nscd", Err: os.ErrNotExist}),
+			call("verbosef", stub.ExpectArgs{"path %q does not yet exist", []any{"/var/run/nscd"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{"/"}, nePrefix+"/etc/dbus", nil), // to match hidePaths
+			call("evalSymlinks", stub.ExpectArgs{"/etc/"}, nePrefix+"/etc", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/lib/hakurei/base/org.nixos/.ro-store"}, nePrefix+"/var/lib/hakurei/base/org.nixos/.ro-store", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/lib/hakurei/base/org.nixos/org.chromium.Chromium"}, nePrefix+"/var/lib/hakurei/base/org.nixos/org.chromium.Chromium", nil),
+			call("verbosef", stub.ExpectArgs{"hiding path %q from %q", []any{"/proc/nonexistent/eval/etc/dbus", "/etc/"}}, nil, nil),
+		}, newI().
+			Ensure(m("/var/lib/hakurei/u0"), 0700).
+			UpdatePermType(system.User, m("/var/lib/hakurei/u0"),
+				acl.Execute).
+			UpdatePermType(system.User, m("/var/lib/hakurei/u0/org.chromium.Chromium"),
+				acl.Read, acl.Write, acl.Execute), nil, nil, insertsOps(needsApplyState(func(state *outcomeStateParams) {
+			state.filesystem = configSmall.Container.Filesystem
+			// emulates spParamsOp passing through $TERM
+			state.env["TERM"] = "xterm"
+		})), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Env: []string{
+				"GOOGLE_DEFAULT_CLIENT_ID=77185425430.apps.googleusercontent.com",
+				"GOOGLE_DEFAULT_CLIENT_SECRET=OTJgUOQcT7lO7GsGZq2G4IlT",
+			},
+
+			ReadOnlyRoot:  true,
+			WritablePaths: wantWritablePaths,
+
+			Ops: new(container.Ops).
+				Etc(fhs.AbsEtc, wantAutoEtcPrefix).
+				OverlayReadonly(
+					check.MustAbs("/nix/store"),
+					fhs.AbsVarLib.Append("hakurei/base/org.nixos/.ro-store"),
+					fhs.AbsVarLib.Append("hakurei/base/org.nixos/org.chromium.Chromium")).
+				Readonly(hst.AbsPrivateTmp, 0755).
+				Tmpfs(m("/proc/nonexistent/eval/etc/dbus"), 1<<13, 0755).
+				Remount(fhs.AbsDev, syscall.MS_RDONLY).
+				Remount(fhs.AbsRoot, syscall.MS_RDONLY),
+		}, nil, nil},
+
+		{"success hooks", func(isShim, clearUnexported bool) outcomeOp {
+			if !isShim {
+				return new(spFilesystemOp)
+			}
+			return &spFilesystemOp{HidePaths: []*check.Absolute{m("/proc/nonexistent/eval/etc/dbus")}}
+		}, func() *hst.Config {
+			c := newConfigSmall()
+			c.Container.EnvScrub = []string{"GOOGLE_API_KEY"}
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{dbus.SystemBusAddress}, "invalid:meow=0;unix:path=/system_bus_socket;unix:path=system_bus_socket", nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is in an unusual location", []any{"/system_bus_socket"}}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is not absolute", []any{"system_bus_socket"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/xdg_runtime_dir"}, nePrefix+"/xdg_runtime_dir", nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/tmp/hakurei.0"}, nePrefix+"/tmp/hakurei.0", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/run/nscd"}, "", &os.PathError{Op: "lstat", Path: "/var/run/nscd", Err: os.ErrNotExist}),
+			call("verbosef", stub.ExpectArgs{"path %q does not yet exist", []any{"/var/run/nscd"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{"/"}, nePrefix+"/etc/dbus", nil), // to match hidePaths
+			call("evalSymlinks", stub.ExpectArgs{"/etc/"}, nePrefix+"/etc", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/lib/hakurei/base/org.nixos/.ro-store"}, nePrefix+"/var/lib/hakurei/base/org.nixos/.ro-store", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/lib/hakurei/base/org.nixos/org.chromium.Chromium"}, nePrefix+"/var/lib/hakurei/base/org.nixos/org.chromium.Chromium", nil),
+			call("verbosef", stub.ExpectArgs{"hiding path %q from %q", []any{"/proc/nonexistent/eval/etc/dbus", "/etc/"}}, nil, nil),
+		}, newI().
+			Ensure(m("/var/lib/hakurei/u0"), 0700).
+			UpdatePermType(system.User, m("/var/lib/hakurei/u0"),
+				acl.Execute).
+			UpdatePermType(system.User, m("/var/lib/hakurei/u0/org.chromium.Chromium"),
+				acl.Read, acl.Write, acl.Execute), nil, nil, insertsOps(needsApplyState(func(state *outcomeStateParams) {
+			state.filesystem = configSmall.Container.Filesystem
+			state.hooks = []hst.ParamsHook{func(ops hst.Ops, env map[string]string) {
+				ops.Bind(m("/run/media"), m("/run/media"), std.BindOptional)
+				env["HAKUREI_HOOK"] = "${UNEXPANDED}"
+				env["GOOGLE_API_KEY"] = "scrubbed"
+			}, func(_ hst.Ops, env map[string]string) {
+				// hooks run in the order they are registered
+				env["HAKUREI_HOOK"] += ":1"
+			}}
+		})), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Env: []string{
+				"GOOGLE_DEFAULT_CLIENT_ID=77185425430.apps.googleusercontent.com",
+				"GOOGLE_DEFAULT_CLIENT_SECRET=OTJgUOQcT7lO7GsGZq2G4IlT",
+				"HAKUREI_HOOK=${UNEXPANDED}:1",
+			},
+
+			ReadOnlyRoot:  true,
+			WritablePaths: wantWritablePaths,
+
+			Ops: new(container.Ops).
+				Etc(fhs.AbsEtc, wantAutoEtcPrefix).
+				OverlayReadonly(
+					check.MustAbs("/nix/store"),
+					fhs.AbsVarLib.Append("hakurei/base/org.nixos/.ro-store"),
+					fhs.AbsVarLib.Append("hakurei/base/org.nixos/org.chromium.Chromium")).
+				Readonly(hst.AbsPrivateTmp, 0755).
+				Tmpfs(m("/proc/nonexistent/eval/etc/dbus"), 1<<13, 0755).
+				Bind(m("/run/media"), m("/run/media"), std.BindOptional).
+				Remount(fhs.AbsDev, syscall.MS_RDONLY).
+				Remount(fhs.AbsRoot, syscall.MS_RDONLY),
+		}, nil, nil},
+
+		{"invalid env reference", func(bool, bool) outcomeOp { return new(spFilesystemOp) }, func() *hst.Config {
+			c := newConfigSmall()
+			c.Container.Env = map[string]string{"PATH": "${HOST_PATH:/usr/local/bin"}
+			return c
+		}, nil, nil, nil, nil, &hst.AppError{
+			Step: "finalise",
+			Err:  env.ErrUnterminated,
+			Msg:  `invalid value of environment variable "PATH"`,
+		}, nil, nil, nil, nil, nil},
+
+		{"env unresolved strict", func(isShim, clearUnexported bool) outcomeOp {
+			if !isShim {
+				return new(spFilesystemOp)
+			}
+			return &spFilesystemOp{HidePaths: []*check.Absolute{m("/proc/nonexistent/eval/etc/dbus")}}
+		}, func() *hst.Config {
+			c := newConfigSmall()
+			c.Container.Env = map[string]string{"PATH": "${HOST_PATH}:/usr/local/bin"}
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"HOST_PATH"}, nil, nil),
+			call("lookupEnv", stub.ExpectArgs{dbus.SystemBusAddress}, "invalid:meow=0;unix:path=/system_bus_socket;unix:path=system_bus_socket", nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is in an unusual location", []any{"/system_bus_socket"}}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is not absolute", []any{"system_bus_socket"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/xdg_runtime_dir"}, nePrefix+"/xdg_runtime_dir", nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/tmp/hakurei.0"}, nePrefix+"/tmp/hakurei.0", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/run/nscd"}, "", &os.PathError{Op: "lstat", Path: "/var/run/nscd", Err: os.ErrNotExist}),
+			call("verbosef", stub.ExpectArgs{"path %q does not yet exist", []any{"/var/run/nscd"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{"/"}, nePrefix+"/etc/dbus", nil), // to match hidePaths
+			call("evalSymlinks", stub.ExpectArgs{"/etc/"}, nePrefix+"/etc", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/lib/hakurei/base/org.nixos/.ro-store"}, nePrefix+"/var/lib/hakurei/base/org.nixos/.ro-store", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/lib/hakurei/base/org.nixos/org.chromium.Chromium"}, nePrefix+"/var/lib/hakurei/base/org.nixos/org.chromium.Chromium", nil),
+			call("verbosef", stub.ExpectArgs{"hiding path %q from %q", []any{"/proc/nonexistent/eval/etc/dbus", "/etc/"}}, nil, nil),
+		}, newI().
+			Ensure(m("/var/lib/hakurei/u0"), 0700).
+			UpdatePermType(system.User, m("/var/lib/hakurei/u0"),
+				acl.Execute).
+			UpdatePermType(system.User, m("/var/lib/hakurei/u0/org.chromium.Chromium"),
+				acl.Read, acl.Write, acl.Execute), nil, nil, insertsOps(needsApplyState(func(state *outcomeStateParams) {
+			state.filesystem = configSmall.Container.Filesystem
+		})), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, nil, nil, &hst.AppError{
+			Step: "finalise",
+			Err:  env.UnresolvedError("HOST_PATH"),
+			Msg:  `cannot expand environment variable "PATH"`,
+		}},
+
+		{"success env expand", func(isShim, clearUnexported bool) outcomeOp {
+			if !isShim {
+				return new(spFilesystemOp)
+			}
+			return &spFilesystemOp{
+				HidePaths: []*check.Absolute{m("/proc/nonexistent/eval/etc/dbus")},
+				EnvHost:   map[string]string{"HOST_PATH": "/run/current-system/sw/bin"},
+			}
+		}, func() *hst.Config {
+			c := newConfigSmall()
+			c.Container.Flags &= ^hst.FEnvStrict
+			c.Container.Env = map[string]string{
+				"PATH":  "${HOST_PATH}:/usr/local/bin",
+				"PRICE": "$$5 or $5",
+				"UNSET": "${NONEXISTENT}",
+				"USER":  "${HOST_PATH}",
+			}
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"HOST_PATH"}, "/run/current-system/sw/bin", nil),
+			call("lookupEnv", stub.ExpectArgs{"NONEXISTENT"}, nil, nil),
+			call("lookupEnv", stub.ExpectArgs{dbus.SystemBusAddress}, "invalid:meow=0;unix:path=/system_bus_socket;unix:path=system_bus_socket", nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is in an unusual location", []any{"/system_bus_socket"}}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is not absolute", []any{"system_bus_socket"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/xdg_runtime_dir"}, nePrefix+"/xdg_runtime_dir", nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/tmp/hakurei.0"}, nePrefix+"/tmp/hakurei.0", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/run/nscd"}, "", &os.PathError{Op: "lstat", Path: "/var/run/nscd", Err: os.ErrNotExist}),
+			call("verbosef", stub.ExpectArgs{"path %q does not yet exist", []any{"/var/run/nscd"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{"/"}, nePrefix+"/etc/dbus", nil), // to match hidePaths
+			call("evalSymlinks", stub.ExpectArgs{"/etc/"}, nePrefix+"/etc", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/lib/hakurei/base/org.nixos/.ro-store"}, nePrefix+"/var/lib/hakurei/base/org.nixos/.ro-store", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/lib/hakurei/base/org.nixos/org.chromium.Chromium"}, nePrefix+"/var/lib/hakurei/base/org.nixos/org.chromium.Chromium", nil),
+			call("verbosef", stub.ExpectArgs{"hiding path %q from %q", []any{"/proc/nonexistent/eval/etc/dbus", "/etc/"}}, nil, nil),
+		}, newI().
+			Ensure(m("/var/lib/hakurei/u0"), 0700).
+			UpdatePermType(system.User, m("/var/lib/hakurei/u0"),
+				acl.Execute).
+			UpdatePermType(system.User, m("/var/lib/hakurei/u0/org.chromium.Chromium"),
+				acl.Read, acl.Write, acl.Execute), nil, nil, insertsOps(needsApplyState(func(state *outcomeStateParams) {
+			state.filesystem = configSmall.Container.Filesystem
+			// emulates an outcomeOp replacing a configured value
+			state.env["USER"] = "${HOST_PATH}/chronos"
+		})), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Env: []string{
+				"PATH=/run/current-system/sw/bin:/usr/local/bin",
+				"PRICE=$5 or $5",
+				"UNSET=",
+				"USER=${HOST_PATH}/chronos",
+			},
+
+			ReadOnlyRoot:  true,
+			WritablePaths: wantWritablePaths,
+
+			Ops: new(container.Ops).
+				Etc(fhs.AbsEtc, wantAutoEtcPrefix).
+				OverlayReadonly(
+					check.MustAbs("/nix/store"),
+					fhs.AbsVarLib.Append("hakurei/base/org.nixos/.ro-store"),
+					fhs.AbsVarLib.Append("hakurei/base/org.nixos/org.chromium.Chromium")).
+				Readonly(hst.AbsPrivateTmp, 0755).
+				Tmpfs(m("/proc/nonexistent/eval/etc/dbus"), 1<<13, 0755).
+				Remount(fhs.AbsDev, syscall.MS_RDONLY).
+				Remount(fhs.AbsRoot, syscall.MS_RDONLY),
+		}, nil, nil},
+
+		{"success pass env", func(isShim, clearUnexported bool) outcomeOp {
+			if !isShim {
+				return new(spFilesystemOp)
+			}
+			return &spFilesystemOp{
+				HidePaths: []*check.Absolute{m("/proc/nonexistent/eval/etc/dbus")},
+				EnvPass: map[string]string{
+					"MESA_DEBUG":                  "1",
+					"MESA_LOADER_DRIVER_OVERRIDE": "iris",
+					"TERM":                        "linux",
+					"WAYLAND_DEBUG":               "client",
+				},
+			}
+		}, func() *hst.Config {
+			c := newConfigSmall()
+			c.Container.Env = map[string]string{"MESA_LOADER_DRIVER_OVERRIDE": "zink"}
+			c.Container.PassEnv = []string{"MESA_*", "TERM", "WAYLAND_DEBUG"}
+			return c
+		}, nil, []stub.Call{
+			call("environ", stub.ExpectArgs{}, []string{
+				"MESA_DEBUG=1",
+				"MESA_LOADER_DRIVER_OVERRIDE=iris",
+				"MESAX=1",
+				"SSH_AUTH_SOCK=/run/user/1000/ssh-agent",
+				"TERM=linux",
+				"WAYLAND_DEBUG=client",
+				"WAYLAND_DISPLAY=wayland-0",
+			}, nil),
+			call("lookupEnv", stub.ExpectArgs{dbus.SystemBusAddress}, "invalid:meow=0;unix:path=/system_bus_socket;unix:path=system_bus_socket", nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is in an unusual location", []any{"/system_bus_socket"}}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"dbus socket %q is not absolute", []any{"system_bus_socket"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/xdg_runtime_dir"}, nePrefix+"/xdg_runtime_dir", nil),
+			call("evalSymlinks", stub.ExpectArgs{container.Nonexistent + "/tmp/hakurei.0"}, nePrefix+"/tmp/hakurei.0", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/run/nscd"}, "", &os.PathError{Op: "lstat", Path: "/var/run/nscd", Err: os.ErrNotExist}),
+			call("verbosef", stub.ExpectArgs{"path %q does not yet exist", []any{"/var/run/nscd"}}, nil, nil),
+			call("evalSymlinks", stub.ExpectArgs{"/"}, nePrefix+"/etc/dbus", nil), // to match hidePaths
+			call("evalSymlinks", stub.ExpectArgs{"/etc/"}, nePrefix+"/etc", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/lib/hakurei/base/org.nixos/.ro-store"}, nePrefix+"/var/lib/hakurei/base/org.nixos/.ro-store", nil),
+			call("evalSymlinks", stub.ExpectArgs{"/var/lib/hakurei/base/org.nixos/org.chromium.Chromium"}, nePrefix+"/var/lib/hakurei/base/org.nixos/org.chromium.Chromium", nil),
+			call("verbosef", stub.ExpectArgs{"hiding path %q from %q", []any{"/proc/nonexistent/eval/etc/dbus", "/etc/"}}, nil, nil),
+		}, newI().
+			Ensure(m("/var/lib/hakurei/u0"), 0700).
+			UpdatePermType(system.User, m("/var/lib/hakurei/u0"),
+				acl.Execute).
+			UpdatePermType(system.User, m("/var/lib/hakurei/u0/org.chromium.Chromium"),
+				acl.Read, acl.Write, acl.Execute), nil, nil, insertsOps(needsApplyState(func(state *outcomeStateParams) {
+			state.filesystem = configSmall.Container.Filesystem
+			// emulates spParamsOp passing through $TERM
+			state.env["TERM"] = "xterm"
+		})), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Env: []string{
+				"MESA_DEBUG=1",
+				"MESA_LOADER_DRIVER_OVERRIDE=zink",
+				"TERM=xterm",
+				"WAYLAND_DEBUG=client",
+			},
+
+			ReadOnlyRoot:  true,
+			WritablePaths: wantWritablePaths,
+
 			Ops: new(container.Ops).
 				Etc(fhs.AbsEtc, wantAutoEtcPrefix).
 				OverlayReadonly(
@@ -412,6 +924,9 @@ func TestSpFilesystemOp(t *testing.T) {
 				"GOOGLE_DEFAULT_CLIENT_SECRET=OTJgUOQcT7lO7GsGZq2G4IlT",
 			},
 
+			ReadOnlyRoot:  true,
+			WritablePaths: wantWritablePaths,
+
 			Ops: new(container.Ops).
 				Etc(fhs.AbsEtc, wantAutoEtcPrefix).
 				Tmpfs(fhs.AbsTmp, 0, 0755).
@@ -467,6 +982,39 @@ func TestFlattenExtraPerms(t *testing.T) {
 	}
 }
 
+func TestExpandHome(t *testing.T) {
+	t.Parallel()
+
+	c := &hst.ContainerConfig{Home: m("/data/data/org.chromium.Chromium"), Filesystem: []hst.FilesystemConfigJSON{
+		{FilesystemConfig: &hst.FSBind{Source: m("/etc")}},
+		{FilesystemConfig: &hst.FSEphemeral{Target: m("/tmp")}},
+		{FilesystemConfig: &hst.FSBind{Source: m("/mnt/config"), HomeTarget: ".config/app", Write: true}},
+		{FilesystemConfig: &hst.FSBind{HomeSource: ".cache/chromium", HomeTarget: ".cache", Write: true}},
+	}}
+	want := []hst.FilesystemConfigJSON{
+		{FilesystemConfig: &hst.FSBind{Source: m("/etc")}},
+		{FilesystemConfig: &hst.FSEphemeral{Target: m("/tmp")}},
+		{FilesystemConfig: &hst.FSBind{Source: m("/mnt/config"), Target: m("/data/data/org.chromium.Chromium/.config/app"), Write: true}},
+		{FilesystemConfig: &hst.FSBind{Source: m("/home/user/.cache/chromium"), Target: m("/data/data/org.chromium.Chromium/.cache"), Write: true}},
+	}
+
+	if got, err := expandHome(c, m("/home/user")); err != nil {
+		t.Fatalf("expandHome: error = %v", err)
+	} else if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandHome: %#v, want %#v", got, want)
+	}
+	if b := c.Filesystem[2].FilesystemConfig.(*hst.FSBind); b.Target != nil || b.HomeTarget != ".config/app" {
+		t.Errorf("expandHome: clobbered config %#v", b)
+	}
+
+	c.Filesystem = append(c.Filesystem, hst.FilesystemConfigJSON{FilesystemConfig: &hst.FSBind{HomeSource: "../a2"}})
+	wantErr := &hst.AppError{Step: "expand home relative path", Err: hst.ErrHomeEscape,
+		Msg: `path "../a2" escapes home directory`}
+	if _, err := expandHome(c, m("/home/user")); !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("expandHome: error = %v, want %v", err, wantErr)
+	}
+}
+
 // invalidFSHost implements the Host method of [hst.FilesystemConfig] with an invalid response.
 type invalidFSHost bool
 
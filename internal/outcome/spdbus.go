@@ -1,15 +1,14 @@
 package outcome
 
 import (
-	"encoding/gob"
-
 	"hakurei.app/container/fhs"
 	"hakurei.app/hst"
 	"hakurei.app/internal/acl"
 	"hakurei.app/internal/dbus"
+	"hakurei.app/internal/system"
 )
 
-func init() { gob.Register(new(spDBusOp)) }
+func init() { registerOp(new(spDBusOp)) }
 
 // spDBusOp maintains an xdg-dbus-proxy instance for the container.
 // Runs after spRuntimeOp.
@@ -27,8 +26,10 @@ func (s *spDBusOp) toSystem(state *outcomeStateSys) error {
 		state.sessionBus = dbus.NewConfig(state.appId, true, true)
 	}
 
-	// downstream socket paths
-	sessionPath, systemPath := state.instance().Append("bus"), state.instance().Append("system_bus_socket")
+	// downstream socket paths, the directory is bound as the sockets are recreated on restart
+	dbusDir := state.instance().Append("dbus")
+	state.sys.Ephemeral(system.Process, dbusDir, 0711)
+	sessionPath, systemPath := dbusDir.Append("bus"), dbusDir.Append("system_bus_socket")
 
 	var sessionBus, systemBus dbus.ProxyPair
 	sessionBus[0], systemBus[0] = state.k.dbusAddress()
@@ -46,13 +47,34 @@ func (s *spDBusOp) toSystem(state *outcomeStateSys) error {
 }
 
 func (s *spDBusOp) toContainer(state *outcomeStateParams) error {
+	dbusInner := state.Container.PrivateTmpDir().Append("dbus")
+	state.params.Bind(state.instancePath().Append("dbus"), dbusInner, 0)
+
 	sessionInner := state.runtimeDir.Append("bus")
 	state.env["DBUS_SESSION_BUS_ADDRESS"] = "unix:path=" + sessionInner.String()
-	state.params.Bind(state.instancePath().Append("bus"), sessionInner, 0)
+	state.params.Link(sessionInner, dbusInner.Append("bus").String(), false)
 	if s.ProxySystem {
 		systemInner := fhs.AbsVar.Append("run/dbus/system_bus_socket")
 		state.env["DBUS_SYSTEM_BUS_ADDRESS"] = "unix:path=" + systemInner.String()
-		state.params.Bind(state.instancePath().Append("system_bus_socket"), systemInner, 0)
+		state.params.Link(systemInner, dbusInner.Append("system_bus_socket").String(), false)
 	}
 	return nil
 }
+
+// reloadBus resolves the message bus configuration supplied to finalise again and restarts
+// the message bus proxy with the result, so changes to referenced bus policies take effect.
+func (k *outcome) reloadBus() error {
+	session, err := resolveBus(k.syscallDispatcher, k.config.SessionBus, "session")
+	if err != nil {
+		return err
+	}
+	var system *hst.BusConfig
+	if system, err = resolveBus(k.syscallDispatcher, k.config.SystemBus, "system"); err != nil {
+		return err
+	}
+
+	if session == nil {
+		session = dbus.NewConfig(k.config.ID, true, true)
+	}
+	return k.sys.RestartDBus(session, system)
+}
@@ -1,6 +1,7 @@
 package outcome
 
 import (
+	"os"
 	"syscall"
 	"testing"
 
@@ -59,7 +60,7 @@ func TestSpDBusOp(t *testing.T) {
 			call("isVerbose", stub.ExpectArgs{}, true, nil),
 			call("verbose", stub.ExpectArgs{[]any{"session bus proxy:", []string{
 				"unix:path=/run/user/1000/bus",
-				wantInstancePrefix + "/bus",
+				wantInstancePrefix + "/dbus/bus",
 				"--filter",
 				"--talk=org.freedesktop.DBus",
 				"--talk=org.freedesktop.Notifications",
@@ -70,7 +71,7 @@ func TestSpDBusOp(t *testing.T) {
 			}}}, nil, nil),
 			call("verbose", stub.ExpectArgs{[]any{"message bus proxy final args:", helper.MustNewCheckedArgs(
 				"unix:path=/run/user/1000/bus",
-				wantInstancePrefix+"/bus",
+				wantInstancePrefix+"/dbus/bus",
 				"--filter",
 				"--talk=org.freedesktop.DBus",
 				"--talk=org.freedesktop.Notifications",
@@ -81,22 +82,23 @@ func TestSpDBusOp(t *testing.T) {
 			)}}, nil, nil),
 		}, func() *system.I {
 			sys := system.New(panicMsgContext{}, message.New(nil), checkExpectUid)
-			sys.Ephemeral(system.Process, m(wantInstancePrefix), 0711)
+			sys.Ephemeral(system.Process, m(wantInstancePrefix), 0711).
+				Ephemeral(system.Process, m(wantInstancePrefix+"/dbus"), 0711)
 			if err := sys.ProxyDBus(
 				dbus.NewConfig(config.ID, true, true), nil,
-				dbus.ProxyPair{"unix:path=/run/user/1000/bus", wantInstancePrefix + "/bus"},
-				dbus.ProxyPair{"unix:path=/var/run/dbus/system_bus_socket", wantInstancePrefix + "/system_bus_socket"},
+				dbus.ProxyPair{"unix:path=/run/user/1000/bus", wantInstancePrefix + "/dbus/bus"},
+				dbus.ProxyPair{"unix:path=/var/run/dbus/system_bus_socket", wantInstancePrefix + "/dbus/system_bus_socket"},
 			); err != nil {
 				t.Fatalf("cannot prepare sys: %v", err)
 			}
-			sys.UpdatePerm(m(wantInstancePrefix+"/bus"), acl.Read, acl.Write)
+			sys.UpdatePerm(m(wantInstancePrefix+"/dbus/bus"), acl.Read, acl.Write)
 			return sys
 		}(), sysUsesInstance(nil), nil, insertsOps(afterSpRuntimeOp(nil)), []stub.Call{
 			// this op configures the container state and does not make calls during toContainer
 		}, &container.Params{
 			Ops: new(container.Ops).
-				Bind(m(wantInstancePrefix+"/bus"),
-					m("/run/user/1000/bus"), 0),
+				Bind(m(wantInstancePrefix+"/dbus"), m("/.hakurei/dbus"), 0).
+				Link(m("/run/user/1000/bus"), "/.hakurei/dbus/bus", false),
 		}, paramsWantEnv(config, map[string]string{
 			"DBUS_SESSION_BUS_ADDRESS": "unix:path=/run/user/1000/bus",
 		}, nil), nil},
@@ -114,7 +116,7 @@ func TestSpDBusOp(t *testing.T) {
 			call("isVerbose", stub.ExpectArgs{}, true, nil),
 			call("verbose", stub.ExpectArgs{[]any{"session bus proxy:", []string{
 				"unix:path=/run/user/1000/bus",
-				wantInstancePrefix + "/bus",
+				wantInstancePrefix + "/dbus/bus",
 				"--filter",
 				"--talk=org.freedesktop.Notifications",
 				"--talk=org.freedesktop.FileManager1",
@@ -131,7 +133,7 @@ func TestSpDBusOp(t *testing.T) {
 			}}}, nil, nil),
 			call("verbose", stub.ExpectArgs{[]any{"system bus proxy:", []string{
 				"unix:path=/var/run/dbus/system_bus_socket",
-				wantInstancePrefix + "/system_bus_socket",
+				wantInstancePrefix + "/dbus/system_bus_socket",
 				"--filter",
 				"--talk=org.bluez",
 				"--talk=org.freedesktop.Avahi",
@@ -139,7 +141,7 @@ func TestSpDBusOp(t *testing.T) {
 			}}}, nil, nil),
 			call("verbose", stub.ExpectArgs{[]any{"message bus proxy final args:", helper.MustNewCheckedArgs(
 				"unix:path=/run/user/1000/bus",
-				wantInstancePrefix+"/bus",
+				wantInstancePrefix+"/dbus/bus",
 				"--filter",
 				"--talk=org.freedesktop.Notifications",
 				"--talk=org.freedesktop.FileManager1",
@@ -155,7 +157,7 @@ func TestSpDBusOp(t *testing.T) {
 				"--broadcast=org.freedesktop.portal.*=@/org/freedesktop/portal/*",
 
 				"unix:path=/var/run/dbus/system_bus_socket",
-				wantInstancePrefix+"/system_bus_socket",
+				wantInstancePrefix+"/dbus/system_bus_socket",
 				"--filter",
 				"--talk=org.bluez",
 				"--talk=org.freedesktop.Avahi",
@@ -163,28 +165,60 @@ func TestSpDBusOp(t *testing.T) {
 			)}}, nil, nil),
 		}, func() *system.I {
 			sys := system.New(panicMsgContext{}, message.New(nil), checkExpectUid)
-			sys.Ephemeral(system.Process, m(wantInstancePrefix), 0711)
+			sys.Ephemeral(system.Process, m(wantInstancePrefix), 0711).
+				Ephemeral(system.Process, m(wantInstancePrefix+"/dbus"), 0711)
 			if err := sys.ProxyDBus(
 				config.SessionBus, config.SystemBus,
-				dbus.ProxyPair{"unix:path=/run/user/1000/bus", wantInstancePrefix + "/bus"},
-				dbus.ProxyPair{"unix:path=/var/run/dbus/system_bus_socket", wantInstancePrefix + "/system_bus_socket"},
+				dbus.ProxyPair{"unix:path=/run/user/1000/bus", wantInstancePrefix + "/dbus/bus"},
+				dbus.ProxyPair{"unix:path=/var/run/dbus/system_bus_socket", wantInstancePrefix + "/dbus/system_bus_socket"},
 			); err != nil {
 				t.Fatalf("cannot prepare sys: %v", err)
 			}
-			sys.UpdatePerm(m(wantInstancePrefix+"/bus"), acl.Read, acl.Write).
-				UpdatePerm(m(wantInstancePrefix+"/system_bus_socket"), acl.Read, acl.Write)
+			sys.UpdatePerm(m(wantInstancePrefix+"/dbus/bus"), acl.Read, acl.Write).
+				UpdatePerm(m(wantInstancePrefix+"/dbus/system_bus_socket"), acl.Read, acl.Write)
 			return sys
 		}(), sysUsesInstance(nil), nil, insertsOps(afterSpRuntimeOp(nil)), []stub.Call{
 			// this op configures the container state and does not make calls during toContainer
 		}, &container.Params{
 			Ops: new(container.Ops).
-				Bind(m(wantInstancePrefix+"/bus"),
-					m("/run/user/1000/bus"), 0).
-				Bind(m(wantInstancePrefix+"/system_bus_socket"),
-					m("/var/run/dbus/system_bus_socket"), 0),
+				Bind(m(wantInstancePrefix+"/dbus"), m("/.hakurei/dbus"), 0).
+				Link(m("/run/user/1000/bus"), "/.hakurei/dbus/bus", false).
+				Link(m("/var/run/dbus/system_bus_socket"), "/.hakurei/dbus/system_bus_socket", false),
 		}, paramsWantEnv(config, map[string]string{
 			"DBUS_SESSION_BUS_ADDRESS": "unix:path=/run/user/1000/bus",
 			"DBUS_SYSTEM_BUS_ADDRESS":  "unix:path=/var/run/dbus/system_bus_socket",
 		}, nil), nil},
 	})
 }
+
+func TestReloadBus(t *testing.T) {
+	t.Parallel()
+
+	const policyPath = "/etc/hakurei/portal-safe"
+
+	fReload := func(f func(config *hst.Config)) func(k *kstub) error {
+		return func(k *kstub) error {
+			config := hst.Template()
+			f(config)
+			return (&outcome{
+				syscallDispatcher: k,
+				config:            config,
+				sys:               system.New(t.Context(), message.New(nil), checkExpectUid),
+			}).reloadBus()
+		}
+	}
+
+	checkSimple(t, "reloadBus", []simpleTestCase{
+		{"policy missing", fReload(func(config *hst.Config) {
+			config.SystemBus.PolicyRef = m(policyPath)
+		}), stub.Expect{Calls: []stub.Call{
+			call("open", stub.ExpectArgs{policyPath}, (*stubOsFile)(nil), os.ErrNotExist),
+		}}, &hst.AppError{Step: "resolve configuration", Err: os.ErrNotExist,
+			Msg: `system bus policy "/etc/hakurei/portal-safe" does not exist`}},
+
+		{"not committed", fReload(func(config *hst.Config) {
+			config.SessionBus = nil
+		}), stub.Expect{}, &system.OpError{Op: "dbus", Err: system.ErrDBusNotRunning,
+			Msg: "attempted to restart message bus proxy outside committed state"}},
+	})
+}
@@ -0,0 +1,86 @@
+package outcome
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"hakurei.app/container/check"
+	"hakurei.app/container/std"
+	"hakurei.app/hst"
+	"hakurei.app/internal/acl"
+)
+
+func init() { registerOp(new(spDeviceOp)) }
+
+// spDeviceOp binds explicitly configured host device nodes into the container,
+// covering both InputDevices and Devices. Runs before spFilesystemOp.
+type spDeviceOp struct {
+	// Present device nodes. Populated during toSystem.
+	Devices []*check.Absolute
+}
+
+func (s *spDeviceOp) toSystem(state *outcomeStateSys) error {
+	if len(state.Container.InputDevices) == 0 && len(state.Container.Devices) == 0 {
+		return errNotEnabled
+	}
+
+	if len(state.Container.InputDevices) > 0 {
+		state.msg.Verbose("direct input device access, PROCEED WITH CAUTION")
+	}
+	uid := state.k.getuid()
+	for _, d := range [...]struct {
+		// description of nodes in the list
+		name string
+		// type bits required for a node to be bound
+		mode os.FileMode
+		// description of the required type
+		typ string
+		// validated via hst
+		nodes []*check.Absolute
+	}{
+		{"input device", os.ModeCharDevice, "character device", state.Container.InputDevices},
+		{"device", os.ModeDevice, "device", state.Container.Devices},
+	} {
+		for _, a := range d.nodes {
+			if fi, err := state.k.stat(a.String()); err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					return &hst.AppError{Step: "access " + d.name, Err: err}
+				}
+				state.msg.Verbosef("%s %q not present, skipping", d.name, a.String())
+			} else if fi.Mode()&d.mode == 0 {
+				state.msg.Verbosef("%q is not a %s, skipping", a.String(), d.typ)
+			} else {
+				s.Devices = append(s.Devices, a)
+				grantDevice(state, a, fi, uid)
+			}
+		}
+	}
+
+	if len(s.Devices) == 0 {
+		state.msg.Verbose("no configured device present on the host")
+		return errNotEnabled
+	}
+	return nil
+}
+
+// grantDevice adds ACL entries granting the target user read and write access to a device node.
+// Nodes already granting this access to other users are left alone, and so are nodes not owned
+// by the current user, since only the owner may change their ACL.
+func grantDevice(state *outcomeStateSys, a *check.Absolute, fi os.FileInfo, uid int) {
+	if fi.Mode().Perm()&0006 == 0006 {
+		return
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); !ok || int(st.Uid) != uid {
+		state.msg.Verbosef("device %q is not owned by the current user, access depends on its mode", a.String())
+		return
+	}
+	state.sys.UpdatePerm(a, acl.Read, acl.Write)
+}
+
+func (s *spDeviceOp) toContainer(state *outcomeStateParams) error {
+	for _, a := range s.Devices {
+		state.params.Bind(a, a, std.BindWritable|std.BindDevice)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package outcome
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"hakurei.app/container"
+	"hakurei.app/container/check"
+	"hakurei.app/container/std"
+	"hakurei.app/container/stub"
+	"hakurei.app/hst"
+	"hakurei.app/internal/acl"
+)
+
+func TestSpDeviceOp(t *testing.T) {
+	t.Parallel()
+	config := hst.Template()
+
+	newConfig := func() *hst.Config {
+		c := hst.Template()
+		c.Container.InputDevices = []*check.Absolute{
+			m("/dev/input/event3"),
+			m("/dev/input/event4"),
+		}
+		c.Container.Devices = []*check.Absolute{
+			m("/dev/kvm"),
+			m("/dev/dri"),
+			m("/dev/null"),
+			m("/dev/dri/renderD128"),
+		}
+		return c
+	}
+	newDevicesConfig := func() *hst.Config {
+		c := newConfig()
+		c.Container.InputDevices = nil
+		return c
+	}
+
+	checkOpBehaviour(t, []opBehaviourTestCase{
+		{"not enabled", func(bool, bool) outcomeOp {
+			return new(spDeviceOp)
+		}, hst.Template, nil, nil, nil, nil, errNotEnabled, nil, nil, nil, nil, nil},
+
+		{"stat input", func(bool, bool) outcomeOp {
+			return new(spDeviceOp)
+		}, newConfig, nil, []stub.Call{
+			call("verbose", stub.ExpectArgs{[]any{"direct input device access, PROCEED WITH CAUTION"}}, nil, nil),
+			call("getuid", stub.ExpectArgs{}, 1000, nil),
+			call("stat", stub.ExpectArgs{"/dev/input/event3"}, stubFileInfoMode(0), stub.UniqueError(1)),
+		}, nil, nil, &hst.AppError{Step: "access input device", Err: stub.UniqueError(1)}, nil, nil, nil, nil, nil},
+
+		{"stat", func(bool, bool) outcomeOp {
+			return new(spDeviceOp)
+		}, newDevicesConfig, nil, []stub.Call{
+			call("getuid", stub.ExpectArgs{}, 1000, nil),
+			call("stat", stub.ExpectArgs{"/dev/kvm"}, stubFileInfoMode(0), stub.UniqueError(0)),
+		}, nil, nil, &hst.AppError{Step: "access device", Err: stub.UniqueError(0)}, nil, nil, nil, nil, nil},
+
+		{"absent", func(bool, bool) outcomeOp {
+			return new(spDeviceOp)
+		}, newConfig, nil, []stub.Call{
+			call("verbose", stub.ExpectArgs{[]any{"direct input device access, PROCEED WITH CAUTION"}}, nil, nil),
+			call("getuid", stub.ExpectArgs{}, 1000, nil),
+			call("stat", stub.ExpectArgs{"/dev/input/event3"}, stubFileInfoMode(0), &os.PathError{Op: "stat", Path: "/dev/input/event3", Err: syscall.ENOENT}),
+			call("verbosef", stub.ExpectArgs{"%s %q not present, skipping", []any{"input device", "/dev/input/event3"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/dev/input/event4"}, stubFileInfoMode(os.ModeDevice|0660), nil),
+			call("verbosef", stub.ExpectArgs{"%q is not a %s, skipping", []any{"/dev/input/event4", "character device"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/dev/kvm"}, stubFileInfoMode(0), &os.PathError{Op: "stat", Path: "/dev/kvm", Err: syscall.ENOENT}),
+			call("verbosef", stub.ExpectArgs{"%s %q not present, skipping", []any{"device", "/dev/kvm"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/dev/dri"}, stubFileInfoMode(os.ModeDir|0755), nil),
+			call("verbosef", stub.ExpectArgs{"%q is not a %s, skipping", []any{"/dev/dri", "device"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/dev/null"}, stubFileInfoMode(0), &os.PathError{Op: "stat", Path: "/dev/null", Err: syscall.ENOENT}),
+			call("verbosef", stub.ExpectArgs{"%s %q not present, skipping", []any{"device", "/dev/null"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/dev/dri/renderD128"}, stubFileInfoMode(0644), nil),
+			call("verbosef", stub.ExpectArgs{"%q is not a %s, skipping", []any{"/dev/dri/renderD128", "device"}}, nil, nil),
+			call("verbose", stub.ExpectArgs{[]any{"no configured device present on the host"}}, nil, nil),
+		}, nil, nil, errNotEnabled, nil, nil, nil, nil, nil},
+
+		{"success", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spDeviceOp)
+			}
+			return &spDeviceOp{Devices: []*check.Absolute{
+				m("/dev/input/event3"),
+				m("/dev/kvm"),
+				m("/dev/null"),
+				m("/dev/dri/renderD128"),
+			}}
+		}, newConfig, nil, []stub.Call{
+			call("verbose", stub.ExpectArgs{[]any{"direct input device access, PROCEED WITH CAUTION"}}, nil, nil),
+			call("getuid", stub.ExpectArgs{}, 1000, nil),
+			call("stat", stub.ExpectArgs{"/dev/input/event3"}, stubFileInfoDevice{os.ModeDevice | os.ModeCharDevice | 0660, 1000}, nil),
+			call("stat", stub.ExpectArgs{"/dev/input/event4"}, stubFileInfoMode(0), &os.PathError{Op: "stat", Path: "/dev/input/event4", Err: syscall.ENOENT}),
+			call("verbosef", stub.ExpectArgs{"%s %q not present, skipping", []any{"input device", "/dev/input/event4"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/dev/kvm"}, stubFileInfoDevice{os.ModeDevice | os.ModeCharDevice | 0660, 0}, nil),
+			call("verbosef", stub.ExpectArgs{"device %q is not owned by the current user, access depends on its mode", []any{"/dev/kvm"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/dev/dri"}, stubFileInfoMode(os.ModeDir|0755), nil),
+			call("verbosef", stub.ExpectArgs{"%q is not a %s, skipping", []any{"/dev/dri", "device"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/dev/null"}, stubFileInfoMode(os.ModeDevice|os.ModeCharDevice|0666), nil),
+			call("stat", stub.ExpectArgs{"/dev/dri/renderD128"}, stubFileInfoDevice{os.ModeDevice | os.ModeCharDevice | 0600, 1000}, nil),
+		}, newI().
+			UpdatePerm(m("/dev/input/event3"), acl.Read, acl.Write).
+			UpdatePerm(m("/dev/dri/renderD128"), acl.Read, acl.Write), nil, nil, insertsOps(nil), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Ops: new(container.Ops).
+				Bind(m("/dev/input/event3"), m("/dev/input/event3"), std.BindWritable|std.BindDevice).
+				Bind(m("/dev/kvm"), m("/dev/kvm"), std.BindWritable|std.BindDevice).
+				Bind(m("/dev/null"), m("/dev/null"), std.BindWritable|std.BindDevice).
+				Bind(m("/dev/dri/renderD128"), m("/dev/dri/renderD128"), std.BindWritable|std.BindDevice),
+		}, paramsWantEnv(config, nil, nil), nil},
+	})
+}
+
+// stubFileInfoDevice is a device node with mode and owner uid.
+type stubFileInfoDevice struct {
+	mode fs.FileMode
+	uid  uint32
+}
+
+func (s stubFileInfoDevice) Name() string       { panic("attempted to call Name") }
+func (s stubFileInfoDevice) Size() int64        { panic("attempted to call Size") }
+func (s stubFileInfoDevice) Mode() fs.FileMode  { return s.mode }
+func (s stubFileInfoDevice) ModTime() time.Time { panic("attempted to call ModTime") }
+func (s stubFileInfoDevice) IsDir() bool        { panic("attempted to call IsDir") }
+func (s stubFileInfoDevice) Sys() any           { return &syscall.Stat_t{Uid: s.uid} }
@@ -0,0 +1,115 @@
+package outcome
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strings"
+
+	"hakurei.app/container/check"
+	"hakurei.app/hst"
+)
+
+const (
+	// vulkanDriverEnv is the environment variable holding Vulkan driver manifest files or directories
+	// holding them. Unlike the deprecated vulkanICDEnv, the loader accepts directories in this value.
+	vulkanDriverEnv = "VK_DRIVER_FILES"
+	// vulkanICDEnv is the deprecated predecessor of vulkanDriverEnv, which only accepts manifest files.
+	vulkanICDEnv = "VK_ICD_FILENAMES"
+	// eglVendorEnv is the environment variable holding glvnd EGL vendor manifest directories.
+	eglVendorEnv = "__EGL_VENDOR_LIBRARY_DIRS"
+)
+
+var (
+	// gpuVulkanPaths are directories holding Vulkan driver manifests, in loader search order.
+	gpuVulkanPaths = []*check.Absolute{
+		check.MustAbs("/etc/vulkan/icd.d"),
+		check.MustAbs("/usr/share/vulkan/icd.d"),
+	}
+	// gpuEGLPaths are directories holding glvnd EGL vendor manifests, in loader search order.
+	gpuEGLPaths = []*check.Absolute{
+		check.MustAbs("/etc/glvnd/egl_vendor.d"),
+		check.MustAbs("/usr/share/glvnd/egl_vendor.d"),
+	}
+)
+
+func init() { registerOp(new(spGPUOp)) }
+
+// spGPUOp binds host GPU loader configuration readonly and points the loaders to them.
+// Runs before spFilesystemOp.
+type spGPUOp struct {
+	// Present Vulkan driver manifest directories. Populated during toSystem.
+	Vulkan []*check.Absolute
+	// Present EGL vendor manifest directories. Populated during toSystem.
+	EGL []*check.Absolute
+}
+
+func (s *spGPUOp) toSystem(state *outcomeStateSys) error {
+	if state.Container.Flags&hst.FGPUConfig == 0 {
+		return errNotEnabled
+	}
+
+	var err error
+	if s.Vulkan, err = gpuPresentPaths(state, gpuVulkanPaths); err != nil {
+		return err
+	}
+	if s.EGL, err = gpuPresentPaths(state, gpuEGLPaths); err != nil {
+		return err
+	}
+
+	if len(s.Vulkan) == 0 && len(s.EGL) == 0 {
+		state.msg.Verbose("no GPU configuration present on the host")
+		return errNotEnabled
+	}
+	return nil
+}
+
+func (s *spGPUOp) toContainer(state *outcomeStateParams) error {
+	// appended to filesystem to not be covered by configured mount points
+	state.filesystem = slices.Clip(state.filesystem)
+	for _, a := range slices.Concat(s.Vulkan, s.EGL) {
+		state.filesystem = append(state.filesystem, hst.FilesystemConfigJSON{
+			FilesystemConfig: &hst.FSBind{Source: a, Optional: true}})
+	}
+
+	for _, v := range [...]struct {
+		key   string
+		paths []*check.Absolute
+		// explicitly configured variables taking precedence over key
+		explicit []string
+	}{
+		{vulkanDriverEnv, s.Vulkan, []string{vulkanDriverEnv, vulkanICDEnv}},
+		{eglVendorEnv, s.EGL, []string{eglVendorEnv}},
+	} {
+		if len(v.paths) == 0 {
+			continue
+		}
+		if slices.ContainsFunc(v.explicit, func(key string) bool { _, ok := state.Container.Env[key]; return ok }) {
+			continue
+		}
+		paths := make([]string, len(v.paths))
+		for i, a := range v.paths {
+			paths[i] = a.String()
+		}
+		state.env[v.key] = strings.Join(paths, ":")
+	}
+	return nil
+}
+
+// gpuPresentPaths returns the subset of candidates present as directories on the host.
+func gpuPresentPaths(state *outcomeStateSys, candidates []*check.Absolute) ([]*check.Absolute, error) {
+	var present []*check.Absolute
+	for _, a := range candidates {
+		if fi, err := state.k.stat(a.String()); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return nil, &hst.AppError{Step: "access GPU configuration", Err: err}
+			}
+			state.msg.Verbosef("GPU configuration %q not present, skipping", a.String())
+		} else if !fi.IsDir() {
+			state.msg.Verbosef("GPU configuration %q is not a directory, skipping", a.String())
+		} else {
+			present = append(present, a)
+		}
+	}
+	return present, nil
+}
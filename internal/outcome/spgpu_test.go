@@ -0,0 +1,112 @@
+package outcome
+
+import (
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"hakurei.app/container"
+	"hakurei.app/container/check"
+	"hakurei.app/container/stub"
+	"hakurei.app/hst"
+)
+
+func TestSpGPUOp(t *testing.T) {
+	t.Parallel()
+	config := hst.Template()
+
+	checkFilesystem := func(want ...string) extraCheckParamsFunc {
+		return func(t *testing.T, state *outcomeStateParams) {
+			wantFilesystem := make([]hst.FilesystemConfigJSON, len(want))
+			for i, name := range want {
+				wantFilesystem[i] = hst.FilesystemConfigJSON{FilesystemConfig: &hst.FSBind{Source: m(name), Optional: true}}
+			}
+			if !reflect.DeepEqual(state.filesystem, wantFilesystem) {
+				t.Errorf("toContainer: filesystem = %#v, want %#v", state.filesystem, wantFilesystem)
+			}
+		}
+	}
+
+	checkOpBehaviour(t, []opBehaviourTestCase{
+		{"not enabled", func(bool, bool) outcomeOp {
+			return new(spGPUOp)
+		}, func() *hst.Config {
+			c := hst.Template()
+			c.Container.Flags &= ^hst.FGPUConfig
+			return c
+		}, nil, nil, nil, nil, errNotEnabled, nil, nil, nil, nil, nil},
+
+		{"stat", func(bool, bool) outcomeOp {
+			return new(spGPUOp)
+		}, hst.Template, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{"/etc/vulkan/icd.d"}, stubFileInfoIsDir(false), stub.UniqueError(0)),
+		}, nil, nil, &hst.AppError{Step: "access GPU configuration", Err: stub.UniqueError(0)}, nil, nil, nil, nil, nil},
+
+		{"absent", func(bool, bool) outcomeOp {
+			return new(spGPUOp)
+		}, hst.Template, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{"/etc/vulkan/icd.d"}, stubFileInfoIsDir(false), &os.PathError{Op: "stat", Path: "/etc/vulkan/icd.d", Err: syscall.ENOENT}),
+			call("verbosef", stub.ExpectArgs{"GPU configuration %q not present, skipping", []any{"/etc/vulkan/icd.d"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/usr/share/vulkan/icd.d"}, stubFileInfoIsDir(false), &os.PathError{Op: "stat", Path: "/usr/share/vulkan/icd.d", Err: syscall.ENOENT}),
+			call("verbosef", stub.ExpectArgs{"GPU configuration %q not present, skipping", []any{"/usr/share/vulkan/icd.d"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/etc/glvnd/egl_vendor.d"}, stubFileInfoIsDir(false), nil),
+			call("verbosef", stub.ExpectArgs{"GPU configuration %q is not a directory, skipping", []any{"/etc/glvnd/egl_vendor.d"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/usr/share/glvnd/egl_vendor.d"}, stubFileInfoIsDir(false), &os.PathError{Op: "stat", Path: "/usr/share/glvnd/egl_vendor.d", Err: syscall.ENOENT}),
+			call("verbosef", stub.ExpectArgs{"GPU configuration %q not present, skipping", []any{"/usr/share/glvnd/egl_vendor.d"}}, nil, nil),
+			call("verbose", stub.ExpectArgs{[]any{"no GPU configuration present on the host"}}, nil, nil),
+		}, nil, nil, errNotEnabled, nil, nil, nil, nil, nil},
+
+		{"success env override", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spGPUOp)
+			}
+			return &spGPUOp{Vulkan: []*check.Absolute{m("/usr/share/vulkan/icd.d")}}
+		}, func() *hst.Config {
+			c := hst.Template()
+			c.Container.Env = map[string]string{vulkanICDEnv: "/run/opengl-driver/share/vulkan/icd.d"}
+			return c
+		}, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{"/etc/vulkan/icd.d"}, stubFileInfoIsDir(false), &os.PathError{Op: "stat", Path: "/etc/vulkan/icd.d", Err: syscall.ENOENT}),
+			call("verbosef", stub.ExpectArgs{"GPU configuration %q not present, skipping", []any{"/etc/vulkan/icd.d"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/usr/share/vulkan/icd.d"}, stubFileInfoIsDir(true), nil),
+			call("stat", stub.ExpectArgs{"/etc/glvnd/egl_vendor.d"}, stubFileInfoIsDir(false), &os.PathError{Op: "stat", Path: "/etc/glvnd/egl_vendor.d", Err: syscall.ENOENT}),
+			call("verbosef", stub.ExpectArgs{"GPU configuration %q not present, skipping", []any{"/etc/glvnd/egl_vendor.d"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/usr/share/glvnd/egl_vendor.d"}, stubFileInfoIsDir(false), &os.PathError{Op: "stat", Path: "/usr/share/glvnd/egl_vendor.d", Err: syscall.ENOENT}),
+			call("verbosef", stub.ExpectArgs{"GPU configuration %q not present, skipping", []any{"/usr/share/glvnd/egl_vendor.d"}}, nil, nil),
+		}, newI(), nil, nil, insertsOps(nil), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Ops: new(container.Ops),
+		}, paramsWantEnv(&hst.Config{Container: &hst.ContainerConfig{
+			Env: map[string]string{vulkanICDEnv: "/run/opengl-driver/share/vulkan/icd.d"},
+		}}, nil, checkFilesystem("/usr/share/vulkan/icd.d")), nil},
+
+		{"success", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spGPUOp)
+			}
+			return &spGPUOp{
+				Vulkan: []*check.Absolute{m("/etc/vulkan/icd.d"), m("/usr/share/vulkan/icd.d")},
+				EGL:    []*check.Absolute{m("/usr/share/glvnd/egl_vendor.d")},
+			}
+		}, hst.Template, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{"/etc/vulkan/icd.d"}, stubFileInfoIsDir(true), nil),
+			call("stat", stub.ExpectArgs{"/usr/share/vulkan/icd.d"}, stubFileInfoIsDir(true), nil),
+			call("stat", stub.ExpectArgs{"/etc/glvnd/egl_vendor.d"}, stubFileInfoIsDir(false), &os.PathError{Op: "stat", Path: "/etc/glvnd/egl_vendor.d", Err: syscall.ENOENT}),
+			call("verbosef", stub.ExpectArgs{"GPU configuration %q not present, skipping", []any{"/etc/glvnd/egl_vendor.d"}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/usr/share/glvnd/egl_vendor.d"}, stubFileInfoIsDir(true), nil),
+		}, newI(), nil, nil, insertsOps(nil), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Ops: new(container.Ops),
+		}, paramsWantEnv(config, map[string]string{
+			vulkanDriverEnv: "/etc/vulkan/icd.d:/usr/share/vulkan/icd.d",
+			eglVendorEnv:    "/usr/share/glvnd/egl_vendor.d",
+		}, checkFilesystem(
+			"/etc/vulkan/icd.d",
+			"/usr/share/vulkan/icd.d",
+			"/usr/share/glvnd/egl_vendor.d",
+		)), nil},
+	})
+}
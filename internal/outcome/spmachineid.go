@@ -0,0 +1,43 @@
+package outcome
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"hakurei.app/container/fhs"
+	"hakurei.app/hst"
+)
+
+func init() { registerOp(spMachineIDOp{}) }
+
+// machineIDSalt is hashed alongside [hst.ID] to derive the machine-id of an instance.
+const machineIDSalt = "hakurei.app/machine-id\x00"
+
+// spMachineIDOp places a machine-id unique to the instance in the container.
+type spMachineIDOp struct{}
+
+func (s spMachineIDOp) toSystem(state *outcomeStateSys) error {
+	if state.Container.Flags&hst.FMachineID == 0 {
+		return errNotEnabled
+	}
+	return nil
+}
+
+func (s spMachineIDOp) toContainer(state *outcomeStateParams) error {
+	data := []byte(machineID(state.ID) + "\n")
+	state.params.
+		Place(fhs.AbsEtc.Append("machine-id"), data).
+		Place(fhs.AbsVarLib.Append("dbus", "machine-id"), data)
+	return nil
+}
+
+// machineID returns the machine-id derived from id, formatted as described in machine-id(5).
+// The instance [hst.ID] is hashed as its leading bytes hold its creation time.
+func machineID(id *hst.ID) string {
+	sum := sha256.Sum256(append([]byte(machineIDSalt), id[:]...))
+	v := sum[:16]
+	// formatted as a version 4 UUID, the same as machine-id generated by systemd
+	v[6] = v[6]&0x0f | 0x40
+	v[8] = v[8]&0x3f | 0x80
+	return hex.EncodeToString(v)
+}
@@ -0,0 +1,53 @@
+package outcome
+
+import (
+	"testing"
+
+	"hakurei.app/container"
+	"hakurei.app/container/stub"
+	"hakurei.app/hst"
+)
+
+func TestSpMachineIDOp(t *testing.T) {
+	t.Parallel()
+	config := hst.Template()
+
+	checkOpBehaviour(t, []opBehaviourTestCase{
+		{"not enabled", func(bool, bool) outcomeOp { return spMachineIDOp{} }, func() *hst.Config {
+			c := hst.Template()
+			c.Container.Flags &= ^hst.FMachineID
+			return c
+		}, nil, nil, nil, nil, errNotEnabled, nil, nil, nil, nil, nil},
+
+		{"success", func(bool, bool) outcomeOp { return spMachineIDOp{} }, hst.Template, nil, []stub.Call{
+			// this op only checks configuration and does not make calls during toSystem
+		}, newI(), nil, nil, insertsOps(nil), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Ops: new(container.Ops).
+				Place(m("/etc/machine-id"), []byte("3fbc13d5632f4dc1a89b0747b3607aff\n")).
+				Place(m("/var/lib/dbus/machine-id"), []byte("3fbc13d5632f4dc1a89b0747b3607aff\n")),
+		}, paramsWantEnv(config, nil, nil), nil},
+	})
+}
+
+func TestMachineID(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		id   hst.ID
+		want string
+	}{
+		{"zero", hst.ID{}, "8a92d8d0f9ed4532a58cac17d56aa404"},
+		{"instance", checkExpectInstanceId, "3fbc13d5632f4dc1a89b0747b3607aff"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := machineID(&tc.id); got != tc.want {
+				t.Errorf("machineID: %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package outcome
+
+import (
+	"hakurei.app/container/check"
+	"hakurei.app/container/fhs"
+	"hakurei.app/container/std"
+	"hakurei.app/hst"
+)
+
+func init() { registerOp(spNetworkFilesOp{}) }
+
+// networkFiles are pathnames of host files describing name resolution, bound by spNetworkFilesOp.
+var networkFiles = []*check.Absolute{
+	fhs.AbsEtc.Append("resolv.conf"),
+	fhs.AbsEtc.Append("hosts"),
+	fhs.AbsEtc.Append("nsswitch.conf"),
+}
+
+// spNetworkFilesOp binds host name resolution configuration into the container.
+type spNetworkFilesOp struct{}
+
+func (s spNetworkFilesOp) toSystem(state *outcomeStateSys) error {
+	if state.Container.Flags&hst.FNetworkFiles == 0 {
+		return errNotEnabled
+	}
+	return nil
+}
+
+func (s spNetworkFilesOp) toContainer(state *outcomeStateParams) error {
+	for _, a := range networkFiles {
+		// files absent on the host are skipped by container init
+		state.params.Bind(a, a, std.BindOptional)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package outcome
+
+import (
+	"testing"
+
+	"hakurei.app/container"
+	"hakurei.app/container/std"
+	"hakurei.app/container/stub"
+	"hakurei.app/hst"
+)
+
+func TestSpNetworkFilesOp(t *testing.T) {
+	t.Parallel()
+	config := hst.Template()
+
+	checkOpBehaviour(t, []opBehaviourTestCase{
+		{"not enabled", func(bool, bool) outcomeOp { return spNetworkFilesOp{} }, func() *hst.Config {
+			c := hst.Template()
+			c.Container.Flags &= ^hst.FNetworkFiles
+			return c
+		}, nil, nil, nil, nil, errNotEnabled, nil, nil, nil, nil, nil},
+
+		{"success", func(bool, bool) outcomeOp { return spNetworkFilesOp{} }, hst.Template, nil, []stub.Call{
+			// this op only checks configuration and does not make calls during toSystem
+		}, newI(), nil, nil, insertsOps(nil), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Ops: new(container.Ops).
+				Bind(m("/etc/resolv.conf"), m("/etc/resolv.conf"), std.BindOptional).
+				Bind(m("/etc/hosts"), m("/etc/hosts"), std.BindOptional).
+				Bind(m("/etc/nsswitch.conf"), m("/etc/nsswitch.conf"), std.BindOptional),
+		}, paramsWantEnv(config, nil, nil), nil},
+	})
+}
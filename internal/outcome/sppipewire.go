@@ -0,0 +1,52 @@
+package outcome
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"hakurei.app/container/check"
+	"hakurei.app/hst"
+	"hakurei.app/internal/acl"
+)
+
+// pipewireSocketName is the name of the default PipeWire native socket in XDG_RUNTIME_DIR.
+const pipewireSocketName = "pipewire-0"
+
+func init() { registerOp(new(spPipeWireOp)) }
+
+// spPipeWireOp exports the PipeWire native socket to the container.
+// Runs after spRuntimeOp.
+type spPipeWireOp struct {
+	// Path to host PipeWire socket. Populated during toSystem.
+	SocketPath *check.Absolute
+}
+
+func (s *spPipeWireOp) toSystem(state *outcomeStateSys) error {
+	if state.et&hst.EPipeWire == 0 {
+		return errNotEnabled
+	}
+
+	// PipeWire socket (usually `/run/user/%d/pipewire-0`)
+	socketPath := state.sc.RuntimePath.Append(pipewireSocketName)
+	if _, err := state.k.stat(socketPath.String()); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return &hst.AppError{Step: fmt.Sprintf("access PipeWire socket %q", socketPath), Err: err}
+		}
+		return newWithMessageError(fmt.Sprintf("PipeWire socket %q not found", socketPath), err)
+	}
+
+	// the socket is reached through XDG_RUNTIME_DIR, which the target user is granted execute on
+	state.ensureRuntimeDir()
+	s.SocketPath = socketPath
+	state.sys.UpdatePermType(hst.EPipeWire, socketPath, acl.Read, acl.Write, acl.Execute)
+	return nil
+}
+
+func (s *spPipeWireOp) toContainer(state *outcomeStateParams) error {
+	if s.SocketPath == nil {
+		return newWithMessage("invalid PipeWire socket path")
+	}
+	state.params.Bind(s.SocketPath, state.runtimeDir.Append(pipewireSocketName), 0)
+	return nil
+}
@@ -0,0 +1,90 @@
+package outcome
+
+import (
+	"os"
+	"testing"
+
+	"hakurei.app/container"
+	"hakurei.app/container/stub"
+	"hakurei.app/hst"
+	"hakurei.app/internal/acl"
+	"hakurei.app/internal/system"
+)
+
+func TestSpPipeWireOp(t *testing.T) {
+	t.Parallel()
+	config := hst.Template()
+
+	newConfig := func() *hst.Config {
+		c := hst.Template()
+		*c.Enablements |= hst.Enablements(hst.EPipeWire)
+		return c
+	}
+
+	checkOpBehaviour(t, []opBehaviourTestCase{
+		{"not enabled", func(bool, bool) outcomeOp {
+			return new(spPipeWireOp)
+		}, hst.Template, nil, nil, nil, nil, errNotEnabled, nil, nil, nil, nil, nil},
+
+		{"stat", func(bool, bool) outcomeOp {
+			return new(spPipeWireOp)
+		}, newConfig, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{wantRuntimePath + "/pipewire-0"}, (*stubFi)(nil), stub.UniqueError(0)),
+		}, nil, nil, &hst.AppError{
+			Step: `access PipeWire socket "/proc/nonexistent/xdg_runtime_dir/pipewire-0"`,
+			Err:  stub.UniqueError(0),
+		}, nil, nil, nil, nil, nil},
+
+		{"nonexistent", func(bool, bool) outcomeOp {
+			return new(spPipeWireOp)
+		}, newConfig, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{wantRuntimePath + "/pipewire-0"}, (*stubFi)(nil), os.ErrNotExist),
+		}, nil, nil, &hst.AppError{
+			Step: "finalise",
+			Err:  os.ErrNotExist,
+			Msg:  `PipeWire socket "/proc/nonexistent/xdg_runtime_dir/pipewire-0" not found`,
+		}, nil, nil, nil, nil, nil},
+
+		{"invalid", func(isShim, clearUnexported bool) outcomeOp {
+			// emulates a corrupted op received by the shim
+			if !isShim || clearUnexported {
+				return new(spPipeWireOp)
+			}
+			return &spPipeWireOp{SocketPath: m(wantRuntimePath + "/pipewire-0")}
+		}, newConfig, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{wantRuntimePath + "/pipewire-0"}, &stubFi{mode: os.ModeSocket | 0777}, nil),
+		}, newI().
+			// state.ensureRuntimeDir
+			Ensure(m(wantRuntimePath), 0700).
+			UpdatePermType(system.User, m(wantRuntimePath), acl.Execute).
+			Ensure(m(wantRunDirPath), 0700).
+			UpdatePermType(system.User, m(wantRunDirPath), acl.Execute).
+			// toSystem
+			UpdatePermType(hst.EPipeWire, m(wantRuntimePath+"/pipewire-0"), acl.Read, acl.Write, acl.Execute), nil, nil, insertsOps(afterSpRuntimeOp(nil)), nil, nil, nil, &hst.AppError{
+			Step: "finalise",
+			Err:  os.ErrInvalid,
+			Msg:  "invalid PipeWire socket path",
+		}},
+
+		{"success", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spPipeWireOp)
+			}
+			return &spPipeWireOp{SocketPath: m(wantRuntimePath + "/pipewire-0")}
+		}, newConfig, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{wantRuntimePath + "/pipewire-0"}, &stubFi{mode: os.ModeSocket | 0777}, nil),
+		}, newI().
+			// state.ensureRuntimeDir
+			Ensure(m(wantRuntimePath), 0700).
+			UpdatePermType(system.User, m(wantRuntimePath), acl.Execute).
+			Ensure(m(wantRunDirPath), 0700).
+			UpdatePermType(system.User, m(wantRunDirPath), acl.Execute).
+			// toSystem
+			UpdatePermType(hst.EPipeWire, m(wantRuntimePath+"/pipewire-0"), acl.Read, acl.Write, acl.Execute), nil, nil, insertsOps(afterSpRuntimeOp(nil)), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Ops: new(container.Ops).
+				Bind(m(wantRuntimePath+"/pipewire-0"), m("/run/user/1000/pipewire-0"), 0),
+		}, paramsWantEnv(config, nil, nil), nil},
+	})
+}
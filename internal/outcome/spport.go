@@ -0,0 +1,253 @@
+package outcome
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"hakurei.app/hst"
+	"hakurei.app/message"
+)
+
+func init() { registerOp(spPortOp{}) }
+
+// spPortOp publishes ports listened on in the container network namespace on the host loopback interface.
+type spPortOp struct{}
+
+func (s spPortOp) toSystem(state *outcomeStateSys) error {
+	if len(state.Container.PublishPorts) == 0 {
+		return errNotEnabled
+	}
+	return nil
+}
+
+func (s spPortOp) toContainer(state *outcomeStateParams) error {
+	// validated via hst to not be combined with FHostNet
+	state.params.LoopbackOnly = true
+	state.params.ServeDial = true
+	state.publishPorts = state.Container.PublishPorts
+	return nil
+}
+
+// udpIdleTimeout is the duration a forwarded udp flow is kept without receiving a reply.
+const udpIdleTimeout = 2 * time.Minute
+
+// udpPacketMax is the size of buffers holding forwarded udp packets.
+const udpPacketMax = 1 << 16
+
+// portPublisher listens on published ports on the host loopback interface and forwards
+// connections into the container network namespace via dial. Closing portPublisher
+// closes its listeners and all forwarded connections.
+type portPublisher struct {
+	msg message.Msg
+	// provides the Dial method of [container.Container]
+	dial func(network string, port uint16) (*os.File, error)
+
+	// guards all fields below
+	mu sync.Mutex
+	// set once Close is called
+	closed bool
+	// listeners and forwarded connections
+	conns map[io.Closer]struct{}
+
+	// forwarding goroutines
+	wg sync.WaitGroup
+}
+
+// newPortPublisher returns a new portPublisher listening on the host ports of ports.
+func newPortPublisher(
+	msg message.Msg,
+	dial func(network string, port uint16) (*os.File, error),
+	ports []hst.PortMap,
+) (*portPublisher, error) {
+	p := &portPublisher{msg: msg, dial: dial, conns: make(map[io.Closer]struct{})}
+	for _, port := range ports {
+		addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port.Host)))
+		switch port.Network() {
+		case "udp":
+			c, err := net.ListenPacket("udp", addr)
+			if err != nil {
+				_ = p.Close()
+				return nil, err
+			}
+			p.track(c)
+			p.wg.Add(1)
+			go p.serveUDP(c.(*net.UDPConn), port.Container)
+
+		default:
+			l, err := net.Listen("tcp", addr)
+			if err != nil {
+				_ = p.Close()
+				return nil, err
+			}
+			p.track(l)
+			p.wg.Add(1)
+			go p.serveTCP(l, port.Container)
+		}
+		msg.Verbosef("publishing container port %d/%s on %s", port.Container, port.Network(), addr)
+	}
+	return p, nil
+}
+
+// track adds c to connections closed by Close, and closes it if Close was already called.
+func (p *portPublisher) track(c io.Closer) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		_ = c.Close()
+		return false
+	}
+	p.conns[c] = struct{}{}
+	return true
+}
+
+// untrack removes c from connections closed by Close and closes it.
+func (p *portPublisher) untrack(c io.Closer) {
+	p.mu.Lock()
+	delete(p.conns, c)
+	p.mu.Unlock()
+	_ = c.Close()
+}
+
+// dialConn dials port in the container network namespace and returns the connection.
+func (p *portPublisher) dialConn(network string, port uint16) (net.Conn, error) {
+	f, err := p.dial(network, port)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return net.FileConn(f)
+}
+
+// serveTCP accepts connections on l until it is closed.
+func (p *portPublisher) serveTCP(l net.Listener, port uint16) {
+	defer p.wg.Done()
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				p.msg.Verbosef("cannot accept connection for port %d: %v", port, err)
+			}
+			return
+		}
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			if !p.track(c) {
+				return
+			}
+			defer p.untrack(c)
+
+			inner, dialErr := p.dialConn("tcp", port)
+			if dialErr != nil {
+				p.msg.Verbosef("cannot forward connection from %s: %v", c.RemoteAddr(), dialErr)
+				return
+			}
+			if !p.track(inner) {
+				return
+			}
+			defer p.untrack(inner)
+
+			done := make(chan struct{})
+			go func() { p.splice(inner, c); close(done) }()
+			p.splice(c, inner)
+			<-done
+		}()
+	}
+}
+
+// splice copies from src to dst until EOF, then shuts down the write side of dst.
+func (p *portPublisher) splice(dst, src net.Conn) {
+	if _, err := io.Copy(dst, src); err != nil && !errors.Is(err, net.ErrClosed) {
+		p.msg.Verbosef("cannot forward connection: %v", err)
+	}
+	if c, ok := dst.(interface{ CloseWrite() error }); ok {
+		_ = c.CloseWrite()
+	}
+}
+
+// serveUDP forwards packets received on c until it is closed, with a connected socket
+// dialed in the container network namespace for every remote address.
+func (p *portPublisher) serveUDP(c *net.UDPConn, port uint16) {
+	defer p.wg.Done()
+
+	var mu sync.Mutex
+	flows := make(map[string]net.Conn)
+	buf := make([]byte, udpPacketMax)
+	for {
+		n, addr, err := c.ReadFromUDP(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				p.msg.Verbosef("cannot receive packet for port %d: %v", port, err)
+			}
+			return
+		}
+
+		mu.Lock()
+		inner, ok := flows[addr.String()]
+		mu.Unlock()
+		if !ok {
+			if inner, err = p.dialConn("udp", port); err != nil {
+				p.msg.Verbosef("cannot forward packet from %s: %v", addr, err)
+				continue
+			}
+			if !p.track(inner) {
+				return
+			}
+			mu.Lock()
+			flows[addr.String()] = inner
+			mu.Unlock()
+
+			p.wg.Add(1)
+			go func() {
+				defer p.wg.Done()
+				defer func() {
+					mu.Lock()
+					delete(flows, addr.String())
+					mu.Unlock()
+					p.untrack(inner)
+				}()
+
+				reply := make([]byte, udpPacketMax)
+				for {
+					if err := inner.SetReadDeadline(time.Now().Add(udpIdleTimeout)); err != nil {
+						return
+					}
+					rn, err := inner.Read(reply)
+					if err != nil {
+						return
+					}
+					if _, err = c.WriteToUDP(reply[:rn], addr); err != nil {
+						return
+					}
+				}
+			}()
+		}
+
+		if _, err = inner.Write(buf[:n]); err != nil {
+			p.msg.Verbosef("cannot forward packet from %s: %v", addr, err)
+		}
+	}
+}
+
+// Close closes all listeners and forwarded connections, and waits for forwarding to stop.
+func (p *portPublisher) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	var errs []error
+	for c := range p.conns {
+		if err := c.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			errs = append(errs, err)
+		}
+	}
+	clear(p.conns)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	return errors.Join(errs...)
+}
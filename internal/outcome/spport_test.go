@@ -0,0 +1,208 @@
+package outcome
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"hakurei.app/container"
+	"hakurei.app/container/stub"
+	"hakurei.app/hst"
+	"hakurei.app/message"
+)
+
+func TestSpPortOp(t *testing.T) {
+	t.Parallel()
+
+	ports := []hst.PortMap{
+		{Container: 8080, Host: 18080},
+		{Protocol: "udp", Container: 5353, Host: 15353},
+	}
+	newConfig := func() *hst.Config {
+		c := hst.Template()
+		c.Container.PublishPorts = slices.Clone(ports)
+		return c
+	}
+
+	checkOpBehaviour(t, []opBehaviourTestCase{
+		{"not enabled", func(bool, bool) outcomeOp {
+			return spPortOp{}
+		}, hst.Template, nil, nil, nil, nil, errNotEnabled, nil, nil, nil, nil, nil},
+
+		{"success", func(bool, bool) outcomeOp {
+			return spPortOp{}
+		}, newConfig, nil, []stub.Call{
+			// this op does not make calls during toSystem
+		}, newI(), nil, nil, nil, []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			LoopbackOnly: true,
+			ServeDial:    true,
+		}, func(t *testing.T, state *outcomeStateParams) {
+			if !reflect.DeepEqual(state.publishPorts, ports) {
+				t.Errorf("toContainer: publishPorts = %#v, want %#v", state.publishPorts, ports)
+			}
+		}, nil},
+	})
+}
+
+func TestPortPublisher(t *testing.T) {
+	t.Parallel()
+
+	// stands in for a service in the container network namespace
+	tcpService, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: error = %v", err)
+	}
+	t.Cleanup(func() { _ = tcpService.Close() })
+	go func() {
+		for {
+			c, acceptErr := tcpService.Accept()
+			if acceptErr != nil {
+				return
+			}
+			go func() { _, _ = io.Copy(c, c); _ = c.Close() }()
+		}
+	}()
+
+	udpService, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: error = %v", err)
+	}
+	t.Cleanup(func() { _ = udpService.Close() })
+	go func() {
+		buf := make([]byte, udpPacketMax)
+		for {
+			n, addr, readErr := udpService.ReadFromUDP(buf)
+			if readErr != nil {
+				return
+			}
+			_, _ = udpService.WriteToUDP(append([]byte("udp "), buf[:n]...), addr)
+		}
+	}()
+
+	tcpPort := uint16(tcpService.Addr().(*net.TCPAddr).Port)
+	udpPort := uint16(udpService.LocalAddr().(*net.UDPAddr).Port)
+	dial := func(network string, port uint16) (*os.File, error) {
+		if port == 1 {
+			return nil, container.DialError("connection refused")
+		}
+		c, dialErr := net.Dial(network, net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port))))
+		if dialErr != nil {
+			return nil, dialErr
+		}
+		defer c.Close()
+		return c.(interface{ File() (*os.File, error) }).File()
+	}
+
+	// host ports are allocated by the kernel and looked up afterwards
+	hostPort := func(network string) uint16 {
+		switch network {
+		case "udp":
+			c, listenErr := net.ListenPacket("udp", "127.0.0.1:0")
+			if listenErr != nil {
+				t.Fatalf("ListenPacket: error = %v", listenErr)
+			}
+			defer c.Close()
+			return uint16(c.LocalAddr().(*net.UDPAddr).Port)
+		default:
+			l, listenErr := net.Listen("tcp", "127.0.0.1:0")
+			if listenErr != nil {
+				t.Fatalf("Listen: error = %v", listenErr)
+			}
+			defer l.Close()
+			return uint16(l.Addr().(*net.TCPAddr).Port)
+		}
+	}
+	ports := []hst.PortMap{
+		{Container: tcpPort, Host: hostPort("tcp")},
+		{Protocol: "udp", Container: udpPort, Host: hostPort("udp")},
+		{Container: 1, Host: hostPort("tcp")},
+	}
+
+	msg := message.New(nil)
+	p, err := newPortPublisher(msg, dial, ports)
+	if err != nil {
+		t.Fatalf("newPortPublisher: error = %v", err)
+	}
+
+	t.Run("tcp", func(t *testing.T) {
+		c, dialErr := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(ports[0].Host))))
+		if dialErr != nil {
+			t.Fatalf("Dial: error = %v", dialErr)
+		}
+		defer c.Close()
+
+		want := []byte("hello through the published port\n")
+		got := make([]byte, len(want))
+		if _, err = c.Write(want); err != nil {
+			t.Fatalf("Write: error = %v", err)
+		}
+		if _, err = io.ReadFull(c, got); err != nil {
+			t.Fatalf("ReadFull: error = %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("ReadFull: %q, want %q", got, want)
+		}
+	})
+
+	t.Run("udp", func(t *testing.T) {
+		c, dialErr := net.Dial("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(ports[1].Host))))
+		if dialErr != nil {
+			t.Fatalf("Dial: error = %v", dialErr)
+		}
+		defer c.Close()
+
+		if _, err = c.Write([]byte("ping")); err != nil {
+			t.Fatalf("Write: error = %v", err)
+		}
+		buf := make([]byte, udpPacketMax)
+		if n, readErr := c.Read(buf); readErr != nil {
+			t.Fatalf("Read: error = %v", readErr)
+		} else if string(buf[:n]) != "udp ping" {
+			t.Errorf("Read: %q", string(buf[:n]))
+		}
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		c, dialErr := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(ports[2].Host))))
+		if dialErr != nil {
+			t.Fatalf("Dial: error = %v", dialErr)
+		}
+		defer c.Close()
+
+		// closed by the publisher after the container refuses the connection
+		if _, readErr := c.Read(make([]byte, 1)); !errors.Is(readErr, io.EOF) {
+			t.Errorf("Read: error = %v", readErr)
+		}
+	})
+
+	if err = p.Close(); err != nil {
+		t.Errorf("Close: error = %v", err)
+	}
+	if _, err = net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(ports[0].Host)))); err == nil {
+		t.Error("Dial: unexpected success after Close")
+	}
+
+	t.Run("listen", func(t *testing.T) {
+		l, listenErr := net.Listen("tcp", "127.0.0.1:0")
+		if listenErr != nil {
+			t.Fatalf("Listen: error = %v", listenErr)
+		}
+		defer l.Close()
+
+		if _, err = newPortPublisher(msg, dial, []hst.PortMap{
+			{Container: tcpPort, Host: hostPort("tcp")},
+			{Container: tcpPort, Host: uint16(l.Addr().(*net.TCPAddr).Port)},
+		}); !errors.Is(err, syscall.EADDRINUSE) {
+			t.Errorf("newPortPublisher: error = %v", err)
+		}
+	})
+}
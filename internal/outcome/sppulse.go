@@ -1,7 +1,6 @@
 package outcome
 
 import (
-	"encoding/gob"
 	"errors"
 	"fmt"
 	"io"
@@ -17,7 +16,7 @@ import (
 
 const pulseCookieSizeMax = 1 << 8
 
-func init() { gob.Register(new(spPulseOp)) }
+func init() { registerOp(new(spPulseOp)) }
 
 // spPulseOp exports the PulseAudio server to the container.
 // Runs after spRuntimeOp.
@@ -81,7 +80,7 @@ func (s *spPulseOp) toContainer(state *outcomeStateParams) error {
 	state.env["PULSE_SERVER"] = "unix:" + innerPulseSocket.String()
 
 	if s.Cookie != nil {
-		innerDst := hst.AbsPrivateTmp.Append("/pulse-cookie")
+		innerDst := state.Container.PrivateTmpDir().Append("/pulse-cookie")
 
 		if s.CookieSize < 0 || s.CookieSize > pulseCookieSizeMax {
 			return newWithMessage("unexpected PulseAudio cookie size")
@@ -1,8 +1,6 @@
 package outcome
 
 import (
-	"encoding/gob"
-
 	"hakurei.app/container/check"
 	"hakurei.app/container/fhs"
 	"hakurei.app/container/std"
@@ -56,7 +54,7 @@ const (
 	envXDGSessionType = "XDG_SESSION_TYPE"
 )
 
-func init() { gob.Register(new(spRuntimeOp)) }
+func init() { registerOp(new(spRuntimeOp)) }
 
 const (
 	sessionTypeUnspec = iota
@@ -0,0 +1,55 @@
+package outcome
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+
+	"hakurei.app/container/check"
+	"hakurei.app/hst"
+	"hakurei.app/internal/acl"
+)
+
+func init() { registerOp(new(spRuntimeSocketOp)) }
+
+// spRuntimeSocketOp binds explicitly configured sockets in the host XDG_RUNTIME_DIR into the container.
+// Runs after spRuntimeOp.
+type spRuntimeSocketOp struct {
+	// Pathnames of host sockets. Populated during toSystem.
+	Sockets []*check.Absolute
+}
+
+func (s *spRuntimeSocketOp) toSystem(state *outcomeStateSys) error {
+	if len(state.Container.RuntimeSockets) == 0 {
+		return errNotEnabled
+	}
+
+	for _, name := range state.Container.RuntimeSockets { // validated via hst
+		socketPath := state.sc.RuntimePath.Append(name)
+		if fi, err := state.k.stat(socketPath.String()); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return &hst.AppError{Step: fmt.Sprintf("access runtime socket %q", socketPath), Err: err}
+			}
+			return newWithMessageError(fmt.Sprintf("runtime socket %q not found", socketPath), err)
+		} else if fi.Mode()&os.ModeSocket == 0 {
+			return newWithMessage(fmt.Sprintf("%q is not a socket", socketPath))
+		}
+		s.Sockets = append(s.Sockets, socketPath)
+	}
+
+	// sockets are reached through XDG_RUNTIME_DIR, which the target user is granted execute on
+	state.ensureRuntimeDir()
+	for _, a := range s.Sockets {
+		state.sys.UpdatePerm(a, acl.Read, acl.Write, acl.Execute)
+	}
+	return nil
+}
+
+func (s *spRuntimeSocketOp) toContainer(state *outcomeStateParams) error {
+	for _, a := range s.Sockets {
+		state.params.Bind(a, state.runtimeDir.Append(path.Base(a.String())), 0)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package outcome
+
+import (
+	"os"
+	"testing"
+
+	"hakurei.app/container"
+	"hakurei.app/container/check"
+	"hakurei.app/container/stub"
+	"hakurei.app/hst"
+	"hakurei.app/internal/acl"
+	"hakurei.app/internal/system"
+)
+
+func TestSpRuntimeSocketOp(t *testing.T) {
+	t.Parallel()
+	config := hst.Template()
+
+	newConfig := func() *hst.Config {
+		c := hst.Template()
+		c.Container.RuntimeSockets = []string{"bus", "doc-portal"}
+		return c
+	}
+
+	checkOpBehaviour(t, []opBehaviourTestCase{
+		{"not enabled", func(bool, bool) outcomeOp {
+			return new(spRuntimeSocketOp)
+		}, hst.Template, nil, nil, nil, nil, errNotEnabled, nil, nil, nil, nil, nil},
+
+		{"stat", func(bool, bool) outcomeOp {
+			return new(spRuntimeSocketOp)
+		}, newConfig, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{wantRuntimePath + "/bus"}, (*stubFi)(nil), stub.UniqueError(0)),
+		}, nil, nil, &hst.AppError{
+			Step: `access runtime socket "/proc/nonexistent/xdg_runtime_dir/bus"`,
+			Err:  stub.UniqueError(0),
+		}, nil, nil, nil, nil, nil},
+
+		{"nonexistent", func(bool, bool) outcomeOp {
+			return new(spRuntimeSocketOp)
+		}, newConfig, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{wantRuntimePath + "/bus"}, &stubFi{mode: os.ModeSocket | 0666}, nil),
+			call("stat", stub.ExpectArgs{wantRuntimePath + "/doc-portal"}, (*stubFi)(nil), os.ErrNotExist),
+		}, nil, nil, &hst.AppError{
+			Step: "finalise",
+			Err:  os.ErrNotExist,
+			Msg:  `runtime socket "/proc/nonexistent/xdg_runtime_dir/doc-portal" not found`,
+		}, nil, nil, nil, nil, nil},
+
+		{"not socket", func(bool, bool) outcomeOp {
+			return new(spRuntimeSocketOp)
+		}, newConfig, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{wantRuntimePath + "/bus"}, &stubFi{mode: os.ModeDir | 0700}, nil),
+		}, nil, nil, &hst.AppError{
+			Step: "finalise",
+			Err:  os.ErrInvalid,
+			Msg:  `"/proc/nonexistent/xdg_runtime_dir/bus" is not a socket`,
+		}, nil, nil, nil, nil, nil},
+
+		{"success", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spRuntimeSocketOp)
+			}
+			return &spRuntimeSocketOp{Sockets: []*check.Absolute{
+				m(wantRuntimePath + "/bus"),
+				m(wantRuntimePath + "/doc-portal"),
+			}}
+		}, newConfig, nil, []stub.Call{
+			call("stat", stub.ExpectArgs{wantRuntimePath + "/bus"}, &stubFi{mode: os.ModeSocket | 0666}, nil),
+			call("stat", stub.ExpectArgs{wantRuntimePath + "/doc-portal"}, &stubFi{mode: os.ModeSocket | 0600}, nil),
+		}, newI().
+			// state.ensureRuntimeDir
+			Ensure(m(wantRuntimePath), 0700).
+			UpdatePermType(system.User, m(wantRuntimePath), acl.Execute).
+			Ensure(m(wantRunDirPath), 0700).
+			UpdatePermType(system.User, m(wantRunDirPath), acl.Execute).
+			// toSystem
+			UpdatePerm(m(wantRuntimePath+"/bus"), acl.Read, acl.Write, acl.Execute).
+			UpdatePerm(m(wantRuntimePath+"/doc-portal"), acl.Read, acl.Write, acl.Execute), nil, nil, insertsOps(afterSpRuntimeOp(nil)), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Ops: new(container.Ops).
+				Bind(m(wantRuntimePath+"/bus"), m("/run/user/1000/bus"), 0).
+				Bind(m(wantRuntimePath+"/doc-portal"), m("/run/user/1000/doc-portal"), 0),
+		}, paramsWantEnv(config, nil, nil), nil},
+	})
+}
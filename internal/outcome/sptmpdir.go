@@ -1,8 +1,6 @@
 package outcome
 
 import (
-	"encoding/gob"
-
 	"hakurei.app/container/check"
 	"hakurei.app/container/fhs"
 	"hakurei.app/container/std"
@@ -11,7 +9,7 @@ import (
 	"hakurei.app/internal/system"
 )
 
-func init() { gob.Register(spTmpdirOp{}) }
+func init() { registerOp(spTmpdirOp{}) }
 
 // spTmpdirOp sets up TMPDIR inside the container.
 type spTmpdirOp struct{}
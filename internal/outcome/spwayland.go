@@ -1,15 +1,13 @@
 package outcome
 
 import (
-	"encoding/gob"
-
 	"hakurei.app/container/check"
 	"hakurei.app/hst"
 	"hakurei.app/internal/acl"
 	"hakurei.app/internal/wayland"
 )
 
-func init() { gob.Register(new(spWaylandOp)) }
+func init() { registerOp(new(spWaylandOp)) }
 
 // spWaylandOp exports the Wayland display server to the container.
 // Runs after spRuntimeOp.
@@ -1,12 +1,13 @@
 package outcome
 
 import (
-	"encoding/gob"
 	"errors"
 	"fmt"
 	"io/fs"
+	"os"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"hakurei.app/container/check"
 	"hakurei.app/container/fhs"
@@ -16,12 +17,14 @@ import (
 
 var absX11SocketDir = fhs.AbsTmp.Append(".X11-unix")
 
-func init() { gob.Register(new(spX11Op)) }
+func init() { registerOp(new(spX11Op)) }
 
 // spX11Op exports the X11 display server to the container.
 type spX11Op struct {
 	// Value of $DISPLAY, stored during toSystem
 	Display string
+	// Path to host X11 authority file. Populated during toSystem if DirectXauthority or X11Cookie is true.
+	Xauthority *check.Absolute
 }
 
 func (s *spX11Op) toSystem(state *outcomeStateSys) error {
@@ -38,9 +41,11 @@ func (s *spX11Op) toSystem(state *outcomeStateSys) error {
 	// the socket file at `/tmp/.X11-unix/X%d` is typically owned by the priv user
 	// and not accessible by the target user
 	var socketPath *check.Absolute
+	display := -1
 	if len(s.Display) > 1 && s.Display[0] == ':' { // `:%d`
 		if n, err := strconv.Atoi(s.Display[1:]); err == nil && n >= 0 {
 			socketPath = absX11SocketDir.Append("X" + strconv.Itoa(n))
+			display = n
 		}
 	} else if len(s.Display) > 5 && strings.HasPrefix(s.Display, "unix:") { // `unix:%s`
 		if a, err := check.NewAbs(s.Display[5:]); err == nil {
@@ -60,6 +65,34 @@ func (s *spX11Op) toSystem(state *outcomeStateSys) error {
 		}
 	}
 
+	if state.directXauthority { // bind mount X11 authority file (insecure)
+		state.msg.Verbose("direct X11 authority access, PROCEED WITH CAUTION")
+		if a, err := discoverXauthority(state.k); err != nil {
+			return err
+		} else {
+			s.Xauthority = a
+			// the target user traverses these directories when the file is bind mounted
+			for _, dir := range xauthorityParents(a, state.HomePath, state.sc.RuntimePath) {
+				state.sys.UpdatePermType(hst.EX11, dir, acl.Execute)
+			}
+			state.sys.UpdatePermType(hst.EX11, a, acl.Read)
+		}
+	}
+
+	if state.x11Cookie {
+		if display < 0 {
+			state.msg.Verbosef("cannot generate X11 cookie for display %q, falling back to ChangeHosts", s.Display)
+		} else if pathname, err := state.k.lookPath("xauth"); err != nil {
+			state.msg.Verbosef("cannot locate xauth, falling back to ChangeHosts: %v", err)
+		} else if xauth, err := check.NewAbs(pathname); err != nil {
+			return &hst.AppError{Step: "locate xauth", Err: err}
+		} else {
+			s.Xauthority = state.instance().Append("Xauthority")
+			state.sys.Xauth(xauth, s.Xauthority, display)
+			return nil
+		}
+	}
+
 	state.sys.ChangeHosts("#" + state.uid.String())
 	return nil
 }
@@ -67,5 +100,61 @@ func (s *spX11Op) toSystem(state *outcomeStateSys) error {
 func (s *spX11Op) toContainer(state *outcomeStateParams) error {
 	state.env["DISPLAY"] = s.Display
 	state.params.Bind(absX11SocketDir, absX11SocketDir, 0)
+
+	if s.Xauthority != nil {
+		innerDst := state.Container.PrivateTmpDir().Append("Xauthority")
+		state.env["XAUTHORITY"] = innerDst.String()
+		state.params.Bind(s.Xauthority, innerDst, 0)
+	}
+	return nil
+}
+
+// discoverXauthority returns the pathname of the X11 authority file of the current user.
+func discoverXauthority(k syscallDispatcher) (*check.Absolute, error) {
+	const xauthLocateStep = "locate X11 authority file"
+
+	var pathname *check.Absolute
+	if p, ok := k.lookupEnv("XAUTHORITY"); ok {
+		if a, err := check.NewAbs(p); err != nil {
+			return nil, &hst.AppError{Step: xauthLocateStep, Err: err}
+		} else {
+			pathname = a
+		}
+	} else if p, ok = k.lookupEnv("HOME"); ok {
+		if a, err := check.NewAbs(p); err != nil {
+			return nil, &hst.AppError{Step: xauthLocateStep, Err: err}
+		} else {
+			pathname = a.Append(".Xauthority")
+		}
+	} else {
+		return nil, newWithMessage("neither XAUTHORITY nor HOME is set")
+	}
+
+	if fi, err := k.stat(pathname.String()); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, &hst.AppError{Step: fmt.Sprintf("access X11 authority file %q", pathname), Err: err}
+		}
+		return nil, newWithMessageError(fmt.Sprintf("X11 authority file %q not found", pathname), err)
+	} else if fi.IsDir() {
+		return nil, &hst.AppError{Step: fmt.Sprintf("access X11 authority file %q", pathname),
+			Err: &os.PathError{Op: "stat", Path: pathname.String(), Err: syscall.EISDIR}}
+	}
+	return pathname, nil
+}
+
+// xauthorityParents returns the directories containing pathname up to and including home.
+// The runtime directory already grants execute access, so parents are not collected beyond it.
+// No directories are returned if pathname is under neither.
+func xauthorityParents(pathname, home, runtime *check.Absolute) []*check.Absolute {
+	var dirs []*check.Absolute
+	for dir := pathname.Dir(); dir.String() != fhs.Root; dir = dir.Dir() {
+		if dir.Is(runtime) {
+			return dirs
+		}
+		dirs = append(dirs, dir)
+		if dir.Is(home) {
+			return dirs
+		}
+	}
 	return nil
 }
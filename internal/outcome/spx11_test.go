@@ -2,12 +2,17 @@ package outcome
 
 import (
 	"os"
+	"os/exec"
+	"reflect"
+	"syscall"
 	"testing"
 
 	"hakurei.app/container"
+	"hakurei.app/container/check"
 	"hakurei.app/container/stub"
 	"hakurei.app/hst"
 	"hakurei.app/internal/acl"
+	"hakurei.app/internal/system"
 )
 
 func TestSpX11Op(t *testing.T) {
@@ -93,6 +98,157 @@ func TestSpX11Op(t *testing.T) {
 			"DISPLAY": "unix:/tmp/.X11-unix/X0",
 		}, nil), nil},
 
+		{"xauthority unset", func(bool, bool) outcomeOp {
+			return new(spX11Op)
+		}, func() *hst.Config {
+			c := hst.Template()
+			*c.Enablements |= hst.Enablements(hst.EX11)
+			c.DirectXauthority = true
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"DISPLAY"}, ":0", nil),
+			call("stat", stub.ExpectArgs{"/tmp/.X11-unix/X0"}, (*stubFi)(nil), nil),
+			call("verbose", stub.ExpectArgs{[]any{"direct X11 authority access, PROCEED WITH CAUTION"}}, nil, nil),
+			call("lookupEnv", stub.ExpectArgs{"XAUTHORITY"}, nil, nil),
+			call("lookupEnv", stub.ExpectArgs{"HOME"}, nil, nil),
+		}, nil, nil, &hst.AppError{
+			Step: "finalise",
+			Err:  os.ErrInvalid,
+			Msg:  "neither XAUTHORITY nor HOME is set",
+		}, nil, nil, nil, nil, nil},
+
+		{"xauthority nonexistent", func(bool, bool) outcomeOp {
+			return new(spX11Op)
+		}, func() *hst.Config {
+			c := hst.Template()
+			*c.Enablements |= hst.Enablements(hst.EX11)
+			c.DirectXauthority = true
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"DISPLAY"}, ":0", nil),
+			call("stat", stub.ExpectArgs{"/tmp/.X11-unix/X0"}, (*stubFi)(nil), nil),
+			call("verbose", stub.ExpectArgs{[]any{"direct X11 authority access, PROCEED WITH CAUTION"}}, nil, nil),
+			call("lookupEnv", stub.ExpectArgs{"XAUTHORITY"}, nil, nil),
+			call("lookupEnv", stub.ExpectArgs{"HOME"}, "/home/ophestra", nil),
+			call("stat", stub.ExpectArgs{"/home/ophestra/.Xauthority"}, (*stubFi)(nil), os.ErrNotExist),
+		}, nil, nil, &hst.AppError{
+			Step: "finalise",
+			Err:  os.ErrNotExist,
+			Msg:  `X11 authority file "/home/ophestra/.Xauthority" not found`,
+		}, nil, nil, nil, nil, nil},
+
+		{"xauthority directory", func(bool, bool) outcomeOp {
+			return new(spX11Op)
+		}, func() *hst.Config {
+			c := hst.Template()
+			*c.Enablements |= hst.Enablements(hst.EX11)
+			c.DirectXauthority = true
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"DISPLAY"}, ":0", nil),
+			call("stat", stub.ExpectArgs{"/tmp/.X11-unix/X0"}, (*stubFi)(nil), nil),
+			call("verbose", stub.ExpectArgs{[]any{"direct X11 authority access, PROCEED WITH CAUTION"}}, nil, nil),
+			call("lookupEnv", stub.ExpectArgs{"XAUTHORITY"}, "/run/user/1000/xauth_Xbhdrv", nil),
+			call("stat", stub.ExpectArgs{"/run/user/1000/xauth_Xbhdrv"}, &stubFi{isDir: true}, nil),
+		}, nil, nil, &hst.AppError{
+			Step: `access X11 authority file "/run/user/1000/xauth_Xbhdrv"`,
+			Err:  &os.PathError{Op: "stat", Path: "/run/user/1000/xauth_Xbhdrv", Err: syscall.EISDIR},
+		}, nil, nil, nil, nil, nil},
+
+		{"success xauthority", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spX11Op)
+			}
+			return &spX11Op{Display: ":0", Xauthority: m("/run/user/1000/xauth_Xbhdrv")}
+		}, func() *hst.Config {
+			c := hst.Template()
+			*c.Enablements |= hst.Enablements(hst.EX11)
+			c.DirectXauthority = true
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"DISPLAY"}, ":0", nil),
+			call("stat", stub.ExpectArgs{"/tmp/.X11-unix/X0"}, (*stubFi)(nil), nil),
+			call("verbose", stub.ExpectArgs{[]any{"direct X11 authority access, PROCEED WITH CAUTION"}}, nil, nil),
+			call("lookupEnv", stub.ExpectArgs{"XAUTHORITY"}, "/run/user/1000/xauth_Xbhdrv", nil),
+			call("stat", stub.ExpectArgs{"/run/user/1000/xauth_Xbhdrv"}, &stubFi{}, nil),
+		}, newI().
+			UpdatePermType(hst.EX11, m("/tmp/.X11-unix/X0"), acl.Read, acl.Write, acl.Execute).
+			UpdatePermType(hst.EX11, m("/run/user/1000/xauth_Xbhdrv"), acl.Read).
+			ChangeHosts("#10009"), nil, nil, insertsOps(nil), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Ops: new(container.Ops).
+				Bind(absX11SocketDir, absX11SocketDir, 0).
+				Bind(m("/run/user/1000/xauth_Xbhdrv"), m("/.hakurei/Xauthority"), 0),
+		}, paramsWantEnv(config, map[string]string{
+			"DISPLAY":    ":0",
+			"XAUTHORITY": "/.hakurei/Xauthority",
+		}, nil), nil},
+
+		{"success xauthority home", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spX11Op)
+			}
+			return &spX11Op{Display: ":0", Xauthority: m("/home/ophestra/.local/state/Xauthority")}
+		}, func() *hst.Config {
+			c := hst.Template()
+			*c.Enablements |= hst.Enablements(hst.EX11)
+			c.DirectXauthority = true
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"DISPLAY"}, ":0", nil),
+			call("stat", stub.ExpectArgs{"/tmp/.X11-unix/X0"}, (*stubFi)(nil), nil),
+			call("verbose", stub.ExpectArgs{[]any{"direct X11 authority access, PROCEED WITH CAUTION"}}, nil, nil),
+			call("lookupEnv", stub.ExpectArgs{"XAUTHORITY"}, "/home/ophestra/.local/state/Xauthority", nil),
+			call("stat", stub.ExpectArgs{"/home/ophestra/.local/state/Xauthority"}, &stubFi{}, nil),
+		}, newI().
+			UpdatePermType(hst.EX11, m("/tmp/.X11-unix/X0"), acl.Read, acl.Write, acl.Execute).
+			UpdatePermType(hst.EX11, m("/home/ophestra/.local/state"), acl.Execute).
+			UpdatePermType(hst.EX11, m("/home/ophestra/.local"), acl.Execute).
+			UpdatePermType(hst.EX11, m("/home/ophestra"), acl.Execute).
+			UpdatePermType(hst.EX11, m("/home/ophestra/.local/state/Xauthority"), acl.Read).
+			ChangeHosts("#10009"), nil, nil, insertsOps(nil), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Ops: new(container.Ops).
+				Bind(absX11SocketDir, absX11SocketDir, 0).
+				Bind(m("/home/ophestra/.local/state/Xauthority"), m("/.hakurei/Xauthority"), 0),
+		}, paramsWantEnv(config, map[string]string{
+			"DISPLAY":    ":0",
+			"XAUTHORITY": "/.hakurei/Xauthority",
+		}, nil), nil},
+
+		{"success xauthority private tmp path", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spX11Op)
+			}
+			return &spX11Op{Display: ":0", Xauthority: m("/run/user/1000/xauth_Xbhdrv")}
+		}, func() *hst.Config {
+			c := hst.Template()
+			*c.Enablements |= hst.Enablements(hst.EX11)
+			c.DirectXauthority = true
+			c.Container.PrivateTmpPath = m("/run/hakurei")
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"DISPLAY"}, ":0", nil),
+			call("stat", stub.ExpectArgs{"/tmp/.X11-unix/X0"}, (*stubFi)(nil), nil),
+			call("verbose", stub.ExpectArgs{[]any{"direct X11 authority access, PROCEED WITH CAUTION"}}, nil, nil),
+			call("lookupEnv", stub.ExpectArgs{"XAUTHORITY"}, "/run/user/1000/xauth_Xbhdrv", nil),
+			call("stat", stub.ExpectArgs{"/run/user/1000/xauth_Xbhdrv"}, &stubFi{}, nil),
+		}, newI().
+			UpdatePermType(hst.EX11, m("/tmp/.X11-unix/X0"), acl.Read, acl.Write, acl.Execute).
+			UpdatePermType(hst.EX11, m("/run/user/1000/xauth_Xbhdrv"), acl.Read).
+			ChangeHosts("#10009"), nil, nil, insertsOps(nil), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Ops: new(container.Ops).
+				Bind(absX11SocketDir, absX11SocketDir, 0).
+				Bind(m("/run/user/1000/xauth_Xbhdrv"), m("/run/hakurei/Xauthority"), 0),
+		}, paramsWantEnv(config, map[string]string{
+			"DISPLAY":    ":0",
+			"XAUTHORITY": "/run/hakurei/Xauthority",
+		}, nil), nil},
+
 		{"success", func(isShim, _ bool) outcomeOp {
 			if !isShim {
 				return new(spX11Op)
@@ -115,5 +271,124 @@ func TestSpX11Op(t *testing.T) {
 		}, paramsWantEnv(config, map[string]string{
 			"DISPLAY": ":0",
 		}, nil), nil},
+
+		{"cookie display fallback", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spX11Op)
+			}
+			return &spX11Op{Display: "unix:/tmp/.X11-unix/X0"}
+		}, func() *hst.Config {
+			c := hst.Template()
+			*c.Enablements |= hst.Enablements(hst.EX11)
+			c.X11Cookie = true
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"DISPLAY"}, "unix:/tmp/.X11-unix/X0", nil),
+			call("stat", stub.ExpectArgs{"/tmp/.X11-unix/X0"}, (*stubFi)(nil), os.ErrNotExist),
+			call("verbosef", stub.ExpectArgs{"cannot generate X11 cookie for display %q, falling back to ChangeHosts", []any{"unix:/tmp/.X11-unix/X0"}}, nil, nil),
+		}, newI().
+			ChangeHosts("#10009"), nil, nil, insertsOps(nil), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Ops: new(container.Ops).
+				Bind(absX11SocketDir, absX11SocketDir, 0),
+		}, paramsWantEnv(config, map[string]string{
+			"DISPLAY": "unix:/tmp/.X11-unix/X0",
+		}, nil), nil},
+
+		{"cookie xauth fallback", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spX11Op)
+			}
+			return &spX11Op{Display: ":0"}
+		}, func() *hst.Config {
+			c := hst.Template()
+			*c.Enablements |= hst.Enablements(hst.EX11)
+			c.X11Cookie = true
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"DISPLAY"}, ":0", nil),
+			call("stat", stub.ExpectArgs{"/tmp/.X11-unix/X0"}, (*stubFi)(nil), nil),
+			call("lookPath", stub.ExpectArgs{"xauth"}, "", exec.ErrNotFound),
+			call("verbosef", stub.ExpectArgs{"cannot locate xauth, falling back to ChangeHosts: %v", []any{exec.ErrNotFound}}, nil, nil),
+		}, newI().
+			UpdatePermType(hst.EX11, m("/tmp/.X11-unix/X0"), acl.Read, acl.Write, acl.Execute).
+			ChangeHosts("#10009"), nil, nil, insertsOps(nil), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Ops: new(container.Ops).
+				Bind(absX11SocketDir, absX11SocketDir, 0),
+		}, paramsWantEnv(config, map[string]string{
+			"DISPLAY": ":0",
+		}, nil), nil},
+
+		{"cookie xauth relative", func(bool, bool) outcomeOp {
+			return new(spX11Op)
+		}, func() *hst.Config {
+			c := hst.Template()
+			*c.Enablements |= hst.Enablements(hst.EX11)
+			c.X11Cookie = true
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"DISPLAY"}, ":0", nil),
+			call("stat", stub.ExpectArgs{"/tmp/.X11-unix/X0"}, (*stubFi)(nil), nil),
+			call("lookPath", stub.ExpectArgs{"xauth"}, "bin/xauth", nil),
+		}, nil, nil, &hst.AppError{
+			Step: "locate xauth",
+			Err:  &check.AbsoluteError{Pathname: "bin/xauth"},
+		}, nil, nil, nil, nil, nil},
+
+		{"success cookie", func(isShim, _ bool) outcomeOp {
+			if !isShim {
+				return new(spX11Op)
+			}
+			return &spX11Op{Display: ":0", Xauthority: m(wantInstancePrefix + "/Xauthority")}
+		}, func() *hst.Config {
+			c := hst.Template()
+			*c.Enablements |= hst.Enablements(hst.EX11)
+			c.X11Cookie = true
+			return c
+		}, nil, []stub.Call{
+			call("lookupEnv", stub.ExpectArgs{"DISPLAY"}, ":0", nil),
+			call("stat", stub.ExpectArgs{"/tmp/.X11-unix/X0"}, (*stubFi)(nil), nil),
+			call("lookPath", stub.ExpectArgs{"xauth"}, "/run/current-system/sw/bin/xauth", nil),
+		}, newI().
+			UpdatePermType(hst.EX11, m("/tmp/.X11-unix/X0"), acl.Read, acl.Write, acl.Execute).
+			Ephemeral(system.Process, m(wantInstancePrefix), 0711).
+			Xauth(m("/run/current-system/sw/bin/xauth"), m(wantInstancePrefix+"/Xauthority"), 0), nil, nil, insertsOps(nil), []stub.Call{
+			// this op configures the container state and does not make calls during toContainer
+		}, &container.Params{
+			Ops: new(container.Ops).
+				Bind(absX11SocketDir, absX11SocketDir, 0).
+				Bind(m(wantInstancePrefix+"/Xauthority"), m("/.hakurei/Xauthority"), 0),
+		}, paramsWantEnv(config, map[string]string{
+			"DISPLAY":    ":0",
+			"XAUTHORITY": "/.hakurei/Xauthority",
+		}, nil), nil},
 	})
 }
+
+func TestXauthorityParents(t *testing.T) {
+	t.Parallel()
+
+	home, runtime := m("/home/ophestra"), m("/run/user/1000")
+	testCases := []struct {
+		name     string
+		pathname *check.Absolute
+		want     []*check.Absolute
+	}{
+		{"home", m("/home/ophestra/.Xauthority"), []*check.Absolute{home}},
+		{"home nested", m("/home/ophestra/.cache/Xauthority"), []*check.Absolute{m("/home/ophestra/.cache"), home}},
+		{"runtime", m("/run/user/1000/xauth_Xbhdrv"), nil},
+		{"runtime nested", m("/run/user/1000/sddm/xauth_Xbhdrv"), []*check.Absolute{m("/run/user/1000/sddm")}},
+		{"elsewhere", m("/tmp/xauth-1000-_0"), nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := xauthorityParents(tc.pathname, home, runtime); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("xauthorityParents: %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
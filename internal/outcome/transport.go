@@ -0,0 +1,192 @@
+package outcome
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// transportEnv is the name of the environment variable selecting the [stateTransport]
+// used by the priv side to transmit outcomeState, overriding transportDefault.
+const transportEnv = "HAKUREI_TRANSPORT"
+
+// transportDefault is the name of the [stateTransport] used when transportEnv is not set.
+//
+// This can be set by the linker.
+var transportDefault = "gob"
+
+// stateTransport encodes and decodes outcomeState transmitted from priv side to shim.
+type stateTransport interface {
+	// id returns the byte identifying this transport on the wire.
+	id() byte
+
+	// encode writes the representation of state to w.
+	encode(w io.Writer, state *outcomeState) error
+	// decode reads the representation of state from r.
+	decode(r io.Reader, state *outcomeState) error
+}
+
+// stateTransports holds all [stateTransport] implementations by name.
+var stateTransports = map[string]stateTransport{
+	"gob":  gobTransport{},
+	"json": jsonTransport{},
+}
+
+// ErrTransport is returned when transmitting or receiving outcomeState via an unknown [stateTransport].
+var ErrTransport = errors.New("unknown outcome state transport")
+
+// writeState writes state to w via the [stateTransport] of the specified name,
+// preceded by the byte identifying the transport.
+func writeState(w io.Writer, name string, state *outcomeState) error {
+	t, ok := stateTransports[name]
+	if !ok {
+		return ErrTransport
+	}
+	if _, err := w.Write([]byte{t.id()}); err != nil {
+		return err
+	}
+	return t.encode(w, state)
+}
+
+// readState reads state from r via the [stateTransport] identified by its first byte.
+func readState(r io.Reader, state *outcomeState) error {
+	var buf [1]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return err
+	}
+	for _, t := range stateTransports {
+		if t.id() == buf[0] {
+			return t.decode(r, state)
+		}
+	}
+	return ErrTransport
+}
+
+// gobTransport implements [stateTransport] via [gob].
+type gobTransport struct{}
+
+func (gobTransport) id() byte { return 'g' }
+func (gobTransport) encode(w io.Writer, state *outcomeState) error {
+	return gob.NewEncoder(w).Encode(state)
+}
+func (gobTransport) decode(r io.Reader, state *outcomeState) error {
+	return gob.NewDecoder(r).Decode(state)
+}
+
+// jsonTransport implements [stateTransport] via [json]. Values of outcomeOp are
+// represented alongside the name their concrete type is registered under via registerOp.
+type jsonTransport struct{}
+
+func (jsonTransport) id() byte { return 'j' }
+func (jsonTransport) encode(w io.Writer, state *outcomeState) error {
+	return json.NewEncoder(w).Encode(state)
+}
+func (jsonTransport) decode(r io.Reader, state *outcomeState) error {
+	return json.NewDecoder(r).Decode(state)
+}
+
+// opTypes holds the concrete types of outcomeOp implementations registered via registerOp.
+var opTypes = make(map[string]reflect.Type)
+
+// registerOp registers the concrete type of op with [gob] and jsonTransport.
+// This must only be called during initialisation.
+func registerOp(op outcomeOp) {
+	gob.Register(op)
+
+	t := reflect.TypeOf(op)
+	name := opTypeName(t)
+	if _, ok := opTypes[name]; ok {
+		panic("attempting to register duplicate op type " + name)
+	}
+	opTypes[name] = t
+}
+
+// opTypeName returns the name the concrete outcomeOp type t is registered under.
+func opTypeName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		return "*" + t.Elem().Name()
+	}
+	return t.Name()
+}
+
+// opJSON is the [json] representation of an outcomeOp.
+type opJSON struct {
+	// Name the concrete type is registered under.
+	Type string `json:"type"`
+	// Representation of the outcomeOp value.
+	Value json.RawMessage `json:"value"`
+}
+
+// UnknownOpTypeError is returned by jsonTransport when decoding an outcomeOp of an unregistered type.
+type UnknownOpTypeError string
+
+func (e UnknownOpTypeError) Error() string { return "unknown op type " + strconv.Quote(string(e)) }
+
+// shimParamsJSON is shimParams stripped of its methods.
+type shimParamsJSON shimParams
+
+func (p *shimParams) MarshalJSON() ([]byte, error) {
+	var ops []opJSON
+	if p.Ops != nil {
+		ops = make([]opJSON, len(p.Ops))
+	}
+	for i, op := range p.Ops {
+		t := reflect.TypeOf(op)
+		name := opTypeName(t)
+		if opTypes[name] != t {
+			return nil, UnknownOpTypeError(name)
+		}
+
+		if data, err := json.Marshal(op); err != nil {
+			return nil, err
+		} else {
+			ops[i] = opJSON{name, data}
+		}
+	}
+
+	return json.Marshal(&struct {
+		*shimParamsJSON
+		Ops []opJSON
+	}{(*shimParamsJSON)(p), ops})
+}
+
+func (p *shimParams) UnmarshalJSON(data []byte) error {
+	v := struct {
+		*shimParamsJSON
+		Ops []opJSON
+	}{shimParamsJSON: (*shimParamsJSON)(p)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if v.Ops == nil {
+		p.Ops = nil
+		return nil
+	}
+	p.Ops = make([]outcomeOp, len(v.Ops))
+	for i, e := range v.Ops {
+		t, ok := opTypes[e.Type]
+		if !ok {
+			return UnknownOpTypeError(e.Type)
+		}
+
+		var pv reflect.Value
+		if t.Kind() == reflect.Pointer {
+			pv = reflect.New(t.Elem())
+		} else {
+			pv = reflect.New(t)
+		}
+		if err := json.Unmarshal(e.Value, pv.Interface()); err != nil {
+			return err
+		}
+		if t.Kind() == reflect.Pointer {
+			p.Ops[i] = pv.Interface().(outcomeOp)
+		} else {
+			p.Ops[i] = pv.Elem().Interface().(outcomeOp)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package outcome
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"hakurei.app/container/check"
+	"hakurei.app/hst"
+	"hakurei.app/internal/env"
+)
+
+func TestStateTransport(t *testing.T) {
+	t.Parallel()
+
+	// every registered op must be present here with its exported fields populated
+	samples := map[string]outcomeOp{
+		"spAccountOp":        spAccountOp{},
+		"spMachineIDOp":      spMachineIDOp{},
+		"spNetworkFilesOp":   spNetworkFilesOp{},
+		"*spCgroupOp":        &spCgroupOp{Path: "/sys/fs/cgroup/hakurei.slice/app-0.scope", CPUInfo: []byte("processor\t: 0\n")},
+		"*spParamsOp":        &spParamsOp{Term: "xterm", TermSet: true},
+		"*spFilesystemOp":    &spFilesystemOp{HidePaths: []*check.Absolute{m("/run/user/1000/bus")}, EnvHost: map[string]string{"TERM": "xterm"}, EnvPass: map[string]string{"WAYLAND_DEBUG": "1"}},
+		"*spDBusOp":          &spDBusOp{ProxySystem: true},
+		"*spGPUOp":           &spGPUOp{Vulkan: []*check.Absolute{m("/usr/share/vulkan/icd.d")}, EGL: []*check.Absolute{m("/usr/share/glvnd/egl_vendor.d")}},
+		"*spDeviceOp":        &spDeviceOp{Devices: []*check.Absolute{m("/dev/kvm")}},
+		"*spPipeWireOp":      &spPipeWireOp{SocketPath: m("/run/user/1000/pipewire-0")},
+		"*spPulseOp":         &spPulseOp{Cookie: &[pulseCookieSizeMax]byte{0xde, 0xad}, CookieSize: 2},
+		"*spRuntimeOp":       &spRuntimeOp{SessionType: sessionTypeWayland},
+		"*spRuntimeSocketOp": &spRuntimeSocketOp{Sockets: []*check.Absolute{m("/run/user/1000/bus")}},
+		"spTmpdirOp":         spTmpdirOp{},
+		"spPortOp":           spPortOp{},
+		"*spWaylandOp":       &spWaylandOp{SocketPath: m("/run/user/1000/wayland-0")},
+		"*spX11Op":           &spX11Op{Display: ":0", Xauthority: m("/run/user/1000/xauth")},
+	}
+
+	ops := make([]outcomeOp, 0, len(opTypes))
+	for _, name := range slices.Sorted(maps.Keys(opTypes)) {
+		if op, ok := samples[name]; !ok {
+			t.Fatalf("registered op %s has no sample", name)
+		} else if reflect.TypeOf(op) != opTypes[name] {
+			t.Fatalf("sample of op %s has type %T", name, op)
+		} else {
+			ops = append(ops, op)
+		}
+	}
+	if len(ops) != len(samples) {
+		t.Fatalf("got %d samples for %d registered ops", len(samples), len(ops))
+	}
+
+	id := hst.ID{0xde, 0xad, 0xbe, 0xef}
+	config := hst.Template()
+	// undefined bits do not survive a round trip through jsonTransport
+	config.Container.Flags &= hst.FAll
+	want := &outcomeState{
+		Shim: &shimParams{
+			PrivPID:   0xdead,
+			WaitDelay: 5 * time.Second,
+			Verbose:   true,
+			Ops:       ops,
+		},
+		ID:        &id,
+		Identity:  9,
+		UserID:    1000,
+		Container: config.Container,
+		Mapuid:    1000,
+		Mapgid:    100,
+		Paths:     &env.Paths{TempDir: m("/tmp"), RuntimePath: m("/run/user/1000")},
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(stateTransports)) {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := writeState(&buf, name, want); err != nil {
+				t.Fatalf("writeState: error = %v", err)
+			}
+			if buf.Bytes()[0] != stateTransports[name].id() {
+				t.Errorf("writeState: id = %#x, want %#x", buf.Bytes()[0], stateTransports[name].id())
+			}
+
+			var got outcomeState
+			if err := readState(&buf, &got); err != nil {
+				t.Fatalf("readState: error = %v", err)
+			}
+			if !reflect.DeepEqual(&got, want) {
+				t.Errorf("readState:\n%s\nwant\n%s", mustMarshal(&got), mustMarshal(want))
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []struct {
+			name string
+			f    func() error
+			want error
+		}{
+			{"write unknown transport", func() error {
+				return writeState(io.Discard, "nonexistent", want)
+			}, ErrTransport},
+			{"read unknown transport", func() error {
+				return readState(bytes.NewReader([]byte{0}), new(outcomeState))
+			}, ErrTransport},
+			{"read closed", func() error {
+				return readState(bytes.NewReader(nil), new(outcomeState))
+			}, io.EOF},
+			{"read unknown op", func() error {
+				return readState(bytes.NewReader([]byte(`j{"Shim":{"Ops":[{"type":"*spNonexistentOp","value":{}}]}}`)), new(outcomeState))
+			}, UnknownOpTypeError("*spNonexistentOp")},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+				if err := tc.f(); !errors.Is(err, tc.want) {
+					t.Errorf("error = %v, want %v", err, tc.want)
+				}
+			})
+		}
+	})
+}
+
+func TestOutcomeOpTypes(t *testing.T) {
+	t.Parallel()
+
+	for _, typ := range outcomeOpTypes() {
+		t.Run(opTypeName(typ), func(t *testing.T) {
+			t.Parallel()
+
+			st := typ
+			if st.Kind() == reflect.Pointer {
+				st = st.Elem()
+			}
+			if st.Kind() != reflect.Struct {
+				t.Fatalf("op has kind %s", st.Kind())
+			}
+			// unexported fields are silently dropped by every stateTransport
+			for i := range st.NumField() {
+				if field := st.Field(i); !field.IsExported() {
+					t.Errorf("field %s is not exported", field.Name)
+				}
+			}
+
+			v := reflect.New(st)
+			fillOpValue(t, v.Elem())
+			if typ.Kind() != reflect.Pointer {
+				v = v.Elem()
+			}
+			want := &outcomeState{Shim: &shimParams{Ops: []outcomeOp{v.Interface().(outcomeOp)}}}
+
+			for _, name := range slices.Sorted(maps.Keys(stateTransports)) {
+				var buf bytes.Buffer
+				if err := writeState(&buf, name, want); err != nil {
+					t.Fatalf("writeState: error = %v", err)
+				}
+				var got outcomeState
+				if err := readState(&buf, &got); err != nil {
+					t.Fatalf("readState: error = %v", err)
+				}
+				if !reflect.DeepEqual(&got, want) {
+					t.Errorf("readState: %s\n%s\nwant\n%s", name, mustMarshal(&got), mustMarshal(want))
+				}
+			}
+		})
+	}
+}
+
+// outcomeOpTypes returns the concrete types of all registered outcomeOp implementations, sorted by name.
+func outcomeOpTypes() []reflect.Type {
+	types := make([]reflect.Type, 0, len(opTypes))
+	for _, name := range slices.Sorted(maps.Keys(opTypes)) {
+		types = append(types, opTypes[name])
+	}
+	return types
+}
+
+// fillOpValue populates every exported field reachable from v with a non-zero value.
+func fillOpValue(t *testing.T, v reflect.Value) {
+	if !v.CanSet() {
+		return
+	}
+	if v.Type() == reflect.TypeFor[*check.Absolute]() {
+		v.Set(reflect.ValueOf(m("/proc/nonexistent")))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fillOpValue(t, v.Elem())
+	case reflect.Struct:
+		for i := range v.NumField() {
+			fillOpValue(t, v.Field(i))
+		}
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fillOpValue(t, v.Index(0))
+	case reflect.Array:
+		for i := range v.Len() {
+			fillOpValue(t, v.Index(i))
+		}
+	case reflect.Map:
+		key, elem := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+		fillOpValue(t, key)
+		fillOpValue(t, elem)
+		v.Set(reflect.MakeMap(v.Type()))
+		v.SetMapIndex(key, elem)
+
+	case reflect.String:
+		v.SetString("hakurei")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(0x7f)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(0xff)
+
+	default:
+		t.Fatalf("cannot populate value of kind %s", v.Kind())
+	}
+}
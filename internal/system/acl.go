@@ -18,8 +18,9 @@ func (sys *I) UpdatePerm(path *check.Absolute, perms ...acl.Perm) *I {
 }
 
 // UpdatePermType maintains [acl.Perms] on a file until its [Enablement] is no longer satisfied.
+// The ACL_MASK entry of the file is extended to include perms, and restored on revert.
 func (sys *I) UpdatePermType(et hst.Enablement, path *check.Absolute, perms ...acl.Perm) *I {
-	sys.ops = append(sys.ops, &aclUpdateOp{et, path.String(), perms})
+	sys.ops = append(sys.ops, &aclUpdateOp{et, path.String(), perms, nil})
 	return sys
 }
 
@@ -28,19 +29,32 @@ type aclUpdateOp struct {
 	et    hst.Enablement
 	path  string
 	perms acl.Perms
+	// ACL_MASK entry prior to apply, removed on revert if [acl.MaskNone] and no longer required.
+	// The mask is recalculated on revert otherwise, or if nil, e.g. for an op restored from [Record].
+	mask *acl.Mask
 }
 
 func (a *aclUpdateOp) Type() hst.Enablement { return a.et }
+func (a *aclUpdateOp) name() string         { return "acl" }
 
 func (a *aclUpdateOp) apply(sys *I) error {
 	sys.msg.Verbose("applying ACL", a)
-	return newOpError("acl", sys.aclUpdate(a.path, sys.uid, a.perms...), false)
+	mask, err := sys.aclUpdate(a.path, sys.uid, a.perms...)
+	if err == nil {
+		a.mask = &mask
+	}
+	return newOpError("acl", err, false)
 }
 
 func (a *aclUpdateOp) revert(sys *I, ec *Criteria) error {
 	if ec.hasType(a.Type()) {
 		sys.msg.Verbose("stripping ACL", a)
-		err := sys.aclUpdate(a.path, sys.uid)
+		var err error
+		if a.mask != nil {
+			err = sys.aclRestore(a.path, sys.uid, *a.mask)
+		} else {
+			_, err = sys.aclUpdate(a.path, sys.uid)
+		}
 		if errors.Is(err, os.ErrNotExist) {
 			// the ACL is effectively stripped if the file no longer exists
 			sys.msg.Verbosef("target of ACL %s no longer exists", a)
@@ -0,0 +1,76 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"hakurei.app/hst"
+)
+
+/*
+Attach registers an existing process for the cgroup set up by a preceding [I.Cgroup] call.
+
+This provides best-effort hardening of a process started outside hakurei: only operations
+held by [I], such as cgroup limits and ACL updates, apply to the process. Seccomp filters and
+landlock rulesets cannot be applied retroactively. The process must be owned by the caller.
+*/
+func (sys *I) Attach(pid int) *I {
+	if sys == nil || pid <= 0 {
+		panic("invalid attach specification")
+	}
+	sys.ops = append(sys.ops, attachOp(pid))
+	return sys
+}
+
+// attachOp writes a process id to cgroup.procs of the last cgroupOp preceding it.
+type attachOp int
+
+func (a attachOp) Type() hst.Enablement { return Process }
+func (a attachOp) name() string         { return "attach" }
+
+func (a attachOp) apply(sys *I) error {
+	sys.msg.Verbosef("attaching process %d", int(a))
+
+	if fi, err := sys.stat(a.Path()); err != nil {
+		return newOpErrorMessage("attach", err, fmt.Sprintf("process %d does not exist", int(a)), false)
+	} else if st, ok := fi.Sys().(*syscall.Stat_t); !ok || st == nil {
+		return newOpErrorMessage("attach", syscall.EINVAL, fmt.Sprintf("cannot determine owner of process %d", int(a)), false)
+	} else if int(st.Uid) != sys.getuid() {
+		return newOpErrorMessage("attach", syscall.EPERM, fmt.Sprintf("process %d is not owned by the caller", int(a)), false)
+	}
+
+	var c *cgroupOp
+	for _, o := range sys.ops {
+		if o == Op(a) {
+			break
+		}
+		if v, ok := o.(*cgroupOp); ok {
+			c = v
+		}
+	}
+	if c == nil {
+		return newOpErrorMessage("attach", syscall.EINVAL, "attach requires a preceding cgroup", false)
+	}
+
+	if err := os.WriteFile(filepath.Join(c.path, "cgroup.procs"), []byte(strconv.Itoa(int(a))), 0644); err != nil {
+		return newOpError("attach", err, false)
+	}
+	return nil
+}
+
+func (a attachOp) revert(sys *I, _ *Criteria) error {
+	// the process is moved out of the instance cgroup by the kernel as it terminates
+	sys.msg.Verbosef("process %d remains attached", int(a))
+	return nil
+}
+
+func (a attachOp) Is(o Op) bool {
+	target, ok := o.(attachOp)
+	return ok && a == target
+}
+
+func (a attachOp) Path() string   { return "/proc/" + strconv.Itoa(int(a)) }
+func (a attachOp) String() string { return "pid " + strconv.Itoa(int(a)) }
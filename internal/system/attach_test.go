@@ -0,0 +1,89 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"hakurei.app/container/check"
+	"hakurei.app/container/stub"
+	"hakurei.app/message"
+)
+
+func TestAttachOp(t *testing.T) {
+	t.Parallel()
+
+	checkOpBehaviour(t, []opBehaviourTestCase{
+		{"stat", 0xbeef, 0xff, attachOp(0xcafe), []stub.Call{
+			call("verbosef", stub.ExpectArgs{"attaching process %d", []any{0xcafe}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/proc/51966"}, nil, os.ErrNotExist),
+		}, &OpError{Op: "attach", Err: os.ErrNotExist, Msg: "process 51966 does not exist"}, nil, nil},
+
+		{"owner", 0xbeef, 0xff, attachOp(0xcafe), []stub.Call{
+			call("verbosef", stub.ExpectArgs{"attaching process %d", []any{0xcafe}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/proc/51966"}, procFi(0), nil),
+			call("getuid", stub.ExpectArgs{}, 1000, nil),
+		}, &OpError{Op: "attach", Err: syscall.EPERM, Msg: "process 51966 is not owned by the caller"}, nil, nil},
+
+		{"cgroup", 0xbeef, 0xff, attachOp(0xcafe), []stub.Call{
+			call("verbosef", stub.ExpectArgs{"attaching process %d", []any{0xcafe}}, nil, nil),
+			call("stat", stub.ExpectArgs{"/proc/51966"}, procFi(1000), nil),
+			call("getuid", stub.ExpectArgs{}, 1000, nil),
+		}, &OpError{Op: "attach", Err: syscall.EINVAL, Msg: "attach requires a preceding cgroup"}, nil, nil},
+	})
+
+	checkOpsBuilder(t, "Attach", []opsBuilderTestCase{
+		{"pid", 0xcafe, func(_ *testing.T, sys *I) {
+			sys.Attach(0xbeef)
+		}, []Op{attachOp(0xbeef)}, stub.Expect{}},
+	})
+
+	checkOpIs(t, []opIsTestCase{
+		{"type differs", attachOp(0xbeef), new(cgroupOp), false},
+		{"pid differs", attachOp(0xbeef), attachOp(0xcafe), false},
+		{"equals", attachOp(0xbeef), attachOp(0xbeef), true},
+	})
+
+	checkOpMeta(t, []opMetaTestCase{
+		{"pid", attachOp(0xbeef), Process, "/proc/48879", "pid 48879"},
+	})
+}
+
+func TestAttach(t *testing.T) {
+	t.Parallel()
+
+	sys := New(t.Context(), message.New(nil), 0xbeef)
+	base := check.MustAbs(t.TempDir())
+	target := base.Append("hakurei-1", "instance")
+
+	sys.Cgroup(base, target, CgroupLimits{}).Attach(os.Getpid())
+
+	if err := sys.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(target.String(), "cgroup.procs")); err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	} else if got := strings.TrimSpace(string(data)); got != strconv.Itoa(os.Getpid()) {
+		t.Fatalf("cgroup.procs: %q", got)
+	}
+
+	// cgroup.procs is not removable on cgroupfs and is left behind here
+	if err := sys.Revert(nil); err != nil {
+		t.Fatalf("Revert: %v", err)
+	}
+}
+
+// procFi partially implements [os.FileInfo] for a /proc/pid directory owned by its value.
+type procFi uint32
+
+func (procFi) Name() string       { panic("unreachable") }
+func (procFi) Size() int64        { panic("unreachable") }
+func (procFi) Mode() os.FileMode  { panic("unreachable") }
+func (procFi) ModTime() time.Time { panic("unreachable") }
+func (procFi) IsDir() bool        { panic("unreachable") }
+func (fi procFi) Sys() any        { return &syscall.Stat_t{Uid: uint32(fi)} }
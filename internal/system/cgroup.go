@@ -3,8 +3,12 @@ package system
 import (
 	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -13,10 +17,30 @@ import (
 )
 
 // CgroupLimits configures basic cgroup v2 resource controllers.
+// The zero value writes no controller files and is suitable for accounting only.
 type CgroupLimits struct {
-	CPU    uint64
-	Memory uint64
-	Pids   int
+	CPU uint64
+	// CPUWeight is written to cpu.weight if non-zero. It is independent of CPU and the two
+	// may be set together: weight distributes time under contention, while cpu.max caps it.
+	CPUWeight uint64
+	Memory    uint64
+	// MemorySwapMax is written to memory.swap.max if non-zero.
+	MemorySwapMax uint64
+	// MemoryLow is written to memory.low if non-zero.
+	MemoryLow uint64
+	Pids      int
+	// IOMax holds io.max entries keyed by device number in MAJ:MIN form.
+	IOMax map[string]hst.CgroupIOLimit
+	// CPUSet is written to cpuset.cpus if non-empty.
+	CPUSet string
+
+	// Persist keeps created cgroup directories and the controller files written to them on revert,
+	// so resource usage of the exited container remains available for inspection.
+	Persist bool
+	// EnableControllers enables required controllers missing from cgroup.subtree_control of the
+	// slice and of other cgroups on the path not created by this operation. These are disabled
+	// again on revert. Controllers are always enabled in cgroups created by this operation.
+	EnableControllers bool
 }
 
 // Cgroup registers a process-scoped cgroup operation rooted at base and applied to target.
@@ -38,9 +62,17 @@ type cgroupOp struct {
 	limits  CgroupLimits
 	created []string
 	files   []string
+	// devices with an io.max entry written
+	devices []string
+	// controllers enabled in cgroups not created by this operation
+	enabled []cgroupController
 }
 
+// cgroupController is a controller enabled in cgroup.subtree_control of dir.
+type cgroupController struct{ dir, name string }
+
 func (c *cgroupOp) Type() hst.Enablement { return Process }
+func (c *cgroupOp) name() string         { return "cgroup" }
 
 func (c *cgroupOp) apply(sys *I) error {
 	sys.msg.Verbosef("configuring cgroup %q", c.path)
@@ -53,11 +85,25 @@ func (c *cgroupOp) apply(sys *I) error {
 		return newOpError("cgroup", err, false)
 	}
 
-	if err := c.ensurePath(sys); err != nil {
+	controllers := c.controllers()
+	delegate, err := c.checkAvailable(sys, controllers)
+	if err != nil {
 		return err
 	}
 
-	if err := c.applyLimits(); err != nil {
+	if err = c.ensurePath(sys); err != nil {
+		return err
+	}
+
+	if delegate {
+		if err = c.enableControllers(sys, controllers); err != nil {
+			c.removeCreated(sys)
+			return err
+		}
+	}
+
+	if err = c.applyLimits(); err != nil {
+		c.removeCreated(sys)
 		return err
 	}
 
@@ -95,39 +141,307 @@ func (c *cgroupOp) ensurePath(sys *I) error {
 	return nil
 }
 
+// controllers returns the names of controllers required by the configured limits.
+func (c *cgroupOp) controllers() []string {
+	var names []string
+	if c.limits.CPU > 0 || c.limits.CPUWeight > 0 {
+		names = append(names, "cpu")
+	}
+	if c.limits.Memory > 0 || c.limits.MemorySwapMax > 0 || c.limits.MemoryLow > 0 {
+		names = append(names, "memory")
+	}
+	if c.limits.Pids > 0 {
+		names = append(names, "pids")
+	}
+	for dev, limit := range c.limits.IOMax {
+		if ioMaxEntry(dev, limit) != "" {
+			names = append(names, "io")
+			break
+		}
+	}
+	if c.limits.CPUSet != "" {
+		names = append(names, "cpuset")
+	}
+	return names
+}
+
+// readControllerList returns the space-separated controller names held by a cgroup interface file.
+func readControllerList(name string) ([]string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// checkAvailable verifies that every required controller is delegated to the slice.
+// It reports whether controllers are to be enabled along the cgroup path, which is
+// skipped if no controllers are required or the slice is not on a cgroup2 hierarchy.
+func (c *cgroupOp) checkAvailable(sys *I, controllers []string) (bool, error) {
+	if len(controllers) == 0 {
+		return false, nil
+	}
+
+	name := filepath.Join(c.base, "cgroup.controllers")
+	available, err := readControllerList(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			sys.msg.Verbosef("skipping controller delegation check for %q: not a cgroup2 hierarchy", c.base)
+			return false, nil
+		}
+		return false, newOpError("cgroup", err, false)
+	}
+
+	for _, controller := range controllers {
+		if !slices.Contains(available, controller) {
+			return false, newOpErrorMessage("cgroup", syscall.ENOTSUP,
+				fmt.Sprintf("cgroup controller %q is not delegated to %q", controller, c.base), false)
+		}
+	}
+	return true, nil
+}
+
+// enableControllers enables missing controllers in cgroup.subtree_control of every
+// cgroup from the slice down to the parent of the target cgroup. Cgroups not created
+// by ensurePath are only written to if [CgroupLimits.EnableControllers] is set.
+func (c *cgroupOp) enableControllers(sys *I, controllers []string) error {
+	dir := c.base
+	parents := []string{dir}
+	rel := strings.Trim(strings.TrimPrefix(c.path, c.base), string(os.PathSeparator))
+	parts := strings.Split(rel, string(os.PathSeparator))
+	for _, part := range parts[:len(parts)-1] {
+		if part == "" {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		parents = append(parents, dir)
+	}
+
+	for _, parent := range parents {
+		name := filepath.Join(parent, "cgroup.subtree_control")
+		enabled, err := readControllerList(name)
+		if err != nil {
+			return newOpError("cgroup", err, false)
+		}
+
+		var missing []string
+		for _, controller := range controllers {
+			if !slices.Contains(enabled, controller) {
+				missing = append(missing, controller)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		// cgroups created here hold no processes and take their controllers with them on removal
+		created := slices.Contains(c.created, parent)
+		if !created {
+			if !c.limits.EnableControllers {
+				return newOpErrorMessage("cgroup", syscall.ENOTSUP,
+					fmt.Sprintf("cgroup controller %q is not enabled in %q", missing[0], parent), false)
+			}
+			if err = checkNoProcesses(parent); err != nil {
+				return err
+			}
+		}
+
+		for _, controller := range missing {
+			sys.msg.Verbosef("enabling cgroup controller %q in %q", controller, parent)
+			if err = writeEntries(name, 0, []string{"+" + controller}); err != nil {
+				return newOpErrorMessage("cgroup", err,
+					fmt.Sprintf("cannot enable cgroup controller %q in %q: %v", controller, parent, err), false)
+			}
+			if !created {
+				c.enabled = append(c.enabled, cgroupController{parent, controller})
+			}
+		}
+	}
+	return nil
+}
+
+// checkNoProcesses returns an error if the cgroup at dir has member processes, as the kernel
+// refuses to enable controllers in cgroup.subtree_control of such a cgroup.
+func checkNoProcesses(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return newOpError("cgroup", err, false)
+	}
+	if len(strings.TrimSpace(string(data))) > 0 {
+		return newOpErrorMessage("cgroup", syscall.EBUSY,
+			fmt.Sprintf("cannot enable cgroup controllers in %q as it has member processes", dir), false)
+	}
+	return nil
+}
+
+// disableControllers disables controllers enabled by enableControllers in cgroups not created
+// by this operation. Cgroups still holding child cgroups are skipped, as other instances may
+// depend on their controllers.
+func (c *cgroupOp) disableControllers(sys *I) {
+	for i := len(c.enabled) - 1; i >= 0; i-- {
+		e := c.enabled[i]
+		if entries, err := os.ReadDir(e.dir); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				sys.msg.Verbosef("cannot read cgroup %q: %v", e.dir, err)
+			}
+			continue
+		} else if slices.ContainsFunc(entries, fs.DirEntry.IsDir) {
+			sys.msg.Verbosef("keeping cgroup controller %q in %q in use by child cgroups", e.name, e.dir)
+			continue
+		}
+
+		name := filepath.Join(e.dir, "cgroup.subtree_control")
+		if err := writeEntries(name, 0, []string{"-" + e.name}); err != nil {
+			sys.msg.Verbosef("cannot disable cgroup controller %q in %q: %v", e.name, e.dir, err)
+		}
+	}
+	c.enabled = nil
+}
+
+// removeCreated removes cgroup directories created by ensurePath and undoes other changes after a failed apply.
+func (c *cgroupOp) removeCreated(sys *I) {
+	for i := len(c.files) - 1; i >= 0; i-- {
+		if err := os.Remove(c.files[i]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			sys.msg.Verbosef("cannot remove cgroup file %q: %v", c.files[i], err)
+		}
+	}
+	c.files, c.devices = nil, nil
+
+	for i := len(c.created) - 1; i >= 0; i-- {
+		if err := sys.remove(c.created[i]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			sys.msg.Verbosef("cannot remove cgroup path %q: %v", c.created[i], err)
+		}
+	}
+	c.created = nil
+
+	c.disableControllers(sys)
+}
+
 func (c *cgroupOp) applyLimits() error {
 	if c.limits.CPU > 0 {
 		if err := c.writeControllerFile("cpu.max", fmt.Sprintf("%d 100000", c.limits.CPU)); err != nil {
 			return err
 		}
 	}
+	if c.limits.CPUWeight > 0 {
+		if err := c.writeControllerFile("cpu.weight", fmt.Sprintf("%d", c.limits.CPUWeight)); err != nil {
+			return err
+		}
+	}
 	if c.limits.Memory > 0 {
 		if err := c.writeControllerFile("memory.max", fmt.Sprintf("%d", c.limits.Memory)); err != nil {
 			return err
 		}
 	}
+	if c.limits.MemorySwapMax > 0 {
+		if err := c.writeControllerFile("memory.swap.max", fmt.Sprintf("%d", c.limits.MemorySwapMax)); err != nil {
+			return err
+		}
+	}
+	if c.limits.MemoryLow > 0 {
+		if err := c.writeControllerFile("memory.low", fmt.Sprintf("%d", c.limits.MemoryLow)); err != nil {
+			return err
+		}
+	}
 	if c.limits.Pids > 0 {
 		if err := c.writeControllerFile("pids.max", fmt.Sprintf("%d", c.limits.Pids)); err != nil {
 			return err
 		}
 	}
+	if c.limits.CPUSet != "" {
+		if err := c.writeControllerFile("cpuset.cpus", c.limits.CPUSet); err != nil {
+			return err
+		}
+	}
+	if len(c.limits.IOMax) > 0 {
+		devices := slices.Sorted(maps.Keys(c.limits.IOMax))
+		entries := make([]string, 0, len(devices))
+		for _, dev := range devices {
+			if entry := ioMaxEntry(dev, c.limits.IOMax[dev]); entry != "" {
+				entries = append(entries, entry)
+				c.devices = append(c.devices, dev)
+			}
+		}
+		if len(entries) > 0 {
+			if err := c.writeControllerFile("io.max", entries...); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
-func (c *cgroupOp) writeControllerFile(name, value string) error {
+// ioMaxEntry returns the io.max line for a device, or the zero value if no limits are set.
+func ioMaxEntry(dev string, limit hst.CgroupIOLimit) string {
+	var buf strings.Builder
+	for _, v := range [...]struct {
+		key   string
+		value uint64
+	}{
+		{"rbps", limit.RBPS},
+		{"wbps", limit.WBPS},
+		{"riops", limit.RIOPS},
+		{"wiops", limit.WIOPS},
+	} {
+		if v.value > 0 {
+			buf.WriteString(" " + v.key + "=" + strconv.FormatUint(v.value, 10))
+		}
+	}
+	if buf.Len() == 0 {
+		return ""
+	}
+	return dev + buf.String()
+}
+
+// writeControllerFile writes each value to the controller file in a separate write,
+// as controllers such as io.max only accept a single entry per write.
+func (c *cgroupOp) writeControllerFile(name string, values ...string) error {
 	file := filepath.Join(c.path, name)
-	if err := os.WriteFile(file, []byte(value), 0644); err != nil {
+	if err := writeEntries(file, os.O_CREATE|os.O_TRUNC, values); err != nil {
 		return newOpError("cgroup", err, false)
 	}
 	c.files = append(c.files, file)
 	return nil
 }
 
+func writeEntries(name string, flag int, values []string) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|flag, 0644)
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		if _, err = f.Write([]byte(value)); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
 func (c *cgroupOp) revert(sys *I, ec *Criteria) error {
 	if ec != nil && !ec.hasType(Process) {
 		sys.msg.Verbosef("skipping revert for cgroup %q", c.path)
 		return nil
 	}
+	if c.limits.Persist {
+		sys.msg.Verbosef("keeping cgroup %q", c.path)
+		return nil
+	}
+
+	// io.max entries outlive the file in a cgroup that cannot be removed
+	if len(c.devices) > 0 {
+		entries := make([]string, len(c.devices))
+		for i, dev := range c.devices {
+			entries[i] = dev + " rbps=max wbps=max riops=max wiops=max"
+		}
+		file := filepath.Join(c.path, "io.max")
+		if err := writeEntries(file, 0, entries); err != nil && !errors.Is(err, os.ErrNotExist) {
+			sys.msg.Verbosef("cannot reset cgroup file %q: %v", file, err)
+		}
+	}
 
 	for i := len(c.files) - 1; i >= 0; i-- {
 		file := c.files[i]
@@ -143,13 +457,15 @@ func (c *cgroupOp) revert(sys *I, ec *Criteria) error {
 			switch {
 			case errors.Is(err, os.ErrNotExist):
 				continue
-			case errors.Is(err, syscall.ENOTEMPTY):
+			case errors.Is(err, syscall.ENOTEMPTY), errors.Is(err, syscall.EBUSY):
 				sys.msg.Verbosef("skipping busy cgroup path %q", dir)
 			default:
 				errs = append(errs, newOpError("cgroup", err, true))
 			}
 		}
 	}
+
+	c.disableControllers(sys)
 	return errors.Join(errs...)
 }
 
@@ -160,12 +476,22 @@ func (c *cgroupOp) Is(o Op) bool {
 	}
 	return c.base == target.base &&
 		c.path == target.path &&
-		c.limits == target.limits
+		c.limits.CPU == target.limits.CPU &&
+		c.limits.CPUWeight == target.limits.CPUWeight &&
+		c.limits.Memory == target.limits.Memory &&
+		c.limits.MemorySwapMax == target.limits.MemorySwapMax &&
+		c.limits.MemoryLow == target.limits.MemoryLow &&
+		c.limits.Pids == target.limits.Pids &&
+		c.limits.CPUSet == target.limits.CPUSet &&
+		c.limits.Persist == target.limits.Persist &&
+		c.limits.EnableControllers == target.limits.EnableControllers &&
+		maps.Equal(c.limits.IOMax, target.limits.IOMax)
 }
 
 func (c *cgroupOp) Path() string { return c.path }
 
 func (c *cgroupOp) String() string {
-	return fmt.Sprintf("base: %q path: %q cpu: %d memory: %d pids: %d",
-		c.base, c.path, c.limits.CPU, c.limits.Memory, c.limits.Pids)
+	return fmt.Sprintf("base: %q path: %q cpu: %d weight: %d memory: %d swap: %d low: %d pids: %d io: %d cpuset: %q persist: %v",
+		c.base, c.path, c.limits.CPU, c.limits.CPUWeight, c.limits.Memory, c.limits.MemorySwapMax, c.limits.MemoryLow,
+		c.limits.Pids, len(c.limits.IOMax), c.limits.CPUSet, c.limits.Persist)
 }
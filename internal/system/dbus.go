@@ -74,6 +74,68 @@ func (sys *I) ProxyDBus(
 	return nil
 }
 
+// ErrDBusNotRunning is returned by [I.RestartDBus] if no message bus proxy is running.
+var ErrDBusNotRunning = errors.New("message bus proxy not running")
+
+/*
+RestartDBus restarts the message bus proxy set up by [I.ProxyDBus] with the filter rules
+described by session and system. The proxy sockets are recreated at the same paths, so they
+must be exposed through their parent directory. Permissions set up by [I.UpdatePermType] on
+the proxy sockets are applied again to the new sockets.
+
+RestartDBus must only be called after a successful Commit and before Revert.
+*/
+func (sys *I) RestartDBus(session, system *hst.BusConfig) error {
+	if !sys.committed || sys.reverted {
+		return newOpErrorMessage("dbus", ErrDBusNotRunning,
+			"attempted to restart message bus proxy outside committed state", false)
+	}
+
+	var d *dbusProxyOp
+	for _, o := range sys.ops {
+		if v, ok := o.(*dbusProxyOp); ok && v.proxy != nil {
+			d = v
+			break
+		}
+	}
+	if d == nil {
+		return newOpErrorMessage("dbus", ErrDBusNotRunning,
+			"attempted to restart message bus proxy without a running instance", false)
+	}
+
+	if session == nil {
+		return newOpErrorMessage("dbus", ErrDBusConfig,
+			"attempted to create message bus proxy args without session bus config", false)
+	}
+	if (system != nil) != d.system {
+		return newOpErrorMessage("dbus", dbus.ErrRestartSocket,
+			"restarting message bus proxy must not toggle the system bus", false)
+	}
+
+	final, err := sys.dbusFinalise(d.final.Session, d.final.System, session, system)
+	if err != nil {
+		return newOpErrorMessage("dbus", err,
+			fmt.Sprintf("cannot finalise message bus proxy: %v", err), false)
+	}
+	if err = sys.dbusProxyRestart(d.proxy, final); err != nil {
+		d.out.Dump()
+		return newOpErrorMessage("dbus", err,
+			fmt.Sprintf("cannot restart message bus proxy: %v", err), false)
+	}
+	d.final = final
+	sys.msg.Verbose("restarted message bus proxy", d.proxy)
+
+	// the new instance created new socket files
+	for _, o := range sys.ops {
+		if a, ok := o.(*aclUpdateOp); ok && (a.path == final.Session[1] || (d.system && a.path == final.System[1])) {
+			if err = a.apply(sys); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // dbusProxyOp implements [I.ProxyDBus].
 type dbusProxyOp struct {
 	proxy *dbus.Proxy // populated during apply
@@ -85,6 +147,7 @@ type dbusProxyOp struct {
 }
 
 func (d *dbusProxyOp) Type() hst.Enablement { return Process }
+func (d *dbusProxyOp) name() string         { return "dbus" }
 
 func (d *dbusProxyOp) apply(sys *I) error {
 	sys.msg.Verbosef("session bus proxy on %q for upstream %q", d.final.Session[1], d.final.Session[0])
@@ -11,6 +11,7 @@ import (
 
 	"hakurei.app/container/stub"
 	"hakurei.app/hst"
+	"hakurei.app/internal/acl"
 	"hakurei.app/internal/dbus"
 	"hakurei.app/internal/helper"
 )
@@ -379,6 +380,103 @@ func TestDBusProxyOp(t *testing.T) {
 	})
 }
 
+func TestRestartDBus(t *testing.T) {
+	t.Parallel()
+
+	newSys := func(t *testing.T, sys *I, system bool) *dbusProxyOp {
+		d := &dbusProxyOp{final: dbusNewFinalSample(0), out: new(linePrefixWriter), system: system}
+		d.proxy = dbus.New(t.Context(), sys.msg, d.final, d.out)
+		sys.committed = true
+		sys.ops = append(sys.ops,
+			&aclUpdateOp{Process, "/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f", []acl.Perm{acl.Execute}, nil},
+			d,
+			&aclUpdateOp{Process, d.final.Session[1], []acl.Perm{acl.Read, acl.Write}, nil},
+			&aclUpdateOp{Process, d.final.System[1], []acl.Perm{acl.Read, acl.Write}, nil},
+		)
+		return d
+	}
+	checkRestart := func(t *testing.T, sys *I, wantErr error) {
+		if err := sys.RestartDBus(
+			&hst.BusConfig{Talk: []string{"session\x00"}, Filter: true},
+			&hst.BusConfig{Talk: []string{"system\x00"}, Filter: true},
+		); !reflect.DeepEqual(err, wantErr) {
+			t.Errorf("RestartDBus: error = %v, want %v", err, wantErr)
+		}
+	}
+
+	finaliseCall := call("dbusFinalise", stub.ExpectArgs{
+		dbus.ProxyPair{"unix:path=/run/user/1000/bus", "/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f/bus"},
+		dbus.ProxyPair{"unix:path=/run/dbus/system_bus_socket", "/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f/system_bus_socket"},
+		&hst.BusConfig{Talk: []string{"session\x00"}, Filter: true},
+		&hst.BusConfig{Talk: []string{"system\x00"}, Filter: true},
+	}, dbusNewFinalSample(1), nil)
+
+	checkOpsBuilder(t, "RestartDBus", []opsBuilderTestCase{
+		{"not committed", 0xcafe, func(t *testing.T, sys *I) {
+			checkRestart(t, sys, &OpError{
+				Op: "dbus", Err: ErrDBusNotRunning,
+				Msg: "attempted to restart message bus proxy outside committed state",
+			})
+		}, nil, stub.Expect{}},
+
+		{"not running", 0xcafe, func(t *testing.T, sys *I) {
+			sys.committed = true
+			sys.ops = append(sys.ops, &dbusProxyOp{final: dbusNewFinalSample(0), system: true})
+			checkRestart(t, sys, &OpError{
+				Op: "dbus", Err: ErrDBusNotRunning,
+				Msg: "attempted to restart message bus proxy without a running instance",
+			})
+		}, []Op{&dbusProxyOp{final: dbusNewFinalSample(0), system: true}}, stub.Expect{}},
+
+		{"toggle system", 0xcafe, func(t *testing.T, sys *I) {
+			newSys(t, sys, false)
+			checkRestart(t, sys, &OpError{
+				Op: "dbus", Err: dbus.ErrRestartSocket,
+				Msg: "restarting message bus proxy must not toggle the system bus",
+			})
+		}, []Op{
+			&aclUpdateOp{Process, "/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f", []acl.Perm{acl.Execute}, nil},
+			&dbusProxyOp{final: dbusNewFinalSample(0)},
+			&aclUpdateOp{Process, "/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f/bus", []acl.Perm{acl.Read, acl.Write}, nil},
+			&aclUpdateOp{Process, "/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f/system_bus_socket", []acl.Perm{acl.Read, acl.Write}, nil},
+		}, stub.Expect{}},
+
+		{"dbusProxyRestart", 0xcafe, func(t *testing.T, sys *I) {
+			newSys(t, sys, true)
+			checkRestart(t, sys, &OpError{
+				Op: "dbus", Err: stub.UniqueError(0),
+				Msg: "cannot restart message bus proxy: unique error 0 injected by the test suite",
+			})
+		}, []Op{
+			&aclUpdateOp{Process, "/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f", []acl.Perm{acl.Execute}, nil},
+			&dbusProxyOp{final: dbusNewFinalSample(0), system: true},
+			&aclUpdateOp{Process, "/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f/bus", []acl.Perm{acl.Read, acl.Write}, nil},
+			&aclUpdateOp{Process, "/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f/system_bus_socket", []acl.Perm{acl.Read, acl.Write}, nil},
+		}, stub.Expect{Calls: []stub.Call{
+			finaliseCall,
+			call("dbusProxyRestart", stub.ExpectArgs{dbusNewFinalSample(0), dbusNewFinalSample(1)}, nil, stub.UniqueError(0)),
+		}}},
+
+		{"success", 0xcafe, func(t *testing.T, sys *I) {
+			newSys(t, sys, true)
+			checkRestart(t, sys, nil)
+		}, []Op{
+			&aclUpdateOp{Process, "/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f", []acl.Perm{acl.Execute}, nil},
+			&dbusProxyOp{final: dbusNewFinalSample(1), system: true},
+			&aclUpdateOp{Process, "/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f/bus", []acl.Perm{acl.Read, acl.Write}, nil},
+			&aclUpdateOp{Process, "/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f/system_bus_socket", []acl.Perm{acl.Read, acl.Write}, nil},
+		}, stub.Expect{Calls: []stub.Call{
+			finaliseCall,
+			call("dbusProxyRestart", stub.ExpectArgs{dbusNewFinalSample(0), dbusNewFinalSample(1)}, nil, nil),
+			call("verbose", stub.ExpectArgs{[]any{"restarted message bus proxy", ignoreValue{}}}, nil, nil),
+			call("verbose", stub.ExpectArgs{[]any{"applying ACL", ignoreValue{}}}, nil, nil),
+			call("aclUpdate", stub.ExpectArgs{"/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f/bus", 0xcafe, []acl.Perm{acl.Read, acl.Write}}, acl.MaskNone, nil),
+			call("verbose", stub.ExpectArgs{[]any{"applying ACL", ignoreValue{}}}, nil, nil),
+			call("aclUpdate", stub.ExpectArgs{"/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f/system_bus_socket", 0xcafe, []acl.Perm{acl.Read, acl.Write}}, acl.MaskNone, nil),
+		}}},
+	})
+}
+
 func dbusNewFinalSample(v int) *dbus.Final {
 	return &dbus.Final{
 		Session: dbus.ProxyPair{"unix:path=/run/user/1000/bus", "/tmp/hakurei.0/99dd71ee2146369514e0d10783368f8f/bus"},
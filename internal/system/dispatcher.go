@@ -1,10 +1,17 @@
 package system
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"os"
+	"os/exec"
+	"strconv"
 
 	"hakurei.app/container/check"
 	"hakurei.app/hst"
@@ -28,6 +35,8 @@ type syscallDispatcher interface {
 	// just synchronising access is not enough, as this is for test instrumentation.
 	new(f func(k syscallDispatcher))
 
+	// getuid provides os.Getuid.
+	getuid() int
 	// stat provides os.Stat.
 	stat(name string) (os.FileInfo, error)
 	// open provides [os.Open].
@@ -40,22 +49,33 @@ type syscallDispatcher interface {
 	link(oldname, newname string) error
 	// remove provides os.Remove.
 	remove(name string) error
+	// writeFile provides [os.WriteFile].
+	writeFile(name string, data []byte, perm os.FileMode) error
 
 	// println provides [log.Println].
 	println(v ...any)
 
 	// aclUpdate provides [acl.Update].
-	aclUpdate(name string, uid int, perms ...acl.Perm) error
+	aclUpdate(name string, uid int, perms ...acl.Perm) (acl.Mask, error)
+	// aclRestore provides [acl.Restore].
+	aclRestore(name string, uid int, mask acl.Mask) error
 
 	waylandNew(displayPath, bindPath *check.Absolute, appID, instanceID string) (*wayland.SecurityContext, error)
 
 	// xcbChangeHosts provides [xcb.ChangeHosts].
 	xcbChangeHosts(mode xcb.HostMode, family xcb.Family, address string) error
 
+	// xauthGenerate generates a random MIT-MAGIC-COOKIE-1 and registers it with the X server
+	// on display via the xauth program at pathname xauth, writing it to the X11 authority file at name.
+	// The xauth program is killed if ctx is canceled.
+	xauthGenerate(ctx context.Context, xauth, name, display string, timeout int) (cookie []byte, err error)
+
 	// dbusFinalise provides [dbus.Finalise].
 	dbusFinalise(sessionBus, systemBus dbus.ProxyPair, session, system *hst.BusConfig) (final *dbus.Final, err error)
 	// dbusProxyStart provides the Start method of [dbus.Proxy].
 	dbusProxyStart(proxy *dbus.Proxy) error
+	// dbusProxyRestart provides the Restart method of [dbus.Proxy].
+	dbusProxyRestart(proxy *dbus.Proxy, final *dbus.Final) error
 	// dbusProxyClose provides the Close method of [dbus.Proxy].
 	dbusProxyClose(proxy *dbus.Proxy)
 	// dbusProxyWait provides the Wait method of [dbus.Proxy].
@@ -67,18 +87,25 @@ type direct struct{}
 
 func (k direct) new(f func(k syscallDispatcher)) { go f(k) }
 
+func (k direct) getuid() int                               { return os.Getuid() }
 func (k direct) stat(name string) (os.FileInfo, error)     { return os.Stat(name) }
 func (k direct) open(name string) (osFile, error)          { return os.Open(name) }
 func (k direct) mkdir(name string, perm os.FileMode) error { return os.Mkdir(name, perm) }
 func (k direct) chmod(name string, mode os.FileMode) error { return os.Chmod(name, mode) }
 func (k direct) link(oldname, newname string) error        { return os.Link(oldname, newname) }
 func (k direct) remove(name string) error                  { return os.Remove(name) }
+func (k direct) writeFile(name string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
 
 func (k direct) println(v ...any) { log.Println(v...) }
 
-func (k direct) aclUpdate(name string, uid int, perms ...acl.Perm) error {
+func (k direct) aclUpdate(name string, uid int, perms ...acl.Perm) (acl.Mask, error) {
 	return acl.Update(name, uid, perms...)
 }
+func (k direct) aclRestore(name string, uid int, mask acl.Mask) error {
+	return acl.Restore(name, uid, mask)
+}
 
 func (k direct) waylandNew(displayPath, bindPath *check.Absolute, appID, instanceID string) (*wayland.SecurityContext, error) {
 	return wayland.New(displayPath, bindPath, appID, instanceID)
@@ -88,6 +115,25 @@ func (k direct) xcbChangeHosts(mode xcb.HostMode, family xcb.Family, address str
 	return xcb.ChangeHosts(mode, family, address)
 }
 
+func (k direct) xauthGenerate(ctx context.Context, xauth, name, display string, timeout int) (cookie []byte, err error) {
+	cookie = make([]byte, 16)
+	if _, err = rand.Read(cookie); err != nil {
+		return
+	}
+
+	cmd := exec.CommandContext(ctx, xauth, "-q", "-f", name,
+		"generate", display, xauthProtocol, "trusted",
+		"timeout", strconv.Itoa(timeout),
+		"data", hex.EncodeToString(cookie))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if out = bytes.TrimSpace(out); len(out) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, out)
+		}
+		return nil, err
+	}
+	return
+}
+
 func (k direct) dbusFinalise(sessionBus, systemBus dbus.ProxyPair, session, system *hst.BusConfig) (final *dbus.Final, err error) {
 	return dbus.Finalise(sessionBus, systemBus, session, system)
 }
@@ -95,3 +141,7 @@ func (k direct) dbusFinalise(sessionBus, systemBus dbus.ProxyPair, session, syst
 func (k direct) dbusProxyStart(proxy *dbus.Proxy) error { return proxy.Start() }
 func (k direct) dbusProxyClose(proxy *dbus.Proxy)       { proxy.Close() }
 func (k direct) dbusProxyWait(proxy *dbus.Proxy) error  { return proxy.Wait() }
+
+func (k direct) dbusProxyRestart(proxy *dbus.Proxy, final *dbus.Final) error {
+	return proxy.Restart(final)
+}
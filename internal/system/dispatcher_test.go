@@ -1,6 +1,7 @@
 package system
 
 import (
+	"context"
 	"log"
 	"os"
 	"reflect"
@@ -205,6 +206,8 @@ type kstub struct{ *stub.Stub[syscallDispatcher] }
 
 func (k *kstub) new(f func(k syscallDispatcher)) { k.Helper(); k.New(f) }
 
+func (k *kstub) getuid() int { k.Helper(); return k.Expects("getuid").Ret.(int) }
+
 func (k *kstub) stat(name string) (fi os.FileInfo, err error) {
 	k.Helper()
 	expect := k.Expects("stat")
@@ -254,6 +257,14 @@ func (k *kstub) remove(name string) error {
 		stub.CheckArg(k.Stub, "name", name, 0))
 }
 
+func (k *kstub) writeFile(name string, data []byte, perm os.FileMode) error {
+	k.Helper()
+	return k.Expects("writeFile").Error(
+		stub.CheckArg(k.Stub, "name", name, 0),
+		stub.CheckArgReflect(k.Stub, "data", data, 1),
+		stub.CheckArg(k.Stub, "perm", perm, 2))
+}
+
 func (k *kstub) println(v ...any) {
 	k.Helper()
 	k.Expects("println")
@@ -262,14 +273,23 @@ func (k *kstub) println(v ...any) {
 	}
 }
 
-func (k *kstub) aclUpdate(name string, uid int, perms ...acl.Perm) error {
+func (k *kstub) aclUpdate(name string, uid int, perms ...acl.Perm) (acl.Mask, error) {
 	k.Helper()
-	return k.Expects("aclUpdate").Error(
+	expect := k.Expects("aclUpdate")
+	return expect.Ret.(acl.Mask), expect.Error(
 		stub.CheckArg(k.Stub, "name", name, 0),
 		stub.CheckArg(k.Stub, "uid", uid, 1),
 		stub.CheckArgReflect(k.Stub, "perms", perms, 2))
 }
 
+func (k *kstub) aclRestore(name string, uid int, mask acl.Mask) error {
+	k.Helper()
+	return k.Expects("aclRestore").Error(
+		stub.CheckArg(k.Stub, "name", name, 0),
+		stub.CheckArg(k.Stub, "uid", uid, 1),
+		stub.CheckArg(k.Stub, "mask", mask, 2))
+}
+
 func (k *kstub) waylandNew(displayPath, bindPath *check.Absolute, appID, instanceID string) (*wayland.SecurityContext, error) {
 	k.Helper()
 	return nil, k.Expects("waylandNew").Error(
@@ -287,6 +307,20 @@ func (k *kstub) xcbChangeHosts(mode xcb.HostMode, family xcb.Family, address str
 		stub.CheckArg(k.Stub, "address", address, 2))
 }
 
+func (k *kstub) xauthGenerate(_ context.Context, xauth, name, display string, timeout int) (cookie []byte, err error) {
+	k.Helper()
+	expect := k.Expects("xauthGenerate")
+	err = expect.Error(
+		stub.CheckArg(k.Stub, "xauth", xauth, 0),
+		stub.CheckArg(k.Stub, "name", name, 1),
+		stub.CheckArg(k.Stub, "display", display, 2),
+		stub.CheckArg(k.Stub, "timeout", timeout, 3))
+	if err == nil {
+		cookie = expect.Ret.([]byte)
+	}
+	return
+}
+
 func (k *kstub) dbusFinalise(sessionBus, systemBus dbus.ProxyPair, session, system *hst.BusConfig) (final *dbus.Final, err error) {
 	k.Helper()
 	expect := k.Expects("dbusFinalise")
@@ -307,6 +341,15 @@ func (k *kstub) dbusProxyStart(proxy *dbus.Proxy) error {
 	k.Helper()
 	return k.dbusProxySCW(k.Expects("dbusProxyStart"), proxy)
 }
+func (k *kstub) dbusProxyRestart(proxy *dbus.Proxy, final *dbus.Final) error {
+	k.Helper()
+	expect := k.Expects("dbusProxyRestart")
+	if !reflect.DeepEqual(final, expect.Args[1]) {
+		k.Errorf("dbusProxyRestart: final = %#v, want %#v", final, expect.Args[1])
+		return os.ErrInvalid
+	}
+	return k.dbusProxySCW(expect, proxy)
+}
 func (k *kstub) dbusProxyClose(proxy *dbus.Proxy) {
 	k.Helper()
 	if k.dbusProxySCW(k.Expects("dbusProxyClose"), proxy) != nil {
@@ -25,6 +25,7 @@ type hardlinkOp struct {
 }
 
 func (l *hardlinkOp) Type() hst.Enablement { return l.et }
+func (l *hardlinkOp) name() string         { return "hardlink" }
 
 func (l *hardlinkOp) apply(sys *I) error {
 	sys.msg.Verbose("linking", l)
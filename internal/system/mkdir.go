@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 
 	"hakurei.app/container/check"
 	"hakurei.app/hst"
@@ -16,6 +17,8 @@ func (sys *I) Ensure(name *check.Absolute, perm os.FileMode) *I {
 }
 
 // Ephemeral ensures the existence of a directory until its [Enablement] is no longer satisfied.
+// A directory of type [Process] is unique to the instance, so [hst.ErrInstanceExists] is returned
+// if it already exists, in place of taking over a directory held by another instance.
 func (sys *I) Ephemeral(et hst.Enablement, name *check.Absolute, perm os.FileMode) *I {
 	sys.ops = append(sys.ops, &mkdirOp{et, name.String(), perm, true})
 	return sys
@@ -30,6 +33,7 @@ type mkdirOp struct {
 }
 
 func (m *mkdirOp) Type() hst.Enablement { return m.et }
+func (m *mkdirOp) name() string         { return "mkdir" }
 
 func (m *mkdirOp) apply(sys *I) error {
 	sys.msg.Verbose("ensuring directory", m)
@@ -38,6 +42,12 @@ func (m *mkdirOp) apply(sys *I) error {
 		if !errors.Is(err, os.ErrExist) {
 			return newOpError("mkdir", err, false)
 		}
+		if m.ephemeral && m.et == Process {
+			// not reverted as it belongs to another instance
+			return newOpErrorMessage("mkdir", hst.ErrInstanceExists,
+				"instance directory "+strconv.Quote(m.path)+" already exists, "+
+					"another instance with the same identity may be running", false)
+		}
 		// directory exists, ensure mode
 		return newOpError("mkdir", sys.chmod(m.path, m.perm), false)
 	} else {
@@ -1,10 +1,14 @@
 package system
 
 import (
+	"errors"
 	"os"
 	"testing"
 
+	"hakurei.app/container/check"
 	"hakurei.app/container/stub"
+	"hakurei.app/hst"
+	"hakurei.app/message"
 )
 
 func TestMkdirOp(t *testing.T) {
@@ -48,6 +52,12 @@ func TestMkdirOp(t *testing.T) {
 			call("verbose", stub.ExpectArgs{[]any{"skipping ephemeral directory", &mkdirOp{User, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0711, true}}}, nil, nil),
 		}, nil},
 
+		{"exists", 0xbeef, 0xff, &mkdirOp{Process, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0711, true}, []stub.Call{
+			call("verbose", stub.ExpectArgs{[]any{"ensuring directory", &mkdirOp{Process, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0711, true}}}, nil, nil),
+			call("mkdir", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", os.FileMode(0711)}, nil, os.ErrExist),
+		}, &OpError{Op: "mkdir", Err: hst.ErrInstanceExists,
+			Msg: `instance directory "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9" already exists, another instance with the same identity may be running`}, nil, nil},
+
 		{"success", 0xbeef, 0xff, &mkdirOp{Process, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0711, true}, []stub.Call{
 			call("verbose", stub.ExpectArgs{[]any{"ensuring directory", &mkdirOp{Process, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0711, true}}}, nil, nil),
 			call("mkdir", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", os.FileMode(0711)}, nil, nil),
@@ -108,6 +118,26 @@ func TestMkdirOp(t *testing.T) {
 
 		{"ephemeral", &mkdirOp{User, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0700, true},
 			User, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9",
-			`mode: -rwx------ type: user path: "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9"`},
-	})
+			`mode: -rwx------ type: user path: "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9"`}})
+}
+
+func TestMkdirOpInstanceExists(t *testing.T) {
+	t.Parallel()
+
+	base := check.MustAbs(t.TempDir())
+	instance := base.Append("f2f3bcd492d0266438fa9bf164fe90d9")
+	// directory of another instance sharing the same ID
+	if err := os.Mkdir(instance.String(), 0711); err != nil {
+		t.Fatal(err)
+	}
+
+	sys := New(t.Context(), message.New(nil), 0xbeef).
+		Ensure(base.Append("runtime"), 0700).
+		Ephemeral(Process, instance, 0711)
+	if err := sys.Commit(); !errors.Is(err, hst.ErrInstanceExists) {
+		t.Fatalf("Commit: error = %v, want %v", err, hst.ErrInstanceExists)
+	}
+	if _, err := os.Stat(instance.String()); err != nil {
+		t.Errorf("Stat: error = %v", err)
+	}
 }
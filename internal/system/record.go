@@ -0,0 +1,143 @@
+package system
+
+import (
+	"context"
+	"os"
+	"strconv"
+
+	"hakurei.app/container/check"
+	"hakurei.app/hst"
+	"hakurei.app/message"
+)
+
+// Record holds the state of [I] required to revert its [Op] in a different process,
+// for cleaning up after a process terminating without calling Revert. See [I.Record] and [Restore].
+type Record struct {
+	// Value of [I.UID].
+	UID int `json:"uid"`
+	// Revertible [Op] in the order they are applied.
+	Ops []OpRecord `json:"ops"`
+}
+
+// OpRecord holds the state of a single [Op] required to revert it.
+type OpRecord struct {
+	// Name of the operation, as in [OpError.Op].
+	Kind string `json:"kind"`
+	// Value returned by the Type method of [Op].
+	Type hst.Enablement `json:"type"`
+	// Absolute pathname the operation targets.
+	Path string `json:"path,omitempty"`
+	// Name of the X11 host access entry.
+	Name string `json:"name,omitempty"`
+	// Cgroup directories created by the operation, removed in reverse order.
+	Created []string `json:"created,omitempty"`
+}
+
+/*
+Record returns the state of [Op] held by sys required to revert them via [Restore].
+
+Record is intended to be called ahead of Commit, so the result is available in case the process
+terminates before it is able to revert. [Op] with no effect to revert, such as attaching a process,
+and the message bus proxy, which terminates with the process, are omitted. The wayland security
+context also ends with the process, so only its socket is removed by the restored [Op].
+*/
+func (sys *I) Record() *Record {
+	r := &Record{UID: sys.uid, Ops: make([]OpRecord, 0, len(sys.ops))}
+	for _, o := range sys.ops {
+		switch op := o.(type) {
+		case *aclUpdateOp:
+			r.Ops = append(r.Ops, OpRecord{Kind: op.name(), Type: op.et, Path: op.path})
+		case *hardlinkOp:
+			r.Ops = append(r.Ops, OpRecord{Kind: op.name(), Type: op.et, Path: op.dst})
+		case *mkdirOp:
+			if op.ephemeral {
+				r.Ops = append(r.Ops, OpRecord{Kind: op.name(), Type: op.et, Path: op.path})
+			}
+		case xhostOp:
+			r.Ops = append(r.Ops, OpRecord{Kind: op.name(), Type: op.Type(), Name: string(op)})
+		case *xauthOp:
+			r.Ops = append(r.Ops, OpRecord{Kind: op.name(), Type: op.Type(), Path: op.pathname.String()})
+		case *waylandOp:
+			r.Ops = append(r.Ops, OpRecord{Kind: op.name(), Type: op.Type(), Path: op.dst.String()})
+		case *cgroupOp:
+			if !op.limits.Persist {
+				created := op.created
+				if len(created) == 0 {
+					// the instance cgroup is unique to the instance, while its parents are not known before apply
+					created = []string{op.path}
+				}
+				r.Ops = append(r.Ops, OpRecord{Kind: op.name(), Type: op.Type(), Path: op.path, Created: created})
+			}
+		}
+	}
+	return r
+}
+
+// Restore returns the address of a new [I] holding [Op] described by r. The resulting [I] is
+// only suitable for a call to Revert, and must not be committed. Restore returns [OpError]
+// for a malformed [OpRecord].
+func Restore(ctx context.Context, msg message.Msg, r *Record) (*I, error) {
+	if r == nil {
+		return nil, newOpErrorMessage("restore", os.ErrInvalid, "invalid system operation record", false)
+	}
+	sys := New(ctx, msg, r.UID)
+	if err := sys.restore(r); err != nil {
+		return nil, err
+	}
+	return sys, nil
+}
+
+// restore appends [Op] described by r to sys.
+func (sys *I) restore(r *Record) error {
+	sys.uid = r.UID
+	// Commit is not meaningful for the partial state held by restored ops
+	sys.committed = true
+
+	for i, o := range r.Ops {
+		invalid := newOpErrorMessage(o.Kind, os.ErrInvalid,
+			"invalid "+o.Kind+" operation record at index "+strconv.Itoa(i), false)
+
+		pathname, err := check.NewAbs(o.Path)
+		if err != nil && o.Kind != "xhost" {
+			return invalid
+		}
+
+		switch o.Kind {
+		case "acl":
+			sys.ops = append(sys.ops, &aclUpdateOp{o.Type, o.Path, nil, nil})
+		case "hardlink":
+			sys.ops = append(sys.ops, &hardlinkOp{o.Type, o.Path, ""})
+		case "mkdir":
+			sys.ops = append(sys.ops, &mkdirOp{o.Type, o.Path, 0, true})
+		case "xhost":
+			if o.Name == "" {
+				return invalid
+			}
+			sys.ops = append(sys.ops, xhostOp(o.Name))
+		case "xauth":
+			sys.ops = append(sys.ops, &xauthOp{pathname: pathname})
+		case "wayland":
+			sys.ops = append(sys.ops, &waylandOp{dst: pathname})
+		case "cgroup":
+			for _, dir := range o.Created {
+				if _, err = check.NewAbs(dir); err != nil {
+					return invalid
+				}
+			}
+			sys.ops = append(sys.ops, &cgroupOp{path: o.Path, created: o.Created})
+		default:
+			return invalid
+		}
+	}
+	return nil
+}
+
+// RevertRecord reverts [Op] described by r meeting [Criteria] on behalf of a process that terminated
+// without reverting them. The [Criteria] is determined the same way as for a call to [I.Revert].
+func RevertRecord(ctx context.Context, msg message.Msg, r *Record, ec *Criteria) error {
+	sys, err := Restore(ctx, msg, r)
+	if err != nil {
+		return err
+	}
+	return sys.Revert(ec)
+}
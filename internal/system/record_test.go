@@ -0,0 +1,122 @@
+package system
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"hakurei.app/container/stub"
+	"hakurei.app/hst"
+	"hakurei.app/internal/acl"
+	"hakurei.app/internal/xcb"
+	"hakurei.app/message"
+)
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+
+	const (
+		instance = "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9"
+		runtime  = "/run/user/1000/hakurei/f2f3bcd492d0266438fa9bf164fe90d9"
+		cgroup   = "/sys/fs/cgroup/hakurei.slice/hakurei-0/f2f3bcd492d0266438fa9bf164fe90d9"
+
+		saved = `{"uid":1000,"ops":[` +
+			`{"kind":"mkdir","type":64,"path":"` + instance + `"},` +
+			`{"kind":"acl","type":32,"path":"/run/user/1000/hakurei"},` +
+			`{"kind":"hardlink","type":64,"path":"` + runtime + `/pulse"},` +
+			`{"kind":"xhost","type":2,"name":"#1000"},` +
+			`{"kind":"xauth","type":64,"path":"` + instance + `/Xauthority"},` +
+			`{"kind":"wayland","type":64,"path":"` + runtime + `/wayland"},` +
+			`{"kind":"cgroup","type":64,"path":"` + cgroup + `","created":["` + cgroup + `"]}]}`
+	)
+
+	t.Run("record", func(t *testing.T) {
+		t.Parallel()
+
+		sys, _ := InternalNew(t, stub.Expect{}, 1000)
+		sys.
+			Ensure(m("/tmp/hakurei.0"), 0711).
+			Ephemeral(Process, m(instance), 0711).
+			UpdatePermType(User, m("/run/user/1000/hakurei"), acl.Execute).
+			Link(m("/run/user/1000/pulse/native"), m(runtime+"/pulse")).
+			ChangeHosts("#1000").
+			Xauth(m("/run/current-system/sw/bin/xauth"), m(instance+"/Xauthority"), 0).
+			Wayland(m(runtime+"/wayland"), m("/run/user/1000/wayland-0"), "org.chromium.Chromium", "f2f3bcd492d0266438fa9bf164fe90d9").
+			Cgroup(m("/sys/fs/cgroup/hakurei.slice"), m(cgroup), CgroupLimits{Pids: 64}).
+			Attach(0xbeef).
+			Cgroup(m("/sys/fs/cgroup/hakurei.slice"), m(cgroup+"-persist"), CgroupLimits{Persist: true})
+
+		if got, err := json.Marshal(sys.Record()); err != nil {
+			t.Fatalf("Marshal: error = %v", err)
+		} else if string(got) != saved {
+			t.Errorf("Record:\n%s\nwant\n%s", got, saved)
+		}
+	})
+
+	t.Run("revert", func(t *testing.T) {
+		t.Parallel()
+
+		var r Record
+		if err := json.Unmarshal([]byte(saved), &r); err != nil {
+			t.Fatalf("Unmarshal: error = %v", err)
+		}
+
+		sys, s := InternalNew(t, stub.Expect{Calls: []stub.Call{
+			call("remove", stub.ExpectArgs{cgroup}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"hanging up wayland socket on %q", []any{m(runtime + "/wayland")}}, nil, nil),
+			call("remove", stub.ExpectArgs{runtime + "/wayland"}, nil, os.ErrNotExist),
+			call("verbosef", stub.ExpectArgs{"removing X11 authority file %q", []any{m(instance + "/Xauthority")}}, nil, nil),
+			call("remove", stub.ExpectArgs{instance + "/Xauthority"}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"deleting entry %s from X11", []any{xhostOp("#1000")}}, nil, nil),
+			call("xcbChangeHosts", stub.ExpectArgs{xcb.HostMode(xcb.HostModeDelete), xcb.Family(xcb.FamilyServerInterpreted), "localuser\x00#1000"}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"removing hard link %q", []any{runtime + "/pulse"}}, nil, nil),
+			call("remove", stub.ExpectArgs{runtime + "/pulse"}, nil, nil),
+			call("verbose", stub.ExpectArgs{[]any{"skipping ACL", ignoreValue{}}}, nil, nil),
+			call("verbose", stub.ExpectArgs{[]any{"destroying ephemeral directory", ignoreValue{}}}, nil, nil),
+			call("remove", stub.ExpectArgs{instance}, nil, nil),
+		}}, 0)
+		if err := sys.restore(&r); err != nil {
+			t.Fatalf("restore: error = %v", err)
+		}
+		if sys.UID() != 1000 {
+			t.Errorf("UID: %d, want 1000", sys.UID())
+		}
+
+		ec := Criteria(Process | hst.EX11)
+		if err := sys.Revert(&ec); err != nil {
+			t.Errorf("Revert: error = %v", err)
+		}
+		s.VisitIncomplete(func(s *stub.Stub[syscallDispatcher]) {
+			t.Errorf("Revert: %d calls, want %d", s.Pos(), 12)
+		})
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []struct {
+			name string
+			r    *Record
+			want error
+		}{
+			{"nil", nil, &OpError{Op: "restore", Err: os.ErrInvalid, Msg: "invalid system operation record"}},
+			{"relative", &Record{Ops: []OpRecord{{Kind: "acl", Path: "run/user/1000"}}},
+				&OpError{Op: "acl", Err: os.ErrInvalid, Msg: "invalid acl operation record at index 0"}},
+			{"xhost", &Record{Ops: []OpRecord{{Kind: "mkdir", Path: "/tmp/hakurei.0"}, {Kind: "xhost"}}},
+				&OpError{Op: "xhost", Err: os.ErrInvalid, Msg: "invalid xhost operation record at index 1"}},
+			{"cgroup", &Record{Ops: []OpRecord{{Kind: "cgroup", Path: cgroup, Created: []string{"hakurei-0"}}}},
+				&OpError{Op: "cgroup", Err: os.ErrInvalid, Msg: "invalid cgroup operation record at index 0"}},
+			{"unknown", &Record{Ops: []OpRecord{{Kind: "dbus", Path: "/"}}},
+				&OpError{Op: "dbus", Err: os.ErrInvalid, Msg: "invalid dbus operation record at index 0"}},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+				if _, err := Restore(t.Context(), message.New(nil), tc.r); !reflect.DeepEqual(err, tc.want) {
+					t.Errorf("Restore: error = %v, want %v", err, tc.want)
+				}
+			})
+		}
+	})
+}
@@ -4,7 +4,9 @@ package system
 import (
 	"context"
 	"errors"
+	"os"
 	"strings"
+	"time"
 
 	"hakurei.app/hst"
 	"hakurei.app/message"
@@ -38,6 +40,8 @@ type Op interface {
 
 	apply(sys *I) error
 	revert(sys *I, ec *Criteria) error
+	// name returns the name of the operation, used as [OpError.Op].
+	name() string
 
 	Is(o Op) bool
 	Path() string
@@ -83,6 +87,10 @@ type I struct {
 	uid int
 	ops []Op
 	ctx context.Context
+	// maximum duration of a single apply during Commit, zero for no limit
+	timeout time.Duration
+	// canceled once the Op being applied times out, nil outside a timed apply
+	applyCtx context.Context
 
 	// the behaviour of Commit is only defined for up to one call
 	committed bool
@@ -95,6 +103,17 @@ type I struct {
 
 func (sys *I) UID() int { return sys.uid }
 
+/*
+SetOpTimeout sets the maximum duration Commit waits for a single [Op] to apply. An [Op] not
+applied in time causes Commit to fail with [OpError] wrapping [os.ErrDeadlineExceeded] and to
+revert all [Op] applied before it. The zero value, which is the default, disables the timeout.
+
+The context of an [Op] not applied in time is canceled and the [Op] is abandoned: Commit does
+not wait for it to return before rolling back. If it is applied regardless, it is reverted
+once it returns, which might not happen before the calling process exits.
+*/
+func (sys *I) SetOpTimeout(d time.Duration) *I { sys.timeout = d; return sys }
+
 // Equal returns whether all [Op] instances held by sys matches that of target.
 func (sys *I) Equal(target *I) bool {
 	if sys == nil || target == nil || sys.uid != target.uid || len(sys.ops) != len(target.ops) {
@@ -133,12 +152,14 @@ func (sys *I) Commit() error {
 	}()
 
 	for _, o := range sys.ops {
-		if err := o.apply(sys); err != nil {
-			return err
-		} else {
+		applied, err := sys.apply(o)
+		if applied {
 			// register partial commit
 			sp.ops = append(sp.ops, o)
 		}
+		if err != nil {
+			return err
+		}
 	}
 
 	// disarm partial commit rollback
@@ -146,6 +167,53 @@ func (sys *I) Commit() error {
 	return nil
 }
 
+// opContext returns the context an [Op] observes while being applied.
+func (sys *I) opContext() context.Context {
+	if sys.applyCtx != nil {
+		return sys.applyCtx
+	}
+	return sys.ctx
+}
+
+// apply applies o and returns whether it was applied. If o does not complete within the
+// duration set by SetOpTimeout, its context is canceled and o is abandoned, returning
+// [OpError] wrapping [os.ErrDeadlineExceeded] immediately. An abandoned o applied late is
+// reverted as soon as it returns.
+func (sys *I) apply(o Op) (applied bool, err error) {
+	if sys.timeout <= 0 {
+		err = o.apply(sys)
+		return err == nil, err
+	}
+
+	ctx, cancel := context.WithCancel(sys.ctx)
+	sys.applyCtx = ctx
+
+	done := make(chan error, 1)
+	go func() { done <- o.apply(sys) }()
+	t := time.NewTimer(sys.timeout)
+	defer t.Stop()
+	select {
+	case err = <-done:
+		cancel()
+		sys.applyCtx = nil
+		return err == nil, err
+	case <-t.C:
+		// applyCtx is left in place as o might still observe it, Commit does not apply further ops
+		cancel()
+		sys.msg.Verbosef("%s operation on %s timed out, abandoning it", o.name(), o.Path())
+		go func() {
+			if <-done != nil {
+				return
+			}
+			if revertErr := o.revert(sys, nil); revertErr != nil {
+				printJoinedError(sys.println, "cannot revert abandoned operation:", revertErr)
+			}
+		}()
+		return false, newOpErrorMessage(o.name(), os.ErrDeadlineExceeded,
+			"cannot apply "+o.name()+" operation on "+o.Path()+": timed out after "+sys.timeout.String(), false)
+	}
+}
+
 // Revert reverts all [Op] meeting [Criteria] held by [I].
 func (sys *I) Revert(ec *Criteria) error {
 	if sys.reverted {
@@ -1,6 +1,7 @@
 package system
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
@@ -8,11 +9,14 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"hakurei.app/container/check"
 	"hakurei.app/container/stub"
 	"hakurei.app/hst"
+	"hakurei.app/internal/acl"
 	"hakurei.app/internal/xcb"
 	"hakurei.app/message"
 )
@@ -27,7 +31,9 @@ func TestCriteria(t *testing.T) {
 	}{
 		{"nil", 0xff, hst.EWayland, true},
 		{"nil user", 0xff, User, false},
-		{"all", hst.EWayland | hst.EX11 | hst.EDBus | hst.EPulse | User | Process, Process, true},
+		{"all", hst.EWayland | hst.EX11 | hst.EDBus | hst.EPulse | hst.EPipeWire | User | Process, Process, true},
+		{"pipewire", hst.EPipeWire | Process, hst.EPipeWire, true},
+		{"pipewire pulse", hst.EPulse | Process, hst.EPipeWire, false},
 	}
 
 	for _, tc := range testCases {
@@ -53,9 +59,17 @@ func TestCgroupOp(t *testing.T) {
 	target := base.Append("hakurei-1", "instance")
 
 	sys.Cgroup(base, target, CgroupLimits{
-		CPU:    50000,
-		Memory: 2048,
-		Pids:   16,
+		CPU:           50000,
+		CPUWeight:     200,
+		Memory:        2048,
+		MemorySwapMax: 1024,
+		MemoryLow:     512,
+		Pids:          16,
+		IOMax: map[string]hst.CgroupIOLimit{
+			"8:0":   {RBPS: 1 << 20, WIOPS: 120},
+			"259:0": {WBPS: 1 << 20},
+			"7:0":   {},
+		},
 	})
 
 	if err := sys.Commit(); err != nil {
@@ -73,12 +87,25 @@ func TestCgroupOp(t *testing.T) {
 	if got := read("cpu.max"); strings.TrimSpace(got) != "50000 100000" {
 		t.Fatalf("cpu.max: %q", got)
 	}
+	if got := read("cpu.weight"); strings.TrimSpace(got) != "200" {
+		t.Fatalf("cpu.weight: %q", got)
+	}
 	if got := read("memory.max"); strings.TrimSpace(got) != "2048" {
 		t.Fatalf("memory.max: %q", got)
 	}
+	if got := read("memory.swap.max"); strings.TrimSpace(got) != "1024" {
+		t.Fatalf("memory.swap.max: %q", got)
+	}
+	if got := read("memory.low"); strings.TrimSpace(got) != "512" {
+		t.Fatalf("memory.low: %q", got)
+	}
 	if got := read("pids.max"); strings.TrimSpace(got) != "16" {
 		t.Fatalf("pids.max: %q", got)
 	}
+	// each entry is written separately, resulting in concatenation on a regular file
+	if got := read("io.max"); got != "259:0 wbps=1048576"+"8:0 rbps=1048576 wiops=120" {
+		t.Fatalf("io.max: %q", got)
+	}
 
 	if err := sys.Revert(nil); err != nil {
 		t.Fatalf("Revert: %v", err)
@@ -88,6 +115,232 @@ func TestCgroupOp(t *testing.T) {
 	}
 }
 
+func TestCgroupOpCPUSet(t *testing.T) {
+	t.Parallel()
+
+	sys := New(t.Context(), message.New(nil), 0xbeef)
+	base := check.MustAbs(t.TempDir())
+	target := base.Append("hakurei-1", "instance")
+
+	sys.Cgroup(base, target, CgroupLimits{CPUSet: "0-3,7"})
+
+	if err := sys.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(target.String(), "cpuset.cpus")); err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	} else if got := strings.TrimSpace(string(data)); got != "0-3,7" {
+		t.Fatalf("cpuset.cpus: %q", got)
+	}
+
+	if !sys.Equal(New(t.Context(), message.New(nil), 0xbeef).Cgroup(base, target, CgroupLimits{CPUSet: "0-3,7"})) {
+		t.Errorf("Equal: unexpected false")
+	}
+	if sys.Equal(New(t.Context(), message.New(nil), 0xbeef).Cgroup(base, target, CgroupLimits{CPUSet: "0-3"})) {
+		t.Errorf("Equal: unexpected true")
+	}
+
+	if err := sys.Revert(nil); err != nil {
+		t.Fatalf("Revert: %v", err)
+	}
+	if _, err := os.Stat(target.String()); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("target still exists: %v", err)
+	}
+}
+
+func TestCgroupOpAccounting(t *testing.T) {
+	t.Parallel()
+
+	sys := New(t.Context(), message.New(nil), 0xbeef)
+	base := check.MustAbs(t.TempDir())
+	target := base.Append("hakurei-1", "instance")
+
+	sys.Cgroup(base, target, CgroupLimits{})
+
+	if err := sys.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if entries, err := os.ReadDir(target.String()); err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	} else if len(entries) != 0 {
+		t.Fatalf("ReadDir: %d entries, want 0", len(entries))
+	}
+
+	if err := sys.Revert(nil); err != nil {
+		t.Fatalf("Revert: %v", err)
+	}
+	if _, err := os.Stat(base.Append("hakurei-1").String()); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("instance root still exists: %v", err)
+	}
+}
+
+func TestCgroupOpPersist(t *testing.T) {
+	t.Parallel()
+
+	sys := New(t.Context(), message.New(nil), 0xbeef)
+	base := check.MustAbs(t.TempDir())
+	target := base.Append("hakurei-1", "instance")
+
+	sys.Cgroup(base, target, CgroupLimits{Pids: 16, Persist: true})
+	if sys.Equal(New(t.Context(), message.New(nil), 0xbeef).Cgroup(base, target, CgroupLimits{Pids: 16})) {
+		t.Errorf("Equal: unexpected true")
+	}
+
+	if err := sys.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if err := sys.Revert(nil); err != nil {
+		t.Fatalf("Revert: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(target.String(), "pids.max")); err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	} else if got := strings.TrimSpace(string(data)); got != "16" {
+		t.Fatalf("pids.max: %q", got)
+	}
+}
+
+func TestCgroupOpDelegation(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T, controllers, subtree string) (base, target *check.Absolute) {
+		base = check.MustAbs(t.TempDir())
+		target = base.Append("instance")
+		if err := os.WriteFile(base.Append("cgroup.controllers").String(), []byte(controllers), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := os.WriteFile(base.Append("cgroup.subtree_control").String(), []byte(subtree), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		return
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+		base, target := setup(t, "cpuset cpu io memory pids\n", "cpu memory\n")
+
+		sys := New(t.Context(), message.New(nil), 0xbeef).
+			Cgroup(base, target, CgroupLimits{CPU: 50000, Memory: 2048})
+		if err := sys.Commit(); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+		if data, err := os.ReadFile(base.Append("cgroup.subtree_control").String()); err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		} else if got := string(data); got != "cpu memory\n" {
+			t.Errorf("cgroup.subtree_control: %q", got)
+		}
+		if err := sys.Revert(nil); err != nil {
+			t.Fatalf("Revert: %v", err)
+		}
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		t.Parallel()
+		base, target := setup(t, "cpuset cpu io memory pids\n", "cpu\n")
+
+		sys := New(t.Context(), message.New(nil), 0xbeef).
+			Cgroup(base, target, CgroupLimits{Pids: 16})
+		wantErr := &OpError{Op: "cgroup", Err: syscall.ENOTSUP,
+			Msg: `cgroup controller "pids" is not enabled in "` + base.String() + `"`}
+		if err := sys.Commit(); !reflect.DeepEqual(err, wantErr) {
+			t.Fatalf("Commit: error = %#v, want %#v", err, wantErr)
+		}
+		if _, err := os.Stat(target.String()); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("target exists: %v", err)
+		}
+		if data, err := os.ReadFile(base.Append("cgroup.subtree_control").String()); err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		} else if got := string(data); got != "cpu\n" {
+			t.Errorf("cgroup.subtree_control: %q", got)
+		}
+	})
+
+	t.Run("auto enable", func(t *testing.T) {
+		t.Parallel()
+		base, target := setup(t, "cpuset cpu io memory pids\n", "\n")
+
+		sys := New(t.Context(), message.New(nil), 0xbeef).
+			Cgroup(base, target, CgroupLimits{Pids: 16, EnableControllers: true})
+		if err := sys.Commit(); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+		// each write overwrites the regular file standing in for cgroup.subtree_control
+		if data, err := os.ReadFile(base.Append("cgroup.subtree_control").String()); err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		} else if got := string(data); got != "+pids" {
+			t.Errorf("cgroup.subtree_control: %q", got)
+		}
+		if err := sys.Revert(nil); err != nil {
+			t.Fatalf("Revert: %v", err)
+		}
+		if data, err := os.ReadFile(base.Append("cgroup.subtree_control").String()); err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		} else if got := string(data); got != "-pids" {
+			t.Errorf("cgroup.subtree_control: %q", got)
+		}
+	})
+
+	t.Run("auto enable busy", func(t *testing.T) {
+		t.Parallel()
+		base, target := setup(t, "cpuset cpu io memory pids\n", "\n")
+		if err := os.WriteFile(base.Append("cgroup.procs").String(), []byte("1\n"), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+
+		sys := New(t.Context(), message.New(nil), 0xbeef).
+			Cgroup(base, target, CgroupLimits{Pids: 16, EnableControllers: true})
+		wantErr := &OpError{Op: "cgroup", Err: syscall.EBUSY,
+			Msg: `cannot enable cgroup controllers in "` + base.String() + `" as it has member processes`}
+		if err := sys.Commit(); !reflect.DeepEqual(err, wantErr) {
+			t.Fatalf("Commit: error = %#v, want %#v", err, wantErr)
+		}
+		if _, err := os.Stat(target.String()); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("target exists: %v", err)
+		}
+	})
+
+	t.Run("auto enable shared", func(t *testing.T) {
+		t.Parallel()
+		base, target := setup(t, "cpuset cpu io memory pids\n", "\n")
+		if err := os.Mkdir(base.Append("sibling").String(), 0755); err != nil {
+			t.Fatalf("Mkdir: %v", err)
+		}
+
+		sys := New(t.Context(), message.New(nil), 0xbeef).
+			Cgroup(base, target, CgroupLimits{Pids: 16, EnableControllers: true})
+		if err := sys.Commit(); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+		if err := sys.Revert(nil); err != nil {
+			t.Fatalf("Revert: %v", err)
+		}
+		// controllers stay enabled while another cgroup remains in the slice
+		if data, err := os.ReadFile(base.Append("cgroup.subtree_control").String()); err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		} else if got := string(data); got != "+pids" {
+			t.Errorf("cgroup.subtree_control: %q", got)
+		}
+	})
+
+	t.Run("not delegated", func(t *testing.T) {
+		t.Parallel()
+		base, target := setup(t, "cpu pids\n", "cpu pids\n")
+
+		sys := New(t.Context(), message.New(nil), 0xbeef).
+			Cgroup(base, target, CgroupLimits{CPU: 50000, Memory: 2048})
+		wantErr := &OpError{Op: "cgroup", Err: syscall.ENOTSUP,
+			Msg: `cgroup controller "memory" is not delegated to "` + base.String() + `"`}
+		if err := sys.Commit(); !reflect.DeepEqual(err, wantErr) {
+			t.Fatalf("Commit: error = %#v, want %#v", err, wantErr)
+		}
+		if _, err := os.Stat(target.String()); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("target exists: %v", err)
+		}
+	})
+}
+
 func TestTypeString(t *testing.T) {
 	t.Parallel()
 
@@ -99,11 +352,13 @@ func TestTypeString(t *testing.T) {
 		{hst.EX11, hst.EX11.String()},
 		{hst.EDBus, hst.EDBus.String()},
 		{hst.EPulse, hst.EPulse.String()},
+		{hst.EPipeWire, hst.EPipeWire.String()},
 		{User, "user"},
 		{Process, "process"},
 		{User | Process, "user, process"},
 		{hst.EWayland | User | Process, "wayland, user, process"},
 		{hst.EX11 | Process, "x11, process"},
+		{hst.EPulse | hst.EPipeWire | User, "pulseaudio, pipewire, user"},
 	}
 
 	for _, tc := range testCases {
@@ -290,6 +545,60 @@ func TestCommitRevert(t *testing.T) {
 			&OpError{Op: "xhost", Err: stub.UniqueError(1), Revert: true},
 			&OpError{Op: "mkdir", Err: stub.UniqueError(0), Revert: true})},
 
+		{"apply xauth", func(sys *I) {
+			sys.
+				Ephemeral(Process, m("/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9"), 0711).
+				Xauth(m("/run/current-system/sw/bin/xauth"), m("/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority"), 0)
+		}, 0xff, []stub.Call{
+			call("verbose", stub.ExpectArgs{[]any{"ensuring directory", &mkdirOp{Process, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0711, true}}}, nil, nil),
+			call("mkdir", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", os.FileMode(0711)}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"generating X11 authorization for display %d in %q", []any{0, m("/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority")}}, nil, nil),
+			call("xauthGenerate", stub.ExpectArgs{"/run/current-system/sw/bin/xauth", "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority", ":0", xauthTimeout}, []byte{0xfe, 0xed}, nil),
+			call("writeFile", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority", xauthEntry(0, []byte{0xfe, 0xed}), os.FileMode(0600)}, nil, stub.UniqueError(2)),
+			call("remove", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority"}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"commit faulted after %d ops, rolling back partial commit", []any{1}}, nil, nil),
+			call("verbose", stub.ExpectArgs{[]any{"destroying ephemeral directory", &mkdirOp{Process, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0711, true}}}, nil, nil),
+			call("remove", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9"}, nil, nil),
+		}, &OpError{Op: "xauth", Err: stub.UniqueError(2)}, nil, nil},
+
+		{"revert multi xauth", func(sys *I) {
+			sys.
+				Ephemeral(Process, m("/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9"), 0711).
+				Xauth(m("/run/current-system/sw/bin/xauth"), m("/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority"), 0)
+		}, 0xff, []stub.Call{
+			call("verbose", stub.ExpectArgs{[]any{"ensuring directory", &mkdirOp{Process, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0711, true}}}, nil, nil),
+			call("mkdir", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", os.FileMode(0711)}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"generating X11 authorization for display %d in %q", []any{0, m("/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority")}}, nil, nil),
+			call("xauthGenerate", stub.ExpectArgs{"/run/current-system/sw/bin/xauth", "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority", ":0", xauthTimeout}, []byte{0xfe, 0xed}, nil),
+			call("writeFile", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority", xauthEntry(0, []byte{0xfe, 0xed}), os.FileMode(0600)}, nil, nil),
+			call("aclUpdate", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority", 0xbad, []acl.Perm{acl.Read}}, acl.MaskNone, nil),
+		}, nil, []stub.Call{
+			call("verbosef", stub.ExpectArgs{"removing X11 authority file %q", []any{m("/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority")}}, nil, nil),
+			call("remove", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority"}, nil, stub.UniqueError(1)),
+			call("verbose", stub.ExpectArgs{[]any{"destroying ephemeral directory", &mkdirOp{Process, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0711, true}}}, nil, nil),
+			call("remove", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9"}, nil, stub.UniqueError(0)),
+		}, errors.Join(
+			&OpError{Op: "xauth", Err: stub.UniqueError(1), Revert: true},
+			&OpError{Op: "mkdir", Err: stub.UniqueError(0), Revert: true})},
+
+		{"success xauth", func(sys *I) {
+			sys.
+				Ephemeral(Process, m("/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9"), 0711).
+				Xauth(m("/run/current-system/sw/bin/xauth"), m("/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority"), 0)
+		}, 0xff, []stub.Call{
+			call("verbose", stub.ExpectArgs{[]any{"ensuring directory", &mkdirOp{Process, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0711, true}}}, nil, nil),
+			call("mkdir", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", os.FileMode(0711)}, nil, nil),
+			call("verbosef", stub.ExpectArgs{"generating X11 authorization for display %d in %q", []any{0, m("/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority")}}, nil, nil),
+			call("xauthGenerate", stub.ExpectArgs{"/run/current-system/sw/bin/xauth", "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority", ":0", xauthTimeout}, []byte{0xfe, 0xed}, nil),
+			call("writeFile", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority", xauthEntry(0, []byte{0xfe, 0xed}), os.FileMode(0600)}, nil, nil),
+			call("aclUpdate", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority", 0xbad, []acl.Perm{acl.Read}}, acl.MaskNone, nil),
+		}, nil, []stub.Call{
+			call("verbosef", stub.ExpectArgs{"removing X11 authority file %q", []any{m("/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority")}}, nil, nil),
+			call("remove", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9/Xauthority"}, nil, nil),
+			call("verbose", stub.ExpectArgs{[]any{"destroying ephemeral directory", &mkdirOp{Process, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0711, true}}}, nil, nil),
+			call("remove", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9"}, nil, nil),
+		}, nil},
+
 		{"success", func(sys *I) {
 			sys.
 				Ephemeral(Process, m("/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9"), 0711).
@@ -367,6 +676,93 @@ func TestCommitRevert(t *testing.T) {
 	})
 }
 
+// blockOp is an [Op] whose apply blocks until release is closed, or its context is canceled
+// if it is not stubborn.
+type blockOp struct {
+	release  chan struct{}
+	stubborn bool
+	// closed when revert is called
+	reverted chan struct{}
+}
+
+func (b *blockOp) Type() hst.Enablement { return Process }
+func (b *blockOp) apply(sys *I) error {
+	if b.stubborn {
+		<-b.release
+		return nil
+	}
+	select {
+	case <-b.release:
+		return nil
+	case <-sys.opContext().Done():
+		return context.Cause(sys.opContext())
+	}
+}
+func (b *blockOp) revert(*I, *Criteria) error { close(b.reverted); return nil }
+func (b *blockOp) name() string               { return "block" }
+func (b *blockOp) Is(o Op) bool               { target, ok := o.(*blockOp); return ok && b == target }
+func (b *blockOp) Path() string               { return "/dev/null" }
+func (b *blockOp) String() string             { return "blocking op" }
+
+func TestCommitTimeout(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		stubborn bool
+		// whether release is closed after Commit returns
+		release bool
+	}{
+		{"canceled", false, false},
+		{"applied late", true, true},
+		{"never returns", true, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sys, s := InternalNew(t, stub.Expect{Calls: []stub.Call{
+				call("verbose", stub.ExpectArgs{[]any{"ensuring directory", &mkdirOp{Process, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0711, true}}}, nil, nil),
+				call("mkdir", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", os.FileMode(0711)}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"%s operation on %s timed out, abandoning it", []any{"block", "/dev/null"}}, nil, nil),
+				call("verbosef", stub.ExpectArgs{"commit faulted after %d ops, rolling back partial commit", []any{1}}, nil, nil),
+				call("verbose", stub.ExpectArgs{[]any{"destroying ephemeral directory", &mkdirOp{Process, "/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9", 0711, true}}}, nil, nil),
+				call("remove", stub.ExpectArgs{"/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9"}, nil, nil),
+			}}, 0xbad)
+			defer stub.HandleExit(t)
+
+			b := &blockOp{release: make(chan struct{}), stubborn: tc.stubborn, reverted: make(chan struct{})}
+			sys.Ephemeral(Process, m("/tmp/hakurei.0/f2f3bcd492d0266438fa9bf164fe90d9"), 0711).SetOpTimeout(time.Millisecond)
+			sys.ops = append(sys.ops, b)
+
+			wantErr := &OpError{Op: "block", Err: os.ErrDeadlineExceeded,
+				Msg: "cannot apply block operation on /dev/null: timed out after 1ms"}
+			if err := sys.Commit(); !reflect.DeepEqual(err, wantErr) {
+				t.Errorf("Commit: error = %v, want %v", err, wantErr)
+			}
+			s.VisitIncomplete(func(s *stub.Stub[syscallDispatcher]) {
+				t.Errorf("Commit: %d calls, want %d", s.Pos(), s.Len())
+			})
+
+			if tc.release {
+				close(b.release)
+				select {
+				case <-b.reverted:
+				case <-time.After(5 * time.Second):
+					t.Fatal("Commit: abandoned op applied late is not reverted")
+				}
+				return
+			}
+
+			select {
+			case <-b.reverted:
+				t.Error("Commit: abandoned op not applied is reverted")
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
 func TestNop(t *testing.T) {
 	// these do nothing
 	new(noCopy).Unlock()
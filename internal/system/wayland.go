@@ -28,6 +28,7 @@ type waylandOp struct {
 }
 
 func (w *waylandOp) Type() hst.Enablement { return Process }
+func (w *waylandOp) name() string         { return "wayland" }
 
 func (w *waylandOp) apply(sys *I) (err error) {
 	if w.ctx, err = sys.waylandNew(w.src, w.dst, w.appID, w.instanceID); err != nil {
@@ -42,7 +43,7 @@ func (w *waylandOp) apply(sys *I) (err error) {
 			return newOpError("wayland", err, false)
 		}
 
-		if err = sys.aclUpdate(w.dst.String(), sys.uid, acl.Read, acl.Write, acl.Execute); err != nil {
+		if _, err = sys.aclUpdate(w.dst.String(), sys.uid, acl.Read, acl.Write, acl.Execute); err != nil {
 			if closeErr := w.ctx.Close(); closeErr != nil {
 				return newOpError("wayland", errors.Join(err, closeErr), false)
 			}
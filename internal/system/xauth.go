@@ -0,0 +1,101 @@
+package system
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"hakurei.app/container/check"
+	"hakurei.app/hst"
+	"hakurei.app/internal/acl"
+)
+
+const (
+	// xauthTimeout is the number of seconds the X server retains an authorization
+	// generated by [I.Xauth] after its last client disconnects, or after it is generated
+	// if no client ever connects. This matches the default ForwardX11Timeout of OpenSSH.
+	xauthTimeout = 1200
+
+	// xauthFamilyWild is the address family matching any host, see Xauth.h.
+	xauthFamilyWild = 0xffff
+	// xauthProtocol is the name of the only authorization protocol generated by [I.Xauth].
+	xauthProtocol = "MIT-MAGIC-COOKIE-1"
+)
+
+/*
+Xauth registers a freshly generated MIT-MAGIC-COOKIE-1 authorization with the X server on display
+number display via the SECURITY extension, using the xauth program at pathname xauth, and writes it
+to a new X11 authority file at pathname readable by the target user.
+
+The entry is written with the wildcard address family, so it is matched regardless of the hostname
+of the container. The X11 authority file is removed on revert, but there is no way to revoke the
+authorization via xauth, so the X server instead expires it [xauthTimeout] seconds after its last
+client disconnects.
+*/
+func (sys *I) Xauth(xauth, pathname *check.Absolute, display int) *I {
+	sys.ops = append(sys.ops, &xauthOp{xauth, pathname, display})
+	return sys
+}
+
+// xauthOp implements [I.Xauth].
+type xauthOp struct {
+	xauth, pathname *check.Absolute
+	display         int
+}
+
+func (x *xauthOp) Type() hst.Enablement { return Process }
+func (x *xauthOp) name() string         { return "xauth" }
+
+func (x *xauthOp) apply(sys *I) error {
+	sys.msg.Verbosef("generating X11 authorization for display %d in %q", x.display, x.pathname)
+	cookie, err := sys.xauthGenerate(sys.opContext(), x.xauth.String(), x.pathname.String(), ":"+strconv.Itoa(x.display), xauthTimeout)
+	if err == nil {
+		// the entry written by xauth is bound to the hostname of the host
+		if err = sys.writeFile(x.pathname.String(), xauthEntry(x.display, cookie), 0600); err == nil {
+			_, err = sys.aclUpdate(x.pathname.String(), sys.uid, acl.Read)
+		}
+	}
+
+	if err != nil {
+		if removeErr := sys.remove(x.pathname.String()); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			return newOpError("xauth", errors.Join(err, removeErr), false)
+		}
+		return newOpError("xauth", err, false)
+	}
+	return nil
+}
+
+func (x *xauthOp) revert(sys *I, _ *Criteria) error {
+	sys.msg.Verbosef("removing X11 authority file %q", x.pathname)
+	if err := sys.remove(x.pathname.String()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return newOpError("xauth", err, true)
+	}
+	return nil
+}
+
+func (x *xauthOp) Is(o Op) bool {
+	target, ok := o.(*xauthOp)
+	return ok && x != nil && target != nil &&
+		x.xauth.Is(target.xauth) && x.pathname.Is(target.pathname) &&
+		x.display == target.display
+}
+
+func (x *xauthOp) Path() string { return x.pathname.String() }
+func (x *xauthOp) String() string {
+	return fmt.Sprintf("display %d in %q via %q", x.display, x.pathname, x.xauth)
+}
+
+// xauthEntry returns the X11 authority file representation of a single
+// MIT-MAGIC-COOKIE-1 entry matching display on any host.
+func xauthEntry(display int, cookie []byte) []byte {
+	number := strconv.Itoa(display)
+	buf := make([]byte, 0, 2*5+len(number)+len(xauthProtocol)+len(cookie))
+	buf = binary.BigEndian.AppendUint16(buf, xauthFamilyWild)
+	for _, field := range [][]byte{nil, []byte(number), []byte(xauthProtocol), cookie} {
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(field)))
+		buf = append(buf, field...)
+	}
+	return buf
+}
@@ -0,0 +1,96 @@
+package system
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"hakurei.app/container/stub"
+	"hakurei.app/internal/acl"
+)
+
+func TestXauthOp(t *testing.T) {
+	t.Parallel()
+
+	const pathname = "/tmp/hakurei.1971/ebf083d1b175911782d413369b64ce7c/Xauthority"
+	cookie := []byte{0xde, 0xad, 0xbe, 0xef, 0xfe, 0xed, 0xfa, 0xce, 0xca, 0xfe, 0xba, 0xbe, 0x0b, 0xad, 0xf0, 0x0d}
+	newOp := func() *xauthOp { return &xauthOp{m("/run/current-system/sw/bin/xauth"), m(pathname), 0} }
+
+	checkOpBehaviour(t, []opBehaviourTestCase{
+		{"xauthGenerate", 0xbeef, 0xff, newOp(), []stub.Call{
+			call("verbosef", stub.ExpectArgs{"generating X11 authorization for display %d in %q", []any{0, m(pathname)}}, nil, nil),
+			call("xauthGenerate", stub.ExpectArgs{"/run/current-system/sw/bin/xauth", pathname, ":0", xauthTimeout}, nil, stub.UniqueError(3)),
+			call("remove", stub.ExpectArgs{pathname}, nil, os.ErrNotExist),
+		}, &OpError{Op: "xauth", Err: stub.UniqueError(3)}, nil, nil},
+
+		{"writeFile", 0xbeef, 0xff, newOp(), []stub.Call{
+			call("verbosef", stub.ExpectArgs{"generating X11 authorization for display %d in %q", []any{0, m(pathname)}}, nil, nil),
+			call("xauthGenerate", stub.ExpectArgs{"/run/current-system/sw/bin/xauth", pathname, ":0", xauthTimeout}, cookie, nil),
+			call("writeFile", stub.ExpectArgs{pathname, xauthEntry(0, cookie), os.FileMode(0600)}, nil, stub.UniqueError(2)),
+			call("remove", stub.ExpectArgs{pathname}, nil, stub.UniqueError(1)),
+		}, &OpError{Op: "xauth", Err: errors.Join(stub.UniqueError(2), stub.UniqueError(1))}, nil, nil},
+
+		{"aclUpdate", 0xbeef, 0xff, newOp(), []stub.Call{
+			call("verbosef", stub.ExpectArgs{"generating X11 authorization for display %d in %q", []any{0, m(pathname)}}, nil, nil),
+			call("xauthGenerate", stub.ExpectArgs{"/run/current-system/sw/bin/xauth", pathname, ":0", xauthTimeout}, cookie, nil),
+			call("writeFile", stub.ExpectArgs{pathname, xauthEntry(0, cookie), os.FileMode(0600)}, nil, nil),
+			call("aclUpdate", stub.ExpectArgs{pathname, 0xbeef, []acl.Perm{acl.Read}}, acl.MaskNone, stub.UniqueError(0)),
+			call("remove", stub.ExpectArgs{pathname}, nil, nil),
+		}, &OpError{Op: "xauth", Err: stub.UniqueError(0)}, nil, nil},
+
+		{"remove", 0xbeef, 0xff, newOp(), []stub.Call{
+			call("verbosef", stub.ExpectArgs{"generating X11 authorization for display %d in %q", []any{0, m(pathname)}}, nil, nil),
+			call("xauthGenerate", stub.ExpectArgs{"/run/current-system/sw/bin/xauth", pathname, ":0", xauthTimeout}, cookie, nil),
+			call("writeFile", stub.ExpectArgs{pathname, xauthEntry(0, cookie), os.FileMode(0600)}, nil, nil),
+			call("aclUpdate", stub.ExpectArgs{pathname, 0xbeef, []acl.Perm{acl.Read}}, acl.MaskNone, nil),
+		}, nil, []stub.Call{
+			call("verbosef", stub.ExpectArgs{"removing X11 authority file %q", []any{m(pathname)}}, nil, nil),
+			call("remove", stub.ExpectArgs{pathname}, nil, stub.UniqueError(0)),
+		}, &OpError{Op: "xauth", Err: stub.UniqueError(0), Revert: true}},
+
+		{"success", 0xbeef, 0xff, newOp(), []stub.Call{
+			call("verbosef", stub.ExpectArgs{"generating X11 authorization for display %d in %q", []any{0, m(pathname)}}, nil, nil),
+			call("xauthGenerate", stub.ExpectArgs{"/run/current-system/sw/bin/xauth", pathname, ":0", xauthTimeout}, cookie, nil),
+			call("writeFile", stub.ExpectArgs{pathname, xauthEntry(0, cookie), os.FileMode(0600)}, nil, nil),
+			call("aclUpdate", stub.ExpectArgs{pathname, 0xbeef, []acl.Perm{acl.Read}}, acl.MaskNone, nil),
+		}, nil, []stub.Call{
+			call("verbosef", stub.ExpectArgs{"removing X11 authority file %q", []any{m(pathname)}}, nil, nil),
+			call("remove", stub.ExpectArgs{pathname}, nil, os.ErrNotExist),
+		}, nil},
+	})
+
+	checkOpsBuilder(t, "Xauth", []opsBuilderTestCase{
+		{"xauth", 0xcafe, func(_ *testing.T, sys *I) {
+			sys.Xauth(m("/run/current-system/sw/bin/xauth"), m(pathname), 0)
+		}, []Op{
+			newOp(),
+		}, stub.Expect{}},
+	})
+
+	checkOpIs(t, []opIsTestCase{
+		{"xauth differs", newOp(), &xauthOp{m("/usr/bin/xauth"), m(pathname), 0}, false},
+		{"pathname differs", newOp(), &xauthOp{m("/run/current-system/sw/bin/xauth"), m(pathname + "\x00"), 0}, false},
+		{"display differs", newOp(), &xauthOp{m("/run/current-system/sw/bin/xauth"), m(pathname), 1}, false},
+		{"equals", newOp(), newOp(), true},
+	})
+
+	checkOpMeta(t, []opMetaTestCase{
+		{"xauth", newOp(), Process, pathname,
+			`display 0 in "` + pathname + `" via "/run/current-system/sw/bin/xauth"`},
+	})
+}
+
+func TestXauthEntry(t *testing.T) {
+	t.Parallel()
+
+	want := []byte{
+		0xff, 0xff, // FamilyWild
+		0x00, 0x00, // address
+		0x00, 0x02, '1', '0', // number
+		0x00, 0x12, 'M', 'I', 'T', '-', 'M', 'A', 'G', 'I', 'C', '-', 'C', 'O', 'O', 'K', 'I', 'E', '-', '1',
+		0x00, 0x04, 0xde, 0xad, 0xbe, 0xef,
+	}
+	if got := xauthEntry(10, []byte{0xde, 0xad, 0xbe, 0xef}); string(got) != string(want) {
+		t.Errorf("xauthEntry: %#v, want %#v", got, want)
+	}
+}
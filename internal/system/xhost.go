@@ -15,6 +15,7 @@ func (sys *I) ChangeHosts(username string) *I {
 type xhostOp string
 
 func (x xhostOp) Type() hst.Enablement { return hst.EX11 }
+func (x xhostOp) name() string         { return "xhost" }
 
 func (x xhostOp) apply(sys *I) error {
 	sys.msg.Verbosef("inserting entry %s to X11", x)
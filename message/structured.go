@@ -0,0 +1,154 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// suspendRecordsMax is the maximum number of records withheld by [NewStructured] while suspended.
+const suspendRecordsMax = 1 << 16
+
+// structuredMsg is an implementation of the [Msg] interface emitting records to a [slog.Handler].
+// The zero value is not safe for use. Callers should use the [NewStructured] function instead.
+type structuredMsg struct {
+	verbose atomic.Bool
+
+	h      slog.Handler
+	logger *log.Logger
+
+	s atomic.Bool
+	// for synchronising pending and dropped
+	mu      sync.Mutex
+	pending []pendingRecord
+	dropped int
+}
+
+// pendingRecord is a [slog.Record] withheld between calls to Suspend and Resume.
+type pendingRecord struct {
+	h slog.Handler
+	r slog.Record
+}
+
+/*
+NewStructured returns a new [Msg] emitting records to h.
+
+Verbose and Verbosef emit a record at [slog.LevelDebug] with the formatted message. Arguments
+of type [slog.Attr] are additionally added to the record as attributes, so callers may attach
+key-value pairs to existing messages. Attributes common to all records, such as the instance
+identifier, should be attached to h via its WithAttrs method.
+
+The [log.Logger] returned by GetLogger emits records at [slog.LevelInfo]. Records are withheld
+between calls to Suspend and Resume, regardless of where they originate from.
+*/
+func NewStructured(h slog.Handler) Msg {
+	msg := &structuredMsg{h: h}
+	msg.logger = slog.NewLogLogger(&structuredHandler{msg, h}, slog.LevelInfo)
+	return msg
+}
+
+func (msg *structuredMsg) GetLogger() *log.Logger { return msg.logger }
+
+func (msg *structuredMsg) IsVerbose() bool               { return msg.verbose.Load() }
+func (msg *structuredMsg) SwapVerbose(verbose bool) bool { return msg.verbose.Swap(verbose) }
+func (msg *structuredMsg) Verbose(v ...any) {
+	if msg.verbose.Load() {
+		msg.emit(strings.TrimSuffix(fmt.Sprintln(v...), "\n"), v)
+	}
+}
+func (msg *structuredMsg) Verbosef(format string, v ...any) {
+	if msg.verbose.Load() {
+		msg.emit(fmt.Sprintf(format, v...), v)
+	}
+}
+
+// emit emits a record at [slog.LevelDebug] holding message and all attributes in v.
+func (msg *structuredMsg) emit(message string, v []any) {
+	ctx := context.Background()
+	if !msg.h.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	r := slog.NewRecord(time.Now(), slog.LevelDebug, message, 0)
+	for _, a := range v {
+		if attr, ok := a.(slog.Attr); ok {
+			r.AddAttrs(attr)
+		}
+	}
+	_ = msg.handle(ctx, msg.h, r)
+}
+
+// handle passes r to h, or withholds it if called between calls to Suspend and Resume.
+func (msg *structuredMsg) handle(ctx context.Context, h slog.Handler, r slog.Record) error {
+	if !msg.s.Load() {
+		return h.Handle(ctx, r)
+	}
+
+	msg.mu.Lock()
+	defer msg.mu.Unlock()
+	if len(msg.pending) >= suspendRecordsMax {
+		msg.dropped++
+		return nil
+	}
+	msg.pending = append(msg.pending, pendingRecord{h, r.Clone()})
+	return nil
+}
+
+func (msg *structuredMsg) Suspend() bool { return msg.s.CompareAndSwap(false, true) }
+
+// Resume passes all withheld records to their handlers and prints a message if
+// records were dropped between calls to Suspend and Resume.
+func (msg *structuredMsg) Resume() bool {
+	if !msg.s.CompareAndSwap(true, false) {
+		return false
+	}
+
+	msg.mu.Lock()
+	pending, dropped := msg.pending, msg.dropped
+	msg.pending, msg.dropped = nil, 0
+	msg.mu.Unlock()
+
+	ctx := context.Background()
+	for _, p := range pending {
+		if err := p.h.Handle(ctx, p.r); err != nil {
+			// probably going to result in an error as well, so this message is as good as unreachable
+			msg.logger.Printf("cannot emit record on resume: %v", err)
+			break
+		}
+	}
+	if dropped > 0 {
+		msg.logger.Printf("dropped %d records while output is suspended", dropped)
+	}
+	return true
+}
+
+// BeforeExit prints a message if called between calls to Suspend and Resume.
+func (msg *structuredMsg) BeforeExit() {
+	if msg.Resume() {
+		msg.logger.Printf("beforeExit reached on suspended output")
+	}
+}
+
+// structuredHandler wraps a [slog.Handler] to withhold records while suspended.
+type structuredHandler struct {
+	msg *structuredMsg
+	h   slog.Handler
+}
+
+func (h *structuredHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.h.Enabled(ctx, level)
+}
+func (h *structuredHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.msg.handle(ctx, h.h, r)
+}
+func (h *structuredHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &structuredHandler{h.msg, h.h.WithAttrs(attrs)}
+}
+func (h *structuredHandler) WithGroup(name string) slog.Handler {
+	return &structuredHandler{h.msg, h.h.WithGroup(name)}
+}
@@ -0,0 +1,83 @@
+package message_test
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"hakurei.app/message"
+)
+
+func TestStructuredMsg(t *testing.T) {
+	t.Parallel()
+
+	var buf strings.Builder
+	msg := message.NewStructured(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}).WithAttrs([]slog.Attr{slog.String("instance", "8e2c76b066dabe574cf073bdb46eb5c1")}))
+
+	steps := []struct {
+		name string
+		want string
+		f    func(t *testing.T, msg message.Msg)
+	}{
+		{"write discard", "", func(_ *testing.T, msg message.Msg) {
+			msg.Verbose("\x00")
+			msg.Verbosef("\x00")
+		}},
+
+		{"swap true", "", func(t *testing.T, msg message.Msg) {
+			if msg.SwapVerbose(true) {
+				t.Error("SwapVerbose unexpected true")
+			}
+		}},
+		{"write verbose", `level=DEBUG msg="bring up 1" instance=8e2c76b066dabe574cf073bdb46eb5c1` + "\n", func(_ *testing.T, msg message.Msg) {
+			msg.Verbose("bring", "up", 1)
+		}},
+		{"write verbosef attr", `level=DEBUG msg="mounting \"/proc\" (pid=1)" instance=8e2c76b066dabe574cf073bdb46eb5c1 pid=1` + "\n", func(_ *testing.T, msg message.Msg) {
+			msg.Verbosef("mounting %q (%v)", "/proc", slog.Int("pid", 1))
+		}},
+		{"write logger", `level=INFO msg="cannot open" instance=8e2c76b066dabe574cf073bdb46eb5c1` + "\n", func(_ *testing.T, msg message.Msg) {
+			msg.GetLogger().Print("cannot open")
+		}},
+
+		{"suspend", "", func(t *testing.T, msg message.Msg) {
+			if !msg.Suspend() {
+				t.Error("Suspend unexpected failure")
+			}
+			msg.Verbose("withheld")
+			msg.GetLogger().Print("withheld logger")
+		}},
+		{"resume", `level=DEBUG msg=withheld instance=8e2c76b066dabe574cf073bdb46eb5c1` + "\n" +
+			`level=INFO msg="withheld logger" instance=8e2c76b066dabe574cf073bdb46eb5c1` + "\n", func(t *testing.T, msg message.Msg) {
+			if !msg.Resume() {
+				t.Error("Resume unexpected failure")
+			}
+		}},
+		{"resume noop", "", func(t *testing.T, msg message.Msg) {
+			if msg.Resume() {
+				t.Error("Resume unexpected success")
+			}
+		}},
+
+		{"beforeExit", `level=INFO msg="beforeExit reached on suspended output" instance=8e2c76b066dabe574cf073bdb46eb5c1` + "\n", func(_ *testing.T, msg message.Msg) {
+			msg.Suspend()
+			msg.BeforeExit()
+		}},
+	}
+	for _, step := range steps {
+		// these share the same handler, so cannot be subtests
+		t.Logf("running step %q", step.name)
+		buf.Reset()
+		step.f(t, msg)
+		if got := buf.String(); got != step.want {
+			t.Errorf("output: %q, want %q", got, step.want)
+		}
+	}
+}
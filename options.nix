@@ -279,6 +279,14 @@ in
                     Whether to share the PulseAudio socket and cookie.
                   '';
                 };
+
+                pipewire = mkOption {
+                  type = nullOr bool;
+                  default = false;
+                  description = ''
+                    Whether to share the PipeWire native socket.
+                  '';
+                };
               };
 
               share = mkOption {
@@ -49,6 +49,7 @@ in
     env = [
       "DBUS_SESSION_BUS_ADDRESS=unix:path=/run/user/1000/bus"
       "HOME=/var/lib/hakurei/u0/a3"
+      "LOGNAME=u0_a3"
       "PULSE_SERVER=unix:/run/user/1000/pulse/native"
       "SHELL=/run/current-system/sw/bin/bash"
       "TERM=linux"
@@ -14,6 +14,7 @@
   want = {
     env = [
       "HOME=/var/lib/hakurei/u0/a0"
+      "LOGNAME=u0_a0"
       "SHELL=/run/current-system/sw/bin/bash"
       "TERM=linux"
       "USER=u0_a0"
@@ -177,6 +177,11 @@ machine.fail('echo \'{"container":{"shell":"/proc/nonexistent","home":"/proc/non
 machine.wait_for_file("/tmp/invalid-identifier-fd")
 print(machine.succeed('grep "^hakurei: cannot write identifier: bad file descriptor$" /tmp/invalid-identifier-fd'))
 
+# Check malformed instance id behaviour:
+machine.fail('echo \'{"container":{"shell":"/proc/nonexistent","home":"/proc/nonexistent","path":"/proc/nonexistent"}}\' | sudo -u alice -i hakurei -v app --instance-id invalid - 2>&1 | tee > /tmp/invalid-instance-id')
+machine.wait_for_file("/tmp/invalid-instance-id")
+print(machine.succeed('grep "^hakurei: generator returned malformed instance id \\"invalid\\"$" /tmp/invalid-instance-id'))
+
 # Check interrupt shim behaviour:
 swaymsg("exec sh -c 'ne-foot; echo -n $? > /tmp/monitor-exit-code'")
 wait_for_window(f"u0_a{hakurei_identity(0)}@machine")